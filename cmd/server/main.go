@@ -17,6 +17,7 @@ import (
 
 	"github.com/joho/godotenv"
 	configaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/config_access"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kiro"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/cmd"
@@ -24,8 +25,14 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/providerstatus"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/selfupdate"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/store"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/telemetry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tenant"
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
+	geminicommon "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/common"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
@@ -65,6 +72,19 @@ func setKiroIncognitoMode(cfg *config.Config, useIncognito, noIncognito bool) {
 // It parses command-line flags, loads configuration, and starts the appropriate
 // service based on the provided flags (login, codex-login, or server mode).
 func main() {
+	// Management subcommands talk to a running proxy and keep their output free of the banner.
+	if len(os.Args) > 1 && cmd.IsSubcommand(os.Args[1]) {
+		os.Exit(cmd.RunSubcommand(os.Args[1:], DefaultConfigPath))
+	}
+	// "login PROVIDER [flags]" is shorthand for the provider's login flag.
+	if expanded, ok, errLogin := cmd.ExpandLoginCommand(os.Args[1:]); ok {
+		if errLogin != nil {
+			_, _ = fmt.Fprintln(os.Stderr, errLogin)
+			os.Exit(2)
+		}
+		os.Args = append([]string{os.Args[0]}, expanded...)
+	}
+
 	fmt.Printf("CLIProxyAPI Version: %s, Commit: %s, BuiltAt: %s\n", buildinfo.Version, buildinfo.Commit, buildinfo.BuildDate)
 
 	// Command-line flags to control the application's behavior.
@@ -77,15 +97,23 @@ func main() {
 	var noBrowser bool
 	var oauthCallbackPort int
 	var antigravityLogin bool
+	var antigravityTier string
+	var skipVerify bool
+	var loginLabels string
 	var kiroLogin bool
 	var kiroGoogleLogin bool
 	var kiroAWSLogin bool
 	var kiroAWSAuthCode bool
 	var kiroImport bool
 	var githubCopilotLogin bool
+	var selfUpdate bool
 	var projectID string
 	var vertexImport string
+	var authMigrateExport string
+	var authMigrateImport string
+	var authMigrateRemoveSource bool
 	var configPath string
+	var profile string
 	var password string
 	var noIncognito bool
 	var useIncognito bool
@@ -102,15 +130,23 @@ func main() {
 	flag.BoolVar(&useIncognito, "incognito", false, "Open browser in incognito/private mode for OAuth (useful for multiple accounts)")
 	flag.BoolVar(&noIncognito, "no-incognito", false, "Force disable incognito mode (uses existing browser session)")
 	flag.BoolVar(&antigravityLogin, "antigravity-login", false, "Login to Antigravity using OAuth")
+	flag.StringVar(&antigravityTier, "tier", "", "Tier to onboard a new Antigravity account to (defaults to the account's default tier)")
+	flag.BoolVar(&skipVerify, "skip-verify", false, "Skip the test request sent after Antigravity login")
+	flag.StringVar(&loginLabels, "labels", "", "Labels stored on the credential created by a login, e.g. tier=pro,region=eu")
 	flag.BoolVar(&kiroLogin, "kiro-login", false, "Login to Kiro using Google OAuth")
 	flag.BoolVar(&kiroGoogleLogin, "kiro-google-login", false, "Login to Kiro using Google OAuth (same as --kiro-login)")
 	flag.BoolVar(&kiroAWSLogin, "kiro-aws-login", false, "Login to Kiro using AWS Builder ID (device code flow)")
 	flag.BoolVar(&kiroAWSAuthCode, "kiro-aws-authcode", false, "Login to Kiro using AWS Builder ID (authorization code flow, better UX)")
 	flag.BoolVar(&kiroImport, "kiro-import", false, "Import Kiro token from Kiro IDE (~/.aws/sso/cache/kiro-auth-token.json)")
 	flag.BoolVar(&githubCopilotLogin, "github-copilot-login", false, "Login to GitHub Copilot using device flow")
+	flag.BoolVar(&selfUpdate, "update", false, "Download, verify and install the latest release in place")
 	flag.StringVar(&projectID, "project_id", "", "Project ID (Gemini only, not required)")
 	flag.StringVar(&configPath, "config", DefaultConfigPath, "Configure File Path")
+	flag.StringVar(&profile, "profile", "", "Config profile to apply, overriding the profile set in the config file")
 	flag.StringVar(&vertexImport, "vertex-import", "", "Import Vertex service account key JSON file")
+	flag.StringVar(&authMigrateExport, "auth-migrate-export", "", "Copy all credentials from the active token store into this directory")
+	flag.StringVar(&authMigrateImport, "auth-migrate-import", "", "Copy all credentials from this directory into the active token store")
+	flag.BoolVar(&authMigrateRemoveSource, "auth-migrate-remove-source", false, "Delete each source credential once its migrated copy is verified")
 	flag.StringVar(&password, "password", "", "")
 
 	flag.CommandLine.Usage = func() {
@@ -138,10 +174,13 @@ func main() {
 			}
 			_, _ = fmt.Fprint(out, s+"\n")
 		})
+		_, _ = fmt.Fprintln(out)
+		cmd.PrintSubcommandUsage(out)
 	}
 
 	// Parse the command-line flags.
 	flag.Parse()
+	config.SetProfile(profile)
 
 	// Core application variables.
 	var err error
@@ -435,6 +474,15 @@ func main() {
 		}
 	}
 	usage.SetStatisticsEnabled(cfg.UsageStatisticsEnabled)
+	usage.ConfigureAttribution(cfg)
+	usage.ConfigureSnapshots(cfg)
+	usage.ConfigureReports(cfg)
+	providerstatus.Configure(cfg)
+	telemetry.Configure(cfg)
+	tenant.Configure(cfg)
+	audit.Configure(cfg)
+	registry.SetModelCapabilities(cfg.ModelCapabilities)
+	geminicommon.ConfigureRemoteMedia(cfg)
 	coreauth.SetQuotaCooldownDisabled(cfg.DisableCooling)
 
 	if err = logging.ConfigureLogOutput(cfg); err != nil {
@@ -445,7 +493,8 @@ func main() {
 	log.Infof("CLIProxyAPI Version: %s, Commit: %s, BuiltAt: %s", buildinfo.Version, buildinfo.Commit, buildinfo.BuildDate)
 
 	// Set the log level based on the configuration.
-	util.SetLogLevel(cfg)
+	logging.ConfigureLogLevel(cfg)
+	logging.WatchLogSignals()
 
 	if resolvedAuthDir, errResolveAuthDir := util.ResolveAuthDir(cfg.AuthDir); errResolveAuthDir != nil {
 		log.Errorf("failed to resolve auth directory: %v", errResolveAuthDir)
@@ -459,6 +508,21 @@ func main() {
 	options := &cmd.LoginOptions{
 		NoBrowser:    noBrowser,
 		CallbackPort: oauthCallbackPort,
+		Metadata:     map[string]string{},
+	}
+	if antigravityTier != "" {
+		options.Metadata["tier"] = antigravityTier
+	}
+	if skipVerify {
+		options.Metadata["skip_verify"] = "true"
+	}
+	if loginLabels != "" {
+		labels, errLabels := coreauth.ParseLabels(loginLabels)
+		if errLabels != nil {
+			log.Errorf("invalid -labels: %v", errLabels)
+			return
+		}
+		options.Labels = labels
 	}
 
 	// Register the shared token store once so all components use the same persistence backend.
@@ -480,6 +544,12 @@ func main() {
 	if vertexImport != "" {
 		// Handle Vertex service account import
 		cmd.DoVertexImport(cfg, vertexImport)
+	} else if authMigrateExport != "" {
+		// Handle credential export from the active token store
+		cmd.DoAuthMigrate(cfg, authMigrateExport, true, authMigrateRemoveSource)
+	} else if authMigrateImport != "" {
+		// Handle credential import into the active token store
+		cmd.DoAuthMigrate(cfg, authMigrateImport, false, authMigrateRemoveSource)
 	} else if login {
 		// Handle Google/Gemini login
 		cmd.DoLogin(cfg, projectID, options)
@@ -525,6 +595,8 @@ func main() {
 		cmd.DoKiroAWSAuthCodeLogin(cfg, options)
 	} else if kiroImport {
 		cmd.DoKiroImport(cfg, options)
+	} else if selfUpdate {
+		cmd.DoSelfUpdate(cfg)
 	} else {
 		// In cloud deploy mode without config file, just wait for shutdown signals
 		if isCloudDeploy && !configFileExists {
@@ -534,6 +606,10 @@ func main() {
 		}
 		// Start the main proxy service
 		managementasset.StartAutoUpdater(context.Background(), configFilePath)
+		if cfg.CheckForUpdates {
+			go selfupdate.CheckAndLog(context.Background(), cfg.ProxyURL)
+		}
+		go util.CheckConfiguredProxies(context.Background(), cfg)
 
 		// 初始化并启动 Kiro token 后台刷新
 		if cfg.AuthDir != "" {
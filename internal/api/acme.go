@@ -0,0 +1,96 @@
+package api
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// acmeDomains returns the host names configured for automatic certificates.
+func acmeDomains(cfg *config.Config) []string {
+	if cfg == nil {
+		return nil
+	}
+	domains := make([]string, 0, len(cfg.TLS.ACME.Domains))
+	for _, domain := range cfg.TLS.ACME.Domains {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// acmeCacheDir resolves where ACME account keys and certificates are kept.
+func acmeCacheDir(cfg *config.Config) string {
+	if dir := strings.TrimSpace(cfg.TLS.ACME.CacheDir); dir != "" {
+		return dir
+	}
+	if base := util.WritablePath(); base != "" {
+		return filepath.Join(base, "acme")
+	}
+	if authDir, err := util.ResolveAuthDir(cfg.AuthDir); err == nil && authDir != "" {
+		return filepath.Join(authDir, "acme")
+	}
+	return "acme"
+}
+
+// acmeTLSConfig returns TLS settings that obtain and renew certificates for the configured
+// domains, keeping the client certificate settings of base. It starts the HTTP-01 challenge
+// listener when one is configured.
+func (s *Server) acmeTLSConfig(base *tls.Config) (*tls.Config, error) {
+	domains := acmeDomains(s.cfg)
+	dir := acmeCacheDir(s.cfg)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create acme cache dir: %w", err)
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(dir),
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Email:      strings.TrimSpace(s.cfg.TLS.ACME.Email),
+	}
+	if directoryURL := strings.TrimSpace(s.cfg.TLS.ACME.DirectoryURL); directoryURL != "" {
+		manager.Client = &acme.Client{DirectoryURL: directoryURL}
+	}
+
+	tlsCfg := manager.TLSConfig()
+	if base != nil {
+		tlsCfg.ClientAuth = base.ClientAuth
+		tlsCfg.VerifyPeerCertificate = base.VerifyPeerCertificate
+		// The CA presents no client certificate when it validates TLS-ALPN-01 challenges.
+		challenge := &tls.Config{GetCertificate: manager.GetCertificate, NextProtos: []string{acme.ALPNProto}}
+		tlsCfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			if slices.Contains(hello.SupportedProtos, acme.ALPNProto) {
+				return challenge, nil
+			}
+			return nil, nil
+		}
+	}
+
+	if port := s.cfg.TLS.ACME.HTTPPort; port > 0 {
+		s.acmeServer = &http.Server{
+			Addr:              fmt.Sprintf("%s:%d", s.cfg.Host, port),
+			Handler:           manager.HTTPHandler(nil),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func(server *http.Server) {
+			if errServe := server.ListenAndServe(); errServe != nil && !errors.Is(errServe, http.ErrServerClosed) {
+				log.Errorf("acme: HTTP challenge listener on %s failed: %v", server.Addr, errServe)
+			}
+		}(s.acmeServer)
+	}
+	log.Infof("acme: serving certificates for %s, cached in %s", strings.Join(domains, ", "), dir)
+	return tlsCfg, nil
+}
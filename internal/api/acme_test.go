@@ -0,0 +1,47 @@
+package api
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/clientcert"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"golang.org/x/crypto/acme"
+)
+
+func TestACMETLSConfig(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "acme")
+	cfg := &config.Config{}
+	cfg.TLS.Enable = true
+	cfg.TLS.ACME = config.TLSACMEConfig{Domains: []string{" Proxy.Example.com ", ""}, CacheDir: dir}
+	cfg.TLS.ClientAuth.Mode = "require"
+	s := &Server{cfg: cfg}
+
+	if got := acmeDomains(cfg); len(got) != 1 || got[0] != "proxy.example.com" {
+		t.Fatalf("acmeDomains = %v", got)
+	}
+	tlsCfg, err := s.acmeTLSConfig(clientcert.TLSConfig(cfg.TLS.ClientAuth))
+	if err != nil {
+		t.Fatalf("acmeTLSConfig: %v", err)
+	}
+	if tlsCfg.ClientAuth != tls.RequireAnyClientCert || tlsCfg.VerifyPeerCertificate == nil {
+		t.Fatal("client certificate settings were not kept")
+	}
+	if s.acmeServer != nil {
+		t.Fatal("challenge listener started without http-port")
+	}
+
+	// Unlisted hosts are refused before the CA is contacted.
+	if _, err = tlsCfg.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.example.com"}); err == nil {
+		t.Fatal("certificate issued for a host that is not configured")
+	}
+
+	challenge, err := tlsCfg.GetConfigForClient(&tls.ClientHelloInfo{SupportedProtos: []string{acme.ALPNProto}})
+	if err != nil || challenge == nil || challenge.ClientAuth != tls.NoClientCert {
+		t.Fatalf("TLS-ALPN-01 handshake config = %+v, %v", challenge, err)
+	}
+	if regular, _ := tlsCfg.GetConfigForClient(&tls.ClientHelloInfo{SupportedProtos: []string{"h2"}}); regular != nil {
+		t.Fatal("regular handshakes must use the listener config")
+	}
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/netaccess"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const bytesPerMB = 1 << 20
+
+// bodyLimitSettings is the active request-limits configuration in bytes.
+type bodyLimitSettings struct {
+	maxBody    int64
+	endpoints  []endpointBodyLimit
+	maxInline  int64
+	configured bool
+}
+
+type endpointBodyLimit struct {
+	path    string
+	maxBody int64
+}
+
+var bodyLimits atomic.Pointer[bodyLimitSettings]
+
+// configureBodyLimits applies the request-limits settings.
+func configureBodyLimits(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+	limits := cfg.RequestLimits
+	next := &bodyLimitSettings{
+		maxBody:   megabytes(limits.MaxBodySizeMB),
+		maxInline: megabytes(limits.MaxInlineDataMB),
+	}
+	for _, endpoint := range limits.Endpoints {
+		path := strings.TrimRight(strings.TrimSpace(endpoint.Path), "/")
+		if path == "" {
+			continue
+		}
+		next.endpoints = append(next.endpoints, endpointBodyLimit{path: path, maxBody: megabytes(endpoint.MaxBodySizeMB)})
+	}
+	next.configured = next.maxBody > 0 || next.maxInline > 0 || len(next.endpoints) > 0
+	bodyLimits.Store(next)
+}
+
+func megabytes(mb int) int64 {
+	if mb <= 0 {
+		return 0
+	}
+	return int64(mb) * bytesPerMB
+}
+
+// limitFor returns the body limit of path, or 0 when it is unlimited.
+func (s *bodyLimitSettings) limitFor(path string) int64 {
+	limit, matched := s.maxBody, -1
+	for _, endpoint := range s.endpoints {
+		if len(endpoint.path) > matched && hasPathPrefix(path, endpoint.path) {
+			limit, matched = endpoint.maxBody, len(endpoint.path)
+		}
+	}
+	return limit
+}
+
+// bodyLimitMiddleware rejects request bodies over the configured limits with 413. Bodies of known
+// length are checked against Content-Length before anything is read; other bodies are read
+// through a limiting reader, so an oversized upload is cut off instead of buffered. JSON bodies
+// are also checked for oversized base64 payloads.
+func bodyLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		st := bodyLimits.Load()
+		if st == nil || !st.configured || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		limit := st.limitFor(c.Request.URL.Path)
+		if limit > 0 {
+			if c.Request.ContentLength > limit {
+				abortRequestTooLarge(c, fmt.Sprintf("request body of %d bytes exceeds the %d byte limit", c.Request.ContentLength, limit))
+				return
+			}
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+		inspect := st.maxInline > 0 && (mediaType == "" || strings.HasSuffix(mediaType, "json"))
+		// Multipart uploads are streamed by their handlers and stay behind the limiting reader.
+		if !inspect && (limit <= 0 || c.Request.ContentLength >= 0 || strings.HasPrefix(mediaType, "multipart/")) {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				abortRequestTooLarge(c, fmt.Sprintf("request body exceeds the %d byte limit", maxErr.Limit))
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("read request body: %v", err)})
+			return
+		}
+		if inspect {
+			if size := oversizedInlineData(body, st.maxInline); size > 0 {
+				abortRequestTooLarge(c, fmt.Sprintf("inline base64 data of %d bytes exceeds the %d byte limit", size, st.maxInline))
+				return
+			}
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Next()
+	}
+}
+
+// abortRequestTooLarge answers 413 in the error format of the API surface being called.
+func abortRequestTooLarge(c *gin.Context, message string) {
+	log.WithFields(log.Fields{
+		"client_ip": netaccess.ClientIP(c),
+		"method":    c.Request.Method,
+		"path":      c.Request.URL.Path,
+	}).Warn(message)
+	// The rest of the body is not read, so the connection cannot be reused.
+	c.Header("Connection", "close")
+	var body gin.H
+	switch inboundProtocol(c.Request.URL.Path) {
+	case protocolClaude:
+		body = gin.H{"type": "error", "error": gin.H{"type": "request_too_large", "message": message}}
+	case protocolGemini:
+		body = gin.H{"error": gin.H{"code": http.StatusRequestEntityTooLarge, "message": message, "status": "INVALID_ARGUMENT"}}
+	default:
+		body = gin.H{"error": gin.H{"message": message, "type": "invalid_request_error", "code": "request_too_large"}}
+	}
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, body)
+}
+
+// oversizedInlineData returns the decoded size of the first base64 payload in body larger than
+// limit, or 0 when there is none. Payloads are data: URLs anywhere in the document and "data"
+// fields, which carry Claude and Gemini inline images and OpenAI input audio.
+func oversizedInlineData(body []byte, limit int64) int64 {
+	var found int64
+	var walk func(key string, value gjson.Result) bool
+	walk = func(key string, value gjson.Result) bool {
+		switch {
+		case value.IsObject() || value.IsArray():
+			value.ForEach(func(k, v gjson.Result) bool { return walk(k.Str, v) })
+		case value.Type == gjson.String:
+			if size := inlineDataSize(key, value.Raw); size > limit {
+				found = size
+			}
+		}
+		return found == 0
+	}
+	walk("", gjson.ParseBytes(body))
+	return found
+}
+
+// inlineDataSize estimates the decoded size of the base64 payload in the raw JSON string of a
+// field named key, or returns 0 when the string carries none.
+func inlineDataSize(key, raw string) int64 {
+	if len(raw) < 2 {
+		return 0
+	}
+	payload := raw[1 : len(raw)-1]
+	if strings.HasPrefix(payload, "data:") {
+		header := payload[:min(len(payload), 256)]
+		idx := strings.Index(header, ";base64,")
+		if idx < 0 {
+			return 0
+		}
+		payload = payload[idx+len(";base64,"):]
+	} else if key != "data" {
+		return 0
+	}
+	return int64(base64.StdEncoding.DecodedLen(len(payload)))
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	proxyconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	configureBodyLimits(&proxyconfig.Config{RequestLimits: proxyconfig.RequestLimitsConfig{
+		MaxBodySizeMB:   1,
+		MaxInlineDataMB: 1,
+		Endpoints:       []proxyconfig.EndpointBodyLimit{{Path: "/v1/files", MaxBodySizeMB: 2}},
+	}})
+	t.Cleanup(func() { configureBodyLimits(&proxyconfig.Config{}) })
+
+	engine := gin.New()
+	engine.Use(bodyLimitMiddleware())
+	engine.POST("/*path", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%d", len(body))
+	})
+
+	large := strings.Repeat("a", 3<<19) // 1.5 MB
+	// 1.2 MB decoded, within the 2 MB body limit of /v1/files.
+	image := `{"messages":[{"content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,` + strings.Repeat("A", 1600000) + `"}}]}]}`
+	cases := []struct {
+		name    string
+		path    string
+		body    string
+		chunked bool
+		want    int
+	}{
+		{name: "small", path: "/v1/chat/completions", body: `{"model":"m"}`, want: http.StatusOK},
+		{name: "content length over limit", path: "/v1/chat/completions", body: large, want: http.StatusRequestEntityTooLarge},
+		{name: "chunked over limit", path: "/v1/messages", body: large, chunked: true, want: http.StatusRequestEntityTooLarge},
+		{name: "endpoint override", path: "/v1/files", body: large, want: http.StatusOK},
+		{name: "inline image", path: "/v1/files", body: image, want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+			rr := httptest.NewRecorder()
+			engine.ServeHTTP(rr, req)
+			if rr.Code != tc.want {
+				t.Fatalf("status = %d, want %d; body=%s", rr.Code, tc.want, rr.Body.String())
+			}
+			if tc.name == "inline image" && !strings.Contains(rr.Body.String(), "inline base64 data") {
+				t.Fatalf("unexpected rejection: %s", rr.Body.String())
+			}
+			if tc.want == http.StatusRequestEntityTooLarge && strings.HasPrefix(tc.path, "/v1/messages") {
+				if got := gjson.Get(rr.Body.String(), "error.type").String(); got != "request_too_large" {
+					t.Fatalf("claude error type = %q", got)
+				}
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/fxamacker/cbor/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/geminicli"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
@@ -672,12 +673,19 @@ func (h *Handler) apiCallTransport(auth *coreauth.Auth) http.RoundTripper {
 		}
 	}
 	if h != nil && h.cfg != nil {
-		if proxyStr := strings.TrimSpace(h.cfg.ProxyURL); proxyStr != "" {
+		var provider string
+		if auth != nil {
+			provider = auth.Provider
+		}
+		if proxyStr := h.cfg.ProxyURLFor(provider); proxyStr != "" {
 			proxyCandidates = append(proxyCandidates, proxyStr)
 		}
 	}
 
 	for _, proxyStr := range proxyCandidates {
+		if strings.EqualFold(proxyStr, config.ProxyDirect) {
+			break
+		}
 		if transport := buildProxyTransport(proxyStr); transport != nil {
 			return transport
 		}
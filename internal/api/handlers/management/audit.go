@@ -0,0 +1,48 @@
+package management
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// GetAuditLog returns audit entries, newest first. Query parameters: request-id, api-key (raw or
+// already hashed), model, outcome, since (RFC 3339 time or a duration such as 1h) and limit.
+func (h *Handler) GetAuditLog(c *gin.Context) {
+	auditLog := audit.Active()
+	if auditLog == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "audit log is disabled"})
+		return
+	}
+	filter := audit.Filter{
+		RequestID: strings.TrimSpace(c.Query("request-id")),
+		Model:     strings.TrimSpace(c.Query("model")),
+		Outcome:   strings.TrimSpace(c.Query("outcome")),
+		APIKey:    util.HashAPIKey(c.Query("api-key")),
+	}
+	if raw := strings.TrimSpace(c.Query("since")); raw != "" {
+		if since, err := time.Parse(time.RFC3339, raw); err == nil {
+			filter.Since = since
+		} else if window, errDuration := time.ParseDuration(raw); errDuration == nil && window > 0 {
+			filter.Since = time.Now().Add(-window)
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since, expected RFC 3339 time or duration"})
+			return
+		}
+	}
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = limit
+	}
+	entries := auditLog.Query(filter)
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "count": len(entries)})
+}
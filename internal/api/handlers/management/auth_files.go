@@ -392,6 +392,9 @@ func (h *Handler) buildAuthFileEntry(auth *coreauth.Auth) gin.H {
 	if email := authEmail(auth); email != "" {
 		entry["email"] = email
 	}
+	if labels := auth.Labels(); len(labels) > 0 {
+		entry["labels"] = labels
+	}
 	if accountType, account := auth.AccountInfo(); accountType != "" || account != "" {
 		if accountType != "" {
 			entry["account_type"] = accountType
@@ -774,20 +777,7 @@ func (h *Handler) PatchAuthFileStatus(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	// Find auth by name or ID
-	var targetAuth *coreauth.Auth
-	if auth, ok := h.authManager.GetByID(name); ok {
-		targetAuth = auth
-	} else {
-		auths := h.authManager.List()
-		for _, auth := range auths {
-			if auth.FileName == name {
-				targetAuth = auth
-				break
-			}
-		}
-	}
-
+	targetAuth := h.findAuth(name)
 	if targetAuth == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "auth file not found"})
 		return
@@ -812,6 +802,117 @@ func (h *Handler) PatchAuthFileStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "disabled": *req.Disabled})
 }
 
+// PatchAuthFileLabels sets the labels of an auth file. Labels are merged into the existing ones
+// and an empty value removes a label; with "replace" the given labels replace all existing ones.
+func (h *Handler) PatchAuthFileLabels(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+
+	var req struct {
+		Name    string            `json:"name"`
+		Labels  map[string]string `json:"labels"`
+		Replace bool              `json:"replace"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	if req.Labels == nil && !req.Replace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "labels is required"})
+		return
+	}
+	labels := make(map[string]string, len(req.Labels))
+	for key, value := range req.Labels {
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if err := coreauth.ValidateLabel(key, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		labels[key] = value
+	}
+
+	targetAuth := h.findAuth(name)
+	if targetAuth == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "auth file not found"})
+		return
+	}
+
+	if req.Replace {
+		targetAuth.SetLabels(nil)
+	}
+	targetAuth.MergeLabels(labels)
+	targetAuth.UpdatedAt = time.Now()
+
+	if _, err := h.authManager.Update(c.Request.Context(), targetAuth); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to update auth: %v", err)})
+		return
+	}
+
+	current := targetAuth.Labels()
+	if current == nil {
+		current = map[string]string{}
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "labels": current})
+}
+
+// RefreshAuthFile refreshes the OAuth tokens of an auth file immediately.
+func (h *Handler) RefreshAuthFile(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	targetAuth := h.findAuth(name)
+	if targetAuth == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "auth file not found"})
+		return
+	}
+	if err := h.authManager.Refresh(c.Request.Context(), targetAuth.ID); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to refresh auth: %v", err)})
+		return
+	}
+	refreshed, _ := h.authManager.GetByID(targetAuth.ID)
+	if refreshed == nil {
+		refreshed = targetAuth
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "last_refresh": refreshed.LastRefreshedAt})
+}
+
+// findAuth looks up an auth by ID or file name.
+func (h *Handler) findAuth(name string) *coreauth.Auth {
+	if auth, ok := h.authManager.GetByID(name); ok {
+		return auth
+	}
+	for _, auth := range h.authManager.List() {
+		if auth.FileName == name {
+			return auth
+		}
+	}
+	return nil
+}
+
 func (h *Handler) disableAuth(ctx context.Context, id string) {
 	if h == nil || h.authManager == nil {
 		return
@@ -0,0 +1,82 @@
+package management
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+)
+
+// GetCanaryRouting returns the canary routes with the traffic each split has served.
+func (h *Handler) GetCanaryRouting(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"canary-routing": h.cfg.CanaryRouting,
+		"stats":          handlers.CanaryStats(h.cfg.CanaryRouting),
+	})
+}
+
+// PutCanaryRouting replaces the canary routes, e.g. to shift weight to a new model. The body is
+// the list of routes or {"items": [...]}.
+func (h *Handler) PutCanaryRouting(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+	var routes []config.CanaryRoute
+	if err = json.Unmarshal(data, &routes); err != nil {
+		var wrapper struct {
+			Items []config.CanaryRoute `json:"items"`
+		}
+		if errWrapper := json.Unmarshal(data, &wrapper); errWrapper != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		routes = wrapper.Items
+	}
+	if err = validateCanaryRoutes(routes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.cfg.CanaryRouting = routes
+	h.persist(c)
+}
+
+// DeleteCanaryStats resets the per-split counters, e.g. after changing weights.
+func (h *Handler) DeleteCanaryStats(c *gin.Context) {
+	handlers.ResetCanaryStats()
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func validateCanaryRoutes(routes []config.CanaryRoute) error {
+	seen := make(map[string]bool, len(routes))
+	for i := range routes {
+		route := &routes[i]
+		route.Alias = strings.TrimSpace(route.Alias)
+		if route.Alias == "" {
+			return fmt.Errorf("canary route %d: alias is required", i)
+		}
+		if seen[strings.ToLower(route.Alias)] {
+			return fmt.Errorf("canary route %q is defined twice", route.Alias)
+		}
+		seen[strings.ToLower(route.Alias)] = true
+		if len(route.Splits) == 0 {
+			return fmt.Errorf("canary route %q: at least one split is required", route.Alias)
+		}
+		for j := range route.Splits {
+			split := &route.Splits[j]
+			split.Model = strings.TrimSpace(split.Model)
+			if split.Model == "" {
+				return fmt.Errorf("canary route %q: split %d has no model", route.Alias, j)
+			}
+			if split.Weight < 0 {
+				return fmt.Errorf("canary route %q: split %q has a negative weight", route.Alias, split.Model)
+			}
+		}
+	}
+	return nil
+}
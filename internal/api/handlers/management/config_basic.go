@@ -1,95 +1,31 @@
 package management
 
 import (
-	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
-	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
-	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
-	log "github.com/sirupsen/logrus"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"gopkg.in/yaml.v3"
 )
 
-const (
-	latestReleaseURL       = "https://api.github.com/repos/router-for-me/CLIProxyAPIPlus/releases/latest"
-	latestReleaseUserAgent = "CLIProxyAPIPlus"
-)
-
 func (h *Handler) GetConfig(c *gin.Context) {
 	if h == nil || h.cfg == nil {
 		c.JSON(200, gin.H{})
 		return
 	}
 	cfgCopy := *h.cfg
-	c.JSON(200, &cfgCopy)
-}
-
-type releaseInfo struct {
-	TagName string `json:"tag_name"`
-	Name    string `json:"name"`
-}
-
-// GetLatestVersion returns the latest release version from GitHub without downloading assets.
-func (h *Handler) GetLatestVersion(c *gin.Context) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	proxyURL := ""
-	if h != nil && h.cfg != nil {
-		proxyURL = strings.TrimSpace(h.cfg.ProxyURL)
-	}
-	if proxyURL != "" {
-		sdkCfg := &sdkconfig.SDKConfig{ProxyURL: proxyURL}
-		util.SetProxy(sdkCfg, client)
-	}
-
-	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, latestReleaseURL, nil)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "request_create_failed", "message": err.Error()})
-		return
-	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("User-Agent", latestReleaseUserAgent)
-
-	resp, err := client.Do(req)
+	redacted, err := config.RedactResolvedReferences(&cfgCopy)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "request_failed", "message": err.Error()})
-		return
-	}
-	defer func() {
-		if errClose := resp.Body.Close(); errClose != nil {
-			log.WithError(errClose).Debug("failed to close latest version response body")
-		}
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		c.JSON(http.StatusBadGateway, gin.H{"error": "unexpected_status", "message": fmt.Sprintf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))})
-		return
-	}
-
-	var info releaseInfo
-	if errDecode := json.NewDecoder(resp.Body).Decode(&info); errDecode != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "decode_failed", "message": errDecode.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "encode_failed", "message": err.Error()})
 		return
 	}
-
-	version := strings.TrimSpace(info.TagName)
-	if version == "" {
-		version = strings.TrimSpace(info.Name)
-	}
-	if version == "" {
-		c.JSON(http.StatusBadGateway, gin.H{"error": "invalid_response", "message": "missing release version"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"latest-version": version})
+	c.JSON(200, redacted)
 }
 
 func WriteConfig(path string, data []byte) error {
@@ -120,6 +56,15 @@ func (h *Handler) PutConfigYAML(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_yaml", "message": err.Error()})
 		return
 	}
+	current, _ := os.ReadFile(h.configFilePath)
+	if !config.LayoutKeysEqual(body, current) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden_field", "message": "include, profile and profiles can only be changed by editing the config file on the host"})
+		return
+	}
+	if fields, errRefs := config.NewSecretReferences(body, current); errRefs == nil && len(fields) > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden_field", "message": secretReferenceMessage(fields)})
+		return
+	}
 	// Validate config using LoadConfigOptional with optional=false to enforce parsing
 	tmpDir := filepath.Dir(h.configFilePath)
 	tmpFile, err := os.CreateTemp(tmpDir, "config-validate-*.yaml")
@@ -142,13 +87,17 @@ func (h *Handler) PutConfigYAML(c *gin.Context) {
 	defer func() {
 		_ = os.Remove(tempFile)
 	}()
-	_, err = config.LoadConfigOptional(tempFile, false)
+	validated, err := config.LoadConfigOptional(tempFile, false)
 	if err != nil {
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid_config", "message": err.Error()})
 		return
 	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	if !requestScriptsEqual(h.cfg, validated) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden_field", "message": "request-scripts runs local commands and can only be changed by editing the config file on the host"})
+		return
+	}
 	if WriteConfig(h.configFilePath, body) != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "write_failed", "message": "failed to write config"})
 		return
@@ -163,6 +112,27 @@ func (h *Handler) PutConfigYAML(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"ok": true, "changed": []string{"config"}})
 }
 
+// secretReferenceMessage explains why new env, file or Vault references are refused.
+func secretReferenceMessage(fields []string) string {
+	return "env, file and vault references can only be added by editing the config file on the host: " + strings.Join(fields, ", ")
+}
+
+// requestScriptsEqual reports whether two configs declare the same request-scripts. Scripts are
+// executed on the host, so the management API must not be able to add or change them.
+func requestScriptsEqual(current, next *config.Config) bool {
+	var a, b []config.RequestScript
+	if current != nil {
+		a = current.RequestScripts
+	}
+	if next != nil {
+		b = next.RequestScripts
+	}
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
 // GetConfigYAML returns the raw config.yaml file bytes without re-encoding.
 // It preserves comments and original formatting/styles.
 func (h *Handler) GetConfigYAML(c *gin.Context) {
@@ -194,6 +164,14 @@ func (h *Handler) PutUsageStatisticsEnabled(c *gin.Context) {
 	h.updateBoolField(c, func(v bool) { h.cfg.UsageStatisticsEnabled = v })
 }
 
+// UsageAttribution
+func (h *Handler) GetUsageAttribution(c *gin.Context) {
+	c.JSON(200, gin.H{"usage-attribution": usage.NormalizeAttributionMode(h.cfg.UsageAttribution)})
+}
+func (h *Handler) PutUsageAttribution(c *gin.Context) {
+	h.updateStringField(c, func(v string) { h.cfg.UsageAttribution = usage.NormalizeAttributionMode(v) })
+}
+
 // UsageStatisticsEnabled
 func (h *Handler) GetLoggingToFile(c *gin.Context) {
 	c.JSON(200, gin.H{"logging-to-file": h.cfg.LoggingToFile})
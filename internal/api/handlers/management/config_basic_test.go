@@ -0,0 +1,105 @@
+package management
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestPutConfigYAMLRejectsRequestScriptChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("port: 8317\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	h := NewHandler(cfg, configPath, nil)
+
+	put := func(body string) int {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodPut, "/v0/management/config.yaml", strings.NewReader(body))
+		h.PutConfigYAML(c)
+		return rec.Code
+	}
+
+	withScript := "port: 8317\nrequest-scripts:\n  - stage: inbound\n    command: [\"sh\", \"-c\", \"id\"]\n"
+	if code := put(withScript); code != http.StatusForbidden {
+		t.Fatalf("adding a request script: status = %d, want %d", code, http.StatusForbidden)
+	}
+	data, _ := os.ReadFile(configPath)
+	if strings.Contains(string(data), "request-scripts") {
+		t.Fatalf("rejected config must not be written: %s", data)
+	}
+
+	if code := put("port: 8318\n"); code != http.StatusOK {
+		t.Fatalf("unrelated change: status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestPutConfigYAMLRejectsNewSecretReferences(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("CLIPROXY_TEST_KEY", "sk-env")
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("api-keys:\n  - ${CLIPROXY_TEST_KEY}\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	h := NewHandler(cfg, configPath, nil)
+
+	put := func(body string) int {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodPut, "/v0/management/config.yaml", strings.NewReader(body))
+		h.PutConfigYAML(c)
+		return rec.Code
+	}
+
+	if code := put("api-keys:\n  - ${CLIPROXY_TEST_KEY}\nproxy-url: file:/etc/hostname\n"); code != http.StatusForbidden {
+		t.Fatalf("adding a file reference: status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := put("api-keys:\n  - ${CLIPROXY_TEST_KEY}\n  - plain\n"); code != http.StatusOK {
+		t.Fatalf("keeping an existing reference: status = %d, want %d", code, http.StatusOK)
+	}
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v0/management/config", nil)
+	h.GetConfig(c)
+	if body := rec.Body.String(); strings.Contains(body, "sk-env") || !strings.Contains(body, "${CLIPROXY_TEST_KEY}") {
+		t.Fatalf("GET config must return the reference, not its value: %s", body)
+	}
+}
+
+func TestPutConfigYAMLRejectsIncludeChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("port: 8317\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	h := NewHandler(cfg, configPath, nil)
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPut, "/v0/management/config.yaml", strings.NewReader("port: 8317\ninclude: auths/*evil*.json\n"))
+	h.PutConfigYAML(c)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("adding an include: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
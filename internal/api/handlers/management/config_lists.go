@@ -703,6 +703,67 @@ func (h *Handler) DeleteOAuthExcludedModels(c *gin.Context) {
 	h.persist(c)
 }
 
+// model-capabilities: []ModelCapability
+func (h *Handler) GetModelCapabilities(c *gin.Context) {
+	c.JSON(200, gin.H{"model-capabilities": config.NormalizeModelCapabilities(h.cfg.ModelCapabilities)})
+}
+
+func (h *Handler) PutModelCapabilities(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(400, gin.H{"error": "failed to read body"})
+		return
+	}
+	var entries []config.ModelCapability
+	if err = json.Unmarshal(data, &entries); err != nil {
+		var wrapper struct {
+			Items []config.ModelCapability `json:"items"`
+		}
+		if err2 := json.Unmarshal(data, &wrapper); err2 != nil {
+			c.JSON(400, gin.H{"error": "invalid body"})
+			return
+		}
+		entries = wrapper.Items
+	}
+	h.cfg.ModelCapabilities = config.NormalizeModelCapabilities(entries)
+	h.persist(c)
+}
+
+// PatchModelCapabilities adds or replaces the override for a single model.
+func (h *Handler) PatchModelCapabilities(c *gin.Context) {
+	var body config.ModelCapability
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(400, gin.H{"error": "invalid body"})
+		return
+	}
+	if strings.TrimSpace(body.ID) == "" {
+		c.JSON(400, gin.H{"error": "missing id"})
+		return
+	}
+	h.cfg.ModelCapabilities = config.NormalizeModelCapabilities(append(h.cfg.ModelCapabilities, body))
+	h.persist(c)
+}
+
+func (h *Handler) DeleteModelCapabilities(c *gin.Context) {
+	id := strings.TrimSpace(c.Query("id"))
+	if id == "" {
+		c.JSON(400, gin.H{"error": "missing id"})
+		return
+	}
+	out := make([]config.ModelCapability, 0, len(h.cfg.ModelCapabilities))
+	for _, entry := range h.cfg.ModelCapabilities {
+		if !strings.EqualFold(strings.TrimSpace(entry.ID), id) {
+			out = append(out, entry)
+		}
+	}
+	if len(out) == len(h.cfg.ModelCapabilities) {
+		c.JSON(404, gin.H{"error": "model not found"})
+		return
+	}
+	h.cfg.ModelCapabilities = config.NormalizeModelCapabilities(out)
+	h.persist(c)
+}
+
 // oauth-model-alias: map[string][]OAuthModelAlias
 func (h *Handler) GetOAuthModelAlias(c *gin.Context) {
 	c.JSON(200, gin.H{"oauth-model-alias": sanitizedOAuthModelAlias(h.cfg.OAuthModelAlias)})
@@ -0,0 +1,101 @@
+package management
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/claude"
+)
+
+var publishVarsOnce sync.Once
+
+// publishVars exposes the runtime diagnostics through expvar next to its memstats.
+func publishVars() {
+	publishVarsOnce.Do(func() {
+		expvar.Publish("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+		expvar.Publish("utls_transports", expvar.Func(func() any { return claude.UTLSTransportStats() }))
+	})
+}
+
+// GetPprof serves the net/http/pprof profiles below /debug/pprof/. The index page lists them.
+// The cmdline endpoint is not served since the command line may carry the management key.
+func (h *Handler) GetPprof(c *gin.Context) {
+	switch name := strings.Trim(c.Param("profile"), "/"); name {
+	case "":
+		// pprof.Index resolves profile names from a path rooted at /debug/pprof/.
+		c.Request.URL.Path = "/debug/pprof/"
+		pprof.Index(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		if runtimepprof.Lookup(name) == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown profile"})
+			return
+		}
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// GetExpvar serves the expvar variables as JSON, in the format of expvar.Handler. The cmdline
+// variable is left out since the command line may carry the management key.
+func (h *Handler) GetExpvar(c *gin.Context) {
+	publishVars()
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if kv.Key == "cmdline" {
+			return
+		}
+		if !first {
+			buf.WriteString(",\n")
+		}
+		first = false
+		fmt.Fprintf(&buf, "%q: %s", kv.Key, kv.Value)
+	})
+	buf.WriteString("\n}\n")
+	c.Data(http.StatusOK, "application/json; charset=utf-8", buf.Bytes())
+}
+
+// GetRuntimeDiagnostics returns goroutine and memory statistics and the state of the utls
+// connection caches. With ?stacks=true it also returns the stacks of all goroutines.
+func (h *Handler) GetRuntimeDiagnostics(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	resp := gin.H{
+		"go-version": runtime.Version(),
+		"num-cpu":    runtime.NumCPU(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"goroutines": runtime.NumGoroutine(),
+		"memory": gin.H{
+			"heap-alloc":     mem.HeapAlloc,
+			"heap-inuse":     mem.HeapInuse,
+			"heap-objects":   mem.HeapObjects,
+			"sys":            mem.Sys,
+			"num-gc":         mem.NumGC,
+			"pause-total-ns": mem.PauseTotalNs,
+		},
+		"utls-transports": claude.UTLSTransportStats(),
+	}
+	if c.Query("stacks") == "true" {
+		var buf bytes.Buffer
+		if err := runtimepprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		resp["stacks"] = buf.String()
+	}
+	c.JSON(http.StatusOK, resp)
+}
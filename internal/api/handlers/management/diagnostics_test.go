@@ -0,0 +1,63 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestDiagnosticsEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(&config.Config{}, "", nil)
+	router := gin.New()
+	router.GET("/v0/management/debug/pprof/*profile", h.GetPprof)
+	router.GET("/v0/management/debug/vars", h.GetExpvar)
+	router.GET("/v0/management/debug/runtime", h.GetRuntimeDiagnostics)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := get("/v0/management/debug/pprof/"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Fatalf("pprof index: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := get("/v0/management/debug/pprof/goroutine?debug=1"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine profile") {
+		t.Fatalf("goroutine profile: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := get("/v0/management/debug/pprof/nonexistent"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown profile: status %d", rec.Code)
+	}
+	if rec := get("/v0/management/debug/pprof/cmdline"); rec.Code != http.StatusNotFound {
+		t.Fatalf("cmdline: status %d", rec.Code)
+	}
+	rec := get("/v0/management/debug/vars")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"utls_transports"`) || strings.Contains(rec.Body.String(), `"cmdline"`) {
+		t.Fatalf("expvar: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	var vars map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("decode expvar response: %v", err)
+	}
+
+	rec = get("/v0/management/debug/runtime?stacks=true")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("runtime: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Goroutines int    `json:"goroutines"`
+		Stacks     string `json:"stacks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode runtime response: %v", err)
+	}
+	if resp.Goroutines == 0 || !strings.Contains(resp.Stacks, "goroutine ") {
+		t.Fatalf("unexpected runtime response: %s", rec.Body.String())
+	}
+}
@@ -8,38 +8,29 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/authguard"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/netaccess"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
 )
 
-type attemptInfo struct {
-	count        int
-	blockedUntil time.Time
-	lastActivity time.Time // track last activity for cleanup
-}
-
-// attemptCleanupInterval controls how often stale IP entries are purged
-const attemptCleanupInterval = 1 * time.Hour
-
-// attemptMaxIdleTime controls how long an IP can be idle before cleanup
-const attemptMaxIdleTime = 2 * time.Hour
-
 // Handler aggregates config reference, persistence path and helpers.
 type Handler struct {
 	cfg                 *config.Config
 	configFilePath      string
 	mu                  sync.Mutex
-	attemptsMu          sync.Mutex
-	failedAttempts      map[string]*attemptInfo // keyed by client IP
+	guard               *authguard.Guard // failed remote attempts, keyed by client IP
 	authManager         *coreauth.Manager
 	usageStats          *usage.RequestStatistics
 	tokenStore          coreauth.Store
@@ -57,45 +48,17 @@ func NewHandler(cfg *config.Config, configFilePath string, manager *coreauth.Man
 	h := &Handler{
 		cfg:                 cfg,
 		configFilePath:      configFilePath,
-		failedAttempts:      make(map[string]*attemptInfo),
+		guard:               authguard.New("management"),
 		authManager:         manager,
 		usageStats:          usage.GetRequestStatistics(),
 		tokenStore:          sdkAuth.GetTokenStore(),
 		allowRemoteOverride: envSecret != "",
 		envSecret:           envSecret,
 	}
-	h.startAttemptCleanup()
-	return h
-}
-
-// startAttemptCleanup launches a background goroutine that periodically
-// removes stale IP entries from failedAttempts to prevent memory leaks.
-func (h *Handler) startAttemptCleanup() {
-	go func() {
-		ticker := time.NewTicker(attemptCleanupInterval)
-		defer ticker.Stop()
-		for range ticker.C {
-			h.purgeStaleAttempts()
-		}
-	}()
-}
-
-// purgeStaleAttempts removes IP entries that have been idle beyond attemptMaxIdleTime
-// and whose ban (if any) has expired.
-func (h *Handler) purgeStaleAttempts() {
-	now := time.Now()
-	h.attemptsMu.Lock()
-	defer h.attemptsMu.Unlock()
-	for ip, ai := range h.failedAttempts {
-		// Skip if still banned
-		if !ai.blockedUntil.IsZero() && now.Before(ai.blockedUntil) {
-			continue
-		}
-		// Remove if idle too long
-		if now.Sub(ai.lastActivity) > attemptMaxIdleTime {
-			delete(h.failedAttempts, ip)
-		}
+	if cfg != nil {
+		h.guard.Configure(cfg.AuthGuard, cfg.ProxyURL)
 	}
+	return h
 }
 
 // NewHandler creates a new management handler instance.
@@ -104,7 +67,12 @@ func NewHandlerWithoutConfigFilePath(cfg *config.Config, manager *coreauth.Manag
 }
 
 // SetConfig updates the in-memory config reference when the server hot-reloads.
-func (h *Handler) SetConfig(cfg *config.Config) { h.cfg = cfg }
+func (h *Handler) SetConfig(cfg *config.Config) {
+	h.cfg = cfg
+	if cfg != nil {
+		h.guard.Configure(cfg.AuthGuard, cfg.ProxyURL)
+	}
+}
 
 // SetAuthManager updates the auth manager reference used by management endpoints.
 func (h *Handler) SetAuthManager(manager *coreauth.Manager) { h.authManager = manager }
@@ -132,16 +100,16 @@ func (h *Handler) SetLogDirectory(dir string) {
 // All requests (local and remote) require a valid management key.
 // Additionally, remote access requires allow-remote-management=true.
 func (h *Handler) Middleware() gin.HandlerFunc {
-	const maxFailures = 5
-	const banDuration = 30 * time.Minute
-
 	return func(c *gin.Context) {
 		c.Header("X-CPA-VERSION", buildinfo.Version)
 		c.Header("X-CPA-COMMIT", buildinfo.Commit)
 		c.Header("X-CPA-BUILD-DATE", buildinfo.BuildDate)
 
-		clientIP := c.ClientIP()
-		localClient := clientIP == "127.0.0.1" || clientIP == "::1"
+		// Not gin's ClientIP: forwarded headers are client-controlled and would let a remote
+		// caller pose as loopback or dodge the guard by rotating addresses. netaccess only
+		// honours them from trusted proxies.
+		clientIP := netaccess.ClientIP(c)
+		localClient := authguard.IsLoopback(clientIP)
 		cfg := h.cfg
 		var (
 			allowRemote bool
@@ -156,45 +124,25 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 		}
 		envSecret := h.envSecret
 
-		fail := func() {}
+		fail := func(string) {}
 		if !localClient {
-			h.attemptsMu.Lock()
-			ai := h.failedAttempts[clientIP]
-			if ai != nil {
-				if !ai.blockedUntil.IsZero() {
-					if time.Now().Before(ai.blockedUntil) {
-						remaining := time.Until(ai.blockedUntil).Round(time.Second)
-						h.attemptsMu.Unlock()
-						c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("IP banned due to too many failed attempts. Try again in %s", remaining)})
-						return
-					}
-					// Ban expired, reset state
-					ai.blockedUntil = time.Time{}
-					ai.count = 0
+			if wait, banned := h.guard.Check(clientIP); wait > 0 {
+				remaining := wait.Round(time.Second)
+				if banned {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("IP banned due to too many failed attempts. Try again in %s", remaining)})
+					return
 				}
+				c.Header("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("too many failed attempts. Try again in %s", remaining)})
+				return
 			}
-			h.attemptsMu.Unlock()
 
 			if !allowRemote {
 				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "remote management disabled"})
 				return
 			}
 
-			fail = func() {
-				h.attemptsMu.Lock()
-				aip := h.failedAttempts[clientIP]
-				if aip == nil {
-					aip = &attemptInfo{}
-					h.failedAttempts[clientIP] = aip
-				}
-				aip.count++
-				aip.lastActivity = time.Now()
-				if aip.count >= maxFailures {
-					aip.blockedUntil = time.Now().Add(banDuration)
-					aip.count = 0
-				}
-				h.attemptsMu.Unlock()
-			}
+			fail = func(reason string) { h.guard.Fail(clientIP, reason) }
 		}
 		if secretHash == "" && envSecret == "" {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "remote management key not set"})
@@ -216,9 +164,7 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 		}
 
 		if provided == "" {
-			if !localClient {
-				fail()
-			}
+			fail("missing management key")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing management key"})
 			return
 		}
@@ -234,32 +180,20 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 
 		if envSecret != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(envSecret)) == 1 {
 			if !localClient {
-				h.attemptsMu.Lock()
-				if ai := h.failedAttempts[clientIP]; ai != nil {
-					ai.count = 0
-					ai.blockedUntil = time.Time{}
-				}
-				h.attemptsMu.Unlock()
+				h.guard.Succeed(clientIP)
 			}
 			c.Next()
 			return
 		}
 
 		if secretHash == "" || bcrypt.CompareHashAndPassword([]byte(secretHash), []byte(provided)) != nil {
-			if !localClient {
-				fail()
-			}
+			fail("invalid management key")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid management key"})
 			return
 		}
 
 		if !localClient {
-			h.attemptsMu.Lock()
-			if ai := h.failedAttempts[clientIP]; ai != nil {
-				ai.count = 0
-				ai.blockedUntil = time.Time{}
-			}
-			h.attemptsMu.Unlock()
+			h.guard.Succeed(clientIP)
 		}
 
 		c.Next()
@@ -270,6 +204,17 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 func (h *Handler) persist(c *gin.Context) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	if next, errMarshal := yaml.Marshal(h.cfg); errMarshal == nil {
+		current, _ := os.ReadFile(h.configFilePath)
+		if fields, errRefs := config.NewSecretReferences(next, current); errRefs == nil && len(fields) > 0 {
+			// Drop the rejected change so it is not written by a later update.
+			if reloaded, errLoad := config.LoadConfig(h.configFilePath); errLoad == nil {
+				*h.cfg = *reloaded
+			}
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden_field", "message": secretReferenceMessage(fields)})
+			return false
+		}
+	}
 	// Preserve comments when writing
 	if err := config.SaveConfigPreserveComments(h.configFilePath, h.cfg); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to save config: %v", err)})
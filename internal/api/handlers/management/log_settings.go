@@ -0,0 +1,57 @@
+package management
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+)
+
+// GetLogSettings returns the configured logging settings and the ones in effect, which differ
+// while a signal forces the log level.
+func (h *Handler) GetLogSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"debug":         h.cfg.Debug,
+		"log-level":     h.cfg.LogLevel,
+		"debug-modules": h.cfg.DebugModules,
+		"log-format":    h.cfg.LogFormat,
+		"effective":     logging.CurrentLogSettings(),
+	})
+}
+
+// PutLogSettings changes the log level, debug modules and log format. The change applies
+// immediately and is persisted. Omitted fields keep their current value.
+func (h *Handler) PutLogSettings(c *gin.Context) {
+	var body struct {
+		LogLevel     *string   `json:"log-level"`
+		DebugModules *[]string `json:"debug-modules"`
+		LogFormat    *string   `json:"log-format"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if body.LogLevel != nil {
+		if _, err := logging.ParseLogLevel(*body.LogLevel); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if body.LogFormat != nil {
+		if _, err := logging.NormalizeLogFormat(*body.LogFormat); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if body.LogLevel != nil {
+		h.cfg.LogLevel = *body.LogLevel
+	}
+	if body.DebugModules != nil {
+		h.cfg.DebugModules = logging.NormalizeDebugModules(*body.DebugModules)
+	}
+	if body.LogFormat != nil {
+		h.cfg.LogFormat = *body.LogFormat
+	}
+	logging.ConfigureLogLevel(h.cfg)
+	h.persist(c)
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 )
 
 const (
@@ -189,7 +190,7 @@ func (h *Handler) GetRequestErrorLogs(c *gin.Context) {
 			continue
 		}
 		name := entry.Name()
-		if !strings.HasPrefix(name, "error-") || !strings.HasSuffix(name, ".log") {
+		if !strings.HasPrefix(name, "error-") || !logging.IsRequestLogFile(name) {
 			continue
 		}
 		info, errInfo := entry.Info()
@@ -257,7 +258,7 @@ func (h *Handler) GetRequestLogByID(c *gin.Context) {
 			continue
 		}
 		name := entry.Name()
-		if strings.HasSuffix(name, suffix) {
+		if strings.HasSuffix(name, suffix) || strings.HasSuffix(name, suffix+".zst") {
 			matchedFile = name
 			break
 		}
@@ -294,7 +295,7 @@ func (h *Handler) GetRequestLogByID(c *gin.Context) {
 		return
 	}
 
-	c.FileAttachment(fullPath, matchedFile)
+	serveRequestLog(c, fullPath, matchedFile)
 }
 
 // DownloadRequestErrorLog downloads a specific error request log file by name.
@@ -319,7 +320,7 @@ func (h *Handler) DownloadRequestErrorLog(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid log file name"})
 		return
 	}
-	if !strings.HasPrefix(name, "error-") || !strings.HasSuffix(name, ".log") {
+	if !strings.HasPrefix(name, "error-") || !logging.IsRequestLogFile(name) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "log file not found"})
 		return
 	}
@@ -350,7 +351,85 @@ func (h *Handler) DownloadRequestErrorLog(c *gin.Context) {
 		return
 	}
 
-	c.FileAttachment(fullPath, name)
+	serveRequestLog(c, fullPath, name)
+}
+
+// serveRequestLog sends a request log as an attachment, decompressing zstd-archived logs.
+func serveRequestLog(c *gin.Context, fullPath, name string) {
+	if !strings.HasSuffix(name, ".zst") {
+		c.FileAttachment(fullPath, name)
+		return
+	}
+	reader, err := logging.OpenRequestLog(fullPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to open log file: %v", err)})
+		return
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	c.DataFromReader(http.StatusOK, -1, "text/plain; charset=utf-8", reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", strings.TrimSuffix(name, ".zst")),
+	})
+}
+
+// SearchRequestLogs queries the index of compressed request logs. Supported query parameters:
+// request-id, key (raw client API key, hashed before matching), key-hash, model, error-class,
+// status, since and until (RFC 3339) and limit (default 100).
+func (h *Handler) SearchRequestLogs(c *gin.Context) {
+	if h == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "handler unavailable"})
+		return
+	}
+	dir := h.logDirectory()
+	if strings.TrimSpace(dir) == "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "log directory not configured"})
+		return
+	}
+
+	filter := logging.RequestLogFilter{
+		RequestID:  strings.TrimSpace(c.Query("request-id")),
+		Key:        strings.TrimSpace(c.Query("key-hash")),
+		Model:      strings.TrimSpace(c.Query("model")),
+		ErrorClass: strings.TrimSpace(c.Query("error-class")),
+		Limit:      100,
+	}
+	if key := strings.TrimSpace(c.Query("key")); key != "" {
+		filter.Key = util.HashAPIKey(key)
+	}
+	if raw := strings.TrimSpace(c.Query("status")); raw != "" {
+		status, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+			return
+		}
+		filter.Status = status
+	}
+	for param, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if raw := strings.TrimSpace(c.Query(param)); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s, expected RFC 3339", param)})
+				return
+			}
+			*target = parsed
+		}
+	}
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := logging.SearchRequestLogIndex(dir, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to search request logs: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "count": len(entries)})
 }
 
 func (h *Handler) logDirectory() string {
@@ -0,0 +1,23 @@
+package management
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/providerstatus"
+)
+
+// GetProviderStatus reports the incidents published by the upstream status feeds, per provider
+// and per feed, for dashboard banners.
+func (h *Handler) GetProviderStatus(c *gin.Context) {
+	enabled := h != nil && h.cfg != nil && h.cfg.ProviderStatus.Enabled
+	feeds := providerstatus.Feeds()
+	if feeds == nil {
+		feeds = []providerstatus.FeedStatus{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":   enabled,
+		"providers": providerstatus.Providers(),
+		"feeds":     feeds,
+	})
+}
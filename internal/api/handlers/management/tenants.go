@@ -0,0 +1,171 @@
+package management
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tenant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+)
+
+// tenantStore returns the active tenant store, answering 400 when tenants are disabled.
+func tenantStore(c *gin.Context) *tenant.Store {
+	store := tenant.Active()
+	if store == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tenants are disabled"})
+	}
+	return store
+}
+
+func writeTenantError(c *gin.Context, err error) {
+	if errors.Is(err, tenant.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// ListTenants returns all tenants with their keys. Key digests are never returned.
+func (h *Handler) ListTenants(c *gin.Context) {
+	store := tenantStore(c)
+	if store == nil {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tenants": store.List()})
+}
+
+// GetTenant returns one tenant and its consumption in the current quota window.
+func (h *Handler) GetTenant(c *gin.Context) {
+	store := tenantStore(c)
+	if store == nil {
+		return
+	}
+	t, ok := store.Get(c.Param("id"))
+	if !ok {
+		writeTenantError(c, tenant.ErrNotFound)
+		return
+	}
+	used, _ := store.Usage(t.ID, time.Now())
+	c.JSON(http.StatusOK, gin.H{"tenant": t, "usage": used})
+}
+
+// CreateTenant adds a tenant from a body with name, labels, allowed-models and quota.
+func (h *Handler) CreateTenant(c *gin.Context) {
+	store := tenantStore(c)
+	if store == nil {
+		return
+	}
+	var spec tenant.Spec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	t, err := store.Create(spec)
+	if err != nil {
+		writeTenantError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, t)
+}
+
+// PatchTenant updates the fields present in the body.
+func (h *Handler) PatchTenant(c *gin.Context) {
+	store := tenantStore(c)
+	if store == nil {
+		return
+	}
+	var spec tenant.Spec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	t, err := store.Update(c.Param("id"), spec)
+	if err != nil {
+		writeTenantError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// DeleteTenant removes a tenant and all of its keys.
+func (h *Handler) DeleteTenant(c *gin.Context) {
+	store := tenantStore(c)
+	if store == nil {
+		return
+	}
+	if err := store.Delete(c.Param("id")); err != nil {
+		writeTenantError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// IssueTenantKey creates an API key for a tenant. The plaintext key is only in this response.
+func (h *Handler) IssueTenantKey(c *gin.Context) {
+	store := tenantStore(c)
+	if store == nil {
+		return
+	}
+	var body struct {
+		Label string `json:"label"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+	}
+	key, secret, err := store.IssueKey(c.Param("id"), body.Label)
+	if err != nil {
+		writeTenantError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"key": key, "api-key": secret})
+}
+
+// RevokeTenantKey revokes a tenant API key. Requests using it are rejected immediately.
+func (h *Handler) RevokeTenantKey(c *gin.Context) {
+	store := tenantStore(c)
+	if store == nil {
+		return
+	}
+	key, err := store.RevokeKey(c.Param("id"), c.Param("keyId"))
+	if err != nil {
+		writeTenantError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+// GetTenantInvoice totals a tenant's usage per model and day between the query parameters from
+// and to (YYYY-MM-DD, inclusive). to defaults to today and from to the first day of to's month.
+func (h *Handler) GetTenantInvoice(c *gin.Context) {
+	store := tenantStore(c)
+	if store == nil {
+		return
+	}
+	id := c.Param("id")
+	if _, ok := store.Get(id); !ok {
+		writeTenantError(c, tenant.ErrNotFound)
+		return
+	}
+	to := c.DefaultQuery("to", time.Now().Format("2006-01-02"))
+	from := c.Query("from")
+	if from == "" {
+		if parsed, err := time.Parse("2006-01-02", to); err == nil {
+			from = parsed.AddDate(0, 0, 1-parsed.Day()).Format("2006-01-02")
+		}
+	}
+	var stats *usage.RequestStatistics
+	if h != nil {
+		stats = h.usageStats
+	}
+	invoice, err := usage.TenantInvoice(usage.Snapshots(), stats, id, from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, invoice)
+}
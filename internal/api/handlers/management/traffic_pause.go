@@ -0,0 +1,108 @@
+package management
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+)
+
+const (
+	defaultDrainTimeout = 60 * time.Second
+	maxDrainTimeout     = 10 * time.Minute
+	drainPollInterval   = 100 * time.Millisecond
+)
+
+// GetTrafficPause returns the current pause state and the number of in-flight requests.
+func (h *Handler) GetTrafficPause(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"traffic-pause": h.cfg.TrafficPause,
+		"in-flight":     handlers.InFlightRequests(),
+		"queued":        handlers.QueuedRequests(),
+	})
+}
+
+// PutTrafficPause updates the pause state. Omitted fields keep their current value.
+func (h *Handler) PutTrafficPause(c *gin.Context) {
+	var body struct {
+		Paused     *bool     `json:"paused"`
+		Providers  *[]string `json:"providers"`
+		RetryAfter *int      `json:"retry-after"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	if body.RetryAfter != nil && *body.RetryAfter < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retry-after must be >= 0"})
+		return
+	}
+	if body.Paused != nil {
+		handlers.SetTrafficPaused(*body.Paused)
+		h.cfg.TrafficPause.Paused = *body.Paused
+	}
+	if body.Providers != nil {
+		providers := make([]string, 0, len(*body.Providers))
+		for _, provider := range *body.Providers {
+			if trimmed := strings.ToLower(strings.TrimSpace(provider)); trimmed != "" {
+				providers = append(providers, trimmed)
+			}
+		}
+		h.cfg.TrafficPause.Providers = providers
+	}
+	if body.RetryAfter != nil {
+		h.cfg.TrafficPause.RetryAfter = *body.RetryAfter
+	}
+	h.persist(c)
+}
+
+// PostTrafficDrain pauses all traffic and waits until in-flight requests complete or the
+// timeout elapses. The pause applies immediately and is then persisted so it survives
+// restarts during maintenance.
+func (h *Handler) PostTrafficDrain(c *gin.Context) {
+	var body struct {
+		TimeoutSeconds int `json:"timeout-seconds"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+	}
+	timeout := defaultDrainTimeout
+	if body.TimeoutSeconds > 0 {
+		timeout = time.Duration(body.TimeoutSeconds) * time.Second
+	}
+	if timeout > maxDrainTimeout {
+		timeout = maxDrainTimeout
+	}
+
+	handlers.SetTrafficPaused(true)
+	h.mu.Lock()
+	h.cfg.TrafficPause.Paused = true
+	errSave := config.SaveConfigPreserveComments(h.configFilePath, h.cfg)
+	h.mu.Unlock()
+	if errSave != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save config: " + errSave.Error()})
+		return
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for handlers.InFlightRequests() > 0 {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-deadline.C:
+			c.JSON(http.StatusOK, gin.H{"status": "timeout", "drained": false, "in-flight": handlers.InFlightRequests()})
+			return
+		case <-ticker.C:
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "drained": true, "in-flight": int64(0)})
+}
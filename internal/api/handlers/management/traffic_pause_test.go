@@ -0,0 +1,70 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+)
+
+func TestTrafficPauseDrainPersistsAcrossRestarts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("port: 8317\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	h := NewHandler(cfg, configPath, nil)
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v0/management/traffic-pause/drain", strings.NewReader(`{"timeout-seconds":1}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	h.PostTrafficDrain(c)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("drain status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Drained bool `json:"drained"`
+	}
+	if err = json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || !resp.Drained {
+		t.Fatalf("expected drained response, got %s", rec.Body.String())
+	}
+
+	reloaded, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("reload config: %v", err)
+	}
+	if !reloaded.TrafficPause.Paused {
+		t.Fatalf("expected paused state to be persisted")
+	}
+	if !handlers.TrafficPaused() {
+		t.Fatalf("expected the drain to pause traffic in memory")
+	}
+
+	rec = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPut, "/v0/management/traffic-pause", strings.NewReader(`{"paused":false}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	h.PutTrafficPause(c)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("resume status = %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if reloaded, err = config.LoadConfig(configPath); err != nil || reloaded.TrafficPause.Paused {
+		t.Fatalf("expected resume to be persisted, err=%v", err)
+	}
+	if handlers.TrafficPaused() {
+		t.Fatalf("expected resume to take effect in memory")
+	}
+}
@@ -2,6 +2,7 @@ package management
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -77,3 +78,90 @@ func (h *Handler) ImportUsageStatistics(c *gin.Context) {
 		"failed_requests": snapshot.FailureCount,
 	})
 }
+
+// ListUsageSnapshots returns the dates that have a persisted daily usage snapshot.
+func (h *Handler) ListUsageSnapshots(c *gin.Context) {
+	store := usage.Snapshots()
+	if store == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false, "dates": []string{}})
+		return
+	}
+	dates, err := store.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "dates": dates})
+}
+
+// CreateUsageSnapshot persists today's usage aggregates immediately.
+func (h *Handler) CreateUsageSnapshot(c *gin.Context) {
+	store := usage.Snapshots()
+	if store == nil || h == nil || h.usageStats == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "usage snapshots are disabled"})
+		return
+	}
+	snapshot := h.usageStats.DailySnapshot(time.Now().Format("2006-01-02"))
+	if err := store.Save(snapshot); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// GetUsageReport returns usage totals per API key, model and provider with cost estimates for
+// the days between the query parameters from and to (YYYY-MM-DD, inclusive). to defaults to today
+// and from to to. format=csv returns CSV instead of JSON.
+func (h *Handler) GetUsageReport(c *gin.Context) {
+	to := c.DefaultQuery("to", time.Now().Format("2006-01-02"))
+	from := c.DefaultQuery("from", to)
+	var stats *usage.RequestStatistics
+	if h != nil {
+		stats = h.usageStats
+	}
+	report, err := usage.BuildReport(usage.Snapshots(), stats, from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	format := usage.ReportFormatJSON
+	if c.Query("format") == usage.ReportFormatCSV {
+		format = usage.ReportFormatCSV
+	}
+	data, contentType, err := report.Encode(format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if format == usage.ReportFormatCSV {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=usage-%s_%s.csv", from, to))
+	}
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// GetUsageDiff compares the usage of two days (query parameters from and to, YYYY-MM-DD) and
+// reports per key, model and provider deltas. to defaults to today.
+func (h *Handler) GetUsageDiff(c *gin.Context) {
+	from := c.Query("from")
+	to := c.DefaultQuery("to", time.Now().Format("2006-01-02"))
+	if from == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from is required (YYYY-MM-DD)"})
+		return
+	}
+	var stats *usage.RequestStatistics
+	if h != nil {
+		stats = h.usageStats
+	}
+	store := usage.Snapshots()
+	fromSnapshot, err := usage.SnapshotForDate(store, stats, from)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	toSnapshot, err := usage.SnapshotForDate(store, stats, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, usage.DiffSnapshots(fromSnapshot, toSnapshot))
+}
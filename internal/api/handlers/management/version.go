@@ -0,0 +1,81 @@
+package management
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/selfupdate"
+)
+
+// versionReleaseURL overrides the release endpoint queried by the version endpoints; empty uses the default.
+var versionReleaseURL string
+
+// latestReleaseTTL bounds how long release metadata is reused. GitHub rate-limits
+// unauthenticated API calls to 60 per hour, and management panels poll these endpoints.
+const latestReleaseTTL = 10 * time.Minute
+
+// latestReleaseCache holds the most recent release lookup shared by GetVersion and GetLatestVersion.
+var latestReleaseCache struct {
+	sync.Mutex
+	releaseURL string
+	release    *selfupdate.Release
+	fetchedAt  time.Time
+}
+
+// latestRelease returns the latest release, reusing a lookup younger than latestReleaseTTL.
+func (h *Handler) latestRelease(ctx context.Context) (*selfupdate.Release, error) {
+	latestReleaseCache.Lock()
+	defer latestReleaseCache.Unlock()
+	if latestReleaseCache.release != nil && latestReleaseCache.releaseURL == versionReleaseURL && time.Since(latestReleaseCache.fetchedAt) < latestReleaseTTL {
+		return latestReleaseCache.release, nil
+	}
+	proxyURL := ""
+	if h != nil && h.cfg != nil {
+		proxyURL = strings.TrimSpace(h.cfg.ProxyURL)
+	}
+	release, err := selfupdate.FetchLatest(ctx, selfupdate.NewHTTPClient(proxyURL, 10*time.Second), versionReleaseURL)
+	if err != nil {
+		return nil, err
+	}
+	latestReleaseCache.releaseURL = versionReleaseURL
+	latestReleaseCache.release = release
+	latestReleaseCache.fetchedAt = time.Now()
+	return release, nil
+}
+
+// GetLatestVersion returns the latest release version without downloading assets.
+func (h *Handler) GetLatestVersion(c *gin.Context) {
+	release, err := h.latestRelease(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "request_failed", "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"latest-version": release.Version()})
+}
+
+// GetVersion reports the running build alongside the latest published release and its changelog.
+func (h *Handler) GetVersion(c *gin.Context) {
+	current := gin.H{
+		"version":    buildinfo.Version,
+		"commit":     buildinfo.Commit,
+		"build-date": buildinfo.BuildDate,
+	}
+	release, err := h.latestRelease(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"current": current, "error": "request_failed", "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"current":          current,
+		"latest-version":   release.Version(),
+		"update-available": selfupdate.IsNewer(buildinfo.Version, release.Version()),
+		"changelog":        release.Body,
+		"release-url":      release.HTMLURL,
+		"published-at":     release.PublishedAt,
+	})
+}
@@ -0,0 +1,50 @@
+package management
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestVersionEndpointsShareCachedRelease(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte(`{"tag_name":"v9.9.9","body":"notes","html_url":"https://example.com/r"}`))
+	}))
+	defer server.Close()
+
+	previousURL := versionReleaseURL
+	versionReleaseURL = server.URL
+	t.Cleanup(func() {
+		versionReleaseURL = previousURL
+		latestReleaseCache.Lock()
+		latestReleaseCache.release = nil
+		latestReleaseCache.fetchedAt = time.Time{}
+		latestReleaseCache.Unlock()
+	})
+
+	h := NewHandler(&config.Config{}, "", nil)
+	for _, handle := range []gin.HandlerFunc{h.GetLatestVersion, h.GetVersion, h.GetLatestVersion} {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		handle(c)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body=%s", rec.Code, rec.Body.String())
+		}
+		if got := gjson.Get(rec.Body.String(), "latest-version").String(); got != "v9.9.9" {
+			t.Fatalf("latest-version = %q", got)
+		}
+	}
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("expected one upstream lookup, got %d", got)
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/quotawindow"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+const (
+	healthStatusOK          = "ok"
+	healthStatusDegraded    = "degraded"
+	healthStatusUnavailable = "unavailable"
+)
+
+// providerHealth summarises the accounts registered for a single provider.
+type providerHealth struct {
+	Status      string `json:"status"`
+	Total       int    `json:"total"`
+	Available   int    `json:"available"`
+	CoolingDown int    `json:"cooling_down"`
+	Disabled    int    `json:"disabled"`
+}
+
+// accountHealth reports the runtime state of a single auth account.
+type accountHealth struct {
+	ID             string          `json:"id"`
+	Provider       string          `json:"provider"`
+	Label          string          `json:"label,omitempty"`
+	Status         coreauth.Status `json:"status"`
+	StatusMessage  string          `json:"status_message,omitempty"`
+	Available      bool            `json:"available"`
+	Disabled       bool            `json:"disabled"`
+	CooldownUntil  *time.Time      `json:"cooldown_until,omitempty"`
+	QuotaExceeded  bool            `json:"quota_exceeded"`
+	TokenExpiresAt *time.Time      `json:"token_expires_at,omitempty"`
+	TokenExpired   bool            `json:"token_expired"`
+	LastError      *coreauth.Error `json:"last_error,omitempty"`
+	// QuotaWindows lists the subscription usage windows last reported by the upstream.
+	QuotaWindows []quotaWindowHealth `json:"quota_windows,omitempty"`
+}
+
+// quotaWindowHealth is a tracked usage window with its estimated remaining share.
+type quotaWindowHealth struct {
+	quotawindow.Window
+	Remaining float64 `json:"remaining"`
+}
+
+// healthReport is the aggregated view rendered by the health endpoints.
+type healthReport struct {
+	Status    string                    `json:"status"`
+	Time      time.Time                 `json:"time"`
+	Providers map[string]providerHealth `json:"providers"`
+	Accounts  []accountHealth           `json:"accounts,omitempty"`
+}
+
+// handleHealth serves GET /health for load balancers and readiness probes.
+// It responds 200 while at least one account can serve requests and 503 otherwise.
+func (s *Server) handleHealth(c *gin.Context) {
+	report := s.buildHealthReport(time.Now())
+	c.JSON(healthHTTPStatus(report.Status), gin.H{"status": report.Status})
+}
+
+// handleHealthDetailed serves GET /health/detailed with per-provider state to API clients.
+// Account identities, labels and upstream errors are omitted; they are only available to
+// the management API.
+func (s *Server) handleHealthDetailed(c *gin.Context) {
+	report := s.buildHealthReport(time.Now())
+	report.Accounts = nil
+	c.JSON(healthHTTPStatus(report.Status), report)
+}
+
+// handleManagementHealth serves GET /v0/management/health with per-provider and per-account state.
+func (s *Server) handleManagementHealth(c *gin.Context) {
+	report := s.buildHealthReport(time.Now())
+	c.JSON(healthHTTPStatus(report.Status), report)
+}
+
+func healthHTTPStatus(status string) int {
+	if status == healthStatusUnavailable {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+// buildHealthReport derives provider and account health from the auth manager state.
+// Reachability is inferred from account availability; no upstream calls are made.
+func (s *Server) buildHealthReport(now time.Time) healthReport {
+	report := healthReport{
+		Status:    healthStatusUnavailable,
+		Time:      now.UTC(),
+		Providers: make(map[string]providerHealth),
+	}
+	if s == nil || s.handlers == nil || s.handlers.AuthManager == nil {
+		return report
+	}
+
+	for _, auth := range s.handlers.AuthManager.List() {
+		if auth == nil {
+			continue
+		}
+		account := accountHealthFor(auth, now)
+		report.Accounts = append(report.Accounts, account)
+
+		provider := report.Providers[auth.Provider]
+		provider.Total++
+		switch {
+		case account.Disabled:
+			provider.Disabled++
+		case account.Available:
+			provider.Available++
+		case account.CooldownUntil != nil:
+			provider.CoolingDown++
+		}
+		report.Providers[auth.Provider] = provider
+	}
+	sort.Slice(report.Accounts, func(i, j int) bool {
+		if report.Accounts[i].Provider != report.Accounts[j].Provider {
+			return report.Accounts[i].Provider < report.Accounts[j].Provider
+		}
+		return report.Accounts[i].ID < report.Accounts[j].ID
+	})
+
+	healthyProviders := 0
+	for name, provider := range report.Providers {
+		provider.Status = healthStatusUnavailable
+		if provider.Available > 0 {
+			provider.Status = healthStatusOK
+			healthyProviders++
+		}
+		report.Providers[name] = provider
+	}
+	switch {
+	case healthyProviders == 0:
+		report.Status = healthStatusUnavailable
+	case healthyProviders < len(report.Providers):
+		report.Status = healthStatusDegraded
+	default:
+		report.Status = healthStatusOK
+	}
+	return report
+}
+
+func accountHealthFor(auth *coreauth.Auth, now time.Time) accountHealth {
+	account := accountHealth{
+		ID:            auth.ID,
+		Provider:      auth.Provider,
+		Label:         auth.Label,
+		Status:        auth.Status,
+		StatusMessage: auth.StatusMessage,
+		Disabled:      auth.Disabled || auth.Status == coreauth.StatusDisabled,
+		QuotaExceeded: auth.Quota.Exceeded,
+		LastError:     auth.LastError,
+	}
+	if auth.Unavailable && auth.NextRetryAfter.After(now) {
+		until := auth.NextRetryAfter
+		if auth.Quota.NextRecoverAt.After(until) {
+			until = auth.Quota.NextRecoverAt
+		}
+		account.CooldownUntil = &until
+	}
+	if expiresAt, ok := auth.ExpirationTime(); ok {
+		account.TokenExpiresAt = &expiresAt
+		account.TokenExpired = !expiresAt.After(now)
+	}
+	for _, window := range quotawindow.Get(auth.ID, now) {
+		account.QuotaWindows = append(account.QuotaWindows, quotaWindowHealth{Window: window, Remaining: window.Remaining(now)})
+	}
+	account.Available = !account.Disabled && account.CooldownUntil == nil && auth.Status != coreauth.StatusError
+	return account
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	server := newTestServer(t)
+
+	rr := httptest.NewRecorder()
+	server.engine.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("/health without accounts: got %d want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	now := time.Now()
+	auths := []*auth.Auth{
+		{ID: "claude-ok", Provider: "claude", Status: auth.StatusActive, Metadata: map[string]any{"expired": now.Add(time.Hour).Format(time.RFC3339)}},
+		{ID: "gemini-cooling", Provider: "gemini", Status: auth.StatusError, Unavailable: true, NextRetryAfter: now.Add(time.Minute), Quota: auth.QuotaState{Exceeded: true}, LastError: &auth.Error{Message: "quota", HTTPStatus: http.StatusTooManyRequests}},
+	}
+	for _, a := range auths {
+		if _, err := server.handlers.AuthManager.Register(context.Background(), a); err != nil {
+			t.Fatalf("register %s: %v", a.ID, err)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	server.engine.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("/health with a healthy account: got %d want %d", rr.Code, http.StatusOK)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/health/detailed", nil)
+	req.Header.Set("Authorization", "Bearer test-key")
+	rr = httptest.NewRecorder()
+	server.engine.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("/health/detailed: got %d want %d; body=%s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var report healthReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if report.Status != healthStatusDegraded {
+		t.Fatalf("status = %q, want %q", report.Status, healthStatusDegraded)
+	}
+	if p := report.Providers["claude"]; p.Status != healthStatusOK || p.Available != 1 {
+		t.Fatalf("claude provider = %+v", p)
+	}
+	if p := report.Providers["gemini"]; p.Status != healthStatusUnavailable || p.CoolingDown != 1 {
+		t.Fatalf("gemini provider = %+v", p)
+	}
+	if len(report.Accounts) != 0 {
+		t.Fatalf("/health/detailed must not expose accounts to API clients, got %d", len(report.Accounts))
+	}
+
+	rr = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rr)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v0/management/health", nil)
+	server.handleManagementHealth(c)
+	report = healthReport{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode management report: %v", err)
+	}
+	if len(report.Accounts) != 2 {
+		t.Fatalf("accounts = %d, want 2", len(report.Accounts))
+	}
+	claude, gemini := report.Accounts[0], report.Accounts[1]
+	if claude.TokenExpiresAt == nil || claude.TokenExpired {
+		t.Fatalf("claude account expiry = %+v", claude)
+	}
+	if gemini.CooldownUntil == nil || !gemini.QuotaExceeded || gemini.LastError == nil {
+		t.Fatalf("gemini account = %+v", gemini)
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/netaccess"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// Inbound API surfaces that api-key-protocols can grant.
+const (
+	protocolOpenAI          = "openai"
+	protocolOpenAIResponses = "openai-responses"
+	protocolClaude          = "claude"
+	protocolGemini          = "gemini"
+	protocolRealtime        = "realtime"
+	protocolBatch           = "batch"
+	protocolAmp             = "amp"
+	protocolWebsocket       = "websocket"
+	protocolHealth          = "health"
+
+	// protocolModels is the shared /v1/models listing, granted with any protocol served by it.
+	protocolModels = "models"
+)
+
+// protocolAccess maps restricted client API keys to the surfaces they may call.
+var protocolAccess atomic.Pointer[map[string]map[string]struct{}]
+
+// configureProtocolAccess applies the api-key-protocols allowlists.
+func configureProtocolAccess(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+	access := make(map[string]map[string]struct{}, len(cfg.APIKeyProtocols))
+	for _, entry := range cfg.APIKeyProtocols {
+		key := strings.TrimSpace(entry.APIKey)
+		if key == "" {
+			continue
+		}
+		allowed := access[key]
+		if allowed == nil {
+			allowed = make(map[string]struct{}, len(entry.Protocols))
+			access[key] = allowed
+		}
+		for _, protocol := range entry.Protocols {
+			protocol = strings.ToLower(strings.TrimSpace(protocol))
+			if protocol == "" {
+				continue
+			}
+			allowed[protocol] = struct{}{}
+			switch protocol {
+			case protocolOpenAI, protocolOpenAIResponses, protocolClaude:
+				allowed[protocolModels] = struct{}{}
+			}
+		}
+	}
+	protocolAccess.Store(&access)
+}
+
+// protocolAllowed reports whether apiKey may call the surface serving path.
+func protocolAllowed(apiKey, path string) (string, bool) {
+	access := protocolAccess.Load()
+	if access == nil {
+		return "", true
+	}
+	allowed, restricted := (*access)[strings.TrimSpace(apiKey)]
+	if !restricted {
+		return "", true
+	}
+	protocol := inboundProtocol(path)
+	_, ok := allowed[protocol]
+	return protocol, ok && protocol != ""
+}
+
+// inboundProtocol classifies a request path into the API surface that serves it. Unknown paths
+// return "" and are refused for restricted keys.
+func inboundProtocol(path string) string {
+	if rest, ok := strings.CutPrefix(path, "/api/provider/"); ok {
+		// Amp provider aliases serve the regular handlers under /api/provider/{provider}.
+		_, rest, _ = strings.Cut(rest, "/")
+		rest = "/" + rest
+		switch {
+		case strings.HasPrefix(rest, "/v1beta1/"):
+			return protocolAmp
+		case !strings.HasPrefix(rest, "/v1/") && !strings.HasPrefix(rest, "/v1beta/"):
+			rest = "/v1" + rest
+		}
+		path = rest
+	}
+	switch {
+	case path == "/v1/models":
+		return protocolModels
+	case hasPathPrefix(path, "/v1/chat/completions"), hasPathPrefix(path, "/v1/completions"), hasPathPrefix(path, "/v1/conversations/compress"):
+		return protocolOpenAI
+	case hasPathPrefix(path, "/v1/responses"):
+		return protocolOpenAIResponses
+	case hasPathPrefix(path, "/v1/messages"):
+		return protocolClaude
+	case hasPathPrefix(path, "/v1/realtime"):
+		return protocolRealtime
+	case hasPathPrefix(path, "/v1/files"), hasPathPrefix(path, "/v1/batches"):
+		return protocolBatch
+	case hasPathPrefix(path, "/v1beta"):
+		return protocolGemini
+	case hasPathPrefix(path, "/api"), hasPathPrefix(path, "/auth"):
+		return protocolAmp
+	case hasPathPrefix(path, "/health"):
+		return protocolHealth
+	case hasPathPrefix(path, "/v1/ws"):
+		return protocolWebsocket
+	}
+	return ""
+}
+
+// hasPathPrefix matches prefix as whole path segments.
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// enforceProtocolAccess aborts requests from keys restricted away from the requested surface and
+// records the attempt in the audit log.
+func enforceProtocolAccess(c *gin.Context, apiKey string) bool {
+	protocol, ok := protocolAllowed(apiKey, c.Request.URL.Path)
+	if ok {
+		return true
+	}
+	if protocol == "" {
+		protocol = "unknown"
+	}
+	log.WithFields(log.Fields{
+		"audit":     "protocol-access-denied",
+		"api_key":   util.HashAPIKey(apiKey),
+		"protocol":  protocol,
+		"method":    c.Request.Method,
+		"path":      c.Request.URL.Path,
+		"client_ip": netaccess.ClientIP(c),
+	}).Warn("API key is not allowed to use this API surface")
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key is not allowed to use the " + protocol + " API"})
+	return false
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	configaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/config_access"
+	proxyconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestInboundProtocol(t *testing.T) {
+	cases := map[string]string{
+		"/v1/chat/completions":       protocolOpenAI,
+		"/v1/completions":            protocolOpenAI,
+		"/v1/models":                 protocolModels,
+		"/v1/responses/compact":      protocolOpenAIResponses,
+		"/v1/messages/count_tokens":  protocolClaude,
+		"/v1/realtime":               protocolRealtime,
+		"/v1/batches/batch_1/cancel": protocolBatch,
+		"/v1/files":                  protocolBatch,
+		"/v1beta/models/gemini-2.5-pro:generateContent": protocolGemini,
+		"/api/provider/openai/chat/completions":         protocolOpenAI,
+		"/v1/conversations/compress":                    protocolOpenAI,
+		"/api/provider/anthropic/v1/messages":           protocolClaude,
+		"/api/provider/google/v1beta/models":            protocolGemini,
+		"/api/provider/google/v1beta1/publishers/x":     protocolAmp,
+		"/api/threads/abc":                              protocolAmp,
+		"/health/detailed":                              protocolHealth,
+		"/v1/ws":                                        protocolWebsocket,
+		"/v1/messagesx":                                 "",
+	}
+	for path, want := range cases {
+		if got := inboundProtocol(path); got != want {
+			t.Errorf("inboundProtocol(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestAuthMiddlewareEnforcesProtocolAccess(t *testing.T) {
+	configaccess.Register()
+	server := newTestServer(t)
+	configureProtocolAccess(&proxyconfig.Config{SDKConfig: sdkconfig.SDKConfig{
+		APIKeyProtocols: []sdkconfig.APIKeyProtocolAccess{{APIKey: "test-key", Protocols: []string{"OpenAI"}}},
+	}})
+	t.Cleanup(func() { configureProtocolAccess(&proxyconfig.Config{}) })
+
+	cases := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/v1/models", http.StatusOK},
+		{http.MethodPost, "/v1/messages", http.StatusForbidden},
+		{http.MethodGet, "/v1beta/models", http.StatusForbidden},
+		{http.MethodGet, "/api/provider/google/v1beta/models", http.StatusForbidden},
+		{http.MethodGet, "/api/provider/openai/v1/models", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer test-key")
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Errorf("%s %s = %d, want %d; body=%s", tc.method, tc.path, rr.Code, tc.want, rr.Body.String())
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -24,10 +25,21 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/middleware"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules"
 	ampmodule "github.com/router-for-me/CLIProxyAPI/v6/internal/api/modules/amp"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kiro"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/authguard"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/batch"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/clientcert"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/grpcgateway"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/netaccess"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/providerstatus"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/telemetry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tenant"
+	geminicommon "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/common"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -59,10 +71,13 @@ type ServerOption func(*serverOptionConfig)
 
 func defaultRequestLoggerFactory(cfg *config.Config, configPath string) logging.RequestLogger {
 	configDir := filepath.Dir(configPath)
+	logsDir := "logs"
 	if base := util.WritablePath(); base != "" {
-		return logging.NewFileRequestLogger(cfg.RequestLog, filepath.Join(base, "logs"), configDir, cfg.ErrorLogsMaxFiles)
+		logsDir = filepath.Join(base, "logs")
 	}
-	return logging.NewFileRequestLogger(cfg.RequestLog, "logs", configDir, cfg.ErrorLogsMaxFiles)
+	requestLogger := logging.NewFileRequestLogger(cfg.RequestLog, logsDir, configDir, cfg.ErrorLogsMaxFiles)
+	requestLogger.SetCompression(cfg.RequestLogCompression)
+	return requestLogger
 }
 
 // WithMiddleware appends additional Gin middleware during server construction.
@@ -153,6 +168,15 @@ type Server struct {
 	// management handler
 	mgmt *managementHandlers.Handler
 
+	// batches serves the OpenAI Batch API and runs queued batches.
+	batches *batch.Manager
+
+	// grpc answers gRPC calls on the API port and passes other requests to the engine.
+	grpc *grpcgateway.Gateway
+
+	// acmeServer answers ACME HTTP-01 challenges when automatic certificates are enabled.
+	acmeServer *http.Server
+
 	// ampModule is the Amp routing module for model mapping hot-reload
 	ampModule *ampmodule.AmpModule
 
@@ -204,6 +228,8 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	// Add middleware
 	engine.Use(logging.GinLogrusLogger())
 	engine.Use(logging.GinLogrusRecovery())
+	engine.Use(netaccess.Middleware())
+	engine.Use(bodyLimitMiddleware())
 	for _, mw := range optionState.extraMiddleware {
 		engine.Use(mw)
 	}
@@ -247,10 +273,18 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 		envManagementSecret: envManagementSecret,
 		wsRoutes:            make(map[string]struct{}),
 	}
+	s.batches = batch.NewManager(engine)
+	s.grpc = grpcgateway.New(engine)
+	s.grpc.Configure(cfg)
 	s.wsAuthEnabled.Store(cfg.WebsocketAuth)
 	// Save initial YAML snapshot
 	s.oldConfigYaml, _ = yaml.Marshal(cfg)
 	s.applyAccessConfig(nil, cfg)
+	configureInboundAuthGuard(cfg)
+	configureProtocolAccess(cfg)
+	configureBodyLimits(cfg)
+	clientcert.Configure(cfg)
+	netaccess.Configure(cfg)
 	if authManager != nil {
 		authManager.SetRetryConfig(cfg.RequestRetry, time.Duration(cfg.MaxRetryInterval)*time.Second)
 	}
@@ -267,6 +301,8 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 
 	// Setup routes
 	s.setupRoutes()
+	// Batches may resume right away, so they are configured once their routes exist.
+	s.batches.Configure(cfg)
 
 	// Register Amp module using V2 interface with Context
 	s.ampModule = ampmodule.NewLegacy(accessManager, AuthMiddleware(accessManager))
@@ -304,7 +340,14 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	// Create HTTP server
 	s.server = &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Handler: engine,
+		Handler: s.grpc,
+	}
+	if cfg.GRPC.Enabled && !cfg.TLS.Enable {
+		// gRPC clients speak HTTP/2, which plaintext listeners only offer as h2c.
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetUnencryptedHTTP2(true)
+		s.server.Protocols = protocols
 	}
 
 	return s
@@ -319,6 +362,7 @@ func (s *Server) setupRoutes() {
 	geminiCLIHandlers := gemini.NewGeminiCLIAPIHandler(s.handlers)
 	claudeCodeHandlers := claude.NewClaudeCodeAPIHandler(s.handlers)
 	openaiResponsesHandlers := openai.NewOpenAIResponsesAPIHandler(s.handlers)
+	openaiRealtimeHandlers := openai.NewOpenAIRealtimeAPIHandler(s.handlers)
 
 	// OpenAI compatible API routes
 	v1 := s.engine.Group("/v1")
@@ -326,11 +370,24 @@ func (s *Server) setupRoutes() {
 	{
 		v1.GET("/models", s.unifiedModelsHandler(openaiHandlers, claudeCodeHandlers))
 		v1.POST("/chat/completions", openaiHandlers.ChatCompletions)
+		v1.POST("/chat/completions/input_tokens", openaiHandlers.ChatCompletionsInputTokens)
 		v1.POST("/completions", openaiHandlers.Completions)
 		v1.POST("/messages", claudeCodeHandlers.ClaudeMessages)
 		v1.POST("/messages/count_tokens", claudeCodeHandlers.ClaudeCountTokens)
 		v1.POST("/responses", openaiResponsesHandlers.Responses)
 		v1.POST("/responses/compact", openaiResponsesHandlers.Compact)
+		v1.POST("/responses/input_tokens", openaiResponsesHandlers.ResponsesInputTokens)
+		v1.POST("/conversations/compress", openaiHandlers.CompressConversation)
+		v1.GET("/realtime", openaiRealtimeHandlers.Realtime)
+		v1.POST("/files", s.batches.UploadFile)
+		v1.GET("/files", s.batches.ListFiles)
+		v1.GET("/files/:id", s.batches.GetFile)
+		v1.GET("/files/:id/content", s.batches.GetFileContent)
+		v1.DELETE("/files/:id", s.batches.DeleteFile)
+		v1.POST("/batches", s.batches.CreateBatch)
+		v1.GET("/batches", s.batches.ListBatches)
+		v1.GET("/batches/:id", s.batches.GetBatch)
+		v1.POST("/batches/:id/cancel", s.batches.CancelBatch)
 	}
 
 	// Gemini compatible API routes
@@ -354,6 +411,11 @@ func (s *Server) setupRoutes() {
 		})
 	})
 
+	// Health endpoints for load balancers and readiness probes.
+	// The detailed view exposes account state and therefore requires a client API key.
+	s.engine.GET("/health", s.handleHealth)
+	s.engine.GET("/health/detailed", AuthMiddleware(s.accessManager), s.handleHealthDetailed)
+
 	// Event logging endpoint - handles Claude Code telemetry requests
 	// Returns 200 OK to prevent 404 errors in logs
 	s.engine.POST("/api/event_logging/batch", func(c *gin.Context) {
@@ -504,14 +566,43 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/usage", s.mgmt.GetUsageStatistics)
 		mgmt.GET("/usage/export", s.mgmt.ExportUsageStatistics)
 		mgmt.POST("/usage/import", s.mgmt.ImportUsageStatistics)
+		mgmt.GET("/usage/snapshots", s.mgmt.ListUsageSnapshots)
+		mgmt.POST("/usage/snapshots", s.mgmt.CreateUsageSnapshot)
+		mgmt.GET("/usage/diff", s.mgmt.GetUsageDiff)
+		mgmt.GET("/usage/report", s.mgmt.GetUsageReport)
 		mgmt.GET("/config", s.mgmt.GetConfig)
 		mgmt.GET("/config.yaml", s.mgmt.GetConfigYAML)
 		mgmt.PUT("/config.yaml", s.mgmt.PutConfigYAML)
 		mgmt.GET("/latest-version", s.mgmt.GetLatestVersion)
+		mgmt.GET("/version", s.mgmt.GetVersion)
+		mgmt.GET("/provider-status", s.mgmt.GetProviderStatus)
+		mgmt.GET("/health", s.handleManagementHealth)
+
+		mgmt.GET("/audit", s.mgmt.GetAuditLog)
+
+		mgmt.GET("/tenants", s.mgmt.ListTenants)
+		mgmt.POST("/tenants", s.mgmt.CreateTenant)
+		mgmt.GET("/tenants/:id", s.mgmt.GetTenant)
+		mgmt.PATCH("/tenants/:id", s.mgmt.PatchTenant)
+		mgmt.DELETE("/tenants/:id", s.mgmt.DeleteTenant)
+		mgmt.POST("/tenants/:id/keys", s.mgmt.IssueTenantKey)
+		mgmt.DELETE("/tenants/:id/keys/:keyId", s.mgmt.RevokeTenantKey)
+		mgmt.GET("/tenants/:id/invoice", s.mgmt.GetTenantInvoice)
+
+		mgmt.GET("/canary-routing", s.mgmt.GetCanaryRouting)
+		mgmt.PUT("/canary-routing", s.mgmt.PutCanaryRouting)
+		mgmt.DELETE("/canary-routing/stats", s.mgmt.DeleteCanaryStats)
 
 		mgmt.GET("/debug", s.mgmt.GetDebug)
 		mgmt.PUT("/debug", s.mgmt.PutDebug)
 		mgmt.PATCH("/debug", s.mgmt.PutDebug)
+		mgmt.GET("/logging", s.mgmt.GetLogSettings)
+		mgmt.PUT("/logging", s.mgmt.PutLogSettings)
+		mgmt.PATCH("/logging", s.mgmt.PutLogSettings)
+		mgmt.GET("/debug/pprof/*profile", s.mgmt.GetPprof)
+		mgmt.POST("/debug/pprof/*profile", s.mgmt.GetPprof)
+		mgmt.GET("/debug/vars", s.mgmt.GetExpvar)
+		mgmt.GET("/debug/runtime", s.mgmt.GetRuntimeDiagnostics)
 
 		mgmt.GET("/logging-to-file", s.mgmt.GetLoggingToFile)
 		mgmt.PUT("/logging-to-file", s.mgmt.PutLoggingToFile)
@@ -528,6 +619,9 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/usage-statistics-enabled", s.mgmt.GetUsageStatisticsEnabled)
 		mgmt.PUT("/usage-statistics-enabled", s.mgmt.PutUsageStatisticsEnabled)
 		mgmt.PATCH("/usage-statistics-enabled", s.mgmt.PutUsageStatisticsEnabled)
+		mgmt.GET("/usage-attribution", s.mgmt.GetUsageAttribution)
+		mgmt.PUT("/usage-attribution", s.mgmt.PutUsageAttribution)
+		mgmt.PATCH("/usage-attribution", s.mgmt.PutUsageAttribution)
 
 		mgmt.GET("/proxy-url", s.mgmt.GetProxyURL)
 		mgmt.PUT("/proxy-url", s.mgmt.PutProxyURL)
@@ -559,6 +653,7 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/request-error-logs", s.mgmt.GetRequestErrorLogs)
 		mgmt.GET("/request-error-logs/:name", s.mgmt.DownloadRequestErrorLog)
 		mgmt.GET("/request-log-by-id/:id", s.mgmt.GetRequestLogByID)
+		mgmt.GET("/request-logs", s.mgmt.SearchRequestLogs)
 		mgmt.GET("/request-log", s.mgmt.GetRequestLog)
 		mgmt.PUT("/request-log", s.mgmt.PutRequestLog)
 		mgmt.PATCH("/request-log", s.mgmt.PutRequestLog)
@@ -597,6 +692,11 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.PUT("/max-retry-interval", s.mgmt.PutMaxRetryInterval)
 		mgmt.PATCH("/max-retry-interval", s.mgmt.PutMaxRetryInterval)
 
+		mgmt.GET("/traffic-pause", s.mgmt.GetTrafficPause)
+		mgmt.PUT("/traffic-pause", s.mgmt.PutTrafficPause)
+		mgmt.PATCH("/traffic-pause", s.mgmt.PutTrafficPause)
+		mgmt.POST("/traffic-pause/drain", s.mgmt.PostTrafficDrain)
+
 		mgmt.GET("/force-model-prefix", s.mgmt.GetForceModelPrefix)
 		mgmt.PUT("/force-model-prefix", s.mgmt.PutForceModelPrefix)
 		mgmt.PATCH("/force-model-prefix", s.mgmt.PutForceModelPrefix)
@@ -630,6 +730,11 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.PATCH("/oauth-excluded-models", s.mgmt.PatchOAuthExcludedModels)
 		mgmt.DELETE("/oauth-excluded-models", s.mgmt.DeleteOAuthExcludedModels)
 
+		mgmt.GET("/model-capabilities", s.mgmt.GetModelCapabilities)
+		mgmt.PUT("/model-capabilities", s.mgmt.PutModelCapabilities)
+		mgmt.PATCH("/model-capabilities", s.mgmt.PatchModelCapabilities)
+		mgmt.DELETE("/model-capabilities", s.mgmt.DeleteModelCapabilities)
+
 		mgmt.GET("/oauth-model-alias", s.mgmt.GetOAuthModelAlias)
 		mgmt.PUT("/oauth-model-alias", s.mgmt.PutOAuthModelAlias)
 		mgmt.PATCH("/oauth-model-alias", s.mgmt.PatchOAuthModelAlias)
@@ -642,6 +747,8 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.POST("/auth-files", s.mgmt.UploadAuthFile)
 		mgmt.DELETE("/auth-files", s.mgmt.DeleteAuthFile)
 		mgmt.PATCH("/auth-files/status", s.mgmt.PatchAuthFileStatus)
+		mgmt.PATCH("/auth-files/labels", s.mgmt.PatchAuthFileLabels)
+		mgmt.POST("/auth-files/refresh", s.mgmt.RefreshAuthFile)
 		mgmt.POST("/vertex/import", s.mgmt.ImportVertexCredential)
 
 		mgmt.GET("/anthropic-auth-url", s.mgmt.RequestAnthropicToken)
@@ -806,9 +913,22 @@ func (s *Server) Start() error {
 	if useTLS {
 		cert := strings.TrimSpace(s.cfg.TLS.Cert)
 		key := strings.TrimSpace(s.cfg.TLS.Key)
-		if cert == "" || key == "" {
+		useACME := len(acmeDomains(s.cfg)) > 0
+		if !useACME && (cert == "" || key == "") {
 			return fmt.Errorf("failed to start HTTPS server: tls.cert or tls.key is empty")
 		}
+		tlsCfg := clientcert.TLSConfig(s.cfg.TLS.ClientAuth)
+		if useACME {
+			var errACME error
+			if tlsCfg, errACME = s.acmeTLSConfig(tlsCfg); errACME != nil {
+				return fmt.Errorf("failed to start HTTPS server: %v", errACME)
+			}
+			// Certificates come from the ACME manager instead of files.
+			cert, key = "", ""
+		}
+		if tlsCfg != nil {
+			s.server.TLSConfig = tlsCfg
+		}
 		log.Debugf("Starting API server on %s with TLS", s.server.Addr)
 		if errServeTLS := s.server.ListenAndServeTLS(cert, key); errServeTLS != nil && !errors.Is(errServeTLS, http.ErrServerClosed) {
 			return fmt.Errorf("failed to start HTTPS server: %v", errServeTLS)
@@ -842,6 +962,12 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
+	if s.acmeServer != nil {
+		if err := s.acmeServer.Shutdown(ctx); err != nil {
+			log.Warnf("failed to shutdown ACME challenge listener: %v", err)
+		}
+	}
+
 	// Shutdown the HTTP server.
 	if err := s.server.Shutdown(ctx); err != nil {
 		return fmt.Errorf("failed to shutdown HTTP server: %v", err)
@@ -906,7 +1032,7 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		}
 	}
 
-	if oldCfg == nil || oldCfg.LoggingToFile != cfg.LoggingToFile || oldCfg.LogsMaxTotalSizeMB != cfg.LogsMaxTotalSizeMB {
+	if oldCfg == nil || oldCfg.LoggingToFile != cfg.LoggingToFile || oldCfg.LogsMaxTotalSizeMB != cfg.LogsMaxTotalSizeMB || oldCfg.LogRotation != cfg.LogRotation {
 		if err := logging.ConfigureLogOutput(cfg); err != nil {
 			log.Errorf("failed to reconfigure log output: %v", err)
 		}
@@ -916,6 +1042,75 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		usage.SetStatisticsEnabled(cfg.UsageStatisticsEnabled)
 	}
 
+	if oldCfg == nil || oldCfg.UsageAttribution != cfg.UsageAttribution || !reflect.DeepEqual(oldCfg.APIKeys, cfg.APIKeys) || !reflect.DeepEqual(oldCfg.Access, cfg.Access) {
+		usage.ConfigureAttribution(cfg)
+	}
+
+	if oldCfg == nil || oldCfg.UsageSnapshots != cfg.UsageSnapshots || oldCfg.AuthDir != cfg.AuthDir {
+		usage.ConfigureSnapshots(cfg)
+	}
+
+	if oldCfg == nil || oldCfg.UsageReports != cfg.UsageReports || oldCfg.AuthDir != cfg.AuthDir || oldCfg.ProxyURL != cfg.ProxyURL {
+		usage.ConfigureReports(cfg)
+	}
+
+	if oldCfg == nil || oldCfg.AuthGuard != cfg.AuthGuard {
+		configureInboundAuthGuard(cfg)
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.APIKeyProtocols, cfg.APIKeyProtocols) {
+		configureProtocolAccess(cfg)
+	}
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.RequestLimits, cfg.RequestLimits) {
+		configureBodyLimits(cfg)
+	}
+
+	if oldCfg != nil && !reflect.DeepEqual(oldCfg.TLS.ClientAuth, cfg.TLS.ClientAuth) {
+		clientcert.Configure(cfg)
+	}
+
+	if oldCfg != nil && !reflect.DeepEqual(oldCfg.NetworkAccess, cfg.NetworkAccess) {
+		netaccess.Configure(cfg)
+	}
+
+	if oldCfg != nil && (oldCfg.Batch != cfg.Batch || oldCfg.AuthDir != cfg.AuthDir) {
+		s.batches.Configure(cfg)
+	}
+
+	if oldCfg != nil && oldCfg.GRPC != cfg.GRPC {
+		s.grpc.Configure(cfg)
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.ProviderStatus, cfg.ProviderStatus) || oldCfg.ProxyURL != cfg.ProxyURL {
+		providerstatus.Configure(cfg)
+	}
+
+	if oldCfg == nil || oldCfg.AuditLog != cfg.AuditLog {
+		audit.Configure(cfg)
+	}
+
+	if oldCfg == nil || oldCfg.Tenants != cfg.Tenants || oldCfg.AuthDir != cfg.AuthDir {
+		tenant.Configure(cfg)
+	}
+
+	if oldCfg == nil || oldCfg.Telemetry != cfg.Telemetry || oldCfg.ProxyURL != cfg.ProxyURL {
+		telemetry.Configure(cfg)
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.ModelCapabilities, cfg.ModelCapabilities) {
+		registry.SetModelCapabilities(cfg.ModelCapabilities)
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.RemoteMedia, cfg.RemoteMedia) {
+		geminicommon.ConfigureRemoteMedia(cfg)
+	}
+
+	if s.requestLogger != nil && (oldCfg == nil || oldCfg.RequestLogCompression != cfg.RequestLogCompression) {
+		if setter, ok := s.requestLogger.(interface{ SetCompression(bool) }); ok {
+			setter.SetCompression(cfg.RequestLogCompression)
+		}
+	}
+
 	if s.requestLogger != nil && (oldCfg == nil || oldCfg.ErrorLogsMaxFiles != cfg.ErrorLogsMaxFiles) {
 		if setter, ok := s.requestLogger.(interface{ SetErrorLogsMaxFiles(int) }); ok {
 			setter.SetErrorLogsMaxFiles(cfg.ErrorLogsMaxFiles)
@@ -930,9 +1125,10 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		s.handlers.AuthManager.SetRetryConfig(cfg.RequestRetry, time.Duration(cfg.MaxRetryInterval)*time.Second)
 	}
 
-	// Update log level dynamically when debug flag changes
-	if oldCfg == nil || oldCfg.Debug != cfg.Debug {
-		util.SetLogLevel(cfg)
+	// Update log level dynamically when the logging settings change
+	if oldCfg == nil || oldCfg.Debug != cfg.Debug || oldCfg.LogLevel != cfg.LogLevel || oldCfg.LogFormat != cfg.LogFormat ||
+		!reflect.DeepEqual(oldCfg.DebugModules, cfg.DebugModules) {
+		logging.ConfigureLogLevel(cfg)
 	}
 
 	prevSecretEmpty := true
@@ -1038,21 +1234,81 @@ func (s *Server) SetWebsocketAuthChangeHandler(fn func(bool, bool)) {
 
 // (management handlers moved to internal/api/handlers/management)
 
+// inboundAuthGuard throttles and bans clients presenting invalid API keys while auth-guard is enabled.
+var (
+	inboundAuthGuard        = authguard.New("api")
+	inboundAuthGuardEnabled atomic.Bool
+)
+
+// configureInboundAuthGuard applies the auth-guard settings to inbound API key authentication.
+func configureInboundAuthGuard(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+	inboundAuthGuard.Configure(cfg.AuthGuard, cfg.ProxyURL)
+	inboundAuthGuardEnabled.Store(cfg.AuthGuard.Enabled)
+}
+
 // AuthMiddleware returns a Gin middleware handler that authenticates requests
 // using the configured authentication providers. When no providers are available,
 // it allows all requests (legacy behaviour).
 func AuthMiddleware(manager *sdkaccess.Manager) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		defer audit.Record(c, time.Now())
+
 		if manager == nil {
 			c.Next()
 			return
 		}
 
-		result, err := manager.Authenticate(c.Request.Context(), c.Request)
+		// Forwarded headers are client-controlled, so the guard keys on the peer address unless the
+		// peer is a trusted proxy.
+		clientIP := netaccess.ClientIP(c)
+		guarded := inboundAuthGuardEnabled.Load() && !authguard.IsLoopback(clientIP)
+		if guarded {
+			if wait, banned := inboundAuthGuard.Check(clientIP); wait > 0 {
+				if banned {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "IP banned due to too many failed authentication attempts"})
+					return
+				}
+				c.Header("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many failed authentication attempts, retry later"})
+				return
+			}
+		}
+
+		// Client certificates and tenant keys are looked up first so they authenticate even when
+		// api-keys is empty.
+		result, err := clientcert.Authenticate(c.Request)
+		tenants := tenant.Active()
+		if err != nil && tenants != nil {
+			result, err = tenants.Authenticate(c.Request.Context(), c.Request)
+		}
+		if err != nil {
+			result, err = manager.Authenticate(c.Request.Context(), c.Request)
+		}
 		if err == nil {
+			if guarded {
+				inboundAuthGuard.Succeed(clientIP)
+			}
 			if result != nil {
+				if !enforceProtocolAccess(c, result.Principal) {
+					return
+				}
+				if tenantID := result.Metadata["tenant"]; tenants != nil && result.Provider == tenant.ProviderName && tenantID != "" {
+					if errAdmit := tenants.Admit(tenantID, time.Now()); errAdmit != nil {
+						if errors.Is(errAdmit, tenant.ErrQuotaExceeded) {
+							c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Tenant quota exceeded"})
+						} else {
+							c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+						}
+						return
+					}
+					c.Set("tenantID", tenantID)
+				}
 				c.Set("apiKey", result.Principal)
 				c.Set("accessProvider", result.Provider)
+				logging.SetRequestAPIKey(c.Request.Context(), apiKeyLabel(result))
 				if len(result.Metadata) > 0 {
 					c.Set("accessMetadata", result.Metadata)
 				}
@@ -1063,8 +1319,14 @@ func AuthMiddleware(manager *sdkaccess.Manager) gin.HandlerFunc {
 
 		switch {
 		case errors.Is(err, sdkaccess.ErrNoCredentials):
+			if guarded {
+				inboundAuthGuard.Fail(clientIP, "missing API key")
+			}
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing API key"})
 		case errors.Is(err, sdkaccess.ErrInvalidCredential):
+			if guarded {
+				inboundAuthGuard.Fail(clientIP, "invalid API key")
+			}
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
 		default:
 			log.Errorf("authentication middleware error: %v", err)
@@ -1072,3 +1334,12 @@ func AuthMiddleware(manager *sdkaccess.Manager) gin.HandlerFunc {
 		}
 	}
 }
+
+// apiKeyLabel identifies the client credential of result in logs without revealing it. Tenant
+// principals are already key identifiers; other keys are masked.
+func apiKeyLabel(result *sdkaccess.Result) string {
+	if result.Provider == tenant.ProviderName {
+		return result.Principal
+	}
+	return util.HideAPIKey(result.Principal)
+}
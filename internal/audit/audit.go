@@ -0,0 +1,274 @@
+// Package audit keeps a per-request audit trail of authenticated API traffic: who called (client
+// address, hashed API key, access provider, tenant), what was requested (model), which upstream
+// credential served it, the outcome and the duration. Entries carry the request ID that is also
+// returned to the client and sent upstream, so one request can be followed across the proxy's
+// logs, the audit trail and the provider's logs. Recent entries are kept in memory for the
+// management API and can additionally be appended to a JSON lines file.
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/netaccess"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxEntries = 10000
+	defaultQueryLimit = 100
+
+	// Gin context keys filled in while a request is served.
+	modelContextKey      = "auditModel"
+	upstreamProviderKey  = "auditUpstreamProvider"
+	upstreamAuthIndexKey = "auditUpstreamAuthIndex"
+	accessProviderGinKey = "accessProvider"
+	apiKeyGinKey         = "apiKey"
+	tenantGinKey         = "tenantID"
+)
+
+// Request outcomes recorded in Entry.Outcome.
+const (
+	OutcomeSuccess       = "success"
+	OutcomeDenied        = "denied"
+	OutcomeRateLimited   = "rate_limited"
+	OutcomeClientError   = "client_error"
+	OutcomeUpstreamError = "upstream_error"
+	OutcomeCancelled     = "cancelled"
+)
+
+// Entry is one audited request.
+type Entry struct {
+	Time           time.Time `json:"time"`
+	RequestID      string    `json:"request_id"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	ClientIP       string    `json:"client_ip"`
+	APIKey         string    `json:"api_key,omitempty"`
+	AccessProvider string    `json:"access_provider,omitempty"`
+	Tenant         string    `json:"tenant,omitempty"`
+	Model          string    `json:"model,omitempty"`
+	Provider       string    `json:"provider,omitempty"`
+	AuthIndex      string    `json:"auth_index,omitempty"`
+	Status         int       `json:"status"`
+	Outcome        string    `json:"outcome"`
+	DurationMs     int64     `json:"duration_ms"`
+}
+
+// Filter selects entries in Query. Empty fields match everything.
+type Filter struct {
+	RequestID string
+	APIKey    string
+	Model     string
+	Outcome   string
+	Since     time.Time
+	Limit     int
+}
+
+// Log holds the most recent entries in a ring buffer and optionally appends every entry to a file.
+type Log struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+	file    *os.File
+}
+
+// New returns a log keeping up to maxEntries entries in memory. When path is set, entries are
+// also appended to it as JSON lines.
+func New(maxEntries int, path string) (*Log, error) {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+	l := &Log{entries: make([]Entry, maxEntries)}
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		l.file = file
+	}
+	return l, nil
+}
+
+// Close releases the audit file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Add appends entry.
+func (l *Log) Add(entry Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+	if l.file != nil {
+		line, err := json.Marshal(entry)
+		if err == nil {
+			line = append(line, '\n')
+			_, err = l.file.Write(line)
+		}
+		if err != nil {
+			log.Warnf("audit: failed to write entry: %v", err)
+		}
+	}
+}
+
+// Query returns the entries matching filter, newest first.
+func (l *Log) Query(filter Filter) []Entry {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	count := l.next
+	if l.full {
+		count = len(l.entries)
+	}
+	out := make([]Entry, 0, min(limit, count))
+	for i := 0; i < count && len(out) < limit; i++ {
+		entry := l.entries[(l.next-1-i+len(l.entries))%len(l.entries)]
+		if filter.matches(entry) {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+func (f Filter) matches(entry Entry) bool {
+	switch {
+	case f.RequestID != "" && entry.RequestID != f.RequestID:
+		return false
+	case f.APIKey != "" && entry.APIKey != f.APIKey:
+		return false
+	case f.Model != "" && !strings.EqualFold(entry.Model, f.Model):
+		return false
+	case f.Outcome != "" && entry.Outcome != f.Outcome:
+		return false
+	case !f.Since.IsZero() && entry.Time.Before(f.Since):
+		return false
+	}
+	return true
+}
+
+var (
+	active      atomic.Pointer[Log]
+	configureMu sync.Mutex
+	activeCfg   config.AuditLogConfig
+)
+
+// Configure applies the audit-log settings.
+func Configure(cfg *config.Config) {
+	configureMu.Lock()
+	defer configureMu.Unlock()
+	var next config.AuditLogConfig
+	if cfg != nil {
+		next = cfg.AuditLog
+	}
+	if next == activeCfg && (active.Load() != nil) == next.Enabled {
+		return
+	}
+	previous := active.Load()
+	activeCfg = next
+	if !next.Enabled {
+		active.Store(nil)
+	} else {
+		l, err := New(next.MaxEntries, strings.TrimSpace(next.File))
+		if err != nil {
+			log.Errorf("audit: %v", err)
+			active.Store(nil)
+		} else {
+			active.Store(l)
+		}
+	}
+	if previous != nil {
+		_ = previous.Close()
+	}
+}
+
+// Active returns the audit log, or nil when auditing is disabled.
+func Active() *Log { return active.Load() }
+
+// SetModel records the model requested by the client for the audit entry of the request.
+func SetModel(c *gin.Context, model string) {
+	if c != nil && active.Load() != nil {
+		c.Set(modelContextKey, model)
+	}
+}
+
+// SetUpstream records the provider and credential that served the request.
+func SetUpstream(c *gin.Context, provider, authIndex string) {
+	if c != nil && active.Load() != nil {
+		c.Set(upstreamProviderKey, provider)
+		c.Set(upstreamAuthIndexKey, authIndex)
+	}
+}
+
+// Record writes the audit entry of a finished request that started at start.
+func Record(c *gin.Context, start time.Time) {
+	l := active.Load()
+	if l == nil || c == nil || c.Request == nil {
+		return
+	}
+	status := c.Writer.Status()
+	entry := Entry{
+		Time:           start.UTC(),
+		RequestID:      logging.GetGinRequestID(c),
+		Method:         c.Request.Method,
+		Path:           c.Request.URL.Path,
+		ClientIP:       netaccess.ClientIP(c),
+		AccessProvider: c.GetString(accessProviderGinKey),
+		Tenant:         c.GetString(tenantGinKey),
+		Model:          c.GetString(modelContextKey),
+		Provider:       c.GetString(upstreamProviderKey),
+		AuthIndex:      c.GetString(upstreamAuthIndexKey),
+		Status:         status,
+		Outcome:        outcome(c, status),
+		DurationMs:     time.Since(start).Milliseconds(),
+	}
+	if key := c.GetString(apiKeyGinKey); key != "" {
+		entry.APIKey = util.HashAPIKey(key)
+	}
+	l.Add(entry)
+}
+
+func outcome(c *gin.Context, status int) string {
+	switch {
+	case c.Request.Context().Err() != nil && status < http.StatusBadRequest:
+		return OutcomeCancelled
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return OutcomeDenied
+	case status == http.StatusTooManyRequests:
+		return OutcomeRateLimited
+	case status >= http.StatusInternalServerError:
+		return OutcomeUpstreamError
+	case status >= http.StatusBadRequest:
+		return OutcomeClientError
+	default:
+		return OutcomeSuccess
+	}
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLogQueryNewestFirstWithinCapacity(t *testing.T) {
+	l, err := New(3, "")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, id := range []string{"a", "b", "c", "d"} {
+		outcome := OutcomeSuccess
+		if id == "c" {
+			outcome = OutcomeDenied
+		}
+		l.Add(Entry{Time: base.Add(time.Duration(i) * time.Minute), RequestID: id, Model: "gpt-5", Outcome: outcome})
+	}
+
+	got := l.Query(Filter{})
+	if len(got) != 3 || got[0].RequestID != "d" || got[2].RequestID != "b" {
+		t.Fatalf("entries = %+v, want d, c, b", got)
+	}
+	if got = l.Query(Filter{Outcome: OutcomeDenied}); len(got) != 1 || got[0].RequestID != "c" {
+		t.Fatalf("denied entries = %+v", got)
+	}
+	if got = l.Query(Filter{Since: base.Add(3 * time.Minute)}); len(got) != 1 || got[0].RequestID != "d" {
+		t.Fatalf("entries since = %+v", got)
+	}
+	if got = l.Query(Filter{Model: "GPT-5", Limit: 2}); len(got) != 2 {
+		t.Fatalf("limited entries = %d, want 2", len(got))
+	}
+}
+
+func TestLogAppendsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	l, err := New(10, path)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	l.Add(Entry{RequestID: "a1b2c3d4", Status: 200, Outcome: OutcomeSuccess})
+	if err = l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		t.Fatal("audit file is empty")
+	}
+	var entry Entry
+	if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if entry.RequestID != "a1b2c3d4" || entry.Status != 200 {
+		t.Fatalf("entry = %+v", entry)
+	}
+}
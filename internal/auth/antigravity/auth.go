@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	log "github.com/sirupsen/logrus"
@@ -151,8 +152,47 @@ func (o *AntigravityAuth) FetchUserInfo(ctx context.Context, accessToken string)
 	return email, nil
 }
 
-// FetchProjectID retrieves the project ID for the authenticated user via loadCodeAssist
+// Tier is a Code Assist tier an account can be onboarded to.
+type Tier struct {
+	ID          string
+	Name        string
+	Description string
+	IsDefault   bool
+}
+
+// CodeAssistStatus is the onboarding state reported by loadCodeAssist. ProjectID is empty until
+// the account has been onboarded to one of AllowedTiers.
+type CodeAssistStatus struct {
+	ProjectID    string
+	CurrentTier  string
+	AllowedTiers []Tier
+}
+
+// DefaultTierID returns the tier the account is onboarded to when no tier is chosen.
+func (s *CodeAssistStatus) DefaultTierID() string {
+	for _, tier := range s.AllowedTiers {
+		if tier.IsDefault && tier.ID != "" {
+			return tier.ID
+		}
+	}
+	return "legacy-tier"
+}
+
+// FetchProjectID retrieves the project ID for the authenticated user via loadCodeAssist,
+// onboarding the account to its default tier when it has no project yet.
 func (o *AntigravityAuth) FetchProjectID(ctx context.Context, accessToken string) (string, error) {
+	status, err := o.LoadCodeAssist(ctx, accessToken)
+	if err != nil {
+		return "", err
+	}
+	if status.ProjectID != "" {
+		return status.ProjectID, nil
+	}
+	return o.OnboardUser(ctx, accessToken, status.DefaultTierID())
+}
+
+// LoadCodeAssist reports the project, current tier and allowed tiers of the authenticated user.
+func (o *AntigravityAuth) LoadCodeAssist(ctx context.Context, accessToken string) (*CodeAssistStatus, error) {
 	loadReqBody := map[string]any{
 		"metadata": map[string]string{
 			"ideType":    "ANTIGRAVITY",
@@ -163,13 +203,13 @@ func (o *AntigravityAuth) FetchProjectID(ctx context.Context, accessToken string
 
 	rawBody, errMarshal := json.Marshal(loadReqBody)
 	if errMarshal != nil {
-		return "", fmt.Errorf("marshal request body: %w", errMarshal)
+		return nil, fmt.Errorf("marshal request body: %w", errMarshal)
 	}
 
 	endpointURL := fmt.Sprintf("%s/%s:loadCodeAssist", APIEndpoint, APIVersion)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointURL, strings.NewReader(string(rawBody)))
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("create request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json")
@@ -179,7 +219,7 @@ func (o *AntigravityAuth) FetchProjectID(ctx context.Context, accessToken string
 
 	resp, errDo := o.httpClient.Do(req)
 	if errDo != nil {
-		return "", fmt.Errorf("execute request: %w", errDo)
+		return nil, fmt.Errorf("execute request: %w", errDo)
 	}
 	defer func() {
 		if errClose := resp.Body.Close(); errClose != nil {
@@ -189,56 +229,124 @@ func (o *AntigravityAuth) FetchProjectID(ctx context.Context, accessToken string
 
 	bodyBytes, errRead := io.ReadAll(resp.Body)
 	if errRead != nil {
-		return "", fmt.Errorf("read response: %w", errRead)
+		return nil, fmt.Errorf("read response: %w", errRead)
 	}
 
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		return "", fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
+		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
 	}
 
-	var loadResp map[string]any
+	var loadResp struct {
+		Project     json.RawMessage `json:"cloudaicompanionProject"`
+		CurrentTier struct {
+			ID string `json:"id"`
+		} `json:"currentTier"`
+		AllowedTiers []struct {
+			ID          string `json:"id"`
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			IsDefault   bool   `json:"isDefault"`
+		} `json:"allowedTiers"`
+	}
 	if errDecode := json.Unmarshal(bodyBytes, &loadResp); errDecode != nil {
-		return "", fmt.Errorf("decode response: %w", errDecode)
+		return nil, fmt.Errorf("decode response: %w", errDecode)
 	}
 
-	// Extract projectID from response
-	projectID := ""
-	if id, ok := loadResp["cloudaicompanionProject"].(string); ok {
-		projectID = strings.TrimSpace(id)
-	}
-	if projectID == "" {
-		if projectMap, ok := loadResp["cloudaicompanionProject"].(map[string]any); ok {
-			if id, okID := projectMap["id"].(string); okID {
-				projectID = strings.TrimSpace(id)
-			}
+	status := &CodeAssistStatus{CurrentTier: strings.TrimSpace(loadResp.CurrentTier.ID)}
+	// The project is returned either as a plain ID or as an object with an id field.
+	var projectID string
+	if errID := json.Unmarshal(loadResp.Project, &projectID); errID != nil {
+		var project struct {
+			ID string `json:"id"`
 		}
+		_ = json.Unmarshal(loadResp.Project, &project)
+		projectID = project.ID
 	}
+	status.ProjectID = strings.TrimSpace(projectID)
+	for _, tier := range loadResp.AllowedTiers {
+		status.AllowedTiers = append(status.AllowedTiers, Tier{
+			ID:          strings.TrimSpace(tier.ID),
+			Name:        strings.TrimSpace(tier.Name),
+			Description: strings.TrimSpace(tier.Description),
+			IsDefault:   tier.IsDefault,
+		})
+	}
+	return status, nil
+}
 
-	if projectID == "" {
-		tierID := "legacy-tier"
-		if tiers, okTiers := loadResp["allowedTiers"].([]any); okTiers {
-			for _, rawTier := range tiers {
-				tier, okTier := rawTier.(map[string]any)
-				if !okTier {
-					continue
-				}
-				if isDefault, okDefault := tier["isDefault"].(bool); okDefault && isDefault {
-					if id, okID := tier["id"].(string); okID && strings.TrimSpace(id) != "" {
-						tierID = strings.TrimSpace(id)
-						break
-					}
-				}
-			}
+// VerifyGenerateContent sends a minimal generateContent request for projectID and returns the
+// model's reply, confirming the account can serve requests.
+func (o *AntigravityAuth) VerifyGenerateContent(ctx context.Context, accessToken, projectID string) (string, error) {
+	requestBody := map[string]any{
+		"model":       VerifyModel,
+		"project":     projectID,
+		"userAgent":   "antigravity",
+		"requestType": "agent",
+		"requestId":   "agent-" + uuid.NewString(),
+		"request": map[string]any{
+			"contents": []map[string]any{
+				{"role": "user", "parts": []map[string]string{{"text": "Reply with the single word OK."}}},
+			},
+			"generationConfig": map[string]any{"maxOutputTokens": 16},
+		},
+	}
+	rawBody, errMarshal := json.Marshal(requestBody)
+	if errMarshal != nil {
+		return "", fmt.Errorf("marshal request body: %w", errMarshal)
+	}
+
+	endpointURL := fmt.Sprintf("%s/%s:generateContent", GenerateEndpoint, APIVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointURL, strings.NewReader(string(rawBody)))
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", GenerateUserAgent)
+
+	resp, errDo := o.httpClient.Do(req)
+	if errDo != nil {
+		return "", fmt.Errorf("execute request: %w", errDo)
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("antigravity generateContent: close body error: %v", errClose)
 		}
+	}()
 
-		projectID, err = o.OnboardUser(ctx, accessToken, tierID)
-		if err != nil {
-			return "", err
+	bodyBytes, errRead := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if errRead != nil {
+		return "", fmt.Errorf("read response: %w", errRead)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body := strings.TrimSpace(string(bodyBytes))
+		if len(body) > 200 {
+			body = body[:200]
 		}
-		return projectID, nil
+		return "", fmt.Errorf("http %d: %s", resp.StatusCode, body)
 	}
 
-	return projectID, nil
+	var generateResp struct {
+		Response struct {
+			Candidates []struct {
+				Content struct {
+					Parts []struct {
+						Text string `json:"text"`
+					} `json:"parts"`
+				} `json:"content"`
+			} `json:"candidates"`
+		} `json:"response"`
+	}
+	if errDecode := json.Unmarshal(bodyBytes, &generateResp); errDecode != nil {
+		return "", fmt.Errorf("decode response: %w", errDecode)
+	}
+	var reply strings.Builder
+	for _, candidate := range generateResp.Response.Candidates {
+		for _, part := range candidate.Content.Parts {
+			reply.WriteString(part.Text)
+		}
+	}
+	return strings.TrimSpace(reply.String()), nil
 }
 
 // OnboardUser attempts to fetch the project ID via onboardUser by polling for completion
@@ -0,0 +1,76 @@
+package antigravity
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// redirectTransport sends every request to the test server, keeping path and query.
+type redirectTransport struct{ target *url.URL }
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestAuth(t *testing.T, handler http.HandlerFunc) *AntigravityAuth {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	return NewAntigravityAuth(nil, &http.Client{Transport: redirectTransport{target: target}})
+}
+
+func TestLoadCodeAssistReportsTiers(t *testing.T) {
+	authSvc := newTestAuth(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"currentTier":{"id":"free-tier"},"allowedTiers":[{"id":"standard-tier","name":"Standard"},{"id":"free-tier","name":"Free","isDefault":true}]}`)
+	})
+	status, err := authSvc.LoadCodeAssist(context.Background(), "token")
+	if err != nil {
+		t.Fatalf("LoadCodeAssist error: %v", err)
+	}
+	if status.ProjectID != "" || status.CurrentTier != "free-tier" || len(status.AllowedTiers) != 2 || status.DefaultTierID() != "free-tier" {
+		t.Fatalf("status = %+v", status)
+	}
+
+	authSvc = newTestAuth(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"cloudaicompanionProject":{"id":"proj-1"}}`)
+	})
+	if status, err = authSvc.LoadCodeAssist(context.Background(), "token"); err != nil || status.ProjectID != "proj-1" || status.DefaultTierID() != "legacy-tier" {
+		t.Fatalf("status = %+v, err = %v", status, err)
+	}
+}
+
+func TestVerifyGenerateContent(t *testing.T) {
+	var got map[string]any
+	authSvc := newTestAuth(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":generateContent") || r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = io.WriteString(w, `{"response":{"candidates":[{"content":{"parts":[{"text":"OK"}]}}]}}`)
+	})
+	reply, err := authSvc.VerifyGenerateContent(context.Background(), "token", "proj-1")
+	if err != nil || reply != "OK" {
+		t.Fatalf("VerifyGenerateContent = %q, %v", reply, err)
+	}
+	if got["project"] != "proj-1" || got["model"] != VerifyModel {
+		t.Fatalf("request = %v", got)
+	}
+
+	authSvc = newTestAuth(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"permission denied"}}`, http.StatusForbidden)
+	})
+	if _, err = authSvc.VerifyGenerateContent(context.Background(), "token", "proj-1"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected a 403 error, got %v", err)
+	}
+}
@@ -32,3 +32,12 @@ const (
 	APIClient      = "google-cloud-sdk vscode_cloudshelleditor/0.1"
 	ClientMetadata = `{"ideType":"IDE_UNSPECIFIED","platform":"PLATFORM_UNSPECIFIED","pluginType":"GEMINI"}`
 )
+
+// Endpoint and user agent of generate requests. The antigravity executor uses them as its
+// defaults, and the test request sent after login to verify the account uses them together with
+// VerifyModel.
+const (
+	GenerateEndpoint  = "https://daily-cloudcode-pa.googleapis.com"
+	GenerateUserAgent = "antigravity/1.104.0 darwin/arm64"
+	VerifyModel       = "gemini-2.5-flash"
+)
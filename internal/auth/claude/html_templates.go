@@ -3,6 +3,8 @@
 // and retrieval for maintaining authenticated sessions with the Claude API.
 package claude
 
+import "strings"
+
 // LoginSuccessHtml is the HTML template displayed to users after successful OAuth authentication.
 // This template provides a user-friendly success page with options to close the window
 // or navigate to the Claude platform. It includes automatic window closing functionality
@@ -216,3 +218,12 @@ const SetupNoticeHtml = `
             <h3>Additional Setup Required</h3>
             <p>To complete your setup, please visit the <a href="{{PLATFORM_URL}}" target="_blank">Claude</a> to configure your account.</p>
         </div>`
+
+// platformURL is linked from the success page.
+const platformURL = "https://console.anthropic.com/"
+
+// SuccessPage renders LoginSuccessHtml for the callback server.
+func SuccessPage() string {
+	page := strings.ReplaceAll(LoginSuccessHtml, "{{PLATFORM_URL}}", platformURL)
+	return strings.Replace(page, "{{SETUP_NOTICE}}", "", 1)
+}
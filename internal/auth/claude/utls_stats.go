@@ -0,0 +1,96 @@
+package claude
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"weak"
+)
+
+// ConnectionStats describes the cached HTTP/2 connection of one host.
+type ConnectionStats struct {
+	Host string `json:"host"`
+	// Usable reports whether the connection can take new requests.
+	Usable         bool       `json:"usable"`
+	Closing        bool       `json:"closing"`
+	Closed         bool       `json:"closed"`
+	ActiveStreams  int        `json:"active-streams"`
+	PendingStreams int        `json:"pending-streams"`
+	LastIdle       *time.Time `json:"last-idle,omitempty"`
+}
+
+// TransportStats describes the connection cache of one utls transport.
+type TransportStats struct {
+	Connections []ConnectionStats `json:"connections"`
+	// PendingDials lists the hosts a connection is being dialed to.
+	PendingDials []string `json:"pending-dials"`
+}
+
+var (
+	transportsMu sync.Mutex
+	// transports tracks the live utls transports without keeping them alive.
+	transports []weak.Pointer[utlsRoundTripper]
+)
+
+func registerTransport(t *utlsRoundTripper) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	transports = append(liveTransportsLocked(), weak.Make(t))
+}
+
+func liveTransportsLocked() []weak.Pointer[utlsRoundTripper] {
+	live := transports[:0]
+	for _, ptr := range transports {
+		if ptr.Value() != nil {
+			live = append(live, ptr)
+		}
+	}
+	return live
+}
+
+// UTLSTransportStats returns the connection caches of the utls transports in use.
+func UTLSTransportStats() []TransportStats {
+	transportsMu.Lock()
+	transports = liveTransportsLocked()
+	var tracked []*utlsRoundTripper
+	for _, ptr := range transports {
+		if t := ptr.Value(); t != nil {
+			tracked = append(tracked, t)
+		}
+	}
+	transportsMu.Unlock()
+
+	out := make([]TransportStats, 0, len(tracked))
+	for _, t := range tracked {
+		out = append(out, t.stats())
+	}
+	return out
+}
+
+func (t *utlsRoundTripper) stats() TransportStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := TransportStats{Connections: []ConnectionStats{}, PendingDials: []string{}}
+	for host, conn := range t.connections {
+		state := conn.State()
+		entry := ConnectionStats{
+			Host:           host,
+			Usable:         conn.CanTakeNewRequest(),
+			Closing:        state.Closing,
+			Closed:         state.Closed,
+			ActiveStreams:  state.StreamsActive,
+			PendingStreams: state.StreamsPending,
+		}
+		if !state.LastIdle.IsZero() {
+			lastIdle := state.LastIdle
+			entry.LastIdle = &lastIdle
+		}
+		stats.Connections = append(stats.Connections, entry)
+	}
+	for host := range t.pending {
+		stats.PendingDials = append(stats.PendingDials, host)
+	}
+	sort.Slice(stats.Connections, func(i, j int) bool { return stats.Connections[i].Host < stats.Connections[j].Host })
+	sort.Strings(stats.PendingDials)
+	return stats
+}
@@ -45,11 +45,13 @@ func newUtlsRoundTripper(cfg *config.SDKConfig) *utlsRoundTripper {
 		}
 	}
 
-	return &utlsRoundTripper{
+	t := &utlsRoundTripper{
 		connections: make(map[string]*http2.ClientConn),
 		pending:     make(map[string]*sync.Cond),
 		dialer:      dialer,
 	}
+	registerTransport(t)
+	return t
 }
 
 // getOrCreateConnection gets an existing connection or creates a new one.
@@ -1,5 +1,7 @@
 package codex
 
+import "strings"
+
 // LoginSuccessHTML is the HTML template for the page shown after a successful
 // OAuth2 authentication with Codex. It informs the user that the authentication
 // was successful and provides a countdown timer to automatically close the window.
@@ -212,3 +214,12 @@ const SetupNoticeHtml = `
             <h3>Additional Setup Required</h3>
             <p>To complete your setup, please visit the <a href="{{PLATFORM_URL}}" target="_blank">Codex</a> to configure your account.</p>
         </div>`
+
+// platformURL is linked from the success page.
+const platformURL = "https://platform.openai.com"
+
+// SuccessPage renders LoginSuccessHtml for the callback server.
+func SuccessPage() string {
+	page := strings.ReplaceAll(LoginSuccessHtml, "{{PLATFORM_URL}}", platformURL)
+	return strings.Replace(page, "{{SETUP_NOTICE}}", "", 1)
+}
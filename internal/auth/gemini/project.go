@@ -0,0 +1,144 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// codeAssistEndpoint is the Cloud Code Assist API used for project discovery. It is a variable
+// so tests can point it at a local server.
+var codeAssistEndpoint = "https://cloudcode-pa.googleapis.com/v1internal"
+
+const (
+	onboardMaxAttempts = 10
+	onboardPollDelay   = 2 * time.Second
+)
+
+// ErrProjectRequired is returned by DiscoverProject when the account's tier needs a
+// user-managed Google Cloud project, which cannot be chosen automatically.
+var ErrProjectRequired = errors.New("gemini cli account requires a Google Cloud project; log in again with --project_id")
+
+var codeAssistMetadata = map[string]string{
+	"ideType":    "IDE_UNSPECIFIED",
+	"platform":   "PLATFORM_UNSPECIFIED",
+	"pluginType": "GEMINI",
+}
+
+// DiscoverProject resolves the Code Assist project of an account without user interaction. It
+// returns the project loadCodeAssist reports and otherwise onboards the account on its default
+// tier, polling until Google has provisioned the managed project.
+func DiscoverProject(ctx context.Context, httpClient *http.Client, accessToken string) (string, error) {
+	var loadResp map[string]any
+	if err := callCodeAssist(ctx, httpClient, accessToken, "loadCodeAssist", map[string]any{"metadata": codeAssistMetadata}, &loadResp); err != nil {
+		return "", fmt.Errorf("load code assist: %w", err)
+	}
+	if projectID := projectIDValue(loadResp["cloudaicompanionProject"]); projectID != "" {
+		return projectID, nil
+	}
+
+	tierID := "legacy-tier"
+	userProject := false
+	if tiers, ok := loadResp["allowedTiers"].([]any); ok {
+		for _, rawTier := range tiers {
+			tier, okTier := rawTier.(map[string]any)
+			if !okTier {
+				continue
+			}
+			if isDefault, _ := tier["isDefault"].(bool); !isDefault {
+				continue
+			}
+			if id, okID := tier["id"].(string); okID && strings.TrimSpace(id) != "" {
+				tierID = strings.TrimSpace(id)
+			}
+			userProject, _ = tier["userDefinedCloudaicompanionProject"].(bool)
+			break
+		}
+	}
+	if userProject {
+		return "", ErrProjectRequired
+	}
+
+	log.Infof("gemini cli: onboarding account on tier %s", tierID)
+	onboardReq := map[string]any{"tierId": tierID, "metadata": codeAssistMetadata}
+	for attempt := 1; attempt <= onboardMaxAttempts; attempt++ {
+		var onboardResp map[string]any
+		if err := callCodeAssist(ctx, httpClient, accessToken, "onboardUser", onboardReq, &onboardResp); err != nil {
+			return "", fmt.Errorf("onboard user: %w", err)
+		}
+		if done, _ := onboardResp["done"].(bool); done {
+			response, _ := onboardResp["response"].(map[string]any)
+			if projectID := projectIDValue(response["cloudaicompanionProject"]); projectID != "" {
+				return projectID, nil
+			}
+			return "", ErrProjectRequired
+		}
+		if attempt == onboardMaxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(onboardPollDelay):
+		}
+	}
+	return "", fmt.Errorf("onboard user: not completed after %d attempts", onboardMaxAttempts)
+}
+
+// projectIDValue reads a cloudaicompanionProject value, which is either an id or an object
+// carrying one.
+func projectIDValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]any:
+		id, _ := v["id"].(string)
+		return strings.TrimSpace(id)
+	}
+	return ""
+}
+
+func callCodeAssist(ctx context.Context, httpClient *http.Client, accessToken, method string, body, result any) error {
+	rawBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal request body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, codeAssistEndpoint+":"+method, bytes.NewReader(rawBody))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "google-api-nodejs-client/9.15.1")
+	req.Header.Set("X-Goog-Api-Client", "gl-node/22.17.0")
+	req.Header.Set("Client-Metadata", "ideType=IDE_UNSPECIFIED,platform=PLATFORM_UNSPECIFIED,pluginType=GEMINI")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("gemini cli %s: close body error: %v", method, errClose)
+		}
+	}()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
+	}
+	if err = json.Unmarshal(bodyBytes, result); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func withCodeAssist(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	prev := codeAssistEndpoint
+	codeAssistEndpoint = srv.URL + "/v1internal"
+	t.Cleanup(func() { codeAssistEndpoint = prev })
+}
+
+func TestDiscoverProject_LoadCodeAssist(t *testing.T) {
+	withCodeAssist(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":loadCodeAssist") {
+			t.Errorf("unexpected call %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		_, _ = w.Write([]byte(`{"cloudaicompanionProject":"proj-1"}`))
+	})
+	got, err := DiscoverProject(context.Background(), http.DefaultClient, "token")
+	if err != nil || got != "proj-1" {
+		t.Fatalf("DiscoverProject = %q, %v", got, err)
+	}
+}
+
+func TestDiscoverProject_OnboardsDefaultTier(t *testing.T) {
+	calls := 0
+	withCodeAssist(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ":loadCodeAssist") {
+			_, _ = w.Write([]byte(`{"allowedTiers":[{"id":"standard-tier"},{"id":"free-tier","isDefault":true}]}`))
+			return
+		}
+		calls++
+		_, _ = w.Write([]byte(`{"done":true,"response":{"cloudaicompanionProject":{"id":"managed-1"}}}`))
+	})
+	got, err := DiscoverProject(context.Background(), http.DefaultClient, "token")
+	if err != nil || got != "managed-1" {
+		t.Fatalf("DiscoverProject = %q, %v", got, err)
+	}
+	if calls != 1 {
+		t.Fatalf("onboardUser calls = %d, want 1", calls)
+	}
+}
+
+func TestDiscoverProject_UserDefinedProjectRequired(t *testing.T) {
+	withCodeAssist(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":loadCodeAssist") {
+			t.Errorf("unexpected call %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"allowedTiers":[{"id":"standard-tier","isDefault":true,"userDefinedCloudaicompanionProject":true}]}`))
+	})
+	if _, err := DiscoverProject(context.Background(), http.DefaultClient, "token"); !errors.Is(err, ErrProjectRequired) {
+		t.Fatalf("err = %v, want ErrProjectRequired", err)
+	}
+}
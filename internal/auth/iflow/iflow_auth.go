@@ -50,6 +50,9 @@ const DefaultAPIBaseURL = "https://apis.iflow.cn/v1"
 // SuccessRedirectURL is exposed for consumers needing the official success page.
 const SuccessRedirectURL = iFlowSuccessRedirectURL
 
+// ErrorRedirectURL is the official page shown when the login fails.
+const ErrorRedirectURL = "https://iflow.cn/oauth/error"
+
 // CallbackPort defines the local port used for OAuth callbacks.
 const CallbackPort = 11451
 
@@ -0,0 +1,239 @@
+// Package oauthcallback implements the local HTTP server that receives OAuth authorization-code
+// callbacks during CLI logins. The server only accepts a callback carrying the state of the login
+// it was started for, hands out a single result and ignores everything after it, so a forged or
+// replayed redirect cannot complete or abort the login. It also provides PKCE code generation.
+package oauthcallback
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrPortInUse is returned by Start when none of the candidate ports could be bound.
+var ErrPortInUse = errors.New("oauth callback port is already in use")
+
+// ErrTimeout is returned by Wait when no callback arrived in time.
+var ErrTimeout = errors.New("timeout waiting for OAuth callback")
+
+const defaultFailureHTML = `<!DOCTYPE html><html><head><meta charset="utf-8"><title>Login failed</title></head>` +
+	`<body><h1>Login failed</h1><p>Please check the CLI output.</p></body></html>`
+
+const defaultSuccessHTML = `<!DOCTYPE html><html><head><meta charset="utf-8"><title>Login successful</title></head>` +
+	`<body><h1>Login successful</h1><p>You can close this window.</p></body></html>`
+
+// Options configures a callback server.
+type Options struct {
+	// Host is the bind address. Empty binds all interfaces, which container and SSH-tunnel
+	// setups rely on; "127.0.0.1" restricts the listener to the local machine.
+	Host string
+	// Port is the preferred port. Zero picks a free port.
+	Port int
+	// FallbackPorts is how many consecutive ports after Port are tried when Port is busy. Only
+	// providers whose redirect URI follows the bound port may set it.
+	FallbackPorts int
+	// Path is the callback path, e.g. "/callback".
+	Path string
+	// State is the expected OAuth state. Callbacks with any other state are rejected.
+	State string
+	// SuccessHTML is served after a successful callback unless SuccessRedirect is set.
+	SuccessHTML string
+	// SuccessRedirect and FailureRedirect send the browser to a provider page instead.
+	SuccessRedirect string
+	FailureRedirect string
+}
+
+// Result is the outcome of a callback.
+type Result struct {
+	Code  string
+	State string
+	Error string
+}
+
+// Server is a running callback server.
+type Server struct {
+	opts     Options
+	server   *http.Server
+	port     int
+	result   chan *Result
+	errChan  chan error
+	mu       sync.Mutex
+	done     bool
+	stopOnce sync.Once
+}
+
+// Start binds the callback server, trying Port and then up to FallbackPorts following ports.
+func Start(opts Options) (*Server, error) {
+	if opts.Path == "" {
+		opts.Path = "/callback"
+	}
+	listener, err := listen(opts.Host, opts.Port, opts.FallbackPorts)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		opts:    opts,
+		port:    listener.Addr().(*net.TCPAddr).Port,
+		result:  make(chan *Result, 1),
+		errChan: make(chan error, 1),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(opts.Path, s.handleCallback)
+	s.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+	}
+	go func() {
+		if errServe := s.server.Serve(listener); errServe != nil && !errors.Is(errServe, http.ErrServerClosed) {
+			select {
+			case s.errChan <- fmt.Errorf("oauth callback server failed: %w", errServe):
+			default:
+			}
+		}
+	}()
+	return s, nil
+}
+
+func listen(host string, port, fallback int) (net.Listener, error) {
+	if port <= 0 {
+		return net.Listen("tcp", net.JoinHostPort(host, "0"))
+	}
+	var lastErr error
+	for candidate := port; candidate <= port+max(fallback, 0) && candidate <= 65535; candidate++ {
+		listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(candidate)))
+		if err == nil {
+			if candidate != port {
+				log.Infof("oauth callback port %d is busy, using %d", port, candidate)
+			}
+			return listener, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("%w: port %d: %v", ErrPortInUse, port, lastErr)
+}
+
+// Port returns the bound port.
+func (s *Server) Port() int { return s.port }
+
+// RedirectURI returns the loopback redirect URI of the server.
+func (s *Server) RedirectURI() string {
+	return fmt.Sprintf("http://localhost:%d%s", s.port, s.opts.Path)
+}
+
+// Wait blocks until a callback result, a server error, ctx cancellation or timeout.
+func (s *Server) Wait(ctx context.Context, timeout time.Duration) (*Result, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case res := <-s.result:
+		return res, nil
+	case err := <-s.errChan:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, ErrTimeout
+	}
+}
+
+// Stop shuts the server down.
+func (s *Server) Stop(ctx context.Context) error {
+	var err error
+	s.stopOnce.Do(func() {
+		err = s.server.Shutdown(ctx)
+	})
+	return err
+}
+
+func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	header.Set("Cache-Control", "no-store")
+	header.Set("Referrer-Policy", "no-referrer")
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("X-Frame-Options", "DENY")
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	res := &Result{
+		Code:  strings.TrimSpace(query.Get("code")),
+		State: strings.TrimSpace(query.Get("state")),
+		Error: strings.TrimSpace(query.Get("error")),
+	}
+	if subtle.ConstantTimeCompare([]byte(res.State), []byte(s.opts.State)) != 1 {
+		// Not consumed: a forged redirect must not end the login the user is completing.
+		log.Warn("oauth callback rejected: state mismatch")
+		http.Error(w, "invalid state", http.StatusBadRequest)
+		return
+	}
+	if res.Error == "" && res.Code == "" {
+		res.Error = "missing_code"
+	}
+
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		http.Error(w, "this login has already been completed", http.StatusGone)
+		return
+	}
+	s.done = true
+	s.mu.Unlock()
+	s.result <- res
+
+	if res.Error != "" {
+		if s.opts.FailureRedirect != "" {
+			http.Redirect(w, r, s.opts.FailureRedirect, http.StatusFound)
+			return
+		}
+		writeHTML(w, http.StatusBadRequest, defaultFailureHTML)
+		return
+	}
+	if s.opts.SuccessRedirect != "" {
+		http.Redirect(w, r, s.opts.SuccessRedirect, http.StatusFound)
+		return
+	}
+	page := s.opts.SuccessHTML
+	if page == "" {
+		page = defaultSuccessHTML
+	}
+	writeHTML(w, http.StatusOK, page)
+}
+
+func writeHTML(w http.ResponseWriter, status int, page string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := w.Write([]byte(page)); err != nil {
+		log.Debugf("oauth callback: write page: %v", err)
+	}
+}
+
+// GeneratePKCE returns an RFC 7636 code verifier and its S256 code challenge.
+func GeneratePKCE() (verifier, challenge string, err error) {
+	// 96 random bytes encode to a 128-character verifier, the maximum RFC 7636 allows.
+	buf := make([]byte, 96)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(buf)
+	hash := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,96 @@
+package oauthcallback
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, url string) int {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServerValidatesStateAndIsSingleUse(t *testing.T) {
+	srv, err := Start(Options{Host: "127.0.0.1", Path: "/cb", State: "expected"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer func() { _ = srv.Stop(context.Background()) }()
+
+	if status := get(t, srv.RedirectURI()+"?code=evil&state=forged"); status != http.StatusBadRequest {
+		t.Fatalf("forged state status = %d, want 400", status)
+	}
+	if status := get(t, srv.RedirectURI()+"?code=good&state=expected"); status != http.StatusOK {
+		t.Fatalf("valid callback status = %d, want 200", status)
+	}
+	if status := get(t, srv.RedirectURI()+"?code=again&state=expected"); status != http.StatusGone {
+		t.Fatalf("replayed callback status = %d, want 410", status)
+	}
+
+	res, err := srv.Wait(context.Background(), time.Second)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if res.Code != "good" || res.State != "expected" || res.Error != "" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+func TestServerFallsBackToNextPort(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer func() { _ = busy.Close() }()
+	port := busy.Addr().(*net.TCPAddr).Port
+
+	if _, err = Start(Options{Host: "127.0.0.1", Port: port, State: "s"}); !errors.Is(err, ErrPortInUse) {
+		t.Fatalf("expected ErrPortInUse without fallback, got %v", err)
+	}
+	srv, err := Start(Options{Host: "127.0.0.1", Port: port, FallbackPorts: 20, State: "s"})
+	if err != nil {
+		t.Skipf("no free port after %d: %v", port, err)
+	}
+	defer func() { _ = srv.Stop(context.Background()) }()
+	if srv.Port() == port {
+		t.Fatalf("bound busy port %s", strconv.Itoa(port))
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	srv, err := Start(Options{Host: "127.0.0.1", State: "s"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer func() { _ = srv.Stop(context.Background()) }()
+	if _, err = srv.Wait(context.Background(), 10*time.Millisecond); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestGeneratePKCE(t *testing.T) {
+	verifier, challenge, err := GeneratePKCE()
+	if err != nil {
+		t.Fatalf("GeneratePKCE: %v", err)
+	}
+	if len(verifier) != 128 {
+		t.Fatalf("verifier length = %d, want 128", len(verifier))
+	}
+	hash := sha256.Sum256([]byte(verifier))
+	if challenge != base64.RawURLEncoding.EncodeToString(hash[:]) {
+		t.Fatal("challenge is not the S256 of the verifier")
+	}
+}
@@ -0,0 +1,300 @@
+// Package authguard protects client authentication against brute-force attempts. Each Guard
+// tracks failed attempts per client IP, answers further attempts with an exponential backoff and
+// bans an IP after too many consecutive failures. Failures and bans are written to the log as
+// audit entries, and a burst of bans is reported as a sustained attack.
+package authguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultMaxFailures    = 5
+	defaultBackoff        = time.Second
+	defaultBanDuration    = 30 * time.Minute
+	defaultMaxBanDuration = 24 * time.Hour
+	defaultAlertThreshold = 3
+	defaultAlertWindow    = 10 * time.Minute
+
+	// idleRetention controls how long an IP without activity is remembered, so repeat offenders
+	// keep their escalated ban length for a while.
+	idleRetention = 24 * time.Hour
+	// purgeInterval controls how often stale entries are dropped.
+	purgeInterval  = time.Hour
+	webhookTimeout = 10 * time.Second
+)
+
+// Alert is the payload reported when bans pile up within the alert window.
+type Alert struct {
+	Event  string    `json:"event"`
+	Scope  string    `json:"scope"`
+	Bans   int       `json:"bans"`
+	Window string    `json:"window"`
+	IPs    []string  `json:"ips"`
+	Time   time.Time `json:"time"`
+}
+
+type settings struct {
+	maxFailures    int
+	backoff        time.Duration
+	banDuration    time.Duration
+	maxBanDuration time.Duration
+	alertThreshold int
+	alertWindow    time.Duration
+	webhook        string
+	proxyURL       string
+}
+
+type entry struct {
+	failures    int
+	bans        int
+	retryAt     time.Time
+	bannedUntil time.Time
+	lastSeen    time.Time
+}
+
+type banEvent struct {
+	ip string
+	at time.Time
+}
+
+// Guard tracks authentication failures of one scope (e.g. "management" or "api").
+type Guard struct {
+	scope string
+
+	mu        sync.Mutex
+	settings  settings
+	entries   map[string]*entry
+	bans      []banEvent
+	lastAlert time.Time
+	lastPurge time.Time
+
+	now    func() time.Time
+	notify func(webhook, proxyURL string, alert Alert)
+}
+
+// New returns a Guard for scope using the default settings.
+func New(scope string) *Guard {
+	g := &Guard{
+		scope:   scope,
+		entries: make(map[string]*entry),
+		now:     time.Now,
+		notify:  postAlert,
+	}
+	g.Configure(config.AuthGuardConfig{}, "")
+	return g
+}
+
+// Configure applies cfg, falling back to defaults for unset or invalid values. Alert webhooks are
+// sent through proxyURL when it is set. Tracked failures and active bans are kept.
+func (g *Guard) Configure(cfg config.AuthGuardConfig, proxyURL string) {
+	s := settings{
+		maxFailures:    cfg.MaxFailures,
+		backoff:        parseDuration(cfg.Backoff, defaultBackoff),
+		banDuration:    parseDuration(cfg.BanDuration, defaultBanDuration),
+		maxBanDuration: parseDuration(cfg.MaxBanDuration, defaultMaxBanDuration),
+		alertThreshold: cfg.AlertThreshold,
+		alertWindow:    parseDuration(cfg.AlertWindow, defaultAlertWindow),
+		webhook:        strings.TrimSpace(cfg.AlertWebhook),
+		proxyURL:       strings.TrimSpace(proxyURL),
+	}
+	if s.maxFailures <= 0 {
+		s.maxFailures = defaultMaxFailures
+	}
+	if s.alertThreshold <= 0 {
+		s.alertThreshold = defaultAlertThreshold
+	}
+	if s.maxBanDuration < s.banDuration {
+		s.maxBanDuration = s.banDuration
+	}
+	g.mu.Lock()
+	g.settings = s
+	g.mu.Unlock()
+}
+
+// Check reports whether ip may attempt to authenticate now. When it may not, wait is the time
+// left and banned tells a ban apart from the backoff after a recent failure.
+func (g *Guard) Check(ip string) (wait time.Duration, banned bool) {
+	if g == nil {
+		return 0, false
+	}
+	now := g.now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	e := g.entries[ip]
+	if e == nil {
+		return 0, false
+	}
+	if now.Before(e.bannedUntil) {
+		return e.bannedUntil.Sub(now), true
+	}
+	if now.Before(e.retryAt) {
+		return e.retryAt.Sub(now), false
+	}
+	return 0, false
+}
+
+// Fail records a failed attempt by ip. reason is written to the audit log.
+func (g *Guard) Fail(ip, reason string) {
+	if g == nil {
+		return
+	}
+	now := g.now()
+	g.mu.Lock()
+	g.purgeLocked(now)
+	s := g.settings
+	e := g.entries[ip]
+	if e == nil {
+		e = &entry{}
+		g.entries[ip] = e
+	}
+	e.lastSeen = now
+	e.failures++
+	failures := e.failures
+
+	if failures < s.maxFailures {
+		e.retryAt = now.Add(doubled(s.backoff, failures-1, s.banDuration))
+		g.mu.Unlock()
+		log.WithFields(log.Fields{"audit": "auth", "scope": g.scope, "ip": ip, "reason": reason, "failures": failures}).Warn("authentication failed")
+		return
+	}
+
+	banFor := doubled(s.banDuration, e.bans, s.maxBanDuration)
+	e.bans++
+	e.failures = 0
+	e.retryAt = time.Time{}
+	e.bannedUntil = now.Add(banFor)
+	g.bans = append(g.bans, banEvent{ip: ip, at: now})
+	alert := g.alertLocked(now, s)
+	g.mu.Unlock()
+
+	log.WithFields(log.Fields{"audit": "auth", "scope": g.scope, "ip": ip, "reason": reason, "failures": failures, "ban": banFor.String()}).Warn("client banned after repeated authentication failures")
+	if alert != nil {
+		log.WithFields(log.Fields{"audit": "auth", "scope": g.scope, "bans": alert.Bans, "window": alert.Window, "ips": strings.Join(alert.IPs, ",")}).Error("sustained authentication attack detected")
+		if s.webhook != "" && g.notify != nil {
+			go g.notify(s.webhook, s.proxyURL, *alert)
+		}
+	}
+}
+
+// Succeed clears the failure count of ip after a successful authentication. The number of past
+// bans is kept so a returning attacker is banned for longer.
+func (g *Guard) Succeed(ip string) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if e := g.entries[ip]; e != nil {
+		e.failures = 0
+		e.retryAt = time.Time{}
+		e.lastSeen = g.now()
+	}
+}
+
+// alertLocked trims ban events outside the alert window and returns an alert when the remaining
+// bans reach the threshold. At most one alert is raised per window.
+func (g *Guard) alertLocked(now time.Time, s settings) *Alert {
+	cutoff := now.Add(-s.alertWindow)
+	kept := g.bans[:0]
+	for _, ev := range g.bans {
+		if ev.at.After(cutoff) {
+			kept = append(kept, ev)
+		}
+	}
+	g.bans = kept
+	if len(kept) < s.alertThreshold || (!g.lastAlert.IsZero() && now.Sub(g.lastAlert) < s.alertWindow) {
+		return nil
+	}
+	g.lastAlert = now
+	seen := make(map[string]struct{}, len(kept))
+	ips := make([]string, 0, len(kept))
+	for _, ev := range kept {
+		if _, ok := seen[ev.ip]; ok {
+			continue
+		}
+		seen[ev.ip] = struct{}{}
+		ips = append(ips, ev.ip)
+	}
+	return &Alert{
+		Event:  "sustained_auth_attack",
+		Scope:  g.scope,
+		Bans:   len(kept),
+		Window: s.alertWindow.String(),
+		IPs:    ips,
+		Time:   now.UTC(),
+	}
+}
+
+// purgeLocked drops entries that are neither banned nor active within idleRetention.
+func (g *Guard) purgeLocked(now time.Time) {
+	if now.Sub(g.lastPurge) < purgeInterval {
+		return
+	}
+	g.lastPurge = now
+	for ip, e := range g.entries {
+		if now.Before(e.bannedUntil) {
+			continue
+		}
+		if now.Sub(e.lastSeen) > idleRetention {
+			delete(g.entries, ip)
+		}
+	}
+}
+
+// doubled returns base doubled n times, capped at limit.
+func doubled(base time.Duration, n int, limit time.Duration) time.Duration {
+	d := base
+	for i := 0; i < n && d < limit; i++ {
+		d *= 2
+	}
+	if d > limit {
+		d = limit
+	}
+	return d
+}
+
+func parseDuration(raw string, fallback time.Duration) time.Duration {
+	if raw = strings.TrimSpace(raw); raw == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil || parsed <= 0 {
+		return fallback
+	}
+	return parsed
+}
+
+func postAlert(webhook, proxyURL string, alert Alert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return
+	}
+	client := &http.Client{Timeout: webhookTimeout}
+	if proxyURL != "" {
+		client = util.SetProxy(&config.SDKConfig{ProxyURL: proxyURL}, client)
+	}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Warnf("auth guard: alert webhook failed: %v", err)
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		log.Warnf("auth guard: alert webhook returned status %d", resp.StatusCode)
+	}
+}
+
+// IsLoopback reports whether ip is a loopback client, which is never throttled.
+func IsLoopback(ip string) bool {
+	return ip == "127.0.0.1" || ip == "::1"
+}
@@ -0,0 +1,107 @@
+package authguard
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func newTestGuard(cfg config.AuthGuardConfig) (*Guard, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := New("test")
+	g.Configure(cfg, "")
+	g.now = func() time.Time { return now }
+	return g, &now
+}
+
+func TestGuardBackoffThenBan(t *testing.T) {
+	g, now := newTestGuard(config.AuthGuardConfig{MaxFailures: 3, Backoff: "1s", BanDuration: "1m", MaxBanDuration: "3m"})
+	ip := "203.0.113.7"
+
+	g.Fail(ip, "invalid")
+	if wait, banned := g.Check(ip); wait != time.Second || banned {
+		t.Fatalf("after first failure: wait=%v banned=%v", wait, banned)
+	}
+	*now = now.Add(time.Second)
+	if wait, _ := g.Check(ip); wait != 0 {
+		t.Fatalf("backoff should have elapsed, wait=%v", wait)
+	}
+	g.Fail(ip, "invalid")
+	if wait, _ := g.Check(ip); wait != 2*time.Second {
+		t.Fatalf("backoff should double, wait=%v", wait)
+	}
+	*now = now.Add(2 * time.Second)
+	g.Fail(ip, "invalid")
+	if wait, banned := g.Check(ip); wait != time.Minute || !banned {
+		t.Fatalf("expected 1m ban, wait=%v banned=%v", wait, banned)
+	}
+
+	// Repeat offenders get doubled bans, capped at the maximum.
+	for _, want := range []time.Duration{2 * time.Minute, 3 * time.Minute} {
+		*now = now.Add(time.Hour)
+		for i := 0; i < 3; i++ {
+			g.Fail(ip, "invalid")
+			*now = now.Add(10 * time.Second)
+		}
+		*now = now.Add(-10 * time.Second)
+		if wait, banned := g.Check(ip); wait != want || !banned {
+			t.Fatalf("expected %v ban, wait=%v banned=%v", want, wait, banned)
+		}
+	}
+}
+
+func TestGuardSucceedClearsFailures(t *testing.T) {
+	g, now := newTestGuard(config.AuthGuardConfig{MaxFailures: 2})
+	ip := "198.51.100.1"
+
+	g.Fail(ip, "invalid")
+	g.Succeed(ip)
+	if wait, _ := g.Check(ip); wait != 0 {
+		t.Fatalf("success should clear backoff, wait=%v", wait)
+	}
+	*now = now.Add(time.Second)
+	g.Fail(ip, "invalid")
+	if _, banned := g.Check(ip); banned {
+		t.Fatal("failure count should restart after success")
+	}
+	if wait, _ := g.Check("198.51.100.2"); wait != 0 {
+		t.Fatalf("other IPs must not be throttled, wait=%v", wait)
+	}
+}
+
+func TestGuardSustainedAttackAlert(t *testing.T) {
+	g, now := newTestGuard(config.AuthGuardConfig{MaxFailures: 1, AlertThreshold: 2, AlertWindow: "10m", AlertWebhook: "http://alerts.invalid"})
+	var mu sync.Mutex
+	var alerts []Alert
+	done := make(chan struct{}, 4)
+	g.notify = func(webhook, proxyURL string, alert Alert) {
+		mu.Lock()
+		alerts = append(alerts, alert)
+		mu.Unlock()
+		done <- struct{}{}
+	}
+
+	g.Fail("192.0.2.1", "invalid")
+	g.Fail("192.0.2.2", "invalid")
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected an alert after two bans")
+	}
+	// Further bans within the window do not raise another alert.
+	g.Fail("192.0.2.3", "invalid")
+	*now = now.Add(11 * time.Minute)
+	g.Fail("192.0.2.4", "invalid")
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(alerts))
+	}
+	if alerts[0].Bans != 2 || len(alerts[0].IPs) != 2 || alerts[0].Scope != "test" {
+		t.Fatalf("unexpected alert: %+v", alerts[0])
+	}
+}
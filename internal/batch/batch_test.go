@@ -0,0 +1,229 @@
+package batch
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
+)
+
+func writeInput(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "input.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	return path
+}
+
+func TestReadInputValidation(t *testing.T) {
+	dir := t.TempDir()
+	path := writeInput(t, dir, strings.Join([]string{
+		`{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"m"}}`,
+		`not json`,
+		`{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"m"}}`,
+		`{"custom_id":"b","method":"GET","url":"/v1/chat/completions","body":{"model":"m"}}`,
+		`{"custom_id":"c","method":"POST","url":"/v1/responses","body":{"model":"m"}}`,
+		``,
+		`{"custom_id":"d","method":"POST","url":"/v1/chat/completions","body":{"model":"m"}}`,
+	}, "\n"))
+
+	lines, errs, err := readInput(path, "/v1/chat/completions", 100)
+	if err != nil {
+		t.Fatalf("readInput: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("valid lines = %d, want 2", len(lines))
+	}
+	wantCodes := []string{"invalid_json_line", "duplicate_custom_id", "invalid_method", "invalid_url"}
+	if len(errs) != len(wantCodes) {
+		t.Fatalf("errors = %+v", errs)
+	}
+	for i, code := range wantCodes {
+		if errs[i].Code != code || errs[i].Line != i+2 {
+			t.Errorf("error %d = %+v, want %s on line %d", i, errs[i], code, i+2)
+		}
+	}
+
+	_, errs, _ = readInput(writeInput(t, dir, "\n"), "/v1/chat/completions", 100)
+	if len(errs) != 1 || errs[0].Code != "empty_file" {
+		t.Fatalf("empty file errors = %+v", errs)
+	}
+}
+
+// testOwner is the owner hash of the configured "secret" client key.
+var testOwner = util.HashAPIKey("secret")
+
+func newTestManager(t *testing.T, handler http.HandlerFunc) *Manager {
+	t.Helper()
+	m := NewManager(handler)
+	cfg := &config.Config{Batch: config.BatchConfig{Enabled: true, Dir: t.TempDir(), Concurrency: 2}}
+	cfg.APIKeys = []string{"secret"}
+	m.Configure(cfg)
+	t.Cleanup(m.stopAll)
+	return m
+}
+
+func waitForStatus(t *testing.T, m *Manager, owner, id string, statuses ...string) Batch {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		b, err := m.Get(owner, id)
+		if err != nil {
+			t.Fatalf("get batch: %v", err)
+		}
+		for _, status := range statuses {
+			if b.Status == status {
+				return b
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("batch %s did not reach %v", id, statuses)
+	return Batch{}
+}
+
+func TestBatchRunsRequestsAndPublishesResults(t *testing.T) {
+	var rateLimited atomic.Bool
+	m := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body := make([]byte, r.ContentLength)
+		_, _ = r.Body.Read(body)
+		if gjson.GetBytes(body, "stream").Bool() {
+			t.Errorf("batch request must not stream")
+		}
+		model := gjson.GetBytes(body, "model").String()
+		switch {
+		case model == "limited" && rateLimited.CompareAndSwap(false, true):
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case model == "missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"message":"unknown model"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"object":"chat.completion","model":"` + model + `"}`))
+		}
+	})
+	st := m.state.Load()
+	input := strings.Join([]string{
+		`{"custom_id":"ok","method":"POST","url":"/v1/chat/completions","body":{"model":"m","stream":true}}`,
+		`{"custom_id":"retry","method":"POST","url":"/v1/chat/completions","body":{"model":"limited"}}`,
+		`{"custom_id":"fail","method":"POST","url":"/v1/chat/completions","body":{"model":"missing"}}`,
+	}, "\n")
+	file, err := st.store.CreateFile(testOwner, "input.jsonl", PurposeBatch, []byte(input), 1)
+	if err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+	created, err := m.Create(testOwner, file.ID, "/v1/chat/completions", "24h", nil)
+	if err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+	if _, err = m.Get("other", created.ID); err != ErrNotFound {
+		t.Fatalf("other owner get error = %v, want ErrNotFound", err)
+	}
+
+	b := waitForStatus(t, m, testOwner, created.ID, StatusCompleted, StatusFailed)
+	if b.Status != StatusCompleted {
+		t.Fatalf("status = %s, errors = %+v", b.Status, b.Errors)
+	}
+	if b.RequestCounts != (RequestCounts{Total: 3, Completed: 2, Failed: 1}) {
+		t.Fatalf("request counts = %+v", b.RequestCounts)
+	}
+	if b.OutputFileID == nil || b.ErrorFileID == nil || b.CompletedAt == nil {
+		t.Fatalf("missing result files: %+v", b)
+	}
+
+	output, err := os.ReadFile(st.store.FilePath(*b.OutputFileID))
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if status := gjson.Get(line, "response.status_code").Int(); status != http.StatusOK {
+			t.Errorf("output line status = %d: %s", status, line)
+		}
+	}
+	if !strings.Contains(string(output), `"custom_id":"retry"`) {
+		t.Errorf("rate-limited request missing from output: %s", output)
+	}
+	errorsFile, _ := os.ReadFile(st.store.FilePath(*b.ErrorFileID))
+	if gjson.GetBytes(errorsFile, "custom_id").String() != "fail" || gjson.GetBytes(errorsFile, "response.status_code").Int() != http.StatusNotFound {
+		t.Errorf("error file = %s", errorsFile)
+	}
+	if outFile, errGet := st.store.GetFile(testOwner, *b.OutputFileID); errGet != nil || outFile.Purpose != PurposeBatchOutput {
+		t.Errorf("output file = %+v, %v", outFile, errGet)
+	}
+}
+
+func TestBatchValidationFailure(t *testing.T) {
+	m := newTestManager(t, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	st := m.state.Load()
+	file, _ := st.store.CreateFile(testOwner, "input.jsonl", PurposeBatch, []byte(`{"custom_id":"a","method":"POST","url":"/v1/responses","body":{}}`), 1)
+
+	if _, err := m.Create(testOwner, file.ID, "/v1/embeddings", "24h", nil); err == nil {
+		t.Fatal("expected unsupported endpoint error")
+	}
+	if _, err := m.Create("other", file.ID, "/v1/chat/completions", "24h", nil); err == nil {
+		t.Fatal("expected another owner's file to be rejected")
+	}
+	stranger, _ := st.store.CreateFile("sha256:unknown", "input.jsonl", PurposeBatch, []byte(`{}`), 1)
+	if _, err := m.Create("sha256:unknown", stranger.ID, "/v1/chat/completions", "24h", nil); err == nil {
+		t.Fatal("expected an owner without a configured key to be rejected")
+	}
+	created, err := m.Create(testOwner, file.ID, "/v1/chat/completions", "24h", nil)
+	if err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+	b := waitForStatus(t, m, testOwner, created.ID, StatusFailed)
+	if b.Errors == nil || len(b.Errors.Data) != 1 || b.Errors.Data[0].Code != "invalid_url" {
+		t.Fatalf("errors = %+v", b.Errors)
+	}
+}
+
+func TestBatchCancel(t *testing.T) {
+	release := make(chan struct{})
+	m := newTestManager(t, func(w http.ResponseWriter, _ *http.Request) {
+		<-release
+		_, _ = w.Write([]byte(`{}`))
+	})
+	st := m.state.Load()
+	var lines []string
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		lines = append(lines, `{"custom_id":"`+id+`","method":"POST","url":"/v1/chat/completions","body":{"model":"m"}}`)
+	}
+	file, _ := st.store.CreateFile(testOwner, "input.jsonl", PurposeBatch, []byte(strings.Join(lines, "\n")), 1)
+	created, err := m.Create(testOwner, file.ID, "/v1/chat/completions", "24h", nil)
+	if err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+	waitForStatus(t, m, testOwner, created.ID, StatusInProgress)
+	b, err := m.Cancel(testOwner, created.ID)
+	if err != nil || b.Status != StatusCancelling {
+		t.Fatalf("cancel = %+v, %v", b, err)
+	}
+	close(release)
+	b = waitForStatus(t, m, testOwner, created.ID, StatusCancelled)
+	if done := b.RequestCounts.Completed + b.RequestCounts.Failed; done >= 5 {
+		t.Fatalf("cancelled batch ran every request: %+v", b.RequestCounts)
+	}
+}
+
+func TestValidID(t *testing.T) {
+	if !validID("file-0123abcd", "file-") {
+		t.Fatal("expected valid file id")
+	}
+	for _, id := range []string{"file-", "file-../x", "batch_0123", "file-ABC"} {
+		if validID(id, "file-") {
+			t.Errorf("validID(%q) = true", id)
+		}
+	}
+}
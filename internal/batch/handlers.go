@@ -0,0 +1,258 @@
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	log "github.com/sirupsen/logrus"
+)
+
+var errDisabled = errors.New("the batch API is not enabled on this server")
+
+// paramError is a client error about one request parameter.
+type paramError struct {
+	param   string
+	message string
+}
+
+func (e *paramError) Error() string { return e.message }
+
+func invalidParam(param, message string) error { return &paramError{param: param, message: message} }
+
+func writeError(c *gin.Context, status int, message, code string) {
+	c.JSON(status, handlers.ErrorResponse{Error: handlers.ErrorDetail{
+		Message: message,
+		Type:    "invalid_request_error",
+		Code:    code,
+	}})
+}
+
+// writeManagerError maps manager errors onto OpenAI style error responses.
+func writeManagerError(c *gin.Context, err error) {
+	var pe *paramError
+	switch {
+	case errors.Is(err, errDisabled):
+		writeError(c, http.StatusNotFound, err.Error(), "batch_disabled")
+	case errors.Is(err, ErrNotFound):
+		writeError(c, http.StatusNotFound, "No such object", "not_found")
+	case errors.As(err, &pe):
+		writeError(c, http.StatusBadRequest, pe.message, "invalid_"+pe.param)
+	default:
+		log.Errorf("batch: %v", err)
+		c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{Error: handlers.ErrorDetail{
+			Message: "internal server error",
+			Type:    "server_error",
+		}})
+	}
+}
+
+// owner identifies the caller. Files and batches are only visible to the API key that created them.
+func owner(c *gin.Context) string {
+	if key, ok := c.Get("apiKey"); ok {
+		return util.HashAPIKey(fmt.Sprint(key))
+	}
+	return ""
+}
+
+// UploadFile handles POST /v1/files. Only files with purpose "batch" are accepted.
+func (m *Manager) UploadFile(c *gin.Context) {
+	st := m.state.Load()
+	if st == nil {
+		writeManagerError(c, errDisabled)
+		return
+	}
+	if purpose := c.PostForm("purpose"); purpose != PurposeBatch {
+		writeError(c, http.StatusBadRequest, fmt.Sprintf("unsupported purpose %q, only batch is supported", purpose), "invalid_purpose")
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		writeError(c, http.StatusBadRequest, "missing file", "missing_required_parameter")
+		return
+	}
+	if header.Size > st.maxFileBytes {
+		writeError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds the %d byte limit", st.maxFileBytes), "file_too_large")
+		return
+	}
+	src, err := header.Open()
+	if err != nil {
+		writeManagerError(c, err)
+		return
+	}
+	defer func() { _ = src.Close() }()
+	content, err := io.ReadAll(io.LimitReader(src, st.maxFileBytes+1))
+	if err != nil {
+		writeManagerError(c, err)
+		return
+	}
+	if int64(len(content)) > st.maxFileBytes {
+		writeError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds the %d byte limit", st.maxFileBytes), "file_too_large")
+		return
+	}
+	file, err := st.store.CreateFile(owner(c), header.Filename, PurposeBatch, content, m.unix())
+	if err != nil {
+		writeManagerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+// ListFiles handles GET /v1/files.
+func (m *Manager) ListFiles(c *gin.Context) {
+	st := m.state.Load()
+	if st == nil {
+		writeManagerError(c, errDisabled)
+		return
+	}
+	files, err := st.store.ListFiles(owner(c))
+	if err != nil {
+		writeManagerError(c, err)
+		return
+	}
+	if purpose := c.Query("purpose"); purpose != "" {
+		filtered := files[:0]
+		for _, file := range files {
+			if file.Purpose == purpose {
+				filtered = append(filtered, file)
+			}
+		}
+		files = filtered
+	}
+	c.JSON(http.StatusOK, gin.H{"object": "list", "data": files, "has_more": false})
+}
+
+// GetFile handles GET /v1/files/:id.
+func (m *Manager) GetFile(c *gin.Context) {
+	st := m.state.Load()
+	if st == nil {
+		writeManagerError(c, errDisabled)
+		return
+	}
+	file, err := st.store.GetFile(owner(c), c.Param("id"))
+	if err != nil {
+		writeManagerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+// GetFileContent handles GET /v1/files/:id/content.
+func (m *Manager) GetFileContent(c *gin.Context) {
+	st := m.state.Load()
+	if st == nil {
+		writeManagerError(c, errDisabled)
+		return
+	}
+	file, err := st.store.GetFile(owner(c), c.Param("id"))
+	if err != nil {
+		writeManagerError(c, err)
+		return
+	}
+	f, err := os.Open(st.store.FilePath(file.ID))
+	if err != nil {
+		writeManagerError(c, ErrNotFound)
+		return
+	}
+	defer func() { _ = f.Close() }()
+	c.DataFromReader(http.StatusOK, file.Bytes, "application/jsonl", f, nil)
+}
+
+// DeleteFile handles DELETE /v1/files/:id.
+func (m *Manager) DeleteFile(c *gin.Context) {
+	st := m.state.Load()
+	if st == nil {
+		writeManagerError(c, errDisabled)
+		return
+	}
+	id := c.Param("id")
+	if err := st.store.DeleteFile(owner(c), id); err != nil {
+		writeManagerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "object": "file", "deleted": true})
+}
+
+// CreateBatch handles POST /v1/batches.
+func (m *Manager) CreateBatch(c *gin.Context) {
+	var req struct {
+		InputFileID      string            `json:"input_file_id"`
+		Endpoint         string            `json:"endpoint"`
+		CompletionWindow string            `json:"completion_window"`
+		Metadata         map[string]string `json:"metadata"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeError(c, http.StatusBadRequest, "invalid request body", "invalid_request")
+		return
+	}
+	if strings.TrimSpace(req.InputFileID) == "" {
+		writeError(c, http.StatusBadRequest, "input_file_id is required", "missing_required_parameter")
+		return
+	}
+	b, err := m.Create(owner(c), req.InputFileID, req.Endpoint, req.CompletionWindow, req.Metadata)
+	if err != nil {
+		writeManagerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// GetBatch handles GET /v1/batches/:id.
+func (m *Manager) GetBatch(c *gin.Context) {
+	b, err := m.Get(owner(c), c.Param("id"))
+	if err != nil {
+		writeManagerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// ListBatches handles GET /v1/batches with the limit and after cursor parameters.
+func (m *Manager) ListBatches(c *gin.Context) {
+	batches, err := m.List(owner(c))
+	if err != nil {
+		writeManagerError(c, err)
+		return
+	}
+	if after := c.Query("after"); after != "" {
+		for i := range batches {
+			if batches[i].ID == after {
+				batches = batches[i+1:]
+				break
+			}
+		}
+	}
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		if n, errParse := strconv.Atoi(raw); errParse == nil && n > 0 {
+			limit = min(n, 100)
+		}
+	}
+	hasMore := len(batches) > limit
+	if hasMore {
+		batches = batches[:limit]
+	}
+	resp := gin.H{"object": "list", "data": batches, "has_more": hasMore, "first_id": nil, "last_id": nil}
+	if len(batches) > 0 {
+		resp["first_id"] = batches[0].ID
+		resp["last_id"] = batches[len(batches)-1].ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CancelBatch handles POST /v1/batches/:id/cancel.
+func (m *Manager) CancelBatch(c *gin.Context) {
+	b, err := m.Cancel(owner(c), c.Param("id"))
+	if err != nil {
+		writeManagerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
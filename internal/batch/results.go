@@ -0,0 +1,138 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// resultLine is one line of a batch output or error file.
+type resultLine struct {
+	ID       string          `json:"id"`
+	CustomID string          `json:"custom_id"`
+	Response *resultResponse `json:"response"`
+	Error    *resultError    `json:"error"`
+}
+
+type resultResponse struct {
+	StatusCode int             `json:"status_code"`
+	RequestID  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type resultError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// resultWriter appends results of a running batch to partial output and error files kept next
+// to the batch, so a batch interrupted by a restart resumes without repeating finished requests.
+type resultWriter struct {
+	store *Store
+	id    string
+	mu    sync.Mutex
+	files map[bool]*os.File
+}
+
+func newResultWriter(store *Store, id string) *resultWriter {
+	return &resultWriter{store: store, id: id, files: make(map[bool]*os.File)}
+}
+
+func (w *resultWriter) path(failed bool) string {
+	if failed {
+		return filepath.Join(w.store.batchesDir(), w.id+".errors.jsonl")
+	}
+	return filepath.Join(w.store.batchesDir(), w.id+".output.jsonl")
+}
+
+// completed returns the custom IDs already recorded and the matching request counts.
+func (w *resultWriter) completed() (map[string]bool, RequestCounts) {
+	done := make(map[string]bool)
+	var counts RequestCounts
+	for _, failed := range []bool{false, true} {
+		f, err := os.Open(w.path(failed))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+		for scanner.Scan() {
+			var line resultLine
+			if json.Unmarshal(scanner.Bytes(), &line) != nil || line.CustomID == "" || done[line.CustomID] {
+				continue
+			}
+			done[line.CustomID] = true
+			if failed {
+				counts.Failed++
+			} else {
+				counts.Completed++
+			}
+		}
+		_ = f.Close()
+	}
+	return done, counts
+}
+
+// write appends line to the output file, or to the error file when the request failed.
+func (w *resultWriter) write(line resultLine, ok bool) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	f := w.files[!ok]
+	if f == nil {
+		if err = os.MkdirAll(w.store.batchesDir(), 0o700); err != nil {
+			return err
+		}
+		f, err = os.OpenFile(w.path(!ok), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		w.files[!ok] = f
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func (w *resultWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for failed, f := range w.files {
+		_ = f.Close()
+		delete(w.files, failed)
+	}
+}
+
+// publish turns the partial output (or error) file into a batch_output file owned by owner and
+// returns its ID, or an empty ID when there are no such results.
+func (w *resultWriter) publish(owner string, failed bool, createdAt int64) (string, error) {
+	w.close()
+	path := w.path(failed)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		_ = os.Remove(path)
+		return "", nil
+	}
+	name := w.id + "_output.jsonl"
+	if failed {
+		name = w.id + "_error.jsonl"
+	}
+	file, err := w.store.CreateFile(owner, name, PurposeBatchOutput, content, createdAt)
+	if err != nil {
+		return "", fmt.Errorf("store %s: %w", name, err)
+	}
+	_ = os.Remove(path)
+	return file.ID, nil
+}
@@ -0,0 +1,725 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	defaultDir           = "batches"
+	defaultConcurrency   = 2
+	defaultMaxRequests   = 50000
+	defaultMaxFileSizeMB = 200
+
+	// completionWindow is the only completion window supported by the OpenAI Batch API.
+	completionWindow = "24h"
+
+	// maxAttempts bounds how often one request is retried while upstreams are rate limited.
+	maxAttempts = 6
+	maxBackoff  = time.Minute
+	// saveInterval throttles progress writes of running batches.
+	saveInterval = time.Second
+	// maxValidationErrors caps the validation errors reported for one input file.
+	maxValidationErrors = 100
+)
+
+// supportedEndpoints lists the endpoints batch requests may target.
+var supportedEndpoints = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	"/v1/responses":        true,
+}
+
+// requestLine is one line of a batch input file.
+type requestLine struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// settings is the active batch configuration.
+type settings struct {
+	store        *Store
+	concurrency  int
+	maxRequests  int
+	maxFileBytes int64
+	// keys maps hashed owners to the configured client API keys used to replay their requests.
+	keys map[string]string
+}
+
+// job is a batch being executed. Its batch is the authoritative state while the job runs.
+type job struct {
+	mu         sync.Mutex
+	batch      *storedBatch
+	cancelled  atomic.Bool
+	stop       context.CancelFunc
+	pauseUntil atomic.Int64
+	lastSave   time.Time
+}
+
+func (j *job) snapshot() Batch {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	b := j.batch.Batch
+	if b.Metadata != nil {
+		meta := make(map[string]string, len(b.Metadata))
+		for k, v := range b.Metadata {
+			meta[k] = v
+		}
+		b.Metadata = meta
+	}
+	return b
+}
+
+// Manager runs batches by replaying their requests through handler, normally the proxy's own
+// gin engine.
+type Manager struct {
+	handler http.Handler
+	state   atomic.Pointer[settings]
+	mu      sync.Mutex
+	jobs    map[string]*job
+	now     func() time.Time
+}
+
+// NewManager returns a manager executing batch requests through handler.
+func NewManager(handler http.Handler) *Manager {
+	return &Manager{handler: handler, jobs: make(map[string]*job), now: time.Now}
+}
+
+// Configure applies the batch settings from cfg. Enabling batches, or moving the store, resumes
+// every unfinished batch found in the store; disabling them pauses running batches.
+func (m *Manager) Configure(cfg *config.Config) {
+	if m == nil {
+		return
+	}
+	if cfg == nil || !cfg.Batch.Enabled {
+		m.state.Store(nil)
+		m.stopAll()
+		return
+	}
+	next := &settings{
+		store:        NewStore(resolveDir(cfg)),
+		concurrency:  cfg.Batch.Concurrency,
+		maxRequests:  cfg.Batch.MaxRequests,
+		maxFileBytes: int64(cfg.Batch.MaxFileSizeMB) << 20,
+		keys:         ownerKeys(cfg),
+	}
+	if next.concurrency <= 0 {
+		next.concurrency = defaultConcurrency
+	}
+	if next.maxRequests <= 0 {
+		next.maxRequests = defaultMaxRequests
+	}
+	if next.maxFileBytes <= 0 {
+		next.maxFileBytes = defaultMaxFileSizeMB << 20
+	}
+	prev := m.state.Swap(next)
+	if prev != nil && prev.store.Dir() == next.store.Dir() {
+		return
+	}
+	m.stopAll()
+	m.resume(next)
+}
+
+// ownerKeys indexes the configured client API keys by the owner hash batches are stored under.
+func ownerKeys(cfg *config.Config) map[string]string {
+	keys := make(map[string]string)
+	add := func(list []string) {
+		for _, key := range list {
+			if key = strings.TrimSpace(key); key != "" {
+				keys[util.HashAPIKey(key)] = key
+			}
+		}
+	}
+	add(cfg.APIKeys)
+	for _, provider := range cfg.Access.Providers {
+		add(provider.APIKeys)
+	}
+	return keys
+}
+
+// Enabled reports whether the Batch API is enabled.
+func (m *Manager) Enabled() bool { return m != nil && m.state.Load() != nil }
+
+func resolveDir(cfg *config.Config) string {
+	if dir := strings.TrimSpace(cfg.Batch.Dir); dir != "" {
+		return dir
+	}
+	if base := util.WritablePath(); base != "" {
+		return filepath.Join(base, defaultDir)
+	}
+	if authDir, err := util.ResolveAuthDir(cfg.AuthDir); err == nil && authDir != "" {
+		return filepath.Join(authDir, defaultDir)
+	}
+	return defaultDir
+}
+
+func (m *Manager) stopAll() {
+	m.mu.Lock()
+	jobs := m.jobs
+	m.jobs = make(map[string]*job)
+	m.mu.Unlock()
+	for _, j := range jobs {
+		j.stop()
+	}
+}
+
+// resume restarts the unfinished batches of the store.
+func (m *Manager) resume(st *settings) {
+	batches, err := st.store.listStoredBatches()
+	if err != nil {
+		log.Errorf("batch: list batches in %s: %v", st.store.Dir(), err)
+		return
+	}
+	for _, b := range batches {
+		switch b.Status {
+		case StatusValidating, StatusInProgress, StatusFinalizing, StatusCancelling:
+			log.Infof("batch: resuming %s (%s)", b.ID, b.Status)
+			m.start(st, b)
+		}
+	}
+}
+
+func (m *Manager) start(st *settings, b *storedBatch) {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{batch: b, stop: cancel}
+	if b.Status == StatusCancelling {
+		j.cancelled.Store(true)
+	}
+	m.mu.Lock()
+	m.jobs[b.ID] = j
+	m.mu.Unlock()
+	go func() {
+		defer func() {
+			cancel()
+			m.mu.Lock()
+			if m.jobs[b.ID] == j {
+				delete(m.jobs, b.ID)
+			}
+			m.mu.Unlock()
+		}()
+		m.run(ctx, st, j)
+	}()
+}
+
+func (m *Manager) job(id string) *job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jobs[id]
+}
+
+func (m *Manager) unix() int64 { return m.now().Unix() }
+
+func int64Ptr(v int64) *int64 { return &v }
+
+// Create queues a batch over the input file inputFileID. Its requests are executed with the
+// owner's API key.
+func (m *Manager) Create(owner, inputFileID, endpoint, window string, metadata map[string]string) (Batch, error) {
+	st := m.state.Load()
+	if st == nil {
+		return Batch{}, errDisabled
+	}
+	if !supportedEndpoints[endpoint] {
+		return Batch{}, invalidParam("endpoint", fmt.Sprintf("unsupported endpoint %q, expected /v1/chat/completions, /v1/completions or /v1/responses", endpoint))
+	}
+	if window != completionWindow {
+		return Batch{}, invalidParam("completion_window", "completion_window must be 24h")
+	}
+	file, err := st.store.GetFile(owner, inputFileID)
+	if err != nil {
+		return Batch{}, invalidParam("input_file_id", fmt.Sprintf("no such file: %s", inputFileID))
+	}
+	if file.Purpose != PurposeBatch {
+		return Batch{}, invalidParam("input_file_id", "input file must have purpose batch")
+	}
+	if _, ok := st.replayKey(owner); !ok {
+		return Batch{}, invalidParam("", "batches require a client API key from the configuration")
+	}
+	now := m.unix()
+	b := &storedBatch{
+		Batch: Batch{
+			ID:               newID("batch_"),
+			Object:           "batch",
+			Endpoint:         endpoint,
+			InputFileID:      inputFileID,
+			CompletionWindow: window,
+			Status:           StatusValidating,
+			CreatedAt:        now,
+			ExpiresAt:        now + int64((24 * time.Hour).Seconds()),
+			Metadata:         metadata,
+		},
+		Owner: owner,
+	}
+	if err = st.store.saveBatch(b); err != nil {
+		return Batch{}, err
+	}
+	created := b.Batch
+	m.start(st, b)
+	return created, nil
+}
+
+// Get returns batch id of owner, reflecting live progress while it runs.
+func (m *Manager) Get(owner, id string) (Batch, error) {
+	st := m.state.Load()
+	if st == nil {
+		return Batch{}, errDisabled
+	}
+	if j := m.job(id); j != nil {
+		b := j.snapshot()
+		j.mu.Lock()
+		batchOwner := j.batch.Owner
+		j.mu.Unlock()
+		if batchOwner != owner {
+			return Batch{}, ErrNotFound
+		}
+		return b, nil
+	}
+	return st.store.GetBatch(owner, id)
+}
+
+// List returns the batches of owner, newest first.
+func (m *Manager) List(owner string) ([]Batch, error) {
+	st := m.state.Load()
+	if st == nil {
+		return nil, errDisabled
+	}
+	batches, err := st.store.ListBatches(owner)
+	if err != nil {
+		return nil, err
+	}
+	for i := range batches {
+		if j := m.job(batches[i].ID); j != nil {
+			batches[i] = j.snapshot()
+		}
+	}
+	return batches, nil
+}
+
+// Cancel stops dispatching the requests of batch id. Requests in flight finish and their results
+// are kept; the batch then moves from cancelling to cancelled.
+func (m *Manager) Cancel(owner, id string) (Batch, error) {
+	st := m.state.Load()
+	if st == nil {
+		return Batch{}, errDisabled
+	}
+	if j := m.job(id); j != nil {
+		j.mu.Lock()
+		if j.batch.Owner != owner {
+			j.mu.Unlock()
+			return Batch{}, ErrNotFound
+		}
+		switch j.batch.Status {
+		case StatusValidating, StatusInProgress:
+			j.batch.Status = StatusCancelling
+			j.batch.CancellingAt = int64Ptr(m.unix())
+			j.cancelled.Store(true)
+			if err := st.store.saveBatch(j.batch); err != nil {
+				log.Errorf("batch %s: save: %v", id, err)
+			}
+		}
+		j.mu.Unlock()
+		return j.snapshot(), nil
+	}
+	b, err := st.store.loadBatch(id)
+	if err != nil || b.Owner != owner {
+		return Batch{}, ErrNotFound
+	}
+	switch b.Status {
+	case StatusValidating, StatusInProgress, StatusCancelling:
+		now := m.unix()
+		b.Status = StatusCancelled
+		if b.CancellingAt == nil {
+			b.CancellingAt = int64Ptr(now)
+		}
+		b.CancelledAt = int64Ptr(now)
+		if err = st.store.saveBatch(b); err != nil {
+			return Batch{}, err
+		}
+	}
+	return b.Batch, nil
+}
+
+// update applies fn to the job's batch and persists it. Unless force is set, writes are
+// throttled to one per saveInterval.
+func (m *Manager) update(st *settings, j *job, force bool, fn func(b *storedBatch)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(j.batch)
+	now := m.now()
+	if !force && now.Sub(j.lastSave) < saveInterval {
+		return
+	}
+	j.lastSave = now
+	if err := st.store.saveBatch(j.batch); err != nil {
+		log.Errorf("batch %s: save: %v", j.batch.ID, err)
+	}
+}
+
+func (m *Manager) run(ctx context.Context, st *settings, j *job) {
+	j.mu.Lock()
+	id := j.batch.ID
+	endpoint := j.batch.Endpoint
+	inputFileID := j.batch.InputFileID
+	status := j.batch.Status
+	j.mu.Unlock()
+
+	lines, validationErrs, err := readInput(st.store.FilePath(inputFileID), endpoint, st.maxRequests)
+	if err != nil {
+		validationErrs = []BatchError{{Code: "invalid_file", Message: err.Error()}}
+	}
+	if len(validationErrs) > 0 {
+		m.update(st, j, true, func(b *storedBatch) {
+			b.Status = StatusFailed
+			b.FailedAt = int64Ptr(m.unix())
+			b.Errors = &BatchErrors{Object: "list", Data: validationErrs}
+		})
+		log.Infof("batch %s: input file rejected with %d error(s)", id, len(validationErrs))
+		return
+	}
+	if status == StatusValidating {
+		m.update(st, j, true, func(b *storedBatch) {
+			b.Status = StatusInProgress
+			b.InProgressAt = int64Ptr(m.unix())
+			b.RequestCounts = RequestCounts{Total: len(lines)}
+		})
+	}
+
+	results := newResultWriter(st.store, id)
+	defer results.close()
+	done, counts := results.completed()
+	m.update(st, j, true, func(b *storedBatch) {
+		b.RequestCounts = RequestCounts{Total: len(lines), Completed: counts.Completed, Failed: counts.Failed}
+	})
+
+	pending := make(chan requestLine)
+	var wg sync.WaitGroup
+	for i := 0; i < st.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range pending {
+				entry, ok := m.execute(ctx, j, line)
+				if ctx.Err() != nil {
+					return
+				}
+				if errWrite := results.write(entry, ok); errWrite != nil {
+					log.Errorf("batch %s: write result: %v", id, errWrite)
+				}
+				m.update(st, j, false, func(b *storedBatch) {
+					if ok {
+						b.RequestCounts.Completed++
+					} else {
+						b.RequestCounts.Failed++
+					}
+				})
+			}
+		}()
+	}
+
+	expired := false
+dispatch:
+	for _, line := range lines {
+		if done[line.CustomID] {
+			continue
+		}
+		if j.cancelled.Load() {
+			break
+		}
+		j.mu.Lock()
+		expiresAt := j.batch.ExpiresAt
+		j.mu.Unlock()
+		if m.unix() >= expiresAt {
+			expired = true
+			break
+		}
+		select {
+		case pending <- line:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(pending)
+	wg.Wait()
+	if ctx.Err() != nil {
+		// Stopped by Configure; the batch resumes from its recorded results.
+		m.update(st, j, true, func(*storedBatch) {})
+		return
+	}
+	m.finalize(st, j, results, expired)
+}
+
+// finalize publishes the result files and moves the batch to its terminal status.
+func (m *Manager) finalize(st *settings, j *job, results *resultWriter, expired bool) {
+	m.update(st, j, true, func(b *storedBatch) {
+		if b.Status == StatusInProgress {
+			b.Status = StatusFinalizing
+			b.FinalizingAt = int64Ptr(m.unix())
+		}
+	})
+	j.mu.Lock()
+	owner := j.batch.Owner
+	id := j.batch.ID
+	j.mu.Unlock()
+
+	outputID, errOut := results.publish(owner, false, m.unix())
+	errorID, errErr := results.publish(owner, true, m.unix())
+	if errOut != nil || errErr != nil {
+		log.Errorf("batch %s: publish results: %v", id, errors.Join(errOut, errErr))
+	}
+	m.update(st, j, true, func(b *storedBatch) {
+		now := m.unix()
+		if outputID != "" {
+			b.OutputFileID = strPtr(outputID)
+		}
+		if errorID != "" {
+			b.ErrorFileID = strPtr(errorID)
+		}
+		switch {
+		case j.cancelled.Load():
+			b.Status = StatusCancelled
+			b.CancelledAt = int64Ptr(now)
+		case expired:
+			b.Status = StatusExpired
+			b.ExpiredAt = int64Ptr(now)
+		default:
+			b.Status = StatusCompleted
+			b.CompletedAt = int64Ptr(now)
+		}
+	})
+	log.Infof("batch %s: %s", id, j.snapshot().Status)
+}
+
+func strPtr(v string) *string { return &v }
+
+// readInput parses and validates a batch input file.
+func readInput(path, endpoint string, maxRequests int) ([]requestLine, []BatchError, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read input file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var (
+		lines []requestLine
+		errs  []BatchError
+		seen  = make(map[string]bool)
+	)
+	addErr := func(code, message string, line int) {
+		if len(errs) < maxValidationErrors {
+			errs = append(errs, BatchError{Code: code, Message: message, Line: line})
+		}
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var line requestLine
+		if errJSON := json.Unmarshal(raw, &line); errJSON != nil {
+			addErr("invalid_json_line", "line is not a valid JSON object", lineNo)
+			continue
+		}
+		switch {
+		case strings.TrimSpace(line.CustomID) == "":
+			addErr("missing_required_parameter", "custom_id is required", lineNo)
+		case seen[line.CustomID]:
+			addErr("duplicate_custom_id", fmt.Sprintf("custom_id %q is not unique", line.CustomID), lineNo)
+		case !strings.EqualFold(line.Method, http.MethodPost):
+			addErr("invalid_method", "method must be POST", lineNo)
+		case line.URL != endpoint:
+			addErr("invalid_url", fmt.Sprintf("url must match the batch endpoint %s", endpoint), lineNo)
+		case !gjson.ParseBytes(line.Body).IsObject():
+			addErr("missing_required_parameter", "body must be a JSON object", lineNo)
+		default:
+			seen[line.CustomID] = true
+			lines = append(lines, line)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("read input file: %w", err)
+	}
+	if len(errs) == 0 && len(lines) == 0 {
+		addErr("empty_file", "the input file contains no requests", 0)
+	}
+	if len(lines) > maxRequests {
+		addErr("too_many_tasks", fmt.Sprintf("the input file contains %d requests, the limit is %d", len(lines), maxRequests), 0)
+	}
+	return lines, errs, nil
+}
+
+// execute runs one batch request, waiting out upstream rate limits. It returns the result line
+// and whether the request succeeded.
+func (m *Manager) execute(ctx context.Context, j *job, line requestLine) (resultLine, bool) {
+	j.mu.Lock()
+	owner := j.batch.Owner
+	j.mu.Unlock()
+
+	body := []byte(line.Body)
+	if gjson.GetBytes(body, "stream").Bool() {
+		if updated, err := sjson.SetBytes(body, "stream", false); err == nil {
+			body = updated
+		}
+	}
+	result := resultLine{ID: newID("batch_req_"), CustomID: line.CustomID}
+	for attempt := 1; ; attempt++ {
+		if err := j.waitPause(ctx, m.now); err != nil {
+			result.Error = &resultError{Code: "batch_cancelled", Message: err.Error()}
+			return result, false
+		}
+		st := m.state.Load()
+		if st == nil {
+			result.Error = &resultError{Code: "batch_cancelled", Message: "batches were disabled"}
+			return result, false
+		}
+		apiKey, ok := st.replayKey(owner)
+		if !ok {
+			result.Error = &resultError{Code: "invalid_api_key", Message: "the API key that created this batch is no longer configured"}
+			return result, false
+		}
+		rec := m.replay(ctx, line.URL, body, apiKey)
+		if rateLimited(rec.status) && attempt < maxAttempts {
+			j.pause(m.now().Add(retryDelay(rec.header, attempt)))
+			continue
+		}
+		requestID := rec.header.Get("X-Request-Id")
+		if requestID == "" {
+			requestID = newID("req_")
+		}
+		respBody := json.RawMessage(rec.body.Bytes())
+		if !json.Valid(respBody) {
+			quoted, _ := json.Marshal(rec.body.String())
+			respBody = quoted
+		}
+		result.Response = &resultResponse{StatusCode: rec.status, RequestID: requestID, Body: respBody}
+		return result, rec.status >= 200 && rec.status < 300
+	}
+}
+
+// replayKey returns the API key that executes the requests of owner. Batches created without
+// authentication (no client keys configured) replay without one.
+func (st *settings) replayKey(owner string) (string, bool) {
+	if owner == "" {
+		return "", true
+	}
+	key, ok := st.keys[owner]
+	return key, ok
+}
+
+// replay serves one request through the manager's handler.
+func (m *Manager) replay(ctx context.Context, url string, body []byte, apiKey string) *responseRecorder {
+	rec := newResponseRecorder()
+	req, err := http.NewRequestWithContext(cliproxyexecutor.WithBatchRequest(ctx), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		rec.WriteHeader(http.StatusInternalServerError)
+		_, _ = rec.Write([]byte(fmt.Sprintf(`{"error":{"message":%q,"type":"server_error"}}`, err.Error())))
+		return rec
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	req.RemoteAddr = "127.0.0.1:0"
+	m.handler.ServeHTTP(rec, req)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec
+}
+
+func rateLimited(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable || status == 529
+}
+
+// retryDelay honours Retry-After and otherwise backs off exponentially from one second.
+func retryDelay(header http.Header, attempt int) time.Duration {
+	if secs, err := strconv.Atoi(strings.TrimSpace(header.Get("Retry-After"))); err == nil && secs > 0 {
+		if d := time.Duration(secs) * time.Second; d < maxBackoff {
+			return d
+		}
+		return maxBackoff
+	}
+	d := time.Second << (attempt - 1)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	return d
+}
+
+// pause holds back every worker of the job until until, so a rate-limited upstream is not hit
+// by the other workers in the meantime.
+func (j *job) pause(until time.Time) {
+	for {
+		current := j.pauseUntil.Load()
+		if until.UnixNano() <= current || j.pauseUntil.CompareAndSwap(current, until.UnixNano()) {
+			return
+		}
+	}
+}
+
+func (j *job) waitPause(ctx context.Context, now func() time.Time) error {
+	for {
+		wait := time.Duration(j.pauseUntil.Load() - now().UnixNano())
+		if wait <= 0 {
+			return ctx.Err()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// responseRecorder buffers a response produced by the replay handler.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: make(http.Header)}
+}
+
+func (r *responseRecorder) Header() http.Header { return r.header }
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(p)
+}
+
+func (r *responseRecorder) Flush() {}
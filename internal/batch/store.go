@@ -0,0 +1,318 @@
+// Package batch implements the OpenAI Batch API (/v1/files and /v1/batches) on top of a local
+// job store. Uploaded JSONL files and batch jobs are kept as files on disk; batch requests are
+// replayed through the proxy's own HTTP handler so authentication, model access, quotas, usage
+// and request logging apply exactly as for live requests.
+package batch
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Batch statuses as defined by the OpenAI Batch API.
+const (
+	StatusValidating = "validating"
+	StatusFailed     = "failed"
+	StatusInProgress = "in_progress"
+	StatusFinalizing = "finalizing"
+	StatusCompleted  = "completed"
+	StatusExpired    = "expired"
+	StatusCancelling = "cancelling"
+	StatusCancelled  = "cancelled"
+)
+
+// File purposes.
+const (
+	PurposeBatch       = "batch"
+	PurposeBatchOutput = "batch_output"
+)
+
+// ErrNotFound is returned when a file or batch does not exist or belongs to another API key.
+var ErrNotFound = errors.New("not found")
+
+// File is the OpenAI file object. Owner is never serialized to clients.
+type File struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Owner     string `json:"-"`
+}
+
+// storedFile is the on-disk form of File, which keeps the owner.
+type storedFile struct {
+	File
+	Owner string `json:"owner,omitempty"`
+}
+
+// RequestCounts reports batch progress.
+type RequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// BatchError describes a validation error of a batch input file.
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// BatchErrors is the list object holding validation errors.
+type BatchErrors struct {
+	Object string       `json:"object"`
+	Data   []BatchError `json:"data"`
+}
+
+// Batch is the OpenAI batch object.
+type Batch struct {
+	ID               string            `json:"id"`
+	Object           string            `json:"object"`
+	Endpoint         string            `json:"endpoint"`
+	Errors           *BatchErrors      `json:"errors"`
+	InputFileID      string            `json:"input_file_id"`
+	CompletionWindow string            `json:"completion_window"`
+	Status           string            `json:"status"`
+	OutputFileID     *string           `json:"output_file_id"`
+	ErrorFileID      *string           `json:"error_file_id"`
+	CreatedAt        int64             `json:"created_at"`
+	InProgressAt     *int64            `json:"in_progress_at"`
+	ExpiresAt        int64             `json:"expires_at"`
+	FinalizingAt     *int64            `json:"finalizing_at"`
+	CompletedAt      *int64            `json:"completed_at"`
+	FailedAt         *int64            `json:"failed_at"`
+	ExpiredAt        *int64            `json:"expired_at"`
+	CancellingAt     *int64            `json:"cancelling_at"`
+	CancelledAt      *int64            `json:"cancelled_at"`
+	RequestCounts    RequestCounts     `json:"request_counts"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+// storedBatch is the on-disk form of Batch, which keeps the owner. Only the hashed owner is
+// stored; the API key used to replay the batch's requests is looked up from the configuration
+// when they run, so no credential is written to disk and removing a key stops its batches.
+type storedBatch struct {
+	Batch
+	Owner string `json:"owner,omitempty"`
+}
+
+// Store persists files and batches under a directory.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore returns a store rooted at dir.
+func NewStore(dir string) *Store { return &Store{dir: dir} }
+
+// Dir returns the directory of the store.
+func (s *Store) Dir() string { return s.dir }
+
+func (s *Store) filesDir() string   { return filepath.Join(s.dir, "files") }
+func (s *Store) batchesDir() string { return filepath.Join(s.dir, "batches") }
+
+// FilePath returns the path of the content of file id.
+func (s *Store) FilePath(id string) string { return filepath.Join(s.filesDir(), id+".jsonl") }
+
+func (s *Store) fileMetaPath(id string) string { return filepath.Join(s.filesDir(), id+".json") }
+func (s *Store) batchPath(id string) string    { return filepath.Join(s.batchesDir(), id+".json") }
+
+// newID returns prefix followed by 24 random hex characters.
+func newID(prefix string) string {
+	buf := make([]byte, 12)
+	_, _ = rand.Read(buf)
+	return prefix + hex.EncodeToString(buf)
+}
+
+// validID guards file system paths built from client supplied identifiers.
+func validID(id, prefix string) bool {
+	if !strings.HasPrefix(id, prefix) || len(id) > 64 {
+		return false
+	}
+	for _, r := range id[len(prefix):] {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return len(id) > len(prefix)
+}
+
+func writeJSONFile(path string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// CreateFile stores content as a new file owned by owner.
+func (s *Store) CreateFile(owner, filename, purpose string, content []byte, createdAt int64) (File, error) {
+	file := File{
+		ID:        newID("file-"),
+		Object:    "file",
+		Bytes:     int64(len(content)),
+		CreatedAt: createdAt,
+		Filename:  filename,
+		Purpose:   purpose,
+		Owner:     owner,
+	}
+	if err := os.MkdirAll(s.filesDir(), 0o700); err != nil {
+		return File{}, err
+	}
+	if err := os.WriteFile(s.FilePath(file.ID), content, 0o600); err != nil {
+		return File{}, err
+	}
+	if err := writeJSONFile(s.fileMetaPath(file.ID), storedFile{File: file, Owner: owner}); err != nil {
+		_ = os.Remove(s.FilePath(file.ID))
+		return File{}, err
+	}
+	return file, nil
+}
+
+// GetFile returns file id when it belongs to owner.
+func (s *Store) GetFile(owner, id string) (File, error) {
+	if !validID(id, "file-") {
+		return File{}, ErrNotFound
+	}
+	data, err := os.ReadFile(s.fileMetaPath(id))
+	if err != nil {
+		return File{}, ErrNotFound
+	}
+	var stored storedFile
+	if err = json.Unmarshal(data, &stored); err != nil || stored.Owner != owner {
+		return File{}, ErrNotFound
+	}
+	stored.File.Owner = stored.Owner
+	return stored.File, nil
+}
+
+// ListFiles returns the files of owner, newest first.
+func (s *Store) ListFiles(owner string) ([]File, error) {
+	entries, err := os.ReadDir(s.filesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []File{}, nil
+		}
+		return nil, err
+	}
+	files := make([]File, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if file, errGet := s.GetFile(owner, strings.TrimSuffix(name, ".json")); errGet == nil {
+			files = append(files, file)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].CreatedAt > files[j].CreatedAt })
+	return files, nil
+}
+
+// DeleteFile removes file id when it belongs to owner.
+func (s *Store) DeleteFile(owner, id string) error {
+	if _, err := s.GetFile(owner, id); err != nil {
+		return err
+	}
+	if err := os.Remove(s.fileMetaPath(id)); err != nil {
+		return err
+	}
+	_ = os.Remove(s.FilePath(id))
+	return nil
+}
+
+// saveBatch writes b to disk.
+func (s *Store) saveBatch(b *storedBatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeJSONFile(s.batchPath(b.ID), b)
+}
+
+// loadBatch reads batch id regardless of owner.
+func (s *Store) loadBatch(id string) (*storedBatch, error) {
+	if !validID(id, "batch_") {
+		return nil, ErrNotFound
+	}
+	s.mu.Lock()
+	data, err := os.ReadFile(s.batchPath(id))
+	s.mu.Unlock()
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var stored storedBatch
+	if err = json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("batch %s: %w", id, err)
+	}
+	return &stored, nil
+}
+
+// GetBatch returns batch id when it belongs to owner.
+func (s *Store) GetBatch(owner, id string) (Batch, error) {
+	stored, err := s.loadBatch(id)
+	if err != nil || stored.Owner != owner {
+		return Batch{}, ErrNotFound
+	}
+	return stored.Batch, nil
+}
+
+// listStoredBatches returns every stored batch.
+func (s *Store) listStoredBatches() ([]*storedBatch, error) {
+	entries, err := os.ReadDir(s.batchesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	batches := make([]*storedBatch, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if stored, errLoad := s.loadBatch(strings.TrimSuffix(name, ".json")); errLoad == nil {
+			batches = append(batches, stored)
+		}
+	}
+	return batches, nil
+}
+
+// ListBatches returns the batches of owner, newest first.
+func (s *Store) ListBatches(owner string) ([]Batch, error) {
+	stored, err := s.listStoredBatches()
+	if err != nil {
+		return nil, err
+	}
+	batches := make([]Batch, 0, len(stored))
+	for _, b := range stored {
+		if b.Owner == owner {
+			batches = append(batches, b.Batch)
+		}
+	}
+	sort.SliceStable(batches, func(i, j int) bool {
+		if batches[i].CreatedAt != batches[j].CreatedAt {
+			return batches[i].CreatedAt > batches[j].CreatedAt
+		}
+		return batches[i].ID > batches[j].ID
+	})
+	return batches, nil
+}
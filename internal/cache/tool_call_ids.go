@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"crypto/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ToolCallIDTTL is how long a generated tool-call ID stays resolvable after its last use.
+	ToolCallIDTTL = 3 * time.Hour
+
+	// toolCallIDRandomLen is the number of random characters after the client prefix.
+	toolCallIDRandomLen = 24
+
+	toolCallIDAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// ToolCallMapping is what the proxy remembers about a tool-call ID it handed to a client.
+type ToolCallMapping struct {
+	// Name is the function the upstream called.
+	Name string
+	// UpstreamID is the ID the upstream assigned to the call, if any, to be sent back with the
+	// function response.
+	UpstreamID string
+
+	lastUsed time.Time
+}
+
+var (
+	toolCallIDs           sync.Map // client tool-call ID -> *ToolCallMapping
+	toolCallCleanupOnce   sync.Once
+	toolCallMappingsMutex sync.Mutex
+)
+
+// NewToolCallID returns a fresh tool-call ID for a call to name, made of prefix (toolu_ for
+// Claude clients, call_ for OpenAI clients) and random characters, and remembers name and the
+// upstream's own call ID so both can be restored when the client echoes the ID back.
+func NewToolCallID(prefix, name, upstreamID string) string {
+	toolCallCleanupOnce.Do(startToolCallCleanup)
+	id := prefix + randomToolCallSuffix()
+	toolCallIDs.Store(id, &ToolCallMapping{Name: name, UpstreamID: upstreamID, lastUsed: time.Now()})
+	return id
+}
+
+// LookupToolCallID returns the mapping of a tool-call ID created by NewToolCallID. Lookups
+// extend the mapping's lifetime, so IDs stay resolvable for as long as a conversation is active.
+func LookupToolCallID(id string) (ToolCallMapping, bool) {
+	value, ok := toolCallIDs.Load(id)
+	if !ok {
+		return ToolCallMapping{}, false
+	}
+	mapping := value.(*ToolCallMapping)
+	now := time.Now()
+	toolCallMappingsMutex.Lock()
+	defer toolCallMappingsMutex.Unlock()
+	if now.Sub(mapping.lastUsed) > ToolCallIDTTL {
+		toolCallIDs.Delete(id)
+		return ToolCallMapping{}, false
+	}
+	mapping.lastUsed = now
+	return *mapping, true
+}
+
+// ToolCallName returns the function name for a tool-call ID echoed by a client. IDs unknown to
+// the registry (for example issued before a restart) fall back to known, the names declared by
+// the conversation's own tool calls, and then to the legacy name-<nanos>-<counter> format.
+func ToolCallName(id string, known map[string]string) string {
+	if mapping, ok := LookupToolCallID(id); ok && mapping.Name != "" {
+		return mapping.Name
+	}
+	if name := known[id]; name != "" {
+		return name
+	}
+	parts := strings.Split(id, "-")
+	switch {
+	case len(parts) > 2 && isDigits(parts[len(parts)-1]) && isDigits(parts[len(parts)-2]):
+		return strings.Join(parts[:len(parts)-2], "-")
+	case len(parts) > 1:
+		return strings.Join(parts[:len(parts)-1], "-")
+	default:
+		return id
+	}
+}
+
+// UpstreamToolCallID returns the upstream's call ID for a client tool-call ID, or "" when the
+// upstream did not assign one.
+func UpstreamToolCallID(id string) string {
+	mapping, _ := LookupToolCallID(id)
+	return mapping.UpstreamID
+}
+
+// UpstreamToolCallIDOr returns the upstream's call ID for id, or id itself when none is known.
+func UpstreamToolCallIDOr(id string) string {
+	if upstreamID := UpstreamToolCallID(id); upstreamID != "" {
+		return upstreamID
+	}
+	return id
+}
+
+func randomToolCallSuffix() string {
+	buf := make([]byte, toolCallIDRandomLen)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	for i, b := range buf {
+		buf[i] = toolCallIDAlphabet[int(b)%len(toolCallIDAlphabet)]
+	}
+	return string(buf)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// startToolCallCleanup periodically drops tool-call mappings that outlived ToolCallIDTTL.
+func startToolCallCleanup() {
+	go func() {
+		ticker := time.NewTicker(CacheCleanupInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purgeExpiredToolCallIDs()
+		}
+	}()
+}
+
+func purgeExpiredToolCallIDs() {
+	now := time.Now()
+	toolCallMappingsMutex.Lock()
+	defer toolCallMappingsMutex.Unlock()
+	toolCallIDs.Range(func(key, value any) bool {
+		if now.Sub(value.(*ToolCallMapping).lastUsed) > ToolCallIDTTL {
+			toolCallIDs.Delete(key)
+		}
+		return true
+	})
+}
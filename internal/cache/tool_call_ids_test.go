@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewToolCallID_RoundTrip(t *testing.T) {
+	id := NewToolCallID("toolu_", "read_file", "upstream-1")
+	if !strings.HasPrefix(id, "toolu_") || len(id) != len("toolu_")+toolCallIDRandomLen {
+		t.Fatalf("id = %q", id)
+	}
+	if other := NewToolCallID("toolu_", "read_file", ""); other == id {
+		t.Fatal("expected unique IDs")
+	}
+	mapping, ok := LookupToolCallID(id)
+	if !ok || mapping.Name != "read_file" || mapping.UpstreamID != "upstream-1" {
+		t.Fatalf("mapping = %+v, %v", mapping, ok)
+	}
+	if got := ToolCallName(id, nil); got != "read_file" {
+		t.Fatalf("ToolCallName = %q, want read_file", got)
+	}
+	if got := UpstreamToolCallIDOr(id); got != "upstream-1" {
+		t.Fatalf("UpstreamToolCallIDOr = %q, want upstream-1", got)
+	}
+}
+
+func TestToolCallName_Fallbacks(t *testing.T) {
+	known := map[string]string{"toolu_unknown": "search"}
+	cases := map[string]string{
+		"toolu_unknown":                   "search",
+		"get-weather-1736000000000000-12": "get-weather",
+		"get_weather-call":                "get_weather",
+		"plain":                           "plain",
+	}
+	for id, want := range cases {
+		if got := ToolCallName(id, known); got != want {
+			t.Errorf("ToolCallName(%q) = %q, want %q", id, got, want)
+		}
+	}
+	if got := UpstreamToolCallIDOr("call_missing"); got != "call_missing" {
+		t.Errorf("UpstreamToolCallIDOr = %q, want call_missing", got)
+	}
+}
@@ -0,0 +1,240 @@
+// Package clientcert implements mutual TLS for the inbound listener. Client certificates are
+// verified against a configured CA bundle, checked against revocation lists and OCSP responders,
+// and mapped to the API keys they authenticate as, so clients inside zero-trust networks need no
+// bearer keys.
+package clientcert
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ProviderName identifies client certificates as the access provider of a request.
+	ProviderName = "client-certificate"
+
+	modeOptional = "optional"
+	modeRequire  = "require"
+)
+
+// settings is the active client certificate configuration.
+type settings struct {
+	roots      *x509.CertPool
+	crls       []*x509.RevocationList
+	ocsp       string
+	identities []config.ClientCertIdentity
+}
+
+var active atomic.Pointer[settings]
+
+// Configure loads the client-auth settings of cfg. Invalid settings are logged and disable
+// certificate authentication, so requests fall back to API keys.
+func Configure(cfg *config.Config) {
+	if cfg == nil || !enabled(cfg.TLS.ClientAuth.Mode) {
+		active.Store(nil)
+		return
+	}
+	next, err := load(cfg.TLS.ClientAuth)
+	if err != nil {
+		log.Errorf("tls client-auth: %v", err)
+		active.Store(nil)
+		return
+	}
+	active.Store(next)
+	log.Infof("tls client-auth: %d identity mapping(s), %d revocation list(s)", len(next.identities), len(next.crls))
+}
+
+func enabled(mode string) bool {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case modeOptional, modeRequire:
+		return true
+	default:
+		return false
+	}
+}
+
+func load(cfg config.TLSClientAuthConfig) (*settings, error) {
+	caPath := strings.TrimSpace(cfg.CA)
+	if caPath == "" {
+		return nil, errors.New("ca is required")
+	}
+	pem, err := os.ReadFile(caPath)
+	if err != nil {
+		return nil, fmt.Errorf("read ca bundle: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caPath)
+	}
+	next := &settings{roots: roots, ocsp: strings.ToLower(strings.TrimSpace(cfg.OCSP)), identities: cfg.Identities}
+	for _, path := range cfg.CRL {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		crl, errCRL := loadCRL(path)
+		if errCRL != nil {
+			return nil, errCRL
+		}
+		if !crl.NextUpdate.IsZero() && time.Now().After(crl.NextUpdate) {
+			log.Warnf("tls client-auth: revocation list %s is past its next update (%s)", path, crl.NextUpdate.Format(time.RFC3339))
+		}
+		next.crls = append(next.crls, crl)
+	}
+	return next, nil
+}
+
+// TLSConfig returns the server TLS settings for the client-auth mode, or nil when mTLS is off.
+// Certificates are requested during the handshake and verified against the settings active at
+// that moment, which keeps the CA bundle and revocation lists reloadable.
+func TLSConfig(cfg config.TLSClientAuthConfig) *tls.Config {
+	mode := strings.ToLower(strings.TrimSpace(cfg.Mode))
+	if !enabled(mode) {
+		return nil
+	}
+	tlsCfg := &tls.Config{ClientAuth: tls.RequestClientCert}
+	if mode == modeRequire {
+		tlsCfg.ClientAuth = tls.RequireAnyClientCert
+	}
+	tlsCfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return nil
+		}
+		certs := make([]*x509.Certificate, 0, len(rawCerts))
+		for _, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("parse client certificate: %w", err)
+			}
+			certs = append(certs, cert)
+		}
+		st := active.Load()
+		if st == nil {
+			return errors.New("client certificate authentication is not configured")
+		}
+		return st.verify(certs)
+	}
+	return tlsCfg
+}
+
+// verify checks the chain of the presented certificates and their revocation status.
+func (s *settings) verify(certs []*x509.Certificate) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	chains, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         s.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("verify client certificate: %w", err)
+	}
+	chain := chains[0]
+	for i := 0; i+1 < len(chain); i++ {
+		if err = s.checkRevocation(chain[i], chain[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Authenticate maps the verified client certificate of r to its API key. Requests without a
+// certificate, or with one that matches no identity, are left to the other access providers.
+func Authenticate(r *http.Request) (*sdkaccess.Result, error) {
+	st := active.Load()
+	if st == nil || r == nil || r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, sdkaccess.ErrNotHandled
+	}
+	// The handshake may predate the current settings, so the chain is verified again.
+	if err := st.verify(r.TLS.PeerCertificates); err != nil {
+		log.Debugf("tls client-auth: %v", err)
+		return nil, sdkaccess.ErrNotHandled
+	}
+	leaf := r.TLS.PeerCertificates[0]
+	for _, identity := range st.identities {
+		if strings.TrimSpace(identity.APIKey) == "" || !matches(identity, leaf) {
+			continue
+		}
+		return &sdkaccess.Result{
+			Provider:  ProviderName,
+			Principal: identity.APIKey,
+			Metadata: map[string]string{
+				"source":  "client-certificate",
+				"subject": leaf.Subject.CommonName,
+			},
+		}, nil
+	}
+	return nil, sdkaccess.ErrNotHandled
+}
+
+// matches reports whether every matcher set on identity matches cert. Identities without any
+// matcher match nothing.
+func matches(identity config.ClientCertIdentity, cert *x509.Certificate) bool {
+	matched := false
+	if cn := strings.TrimSpace(identity.CommonName); cn != "" {
+		if cert.Subject.CommonName != cn {
+			return false
+		}
+		matched = true
+	}
+	if name := strings.TrimSpace(identity.DNSName); name != "" {
+		if !containsFold(cert.DNSNames, name) {
+			return false
+		}
+		matched = true
+	}
+	if email := strings.TrimSpace(identity.Email); email != "" {
+		if !containsFold(cert.EmailAddresses, email) {
+			return false
+		}
+		matched = true
+	}
+	if uri := strings.TrimSpace(identity.URI); uri != "" {
+		found := false
+		for _, u := range cert.URIs {
+			if u.String() == uri {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+		matched = true
+	}
+	if fingerprint := normalizeFingerprint(identity.Fingerprint); fingerprint != "" {
+		sum := sha256.Sum256(cert.Raw)
+		if hex.EncodeToString(sum[:]) != fingerprint {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+func containsFold(values []string, want string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeFingerprint(value string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(value), ":", ""))
+}
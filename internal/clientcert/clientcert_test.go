@@ -0,0 +1,179 @@
+package clientcert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	"golang.org/x/crypto/ocsp"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatalf("create ca: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, serial int64, commonName string, ocspServer string) *x509.Certificate {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if ocspServer != "" {
+		tmpl.OCSPServer = []string{ocspServer}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		t.Fatalf("issue certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func requestWith(cert *x509.Certificate) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	return r
+}
+
+func TestAuthenticateMapsCertificatesAndHonoursCRL(t *testing.T) {
+	ca := newTestCA(t)
+	valid := ca.issue(t, 10, "billing-service", "")
+	revoked := ca.issue(t, 11, "billing-service", "")
+	other := ca.issue(t, 12, "unmapped", "")
+
+	crlDER, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now().Add(-time.Minute),
+		NextUpdate:                time.Now().Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{{SerialNumber: revoked.SerialNumber, RevocationTime: time.Now()}},
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("create crl: %v", err)
+	}
+	dir := t.TempDir()
+	Configure(&config.Config{TLS: config.TLSConfig{ClientAuth: config.TLSClientAuthConfig{
+		Mode:       "optional",
+		CA:         writePEM(t, dir, "ca.pem", "CERTIFICATE", ca.cert.Raw),
+		CRL:        []string{writePEM(t, dir, "ca.crl", "X509 CRL", crlDER)},
+		Identities: []config.ClientCertIdentity{{CommonName: "billing-service", APIKey: "billing-key"}},
+	}}})
+	defer Configure(nil)
+
+	result, err := Authenticate(requestWith(valid))
+	if err != nil || result.Principal != "billing-key" || result.Provider != ProviderName {
+		t.Fatalf("Authenticate(valid) = %+v, %v", result, err)
+	}
+	if _, err = Authenticate(requestWith(revoked)); !errors.Is(err, sdkaccess.ErrNotHandled) {
+		t.Fatalf("revoked certificate authenticated: %v", err)
+	}
+	if _, err = Authenticate(requestWith(other)); !errors.Is(err, sdkaccess.ErrNotHandled) {
+		t.Fatalf("unmapped certificate authenticated: %v", err)
+	}
+
+	verify := TLSConfig(config.TLSClientAuthConfig{Mode: "require"}).VerifyPeerCertificate
+	if err = verify([][]byte{valid.Raw}, nil); err != nil {
+		t.Fatalf("handshake rejected a valid certificate: %v", err)
+	}
+	if err = verify([][]byte{revoked.Raw}, nil); err == nil {
+		t.Fatalf("handshake accepted a revoked certificate")
+	}
+	if err = verify([][]byte{newTestCA(t).issue(t, 1, "billing-service", "").Raw}, nil); err == nil {
+		t.Fatalf("handshake accepted a certificate of an unknown CA")
+	}
+}
+
+func TestOCSPRevocation(t *testing.T) {
+	ca := newTestCA(t)
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		status := ocsp.Good
+		if req.SerialNumber.Int64() == 21 {
+			status = ocsp.Revoked
+		}
+		resp, _ := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+			Status:       status,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, ca.key)
+		_, _ = w.Write(resp)
+	}))
+	defer responder.Close()
+
+	good := ca.issue(t, 20, "svc", responder.URL)
+	revoked := ca.issue(t, 21, "svc", responder.URL)
+	noResponder := ca.issue(t, 22, "svc", "")
+	st := &settings{roots: x509.NewCertPool(), ocsp: ocspHard}
+	st.roots.AddCert(ca.cert)
+
+	if err := st.verify([]*x509.Certificate{good}); err != nil {
+		t.Fatalf("good certificate rejected: %v", err)
+	}
+	if err := st.verify([]*x509.Certificate{revoked}); !errors.Is(err, errRevoked) {
+		t.Fatalf("revoked certificate accepted: %v", err)
+	}
+	if err := st.verify([]*x509.Certificate{noResponder}); err == nil {
+		t.Fatalf("hard OCSP accepted a certificate of unknown status")
+	}
+	st.ocsp = ocspSoft
+	if err := st.verify([]*x509.Certificate{noResponder}); err != nil {
+		t.Fatalf("soft OCSP rejected a certificate of unknown status: %v", err)
+	}
+}
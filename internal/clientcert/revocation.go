@@ -0,0 +1,137 @@
+package clientcert
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	ocspSoft = "soft"
+	ocspHard = "hard"
+
+	ocspTimeout = 5 * time.Second
+	// ocspDefaultTTL caches responses without a next update time.
+	ocspDefaultTTL = time.Hour
+	maxOCSPBytes   = 1 << 20
+)
+
+// errRevoked is returned for certificates revoked by their issuer.
+var errRevoked = errors.New("client certificate is revoked")
+
+// loadCRL reads a PEM or DER encoded certificate revocation list.
+func loadCRL(path string) (*x509.RevocationList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read revocation list: %w", err)
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse revocation list %s: %w", path, err)
+	}
+	return crl, nil
+}
+
+// checkRevocation checks cert, issued by issuer, against the revocation lists of the issuer and
+// its OCSP responder.
+func (s *settings) checkRevocation(cert, issuer *x509.Certificate) error {
+	for _, crl := range s.crls {
+		if !bytes.Equal(crl.RawIssuer, issuer.RawSubject) || crl.CheckSignatureFrom(issuer) != nil {
+			continue
+		}
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber != nil && entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return errRevoked
+			}
+		}
+	}
+	if s.ocsp != ocspSoft && s.ocsp != ocspHard {
+		return nil
+	}
+	status, err := ocspStatus(cert, issuer)
+	switch {
+	case err == nil && status == ocsp.Revoked:
+		return errRevoked
+	case err == nil && status == ocsp.Good:
+		return nil
+	case s.ocsp == ocspHard:
+		if err == nil {
+			err = errors.New("status unknown")
+		}
+		return fmt.Errorf("client certificate revocation status: %w", err)
+	default:
+		return nil
+	}
+}
+
+type ocspEntry struct {
+	status  int
+	expires time.Time
+}
+
+var (
+	ocspMu     sync.Mutex
+	ocspCache  = make(map[string]ocspEntry)
+	ocspClient = &http.Client{Timeout: ocspTimeout}
+)
+
+// ocspStatus asks the responder named in cert for its status, caching answers until their next
+// update.
+func ocspStatus(cert, issuer *x509.Certificate) (int, error) {
+	if len(cert.OCSPServer) == 0 {
+		return ocsp.Unknown, errors.New("certificate names no OCSP responder")
+	}
+	key := string(issuer.RawSubject) + "/" + cert.SerialNumber.String()
+	now := time.Now()
+	ocspMu.Lock()
+	entry, ok := ocspCache[key]
+	ocspMu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.status, nil
+	}
+
+	request, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return ocsp.Unknown, err
+	}
+	var lastErr error
+	for _, server := range cert.OCSPServer {
+		resp, errPost := ocspClient.Post(server, "application/ocsp-request", bytes.NewReader(request))
+		if errPost != nil {
+			lastErr = errPost
+			continue
+		}
+		body, errRead := io.ReadAll(io.LimitReader(resp.Body, maxOCSPBytes))
+		_ = resp.Body.Close()
+		if errRead != nil {
+			lastErr = errRead
+			continue
+		}
+		parsed, errParse := ocsp.ParseResponseForCert(body, cert, issuer)
+		if errParse != nil {
+			lastErr = errParse
+			continue
+		}
+		expires := parsed.NextUpdate
+		if expires.IsZero() {
+			expires = now.Add(ocspDefaultTTL)
+		}
+		ocspMu.Lock()
+		ocspCache[key] = ocspEntry{status: parsed.Status, expires: expires}
+		ocspMu.Unlock()
+		return parsed.Status, nil
+	}
+	return ocsp.Unknown, fmt.Errorf("query OCSP responder: %w", lastErr)
+}
@@ -35,6 +35,7 @@ func DoClaudeLogin(cfg *config.Config, options *LoginOptions) {
 		NoBrowser:    options.NoBrowser,
 		CallbackPort: options.CallbackPort,
 		Metadata:     map[string]string{},
+		Labels:       options.Labels,
 		Prompt:       promptFn,
 	}
 
@@ -9,7 +9,8 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// DoAntigravityLogin triggers the OAuth flow for the antigravity provider and saves tokens.
+// DoAntigravityLogin guides through the Antigravity login: it runs the OAuth flow, onboards
+// accounts without a project to the chosen tier, sends a test request and saves the tokens.
 func DoAntigravityLogin(cfg *config.Config, options *LoginOptions) {
 	if options == nil {
 		options = &LoginOptions{}
@@ -20,11 +21,18 @@ func DoAntigravityLogin(cfg *config.Config, options *LoginOptions) {
 		promptFn = defaultProjectPrompt()
 	}
 
+	metadata := make(map[string]string, len(options.Metadata))
+	for key, value := range options.Metadata {
+		metadata[key] = value
+	}
+
+	fmt.Println("Antigravity login: sign in with Google; accounts without a project are then onboarded and a test request is sent.")
 	manager := newAuthManager()
 	authOpts := &sdkAuth.LoginOptions{
 		NoBrowser:    options.NoBrowser,
 		CallbackPort: options.CallbackPort,
-		Metadata:     map[string]string{},
+		Metadata:     metadata,
+		Labels:       options.Labels,
 		Prompt:       promptFn,
 	}
 
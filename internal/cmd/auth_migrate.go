@@ -0,0 +1,88 @@
+// Package cmd contains CLI helpers. This file implements migrating credentials between the
+// active token store and a directory of auth files.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdkAuth "github.com/router-for-me/CLIProxyAPI/v6/sdk/auth"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// DoAuthMigrate copies every credential between the active token store (file, git, object or
+// Postgres, as configured) and the auth file directory dir. With export the active store is the
+// source, otherwise dir is. Moving between two remote backends is an export followed by an
+// import with the other backend configured. With removeSource each source copy is deleted once
+// the target holds an identical, usable copy.
+func DoAuthMigrate(cfg *config.Config, dir string, export, removeSource bool) {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		log.Errorf("auth-migrate: missing directory")
+		return
+	}
+	resolvedDir, errResolve := util.ResolveAuthDir(dir)
+	if errResolve != nil {
+		log.Errorf("auth-migrate: resolve directory: %v", errResolve)
+		return
+	}
+	active := sdkAuth.GetTokenStore()
+	if setter, ok := active.(interface{ SetBaseDir(string) }); ok {
+		setter.SetBaseDir(cfg.AuthDir)
+	}
+	files := sdkAuth.NewFileTokenStore()
+	files.SetBaseDir(resolvedDir)
+
+	var from, to coreauth.Store = files, active
+	direction := fmt.Sprintf("%s -> active store", resolvedDir)
+	if export {
+		from, to = active, files
+		direction = fmt.Sprintf("active store -> %s", resolvedDir)
+	}
+	results, err := sdkAuth.MigrateTokens(context.Background(), from, to, sdkAuth.MigrateOptions{
+		RemoveSource: removeSource,
+		Probe:        probeMigratedCredential,
+	})
+	if err != nil {
+		log.Errorf("auth-migrate: %v", err)
+		return
+	}
+	failed := 0
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			failed++
+			fmt.Printf("FAILED  %s: %v\n", result.ID, result.Err)
+		case result.Removed:
+			fmt.Printf("MOVED   %s\n", result.ID)
+		default:
+			fmt.Printf("COPIED  %s\n", result.ID)
+		}
+	}
+	fmt.Printf("Auth migration (%s): %d migrated, %d failed\n", direction, len(results)-failed, failed)
+}
+
+// probeMigratedCredential checks that a credential read back from the target is still one the
+// proxy can load: a known provider and some secret to authenticate with. It does not call the
+// upstream, since refreshing a token would rotate it and invalidate the other copy.
+func probeMigratedCredential(_ context.Context, auth *coreauth.Auth) error {
+	if auth == nil {
+		return fmt.Errorf("credential missing")
+	}
+	if provider := strings.TrimSpace(auth.Provider); provider == "" || provider == "unknown" {
+		return fmt.Errorf("credential has no provider type")
+	}
+	for _, key := range []string{"access_token", "refresh_token", "api_key", "token", "cookie", "service_account", "accessToken", "refreshToken", "id_token"} {
+		if value, ok := auth.Metadata[key]; ok && value != nil && value != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("credential carries no token, key or service account")
+}
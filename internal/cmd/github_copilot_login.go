@@ -25,6 +25,7 @@ func DoGitHubCopilotLogin(cfg *config.Config, options *LoginOptions) {
 	authOpts := &sdkAuth.LoginOptions{
 		NoBrowser: options.NoBrowser,
 		Metadata:  map[string]string{},
+		Labels:    options.Labels,
 		Prompt:    options.Prompt,
 	}
 
@@ -27,6 +27,7 @@ func DoIFlowLogin(cfg *config.Config, options *LoginOptions) {
 		NoBrowser:    options.NoBrowser,
 		CallbackPort: options.CallbackPort,
 		Metadata:     map[string]string{},
+		Labels:       options.Labels,
 		Prompt:       promptFn,
 	}
 
@@ -53,6 +53,7 @@ func DoKiroGoogleLogin(cfg *config.Config, options *LoginOptions) {
 	}
 
 	// Save the auth record
+	record.MergeLabels(options.Labels)
 	savedPath, err := manager.SaveAuth(record, cfg)
 	if err != nil {
 		log.Errorf("Failed to save auth: %v", err)
@@ -101,6 +102,7 @@ func DoKiroAWSLogin(cfg *config.Config, options *LoginOptions) {
 	}
 
 	// Save the auth record
+	record.MergeLabels(options.Labels)
 	savedPath, err := manager.SaveAuth(record, cfg)
 	if err != nil {
 		log.Errorf("Failed to save auth: %v", err)
@@ -149,6 +151,7 @@ func DoKiroAWSAuthCodeLogin(cfg *config.Config, options *LoginOptions) {
 	}
 
 	// Save the auth record
+	record.MergeLabels(options.Labels)
 	savedPath, err := manager.SaveAuth(record, cfg)
 	if err != nil {
 		log.Errorf("Failed to save auth: %v", err)
@@ -192,6 +195,7 @@ func DoKiroImport(cfg *config.Config, options *LoginOptions) {
 	}
 
 	// Save the imported auth record
+	record.MergeLabels(options.Labels)
 	savedPath, err := manager.SaveAuth(record, cfg)
 	if err != nil {
 		log.Errorf("Failed to save auth: %v", err)
@@ -164,6 +164,7 @@ func DoLogin(cfg *config.Config, projectID string, options *LoginOptions) {
 	}
 
 	updateAuthRecord(record, storage)
+	record.MergeLabels(options.Labels)
 
 	store := sdkAuth.GetTokenStore()
 	if setter, okSetter := store.(interface{ SetBaseDir(string) }); okSetter && cfg != nil {
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// loginFlags maps the providers accepted by "login PROVIDER" to their login flags.
+var loginFlags = map[string]string{
+	"gemini":         "login",
+	"antigravity":    "antigravity-login",
+	"codex":          "codex-login",
+	"claude":         "claude-login",
+	"qwen":           "qwen-login",
+	"iflow":          "iflow-login",
+	"github-copilot": "github-copilot-login",
+	"kiro":           "kiro-login",
+}
+
+// ExpandLoginCommand rewrites "login PROVIDER [flags]" into the provider's login flag followed
+// by the remaining flags, e.g. "login antigravity -no-browser" into
+// "-antigravity-login -no-browser". It reports false when args do not start with "login".
+func ExpandLoginCommand(args []string) ([]string, bool, error) {
+	if len(args) == 0 || args[0] != "login" {
+		return nil, false, nil
+	}
+	providers := make([]string, 0, len(loginFlags))
+	for provider := range loginFlags {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+		return nil, true, fmt.Errorf("usage: login PROVIDER [flags], where PROVIDER is one of %s", strings.Join(providers, ", "))
+	}
+	flagName, ok := loginFlags[strings.ToLower(args[1])]
+	if !ok {
+		return nil, true, fmt.Errorf("login: unknown provider %q, expected one of %s", args[1], strings.Join(providers, ", "))
+	}
+	return append([]string{"-" + flagName}, args[2:]...), true, nil
+}
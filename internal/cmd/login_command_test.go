@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandLoginCommand(t *testing.T) {
+	got, ok, err := ExpandLoginCommand([]string{"login", "antigravity", "-no-browser", "-tier", "free-tier"})
+	if err != nil || !ok {
+		t.Fatalf("ExpandLoginCommand = %v, %v", ok, err)
+	}
+	if want := []string{"-antigravity-login", "-no-browser", "-tier", "free-tier"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("args = %v, want %v", got, want)
+	}
+
+	if _, ok, _ := ExpandLoginCommand([]string{"-login"}); ok {
+		t.Fatal("flags must not be treated as the login command")
+	}
+	for _, args := range [][]string{{"login"}, {"login", "-no-browser"}, {"login", "nope"}} {
+		if _, ok, err := ExpandLoginCommand(args); !ok || err == nil {
+			t.Errorf("%v: ok = %v, err = %v", args, ok, err)
+		}
+	}
+}
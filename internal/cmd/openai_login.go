@@ -24,6 +24,12 @@ type LoginOptions struct {
 
 	// Prompt allows the caller to provide interactive input when needed.
 	Prompt func(prompt string) (string, error)
+
+	// Metadata passes provider-specific options to the authenticator, e.g. the Antigravity tier.
+	Metadata map[string]string
+
+	// Labels are stored on the saved credential for use in routing rules, e.g. {"tier": "pro"}.
+	Labels map[string]string
 }
 
 // DoCodexLogin triggers the Codex OAuth flow through the shared authentication manager.
@@ -49,6 +55,7 @@ func DoCodexLogin(cfg *config.Config, options *LoginOptions) {
 		NoBrowser:    options.NoBrowser,
 		CallbackPort: options.CallbackPort,
 		Metadata:     map[string]string{},
+		Labels:       options.Labels,
 		Prompt:       promptFn,
 	}
 
@@ -39,6 +39,7 @@ func DoQwenLogin(cfg *config.Config, options *LoginOptions) {
 		NoBrowser:    options.NoBrowser,
 		CallbackPort: options.CallbackPort,
 		Metadata:     map[string]string{},
+		Labels:       options.Labels,
 		Prompt:       promptFn,
 	}
 
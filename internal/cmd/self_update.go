@@ -0,0 +1,47 @@
+// Package cmd contains CLI helpers. This file implements the -update command that replaces
+// the running binary with the latest published release.
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/selfupdate"
+	log "github.com/sirupsen/logrus"
+)
+
+// DoSelfUpdate downloads the latest release for this platform, verifies its checksum and
+// swaps it in place of the current executable. The previous binary is kept next to it
+// with an ".old" suffix so the upgrade can be rolled back by hand.
+func DoSelfUpdate(cfg *config.Config) {
+	proxyURL := ""
+	if cfg != nil {
+		proxyURL = cfg.ProxyURL
+	}
+	exePath, err := os.Executable()
+	if err != nil {
+		log.Errorf("update: resolve executable path: %v", err)
+		return
+	}
+	if resolved, errEval := filepath.EvalSymlinks(exePath); errEval == nil {
+		exePath = resolved
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	version, err := selfupdate.Update(ctx, selfupdate.NewHTTPClient(proxyURL, 5*time.Minute), "", exePath)
+	if errors.Is(err, selfupdate.ErrNoUpdate) {
+		log.Infof("update: already up to date (running %s, latest %s)", buildinfo.Version, version)
+		return
+	}
+	if err != nil {
+		log.Errorf("update failed: %v", err)
+		return
+	}
+	log.Infof("update: installed %s over %s; previous binary saved as %s.old. Restart the service to apply.", version, buildinfo.Version, exePath)
+}
@@ -0,0 +1,599 @@
+// Package cmd contains CLI helpers. This file implements the status, accounts, usage and test
+// subcommands, which operate a running proxy through its management API and client endpoints.
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	managementPath = "/v0/management"
+	// requestTimeout bounds a single call to the proxy, including a full test completion.
+	requestTimeout = 5 * time.Minute
+)
+
+// subcommand is a CLI verb run against a running proxy.
+type subcommand struct {
+	summary string
+	run     func(ctx context.Context, sc *subcommandContext, args []string) error
+}
+
+var subcommands = map[string]subcommand{
+	"status":   {summary: "show provider and account health", run: runStatus},
+	"accounts": {summary: "list, remove or refresh accounts: accounts list | rm NAME | refresh NAME", run: runAccounts},
+	"usage":    {summary: "print a usage report: usage report [-since 7d|YYYY-MM-DD] [-until YYYY-MM-DD]", run: runUsage},
+	"test":     {summary: "send a test prompt through the proxy: test -model MODEL [-prompt TEXT]", run: runTest},
+	"tui":      {summary: "interactive account management and live traffic", run: runTUI},
+}
+
+// IsSubcommand reports whether name is one of the management subcommands.
+func IsSubcommand(name string) bool {
+	_, ok := subcommands[name]
+	return ok
+}
+
+// RunSubcommand runs the subcommand named by args[0] and returns the process exit code.
+func RunSubcommand(args []string, defaultConfigPath string) int {
+	if len(args) == 0 || !IsSubcommand(args[0]) {
+		PrintSubcommandUsage(os.Stderr)
+		return 2
+	}
+	sc := newSubcommandContext(args[0], defaultConfigPath, os.Stdout)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := subcommands[args[0]].run(ctx, sc, args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// PrintSubcommandUsage lists the subcommands and what they do.
+func PrintSubcommandUsage(w io.Writer) {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	_, _ = fmt.Fprintf(w, "Subcommands (run %s SUBCOMMAND -h for flags):\n", filepath.Base(os.Args[0]))
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "  %-9s %s\n", name, subcommands[name].summary)
+	}
+}
+
+// subcommandContext carries the shared flags and the resolved proxy connection settings.
+type subcommandContext struct {
+	out   io.Writer
+	flags *flag.FlagSet
+
+	configPath    string
+	baseURL       string
+	managementKey string
+	apiKey        string
+	insecure      bool
+	jsonOutput    bool
+
+	client *http.Client
+}
+
+// newSubcommandContext registers the flags shared by all subcommands.
+func newSubcommandContext(name, defaultConfigPath string, out io.Writer) *subcommandContext {
+	sc := &subcommandContext{out: out, flags: flag.NewFlagSet(name, flag.ContinueOnError)}
+	sc.flags.StringVar(&sc.configPath, "config", defaultConfigPath, "Configure File Path used to locate the proxy")
+	sc.flags.StringVar(&sc.baseURL, "url", "", "Base URL of the proxy (defaults to the host and port of the config)")
+	sc.flags.StringVar(&sc.managementKey, "management-key", "", "Management key (defaults to MANAGEMENT_PASSWORD or a plaintext remote-management.secret-key)")
+	sc.flags.BoolVar(&sc.insecure, "insecure", false, "Skip TLS certificate verification")
+	sc.flags.BoolVar(&sc.jsonOutput, "json", false, "Print the raw JSON response")
+	return sc
+}
+
+// parse parses flags placed anywhere among args and returns the positional arguments, then
+// resolves the proxy address and keys from the config file.
+func (sc *subcommandContext) parse(args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := sc.flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = sc.flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if err := sc.resolve(); err != nil {
+		return nil, err
+	}
+	return positional, nil
+}
+
+// subcommandConfig is the part of the config file the subcommands need. It is read directly so
+// that running a subcommand never migrates or rewrites the file.
+type subcommandConfig struct {
+	Host string `yaml:"host"`
+	// Port is read as text so that it may be an environment reference.
+	Port string `yaml:"port"`
+	TLS  struct {
+		Enable bool `yaml:"enable"`
+	} `yaml:"tls"`
+	APIKeys          []string `yaml:"api-keys"`
+	RemoteManagement struct {
+		SecretKey string `yaml:"secret-key"`
+	} `yaml:"remote-management"`
+}
+
+func (sc *subcommandContext) resolve() error {
+	var cfg subcommandConfig
+	path := strings.TrimSpace(sc.configPath)
+	if path == "" {
+		if wd, err := os.Getwd(); err == nil {
+			path = filepath.Join(wd, "config.yaml")
+		}
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		if err = yaml.Unmarshal(data, &cfg); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		// Only the values used here are resolved, and ones that do not resolve in this shell
+		// are dropped, so a reference elsewhere in the file cannot stop the subcommands.
+		for _, value := range []*string{&cfg.Host, &cfg.Port, &cfg.RemoteManagement.SecretKey} {
+			*value = resolveConfigValue(*value, filepath.Dir(path))
+		}
+		for i := range cfg.APIKeys {
+			cfg.APIKeys[i] = resolveConfigValue(cfg.APIKeys[i], filepath.Dir(path))
+		}
+	} else if sc.baseURL == "" {
+		return fmt.Errorf("read %s: %w (pass -url to reach the proxy without a config file)", path, err)
+	}
+
+	if sc.baseURL == "" {
+		scheme := "http"
+		if cfg.TLS.Enable {
+			scheme = "https"
+		}
+		host := strings.TrimSpace(cfg.Host)
+		if host == "" || host == "0.0.0.0" || host == "::" {
+			host = "127.0.0.1"
+		}
+		port, _ := strconv.Atoi(strings.TrimSpace(cfg.Port))
+		if port == 0 {
+			port = 8317
+		}
+		sc.baseURL = scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	sc.baseURL = strings.TrimRight(sc.baseURL, "/")
+
+	if sc.managementKey == "" {
+		sc.managementKey = strings.TrimSpace(os.Getenv("MANAGEMENT_PASSWORD"))
+	}
+	if secret := strings.TrimSpace(cfg.RemoteManagement.SecretKey); sc.managementKey == "" && !strings.HasPrefix(secret, "$2") {
+		sc.managementKey = secret
+	}
+	if sc.apiKey == "" && len(cfg.APIKeys) > 0 {
+		sc.apiKey = cfg.APIKeys[0]
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if sc.insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	sc.client = &http.Client{Transport: transport, Timeout: requestTimeout}
+	return nil
+}
+
+func resolveConfigValue(raw, baseDir string) string {
+	value, err := config.ResolveSecretReference(raw, baseDir)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// do sends a request to the proxy and returns the status and body of the response.
+func (sc *subcommandContext) do(ctx context.Context, method, path string, header http.Header, body any) (int, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, sc.baseURL+path, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := sc.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, data, err
+}
+
+// management calls a management endpoint and returns the body of a successful response.
+func (sc *subcommandContext) management(ctx context.Context, method, path string, body any, okStatuses ...int) ([]byte, error) {
+	if sc.managementKey == "" {
+		return nil, errors.New("management key is required: pass -management-key or set MANAGEMENT_PASSWORD")
+	}
+	header := http.Header{"Authorization": {"Bearer " + sc.managementKey}}
+	status, data, err := sc.do(ctx, method, managementPath+path, header, body)
+	if err != nil {
+		return nil, err
+	}
+	if status/100 == 2 {
+		return data, nil
+	}
+	for _, ok := range okStatuses {
+		if status == ok {
+			return data, nil
+		}
+	}
+	return nil, responseError(status, data)
+}
+
+func responseError(status int, data []byte) error {
+	message := gjson.GetBytes(data, "error.message").String()
+	if message == "" {
+		message = gjson.GetBytes(data, "error").String()
+	}
+	if message == "" {
+		message = strings.TrimSpace(string(data))
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return fmt.Errorf("HTTP %d: %s", status, message)
+}
+
+// printJSON writes data indented.
+func (sc *subcommandContext) printJSON(data []byte) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		_, errWrite := sc.out.Write(data)
+		return errWrite
+	}
+	buf.WriteByte('\n')
+	_, err := sc.out.Write(buf.Bytes())
+	return err
+}
+
+func runStatus(ctx context.Context, sc *subcommandContext, args []string) error {
+	if _, err := sc.parse(args); err != nil {
+		return err
+	}
+	data, err := sc.management(ctx, http.MethodGet, "/health", nil, http.StatusServiceUnavailable)
+	if err != nil {
+		return err
+	}
+	if sc.jsonOutput {
+		return sc.printJSON(data)
+	}
+	var report struct {
+		Status    string `json:"status"`
+		Providers map[string]struct {
+			Status      string `json:"status"`
+			Total       int    `json:"total"`
+			Available   int    `json:"available"`
+			CoolingDown int    `json:"cooling_down"`
+			Disabled    int    `json:"disabled"`
+		} `json:"providers"`
+		Accounts []struct {
+			ID            string     `json:"id"`
+			Provider      string     `json:"provider"`
+			Label         string     `json:"label"`
+			Status        string     `json:"status"`
+			StatusMessage string     `json:"status_message"`
+			Available     bool       `json:"available"`
+			CooldownUntil *time.Time `json:"cooldown_until"`
+			QuotaExceeded bool       `json:"quota_exceeded"`
+			TokenExpired  bool       `json:"token_expired"`
+		} `json:"accounts"`
+	}
+	if err = json.Unmarshal(data, &report); err != nil {
+		return fmt.Errorf("decode health report: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(sc.out, "Status: %s\n\n", report.Status)
+	providers := make([]string, 0, len(report.Providers))
+	for name := range report.Providers {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+	tw := tabwriter.NewWriter(sc.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "PROVIDER\tSTATUS\tAVAILABLE\tCOOLING DOWN\tDISABLED\tTOTAL")
+	for _, name := range providers {
+		p := report.Providers[name]
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\n", name, p.Status, p.Available, p.CoolingDown, p.Disabled, p.Total)
+	}
+	if err = tw.Flush(); err != nil {
+		return err
+	}
+	if len(report.Accounts) == 0 {
+		return nil
+	}
+
+	_, _ = fmt.Fprintln(sc.out)
+	tw = tabwriter.NewWriter(sc.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ACCOUNT\tPROVIDER\tSTATUS\tAVAILABLE\tNOTE")
+	for _, account := range report.Accounts {
+		name := account.Label
+		if name == "" {
+			name = account.ID
+		}
+		var notes []string
+		if account.QuotaExceeded {
+			notes = append(notes, "quota exceeded")
+		}
+		if account.CooldownUntil != nil {
+			notes = append(notes, "cooling down until "+account.CooldownUntil.Local().Format(time.DateTime))
+		}
+		if account.TokenExpired {
+			notes = append(notes, "token expired")
+		}
+		if account.StatusMessage != "" {
+			notes = append(notes, account.StatusMessage)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\n", name, account.Provider, account.Status, account.Available, strings.Join(notes, "; "))
+	}
+	return tw.Flush()
+}
+
+func runAccounts(ctx context.Context, sc *subcommandContext, args []string) error {
+	positional, err := sc.parse(args)
+	if err != nil {
+		return err
+	}
+	action := "list"
+	if len(positional) > 0 {
+		action = positional[0]
+	}
+	switch action {
+	case "list", "ls":
+		return listAccounts(ctx, sc)
+	case "rm", "remove":
+		if len(positional) != 2 {
+			return errors.New("usage: accounts rm NAME")
+		}
+		if _, err = sc.management(ctx, http.MethodDelete, "/auth-files?name="+url.QueryEscape(positional[1]), nil); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(sc.out, "removed %s\n", positional[1])
+		return nil
+	case "refresh":
+		if len(positional) != 2 {
+			return errors.New("usage: accounts refresh NAME")
+		}
+		data, errRefresh := sc.management(ctx, http.MethodPost, "/auth-files/refresh", map[string]string{"name": positional[1]})
+		if errRefresh != nil {
+			return errRefresh
+		}
+		if sc.jsonOutput {
+			return sc.printJSON(data)
+		}
+		_, _ = fmt.Fprintf(sc.out, "refreshed %s\n", positional[1])
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q (expected list, rm or refresh)", action)
+	}
+}
+
+func listAccounts(ctx context.Context, sc *subcommandContext) error {
+	data, err := sc.management(ctx, http.MethodGet, "/auth-files", nil)
+	if err != nil {
+		return err
+	}
+	if sc.jsonOutput {
+		return sc.printJSON(data)
+	}
+	tw := tabwriter.NewWriter(sc.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tPROVIDER\tACCOUNT\tSTATUS\tDISABLED\tLAST REFRESH")
+	for _, file := range gjson.GetBytes(data, "files").Array() {
+		account := file.Get("email").String()
+		if account == "" {
+			account = file.Get("label").String()
+		}
+		lastRefresh := ""
+		if ts := file.Get("last_refresh").Time(); !ts.IsZero() {
+			lastRefresh = ts.Local().Format(time.DateTime)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%s\n",
+			file.Get("name").String(), file.Get("provider").String(), account,
+			file.Get("status").String(), file.Get("disabled").Bool(), lastRefresh)
+	}
+	return tw.Flush()
+}
+
+func runUsage(ctx context.Context, sc *subcommandContext, args []string) error {
+	var since, until, format string
+	sc.flags.StringVar(&since, "since", "7d", "Start of the report: a number of days (7d), a duration (48h) or a date (YYYY-MM-DD)")
+	sc.flags.StringVar(&until, "until", "", "Last day of the report (YYYY-MM-DD, defaults to today)")
+	sc.flags.StringVar(&format, "format", "", "Set to csv to print the CSV report")
+	positional, err := sc.parse(args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 && positional[0] != "report" {
+		return fmt.Errorf("unknown action %q (expected report)", positional[0])
+	}
+	now := time.Now()
+	from, err := reportStart(since, now)
+	if err != nil {
+		return err
+	}
+	if until == "" {
+		until = now.Format(time.DateOnly)
+	}
+	query := url.Values{"from": {from}, "to": {until}}
+	if format != "" {
+		query.Set("format", format)
+	}
+	data, err := sc.management(ctx, http.MethodGet, "/usage/report?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if format != "" {
+		_, err = sc.out.Write(data)
+		return err
+	}
+	if sc.jsonOutput {
+		return sc.printJSON(data)
+	}
+
+	totals := gjson.GetBytes(data, "totals")
+	_, _ = fmt.Fprintf(sc.out, "Usage %s to %s: %d requests (%d failed), %d tokens, $%.2f\n\n",
+		from, until, totals.Get("requests").Int(), totals.Get("failures").Int(), totals.Get("total_tokens").Int(), totals.Get("cost").Float())
+	for _, section := range []struct{ title, key string }{{"MODEL", "models"}, {"PROVIDER", "providers"}} {
+		rows := gjson.GetBytes(data, section.key).Map()
+		if len(rows) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(rows))
+		for name := range rows {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return rows[names[i]].Get("total_tokens").Int() > rows[names[j]].Get("total_tokens").Int()
+		})
+		tw := tabwriter.NewWriter(sc.out, 0, 0, 2, ' ', tabwriter.AlignRight)
+		_, _ = fmt.Fprintf(tw, "%s\tREQUESTS\tFAILED\tINPUT\tOUTPUT\tTOTAL\tCOST\t\n", section.title)
+		for _, name := range names {
+			row := rows[name]
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%.2f\t\n", name, row.Get("requests").Int(), row.Get("failures").Int(),
+				row.Get("input_tokens").Int(), row.Get("output_tokens").Int(), row.Get("total_tokens").Int(), row.Get("cost").Float())
+		}
+		if err = tw.Flush(); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(sc.out)
+	}
+	return nil
+}
+
+// reportStart converts a -since value into the first day of the report.
+func reportStart(since string, now time.Time) (string, error) {
+	since = strings.TrimSpace(since)
+	if _, err := time.Parse(time.DateOnly, since); err == nil {
+		return since, nil
+	}
+	if days, ok := strings.CutSuffix(since, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid -since %q", since)
+		}
+		// 1d covers today only, 7d today and the six days before it.
+		return now.AddDate(0, 0, -max(n-1, 0)).Format(time.DateOnly), nil
+	}
+	d, err := time.ParseDuration(since)
+	if err != nil || d < 0 {
+		return "", fmt.Errorf("invalid -since %q, expected 7d, 48h or YYYY-MM-DD", since)
+	}
+	return now.Add(-d).Format(time.DateOnly), nil
+}
+
+// testRequests builds the request of each client API format for a prompt.
+var testRequests = map[string]struct {
+	path func(model string) string
+	body func(model, prompt string) any
+	text func(data []byte) string
+}{
+	"openai": {
+		path: func(string) string { return "/v1/chat/completions" },
+		body: func(model, prompt string) any {
+			return map[string]any{"model": model, "messages": []map[string]string{{"role": "user", "content": prompt}}}
+		},
+		text: func(data []byte) string { return gjson.GetBytes(data, "choices.0.message.content").String() },
+	},
+	"claude": {
+		path: func(string) string { return "/v1/messages" },
+		body: func(model, prompt string) any {
+			return map[string]any{"model": model, "max_tokens": 1024, "messages": []map[string]string{{"role": "user", "content": prompt}}}
+		},
+		text: func(data []byte) string { return joinTexts(gjson.GetBytes(data, `content.#(type=="text")#.text`)) },
+	},
+	"gemini": {
+		path: func(model string) string { return "/v1beta/models/" + url.PathEscape(model) + ":generateContent" },
+		body: func(_, prompt string) any {
+			return map[string]any{"contents": []map[string]any{{"role": "user", "parts": []map[string]string{{"text": prompt}}}}}
+		},
+		text: func(data []byte) string { return joinTexts(gjson.GetBytes(data, "candidates.0.content.parts.#.text")) },
+	},
+}
+
+func joinTexts(result gjson.Result) string {
+	var parts []string
+	for _, item := range result.Array() {
+		parts = append(parts, item.String())
+	}
+	return strings.Join(parts, "")
+}
+
+func runTest(ctx context.Context, sc *subcommandContext, args []string) error {
+	var model, prompt, format string
+	sc.flags.StringVar(&model, "model", "", "Model to call")
+	sc.flags.StringVar(&prompt, "prompt", "Reply with one short sentence.", "Prompt to send")
+	sc.flags.StringVar(&format, "format", "openai", "Client API format: openai, claude or gemini")
+	sc.flags.StringVar(&sc.apiKey, "api-key", "", "Client API key (defaults to the first api-keys entry)")
+	if _, err := sc.parse(args); err != nil {
+		return err
+	}
+	if model == "" {
+		return errors.New("-model is required")
+	}
+	request, ok := testRequests[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q (expected openai, claude or gemini)", format)
+	}
+	header := http.Header{}
+	if sc.apiKey != "" {
+		header.Set("Authorization", "Bearer "+sc.apiKey)
+	}
+	start := time.Now()
+	status, data, err := sc.do(ctx, http.MethodPost, request.path(model), header, request.body(model, prompt))
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	if status != http.StatusOK {
+		return responseError(status, data)
+	}
+	if sc.jsonOutput {
+		return sc.printJSON(data)
+	}
+	_, _ = fmt.Fprintln(sc.out, strings.TrimSpace(request.text(data)))
+	_, _ = fmt.Fprintf(sc.out, "\n%s via %s API in %s\n", model, format, elapsed.Round(time.Millisecond))
+	return nil
+}
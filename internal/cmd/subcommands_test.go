@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportStart(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local)
+	cases := map[string]string{
+		"7d":         "2025-03-04",
+		"1d":         "2025-03-10",
+		"48h":        "2025-03-08",
+		"2025-01-31": "2025-01-31",
+	}
+	for since, want := range cases {
+		got, err := reportStart(since, now)
+		if err != nil || got != want {
+			t.Errorf("reportStart(%q) = %q, %v; want %q", since, got, err, want)
+		}
+	}
+	if _, err := reportStart("soon", now); err == nil {
+		t.Error("expected an error for an invalid -since")
+	}
+}
+
+func TestAccountsSubcommand(t *testing.T) {
+	var refreshed string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid management key"}`))
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v0/management/auth-files":
+			_, _ = w.Write([]byte(`{"files":[{"name":"claude-a.json","provider":"claude","email":"a@example.com","status":"active"}]}`))
+		case "POST /v0/management/auth-files/refresh":
+			buf := new(bytes.Buffer)
+			_, _ = buf.ReadFrom(r.Body)
+			refreshed = buf.String()
+			_, _ = w.Write([]byte(`{"status":"ok"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		sc := newSubcommandContext("accounts", "", &out)
+		err := runAccounts(context.Background(), sc, args)
+		return out.String(), err
+	}
+
+	out, err := run("list", "-url", server.URL, "-management-key", "secret")
+	if err != nil {
+		t.Fatalf("accounts list: %v", err)
+	}
+	if !strings.Contains(out, "claude-a.json") || !strings.Contains(out, "a@example.com") {
+		t.Fatalf("unexpected listing:\n%s", out)
+	}
+
+	if _, err = run("refresh", "claude-a.json", "-url", server.URL, "-management-key", "secret"); err != nil {
+		t.Fatalf("accounts refresh: %v", err)
+	}
+	if !strings.Contains(refreshed, `"claude-a.json"`) {
+		t.Fatalf("refresh request body = %s", refreshed)
+	}
+
+	if _, err = run("list", "-url", server.URL, "-management-key", "wrong"); err == nil || !strings.Contains(err.Error(), "invalid management key") {
+		t.Fatalf("expected the server error, got %v", err)
+	}
+}
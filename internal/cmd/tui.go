@@ -0,0 +1,310 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/browser"
+	"github.com/tidwall/gjson"
+	"golang.org/x/term"
+)
+
+const (
+	tuiRefreshInterval   = 2 * time.Second
+	tuiLoginPollInterval = 2 * time.Second
+	tuiLoginTimeout      = 5 * time.Minute
+)
+
+// tui runs the interactive terminal UI. Key presses and background results are applied to the
+// state on the main loop only, so the state needs no locking.
+type tui struct {
+	ctx    context.Context
+	sc     *subcommandContext
+	state  tuiState
+	events chan func(*tuiState)
+}
+
+func runTUI(ctx context.Context, sc *subcommandContext, args []string) error {
+	if _, err := sc.parse(args); err != nil {
+		return err
+	}
+	if sc.managementKey == "" {
+		return errors.New("management key is required: pass -management-key or set MANAGEMENT_PASSWORD")
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return errors.New("tui needs an interactive terminal")
+	}
+	saved, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("enter raw mode: %w", err)
+	}
+	defer func() { _ = term.Restore(fd, saved) }()
+	// Alternate screen and hidden cursor, restored on exit.
+	_, _ = io.WriteString(sc.out, "\x1b[?1049h\x1b[?25l")
+	defer func() { _, _ = io.WriteString(sc.out, "\x1b[?25h\x1b[?1049l") }()
+
+	t := &tui{ctx: ctx, sc: sc, events: make(chan func(*tuiState), 16)}
+	keys := make(chan string, 16)
+	go readKeys(os.Stdin, keys)
+
+	ticker := time.NewTicker(tuiRefreshInterval)
+	defer ticker.Stop()
+	t.refresh()
+	for {
+		t.state.width, t.state.height, _ = term.GetSize(int(os.Stdout.Fd()))
+		t.draw()
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-keys:
+			if !ok || t.handleKey(key) {
+				return nil
+			}
+		case apply := <-t.events:
+			apply(&t.state)
+		case <-ticker.C:
+			t.refresh()
+		}
+	}
+}
+
+// draw repaints the whole screen.
+func (t *tui) draw() {
+	lines, highlight := t.state.render(time.Now())
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i == highlight {
+			b.WriteString("\x1b[7m" + line + "\x1b[0m")
+		} else {
+			b.WriteString(line)
+		}
+		b.WriteString("\x1b[K")
+		// Raw mode disables output post-processing, so lines end with an explicit carriage return.
+		if i < len(lines)-1 {
+			b.WriteString("\r\n")
+		}
+	}
+	b.WriteString("\x1b[J")
+	_, _ = io.WriteString(t.sc.out, b.String())
+}
+
+// background runs fn off the main loop and applies its result to the state.
+func (t *tui) background(fn func() func(*tuiState)) {
+	go func() {
+		apply := fn()
+		select {
+		case t.events <- apply:
+		case <-t.ctx.Done():
+		}
+	}()
+}
+
+func (t *tui) refresh() {
+	t.background(func() func(*tuiState) {
+		snap, err := t.fetch()
+		return func(st *tuiState) { st.apply(snap, err, time.Now()) }
+	})
+}
+
+// fetch reads the accounts, their health and the usage statistics from the management API.
+func (t *tui) fetch() (tuiSnapshot, error) {
+	files, err := t.sc.management(t.ctx, http.MethodGet, "/auth-files", nil)
+	if err != nil {
+		return tuiSnapshot{}, err
+	}
+	health, err := t.sc.management(t.ctx, http.MethodGet, "/health", nil, http.StatusServiceUnavailable)
+	if err != nil {
+		return tuiSnapshot{}, err
+	}
+	usage, err := t.sc.management(t.ctx, http.MethodGet, "/usage", nil)
+	if err != nil {
+		return tuiSnapshot{}, err
+	}
+	return buildTUISnapshot(files, health, usage), nil
+}
+
+// handleKey updates the state for a key press and reports whether the TUI should exit.
+func (t *tui) handleKey(key string) bool {
+	st := &t.state
+	switch st.mode {
+	case modeConfirmRemove:
+		st.mode = modeBrowse
+		if key == "y" {
+			if account, ok := st.selectedAccount(); ok {
+				t.remove(account)
+			}
+		}
+		return false
+	case modePickProvider:
+		st.mode = modeBrowse
+		if len(key) == 1 && key[0] >= '1' && int(key[0]-'1') < len(tuiLoginProviders) {
+			t.login(tuiLoginProviders[key[0]-'1'])
+		}
+		return false
+	}
+
+	switch key {
+	case "q", "ctrl+c":
+		return true
+	case "tab":
+		st.view = (st.view + 1) % 2
+	case "1":
+		st.view = viewAccounts
+	case "2":
+		st.view = viewTraffic
+	case "up", "k":
+		st.selected = max(st.selected-1, 0)
+	case "down", "j":
+		st.selected = min(st.selected+1, max(len(st.accounts)-1, 0))
+	case "l":
+		st.mode = modePickProvider
+	case "d":
+		if _, ok := st.selectedAccount(); ok && st.view == viewAccounts {
+			st.mode = modeConfirmRemove
+		}
+	case "r":
+		if account, ok := st.selectedAccount(); ok && st.view == viewAccounts {
+			t.refreshToken(account)
+		}
+	case "esc":
+		st.message = ""
+	}
+	return false
+}
+
+func (t *tui) remove(account tuiAccount) {
+	t.state.message = "removing " + account.Name + "…"
+	t.background(func() func(*tuiState) {
+		_, err := t.sc.management(t.ctx, http.MethodDelete, "/auth-files?name="+url.QueryEscape(account.Name), nil)
+		return func(st *tuiState) {
+			st.message = "removed " + account.Name
+			if err != nil {
+				st.message = "remove " + account.Name + ": " + err.Error()
+			}
+			t.refresh()
+		}
+	})
+}
+
+func (t *tui) refreshToken(account tuiAccount) {
+	t.state.message = "refreshing " + account.Name + "…"
+	t.background(func() func(*tuiState) {
+		_, err := t.sc.management(t.ctx, http.MethodPost, "/auth-files/refresh", map[string]string{"name": account.Name})
+		return func(st *tuiState) {
+			st.message = "refreshed " + account.Name
+			if err != nil {
+				st.message = "refresh " + account.Name + ": " + err.Error()
+			}
+			t.refresh()
+		}
+	})
+}
+
+// login starts an OAuth login on the proxy, opens the authorization page and waits until the
+// proxy reports the outcome. The proxy forwards the provider callback to itself, which works when
+// the browser runs on the same machine.
+func (t *tui) login(provider tuiLoginProvider) {
+	t.state.message = "starting " + provider.name + " login…"
+	t.background(func() func(*tuiState) {
+		data, err := t.sc.management(t.ctx, http.MethodGet, provider.endpoint+"?is_webui=true", nil)
+		if err != nil {
+			return func(st *tuiState) { st.message = provider.name + " login: " + err.Error() }
+		}
+		authURL := gjson.GetBytes(data, "url").String()
+		state := gjson.GetBytes(data, "state").String()
+		prompt := provider.name + " login: open " + authURL
+		if code := gjson.GetBytes(data, "user_code").String(); code != "" {
+			prompt += " and enter code " + code
+		}
+		if authURL != "" {
+			_ = browser.OpenURL(authURL)
+		}
+		t.background(func() func(*tuiState) { return t.waitForLogin(provider, state) })
+		return func(st *tuiState) { st.message = prompt }
+	})
+}
+
+// waitForLogin polls the OAuth session until it completes, fails or times out.
+func (t *tui) waitForLogin(provider tuiLoginProvider, state string) func(*tuiState) {
+	if state == "" {
+		return func(*tuiState) {}
+	}
+	deadline := time.Now().Add(tuiLoginTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-t.ctx.Done():
+			return func(*tuiState) {}
+		case <-time.After(tuiLoginPollInterval):
+		}
+		data, err := t.sc.management(t.ctx, http.MethodGet, "/get-auth-status?state="+url.QueryEscape(state), nil)
+		if err != nil {
+			continue
+		}
+		switch status := gjson.GetBytes(data, "status").String(); status {
+		case "wait", "auth_url", "device_code":
+			continue
+		case "ok":
+			return func(st *tuiState) {
+				st.message = provider.name + " login complete"
+				t.refresh()
+			}
+		default:
+			message := gjson.GetBytes(data, "error").String()
+			return func(st *tuiState) { st.message = provider.name + " login failed: " + message }
+		}
+	}
+	return func(st *tuiState) { st.message = provider.name + " login timed out" }
+}
+
+// readKeys decodes key presses from r until it fails.
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		for _, key := range decodeKeys(buf[:n]) {
+			keys <- key
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// decodeKeys splits terminal input into key names: printable characters, arrows, tab, enter,
+// escape and ctrl+c.
+func decodeKeys(input []byte) []string {
+	var keys []string
+	for i := 0; i < len(input); i++ {
+		switch b := input[i]; {
+		case b == 0x1b && i+2 < len(input) && (input[i+1] == '[' || input[i+1] == 'O'):
+			switch input[i+2] {
+			case 'A':
+				keys = append(keys, "up")
+			case 'B':
+				keys = append(keys, "down")
+			}
+			i += 2
+		case b == 0x1b:
+			keys = append(keys, "esc")
+		case b == 0x03:
+			keys = append(keys, "ctrl+c")
+		case b == '\t':
+			keys = append(keys, "tab")
+		case b == '\r' || b == '\n':
+			keys = append(keys, "enter")
+		case b >= 0x20 && b < 0x7f:
+			keys = append(keys, string(b))
+		}
+	}
+	return keys
+}
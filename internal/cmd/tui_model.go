@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+)
+
+// maxFeedRequests caps the live request feed.
+const maxFeedRequests = 200
+
+type tuiView int
+
+const (
+	viewAccounts tuiView = iota
+	viewTraffic
+)
+
+type tuiMode int
+
+const (
+	modeBrowse tuiMode = iota
+	modeConfirmRemove
+	modePickProvider
+)
+
+// tuiLoginProvider is an OAuth login offered by the TUI and the management endpoint starting it.
+type tuiLoginProvider struct {
+	name     string
+	endpoint string
+}
+
+var tuiLoginProviders = []tuiLoginProvider{
+	{name: "Claude", endpoint: "/anthropic-auth-url"},
+	{name: "Codex", endpoint: "/codex-auth-url"},
+	{name: "Gemini CLI", endpoint: "/gemini-cli-auth-url"},
+	{name: "Antigravity", endpoint: "/antigravity-auth-url"},
+	{name: "Qwen", endpoint: "/qwen-auth-url"},
+	{name: "iFlow", endpoint: "/iflow-auth-url"},
+	{name: "Kiro", endpoint: "/kiro-auth-url"},
+	{name: "GitHub Copilot", endpoint: "/github-auth-url"},
+}
+
+// tuiAccount is one row of the accounts view.
+type tuiAccount struct {
+	ID            string
+	Name          string
+	Provider      string
+	Account       string
+	Status        string
+	Disabled      bool
+	AuthIndex     string
+	CooldownUntil time.Time
+	QuotaExceeded bool
+	TokenExpired  bool
+	Requests      int64
+	Tokens        int64
+}
+
+// tuiRequest is one entry of the live request feed.
+type tuiRequest struct {
+	Time     time.Time
+	Model    string
+	Provider string
+	Account  string
+	Tokens   int64
+	Failed   bool
+}
+
+// tuiSnapshot is the proxy state fetched on every refresh.
+type tuiSnapshot struct {
+	health   string
+	accounts []tuiAccount
+	feed     []tuiRequest
+	requests int64
+	tokens   int64
+}
+
+// tuiState is the model rendered by the TUI.
+type tuiState struct {
+	tuiSnapshot
+	view     tuiView
+	mode     tuiMode
+	selected int
+	message  string
+	err      error
+	updated  time.Time
+	width    int
+	height   int
+}
+
+// buildTUISnapshot merges the auth-files, health and usage responses of the management API.
+// Token counters per account are summed from the request details the usage statistics retain.
+func buildTUISnapshot(files, health, usage []byte) tuiSnapshot {
+	snap := tuiSnapshot{health: gjson.GetBytes(health, "status").String()}
+	healthByID := make(map[string]gjson.Result)
+	for _, account := range gjson.GetBytes(health, "accounts").Array() {
+		healthByID[account.Get("id").String()] = account
+	}
+
+	byIndex := make(map[string]int)
+	for _, file := range gjson.GetBytes(files, "files").Array() {
+		account := tuiAccount{
+			ID:        file.Get("id").String(),
+			Name:      file.Get("name").String(),
+			Provider:  file.Get("provider").String(),
+			Account:   file.Get("email").String(),
+			Status:    file.Get("status").String(),
+			Disabled:  file.Get("disabled").Bool(),
+			AuthIndex: file.Get("auth_index").String(),
+		}
+		if account.Account == "" {
+			account.Account = file.Get("label").String()
+		}
+		if state, ok := healthByID[account.ID]; ok {
+			account.CooldownUntil = state.Get("cooldown_until").Time()
+			account.QuotaExceeded = state.Get("quota_exceeded").Bool()
+			account.TokenExpired = state.Get("token_expired").Bool()
+		}
+		if account.AuthIndex != "" {
+			byIndex[account.AuthIndex] = len(snap.accounts)
+		}
+		snap.accounts = append(snap.accounts, account)
+	}
+
+	stats := gjson.GetBytes(usage, "usage")
+	snap.requests = stats.Get("total_requests").Int()
+	snap.tokens = stats.Get("total_tokens").Int()
+	stats.Get("apis").ForEach(func(_, api gjson.Result) bool {
+		api.Get("models").ForEach(func(model, modelStats gjson.Result) bool {
+			for _, detail := range modelStats.Get("details").Array() {
+				request := tuiRequest{
+					Time:     detail.Get("timestamp").Time(),
+					Model:    model.String(),
+					Provider: detail.Get("provider").String(),
+					Account:  detail.Get("source").String(),
+					Tokens:   detail.Get("tokens.total_tokens").Int(),
+					Failed:   detail.Get("failed").Bool(),
+				}
+				if i, ok := byIndex[detail.Get("auth_index").String()]; ok {
+					account := &snap.accounts[i]
+					account.Requests++
+					account.Tokens += request.Tokens
+					request.Account = account.Name
+				}
+				snap.feed = append(snap.feed, request)
+			}
+			return true
+		})
+		return true
+	})
+	sort.SliceStable(snap.feed, func(i, j int) bool { return snap.feed[i].Time.After(snap.feed[j].Time) })
+	if len(snap.feed) > maxFeedRequests {
+		snap.feed = snap.feed[:maxFeedRequests]
+	}
+	return snap
+}
+
+// apply stores a fetched snapshot, keeping the selection on the same account when possible.
+func (st *tuiState) apply(snap tuiSnapshot, err error, now time.Time) {
+	if err != nil {
+		st.err = err
+		return
+	}
+	selectedID := ""
+	if st.selected < len(st.accounts) {
+		selectedID = st.accounts[st.selected].ID
+	}
+	st.tuiSnapshot = snap
+	st.err = nil
+	st.updated = now
+	st.selected = 0
+	for i, account := range st.accounts {
+		if account.ID == selectedID {
+			st.selected = i
+		}
+	}
+}
+
+// selectedAccount returns the account under the cursor.
+func (st *tuiState) selectedAccount() (tuiAccount, bool) {
+	if st.selected < 0 || st.selected >= len(st.accounts) {
+		return tuiAccount{}, false
+	}
+	return st.accounts[st.selected], true
+}
+
+// render draws the state into terminal lines of at most st.width columns and returns the index
+// of the line to highlight, or -1.
+func (st *tuiState) render(now time.Time) ([]string, int) {
+	width, height := max(st.width, 40), max(st.height, 10)
+	lines := []string{
+		fmt.Sprintf("CLIProxyAPI  %s  requests %d  tokens %s  updated %s",
+			orDash(st.health), st.requests, formatCount(st.tokens), formatClock(st.updated)),
+		tabLabel("1 Accounts", st.view == viewAccounts) + " " + tabLabel("2 Traffic", st.view == viewTraffic),
+		"",
+	}
+	body := height - len(lines) - 2
+	highlight := -1
+	switch st.view {
+	case viewAccounts:
+		accounts, selected := st.renderAccounts(body, now)
+		if selected >= 0 {
+			highlight = len(lines) + selected
+		}
+		lines = append(lines, accounts...)
+	case viewTraffic:
+		lines = append(lines, st.renderTraffic(body)...)
+	}
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, "", st.footer())
+	for i, line := range lines {
+		lines[i] = truncate(line, width)
+	}
+	return lines, highlight
+}
+
+func (st *tuiState) renderAccounts(rows int, now time.Time) ([]string, int) {
+	table := [][]string{{"NAME", "PROVIDER", "ACCOUNT", "STATUS", "REQUESTS", "TOKENS", "NOTE"}}
+	for _, account := range st.accounts {
+		var notes []string
+		if account.Disabled {
+			notes = append(notes, "disabled")
+		}
+		if account.CooldownUntil.After(now) {
+			notes = append(notes, "cooldown "+account.CooldownUntil.Sub(now).Round(time.Second).String())
+		}
+		if account.QuotaExceeded {
+			notes = append(notes, "quota exceeded")
+		}
+		if account.TokenExpired {
+			notes = append(notes, "token expired")
+		}
+		table = append(table, []string{account.Name, account.Provider, account.Account, account.Status,
+			fmt.Sprint(account.Requests), formatCount(account.Tokens), strings.Join(notes, ", ")})
+	}
+	lines := formatTable(table)
+	if len(st.accounts) == 0 {
+		return append(lines, "no accounts; press l to log in"), -1
+	}
+	// Scroll so the selected row stays visible below the header.
+	first := max(0, st.selected-(rows-2))
+	out := []string{lines[0]}
+	selected := -1
+	for i := first; i < len(st.accounts) && len(out) < rows; i++ {
+		if i == st.selected {
+			selected = len(out)
+		}
+		out = append(out, lines[i+1])
+	}
+	return out, selected
+}
+
+func (st *tuiState) renderTraffic(rows int) []string {
+	table := [][]string{{"TIME", "MODEL", "PROVIDER", "ACCOUNT", "TOKENS", "RESULT"}}
+	for _, request := range st.feed {
+		if len(table) >= rows {
+			break
+		}
+		result := "ok"
+		if request.Failed {
+			result = "failed"
+		}
+		table = append(table, []string{formatClock(request.Time), request.Model, request.Provider, request.Account,
+			formatCount(request.Tokens), result})
+	}
+	if len(table) == 1 {
+		return append(formatTable(table), "no requests yet")
+	}
+	return formatTable(table)
+}
+
+func (st *tuiState) footer() string {
+	switch {
+	case st.mode == modeConfirmRemove:
+		account, _ := st.selectedAccount()
+		return fmt.Sprintf("Remove %s? (y/n)", account.Name)
+	case st.mode == modePickProvider:
+		choices := make([]string, 0, len(tuiLoginProviders))
+		for i, provider := range tuiLoginProviders {
+			choices = append(choices, fmt.Sprintf("%d %s", i+1, provider.name))
+		}
+		return "Log in to: " + strings.Join(choices, "  ") + "  (esc cancels)"
+	case st.err != nil:
+		return "error: " + st.err.Error()
+	case st.message != "":
+		return st.message
+	case st.view == viewAccounts:
+		return "↑/↓ select  l log in  r refresh token  d remove  tab switch view  q quit"
+	default:
+		return "tab switch view  q quit"
+	}
+}
+
+// formatTable aligns the columns of rows.
+func formatTable(rows [][]string) []string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		var b strings.Builder
+		for i, cell := range row {
+			b.WriteString(cell)
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2))
+			}
+		}
+		lines = append(lines, strings.TrimRight(b.String(), " "))
+	}
+	return lines
+}
+
+func truncate(line string, width int) string {
+	if utf8.RuneCountInString(line) <= width {
+		return line
+	}
+	runes := []rune(line)
+	return string(runes[:width-1]) + "…"
+}
+
+func tabLabel(label string, active bool) string {
+	if active {
+		return "[" + label + "]"
+	}
+	return " " + label + " "
+}
+
+func formatCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprint(n)
+	}
+}
+
+func formatClock(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.TimeOnly)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildTUISnapshot(t *testing.T) {
+	files := []byte(`{"files":[
+		{"id":"claude-a.json","auth_index":"1","name":"claude-a.json","provider":"claude","email":"a@example.com","status":"active"},
+		{"id":"codex-b.json","auth_index":"2","name":"codex-b.json","provider":"codex","label":"b","status":"error"}]}`)
+	health := []byte(`{"status":"degraded","accounts":[{"id":"codex-b.json","cooldown_until":"2030-01-01T00:00:00Z","quota_exceeded":true}]}`)
+	usage := []byte(`{"usage":{"total_requests":3,"total_tokens":600,"apis":{"key":{"models":{
+		"claude-sonnet":{"details":[
+			{"timestamp":"2025-01-01T10:00:00Z","auth_index":"1","provider":"claude","tokens":{"total_tokens":100}},
+			{"timestamp":"2025-01-01T10:02:00Z","auth_index":"1","provider":"claude","tokens":{"total_tokens":200}}]},
+		"gpt-5":{"details":[
+			{"timestamp":"2025-01-01T10:01:00Z","auth_index":"2","provider":"codex","failed":true,"tokens":{"total_tokens":300}}]}}}}}}`)
+
+	snap := buildTUISnapshot(files, health, usage)
+	if snap.health != "degraded" || snap.requests != 3 || snap.tokens != 600 {
+		t.Fatalf("totals = %+v", snap)
+	}
+	if len(snap.accounts) != 2 {
+		t.Fatalf("accounts = %+v", snap.accounts)
+	}
+	if a := snap.accounts[0]; a.Requests != 2 || a.Tokens != 300 || a.Account != "a@example.com" {
+		t.Fatalf("claude account = %+v", a)
+	}
+	if b := snap.accounts[1]; !b.QuotaExceeded || b.CooldownUntil.IsZero() || b.Account != "b" || b.Tokens != 300 {
+		t.Fatalf("codex account = %+v", b)
+	}
+	var models []string
+	for _, request := range snap.feed {
+		models = append(models, request.Model)
+	}
+	if want := []string{"claude-sonnet", "gpt-5", "claude-sonnet"}; !reflect.DeepEqual(models, want) {
+		t.Fatalf("feed order = %v, want %v", models, want)
+	}
+	if !snap.feed[1].Failed || snap.feed[1].Account != "codex-b.json" {
+		t.Fatalf("feed entry = %+v", snap.feed[1])
+	}
+}
+
+func TestTUIRender(t *testing.T) {
+	now := time.Date(2029, 12, 31, 23, 59, 0, 0, time.UTC)
+	st := &tuiState{width: 80, height: 12}
+	st.apply(tuiSnapshot{health: "healthy", accounts: []tuiAccount{
+		{ID: "a", Name: "claude-a.json", Provider: "claude", Status: "active"},
+		{ID: "b", Name: "codex-b.json", Provider: "codex", Status: "active", CooldownUntil: now.Add(time.Minute)},
+	}}, nil, now)
+	st.selected = 1
+
+	lines, highlight := st.render(now)
+	if len(lines) != 12 {
+		t.Fatalf("rendered %d lines, want 12", len(lines))
+	}
+	if highlight < 0 || !strings.Contains(lines[highlight], "codex-b.json") {
+		t.Fatalf("highlight %d does not mark the selected account:\n%s", highlight, strings.Join(lines, "\n"))
+	}
+	if !strings.Contains(lines[highlight], "cooldown 1m0s") {
+		t.Fatalf("cooldown missing: %q", lines[highlight])
+	}
+
+	// A refresh keeps the cursor on the same account even when the order changes.
+	st.apply(tuiSnapshot{accounts: []tuiAccount{{ID: "b"}, {ID: "a"}}}, nil, now)
+	if st.selected != 0 {
+		t.Fatalf("selected = %d, want 0", st.selected)
+	}
+}
+
+func TestDecodeKeys(t *testing.T) {
+	got := decodeKeys([]byte("q\x1b[A\x1b[B\t\x1b\x03"))
+	want := []string{"q", "up", "down", "tab", "esc", "ctrl+c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("decodeKeys = %v, want %v", got, want)
+	}
+}
@@ -35,12 +35,32 @@ type Config struct {
 	// RemoteManagement nests management-related options under 'remote-management'.
 	RemoteManagement RemoteManagement `yaml:"remote-management" json:"-"`
 
+	// OAuthCallback configures the local callback server used by CLI logins.
+	OAuthCallback OAuthCallbackConfig `yaml:"oauth-callback,omitempty" json:"oauth-callback,omitempty"`
+
 	// AuthDir is the directory where authentication token files are stored.
 	AuthDir string `yaml:"auth-dir" json:"-"`
 
+	// ProviderProxies overrides proxy-url per provider (e.g. "antigravity", "vertex"). A value of
+	// "direct" sends that provider's traffic without any proxy. Per-key and per-account proxy-url
+	// settings still take precedence.
+	ProviderProxies map[string]string `yaml:"provider-proxies,omitempty" json:"provider-proxies,omitempty"`
+
 	// Debug enables or disables debug-level logging and other debug features.
 	Debug bool `yaml:"debug" json:"debug"`
 
+	// LogLevel sets the minimum level of application logs: "debug", "info", "warn" or "error".
+	// Empty means "info"; debug: true always logs at debug level.
+	LogLevel string `yaml:"log-level,omitempty" json:"log-level,omitempty"`
+
+	// DebugModules logs the named modules at debug level whatever LogLevel is, e.g. "translator" or
+	// "executor" without "auth".
+	// A module matches any package directory of the code writing the log entry.
+	DebugModules []string `yaml:"debug-modules,omitempty" json:"debug-modules,omitempty"`
+
+	// LogFormat selects the application log format: "text" (default) or "json".
+	LogFormat string `yaml:"log-format,omitempty" json:"log-format,omitempty"`
+
 	// CommercialMode disables high-overhead HTTP middleware features to minimize per-request memory usage.
 	CommercialMode bool `yaml:"commercial-mode" json:"commercial-mode"`
 
@@ -55,9 +75,61 @@ type Config struct {
 	// When exceeded, the oldest error log files are deleted. Default is 10. Set to 0 to disable cleanup.
 	ErrorLogsMaxFiles int `yaml:"error-logs-max-files" json:"error-logs-max-files"`
 
+	// LogRotation controls rotation of main.log and the age limit of every log file, including
+	// request captures.
+	LogRotation LogRotationConfig `yaml:"log-rotation,omitempty" json:"log-rotation,omitempty"`
+
+	// RequestLogCompression stores request logs zstd-compressed (*.log.zst) and records each one in
+	// a searchable index (request ID, key hash, model, time, size, error class).
+	RequestLogCompression bool `yaml:"request-log-compression,omitempty" json:"request-log-compression,omitempty"`
+
+	// CheckForUpdates enables a startup check against the project's releases. Disabled by default.
+	CheckForUpdates bool `yaml:"check-for-updates,omitempty" json:"check-for-updates,omitempty"`
+
 	// UsageStatisticsEnabled toggles in-memory usage aggregation; when false, usage data is discarded.
 	UsageStatisticsEnabled bool `yaml:"usage-statistics-enabled" json:"usage-statistics-enabled"`
 
+	// UsageSnapshots persists daily usage aggregates so past days can be compared after restarts.
+	UsageSnapshots UsageSnapshotConfig `yaml:"usage-snapshots,omitempty" json:"usage-snapshots,omitempty"`
+
+	// UsageReports writes daily or weekly usage summaries to disk, a webhook or an S3 bucket.
+	UsageReports UsageReportConfig `yaml:"usage-reports,omitempty" json:"usage-reports,omitempty"`
+
+	// Batch enables the OpenAI Batch API (/v1/files and /v1/batches) backed by a local job store.
+	Batch BatchConfig `yaml:"batch,omitempty" json:"batch,omitempty"`
+
+	// GRPC serves the management API, and optionally chat inference, as gRPC services on the API port.
+	GRPC GRPCConfig `yaml:"grpc,omitempty" json:"grpc,omitempty"`
+
+	// AuthGuard throttles and temporarily bans clients that repeatedly present invalid API keys or
+	// management keys.
+	AuthGuard AuthGuardConfig `yaml:"auth-guard,omitempty" json:"auth-guard,omitempty"`
+
+	// NetworkAccess restricts inbound clients by address and names the reverse proxies whose
+	// forwarded headers carry the client address.
+	NetworkAccess NetworkAccessConfig `yaml:"network-access,omitempty" json:"network-access,omitempty"`
+
+	// RequestLimits caps the size of inbound request bodies and of the base64 payloads inside them.
+	RequestLimits RequestLimitsConfig `yaml:"request-limits,omitempty" json:"request-limits,omitempty"`
+
+	// ProviderStatus polls upstream status pages so incidents are surfaced to operators and
+	// degraded providers are tried last when a model is served by several providers.
+	ProviderStatus ProviderStatusConfig `yaml:"provider-status,omitempty" json:"provider-status,omitempty"`
+
+	// AuditLog keeps a per-request audit trail queryable through the management API.
+	AuditLog AuditLogConfig `yaml:"audit-log,omitempty" json:"audit-log,omitempty"`
+
+	// Tenants enables tenant API keys issued and revoked through the management API.
+	Tenants TenantsConfig `yaml:"tenants,omitempty" json:"tenants,omitempty"`
+
+	// Telemetry periodically reports anonymous aggregate counters to a maintainer endpoint.
+	// Disabled unless explicitly enabled.
+	Telemetry TelemetryConfig `yaml:"telemetry,omitempty" json:"telemetry,omitempty"`
+
+	// UsageAttribution controls how client API keys are recorded in usage statistics.
+	// Supported values: "key" (default, raw key), "hash" (truncated SHA-256), "provider" (access provider name).
+	UsageAttribution string `yaml:"usage-attribution,omitempty" json:"usage-attribution,omitempty"`
+
 	// DisableCooling disables quota cooldown scheduling when true.
 	DisableCooling bool `yaml:"disable-cooling" json:"disable-cooling"`
 
@@ -65,6 +137,9 @@ type Config struct {
 	RequestRetry int `yaml:"request-retry" json:"request-retry"`
 	// MaxRetryInterval defines the maximum wait time in seconds before retrying a cooled-down credential.
 	MaxRetryInterval int `yaml:"max-retry-interval" json:"max-retry-interval"`
+	// RetryPolicy replays upstream requests on the same credential after transient failures,
+	// before the request moves on to the next credential.
+	RetryPolicy RetryPolicy `yaml:"retry-policy,omitempty" json:"retry-policy,omitempty"`
 
 	// QuotaExceeded defines the behavior when a quota is exceeded.
 	QuotaExceeded QuotaExceeded `yaml:"quota-exceeded" json:"quota-exceeded"`
@@ -91,6 +166,19 @@ type Config struct {
 	// ClaudeKey defines a list of Claude API key configurations as specified in the YAML configuration file.
 	ClaudeKey []ClaudeKey `yaml:"claude-api-key" json:"claude-api-key"`
 
+	// MistralKey defines a list of Mistral La Plateforme API key configurations. base-url defaults
+	// to https://api.mistral.ai/v1; Codestral keys use https://codestral.mistral.ai/v1.
+	MistralKey []ProviderKey `yaml:"mistral-api-key" json:"mistral-api-key"`
+
+	// XAIKey defines a list of xAI (Grok) API key configurations.
+	XAIKey []ProviderKey `yaml:"xai-api-key" json:"xai-api-key"`
+
+	// DeepSeekKey defines a list of DeepSeek API key configurations.
+	DeepSeekKey []ProviderKey `yaml:"deepseek-api-key" json:"deepseek-api-key"`
+
+	// OllamaKey defines a list of local Ollama servers serving chat models.
+	OllamaKey []ProviderKey `yaml:"ollama" json:"ollama"`
+
 	// OpenAICompatibility defines OpenAI API compatibility configurations for external providers.
 	OpenAICompatibility []OpenAICompatibility `yaml:"openai-compatibility" json:"openai-compatibility"`
 
@@ -98,6 +186,11 @@ type Config struct {
 	// Used for services that use Vertex AI-style paths but with simple API key authentication.
 	VertexCompatAPIKey []VertexCompatKey `yaml:"vertex-api-key" json:"vertex-api-key"`
 
+	// VertexRegions lists, per model, the Vertex AI regions tried in order by service account
+	// credentials. A region answering 429 RESOURCE_EXHAUSTED is skipped for the next one. Vertex API
+	// keys are not covered since their base-url fixes the endpoint.
+	VertexRegions []VertexRegionRule `yaml:"vertex-regions,omitempty" json:"vertex-regions,omitempty"`
+
 	// AmpCode contains Amp CLI upstream configuration, management restrictions, and model mappings.
 	AmpCode AmpCode `yaml:"ampcode" json:"ampcode"`
 
@@ -110,18 +203,56 @@ type Config struct {
 	// gemini-cli, vertex, aistudio, antigravity, claude, codex, qwen, iflow, kiro, github-copilot.
 	//
 	// NOTE: This does not apply to existing per-credential model alias features under:
-	// gemini-api-key, codex-api-key, claude-api-key, openai-compatibility, vertex-api-key, and ampcode.
+	// gemini-api-key, codex-api-key, claude-api-key, mistral-api-key, xai-api-key, deepseek-api-key, ollama, openai-compatibility, vertex-api-key, and ampcode.
 	OAuthModelAlias map[string][]OAuthModelAlias `yaml:"oauth-model-alias,omitempty" json:"oauth-model-alias,omitempty"`
 
+	// ModelCapabilities overrides advertised model capabilities (context length, token limits, thinking).
+	// Changes are applied on config reload without restarting the server.
+	ModelCapabilities []ModelCapability `yaml:"model-capabilities,omitempty" json:"model-capabilities,omitempty"`
+
 	// Payload defines default and override rules for provider payload parameters.
 	Payload PayloadConfig `yaml:"payload" json:"payload"`
 
+	// RemoteMedia controls downloading of remote image/file URLs referenced in requests.
+	RemoteMedia RemoteMediaConfig `yaml:"remote-media" json:"remote-media"`
+
+	// GeminiContextCache maps Claude cache_control system breakpoints to Gemini cachedContents
+	// resources for gemini-api-key credentials. Cached contents are billed storage, so this is
+	// opt-in. Default: false.
+	GeminiContextCache bool `yaml:"gemini-context-cache" json:"gemini-context-cache"`
+
 	// IncognitoBrowser enables opening OAuth URLs in incognito/private browsing mode.
 	// This is useful when you want to login with a different account without logging out
 	// from your current session. Default: false.
 	IncognitoBrowser bool `yaml:"incognito-browser" json:"incognito-browser"`
 
 	legacyMigrationPending bool `yaml:"-" json:"-"`
+
+	// layout records the included files and the active profile the config was assembled from.
+	layout *configLayout
+}
+
+// LogRotationConfig controls how log files are rotated and how long they are kept.
+type LogRotationConfig struct {
+	// MaxSizeMB is the size at which main.log is rotated. Zero means 10 MB.
+	MaxSizeMB int `yaml:"max-size-mb,omitempty" json:"max-size-mb,omitempty"`
+	// MaxBackups is the number of rotated main.log files kept. Zero keeps all of them.
+	MaxBackups int `yaml:"max-backups,omitempty" json:"max-backups,omitempty"`
+	// MaxAgeDays deletes rotated main.log files, request captures and error logs older than this
+	// many days. Zero keeps them regardless of age.
+	MaxAgeDays int `yaml:"max-age-days,omitempty" json:"max-age-days,omitempty"`
+	// Compress gzips rotated main.log files.
+	Compress bool `yaml:"compress,omitempty" json:"compress,omitempty"`
+}
+
+// OAuthCallbackConfig configures the local OAuth callback server of CLI logins.
+type OAuthCallbackConfig struct {
+	// Host is the bind address of the callback server. Empty binds all interfaces.
+	Host string `yaml:"host,omitempty" json:"host,omitempty"`
+
+	// PortRange is how many ports after the default callback port are tried when it is busy.
+	// Only providers whose redirect URI follows the bound port (iFlow, Antigravity) use it.
+	PortRange int `yaml:"port-range,omitempty" json:"port-range,omitempty"`
 }
 
 // TLSConfig holds HTTPS server settings.
@@ -132,6 +263,66 @@ type TLSConfig struct {
 	Cert string `yaml:"cert" json:"cert"`
 	// Key is the path to the TLS private key file.
 	Key string `yaml:"key" json:"key"`
+	// ClientAuth enables mutual TLS: client certificates authenticate requests like API keys.
+	ClientAuth TLSClientAuthConfig `yaml:"client-auth,omitempty" json:"client-auth,omitempty"`
+	// ACME obtains and renews the certificate automatically instead of reading Cert and Key.
+	ACME TLSACMEConfig `yaml:"acme,omitempty" json:"acme,omitempty"`
+}
+
+// TLSACMEConfig configures automatic certificates from an ACME CA such as Let's Encrypt.
+// TLS-ALPN-01 challenges are answered on the HTTPS listener, which therefore has to be reachable
+// on port 443; HTTP-01 challenges need HTTPPort to be reachable on port 80.
+type TLSACMEConfig struct {
+	// Domains lists the host names to obtain certificates for. ACME is used when it is non-empty.
+	Domains []string `yaml:"domains,omitempty" json:"domains,omitempty"`
+	// Email is the contact address registered with the CA for expiry and revocation notices.
+	Email string `yaml:"email,omitempty" json:"email,omitempty"`
+	// CacheDir persists the account key and certificates across restarts. Defaults to "acme"
+	// under WRITABLE_PATH or the auth dir.
+	CacheDir string `yaml:"cache-dir,omitempty" json:"cache-dir,omitempty"`
+	// DirectoryURL selects the ACME CA, e.g. the Let's Encrypt staging directory. Defaults to
+	// Let's Encrypt production.
+	DirectoryURL string `yaml:"directory-url,omitempty" json:"directory-url,omitempty"`
+	// HTTPPort, usually 80, serves HTTP-01 challenges and redirects other plain HTTP requests to
+	// HTTPS. Zero disables the plain HTTP listener.
+	HTTPPort int `yaml:"http-port,omitempty" json:"http-port,omitempty"`
+}
+
+// TLSClientAuthConfig configures client certificate authentication on the HTTPS listener.
+// Whether certificates are requested during the handshake is decided at startup; the CA bundle,
+// revocation lists and identities are reloaded with the config.
+type TLSClientAuthConfig struct {
+	// Mode is "optional" to verify certificates clients present and still accept bearer keys, or
+	// "require" to reject connections without a valid certificate. Empty or "off" disables mTLS.
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+	// CA is the path to the PEM bundle of the CAs that issue client certificates.
+	CA string `yaml:"ca,omitempty" json:"ca,omitempty"`
+	// CRL lists paths to certificate revocation lists (PEM or DER) of those CAs.
+	CRL []string `yaml:"crl,omitempty" json:"crl,omitempty"`
+	// OCSP queries the responders named in client certificates: "soft" rejects certificates
+	// reported revoked, "hard" also rejects certificates whose status cannot be determined.
+	// Empty or "off" skips OCSP.
+	OCSP string `yaml:"ocsp,omitempty" json:"ocsp,omitempty"`
+	// Identities maps client certificates to the API keys they authenticate as.
+	Identities []ClientCertIdentity `yaml:"identities,omitempty" json:"identities,omitempty"`
+}
+
+// ClientCertIdentity maps matching client certificates to an API key. Every non-empty matcher
+// must match; the key does not have to be listed in api-keys, and per-key settings such as
+// protocol access, budgets and priorities apply to it.
+type ClientCertIdentity struct {
+	// CommonName matches the subject common name.
+	CommonName string `yaml:"common-name,omitempty" json:"common-name,omitempty"`
+	// DNSName matches a DNS subject alternative name.
+	DNSName string `yaml:"dns-name,omitempty" json:"dns-name,omitempty"`
+	// Email matches an email subject alternative name.
+	Email string `yaml:"email,omitempty" json:"email,omitempty"`
+	// URI matches a URI subject alternative name, e.g. a SPIFFE ID.
+	URI string `yaml:"uri,omitempty" json:"uri,omitempty"`
+	// Fingerprint matches the SHA-256 fingerprint of the certificate in hex; colons are ignored.
+	Fingerprint string `yaml:"fingerprint,omitempty" json:"fingerprint,omitempty"`
+	// APIKey is the API key the certificate authenticates as.
+	APIKey string `yaml:"api-key" json:"api-key"`
 }
 
 // RemoteManagement holds management API configuration under 'remote-management'.
@@ -147,6 +338,30 @@ type RemoteManagement struct {
 	PanelGitHubRepository string `yaml:"panel-github-repository"`
 }
 
+// RetryPolicy configures replay of upstream HTTP requests after transient failures.
+// The already-translated request body is resent on the same credential; once the attempts are
+// exhausted the response is returned and the auth manager may switch to another credential.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per credential, including the first one.
+	// Values of 1 or less disable the policy.
+	MaxAttempts int `yaml:"max-attempts,omitempty" json:"max-attempts,omitempty"`
+
+	// InitialBackoff is the delay before the first replay as a Go duration. Defaults to "500ms".
+	// Each further replay doubles the delay.
+	InitialBackoff string `yaml:"initial-backoff,omitempty" json:"initial-backoff,omitempty"`
+
+	// MaxBackoff caps the delay between attempts as a Go duration. Defaults to "10s".
+	// A Retry-After longer than this skips the replay so another credential can be tried.
+	MaxBackoff string `yaml:"max-backoff,omitempty" json:"max-backoff,omitempty"`
+
+	// RetryOn lists the HTTP status codes that trigger a replay. Defaults to 408, 500, 502, 503 and 504.
+	// Network errors are always replayed.
+	RetryOn []int `yaml:"retry-on,omitempty" json:"retry-on,omitempty"`
+
+	// IgnoreRetryAfter disables honouring the upstream Retry-After header.
+	IgnoreRetryAfter bool `yaml:"ignore-retry-after,omitempty" json:"ignore-retry-after,omitempty"`
+}
+
 // QuotaExceeded defines the behavior when API quota limits are exceeded.
 // It provides configuration options for automatic failover mechanisms.
 type QuotaExceeded struct {
@@ -162,6 +377,94 @@ type RoutingConfig struct {
 	// Strategy selects the credential selection strategy.
 	// Supported values: "round-robin" (default), "fill-first".
 	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// SessionAffinity routes later turns of a conversation to the credential that served the
+	// first one, so provider-side prompt caching and thought signatures keep working. Another
+	// credential is used while the sticky one is cooling down or unavailable.
+	SessionAffinity bool `yaml:"session-affinity,omitempty" json:"session-affinity,omitempty"`
+
+	// SessionAffinityTTL is how long an idle conversation stays pinned, as a Go duration. Defaults to 1h.
+	SessionAffinityTTL string `yaml:"session-affinity-ttl,omitempty" json:"session-affinity-ttl,omitempty"`
+
+	// MaxConcurrencyPerAuth caps the in-flight requests of each credential, so parallel streams
+	// spread over accounts instead of triggering provider-side abuse detection. 0 disables the cap.
+	MaxConcurrencyPerAuth int `yaml:"max-concurrency-per-auth,omitempty" json:"max-concurrency-per-auth,omitempty"`
+
+	// ProviderMaxConcurrency overrides MaxConcurrencyPerAuth for the credentials of a provider,
+	// keyed by provider name (e.g. "claude", "gemini-cli"). 0 disables the cap for that provider.
+	ProviderMaxConcurrency map[string]int `yaml:"provider-max-concurrency,omitempty" json:"provider-max-concurrency,omitempty"`
+
+	// ConcurrencyWait is how long a request waits for a free slot when every credential is at its
+	// cap, as a Go duration. Empty fails such requests with 429 right away.
+	ConcurrencyWait string `yaml:"concurrency-wait,omitempty" json:"concurrency-wait,omitempty"`
+
+	// AccountCaps limits how many tokens or requests a credential may use per usage window. A
+	// credential past a hard cap leaves rotation until its window resets; one past a soft cap is
+	// only used while no other credential is available. The first matching entry applies.
+	AccountCaps []AccountCap `yaml:"account-caps,omitempty" json:"account-caps,omitempty"`
+
+	// LabelRules override routing settings of the credentials carrying given labels. For each
+	// setting, the first entry that matches a credential and sets it applies.
+	LabelRules []LabelRule `yaml:"label-rules,omitempty" json:"label-rules,omitempty"`
+}
+
+// LabelRule applies routing settings to the credentials selected by labels.
+type LabelRule struct {
+	// Labels selects the credentials carrying all of these labels, e.g. {region: eu}. A "*" value
+	// only requires the label to be set. Empty matches every credential.
+	Labels map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+
+	// Priority overrides the priority of matching credentials; higher priorities are used first.
+	Priority *int `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// MaxConcurrency overrides max-concurrency-per-auth and provider-max-concurrency for matching
+	// credentials. 0 disables the cap.
+	MaxConcurrency *int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+}
+
+// AccountCap defines the usage caps of a group of credentials.
+type AccountCap struct {
+	// Provider limits the entry to credentials of this provider, e.g. "claude". Empty matches all.
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+
+	// Auths limits the entry to these credentials, given as auth IDs, labels or account emails.
+	// Empty matches every credential of Provider.
+	Auths []string `yaml:"auths,omitempty" json:"auths,omitempty"`
+
+	// Labels limits the entry to credentials carrying all of these labels, e.g. {tier: pro}.
+	// A "*" value only requires the label to be set.
+	Labels map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+
+	// SoftTokens and HardTokens cap the tokens used per window. 0 disables the cap.
+	SoftTokens int64 `yaml:"soft-tokens,omitempty" json:"soft-tokens,omitempty"`
+	HardTokens int64 `yaml:"hard-tokens,omitempty" json:"hard-tokens,omitempty"`
+
+	// SoftRequests and HardRequests cap the successful requests per window. 0 disables the cap.
+	SoftRequests int64 `yaml:"soft-requests,omitempty" json:"soft-requests,omitempty"`
+	HardRequests int64 `yaml:"hard-requests,omitempty" json:"hard-requests,omitempty"`
+
+	// Window overrides the provider's reset schedule. A Go duration such as "5h" is a rolling window
+	// starting with the first request; "daily" or "daily@HH:MM" resets every day at that time.
+	// Empty uses the provider default: 5-hour windows for claude and codex, daily at midnight
+	// Pacific time for gemini-cli and antigravity, and daily at midnight UTC otherwise.
+	Window string `yaml:"window,omitempty" json:"window,omitempty"`
+
+	// Timezone is the IANA time zone of daily resets. Empty uses UTC, or the provider default.
+	Timezone string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+}
+
+// RemoteMediaConfig configures the opt-in fetcher that inlines remote media URLs
+// (e.g. OpenAI image_url entries pointing at https URLs) for upstreams that only accept inline data.
+type RemoteMediaConfig struct {
+	// Enable turns on fetching of remote media URLs. Default: false.
+	Enable bool `yaml:"enable" json:"enable"`
+	// MaxSizeMB caps the size of a single downloaded file. Defaults to 20 when unset.
+	MaxSizeMB int `yaml:"max-size-mb,omitempty" json:"max-size-mb,omitempty"`
+	// TimeoutSeconds bounds each download. Defaults to 15 when unset.
+	TimeoutSeconds int `yaml:"timeout-seconds,omitempty" json:"timeout-seconds,omitempty"`
+	// AllowedHosts restricts downloads to these hosts. Entries may use a leading "*." wildcard.
+	// When empty, any public https host is allowed.
+	AllowedHosts []string `yaml:"allowed-hosts,omitempty" json:"allowed-hosts,omitempty"`
 }
 
 // OAuthModelAlias defines a model ID alias for a specific channel.
@@ -231,6 +534,240 @@ type AmpUpstreamAPIKeyEntry struct {
 	APIKeys []string `yaml:"api-keys" json:"api-keys"`
 }
 
+// UsageSnapshotConfig configures the daily usage snapshots.
+type UsageSnapshotConfig struct {
+	// Enabled writes a snapshot of each day's usage aggregates. Requires usage-statistics-enabled.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Dir is where snapshots are stored. Defaults to "usage-snapshots" under WRITABLE_PATH or the auth dir.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// RetentionDays deletes snapshots older than the given number of days. 0 keeps them forever.
+	RetentionDays int `yaml:"retention-days,omitempty" json:"retention-days,omitempty"`
+
+	// DetailRetention drops per-request details from memory once older than this Go duration,
+	// e.g. "168h". Older usage stays available through the snapshots. Values below 48h are raised
+	// to 48h; empty keeps all details.
+	DetailRetention string `yaml:"detail-retention,omitempty" json:"detail-retention,omitempty"`
+}
+
+// UsageReportConfig configures the scheduled usage reports. Reports are built from the daily
+// usage snapshots, so days before the last restart are only covered with usage-snapshots enabled.
+type UsageReportConfig struct {
+	// Enabled starts the report scheduler.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Schedule is "daily" (the previous day, default) or "weekly" (the previous Monday to Sunday).
+	Schedule string `yaml:"schedule,omitempty" json:"schedule,omitempty"`
+
+	// Format is "json" (default) or "csv".
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+
+	// Dir is where reports are written. Defaults to "usage-reports" under WRITABLE_PATH or the auth dir.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// Webhook receives each report as a POST request when set.
+	Webhook string `yaml:"webhook,omitempty" json:"webhook,omitempty"`
+
+	// S3 uploads each report to an S3-compatible bucket when Bucket is set.
+	S3 UsageReportS3Config `yaml:"s3,omitempty" json:"s3,omitempty"`
+}
+
+// UsageReportS3Config describes the S3-compatible bucket usage reports are uploaded to.
+type UsageReportS3Config struct {
+	Endpoint  string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	Bucket    string `yaml:"bucket,omitempty" json:"bucket,omitempty"`
+	Prefix    string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	Region    string `yaml:"region,omitempty" json:"region,omitempty"`
+	AccessKey string `yaml:"access-key,omitempty" json:"access-key,omitempty"`
+	SecretKey string `yaml:"secret-key,omitempty" json:"secret-key,omitempty"`
+	UseSSL    bool   `yaml:"use-ssl,omitempty" json:"use-ssl,omitempty"`
+	PathStyle bool   `yaml:"path-style,omitempty" json:"path-style,omitempty"`
+}
+
+// BatchConfig configures the OpenAI-compatible Batch API. Uploaded input files, batch jobs and
+// their result files are stored under Dir; batch requests are executed in the background
+// against the configured upstreams.
+type BatchConfig struct {
+	// Enabled exposes /v1/files and /v1/batches and runs queued batches.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Dir is where files and batches are stored. Defaults to "batches" under WRITABLE_PATH or the auth dir.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// Concurrency is the number of requests of one batch executed in parallel. Defaults to 2.
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+
+	// MaxRequests caps the number of requests in one batch input file. Defaults to 50000.
+	MaxRequests int `yaml:"max-requests,omitempty" json:"max-requests,omitempty"`
+
+	// MaxFileSizeMB caps the size of uploaded files. Defaults to 200.
+	MaxFileSizeMB int `yaml:"max-file-size-mb,omitempty" json:"max-file-size-mb,omitempty"`
+}
+
+// GRPCConfig configures the gRPC gateway. The services are described in
+// internal/grpcgateway/cliproxy.proto and share the API port with the REST API; without TLS the
+// port accepts cleartext HTTP/2 (h2c) when the gateway is enabled at startup.
+type GRPCConfig struct {
+	// Enabled serves the cliproxy.v1.Management service.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Inference additionally serves the bidirectional cliproxy.v1.Inference/Chat stream.
+	Inference bool `yaml:"inference,omitempty" json:"inference,omitempty"`
+}
+
+// AuthGuardConfig configures brute-force protection for client authentication. Failed attempts
+// from one IP are answered with an exponentially growing backoff, and after MaxFailures failures
+// the IP is banned; repeat offenders get doubled bans. Management endpoints are always protected
+// for remote clients; Enabled extends the protection to the API key check of inbound requests.
+// Loopback clients are never throttled.
+type AuthGuardConfig struct {
+	// Enabled applies the guard to inbound API key authentication.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// MaxFailures is the number of consecutive failures that triggers a ban. Defaults to 5.
+	MaxFailures int `yaml:"max-failures,omitempty" json:"max-failures,omitempty"`
+
+	// Backoff is the delay imposed after the first failure as a Go duration, doubled after each
+	// further failure. Defaults to "1s".
+	Backoff string `yaml:"backoff,omitempty" json:"backoff,omitempty"`
+
+	// BanDuration is the length of the first ban as a Go duration. Defaults to "30m".
+	BanDuration string `yaml:"ban-duration,omitempty" json:"ban-duration,omitempty"`
+
+	// MaxBanDuration caps the doubled bans of repeat offenders. Defaults to "24h".
+	MaxBanDuration string `yaml:"max-ban-duration,omitempty" json:"max-ban-duration,omitempty"`
+
+	// AlertThreshold is the number of bans within AlertWindow reported as a sustained attack.
+	// Defaults to 3.
+	AlertThreshold int `yaml:"alert-threshold,omitempty" json:"alert-threshold,omitempty"`
+
+	// AlertWindow is the sliding window for AlertThreshold as a Go duration. Defaults to "10m".
+	AlertWindow string `yaml:"alert-window,omitempty" json:"alert-window,omitempty"`
+
+	// AlertWebhook receives a JSON POST for each sustained-attack alert when set.
+	AlertWebhook string `yaml:"alert-webhook,omitempty" json:"alert-webhook,omitempty"`
+}
+
+// NetworkAccessConfig configures inbound access control by client address. Entries are CIDR
+// ranges or single addresses. The client address is the peer address of the connection, unless
+// the peer is a trusted proxy, in which case it is taken from the forwarded headers.
+type NetworkAccessConfig struct {
+	// Allow, when non-empty, admits only clients within these ranges.
+	Allow []string `yaml:"allow,omitempty" json:"allow,omitempty"`
+
+	// Deny rejects clients within these ranges, even when they are allowed.
+	Deny []string `yaml:"deny,omitempty" json:"deny,omitempty"`
+
+	// TrustedProxies lists the reverse proxies whose forwarded headers are honoured.
+	TrustedProxies []string `yaml:"trusted-proxies,omitempty" json:"trusted-proxies,omitempty"`
+
+	// ClientIPHeaders are the headers consulted, in order, for requests from trusted proxies. The
+	// first one present is used; if it is malformed, the proxy address is used.
+	// Defaults to X-Forwarded-For and X-Real-IP.
+	ClientIPHeaders []string `yaml:"client-ip-headers,omitempty" json:"client-ip-headers,omitempty"`
+}
+
+// RequestLimitsConfig configures request size limits. Oversized requests are rejected with 413
+// before they reach a handler. Zero values disable the respective limit.
+type RequestLimitsConfig struct {
+	// MaxBodySizeMB caps every request body.
+	MaxBodySizeMB int `yaml:"max-body-size-mb,omitempty" json:"max-body-size-mb,omitempty"`
+
+	// Endpoints override MaxBodySizeMB for request paths; the longest matching path wins.
+	Endpoints []EndpointBodyLimit `yaml:"endpoints,omitempty" json:"endpoints,omitempty"`
+
+	// MaxInlineDataMB caps the decoded size of a single base64 payload in a JSON body, such as a
+	// data: URL image or Claude and Gemini inline image data.
+	MaxInlineDataMB int `yaml:"max-inline-data-mb,omitempty" json:"max-inline-data-mb,omitempty"`
+}
+
+// EndpointBodyLimit caps the request bodies of one path and the paths below it.
+type EndpointBodyLimit struct {
+	Path string `yaml:"path" json:"path"`
+
+	// MaxBodySizeMB caps the request body; zero lifts the limit for the path.
+	MaxBodySizeMB int `yaml:"max-body-size-mb" json:"max-body-size-mb"`
+}
+
+// VertexRegionRule maps models to an ordered list of Vertex AI regions.
+type VertexRegionRule struct {
+	// Models lists model names the rule applies to; '*' matches any run of characters.
+	Models []string `yaml:"models" json:"models"`
+
+	// Regions lists the regions tried in order, e.g. "us-central1", "us-east5", "global".
+	Regions []string `yaml:"regions" json:"regions"`
+}
+
+// AuditLogConfig configures the per-request audit trail.
+type AuditLogConfig struct {
+	// Enabled records an entry for every request to the authenticated API routes.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// MaxEntries is how many recent entries are kept in memory for queries. Defaults to 10000.
+	MaxEntries int `yaml:"max-entries,omitempty" json:"max-entries,omitempty"`
+
+	// File additionally appends every entry to this file as JSON lines when set.
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+}
+
+// TenantsConfig configures tenant API keys. Tenants, their labels, model allowlists, quotas and
+// hashed keys are managed through the management API and stored in File rather than in this
+// config.
+type TenantsConfig struct {
+	// Enabled accepts tenant API keys and exposes the /v0/management/tenants endpoints.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// File stores the tenants. Defaults to "tenants.json" under WRITABLE_PATH or the auth dir.
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+}
+
+// TelemetryConfig configures the opt-in anonymous telemetry reporter. Reports carry the build
+// version, platform, request and failure counts per provider and error class counts; never
+// request content, model names, API keys, account identifiers or addresses.
+type TelemetryConfig struct {
+	// Enabled turns on reporting. Requires Endpoint.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Endpoint receives the reports as JSON POST requests.
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+
+	// Interval between reports as a Go duration. Defaults to 24h; values below 1h are raised to 1h.
+	Interval string `yaml:"interval,omitempty" json:"interval,omitempty"`
+}
+
+// ProviderStatusConfig configures polling of upstream provider status feeds.
+type ProviderStatusConfig struct {
+	// Enabled starts the status poller.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Interval between polls as a Go duration. Defaults to 5m; values below 1m are raised to 1m.
+	Interval string `yaml:"interval,omitempty" json:"interval,omitempty"`
+
+	// Feeds replaces the built-in Anthropic, OpenAI and Google Cloud feeds when set.
+	Feeds []ProviderStatusFeed `yaml:"feeds,omitempty" json:"feeds,omitempty"`
+}
+
+// ProviderStatusFeed describes one status feed and the providers it covers.
+type ProviderStatusFeed struct {
+	// Name labels the feed in logs and the management API.
+	Name string `yaml:"name" json:"name"`
+
+	// Format is "statuspage" (Atlassian Statuspage summary.json) or "google-cloud"
+	// (status.cloud.google.com incidents.json).
+	Format string `yaml:"format" json:"format"`
+
+	// URL of the feed.
+	URL string `yaml:"url" json:"url"`
+
+	// Providers lists the provider identifiers (e.g. "claude", "gemini") affected by the feed's incidents.
+	Providers []string `yaml:"providers" json:"providers"`
+
+	// Products limits google-cloud incidents to those affecting a product whose title contains one
+	// of these strings (case-insensitive). Empty matches every incident.
+	Products []string `yaml:"products,omitempty" json:"products,omitempty"`
+}
+
 // PayloadConfig defines default and override parameter rules applied to provider payloads.
 type PayloadConfig struct {
 	// Default defines rules that only set parameters when they are missing in the payload.
@@ -559,16 +1096,31 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 	cfg.AmpCode.RestrictManagementToLocalhost = false // Default to false: API key auth is sufficient
 	cfg.RemoteManagement.PanelGitHubRepository = DefaultPanelGitHubRepository
 	cfg.IncognitoBrowser = false // Default to normal browser (AWS uses incognito by force)
-	if err = yaml.Unmarshal(data, &cfg); err != nil {
+	var root yaml.Node
+	if err = yaml.Unmarshal(data, &root); err != nil {
 		if optional {
 			// In cloud deploy mode, if YAML parsing fails, return empty config instead of error.
 			return &Config{}, nil
 		}
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	// Merge included files and the active profile, resolving secret references file by file.
+	doc, layout, err := assembleConfig(configFile, &root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg.layout = layout
+	if len(doc.Content) > 0 {
+		if err = doc.Decode(&cfg); err != nil {
+			if optional {
+				return &Config{}, nil
+			}
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
 
 	var legacy legacyConfigData
-	if errLegacy := yaml.Unmarshal(data, &legacy); errLegacy == nil {
+	if errLegacy := doc.Decode(&legacy); errLegacy == nil {
 		if cfg.migrateLegacyGeminiKeys(legacy.LegacyGeminiKeys) {
 			cfg.legacyMigrationPending = true
 		}
@@ -591,7 +1143,12 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 
 		// Persist the hashed value back to the config file to avoid re-hashing on next startup.
 		// Preserve YAML comments and ordering; update only the nested key.
-		_ = SaveConfigPreserveCommentsUpdateNestedScalar(configFile, []string{"remote-management", "secret-key"}, hashed)
+		// A secret key given as a reference is hashed in memory only; the reference stays in the file.
+		if !layout.secretKeyIsReference {
+			src := layout.sourceOf("remote-management")
+			path := append(append([]string(nil), src.path...), "remote-management", "secret-key")
+			_ = SaveConfigPreserveCommentsUpdateNestedScalar(src.file, path, hashed)
+		}
 	}
 
 	cfg.RemoteManagement.PanelGitHubRepository = strings.TrimSpace(cfg.RemoteManagement.PanelGitHubRepository)
@@ -607,6 +1164,10 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 		cfg.ErrorLogsMaxFiles = 10
 	}
 
+	cfg.LogRotation.MaxSizeMB = max(cfg.LogRotation.MaxSizeMB, 0)
+	cfg.LogRotation.MaxBackups = max(cfg.LogRotation.MaxBackups, 0)
+	cfg.LogRotation.MaxAgeDays = max(cfg.LogRotation.MaxAgeDays, 0)
+
 	// Sync request authentication providers with inline API keys for backwards compatibility.
 	syncInlineAccessProvider(&cfg)
 
@@ -622,21 +1183,33 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 	// Sanitize Claude key headers
 	cfg.SanitizeClaudeKeys()
 
+	// Sanitize Mistral, xAI, DeepSeek and Ollama entries
+	cfg.SanitizeProviderKeys()
+
 	// Sanitize Kiro keys: trim whitespace from credential fields
 	cfg.SanitizeKiroKeys()
 
 	// Sanitize OpenAI compatibility providers: drop entries without base-url
 	cfg.SanitizeOpenAICompatibility()
 
+	// Validate per-provider proxies and drop invalid entries.
+	cfg.SanitizeProviderProxies()
+
 	// Normalize OAuth provider model exclusion map.
 	cfg.OAuthExcludedModels = NormalizeOAuthExcludedModels(cfg.OAuthExcludedModels)
 
 	// Normalize global OAuth model name aliases.
 	cfg.SanitizeOAuthModelAlias()
 
+	// Normalize model capability overrides.
+	cfg.ModelCapabilities = NormalizeModelCapabilities(cfg.ModelCapabilities)
+
 	// Validate raw payload rules and drop invalid entries.
 	cfg.SanitizePayloadRules()
 
+	// Validate safety thresholds against the Gemini categories and drop invalid entries.
+	cfg.SafetySettings = NormalizeSafetySettings(cfg.SafetySettings)
+
 	if cfg.legacyMigrationPending {
 		fmt.Println("Detected legacy configuration keys, attempting to persist the normalized config...")
 		if !optional && configFile != "" {
@@ -958,10 +1531,73 @@ func hashSecret(secret string) (string, error) {
 
 // SaveConfigPreserveComments writes the config back to YAML while preserving existing comments
 // and key ordering by loading the original file into a yaml.Node tree and updating values in-place.
+// Top-level keys read from an included file or from the active profile are written back there.
 func SaveConfigPreserveComments(configFile string, cfg *Config) error {
 	persistCfg := sanitizeConfigForPersist(cfg)
+	// Marshal the current cfg to YAML, then unmarshal to a yaml.Node we can merge from.
+	rendered, err := yaml.Marshal(persistCfg)
+	if err != nil {
+		return err
+	}
+	var generated yaml.Node
+	if err = yaml.Unmarshal(rendered, &generated); err != nil {
+		return err
+	}
+	if generated.Kind != yaml.DocumentNode || len(generated.Content) == 0 || generated.Content[0] == nil {
+		return fmt.Errorf("invalid generated yaml structure")
+	}
+	if generated.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("expected generated root mapping node")
+	}
+
+	var layout *configLayout
+	if cfg != nil {
+		layout = cfg.layout
+	}
+	if layout == nil || len(layout.owners) == 0 || absPath(layout.main) != absPath(configFile) {
+		return mergeIntoConfigFile(configFile, nil, generated.Content[0], nil)
+	}
+
+	// Split the generated keys by the file and mapping they were read from.
+	type target struct {
+		src  configSource
+		keys *yaml.Node
+	}
+	var targets []*target
+	byKey := make(map[string]*target)
+	foreign := make(map[string]bool)
+	mainKeys := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	root := generated.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i].Value
+		src, owned := layout.owners[key]
+		if !owned {
+			mainKeys.Content = append(mainKeys.Content, root.Content[i], root.Content[i+1])
+			continue
+		}
+		foreign[key] = true
+		id := src.file + "\x00" + strings.Join(src.path, "\x00")
+		t := byKey[id]
+		if t == nil {
+			t = &target{src: src, keys: &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}}
+			byKey[id] = t
+			targets = append(targets, t)
+		}
+		t.keys.Content = append(t.keys.Content, root.Content[i], root.Content[i+1])
+	}
+	for _, t := range targets {
+		if err = mergeIntoConfigFile(t.src.file, t.src.path, t.keys, nil); err != nil {
+			return err
+		}
+	}
+	return mergeIntoConfigFile(configFile, nil, mainKeys, foreign)
+}
+
+// mergeIntoConfigFile merges the generated mapping into the mapping at path of file, preserving
+// comments and ordering. Keys in skip are left untouched.
+func mergeIntoConfigFile(file string, path []string, generated *yaml.Node, skip map[string]bool) error {
 	// Load original YAML as a node tree to preserve comments and ordering.
-	data, err := os.ReadFile(configFile)
+	data, err := os.ReadFile(file)
 	if err != nil {
 		return err
 	}
@@ -976,38 +1612,39 @@ func SaveConfigPreserveComments(configFile string, cfg *Config) error {
 	if original.Content[0] == nil || original.Content[0].Kind != yaml.MappingNode {
 		return fmt.Errorf("expected root mapping node")
 	}
-
-	// Marshal the current cfg to YAML, then unmarshal to a yaml.Node we can merge from.
-	rendered, err := yaml.Marshal(persistCfg)
-	if err != nil {
-		return err
-	}
-	var generated yaml.Node
-	if err = yaml.Unmarshal(rendered, &generated); err != nil {
-		return err
-	}
-	if generated.Kind != yaml.DocumentNode || len(generated.Content) == 0 || generated.Content[0] == nil {
-		return fmt.Errorf("invalid generated yaml structure")
-	}
-	if generated.Content[0].Kind != yaml.MappingNode {
-		return fmt.Errorf("expected generated root mapping node")
+	dst := original.Content[0]
+	for _, key := range path {
+		next := getOrCreateMapValue(dst, key)
+		if next.Kind != yaml.MappingNode {
+			next.Kind = yaml.MappingNode
+			next.Tag = "!!map"
+			next.Content = nil
+		}
+		dst = next
 	}
 
 	// Remove deprecated sections before merging back the sanitized config.
-	removeLegacyAuthBlock(original.Content[0])
-	removeLegacyOpenAICompatAPIKeys(original.Content[0])
-	removeLegacyAmpKeys(original.Content[0])
-	removeLegacyGenerativeLanguageKeys(original.Content[0])
+	if len(path) == 0 {
+		removeLegacyAuthBlock(dst)
+		removeLegacyOpenAICompatAPIKeys(dst)
+		removeLegacyAmpKeys(dst)
+		removeLegacyGenerativeLanguageKeys(dst)
+	}
 
-	pruneMappingToGeneratedKeys(original.Content[0], generated.Content[0], "oauth-excluded-models")
-	pruneMappingToGeneratedKeys(original.Content[0], generated.Content[0], "oauth-model-alias")
+	for _, key := range []string{"oauth-excluded-models", "oauth-model-alias", "model-capabilities", "traffic-pause",
+		"request-queue", "latency-routing", "usage-snapshots", "batch", "retry-policy", "routing", "provider-status",
+		"auth-guard", "system-prompts"} {
+		if !skip[key] {
+			pruneMappingToGeneratedKeys(dst, generated, key)
+		}
+	}
 
 	// Merge generated into original in-place, preserving comments/order of existing nodes.
-	mergeMappingPreserve(original.Content[0], generated.Content[0])
+	mergeMappingPreserve(dst, generated)
 	normalizeCollectionNodeStyles(original.Content[0])
 
 	// Write back.
-	f, err := os.Create(configFile)
+	f, err := os.Create(file)
 	if err != nil {
 		return err
 	}
@@ -1212,6 +1849,10 @@ func mergeNodePreserve(dst, src *yaml.Node) {
 			dst.Content = dst.Content[:len(src.Content)]
 		}
 	case yaml.ScalarNode, yaml.AliasNode:
+		// Keep env and secret references that still resolve to the value being written.
+		if dst.Kind == yaml.ScalarNode && src.Kind == yaml.ScalarNode && keepsReference(dst.Value, src.Value) {
+			return
+		}
 		// For scalars, update Tag and Value but keep Style from dst to preserve quoting
 		dst.Kind = src.Kind
 		dst.Tag = src.Tag
@@ -1365,7 +2006,7 @@ func matchSequenceElement(original []*yaml.Node, used []bool, target *yaml.Node)
 				if used[i] || original[i] == nil || original[i].Kind != yaml.ScalarNode {
 					continue
 				}
-				if strings.TrimSpace(original[i].Value) == val {
+				if strings.TrimSpace(resolvedValue(original[i].Value)) == val {
 					return i
 				}
 			}
@@ -1391,7 +2032,7 @@ func sequenceElementIdentity(node *yaml.Node) string {
 	identityKeys := []string{"id", "name", "alias", "api-key", "api_key", "apikey", "key", "provider", "model"}
 	for _, k := range identityKeys {
 		if v := mappingScalarValue(node, k); v != "" {
-			return k + "=" + v
+			return k + "=" + resolvedValue(v)
 		}
 	}
 	for i := 0; i+1 < len(node.Content); i += 2 {
@@ -1400,7 +2041,7 @@ func sequenceElementIdentity(node *yaml.Node) string {
 		if keyNode == nil || valNode == nil || valNode.Kind != yaml.ScalarNode {
 			continue
 		}
-		val := strings.TrimSpace(valNode.Value)
+		val := strings.TrimSpace(resolvedValue(valNode.Value))
 		if val != "" {
 			return strings.ToLower(strings.TrimSpace(keyNode.Value)) + "=" + val
 		}
@@ -0,0 +1,289 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	includeKey  = "include"
+	profileKey  = "profile"
+	profilesKey = "profiles"
+	// maxIncludeDepth bounds nested includes.
+	maxIncludeDepth = 8
+)
+
+// mainFileOnlyKeys are top-level keys that grant code execution or management access. They are
+// only read from the main config file, never from included files or profiles, so a file that
+// merely matches an include glob (e.g. an uploaded auth file) cannot set them.
+var mainFileOnlyKeys = map[string]bool{
+	"request-scripts":   true,
+	"remote-management": true,
+}
+
+// selectedProfile is the profile chosen on the command line. It overrides the profile key of the
+// config file.
+var selectedProfile string
+
+// SetProfile selects the named profile for every following config load. An empty name falls back
+// to the profile key of the config file. It must be called before the config is first loaded.
+func SetProfile(name string) {
+	selectedProfile = strings.TrimSpace(name)
+}
+
+// configSource is where a top-level config key was read from: the mapping at path in file.
+type configSource struct {
+	file string
+	path []string
+}
+
+// configLayout records how a config was assembled from its files.
+type configLayout struct {
+	main    string
+	profile string
+	// owners maps top-level keys read from an included file or from the active profile to their
+	// source. Keys of the main file are absent.
+	owners map[string]configSource
+	// files lists the included files in load order.
+	files []string
+	// secretKeyIsReference is set when remote-management.secret-key is an env or secret reference.
+	secretKeyIsReference bool
+}
+
+// sourceOf returns where the top-level key was read from.
+func (l *configLayout) sourceOf(key string) configSource {
+	if l != nil {
+		if src, ok := l.owners[key]; ok {
+			return src
+		}
+		return configSource{file: l.main}
+	}
+	return configSource{}
+}
+
+// SourceFiles returns the files included by the config, not counting the main config file.
+func (cfg *Config) SourceFiles() []string {
+	if cfg == nil || cfg.layout == nil {
+		return nil
+	}
+	return append([]string(nil), cfg.layout.files...)
+}
+
+// Profile returns the profile the config was loaded with, or "" when none is active.
+func (cfg *Config) Profile() string {
+	if cfg == nil || cfg.layout == nil {
+		return ""
+	}
+	return cfg.layout.profile
+}
+
+// assembleConfig merges the main config document with its includes and the active profile into a
+// single document with resolved secret references.
+//
+// Every file contributes top-level keys: its includes are read first in the order listed, then
+// its own keys replace any earlier value of the same key. The active profile is applied last in
+// the same way, so its keys and includes win over everything else. Include paths may be globs and
+// are relative to the file that names them; profile includes are relative to the main file.
+func assembleConfig(configFile string, root *yaml.Node) (*yaml.Node, *configLayout, error) {
+	layout := &configLayout{main: configFile, owners: make(map[string]configSource)}
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{merged}}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		// Leave non-mapping documents to the decoder, which reports them.
+		if err := ResolveSecretReferences(root, filepath.Dir(configFile)); err != nil {
+			return nil, nil, err
+		}
+		return root, layout, nil
+	}
+	main := root.Content[0]
+	visited := map[string]bool{absPath(configFile): true}
+	if err := layout.mergeLayer(merged, main, configSource{file: configFile}, filepath.Dir(configFile), visited, 0); err != nil {
+		return nil, nil, err
+	}
+
+	layout.profile = selectedProfile
+	if layout.profile == "" {
+		layout.profile = mappingScalarValue(main, profileKey)
+	}
+	if layout.profile == "" {
+		return doc, layout, nil
+	}
+	var profile *yaml.Node
+	if idx := findMapKeyIndex(main, profilesKey); idx >= 0 {
+		profiles := main.Content[idx+1]
+		if pIdx := findMapKeyIndex(profiles, layout.profile); pIdx >= 0 {
+			profile = profiles.Content[pIdx+1]
+		}
+	}
+	if profile == nil {
+		return nil, nil, fmt.Errorf("profile %q is not defined under %s", layout.profile, profilesKey)
+	}
+	if profile.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("profile %q must be a mapping", layout.profile)
+	}
+	src := configSource{file: configFile, path: []string{profilesKey, layout.profile}}
+	if err := layout.mergeLayer(merged, profile, src, filepath.Dir(configFile), visited, 0); err != nil {
+		return nil, nil, err
+	}
+	return doc, layout, nil
+}
+
+// mergeLayer merges the includes of layer and then its own keys into merged.
+func (l *configLayout) mergeLayer(merged, layer *yaml.Node, src configSource, baseDir string, visited map[string]bool, depth int) error {
+	includes, err := includePaths(layer, baseDir)
+	if err != nil {
+		return fmt.Errorf("%s: %w", src.file, err)
+	}
+	for _, path := range includes {
+		abs := absPath(path)
+		if visited[abs] {
+			return fmt.Errorf("%s: include cycle through %s", src.file, path)
+		}
+		if depth >= maxIncludeDepth {
+			return fmt.Errorf("%s: includes nested deeper than %d levels", src.file, maxIncludeDepth)
+		}
+		data, errRead := os.ReadFile(path)
+		if errRead != nil {
+			return fmt.Errorf("read included config: %w", errRead)
+		}
+		var root yaml.Node
+		if errParse := yaml.Unmarshal(data, &root); errParse != nil {
+			return fmt.Errorf("parse included config %s: %w", path, errParse)
+		}
+		l.files = append(l.files, path)
+		if len(root.Content) == 0 {
+			continue
+		}
+		if root.Content[0].Kind != yaml.MappingNode {
+			return fmt.Errorf("included config %s must be a mapping", path)
+		}
+		visited[abs] = true
+		errMerge := l.mergeLayer(merged, root.Content[0], configSource{file: path}, filepath.Dir(path), visited, depth+1)
+		delete(visited, abs)
+		if errMerge != nil {
+			return errMerge
+		}
+	}
+
+	r := &referenceResolver{baseDir: baseDir, vault: make(map[string]map[string]any)}
+	for i := 0; i+1 < len(layer.Content); i += 2 {
+		key, value := layer.Content[i].Value, layer.Content[i+1]
+		switch key {
+		case includeKey, profileKey, profilesKey:
+			continue
+		}
+		isMain := src.file == l.main && len(src.path) == 0
+		if !isMain && mainFileOnlyKeys[key] {
+			return fmt.Errorf("%s: %s can only be set in the main config file", src.file, key)
+		}
+		if key == "remote-management" {
+			l.secretKeyIsReference = isSecretReference(mappingScalarValue(value, "secret-key"))
+		}
+		value = deepCopyNode(value)
+		if err = r.walk(value, key); err != nil {
+			return fmt.Errorf("%s: %w", src.file, err)
+		}
+		if idx := findMapKeyIndex(merged, key); idx >= 0 {
+			merged.Content[idx+1] = value
+		} else {
+			merged.Content = append(merged.Content, deepCopyNode(layer.Content[i]), value)
+		}
+		if isMain {
+			delete(l.owners, key)
+		} else {
+			l.owners[key] = src
+		}
+	}
+	return nil
+}
+
+// includePaths expands the include list of layer. Globs may match nothing; plain paths must exist.
+func includePaths(layer *yaml.Node, baseDir string) ([]string, error) {
+	idx := findMapKeyIndex(layer, includeKey)
+	if idx < 0 {
+		return nil, nil
+	}
+	node := layer.Content[idx+1]
+	var entries []string
+	switch node.Kind {
+	case yaml.ScalarNode:
+		entries = []string{node.Value}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("%s entries must be paths", includeKey)
+			}
+			entries = append(entries, item.Value)
+		}
+	default:
+		return nil, fmt.Errorf("%s must be a path or a list of paths", includeKey)
+	}
+	var paths []string
+	for _, entry := range entries {
+		entry, err := expandEnv(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", includeKey, err)
+		}
+		if entry == "" {
+			continue
+		}
+		if !filepath.IsAbs(entry) {
+			entry = filepath.Join(baseDir, entry)
+		}
+		if !strings.ContainsAny(entry, "*?[") {
+			paths = append(paths, entry)
+			continue
+		}
+		matches, err := filepath.Glob(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern %q: %w", includeKey, entry, err)
+		}
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// LayoutKeysEqual reports whether two YAML config documents declare the same include, profile and
+// profiles keys. The management API uses it to refuse changes to where the config is read from.
+func LayoutKeysEqual(a, b []byte) bool {
+	var rootA, rootB yaml.Node
+	if yaml.Unmarshal(a, &rootA) != nil || yaml.Unmarshal(b, &rootB) != nil {
+		return false
+	}
+	for _, key := range []string{includeKey, profileKey, profilesKey} {
+		if !reflect.DeepEqual(layoutKeyValue(&rootA, key), layoutKeyValue(&rootB, key)) {
+			return false
+		}
+	}
+	return true
+}
+
+// layoutKeyValue decodes the top-level key of a YAML document, or returns nil when it is absent.
+func layoutKeyValue(root *yaml.Node, key string) any {
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	idx := findMapKeyIndex(root.Content[0], key)
+	if idx < 0 {
+		return nil
+	}
+	var value any
+	if err := root.Content[0].Content[idx+1].Decode(&value); err != nil {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadConfigIncludesAndProfiles(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"config.yaml": `include:
+  - keys.yaml
+  - conf.d/*.yaml
+port: 8400
+profile: home
+profiles:
+  home:
+    proxy-url: socks5://home:1080
+  work:
+    include: work.yaml
+    proxy-url: http://work:3128
+`,
+		"keys.yaml":          "api-keys:\n  - shared-key\nport: 9999\n",
+		"conf.d/10-a.yaml":   "request-retry: 2\n",
+		"conf.d/20-b.yaml":   "request-retry: 5\n",
+		"work.yaml":          "api-keys:\n  - work-key\n",
+		"conf.d/ignored.txt": "port: 1\n",
+	})
+	configFile := filepath.Join(dir, "config.yaml")
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Port != 8400 {
+		t.Errorf("port = %d, want the main file to win over includes", cfg.Port)
+	}
+	if len(cfg.APIKeys) != 1 || cfg.APIKeys[0] != "shared-key" {
+		t.Errorf("api-keys = %v", cfg.APIKeys)
+	}
+	if cfg.RequestRetry != 5 {
+		t.Errorf("request-retry = %d, want the last glob match to win", cfg.RequestRetry)
+	}
+	if cfg.Profile() != "home" || cfg.ProxyURL != "socks5://home:1080" {
+		t.Errorf("profile = %q, proxy-url = %q", cfg.Profile(), cfg.ProxyURL)
+	}
+	if got := len(cfg.SourceFiles()); got != 3 {
+		t.Errorf("source files = %v", cfg.SourceFiles())
+	}
+
+	SetProfile("work")
+	defer SetProfile("")
+	cfg, err = LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig work: %v", err)
+	}
+	if cfg.ProxyURL != "http://work:3128" || len(cfg.APIKeys) != 1 || cfg.APIKeys[0] != "work-key" {
+		t.Errorf("work profile: proxy-url = %q, api-keys = %v", cfg.ProxyURL, cfg.APIKeys)
+	}
+
+	SetProfile("missing")
+	if _, err = LoadConfig(configFile); err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("LoadConfig with an undefined profile: %v", err)
+	}
+}
+
+func TestLoadConfigRejectsIncludeCycle(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"config.yaml": "include: a.yaml\n",
+		"a.yaml":      "include: b.yaml\n",
+		"b.yaml":      "include: a.yaml\n",
+	})
+	if _, err := LoadConfig(filepath.Join(dir, "config.yaml")); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("LoadConfig error = %v, want an include cycle", err)
+	}
+}
+
+func TestLoadConfigRefusesPrivilegedKeysOutsideMainFile(t *testing.T) {
+	script := "request-scripts:\n  - stage: inbound\n    command: [\"sh\", \"-c\", \"id\"]\n"
+	dir := writeConfigFiles(t, map[string]string{
+		"config.yaml":     "include: auths/*.json\n",
+		"auths/evil.json": script,
+	})
+	if _, err := LoadConfig(filepath.Join(dir, "config.yaml")); err == nil || !strings.Contains(err.Error(), "request-scripts") {
+		t.Fatalf("LoadConfig error = %v, want request-scripts refused from an include", err)
+	}
+
+	dir = writeConfigFiles(t, map[string]string{
+		"config.yaml": "profile: dev\nprofiles:\n  dev:\n    remote-management:\n      allow-remote: true\n",
+	})
+	if _, err := LoadConfig(filepath.Join(dir, "config.yaml")); err == nil || !strings.Contains(err.Error(), "remote-management") {
+		t.Fatalf("LoadConfig error = %v, want remote-management refused from a profile", err)
+	}
+}
+
+func TestSaveConfigWritesKeysBackToTheirSource(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"config.yaml": `include: keys.yaml
+# main settings
+port: 8400
+proxy-url: http://default:3128
+profile: work
+profiles:
+  work:
+    proxy-url: http://work:3128
+`,
+		"keys.yaml": "# shared keys\napi-keys:\n  - old-key\n",
+	})
+	configFile := filepath.Join(dir, "config.yaml")
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	cfg.APIKeys = []string{"new-key"}
+	cfg.ProxyURL = "http://work2:3128"
+	cfg.Port = 8500
+	if err = SaveConfigPreserveComments(configFile, cfg); err != nil {
+		t.Fatalf("SaveConfigPreserveComments: %v", err)
+	}
+
+	keys, _ := os.ReadFile(filepath.Join(dir, "keys.yaml"))
+	if !strings.Contains(string(keys), "new-key") || !strings.Contains(string(keys), "# shared keys") {
+		t.Errorf("keys.yaml:\n%s", keys)
+	}
+	main, _ := os.ReadFile(configFile)
+	for _, want := range []string{"port: 8500", "proxy-url: http://default:3128", "proxy-url: http://work2:3128", "include: keys.yaml", "# main settings"} {
+		if !strings.Contains(string(main), want) {
+			t.Errorf("config.yaml lacks %q:\n%s", want, main)
+		}
+	}
+	if strings.Contains(string(main), "new-key") {
+		t.Errorf("config.yaml copied included keys:\n%s", main)
+	}
+
+	reloaded, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig after save: %v", err)
+	}
+	if reloaded.Port != 8500 || reloaded.ProxyURL != "http://work2:3128" || len(reloaded.APIKeys) != 1 || reloaded.APIKeys[0] != "new-key" {
+		t.Errorf("reloaded port = %d, proxy-url = %q, api-keys = %v", reloaded.Port, reloaded.ProxyURL, reloaded.APIKeys)
+	}
+}
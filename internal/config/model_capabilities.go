@@ -0,0 +1,118 @@
+package config
+
+import "strings"
+
+// ModelCapability overrides the advertised capabilities of a model without requiring a rebuild.
+// Zero values leave the built-in or provider-reported value untouched.
+type ModelCapability struct {
+	// ID is the model identifier the override applies to (e.g., "gemini-2.5-pro").
+	ID string `yaml:"id" json:"id"`
+
+	// DisplayName replaces the human-readable model name in listings.
+	DisplayName string `yaml:"display-name,omitempty" json:"display-name,omitempty"`
+
+	// Description replaces the model description in listings.
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+
+	// ContextLength is the context window size reported to OpenAI-style clients.
+	ContextLength int `yaml:"context-length,omitempty" json:"context-length,omitempty"`
+
+	// MaxCompletionTokens is the maximum completion tokens reported to OpenAI-style clients.
+	MaxCompletionTokens int `yaml:"max-completion-tokens,omitempty" json:"max-completion-tokens,omitempty"`
+
+	// InputTokenLimit is the maximum input token limit reported to Gemini-style clients.
+	InputTokenLimit int `yaml:"input-token-limit,omitempty" json:"input-token-limit,omitempty"`
+
+	// OutputTokenLimit is the maximum output token limit reported to Gemini-style clients.
+	OutputTokenLimit int `yaml:"output-token-limit,omitempty" json:"output-token-limit,omitempty"`
+
+	// Vision overrides whether the model accepts image input. False replaces image parts in requests.
+	Vision *bool `yaml:"vision,omitempty" json:"vision,omitempty"`
+
+	// Tools overrides whether the model supports tool/function calling. False drops tools from requests.
+	Tools *bool `yaml:"tools,omitempty" json:"tools,omitempty"`
+
+	// JSONSchema overrides whether the model accepts JSON-schema constrained output. False
+	// downgrades schema-constrained requests to plain JSON output.
+	JSONSchema *bool `yaml:"json-schema,omitempty" json:"json-schema,omitempty"`
+
+	// Thinking replaces the model's thinking budget support when set.
+	Thinking *ModelCapabilityThinking `yaml:"thinking,omitempty" json:"thinking,omitempty"`
+
+	// Pricing sets the model's token prices, used for model listings and usage cost estimates.
+	Pricing *ModelPricing `yaml:"pricing,omitempty" json:"pricing,omitempty"`
+}
+
+// ModelPricing holds token prices in USD per million tokens.
+type ModelPricing struct {
+	// Input is the price of prompt tokens that were not served from cache.
+	Input float64 `yaml:"input" json:"input"`
+	// Output is the price of completion tokens, including reasoning tokens.
+	Output float64 `yaml:"output" json:"output"`
+	// CachedInput is the price of cached prompt tokens. Zero bills them at the Input price.
+	CachedInput float64 `yaml:"cached-input,omitempty" json:"cached-input,omitempty"`
+}
+
+// ModelCapabilityThinking describes the thinking budget range for a model capability override.
+type ModelCapabilityThinking struct {
+	// Min is the minimum allowed thinking budget (inclusive).
+	Min int `yaml:"min,omitempty" json:"min,omitempty"`
+	// Max is the maximum allowed thinking budget (inclusive).
+	Max int `yaml:"max,omitempty" json:"max,omitempty"`
+	// ZeroAllowed indicates whether 0 is a valid value (to disable thinking).
+	ZeroAllowed bool `yaml:"zero-allowed,omitempty" json:"zero-allowed,omitempty"`
+	// DynamicAllowed indicates whether -1 is a valid value (dynamic thinking budget).
+	DynamicAllowed bool `yaml:"dynamic-allowed,omitempty" json:"dynamic-allowed,omitempty"`
+	// Levels defines discrete reasoning effort levels (e.g., "low", "medium", "high").
+	Levels []string `yaml:"levels,omitempty" json:"levels,omitempty"`
+}
+
+// NormalizeModelCapabilities trims model IDs, drops entries without an ID and keeps
+// only the last entry for each model so later definitions win.
+func NormalizeModelCapabilities(entries []ModelCapability) []ModelCapability {
+	if len(entries) == 0 {
+		return nil
+	}
+	index := make(map[string]int, len(entries))
+	out := make([]ModelCapability, 0, len(entries))
+	for _, entry := range entries {
+		entry.ID = strings.TrimSpace(entry.ID)
+		if entry.ID == "" {
+			continue
+		}
+		entry.DisplayName = strings.TrimSpace(entry.DisplayName)
+		entry.Description = strings.TrimSpace(entry.Description)
+		if entry.Thinking != nil {
+			thinking := *entry.Thinking
+			levels := make([]string, 0, len(thinking.Levels))
+			for _, level := range thinking.Levels {
+				if level = strings.ToLower(strings.TrimSpace(level)); level != "" {
+					levels = append(levels, level)
+				}
+			}
+			thinking.Levels = nil
+			if len(levels) > 0 {
+				thinking.Levels = levels
+			}
+			entry.Thinking = &thinking
+		}
+		if entry.Pricing != nil {
+			pricing := *entry.Pricing
+			if pricing.Input < 0 || pricing.Output < 0 || pricing.CachedInput < 0 {
+				pricing = ModelPricing{}
+			}
+			entry.Pricing = &pricing
+		}
+		key := strings.ToLower(entry.ID)
+		if pos, ok := index[key]; ok {
+			out[pos] = entry
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, entry)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+package config
+
+import "strings"
+
+// DefaultOllamaBaseURL is the address of a local Ollama server.
+const DefaultOllamaBaseURL = "http://localhost:11434"
+
+// ProviderKey is one credential of a provider listed in APIKeyProviders, e.g. an entry of
+// mistral-api-key. All of these providers share the same fields and the same sanitize, synthesize,
+// diff and model-alias handling.
+type ProviderKey struct {
+	// APIKey is the authentication key sent to the provider. Keyless providers only need it
+	// when the server sits behind an authenticating proxy.
+	APIKey string `yaml:"api-key,omitempty" json:"api-key,omitempty"`
+
+	// Priority controls selection preference when multiple credentials match.
+	// Higher values are preferred; defaults to 0.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// Prefix optionally namespaces models for this credential (e.g., "teamA/codestral-latest").
+	Prefix string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+
+	// BaseURL overrides the provider's API endpoint. If empty, the provider default is used.
+	BaseURL string `yaml:"base-url,omitempty" json:"base-url,omitempty"`
+
+	// KeepAlive is only used by Ollama. It controls how long the server keeps a model loaded
+	// after a request, as an Ollama duration such as "10m" or "-1" for forever. Empty uses the
+	// server default. A keep_alive field in the request body takes precedence.
+	KeepAlive string `yaml:"keep-alive,omitempty" json:"keep-alive,omitempty"`
+
+	// ProxyURL overrides the global proxy setting for this credential if provided.
+	ProxyURL string `yaml:"proxy-url,omitempty" json:"proxy-url,omitempty"`
+
+	// Models defines upstream model names and aliases for request routing.
+	// When empty, the provider's built-in model list is registered.
+	Models []ProviderModel `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// Headers optionally adds extra HTTP headers for requests sent with this credential.
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+
+	// ExcludedModels lists model IDs that should be excluded for this provider.
+	ExcludedModels []string `yaml:"excluded-models,omitempty" json:"excluded-models,omitempty"`
+}
+
+func (k ProviderKey) GetAPIKey() string  { return k.APIKey }
+func (k ProviderKey) GetBaseURL() string { return k.BaseURL }
+
+// ProviderModel describes a mapping between an alias and the actual upstream model name.
+type ProviderModel struct {
+	// Name is the upstream model identifier used when issuing requests.
+	Name string `yaml:"name" json:"name"`
+
+	// Alias is the client-facing model name that maps to Name.
+	Alias string `yaml:"alias" json:"alias"`
+}
+
+func (m ProviderModel) GetName() string  { return m.Name }
+func (m ProviderModel) GetAlias() string { return m.Alias }
+
+// APIKeyProvider describes a provider configured through a list of ProviderKey entries.
+type APIKeyProvider struct {
+	// Name is the provider identifier of the synthesized auths, e.g. "mistral".
+	Name string
+
+	// ConfigKey is the YAML key of the entry list, e.g. "mistral-api-key".
+	ConfigKey string
+
+	// DefaultBaseURL fills entries without base-url. Empty leaves the default to the executor.
+	DefaultBaseURL string
+
+	// Keyless providers accept entries without an API key; entries listing no models are
+	// dropped instead, since there is no built-in model list.
+	Keyless bool
+
+	// Keys returns the entry list of the provider in cfg.
+	Keys func(cfg *Config) *[]ProviderKey
+}
+
+// APIKeyProviders lists the providers configured through ProviderKey entries, in synthesis order.
+var APIKeyProviders = []APIKeyProvider{
+	{Name: "mistral", ConfigKey: "mistral-api-key", Keys: func(cfg *Config) *[]ProviderKey { return &cfg.MistralKey }},
+	{Name: "xai", ConfigKey: "xai-api-key", Keys: func(cfg *Config) *[]ProviderKey { return &cfg.XAIKey }},
+	{Name: "deepseek", ConfigKey: "deepseek-api-key", Keys: func(cfg *Config) *[]ProviderKey { return &cfg.DeepSeekKey }},
+	{Name: "ollama", ConfigKey: "ollama", DefaultBaseURL: DefaultOllamaBaseURL, Keyless: true, Keys: func(cfg *Config) *[]ProviderKey { return &cfg.OllamaKey }},
+}
+
+// LookupAPIKeyProvider returns the entry of APIKeyProviders named name.
+func LookupAPIKeyProvider(name string) (APIKeyProvider, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, provider := range APIKeyProviders {
+		if provider.Name == name {
+			return provider, true
+		}
+	}
+	return APIKeyProvider{}, false
+}
+
+// FindKey returns the entry of cfg whose API key and base URL match, or nil.
+func (p APIKeyProvider) FindKey(cfg *Config, apiKey, baseURL string) *ProviderKey {
+	if cfg == nil {
+		return nil
+	}
+	keys := *p.Keys(cfg)
+	for i := range keys {
+		entry := &keys[i]
+		base := strings.TrimSpace(entry.BaseURL)
+		if base == "" {
+			base = p.DefaultBaseURL
+		}
+		if strings.EqualFold(strings.TrimSpace(entry.APIKey), apiKey) && strings.EqualFold(base, baseURL) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// SanitizeProviderKeys normalizes the entries of every provider in APIKeyProviders and drops
+// unusable ones: entries without an API key, or for keyless providers entries without models.
+func (cfg *Config) SanitizeProviderKeys() {
+	if cfg == nil {
+		return
+	}
+	for _, provider := range APIKeyProviders {
+		keys := provider.Keys(cfg)
+		if len(*keys) == 0 {
+			continue
+		}
+		out := make([]ProviderKey, 0, len(*keys))
+		for _, e := range *keys {
+			e.APIKey = strings.TrimSpace(e.APIKey)
+			e.Prefix = normalizeModelPrefix(e.Prefix)
+			e.BaseURL = strings.TrimSuffix(strings.TrimSpace(e.BaseURL), "/")
+			if e.BaseURL == "" {
+				e.BaseURL = provider.DefaultBaseURL
+			}
+			e.KeepAlive = strings.TrimSpace(e.KeepAlive)
+			e.ProxyURL = strings.TrimSpace(e.ProxyURL)
+			e.Headers = NormalizeHeaders(e.Headers)
+			e.ExcludedModels = NormalizeExcludedModels(e.ExcludedModels)
+			if provider.Keyless && len(e.Models) == 0 || !provider.Keyless && e.APIKey == "" {
+				continue
+			}
+			out = append(out, e)
+		}
+		*keys = out
+	}
+}
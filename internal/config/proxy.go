@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ProxyDirect as a proxy URL sends traffic without any proxy, overriding the global proxy-url.
+const ProxyDirect = "direct"
+
+// ValidateProxyURL reports whether raw is "direct" or a socks5, http or https proxy URL with a host.
+func ValidateProxyURL(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if strings.EqualFold(raw, ProxyDirect) {
+		return nil
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch parsed.Scheme {
+	case "socks5", "http", "https":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("proxy URL %q has no host", raw)
+	}
+	return nil
+}
+
+// SanitizeProviderProxies lower-cases provider names, trims proxy URLs and drops invalid entries.
+// A dropped entry falls back to the global proxy-url, so it is logged as a warning.
+func (cfg *Config) SanitizeProviderProxies() {
+	if cfg == nil || len(cfg.ProviderProxies) == 0 {
+		return
+	}
+	out := make(map[string]string, len(cfg.ProviderProxies))
+	for provider, proxyURL := range cfg.ProviderProxies {
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		proxyURL = strings.TrimSpace(proxyURL)
+		if provider == "" || proxyURL == "" {
+			continue
+		}
+		if err := ValidateProxyURL(proxyURL); err != nil {
+			log.Warnf("provider-proxies: %s dropped, using the global proxy: %v", provider, err)
+			continue
+		}
+		if strings.EqualFold(proxyURL, ProxyDirect) {
+			proxyURL = ProxyDirect
+		}
+		out[provider] = proxyURL
+	}
+	cfg.ProviderProxies = out
+}
+
+// ProxyURLFor returns the proxy for provider: its provider-proxies entry, otherwise the global
+// proxy-url. The result is empty when no proxy is configured and ProxyDirect when the provider
+// must bypass the global proxy.
+func (cfg *Config) ProxyURLFor(provider string) string {
+	if cfg == nil {
+		return ""
+	}
+	if proxyURL, ok := cfg.ProviderProxies[strings.ToLower(strings.TrimSpace(provider))]; ok {
+		return proxyURL
+	}
+	return strings.TrimSpace(cfg.ProxyURL)
+}
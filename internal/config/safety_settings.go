@@ -0,0 +1,104 @@
+package config
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SafetySettingsConfig configures the Gemini safety thresholds attached to upstream requests.
+type SafetySettingsConfig struct {
+	// Thresholds maps harm categories to thresholds for every request.
+	Thresholds map[string]string `yaml:"thresholds,omitempty" json:"thresholds,omitempty"`
+
+	// Rules override Thresholds for matching requests. Every matching rule applies in order.
+	Rules []SafetySettingsRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// SafetySettingsRule sets safety thresholds for matching models and client API keys.
+type SafetySettingsRule struct {
+	// Models restricts the rule to requested model names or aliases; "*" matches any sequence.
+	// Empty matches all models.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// APIKeys restricts the rule to these client API keys. Empty matches all callers.
+	APIKeys []string `yaml:"api-keys,omitempty" json:"api-keys,omitempty"`
+
+	// Thresholds maps harm categories to thresholds.
+	Thresholds map[string]string `yaml:"thresholds" json:"thresholds"`
+}
+
+// safetyCategories are the harm categories Gemini accepts in safetySettings.
+var safetyCategories = map[string]struct{}{
+	"HARM_CATEGORY_HARASSMENT":        {},
+	"HARM_CATEGORY_HATE_SPEECH":       {},
+	"HARM_CATEGORY_SEXUALLY_EXPLICIT": {},
+	"HARM_CATEGORY_DANGEROUS_CONTENT": {},
+	"HARM_CATEGORY_CIVIC_INTEGRITY":   {},
+}
+
+// safetyThresholds are the block thresholds Gemini accepts in safetySettings.
+var safetyThresholds = map[string]struct{}{
+	"OFF":                    {},
+	"BLOCK_NONE":             {},
+	"BLOCK_ONLY_HIGH":        {},
+	"BLOCK_MEDIUM_AND_ABOVE": {},
+	"BLOCK_LOW_AND_ABOVE":    {},
+}
+
+// NormalizeSafetyCategory returns the canonical Gemini harm category for name, which may omit
+// the HARM_CATEGORY_ prefix and use any case.
+func NormalizeSafetyCategory(name string) (string, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, "HARM_CATEGORY_") {
+		name = "HARM_CATEGORY_" + name
+	}
+	_, ok := safetyCategories[name]
+	return name, ok
+}
+
+// NormalizeSafetyThreshold returns the canonical Gemini block threshold for name.
+func NormalizeSafetyThreshold(name string) (string, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	_, ok := safetyThresholds[name]
+	return name, ok
+}
+
+// NormalizeSafetySettings canonicalizes category and threshold names and drops entries Gemini
+// would reject, logging each dropped entry. Rules left without thresholds are removed.
+func NormalizeSafetySettings(cfg SafetySettingsConfig) SafetySettingsConfig {
+	out := SafetySettingsConfig{Thresholds: normalizeSafetyThresholds(cfg.Thresholds, "thresholds")}
+	for i, rule := range cfg.Rules {
+		rule.Thresholds = normalizeSafetyThresholds(rule.Thresholds, "rules")
+		if len(rule.Thresholds) == 0 {
+			log.WithField("rule_index", i+1).Warn("safety-settings rule dropped: no valid thresholds")
+			continue
+		}
+		out.Rules = append(out.Rules, rule)
+	}
+	return out
+}
+
+func normalizeSafetyThresholds(thresholds map[string]string, section string) map[string]string {
+	if len(thresholds) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(thresholds))
+	for rawCategory, rawThreshold := range thresholds {
+		category, okCategory := NormalizeSafetyCategory(rawCategory)
+		threshold, okThreshold := NormalizeSafetyThreshold(rawThreshold)
+		if !okCategory || !okThreshold {
+			log.WithFields(log.Fields{
+				"section":   section,
+				"category":  rawCategory,
+				"threshold": rawThreshold,
+			}).Warn("safety-settings entry dropped: unknown Gemini harm category or threshold")
+			continue
+		}
+		out[category] = threshold
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,19 @@
+package config
+
+import "testing"
+
+func TestNormalizeSafetySettings(t *testing.T) {
+	cfg := NormalizeSafetySettings(SafetySettingsConfig{
+		Thresholds: map[string]string{"harassment": "block_only_high", "HARM_CATEGORY_MADE_UP": "OFF", "HATE_SPEECH": "SOMETIMES"},
+		Rules: []SafetySettingsRule{
+			{Models: []string{"gemini-*"}, Thresholds: map[string]string{"DANGEROUS_CONTENT": "BLOCK_NONE"}},
+			{Models: []string{"gemini-*"}, Thresholds: map[string]string{"nope": "OFF"}},
+		},
+	})
+	if len(cfg.Thresholds) != 1 || cfg.Thresholds["HARM_CATEGORY_HARASSMENT"] != "BLOCK_ONLY_HIGH" {
+		t.Fatalf("unexpected thresholds: %v", cfg.Thresholds)
+	}
+	if len(cfg.Rules) != 1 || cfg.Rules[0].Thresholds["HARM_CATEGORY_DANGEROUS_CONTENT"] != "BLOCK_NONE" {
+		t.Fatalf("unexpected rules: %+v", cfg.Rules)
+	}
+}
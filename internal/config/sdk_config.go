@@ -29,6 +29,324 @@ type SDKConfig struct {
 	// NonStreamKeepAliveInterval controls how often blank lines are emitted for non-streaming responses.
 	// <= 0 disables keep-alives. Value is in seconds.
 	NonStreamKeepAliveInterval int `yaml:"nonstream-keepalive-interval,omitempty" json:"nonstream-keepalive-interval,omitempty"`
+
+	// UsageMetadataKeys lists request metadata fields (e.g. "user_id", "user") recorded as tags
+	// on usage records. Values are read from the request "metadata" object first, then from
+	// top-level fields. Empty disables tagging.
+	UsageMetadataKeys []string `yaml:"usage-metadata-keys,omitempty" json:"usage-metadata-keys,omitempty"`
+
+	// StrictRequestFields rejects requests containing unknown top-level fields or unsupported
+	// stream_options with a 400 listing each offending field. Intended for staging environments.
+	StrictRequestFields bool `yaml:"strict-request-fields,omitempty" json:"strict-request-fields,omitempty"`
+
+	// APIKeyModels restricts the models individual client API keys may list and call.
+	// Keys without an entry are unrestricted.
+	APIKeyModels []APIKeyModelAccess `yaml:"api-key-models,omitempty" json:"api-key-models,omitempty"`
+
+	// APIKeyProtocols restricts the inbound API surfaces individual client API keys may call.
+	// Keys without an entry are unrestricted.
+	APIKeyProtocols []APIKeyProtocolAccess `yaml:"api-key-protocols,omitempty" json:"api-key-protocols,omitempty"`
+
+	// APIKeyBudgets caps the tokens individual client API keys may consume. Requests are rejected
+	// once a budget is exhausted and streams crossing the limit are ended with a length finish reason.
+	APIKeyBudgets []APIKeyBudget `yaml:"api-key-budgets,omitempty" json:"api-key-budgets,omitempty"`
+
+	// TrafficPause stops accepting new requests globally or for selected providers, for example
+	// while draining before maintenance. It is normally managed through the management API.
+	TrafficPause TrafficPause `yaml:"traffic-pause,omitempty" json:"traffic-pause,omitempty"`
+
+	// RequestQueue holds requests while every credential that could serve them is rate limited,
+	// admitting higher-priority API keys first and shedding requests that wait too long.
+	RequestQueue RequestQueueConfig `yaml:"request-queue,omitempty" json:"request-queue,omitempty"`
+
+	// LatencyRouting substitutes a faster model from a downgrade chain when a request carries a
+	// latency budget that the preferred model's recent p95 latency exceeds.
+	LatencyRouting LatencyRoutingConfig `yaml:"latency-routing,omitempty" json:"latency-routing,omitempty"`
+
+	// CanaryRouting splits the requests for a model alias across several models by weight, for
+	// gradual model migrations.
+	CanaryRouting []CanaryRoute `yaml:"canary-routing,omitempty" json:"canary-routing,omitempty"`
+
+	// RequestScripts run external scripts that may inspect and rewrite request bodies, either as
+	// received from the client or after translation to the upstream format.
+	RequestScripts []RequestScript `yaml:"request-scripts,omitempty" json:"request-scripts,omitempty"`
+
+	// Guardrails filter request and response content per client API key.
+	Guardrails []GuardrailPolicy `yaml:"guardrails,omitempty" json:"guardrails,omitempty"`
+
+	// SystemPrompts injects operator-defined system prompts by model alias or client API key.
+	SystemPrompts SystemPromptConfig `yaml:"system-prompts,omitempty" json:"system-prompts,omitempty"`
+
+	// ContextWindow trims the oldest conversation turns of upstream requests that would not fit the
+	// context window of the target model.
+	ContextWindow ContextWindowConfig `yaml:"context-window,omitempty" json:"context-window,omitempty"`
+
+	// ThinkingOutput rewrites model thinking in responses per client API key.
+	ThinkingOutput []ThinkingOutputPolicy `yaml:"thinking-output,omitempty" json:"thinking-output,omitempty"`
+
+	// Logprobs selects how OpenAI Chat Completions requests asking for logprobs are handled per
+	// model. The first matching rule applies; requests for other models are passed through.
+	Logprobs []LogprobsRule `yaml:"logprobs,omitempty" json:"logprobs,omitempty"`
+
+	// SafetySettings replaces the built-in Gemini safety thresholds, globally and per model or
+	// client API key.
+	SafetySettings SafetySettingsConfig `yaml:"safety-settings,omitempty" json:"safety-settings,omitempty"`
+}
+
+// LogprobsRule handles logprobs requests for matching models.
+type LogprobsRule struct {
+	// Models lists requested model names; "*" matches any sequence. Empty matches all models.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// Mode is "passthrough" (forward the fields upstream), "strip" (drop them and answer with a
+	// Warning header) or "reject" (fail the request with a 400 capability error).
+	Mode string `yaml:"mode" json:"mode"`
+}
+
+// ThinkingOutputPolicy controls how model thinking is returned to clients. A policy listing the
+// caller's key takes precedence over the first policy without api-keys.
+type ThinkingOutputPolicy struct {
+	// APIKeys lists the client API keys the policy applies to. Empty makes it the default policy.
+	APIKeys []string `yaml:"api-keys,omitempty" json:"api-keys,omitempty"`
+
+	// Mode is "strip" (drop thinking), "wrap" (return thinking as regular text between OpenTag and
+	// CloseTag) or "reasoning" (move tagged thinking out of the text into the reasoning field).
+	Mode string `yaml:"mode" json:"mode"`
+
+	// OpenTag and CloseTag delimit thinking in text. They default to <thinking> and </thinking>.
+	OpenTag  string `yaml:"open-tag,omitempty" json:"open-tag,omitempty"`
+	CloseTag string `yaml:"close-tag,omitempty" json:"close-tag,omitempty"`
+}
+
+// SystemPromptConfig configures system prompt injection. Prompts are applied by the executors to
+// the translated upstream request, in the upstream's own system prompt format.
+type SystemPromptConfig struct {
+	// KeyNames maps client API keys to the names rendered by {{key_name}}. Unnamed keys render as
+	// a short hash so raw keys never reach the upstream.
+	KeyNames map[string]string `yaml:"key-names,omitempty" json:"key-names,omitempty"`
+
+	// Rules are applied in order; every matching rule is applied.
+	Rules []SystemPromptRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// SystemPromptRule adds to or replaces the system prompt of matching requests.
+type SystemPromptRule struct {
+	// Models restricts the rule to requested model names or aliases; "*" matches any sequence.
+	// Empty matches all models.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// APIKeys restricts the rule to these client API keys. Empty matches all callers.
+	APIKeys []string `yaml:"api-keys,omitempty" json:"api-keys,omitempty"`
+
+	// Mode is "prepend" (default), "append" or "override", which replaces the client's system prompt.
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+
+	// Prompt is the text to inject. It may use {{date}}, {{datetime}}, {{model}} and {{key_name}}.
+	Prompt string `yaml:"prompt" json:"prompt"`
+}
+
+// ContextWindowConfig configures context window trimming. The size of a translated request is
+// estimated at four bytes per token, with a flat cost for each inline image, audio clip or file, and
+// compared with the context length registered for the model; the system prompt and the latest user
+// turn are never trimmed.
+type ContextWindowConfig struct {
+	// Enabled turns trimming on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Models restricts trimming to requested model names or aliases; "*" matches any sequence.
+	// Empty matches all models.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// ReserveTokens are kept free in addition to the output tokens the request asks for.
+	ReserveTokens int `yaml:"reserve-tokens,omitempty" json:"reserve-tokens,omitempty"`
+
+	// Notice inserts a note where turns were dropped saying how many messages were omitted. An
+	// installed summarizer takes precedence.
+	Notice bool `yaml:"notice,omitempty" json:"notice,omitempty"`
+}
+
+// GuardrailPolicy configures content filters applied to client requests before translation and
+// to responses before they are returned. A policy listing the caller's key takes precedence over
+// the first policy without api-keys, which applies to every other caller.
+type GuardrailPolicy struct {
+	// Name identifies the policy in logs and error messages.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// APIKeys lists the client API keys the policy applies to. Empty makes it the default policy.
+	APIKeys []string `yaml:"api-keys,omitempty" json:"api-keys,omitempty"`
+
+	// Blocklist rejects content containing any of these keywords (case-insensitive).
+	Blocklist []string `yaml:"blocklist,omitempty" json:"blocklist,omitempty"`
+
+	// BlockPatterns rejects content matching any of these regular expressions (RE2 syntax).
+	BlockPatterns []string `yaml:"block-patterns,omitempty" json:"block-patterns,omitempty"`
+
+	// MaxPromptChars rejects requests whose prompt text exceeds this many characters. 0 disables the limit.
+	MaxPromptChars int `yaml:"max-prompt-chars,omitempty" json:"max-prompt-chars,omitempty"`
+
+	// RedactPII lists the kinds of personal data masked in prompts and responses:
+	// "email", "phone" and "key" (API keys and access tokens).
+	RedactPII []string `yaml:"redact-pii,omitempty" json:"redact-pii,omitempty"`
+
+	// ModerationModel, when set, asks this model to classify each prompt before it is sent upstream.
+	ModerationModel string `yaml:"moderation-model,omitempty" json:"moderation-model,omitempty"`
+
+	// ModerationPrompt overrides the classifier instructions. The model must answer ALLOW or BLOCK: <reason>.
+	ModerationPrompt string `yaml:"moderation-prompt,omitempty" json:"moderation-prompt,omitempty"`
+
+	// ModerationFailClosed rejects the request when the moderation call fails instead of allowing it.
+	ModerationFailClosed bool `yaml:"moderation-fail-closed,omitempty" json:"moderation-fail-closed,omitempty"`
+}
+
+// RequestScript configures a request mutation script. The script receives a JSON document with
+// the stage, model, format and body on stdin and writes the replacement body to stdout; empty
+// output keeps the body unchanged.
+type RequestScript struct {
+	// Name identifies the script in logs.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Stage selects when the script runs: "inbound" (client request) or "upstream" (translated request).
+	Stage string `yaml:"stage" json:"stage"`
+
+	// Command is the interpreter and arguments, e.g. ["lua", "/etc/cliproxy/rewrite.lua"].
+	Command []string `yaml:"command" json:"command"`
+
+	// Models restricts the script to matching model IDs; "*" matches any sequence. Empty matches all.
+	Models []string `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// Timeout bounds the wall-clock run time as a Go duration. Defaults to 2s.
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// MaxMemoryMB caps the script's virtual memory on Unix-like systems. 0 disables the limit.
+	MaxMemoryMB int `yaml:"max-memory-mb,omitempty" json:"max-memory-mb,omitempty"`
+
+	// MaxOutputBytes caps the size of the replacement body. Defaults to 8 MiB.
+	MaxOutputBytes int `yaml:"max-output-bytes,omitempty" json:"max-output-bytes,omitempty"`
+}
+
+// TrafficPause describes which traffic is currently refused by the proxy.
+type TrafficPause struct {
+	// Paused refuses new requests for all providers.
+	Paused bool `yaml:"paused,omitempty" json:"paused,omitempty"`
+
+	// Providers refuses new requests for the listed providers only. Models also served by other
+	// providers keep working through them.
+	Providers []string `yaml:"providers,omitempty" json:"providers,omitempty"`
+
+	// RetryAfter is the Retry-After value in seconds returned with 503 responses. Defaults to 30.
+	RetryAfter int `yaml:"retry-after,omitempty" json:"retry-after,omitempty"`
+}
+
+// RequestQueueConfig configures admission control for requests that find all credentials of
+// their model rate limited.
+type RequestQueueConfig struct {
+	// Enabled turns on the queue. When disabled, requests fail immediately with the upstream cooldown error.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// MaxWait is how long a request may wait for a credential as a Go duration. Defaults to 30s.
+	// "0s" sheds the request immediately with 429.
+	MaxWait string `yaml:"max-wait,omitempty" json:"max-wait,omitempty"`
+
+	// MaxQueued caps the number of waiting requests; further requests are shed. Defaults to 100.
+	MaxQueued int `yaml:"max-queued,omitempty" json:"max-queued,omitempty"`
+
+	// APIKeys assigns priorities and waits to individual client API keys.
+	APIKeys []APIKeyPriority `yaml:"api-keys,omitempty" json:"api-keys,omitempty"`
+}
+
+// LatencyRoutingConfig configures deadline-aware model downgrades. Clients state a latency
+// budget with the X-Latency-Budget header (Go duration or milliseconds) or the latency_budget_ms
+// body field; the field is removed before the request is forwarded.
+type LatencyRoutingConfig struct {
+	// Chains lists the downgrade chains. Routing is inactive without chains.
+	Chains []ModelDowngradeChain `yaml:"chains,omitempty" json:"chains,omitempty"`
+
+	// MinSamples is the number of recent requests needed before a model's p95 is trusted.
+	// Defaults to 20.
+	MinSamples int `yaml:"min-samples,omitempty" json:"min-samples,omitempty"`
+
+	// Window is the number of recent request latencies kept per model. Defaults to 200.
+	Window int `yaml:"window,omitempty" json:"window,omitempty"`
+}
+
+// ModelDowngradeChain lists faster substitutes for a preferred model.
+type ModelDowngradeChain struct {
+	// Model is the preferred model as requested by clients.
+	Model string `yaml:"model" json:"model"`
+
+	// Fallbacks are tried in order; the first whose p95 fits the budget, or that has too few
+	// samples to judge, serves the request.
+	Fallbacks []string `yaml:"fallbacks" json:"fallbacks"`
+}
+
+// CanaryRoute splits the requests for one model alias across models.
+type CanaryRoute struct {
+	// Alias is the model name clients request. It may be the name of one of the split models.
+	Alias string `yaml:"alias" json:"alias"`
+
+	// Splits are the models serving the alias. Each request goes to one of them with a
+	// probability proportional to its weight, among the splits the caller may use.
+	Splits []CanarySplit `yaml:"splits" json:"splits"`
+
+	// Sticky keeps each conversation on one split instead of choosing per request, so a
+	// conversation does not switch models between turns.
+	Sticky bool `yaml:"sticky,omitempty" json:"sticky,omitempty"`
+}
+
+// CanarySplit is one model serving a canary alias.
+type CanarySplit struct {
+	// Model is the model that serves the share of requests.
+	Model string `yaml:"model" json:"model"`
+
+	// Weight is the relative share of requests, e.g. 90 and 10 for a 90/10 split. 0 pauses the split.
+	Weight int `yaml:"weight" json:"weight"`
+
+	// Labels restricts the split to credentials carrying all of these labels, so a model can also
+	// be split across credential pools. A "*" value only requires the label to be present.
+	Labels map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// APIKeyPriority sets the admission priority of a client API key.
+type APIKeyPriority struct {
+	// APIKey is the client API key the settings apply to.
+	APIKey string `yaml:"api-key" json:"api-key"`
+
+	// Priority orders waiting requests; higher values are admitted first. Keys without an entry use 0.
+	Priority int `yaml:"priority" json:"priority"`
+
+	// MaxWait overrides request-queue.max-wait for this key.
+	MaxWait string `yaml:"max-wait,omitempty" json:"max-wait,omitempty"`
+}
+
+// APIKeyBudget defines the token budget of a client API key.
+type APIKeyBudget struct {
+	// APIKey is the client API key the budget applies to.
+	APIKey string `yaml:"api-key" json:"api-key"`
+
+	// MaxTokens is the number of tokens (input and output) the key may consume per period.
+	MaxTokens int64 `yaml:"max-tokens" json:"max-tokens"`
+
+	// Period is the budget window as a Go duration (e.g. "24h"). Empty means the budget never resets.
+	Period string `yaml:"period,omitempty" json:"period,omitempty"`
+}
+
+// APIKeyModelAccess lists the model patterns a client API key is allowed to use.
+type APIKeyModelAccess struct {
+	// APIKey is the client API key the allowlist applies to.
+	APIKey string `yaml:"api-key" json:"api-key"`
+
+	// Models lists allowed model IDs; "*" matches any sequence of characters (e.g. "gemini-*").
+	Models []string `yaml:"models" json:"models"`
+}
+
+// APIKeyProtocolAccess lists the inbound API surfaces a client API key is allowed to call.
+type APIKeyProtocolAccess struct {
+	// APIKey is the client API key the allowlist applies to.
+	APIKey string `yaml:"api-key" json:"api-key"`
+
+	// Protocols lists allowed surfaces: "openai", "openai-responses", "claude", "gemini",
+	// "realtime", "batch", "amp" (Amp CLI upstream passthrough), "websocket" and "health".
+	Protocols []string `yaml:"protocols" json:"protocols"`
 }
 
 // StreamingConfig holds server streaming behavior configuration.
@@ -41,6 +359,19 @@ type StreamingConfig struct {
 	// to allow auth rotation / transient recovery.
 	// <= 0 disables bootstrap retries. Default is 0.
 	BootstrapRetries int `yaml:"bootstrap-retries,omitempty" json:"bootstrap-retries,omitempty"`
+
+	// KeepAliveBeforeFirstChunk also emits heartbeats while waiting for the first upstream chunk,
+	// e.g. during long thinking phases. This commits the response headers early, so upstream
+	// failures after the first heartbeat are reported as error events instead of HTTP statuses.
+	KeepAliveBeforeFirstChunk bool `yaml:"keepalive-before-first-chunk,omitempty" json:"keepalive-before-first-chunk,omitempty"`
+
+	// IdleTimeoutSeconds ends a stream with a timeout error when the upstream sends nothing
+	// for this long. <= 0 disables the idle timeout. Default is 0.
+	IdleTimeoutSeconds int `yaml:"idle-timeout-seconds,omitempty" json:"idle-timeout-seconds,omitempty"`
+
+	// MaxDurationSeconds ends a stream with a timeout error once it has run this long in total.
+	// <= 0 disables the limit. Default is 0.
+	MaxDurationSeconds int `yaml:"max-duration-seconds,omitempty" json:"max-duration-seconds,omitempty"`
 }
 
 // AccessConfig groups request authentication providers.
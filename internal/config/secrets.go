@@ -0,0 +1,379 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	fileReferencePrefix  = "file:"
+	vaultReferencePrefix = "vault:"
+	vaultRequestTimeout  = 10 * time.Second
+)
+
+// resolvedReferences maps the raw text of every expanded config value to its resolved value, so
+// that writing the config back keeps the reference instead of persisting the secret.
+var resolvedReferences sync.Map
+
+// secretFields lists the config fields whose values may be secret references: client and
+// provider keys, the management key, object store credentials and proxy URLs. References are only
+// expanded in these fields, so literal "${" or "file:" text elsewhere (prompts, payload rules)
+// is kept as written.
+var secretFields = map[string]bool{
+	"api-keys":                    true,
+	"api-key":                     true,
+	"generative-language-api-key": true,
+	"upstream-api-key":            true,
+	"secret-key":                  true,
+	"access-key":                  true,
+	"access-token":                true,
+	"refresh-token":               true,
+	"proxy-url":                   true,
+}
+
+// isSecretField reports whether the value at path, as built by referenceResolver.walk (e.g.
+// "claude-api-key[0].api-key" or "api-keys[1]"), belongs to one of the secretFields.
+func isSecretField(path string) bool {
+	for strings.HasSuffix(path, "]") {
+		idx := strings.LastIndexByte(path, '[')
+		if idx < 0 {
+			break
+		}
+		path = path[:idx]
+	}
+	if idx := strings.LastIndexByte(path, '.'); idx >= 0 {
+		path = path[idx+1:]
+	}
+	return secretFields[path]
+}
+
+// ResolveSecretReferences expands the secret fields of a parsed YAML document in place:
+//
+//   - ${NAME} is replaced by the environment variable NAME, which must be set;
+//     ${NAME:-default} falls back to default when NAME is unset or empty, and $${ is a literal ${.
+//   - A value of the form file:PATH is replaced by the contents of PATH without trailing line
+//     breaks. Relative paths are resolved against baseDir.
+//   - A value of the form vault:PATH#FIELD is replaced by FIELD of the Vault secret at PATH, read
+//     from VAULT_ADDR with VAULT_TOKEN (and VAULT_NAMESPACE when set). KV v1 and v2 are supported.
+//
+// Environment variables are expanded first, so file: and vault: references may use them.
+// Values outside the secretFields and mapping keys are left untouched.
+func ResolveSecretReferences(root *yaml.Node, baseDir string) error {
+	r := &referenceResolver{baseDir: baseDir, vault: make(map[string]map[string]any)}
+	return r.walk(root, "")
+}
+
+type referenceResolver struct {
+	baseDir string
+	// vault caches the secrets read during one resolution by path.
+	vault map[string]map[string]any
+}
+
+func (r *referenceResolver) walk(node *yaml.Node, path string) error {
+	return forEachSecretField(node, path, func(node *yaml.Node, path string) error {
+		if !isSecretReference(node.Value) {
+			return nil
+		}
+		value, err := r.resolve(node.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		resolvedReferences.Store(node.Value, value)
+		node.Value = value
+		return nil
+	})
+}
+
+// forEachSecretField calls fn for every string scalar below node that belongs to a secret field.
+func forEachSecretField(node *yaml.Node, path string, fn func(node *yaml.Node, path string) error) error {
+	if node == nil {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := forEachSecretField(child, path, fn); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			if err := forEachSecretField(node.Content[i+1], key, fn); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			if err := forEachSecretField(child, fmt.Sprintf("%s[%d]", path, i), fn); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if node.Tag == "!!str" && isSecretField(path) {
+			return fn(node, path)
+		}
+	}
+	return nil
+}
+
+// isSecretReference reports whether value contains anything ResolveSecretReferences expands.
+func isSecretReference(value string) bool {
+	return strings.Contains(value, "${") || strings.HasPrefix(value, fileReferencePrefix) ||
+		strings.HasPrefix(value, vaultReferencePrefix)
+}
+
+func (r *referenceResolver) resolve(raw string) (string, error) {
+	value, err := expandEnv(raw)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case strings.HasPrefix(value, fileReferencePrefix):
+		return r.readFile(strings.TrimPrefix(value, fileReferencePrefix))
+	case strings.HasPrefix(value, vaultReferencePrefix):
+		return r.readVault(strings.TrimPrefix(value, vaultReferencePrefix))
+	}
+	return value, nil
+}
+
+// expandEnv replaces ${NAME} and ${NAME:-default} with environment variables.
+func expandEnv(raw string) (string, error) {
+	var b strings.Builder
+	for {
+		idx := strings.Index(raw, "${")
+		if idx < 0 {
+			b.WriteString(raw)
+			return b.String(), nil
+		}
+		if idx > 0 && raw[idx-1] == '$' {
+			b.WriteString(raw[:idx-1] + "${")
+			raw = raw[idx+2:]
+			continue
+		}
+		b.WriteString(raw[:idx])
+		end := strings.IndexByte(raw[idx:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated ${ in %q", raw[idx:])
+		}
+		expr := raw[idx+2 : idx+end]
+		name, fallback, hasDefault := strings.Cut(expr, ":-")
+		if name == "" {
+			return "", fmt.Errorf("empty variable name in ${%s}", expr)
+		}
+		value, ok := os.LookupEnv(name)
+		switch {
+		case hasDefault && value == "":
+			value = fallback
+		case !ok:
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		b.WriteString(value)
+		raw = raw[idx+end+1:]
+	}
+}
+
+func (r *referenceResolver) readFile(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", fmt.Errorf("file reference has no path")
+	}
+	if !filepath.IsAbs(path) && r.baseDir != "" {
+		path = filepath.Join(r.baseDir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func (r *referenceResolver) readVault(ref string) (string, error) {
+	path, field, ok := strings.Cut(strings.TrimSpace(ref), "#")
+	path = strings.Trim(path, "/")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("vault reference %q must have the form vault:PATH#FIELD", ref)
+	}
+	secret, cached := r.vault[path]
+	if !cached {
+		var err error
+		if secret, err = fetchVaultSecret(path); err != nil {
+			return "", err
+		}
+		r.vault[path] = secret
+	}
+	value, ok := secret[field]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no field %q", path, field)
+	}
+	if s, isString := value.(string); isString {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// fetchVaultSecret reads the secret at path and returns its fields.
+func fetchVaultSecret(path string) (map[string]any, error) {
+	addr := strings.TrimRight(strings.TrimSpace(os.Getenv("VAULT_ADDR")), "/")
+	if addr == "" {
+		return nil, fmt.Errorf("vault reference to %s needs VAULT_ADDR", path)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), vaultRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/v1/"+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("vault request: %w", err)
+	}
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned %d for %s", resp.StatusCode, path)
+	}
+	var payload struct {
+		Data map[string]any `json:"data"`
+	}
+	if err = json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("vault response: %w", err)
+	}
+	// KV v2 nests the fields under data.data next to data.metadata.
+	if nested, ok := payload.Data["data"].(map[string]any); ok {
+		if _, hasMetadata := payload.Data["metadata"]; hasMetadata {
+			return nested, nil
+		}
+	}
+	return payload.Data, nil
+}
+
+// resolvedValue returns the value the raw config value raw stood for when the config was loaded,
+// so that sequence elements given as references match their resolved counterparts.
+func resolvedValue(raw string) string {
+	if !isSecretReference(raw) {
+		return raw
+	}
+	if resolved, ok := resolvedReferences.Load(raw); ok {
+		return resolved.(string)
+	}
+	return raw
+}
+
+// keepsReference reports whether the raw config value raw is a reference that still stands for
+// value, either directly or as the plaintext of a bcrypt hash.
+func keepsReference(raw, value string) bool {
+	if !isSecretReference(raw) {
+		return false
+	}
+	resolved, ok := resolvedReferences.Load(raw)
+	if !ok {
+		return false
+	}
+	if resolved.(string) == value {
+		return true
+	}
+	return looksLikeBcrypt(value) && bcrypt.CompareHashAndPassword([]byte(value), []byte(resolved.(string))) == nil
+}
+
+// ResolveSecretReference expands a single config value the way ResolveSecretReferences does.
+func ResolveSecretReference(raw, baseDir string) (string, error) {
+	if !isSecretReference(raw) {
+		return raw, nil
+	}
+	r := &referenceResolver{baseDir: baseDir, vault: make(map[string]map[string]any)}
+	return r.resolve(raw)
+}
+
+// NewSecretReferences returns the secret fields of the YAML document next that hold an env, file
+// or Vault reference not already present in the document current. The management API uses it to
+// refuse references it did not load itself, which would otherwise read host files or environment
+// variables into the config.
+func NewSecretReferences(next, current []byte) ([]string, error) {
+	known := make(map[string]bool)
+	if len(current) > 0 {
+		var root yaml.Node
+		if err := yaml.Unmarshal(current, &root); err == nil {
+			_ = forEachSecretField(&root, "", func(node *yaml.Node, _ string) error {
+				if isSecretReference(node.Value) {
+					known[node.Value] = true
+				}
+				return nil
+			})
+		}
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(next, &root); err != nil {
+		return nil, err
+	}
+	var fields []string
+	_ = forEachSecretField(&root, "", func(node *yaml.Node, path string) error {
+		if isSecretReference(node.Value) && !known[node.Value] {
+			fields = append(fields, path)
+		}
+		return nil
+	})
+	return fields, nil
+}
+
+// RedactResolvedReferences returns the JSON form of v with every value that was resolved from a
+// secret reference replaced by the reference itself, so resolved secrets are never sent back.
+func RedactResolvedReferences(v any) (any, error) {
+	references := make(map[string]string)
+	resolvedReferences.Range(func(raw, resolved any) bool {
+		if value := resolved.(string); value != "" {
+			references[value] = raw.(string)
+		}
+		return true
+	})
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err = json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return redactValue(out, references), nil
+}
+
+func redactValue(v any, references map[string]string) any {
+	switch value := v.(type) {
+	case string:
+		if raw, ok := references[value]; ok {
+			return raw
+		}
+	case map[string]any:
+		for key, item := range value {
+			value[key] = redactValue(item, references)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = redactValue(item, references)
+		}
+	}
+	return v
+}
@@ -0,0 +1,358 @@
+// Package contextwindow keeps upstream requests within the context window of their model. When
+// the estimated size of a translated request exceeds the budget, the oldest conversation turns are
+// dropped in the upstream's own representation: Chat Completions messages, Responses and Codex
+// input items, Claude messages or Gemini contents. System prompts and the latest user turn are
+// always kept, and because whole turns are dropped, tool calls never lose their results.
+package contextwindow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// bytesPerToken is the estimate used to convert request sizes into tokens.
+const bytesPerToken = 4
+
+// mediaTokens is the flat estimate for one inline image, audio clip or file. Their base64
+// payloads are not counted as text.
+const mediaTokens = 1000
+
+// Turn is a dropped message, flattened to its text.
+type Turn struct {
+	// Role is the message role, or the item type for Responses items without one.
+	Role string
+	Text string
+}
+
+// Summarizer condenses dropped messages into text that replaces them as a user message. Returning
+// "" drops them without a trace.
+type Summarizer func(ctx context.Context, dropped []Turn) string
+
+var (
+	summarizerMu sync.RWMutex
+	summarizer   Summarizer
+)
+
+// SetSummarizer installs the summarizer used for every trimmed request. Nil removes it.
+func SetSummarizer(s Summarizer) {
+	summarizerMu.Lock()
+	summarizer = s
+	summarizerMu.Unlock()
+}
+
+// Result describes the trimming of one request. Token counts are estimates.
+type Result struct {
+	// Limit is the context window of the model.
+	Limit int
+	// Budget is the part of the window left for the request after its output tokens and the
+	// configured reserve.
+	Budget       int
+	TokensBefore int
+	TokensAfter  int
+	// DroppedTurns counts the dropped user turns, DroppedMessages the messages they contained.
+	DroppedTurns    int
+	DroppedMessages int
+	// Summarized is set when a summary or notice replaced the dropped messages.
+	Summarized bool
+}
+
+// Trimmed reports whether any message was dropped.
+func (r Result) Trimmed() bool {
+	return r.DroppedMessages > 0
+}
+
+// Apply trims body, an upstream request in protocol whose fields live under root (e.g. "request"
+// for Gemini CLI envelopes), when it does not fit the context window registered for model.
+// Turns are dropped oldest first until the request fits or only the latest user turn is left.
+// Requests for unknown models, in unsupported protocols or with invalid JSON are returned unchanged.
+func Apply(ctx context.Context, cfg config.ContextWindowConfig, protocol, root, model, requestedModel string, body []byte) ([]byte, Result) {
+	if !cfg.Enabled || len(body) == 0 || !gjson.ValidBytes(body) {
+		return body, Result{}
+	}
+	if requestedModel == "" {
+		requestedModel = model
+	}
+	if !modelMatches(cfg.Models, []string{baseModelName(model), baseModelName(requestedModel)}) {
+		return body, Result{}
+	}
+	if root != "" && !strings.HasSuffix(root, ".") {
+		root += "."
+	}
+	l, ok := layoutFor(protocol, root)
+	if !ok {
+		return body, Result{}
+	}
+	limit := util.LookupModelCapabilities(model).MaxContext
+	if limit <= 0 {
+		limit = util.LookupModelCapabilities(requestedModel).MaxContext
+	}
+	if limit <= 0 {
+		return body, Result{}
+	}
+
+	res := Result{Limit: limit, TokensBefore: estimateTokens(gjson.ParseBytes(body))}
+	res.Budget = limit - outputTokens(body, root) - max(cfg.ReserveTokens, 0)
+	res.TokensAfter = res.TokensBefore
+	if res.TokensBefore <= res.Budget {
+		return body, res
+	}
+	items := gjson.GetBytes(body, l.path)
+	if !items.IsArray() {
+		return body, res
+	}
+
+	// Group the messages into turns, each opened by user input that is not a tool result.
+	all := items.Array()
+	var turns [][]int
+	for i, item := range all {
+		if l.system(item) {
+			continue
+		}
+		if len(turns) == 0 || l.turnStart(item) {
+			turns = append(turns, nil)
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], i)
+	}
+	if len(turns) < 2 {
+		return body, res
+	}
+
+	excess := res.TokensBefore - max(res.Budget, 0)
+	dropped := make(map[int]bool)
+	var droppedTurns []Turn
+	freed := 0
+	for _, turn := range turns[:len(turns)-1] {
+		if freed >= excess {
+			break
+		}
+		for _, i := range turn {
+			dropped[i] = true
+			freed += estimateTokens(all[i])
+			droppedTurns = append(droppedTurns, Turn{Role: itemRole(all[i]), Text: itemText(all[i])})
+		}
+		res.DroppedTurns++
+	}
+
+	summary := summarize(ctx, cfg, droppedTurns)
+	kept := make([]any, 0, len(all)-len(droppedTurns)+1)
+	for i, item := range all {
+		if dropped[i] {
+			if summary != "" && !res.Summarized {
+				kept = append(kept, l.message(summary))
+				res.Summarized = true
+			}
+			continue
+		}
+		kept = append(kept, rawJSON(item.Raw))
+	}
+	out, err := sjson.SetBytes(body, l.path, kept)
+	if err != nil {
+		return body, Result{Limit: res.Limit, Budget: res.Budget, TokensBefore: res.TokensBefore, TokensAfter: res.TokensBefore}
+	}
+	res.DroppedMessages = len(droppedTurns)
+	res.TokensAfter = estimateTokens(gjson.ParseBytes(out))
+	return out, res
+}
+
+func summarize(ctx context.Context, cfg config.ContextWindowConfig, dropped []Turn) string {
+	summarizerMu.RLock()
+	s := summarizer
+	summarizerMu.RUnlock()
+	if s != nil {
+		return strings.TrimSpace(s(ctx, dropped))
+	}
+	if cfg.Notice {
+		return fmt.Sprintf("[%d earlier messages were omitted to fit the context window.]", len(dropped))
+	}
+	return ""
+}
+
+// layout locates the conversation of a request in one protocol.
+type layout struct {
+	path string
+	// system reports messages that are never dropped.
+	system func(item gjson.Result) bool
+	// turnStart reports messages that open a new user turn.
+	turnStart func(item gjson.Result) bool
+	// message builds a user message carrying text.
+	message func(text string) any
+}
+
+func layoutFor(protocol, root string) (layout, bool) {
+	switch protocol {
+	case "openai":
+		return layout{
+			path:      "messages",
+			system:    isSystemRole,
+			turnStart: func(item gjson.Result) bool { return item.Get("role").String() == "user" },
+			message:   func(text string) any { return map[string]any{"role": "user", "content": text} },
+		}, true
+	case "openai-response", "codex":
+		return layout{
+			path:   "input",
+			system: isSystemRole,
+			turnStart: func(item gjson.Result) bool {
+				typ := item.Get("type").String()
+				return item.Get("role").String() == "user" && (typ == "" || typ == "message")
+			},
+			message: func(text string) any {
+				return map[string]any{
+					"type":    "message",
+					"role":    "user",
+					"content": []any{map[string]any{"type": "input_text", "text": text}},
+				}
+			},
+		}, true
+	case "claude":
+		return layout{
+			path:   "messages",
+			system: func(gjson.Result) bool { return false },
+			turnStart: func(item gjson.Result) bool {
+				if item.Get("role").String() != "user" {
+					return false
+				}
+				for _, block := range item.Get("content").Array() {
+					if block.Get("type").String() == "tool_result" {
+						return false
+					}
+				}
+				return true
+			},
+			message: func(text string) any {
+				return map[string]any{"role": "user", "content": []any{map[string]any{"type": "text", "text": text}}}
+			},
+		}, true
+	case "gemini", "gemini-cli", "antigravity":
+		return layout{
+			path:   root + "contents",
+			system: func(gjson.Result) bool { return false },
+			turnStart: func(item gjson.Result) bool {
+				if item.Get("role").String() != "user" {
+					return false
+				}
+				for _, part := range item.Get("parts").Array() {
+					if part.Get("functionResponse").Exists() {
+						return false
+					}
+				}
+				return true
+			},
+			message: func(text string) any {
+				return map[string]any{"role": "user", "parts": []any{map[string]any{"text": text}}}
+			},
+		}, true
+	}
+	return layout{}, false
+}
+
+func isSystemRole(item gjson.Result) bool {
+	role := item.Get("role").String()
+	return role == "system" || role == "developer"
+}
+
+// outputTokens returns the output tokens the request asks for, or 0 when it does not say.
+func outputTokens(body []byte, root string) int {
+	for _, path := range []string{"max_completion_tokens", "max_tokens", "max_output_tokens", root + "generationConfig.maxOutputTokens"} {
+		if v := gjson.GetBytes(body, path).Int(); v > 0 {
+			return int(v)
+		}
+	}
+	return 0
+}
+
+// estimateTokens estimates the tokens of a JSON value: its text at bytesPerToken, plus
+// mediaTokens for every inline image, audio clip or file.
+func estimateTokens(value gjson.Result) int {
+	size, media := len(value.Raw), 0
+	var walk func(v gjson.Result)
+	walk = func(v gjson.Result) {
+		switch {
+		case v.IsObject():
+			// Gemini inlineData, Claude base64 sources and Chat Completions input_audio.
+			if data := v.Get("data"); data.Type == gjson.String && (v.Get("mimeType").Exists() || v.Get("mime_type").Exists() || v.Get("media_type").Exists() || v.Get("format").Exists()) {
+				size -= len(data.Raw)
+				media++
+				return
+			}
+			fallthrough
+		case v.IsArray():
+			v.ForEach(func(_, child gjson.Result) bool {
+				walk(child)
+				return true
+			})
+		case v.Type == gjson.String && isDataURL(v.Str):
+			size -= len(v.Raw)
+			media++
+		}
+	}
+	walk(value)
+	return (max(size, 0)+bytesPerToken-1)/bytesPerToken + media*mediaTokens
+}
+
+// isDataURL reports base64 data URLs such as the image_url of OpenAI image parts.
+func isDataURL(s string) bool {
+	if !strings.HasPrefix(s, "data:") {
+		return false
+	}
+	header, _, ok := strings.Cut(s, ",")
+	return ok && strings.HasSuffix(header, ";base64")
+}
+
+func itemRole(item gjson.Result) string {
+	if role := item.Get("role").String(); role != "" {
+		return role
+	}
+	return item.Get("type").String()
+}
+
+// itemText joins the text and string content values found anywhere in item.
+func itemText(item gjson.Result) string {
+	var parts []string
+	var walk func(key string, value gjson.Result)
+	walk = func(key string, value gjson.Result) {
+		switch {
+		case value.IsObject() || value.IsArray():
+			value.ForEach(func(k, v gjson.Result) bool {
+				walk(k.String(), v)
+				return true
+			})
+		case value.Type == gjson.String && (key == "text" || key == "content" || key == "thinking"):
+			if text := strings.TrimSpace(value.String()); text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+	walk("", item)
+	return strings.Join(parts, "\n")
+}
+
+func baseModelName(model string) string {
+	return strings.TrimPrefix(thinking.ParseSuffix(strings.TrimSpace(model)).ModelName, "models/")
+}
+
+func modelMatches(patterns []string, models []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		for _, model := range models {
+			if util.MatchWildcard(pattern, model) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rawJSON embeds an existing JSON value unchanged when marshalled by sjson.
+type rawJSON string
+
+func (r rawJSON) MarshalJSON() ([]byte, error) { return []byte(r), nil }
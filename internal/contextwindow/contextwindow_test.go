@@ -0,0 +1,138 @@
+package contextwindow
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/tidwall/gjson"
+)
+
+const testModel = "contextwindow-test-model"
+
+func registerTestModel(t *testing.T, contextLength int) {
+	t.Helper()
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient("contextwindow-test", "claude", []*registry.ModelInfo{{ID: testModel, ContextLength: contextLength}})
+	t.Cleanup(func() { reg.UnregisterClient("contextwindow-test") })
+}
+
+func filler(n int) string {
+	return strings.Repeat("x", n)
+}
+
+func TestApplyClaudeDropsOldestTurnsKeepingToolResults(t *testing.T) {
+	registerTestModel(t, 300)
+	body := []byte(`{"system":"be brief","max_tokens":50,"messages":[
+		{"role":"user","content":"` + filler(400) + `"},
+		{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"read","input":{}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"` + filler(200) + `"}]},
+		{"role":"assistant","content":"done"},
+		{"role":"user","content":"second question"},
+		{"role":"assistant","content":"second answer"},
+		{"role":"user","content":"latest question"}
+	]}`)
+
+	out, res := Apply(context.Background(), config.ContextWindowConfig{Enabled: true}, "claude", "", testModel, "", body)
+	if !res.Trimmed() || res.DroppedTurns != 1 || res.DroppedMessages != 4 {
+		t.Fatalf("result = %+v", res)
+	}
+	if res.Budget != 250 || res.TokensAfter >= res.TokensBefore {
+		t.Fatalf("result = %+v", res)
+	}
+	messages := gjson.GetBytes(out, "messages").Array()
+	if len(messages) != 3 || messages[0].Get("content").String() != "second question" {
+		t.Fatalf("messages = %s", gjson.GetBytes(out, "messages").Raw)
+	}
+	if gjson.GetBytes(out, "system").String() != "be brief" {
+		t.Fatalf("system prompt changed: %s", out)
+	}
+}
+
+func TestApplyKeepsLatestTurn(t *testing.T) {
+	registerTestModel(t, 10)
+	body := []byte(`{"messages":[
+		{"role":"system","content":"` + filler(100) + `"},
+		{"role":"user","content":"old"},
+		{"role":"assistant","content":"reply"},
+		{"role":"user","content":"` + filler(200) + `"}
+	]}`)
+
+	out, res := Apply(context.Background(), config.ContextWindowConfig{Enabled: true, Notice: true}, "openai", "", testModel, "", body)
+	if res.DroppedTurns != 1 || !res.Summarized {
+		t.Fatalf("result = %+v", res)
+	}
+	messages := gjson.GetBytes(out, "messages").Array()
+	if len(messages) != 3 {
+		t.Fatalf("messages = %s", gjson.GetBytes(out, "messages").Raw)
+	}
+	if messages[0].Get("role").String() != "system" || !strings.Contains(messages[1].Get("content").String(), "2 earlier messages") {
+		t.Fatalf("messages = %s", gjson.GetBytes(out, "messages").Raw)
+	}
+	if messages[2].Get("content").String() != filler(200) {
+		t.Fatalf("latest turn changed: %s", messages[2].Raw)
+	}
+}
+
+func TestApplyGeminiSummarizer(t *testing.T) {
+	registerTestModel(t, 60)
+	SetSummarizer(func(_ context.Context, dropped []Turn) string {
+		var texts []string
+		for _, turn := range dropped {
+			texts = append(texts, turn.Role+":"+turn.Text)
+		}
+		return "summary of " + strings.Join(texts, ",")
+	})
+	t.Cleanup(func() { SetSummarizer(nil) })
+	body := []byte(`{"request":{"contents":[
+		{"role":"user","parts":[{"text":"` + filler(150) + `"}]},
+		{"role":"model","parts":[{"text":"ok"}]},
+		{"role":"user","parts":[{"text":"now"}]}
+	]}}`)
+
+	out, res := Apply(context.Background(), config.ContextWindowConfig{Enabled: true}, "gemini-cli", "request", testModel, "", body)
+	if res.DroppedMessages != 2 || !res.Summarized {
+		t.Fatalf("result = %+v", res)
+	}
+	contents := gjson.GetBytes(out, "request.contents").Array()
+	if len(contents) != 2 || contents[0].Get("parts.0.text").String() != "summary of user:"+filler(150)+",model:ok" {
+		t.Fatalf("contents = %s", gjson.GetBytes(out, "request.contents").Raw)
+	}
+}
+
+func TestApplyLeavesFittingAndUnknownRequests(t *testing.T) {
+	registerTestModel(t, 1000)
+	body := []byte(`{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"},{"role":"user","content":"bye"}]}`)
+	cfg := config.ContextWindowConfig{Enabled: true}
+
+	if out, res := Apply(context.Background(), cfg, "openai", "", testModel, "", body); res.Trimmed() || string(out) != string(body) {
+		t.Fatalf("fitting request trimmed: %+v %s", res, out)
+	}
+	if out, res := Apply(context.Background(), cfg, "openai", "", "contextwindow-unknown-model", "", body); res.Limit != 0 || string(out) != string(body) {
+		t.Fatalf("unknown model trimmed: %+v %s", res, out)
+	}
+	cfg.Models = []string{"other-*"}
+	if _, res := Apply(context.Background(), cfg, "openai", "", testModel, "", body); res.Limit != 0 {
+		t.Fatalf("unmatched model considered: %+v", res)
+	}
+}
+
+func TestApplyCountsInlineMediaAtFlatCost(t *testing.T) {
+	registerTestModel(t, 2500)
+	image := strings.Repeat("QUJD", 10000)
+	body := []byte(`{"messages":[
+		{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,` + image + `"}}]},
+		{"role":"assistant","content":"a cat"},
+		{"role":"user","content":[{"type":"input_audio","input_audio":{"data":"` + image + `","format":"wav"}},{"type":"text","text":"and this?"}]}
+	]}`)
+
+	out, res := Apply(context.Background(), config.ContextWindowConfig{Enabled: true}, "openai", "", testModel, "", body)
+	if res.Trimmed() || string(out) != string(body) {
+		t.Fatalf("request with inline media trimmed: %+v", res)
+	}
+	if res.TokensBefore < 2*mediaTokens || res.TokensBefore > 2*mediaTokens+100 {
+		t.Fatalf("TokensBefore = %d, want two flat media costs plus the text", res.TokensBefore)
+	}
+}
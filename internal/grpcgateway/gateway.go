@@ -0,0 +1,240 @@
+// Package grpcgateway serves the gRPC services described in cliproxy.proto on the API port.
+// Calls are translated into requests against the REST API handler, so authentication, access
+// rules and behaviour are exactly those of the management and OpenAI endpoints. The gRPC wire
+// format is implemented directly on net/http; messages use the protobuf well-known types.
+package grpcgateway
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxMessageBytes bounds the size of a received gRPC message.
+const maxMessageBytes = 16 << 20
+
+// gRPC status codes, see https://grpc.github.io/grpc/core/md_doc_statuscodes.html.
+const (
+	codeOK                 = 0
+	codeUnknown            = 2
+	codeInvalidArgument    = 3
+	codeDeadlineExceeded   = 4
+	codeNotFound           = 5
+	codeAlreadyExists      = 6
+	codePermissionDenied   = 7
+	codeResourceExhausted  = 8
+	codeFailedPrecondition = 9
+	codeUnimplemented      = 12
+	codeInternal           = 13
+	codeUnavailable        = 14
+	codeUnauthenticated    = 16
+)
+
+// settings is the gateway configuration applied by Configure.
+type settings struct {
+	inference bool
+}
+
+// Gateway answers gRPC requests and passes all other requests to the REST handler.
+type Gateway struct {
+	handler http.Handler
+	state   atomic.Pointer[settings]
+}
+
+// New returns a gateway in front of handler. It serves no gRPC services until configured.
+func New(handler http.Handler) *Gateway {
+	return &Gateway{handler: handler}
+}
+
+// Configure applies the grpc settings from cfg.
+func (g *Gateway) Configure(cfg *config.Config) {
+	if g == nil {
+		return
+	}
+	if cfg == nil || !cfg.GRPC.Enabled {
+		g.state.Store(nil)
+		return
+	}
+	g.state.Store(&settings{inference: cfg.GRPC.Inference})
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	st := g.state.Load()
+	if st == nil || r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		g.handler.ServeHTTP(w, r)
+		return
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "application/grpc" && contentType != "application/grpc+proto" {
+		http.Error(w, "only the proto codec is supported", http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.ProtoMajor < 2 {
+		http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	service, method, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	switch {
+	case service == managementService:
+		g.serveManagement(w, r, method)
+	case service == inferenceService && st.inference:
+		g.serveInference(w, r, method)
+	default:
+		finish(w, codeUnimplemented, fmt.Sprintf("unknown service %s", service))
+	}
+}
+
+// readMessage reads one length-prefixed message. It returns io.EOF once the client closed its
+// side of the stream.
+func readMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("truncated message header")
+		}
+		return nil, err
+	}
+	if prefix[0] != 0 {
+		return nil, fmt.Errorf("compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxMessageBytes {
+		return nil, fmt.Errorf("message of %d bytes exceeds the limit of %d bytes", size, maxMessageBytes)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("truncated message: %w", err)
+	}
+	return payload, nil
+}
+
+// readRequest reads the single request message of a unary call into msg. A call without a
+// message leaves msg empty.
+func readRequest(r *http.Request, msg proto.Message) error {
+	payload, err := readMessage(r.Body)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(payload, msg)
+}
+
+// writeMessage sends msg as one length-prefixed message and flushes it to the client.
+func writeMessage(w http.ResponseWriter, msg proto.Message) error {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	var prefix [5]byte
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(payload)))
+	if _, err = w.Write(append(prefix[:], payload...)); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// finish ends the call with the given status in the trailers.
+func finish(w http.ResponseWriter, code int, message string) {
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", encodeStatusMessage(message))
+	}
+}
+
+// encodeStatusMessage percent-encodes a status message as the gRPC protocol requires.
+func encodeStatusMessage(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c >= 0x20 && c <= 0x7e && c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// statusFromHTTP maps the HTTP status of a REST response to a gRPC status code.
+func statusFromHTTP(status int) int {
+	switch {
+	case status >= 200 && status < 300:
+		return codeOK
+	case status == http.StatusBadRequest, status == http.StatusUnprocessableEntity:
+		return codeInvalidArgument
+	case status == http.StatusUnauthorized:
+		return codeUnauthenticated
+	case status == http.StatusForbidden:
+		return codePermissionDenied
+	case status == http.StatusNotFound:
+		return codeNotFound
+	case status == http.StatusConflict:
+		return codeAlreadyExists
+	case status == http.StatusPreconditionFailed:
+		return codeFailedPrecondition
+	case status == http.StatusTooManyRequests, status == http.StatusPaymentRequired:
+		return codeResourceExhausted
+	case status == http.StatusNotImplemented:
+		return codeUnimplemented
+	case status == http.StatusGatewayTimeout, status == http.StatusRequestTimeout:
+		return codeDeadlineExceeded
+	case status == http.StatusBadGateway, status == http.StatusServiceUnavailable, status == 529:
+		return codeUnavailable
+	case status >= 500:
+		return codeInternal
+	default:
+		return codeUnknown
+	}
+}
+
+// errorMessage extracts the error text of a REST error body.
+func errorMessage(status int, body []byte) string {
+	for _, path := range []string{"error.message", "error", "message"} {
+		if value := gjson.GetBytes(body, path); value.Type == gjson.String && value.String() != "" {
+			return value.String()
+		}
+	}
+	if text := strings.TrimSpace(string(body)); text != "" && len(text) <= 1024 {
+		return text
+	}
+	return http.StatusText(status)
+}
+
+// newRESTRequest builds the REST request for a gRPC call. gRPC metadata arrives as HTTP/2
+// headers and is forwarded as-is, so credentials work the same way as over REST.
+func newRESTRequest(r *http.Request, method, path string, body []byte, contentType string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(r.Context(), method, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.Header {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "grpc-") || lower == "content-type" || lower == "content-length" || lower == "te" {
+			continue
+		}
+		req.Header[key] = append([]string(nil), values...)
+	}
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.RemoteAddr = r.RemoteAddr
+	req.Host = r.Host
+	return req, nil
+}
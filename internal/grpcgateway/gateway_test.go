@@ -0,0 +1,156 @@
+package grpcgateway
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func newTestGateway(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v0/management/usage", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid management key"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"usage":{"total_requests":3}}`))
+	})
+	mux.HandleFunc("/v0/management/config.yaml", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/yaml" || string(body) != "port: 8317\n" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, `{"error":"unexpected %s body %q"}`, r.Header.Get("Content-Type"), body)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !bytes.Contains(body, []byte(`"stream":true`)) {
+			t.Errorf("chat request was not streamed: %s", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"object\":\"chat.completion.chunk\",\"n\":1}\n\n: keep-alive\n\ndata: {\"object\":\"chat.completion.chunk\","))
+		_, _ = w.Write([]byte("\"n\":2}\n\ndata: [DONE]\n\n"))
+	})
+
+	gateway := New(mux)
+	gateway.Configure(&config.Config{GRPC: config.GRPCConfig{Enabled: true, Inference: true}})
+	server := httptest.NewUnstartedServer(gateway)
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// invoke performs a gRPC call with the given request messages and returns the response
+// messages and the grpc-status trailer.
+func invoke(t *testing.T, server *httptest.Server, method, auth string, requests ...proto.Message) ([][]byte, string, string) {
+	t.Helper()
+	var body bytes.Buffer
+	for _, msg := range requests {
+		payload, err := proto.Marshal(msg)
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		var prefix [5]byte
+		binary.BigEndian.PutUint32(prefix[1:], uint32(len(payload)))
+		body.Write(prefix[:])
+		body.Write(payload)
+	}
+	req, _ := http.NewRequest(http.MethodPost, server.URL+method, &body)
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Authorization", auth)
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		t.Fatalf("gRPC call failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var messages [][]byte
+	for {
+		payload, errRead := readMessage(resp.Body)
+		if errors.Is(errRead, io.EOF) {
+			break
+		}
+		if errRead != nil {
+			t.Fatalf("read response: %v", errRead)
+		}
+		messages = append(messages, payload)
+	}
+	return messages, resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+}
+
+func TestManagementUnaryCall(t *testing.T) {
+	server := newTestGateway(t)
+
+	messages, status, _ := invoke(t, server, "/cliproxy.v1.Management/GetUsage", "Bearer secret", &emptypb.Empty{})
+	if status != "0" || len(messages) != 1 {
+		t.Fatalf("status %q with %d messages", status, len(messages))
+	}
+	value := &structpb.Value{}
+	if err := proto.Unmarshal(messages[0], value); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if got := value.GetStructValue().GetFields()["usage"].GetStructValue().GetFields()["total_requests"].GetNumberValue(); got != 3 {
+		t.Fatalf("total_requests = %v", got)
+	}
+
+	_, status, message := invoke(t, server, "/cliproxy.v1.Management/GetUsage", "Bearer wrong", &emptypb.Empty{})
+	if status != fmt.Sprint(codeUnauthenticated) || message != "invalid management key" {
+		t.Fatalf("unauthenticated call returned %q %q", status, message)
+	}
+	if _, status, _ = invoke(t, server, "/cliproxy.v1.Unknown/Method", ""); status != fmt.Sprint(codeUnimplemented) {
+		t.Fatalf("unknown service returned status %q", status)
+	}
+}
+
+func TestManagementCallSendsYAMLVerbatim(t *testing.T) {
+	server := newTestGateway(t)
+
+	call, _ := structpb.NewStruct(map[string]any{"method": "PUT", "path": "/v0/management/config.yaml", "body": "port: 8317\n"})
+	messages, status, message := invoke(t, server, "/cliproxy.v1.Management/Call", "Bearer secret", call)
+	if status != "0" || len(messages) != 1 {
+		t.Fatalf("status %q %q with %d messages", status, message, len(messages))
+	}
+
+	call, _ = structpb.NewStruct(map[string]any{"method": "PUT", "path": "/v0/management/config.yaml", "body": map[string]any{"port": 8317}})
+	if _, status, _ = invoke(t, server, "/cliproxy.v1.Management/Call", "Bearer secret", call); status != fmt.Sprint(codeInvalidArgument) {
+		t.Fatalf("structured YAML body returned status %q", status)
+	}
+}
+
+func TestInferenceChatStream(t *testing.T) {
+	server := newTestGateway(t)
+	request, _ := structpb.NewStruct(map[string]any{"model": "m", "messages": []any{map[string]any{"role": "user", "content": "hi"}}})
+
+	messages, status, _ := invoke(t, server, "/cliproxy.v1.Inference/Chat", "Bearer key", request, request)
+	if status != "0" || len(messages) != 6 {
+		t.Fatalf("status %q with %d messages, want 2 x (2 chunks + done)", status, len(messages))
+	}
+	for i, want := range []string{"n", "n", "done"} {
+		chunk := &structpb.Struct{}
+		if err := proto.Unmarshal(messages[i], chunk); err != nil {
+			t.Fatalf("unmarshal message %d: %v", i, err)
+		}
+		if _, ok := chunk.GetFields()[want]; !ok {
+			t.Fatalf("message %d = %v, want field %q", i, chunk, want)
+		}
+	}
+}
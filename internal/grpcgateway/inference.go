@@ -0,0 +1,135 @@
+package grpcgateway
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/tidwall/sjson"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	inferenceService = "cliproxy.v1.Inference"
+	chatEndpoint     = "/v1/chat/completions"
+)
+
+func (g *Gateway) serveInference(w http.ResponseWriter, r *http.Request, method string) {
+	if method != "Chat" {
+		finish(w, codeUnimplemented, "unknown method "+method)
+		return
+	}
+	// Chat is bidirectional: responses are written while further requests are still arriving.
+	_ = http.NewResponseController(w).EnableFullDuplex()
+	w.WriteHeader(http.StatusOK)
+	for {
+		payload, err := readMessage(r.Body)
+		if errors.Is(err, io.EOF) {
+			finish(w, codeOK, "")
+			return
+		}
+		if err != nil {
+			finish(w, codeInvalidArgument, err.Error())
+			return
+		}
+		request := &structpb.Struct{}
+		if err = proto.Unmarshal(payload, request); err != nil {
+			finish(w, codeInvalidArgument, err.Error())
+			return
+		}
+		if code, message := g.chat(w, r, request); code != codeOK {
+			finish(w, code, message)
+			return
+		}
+		if err = writeMessage(w, &structpb.Struct{Fields: map[string]*structpb.Value{"done": structpb.NewBoolValue(true)}}); err != nil {
+			return
+		}
+	}
+}
+
+// chat streams the chunks of one Chat Completions request to the client.
+func (g *Gateway) chat(w http.ResponseWriter, r *http.Request, request *structpb.Struct) (int, string) {
+	body, err := protojson.Marshal(request)
+	if err != nil {
+		return codeInvalidArgument, err.Error()
+	}
+	if body, err = sjson.SetBytes(body, "stream", true); err != nil {
+		return codeInvalidArgument, err.Error()
+	}
+	req, err := newRESTRequest(r, http.MethodPost, chatEndpoint, body, "application/json")
+	if err != nil {
+		return codeInvalidArgument, err.Error()
+	}
+	out := &sseWriter{header: make(http.Header), emit: func(data []byte) error {
+		chunk := &structpb.Struct{}
+		if errUnmarshal := protojson.Unmarshal(data, chunk); errUnmarshal != nil {
+			return nil
+		}
+		return writeMessage(w, chunk)
+	}}
+	g.handler.ServeHTTP(out, req)
+	if out.err != nil {
+		return codeUnavailable, out.err.Error()
+	}
+	if out.status != 0 && out.status != http.StatusOK {
+		return statusFromHTTP(out.status), errorMessage(out.status, out.errBody.Bytes())
+	}
+	return codeOK, ""
+}
+
+// sseWriter is the response writer of a streaming Chat Completions request. It passes the data
+// of every server-sent event to emit as soon as the event is complete, and captures non-200
+// responses whole.
+type sseWriter struct {
+	header  http.Header
+	status  int
+	pending bytes.Buffer
+	errBody bytes.Buffer
+	emit    func(data []byte) error
+	err     error
+}
+
+func (s *sseWriter) Header() http.Header { return s.header }
+
+func (s *sseWriter) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+}
+
+func (s *sseWriter) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	if s.status != http.StatusOK {
+		return s.errBody.Write(p)
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	s.pending.Write(p)
+	for {
+		line, rest, found := bytes.Cut(s.pending.Bytes(), []byte("\n"))
+		if !found {
+			break
+		}
+		data, isData := bytes.CutPrefix(bytes.TrimRight(line, "\r"), []byte("data:"))
+		// rest aliases the buffer, so the line is consumed before the buffer is touched again.
+		consumed := len(s.pending.Bytes()) - len(rest)
+		if isData {
+			if data = bytes.TrimSpace(data); len(data) > 0 && !bytes.Equal(data, []byte("[DONE]")) {
+				if s.err = s.emit(bytes.Clone(data)); s.err != nil {
+					return 0, s.err
+				}
+			}
+		}
+		s.pending.Next(consumed)
+	}
+	return len(p), nil
+}
+
+// Flush implements http.Flusher; events are forwarded as soon as they are complete.
+func (s *sseWriter) Flush() {}
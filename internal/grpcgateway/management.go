@@ -0,0 +1,137 @@
+package grpcgateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	managementService = "cliproxy.v1.Management"
+	managementPrefix  = "/v0/management/"
+)
+
+// managementReads maps the read-only Management methods to their REST endpoints.
+var managementReads = map[string]string{
+	"ListAccounts": managementPrefix + "auth-files",
+	"GetUsage":     managementPrefix + "usage",
+	"GetConfig":    managementPrefix + "config",
+	"GetHealth":    managementPrefix + "health",
+}
+
+// managementRawBodies maps the Management endpoints that do not take JSON to their content type.
+// Call sends their string "body" verbatim.
+var managementRawBodies = map[string]string{
+	managementPrefix + "config.yaml": "application/yaml",
+}
+
+func (g *Gateway) serveManagement(w http.ResponseWriter, r *http.Request, method string) {
+	if path, ok := managementReads[method]; ok {
+		if err := readRequest(r, &emptypb.Empty{}); err != nil {
+			finish(w, codeInvalidArgument, err.Error())
+			return
+		}
+		g.callManagement(w, r, http.MethodGet, path, nil, "")
+		return
+	}
+	if method != "Call" {
+		finish(w, codeUnimplemented, "unknown method "+method)
+		return
+	}
+
+	call := &structpb.Struct{}
+	if err := readRequest(r, call); err != nil {
+		finish(w, codeInvalidArgument, err.Error())
+		return
+	}
+	fields := call.GetFields()
+	httpMethod := strings.ToUpper(strings.TrimSpace(fields["method"].GetStringValue()))
+	if httpMethod == "" {
+		httpMethod = http.MethodGet
+	}
+	path := strings.TrimSpace(fields["path"].GetStringValue())
+	if !strings.HasPrefix(path, managementPrefix) {
+		finish(w, codeInvalidArgument, "path must start with "+managementPrefix)
+		return
+	}
+	var body []byte
+	contentType, raw := managementRawBodies[path]
+	if value, ok := fields["body"]; ok {
+		if raw {
+			text, isString := value.GetKind().(*structpb.Value_StringValue)
+			if !isString {
+				finish(w, codeInvalidArgument, path+" takes a string body")
+				return
+			}
+			body = []byte(text.StringValue)
+		} else {
+			encoded, err := protojson.Marshal(value)
+			if err != nil {
+				finish(w, codeInvalidArgument, err.Error())
+				return
+			}
+			body, contentType = encoded, "application/json"
+		}
+	}
+	g.callManagement(w, r, httpMethod, path, body, contentType)
+}
+
+// callManagement serves a unary call from the response of a management endpoint.
+func (g *Gateway) callManagement(w http.ResponseWriter, r *http.Request, method, path string, body []byte, contentType string) {
+	req, err := newRESTRequest(r, method, path, body, contentType)
+	if err != nil {
+		finish(w, codeInvalidArgument, err.Error())
+		return
+	}
+	rec := newResponseRecorder()
+	g.handler.ServeHTTP(rec, req)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	if code := statusFromHTTP(rec.status); code != codeOK {
+		finish(w, code, errorMessage(rec.status, rec.body.Bytes()))
+		return
+	}
+
+	result := &structpb.Value{}
+	if payload := bytes.TrimSpace(rec.body.Bytes()); len(payload) == 0 {
+		result = structpb.NewNullValue()
+	} else if !json.Valid(payload) || protojson.Unmarshal(payload, result) != nil {
+		result = structpb.NewStringValue(rec.body.String())
+	}
+	if err = writeMessage(w, result); err != nil {
+		return
+	}
+	finish(w, codeOK, "")
+}
+
+// responseRecorder captures a complete REST response.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: make(http.Header)}
+}
+
+func (r *responseRecorder) Header() http.Header { return r.header }
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(p)
+}
@@ -0,0 +1,356 @@
+// Package guardrail implements the content filters configured under guardrails. Policies are
+// selected per client API key and check prompt text before a request is translated, and generated
+// text before a response is returned: keyword and regular expression blocklists, a prompt length
+// limit, PII redaction, and an optional pre-check by a moderation model.
+package guardrail
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// StageRequest marks violations found in the client request.
+	StageRequest = "request"
+	// StageResponse marks violations found in generated output.
+	StageResponse = "response"
+
+	// defaultStreamWindow is the minimum number of characters carried between stream chunks.
+	// Patterns matching longer spans across a chunk boundary are not caught.
+	defaultStreamWindow = 256
+
+	defaultModerationPrompt = "You are a content moderation classifier for an AI gateway. Decide whether the user text " +
+		"is acceptable to forward to a general-purpose assistant. Block text that seeks serious harm, such as " +
+		"weapons capable of mass casualties, sexual content involving minors, malware, or targeted harassment. " +
+		"Answer with exactly ALLOW, or BLOCK: <short reason>."
+)
+
+// Violation reports content rejected by a policy. It is surfaced to clients as HTTP 400.
+type Violation struct {
+	Policy string
+	Stage  string
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	if v.Policy == "" {
+		return fmt.Sprintf("%s blocked by guardrail: %s", v.Stage, v.Reason)
+	}
+	return fmt.Sprintf("%s blocked by guardrail policy %q: %s", v.Stage, v.Policy, v.Reason)
+}
+
+// StatusCode implements the status interface consulted by the handlers.
+func (v *Violation) StatusCode() int { return http.StatusBadRequest }
+
+// Moderator executes an OpenAI Chat Completions request against model and returns the response body.
+type Moderator func(ctx context.Context, model string, request []byte) ([]byte, error)
+
+// Policy is a compiled GuardrailPolicy.
+type Policy struct {
+	name             string
+	keywords         []string
+	patterns         []*regexp.Regexp
+	maxPromptChars   int
+	redactors        []redactor
+	moderationModel  string
+	moderationPrompt string
+	failClosed       bool
+	// window is the number of trailing characters of a stream carried into the next chunk's
+	// block check, so blocked text split across chunks is still caught.
+	window int
+}
+
+// Name returns the configured policy name.
+func (p *Policy) Name() string {
+	if p == nil {
+		return ""
+	}
+	return p.name
+}
+
+// Set is a compiled guardrails configuration. It is built once per configuration load and is
+// safe for concurrent use.
+type Set struct {
+	policies []*Policy
+	keys     []map[string]struct{}
+}
+
+// Compile compiles policies. Invalid patterns and unknown PII kinds are logged and skipped.
+func Compile(policies []config.GuardrailPolicy) *Set {
+	if len(policies) == 0 {
+		return nil
+	}
+	set := &Set{
+		policies: make([]*Policy, len(policies)),
+		keys:     make([]map[string]struct{}, len(policies)),
+	}
+	for i := range policies {
+		set.policies[i] = compile(&policies[i])
+		for _, key := range policies[i].APIKeys {
+			if key = strings.TrimSpace(key); key != "" {
+				if set.keys[i] == nil {
+					set.keys[i] = make(map[string]struct{}, len(policies[i].APIKeys))
+				}
+				set.keys[i][key] = struct{}{}
+			}
+		}
+	}
+	return set
+}
+
+// Select returns the policy for apiKey: the first policy listing the key, otherwise the first
+// policy without api-keys. It returns nil when no policy applies.
+func (s *Set) Select(apiKey string) *Policy {
+	if s == nil {
+		return nil
+	}
+	apiKey = strings.TrimSpace(apiKey)
+	var fallback *Policy
+	for i, keys := range s.keys {
+		if keys == nil {
+			if fallback == nil {
+				fallback = s.policies[i]
+			}
+			continue
+		}
+		if _, ok := keys[apiKey]; ok && apiKey != "" {
+			return s.policies[i]
+		}
+	}
+	return fallback
+}
+
+func compile(cfg *config.GuardrailPolicy) *Policy {
+	p := &Policy{
+		name:             strings.TrimSpace(cfg.Name),
+		maxPromptChars:   cfg.MaxPromptChars,
+		moderationModel:  strings.TrimSpace(cfg.ModerationModel),
+		moderationPrompt: strings.TrimSpace(cfg.ModerationPrompt),
+		failClosed:       cfg.ModerationFailClosed,
+	}
+	if p.moderationPrompt == "" {
+		p.moderationPrompt = defaultModerationPrompt
+	}
+	p.window = defaultStreamWindow
+	for _, keyword := range cfg.Blocklist {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			p.keywords = append(p.keywords, keyword)
+			p.window = max(p.window, utf8.RuneCountInString(keyword))
+		}
+	}
+	for _, pattern := range cfg.BlockPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Warnf("guardrail policy %q: ignoring invalid block pattern %q: %v", p.name, pattern, err)
+			continue
+		}
+		p.patterns = append(p.patterns, re)
+	}
+	for _, kind := range cfg.RedactPII {
+		r, ok := redactorFor(kind)
+		if !ok {
+			log.Warnf("guardrail policy %q: ignoring unknown redact-pii kind %q", p.name, kind)
+			continue
+		}
+		p.redactors = append(p.redactors, r)
+	}
+	return p
+}
+
+// CheckRequest enforces the policy on a client request body in any inbound format and returns
+// the body with PII redacted. Blocklists are matched before redaction so patterns can target the
+// original text; the moderation model only ever sees the redacted prompt.
+func (p *Policy) CheckRequest(ctx context.Context, body []byte, moderate Moderator) ([]byte, error) {
+	if p == nil || len(body) == 0 || !gjson.ValidBytes(body) {
+		return body, nil
+	}
+	if p.maxPromptChars > 0 {
+		total := 0
+		_, _ = rewriteText(body, promptKeys, func(text string) (string, error) {
+			total += utf8.RuneCountInString(text)
+			return text, nil
+		})
+		if total > p.maxPromptChars {
+			return nil, p.violation(StageRequest, fmt.Sprintf("prompt is %d characters, limit is %d", total, p.maxPromptChars))
+		}
+	}
+	var prompt []string
+	out, err := rewriteText(body, promptKeys, func(text string) (string, error) {
+		if errBlock := p.blocked(StageRequest, text); errBlock != nil {
+			return "", errBlock
+		}
+		text = p.redact(text)
+		prompt = append(prompt, text)
+		return text, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p.moderationModel != "" && moderate != nil && len(prompt) > 0 {
+		if errModerate := p.moderate(ctx, moderate, strings.Join(prompt, "\n\n")); errModerate != nil {
+			return nil, errModerate
+		}
+	}
+	return out, nil
+}
+
+// FilterResponse enforces the policy on a complete response body in the client's format.
+// Streams use a StreamFilter so blocked text split across chunks is caught.
+func (p *Policy) FilterResponse(payload []byte) ([]byte, error) {
+	return p.filter(payload, func(text string) error {
+		return p.blocked(StageResponse, text)
+	})
+}
+
+// StreamFilter enforces a policy on the chunks of one streamed response. Block checks run over a
+// sliding window of the text already streamed plus the new chunk; PII is redacted per chunk.
+type StreamFilter struct {
+	policy *Policy
+	tail   string
+}
+
+// NewStreamFilter returns a filter for one streamed response, or nil for a nil policy.
+func (p *Policy) NewStreamFilter() *StreamFilter {
+	if p == nil {
+		return nil
+	}
+	return &StreamFilter{policy: p}
+}
+
+// Filter enforces the policy on one stream chunk, which may be bare JSON or SSE lines.
+func (f *StreamFilter) Filter(chunk []byte) ([]byte, error) {
+	if f == nil {
+		return chunk, nil
+	}
+	return f.policy.filter(chunk, func(text string) error {
+		joined := f.tail + text
+		if err := f.policy.blocked(StageResponse, joined); err != nil {
+			return err
+		}
+		f.tail = lastRunes(joined, f.policy.window)
+		return nil
+	})
+}
+
+func (p *Policy) filter(payload []byte, check func(text string) error) ([]byte, error) {
+	if p == nil || (len(p.keywords) == 0 && len(p.patterns) == 0 && len(p.redactors) == 0) || len(payload) == 0 {
+		return payload, nil
+	}
+	filter := func(doc []byte) ([]byte, error) {
+		return rewriteText(doc, outputKeys, func(text string) (string, error) {
+			if errBlock := check(text); errBlock != nil {
+				return "", errBlock
+			}
+			return p.redact(text), nil
+		})
+	}
+	trimmed := strings.TrimSpace(string(payload))
+	if strings.HasPrefix(trimmed, "{") && gjson.Valid(trimmed) {
+		return filter(payload)
+	}
+	lines := strings.Split(string(payload), "\n")
+	changed := false
+	for i, line := range lines {
+		rest, ok := strings.CutPrefix(strings.TrimLeft(line, " "), "data:")
+		if !ok {
+			continue
+		}
+		data := strings.TrimSpace(rest)
+		if !strings.HasPrefix(data, "{") || !gjson.Valid(data) {
+			continue
+		}
+		out, err := filter([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		if string(out) != data {
+			lines[i] = "data: " + string(out)
+			changed = true
+		}
+	}
+	if !changed {
+		return payload, nil
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+func lastRunes(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	cut := len(text)
+	for i := 0; i < n; i++ {
+		_, size := utf8.DecodeLastRuneInString(text[:cut])
+		cut -= size
+	}
+	return text[cut:]
+}
+
+func (p *Policy) blocked(stage, text string) error {
+	if len(p.keywords) > 0 {
+		lower := strings.ToLower(text)
+		for _, keyword := range p.keywords {
+			if strings.Contains(lower, keyword) {
+				log.Debugf("guardrail policy %q: %s matched blocked keyword %q", p.name, stage, keyword)
+				return p.violation(stage, "content matches a blocked keyword")
+			}
+		}
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(text) {
+			log.Debugf("guardrail policy %q: %s matched blocked pattern %q", p.name, stage, re.String())
+			return p.violation(stage, "content matches a blocked pattern")
+		}
+	}
+	return nil
+}
+
+func (p *Policy) redact(text string) string {
+	for _, r := range p.redactors {
+		text = r.re.ReplaceAllString(text, r.replacement)
+	}
+	return text
+}
+
+func (p *Policy) moderate(ctx context.Context, moderate Moderator, prompt string) error {
+	request, _ := sjson.SetBytes([]byte(`{"stream":false,"temperature":0,"max_tokens":64}`), "model", p.moderationModel)
+	request, _ = sjson.SetBytes(request, "messages.0", map[string]string{"role": "system", "content": p.moderationPrompt})
+	request, _ = sjson.SetBytes(request, "messages.1", map[string]string{"role": "user", "content": prompt})
+
+	resp, err := moderate(ctx, p.moderationModel, request)
+	if err == nil {
+		verdict := strings.TrimSpace(gjson.GetBytes(resp, "choices.0.message.content").String())
+		upper := strings.ToUpper(verdict)
+		switch {
+		case strings.HasPrefix(upper, "ALLOW"):
+			return nil
+		case strings.HasPrefix(upper, "BLOCK"):
+			reason := strings.TrimSpace(strings.TrimLeft(verdict[len("BLOCK"):], ":- "))
+			if reason == "" {
+				reason = "unspecified"
+			}
+			return p.violation(StageRequest, "flagged by moderation: "+reason)
+		default:
+			err = fmt.Errorf("unexpected verdict %q", verdict)
+		}
+	}
+	if p.failClosed {
+		log.Warnf("guardrail policy %q: moderation with %s failed, rejecting request: %v", p.name, p.moderationModel, err)
+		return p.violation(StageRequest, "moderation check unavailable")
+	}
+	log.Warnf("guardrail policy %q: moderation with %s failed, allowing request: %v", p.name, p.moderationModel, err)
+	return nil
+}
+
+func (p *Policy) violation(stage, reason string) *Violation {
+	return &Violation{Policy: p.name, Stage: stage, Reason: reason}
+}
@@ -0,0 +1,154 @@
+package guardrail
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestSelectPrefersKeyPolicyOverDefault(t *testing.T) {
+	policies := []config.GuardrailPolicy{
+		{Name: "default"},
+		{Name: "strict", APIKeys: []string{"key-a"}},
+	}
+	if got := Compile(policies).Select("key-a").Name(); got != "strict" {
+		t.Fatalf("key-a policy = %q, want strict", got)
+	}
+	if got := Compile(policies).Select("key-b").Name(); got != "default" {
+		t.Fatalf("key-b policy = %q, want default", got)
+	}
+	if Compile(policies[1:]).Select("key-b") != nil {
+		t.Fatal("expected no policy without a default")
+	}
+}
+
+func TestCheckRequestBlocklistAcrossFormats(t *testing.T) {
+	policy := Compile([]config.GuardrailPolicy{{
+		Name:          "p",
+		Blocklist:     []string{"Project Falcon"},
+		BlockPatterns: []string{`\bssn:\s*\d{3}-\d{2}-\d{4}`},
+	}}).Select("")
+
+	blocked := []string{
+		`{"messages":[{"role":"user","content":"tell me about project falcon"}]}`,
+		`{"messages":[{"role":"user","content":[{"type":"text","text":"my ssn: 123-45-6789"}]}]}`,
+		`{"system":"PROJECT FALCON","messages":[]}`,
+		`{"contents":[{"role":"user","parts":[{"text":"project falcon"}]}]}`,
+		`{"input":[{"role":"user","content":[{"type":"input_text","text":"project falcon"}]}]}`,
+		`{"input":"project falcon"}`,
+	}
+	for _, body := range blocked {
+		_, err := policy.CheckRequest(context.Background(), []byte(body), nil)
+		var violation *Violation
+		if !errors.As(err, &violation) || violation.Stage != StageRequest {
+			t.Errorf("CheckRequest(%s) error = %v, want request violation", body, err)
+		}
+	}
+
+	allowed := `{"model":"project-falcon","tools":[{"name":"x","description":"project falcon"}],"messages":[{"role":"user","content":"hello"}]}`
+	if _, err := policy.CheckRequest(context.Background(), []byte(allowed), nil); err != nil {
+		t.Fatalf("CheckRequest(allowed) error = %v", err)
+	}
+}
+
+func TestCheckRequestMaxPromptChars(t *testing.T) {
+	policy := Compile([]config.GuardrailPolicy{{MaxPromptChars: 10}}).Select("")
+	if _, err := policy.CheckRequest(context.Background(), []byte(`{"messages":[{"content":"12345"},{"content":"6789é"}]}`), nil); err != nil {
+		t.Fatalf("10 characters rejected: %v", err)
+	}
+	_, err := policy.CheckRequest(context.Background(), []byte(`{"messages":[{"content":"12345"},{"content":"678901"}]}`), nil)
+	if err == nil || !strings.Contains(err.Error(), "limit is 10") {
+		t.Fatalf("11 characters error = %v", err)
+	}
+}
+
+func TestRedactPII(t *testing.T) {
+	policy := Compile([]config.GuardrailPolicy{{RedactPII: []string{"email", "phone", "key"}}}).Select("")
+	body := `{"messages":[{"role":"user","content":"mail jane.doe@example.co.uk or call +1 555-123-4567, key sk-abcdefghijklmnopqrstuv, build 20250929"}]}`
+	out, err := policy.CheckRequest(context.Background(), []byte(body), nil)
+	if err != nil {
+		t.Fatalf("CheckRequest error = %v", err)
+	}
+	want := "mail [REDACTED_EMAIL] or call [REDACTED_PHONE], key [REDACTED_KEY], build 20250929"
+	if got := gjson.GetBytes(out, "messages.0.content").String(); got != want {
+		t.Fatalf("redacted = %q\nwant %q", got, want)
+	}
+}
+
+func TestModeration(t *testing.T) {
+	policies := []config.GuardrailPolicy{{Name: "mod", ModerationModel: "mod-model", RedactPII: []string{"email"}}}
+	body := []byte(`{"messages":[{"role":"user","content":"write to a@b.io"}]}`)
+
+	var seen []byte
+	verdict := "ALLOW"
+	moderate := func(_ context.Context, model string, request []byte) ([]byte, error) {
+		if model != "mod-model" {
+			t.Fatalf("moderation model = %q", model)
+		}
+		seen = request
+		return []byte(`{"choices":[{"message":{"role":"assistant","content":"` + verdict + `"}}]}`), nil
+	}
+	if _, err := Compile(policies).Select("").CheckRequest(context.Background(), body, moderate); err != nil {
+		t.Fatalf("allowed request error = %v", err)
+	}
+	if got := gjson.GetBytes(seen, "messages.1.content").String(); got != "write to [REDACTED_EMAIL]" {
+		t.Fatalf("moderation saw %q, want redacted prompt", got)
+	}
+
+	verdict = "BLOCK: harassment"
+	_, err := Compile(policies).Select("").CheckRequest(context.Background(), body, moderate)
+	if err == nil || !strings.Contains(err.Error(), "flagged by moderation: harassment") {
+		t.Fatalf("blocked request error = %v", err)
+	}
+
+	failing := func(context.Context, string, []byte) ([]byte, error) { return nil, errors.New("upstream down") }
+	if _, err = Compile(policies).Select("").CheckRequest(context.Background(), body, failing); err != nil {
+		t.Fatalf("fail-open error = %v", err)
+	}
+	policies[0].ModerationFailClosed = true
+	if _, err = Compile(policies).Select("").CheckRequest(context.Background(), body, failing); err == nil {
+		t.Fatal("fail-closed policy allowed request")
+	}
+}
+
+func TestFilterResponse(t *testing.T) {
+	policy := Compile([]config.GuardrailPolicy{{Blocklist: []string{"secret plan"}, RedactPII: []string{"email"}}}).Select("")
+
+	out, err := policy.FilterResponse([]byte(`{"choices":[{"message":{"content":"reach me at x@y.com"}}]}`))
+	if err != nil || gjson.GetBytes(out, "choices.0.message.content").String() != "reach me at [REDACTED_EMAIL]" {
+		t.Fatalf("non-stream = %s, %v", out, err)
+	}
+
+	chunk := "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"x@y.com\"}}\n\n"
+	out, err = policy.FilterResponse([]byte(chunk))
+	want := "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"[REDACTED_EMAIL]\"}}\n\n"
+	if err != nil || string(out) != want {
+		t.Fatalf("sse chunk = %q, %v", out, err)
+	}
+
+	_, err = policy.FilterResponse([]byte(`data: {"type":"response.output_text.delta","delta":"the Secret Plan is"}`))
+	var violation *Violation
+	if !errors.As(err, &violation) || violation.Stage != StageResponse {
+		t.Fatalf("blocked chunk error = %v", err)
+	}
+}
+
+func TestStreamFilterCatchesBlockedTextAcrossChunks(t *testing.T) {
+	policy := Compile([]config.GuardrailPolicy{{Blocklist: []string{"secret plan"}}}).Select("")
+	filter := policy.NewStreamFilter()
+	if _, err := filter.Filter([]byte(`data: {"choices":[{"delta":{"content":"the secr"}}]}`)); err != nil {
+		t.Fatalf("first chunk: %v", err)
+	}
+	_, err := filter.Filter([]byte(`data: {"choices":[{"delta":{"content":"et plan is"}}]}`))
+	var violation *Violation
+	if !errors.As(err, &violation) || violation.Stage != StageResponse {
+		t.Fatalf("split keyword error = %v", err)
+	}
+	if _, err = policy.NewStreamFilter().Filter([]byte(`data: {"choices":[{"delta":{"content":"et plan is"}}]}`)); err != nil {
+		t.Fatalf("a new stream must not inherit the window: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package guardrail
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// promptKeys lists the JSON keys whose string values carry prompt text across the OpenAI,
+// Responses, Claude and Gemini request formats.
+var promptKeys = map[string]struct{}{
+	"content": {}, "text": {}, "input": {}, "prompt": {}, "system": {}, "instructions": {},
+}
+
+// outputKeys lists the JSON keys whose string values carry generated text in responses and
+// stream events of the same formats.
+var outputKeys = map[string]struct{}{
+	"content": {}, "text": {}, "delta": {}, "refusal": {},
+}
+
+type redactor struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// redactors masks PII by kind. Phone numbers must be grouped by spaces, dots or dashes so that
+// timestamps and other long numbers are not mistaken for them.
+var redactors = map[string]redactor{
+	"email": {
+		re:          regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+		replacement: "[REDACTED_EMAIL]",
+	},
+	"phone": {
+		re:          regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)[ .-]?|\b\d{2,4}[ .-])\d{3,4}[ .-]\d{4}\b`),
+		replacement: "[REDACTED_PHONE]",
+	},
+	"key": {
+		re:          regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{16,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_-]{35}|gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}|xox[abpr]-[A-Za-z0-9-]{10,}|eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`),
+		replacement: "[REDACTED_KEY]",
+	},
+}
+
+func redactorFor(kind string) (redactor, bool) {
+	r, ok := redactors[strings.ToLower(strings.TrimSpace(kind))]
+	return r, ok
+}
+
+// rewriteText calls fn for every string value stored under one of keys, directly or inside an
+// array, and writes changed values back. Values inside nested objects are matched by their own
+// keys, so tool arguments and schemas are left alone. An error from fn aborts the rewrite.
+func rewriteText(body []byte, keys map[string]struct{}, fn func(string) (string, error)) ([]byte, error) {
+	type edit struct {
+		path  string
+		value string
+	}
+	var edits []edit
+	var firstErr error
+	var walk func(value gjson.Result, path string, textual bool)
+	walk = func(value gjson.Result, path string, textual bool) {
+		switch {
+		case value.Type == gjson.String:
+			if !textual || value.Str == "" {
+				return
+			}
+			updated, err := fn(value.Str)
+			if err != nil {
+				firstErr = err
+				return
+			}
+			if updated != value.Str {
+				edits = append(edits, edit{path: path, value: updated})
+			}
+		case value.IsArray():
+			index := 0
+			value.ForEach(func(_, item gjson.Result) bool {
+				walk(item, joinPath(path, strconv.Itoa(index)), textual)
+				index++
+				return firstErr == nil
+			})
+		case value.IsObject():
+			value.ForEach(func(key, item gjson.Result) bool {
+				_, isText := keys[key.Str]
+				walk(item, joinPath(path, escapePathKey(key.Str)), isText)
+				return firstErr == nil
+			})
+		}
+	}
+	walk(gjson.ParseBytes(body), "", false)
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	out := body
+	for _, e := range edits {
+		updated, err := sjson.SetBytes(out, e.path, e.value)
+		if err != nil {
+			return nil, err
+		}
+		out = updated
+	}
+	return out, nil
+}
+
+func joinPath(path, segment string) string {
+	if path == "" {
+		return segment
+	}
+	return path + "." + segment
+}
+
+var pathKeyReplacer = strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`, "|", `\|`, "#", `\#`, "@", `\@`)
+
+func escapePathKey(key string) string {
+	return pathKeyReplacer.Replace(key)
+}
@@ -12,28 +12,25 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/netaccess"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	log "github.com/sirupsen/logrus"
 )
 
-// aiAPIPrefixes defines path prefixes for AI API requests that should have request ID tracking.
-var aiAPIPrefixes = []string{
-	"/v1/chat/completions",
-	"/v1/completions",
-	"/v1/messages",
-	"/v1/responses",
-	"/v1beta/models/",
-	"/api/provider/",
-}
-
 const skipGinLogKey = "__gin_skip_request_logging__"
 
+// RequestIDHeader carries the request ID on responses and upstream requests. A well-formed
+// value supplied by the client is reused so callers can correlate with their own logs.
+const RequestIDHeader = "X-Request-Id"
+
+const maxClientRequestIDLength = 64
+
 // GinLogrusLogger returns a Gin middleware handler that logs HTTP requests and responses
 // using logrus. It captures request details including method, path, status code, latency,
-// client IP, and any error messages. Request ID is only added for AI API requests.
+// client IP, and any error messages. Every request is assigned a request ID, which is returned
+// in the X-Request-Id response header.
 //
-// Output format (AI API): [2025-12-23 20:14:10] [info ] | a1b2c3d4 | 200 |       23.559s | ...
-// Output format (others): [2025-12-23 20:14:10] [info ] | -------- | 200 |       23.559s | ...
+// Output format: [2025-12-23 20:14:10] [info ] | a1b2c3d4 | 200 |       23.559s | ...
 //
 // Returns:
 //   - gin.HandlerFunc: A middleware handler for request logging
@@ -43,14 +40,14 @@ func GinLogrusLogger() gin.HandlerFunc {
 		path := c.Request.URL.Path
 		raw := util.MaskSensitiveQuery(c.Request.URL.RawQuery)
 
-		// Only generate request ID for AI API paths
-		var requestID string
-		if isAIAPIPath(path) {
+		requestID := clientRequestID(c.GetHeader(RequestIDHeader))
+		if requestID == "" {
 			requestID = GenerateRequestID()
-			SetGinRequestID(c, requestID)
-			ctx := WithRequestID(c.Request.Context(), requestID)
-			c.Request = c.Request.WithContext(ctx)
 		}
+		SetGinRequestID(c, requestID)
+		ctx := WithRequestFields(WithRequestID(c.Request.Context(), requestID))
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(RequestIDHeader, requestID)
 
 		c.Next()
 
@@ -70,19 +67,16 @@ func GinLogrusLogger() gin.HandlerFunc {
 		}
 
 		statusCode := c.Writer.Status()
-		clientIP := c.ClientIP()
+		clientIP := netaccess.ClientIP(c)
 		method := c.Request.Method
 		errorMessage := c.Errors.ByType(gin.ErrorTypePrivate).String()
 
-		if requestID == "" {
-			requestID = "--------"
-		}
 		logLine := fmt.Sprintf("%3d | %13v | %15s | %-7s \"%s\"", statusCode, latency, clientIP, method, path)
 		if errorMessage != "" {
 			logLine = logLine + " | " + errorMessage
 		}
 
-		entry := log.WithField("request_id", requestID)
+		entry := RequestEntry(ctx)
 
 		switch {
 		case statusCode >= http.StatusInternalServerError:
@@ -95,14 +89,21 @@ func GinLogrusLogger() gin.HandlerFunc {
 	}
 }
 
-// isAIAPIPath checks if the given path is an AI API endpoint that should have request ID tracking.
-func isAIAPIPath(path string) bool {
-	for _, prefix := range aiAPIPrefixes {
-		if strings.HasPrefix(path, prefix) {
-			return true
+// clientRequestID returns value when it is a usable request ID: at most 64 letters, digits,
+// dots, dashes and underscores. Anything else is ignored so it cannot inject into logs.
+func clientRequestID(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || len(value) > maxClientRequestIDLength {
+		return ""
+	}
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return ""
 		}
 	}
-	return false
+	return value
 }
 
 // GinLogrusRecovery returns a Gin middleware handler that recovers from panics and logs
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
@@ -87,7 +88,7 @@ func SetupBaseLogger() {
 		log.SetOutput(os.Stdout)
 		log.SetLevel(log.InfoLevel)
 		log.SetReportCaller(true)
-		log.SetFormatter(&LogFormatter{})
+		log.SetFormatter(newControlFormatter())
 
 		ginInfoWriter = log.StandardLogger().Writer()
 		gin.DefaultWriter = ginInfoWriter
@@ -140,9 +141,13 @@ func ResolveLogDirectory(cfg *config.Config) string {
 	return logDir
 }
 
+// defaultLogMaxSizeMB is the size at which main.log rotates when log-rotation.max-size-mb is unset.
+const defaultLogMaxSizeMB = 10
+
 // ConfigureLogOutput switches the global log destination between rotating files and stdout.
-// When logsMaxTotalSizeMB > 0, a background cleaner removes the oldest log files in the logs directory
-// until the total size is within the limit.
+// main.log is rotated as configured by log-rotation. When logsMaxTotalSizeMB > 0 or
+// log-rotation.max-age-days > 0, a background cleaner removes the oldest log files in the logs
+// directory until they are within the limits.
 func ConfigureLogOutput(cfg *config.Config) error {
 	SetupBaseLogger()
 
@@ -160,12 +165,17 @@ func ConfigureLogOutput(cfg *config.Config) error {
 			_ = logWriter.Close()
 		}
 		protectedPath = filepath.Join(logDir, "main.log")
+		rotation := cfg.LogRotation
+		maxSize := rotation.MaxSizeMB
+		if maxSize <= 0 {
+			maxSize = defaultLogMaxSizeMB
+		}
 		logWriter = &lumberjack.Logger{
 			Filename:   protectedPath,
-			MaxSize:    10,
-			MaxBackups: 0,
-			MaxAge:     0,
-			Compress:   false,
+			MaxSize:    maxSize,
+			MaxBackups: rotation.MaxBackups,
+			MaxAge:     rotation.MaxAgeDays,
+			Compress:   rotation.Compress,
 		}
 		log.SetOutput(logWriter)
 	} else {
@@ -176,7 +186,8 @@ func ConfigureLogOutput(cfg *config.Config) error {
 		log.SetOutput(os.Stdout)
 	}
 
-	configureLogDirCleanerLocked(logDir, cfg.LogsMaxTotalSizeMB, protectedPath)
+	maxAge := time.Duration(cfg.LogRotation.MaxAgeDays) * 24 * time.Hour
+	configureLogDirCleanerLocked(logDir, cfg.LogsMaxTotalSizeMB, maxAge, protectedPath)
 	return nil
 }
 
@@ -0,0 +1,239 @@
+package logging
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// LogFormatText is the default human-readable log format.
+	LogFormatText = "text"
+	// LogFormatJSON writes one JSON object per log entry.
+	LogFormatJSON = "json"
+)
+
+// LogSettings is the logging configuration in effect.
+type LogSettings struct {
+	// Level is the configured minimum level.
+	Level string `json:"level"`
+	// DebugModules are the modules logging at debug level regardless of Level.
+	DebugModules []string `json:"debug-modules"`
+	// Format is the log format, "text" or "json".
+	Format string `json:"format"`
+	// Override is the level forced at runtime by a signal, or "" when none is.
+	Override string `json:"override,omitempty"`
+}
+
+// logControl is the active logging configuration.
+type logControl struct {
+	level    log.Level
+	modules  map[string]bool
+	format   string
+	override *log.Level
+}
+
+var (
+	controlMu sync.RWMutex
+	control   = logControl{level: log.InfoLevel, format: LogFormatText}
+)
+
+// ParseLogLevel parses a configured log level; empty means info.
+func ParseLogLevel(level string) (log.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "":
+		return log.InfoLevel, nil
+	case "warning":
+		return log.WarnLevel, nil
+	}
+	parsed, err := log.ParseLevel(strings.TrimSpace(level))
+	if err != nil {
+		return log.InfoLevel, fmt.Errorf("invalid log level %q", level)
+	}
+	return parsed, nil
+}
+
+// NormalizeLogFormat returns the log format for format; empty means text.
+func NormalizeLogFormat(format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", LogFormatText:
+		return LogFormatText, nil
+	case LogFormatJSON:
+		return LogFormatJSON, nil
+	}
+	return "", fmt.Errorf("invalid log format %q", format)
+}
+
+// NormalizeDebugModules lower-cases, trims and deduplicates module names.
+func NormalizeDebugModules(modules []string) []string {
+	seen := make(map[string]bool, len(modules))
+	var out []string
+	for _, module := range modules {
+		module = strings.ToLower(strings.TrimSpace(module))
+		if module == "" || seen[module] {
+			continue
+		}
+		seen[module] = true
+		out = append(out, module)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ConfigureLogLevel applies the log level, debug modules and log format of cfg and clears any
+// level forced by a signal. Invalid values are logged and replaced by their defaults.
+func ConfigureLogLevel(cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+	SetupBaseLogger()
+	level, err := ParseLogLevel(cfg.LogLevel)
+	if err != nil {
+		log.Errorf("log-level: %v, using info", err)
+	}
+	if cfg.Debug {
+		level = log.DebugLevel
+	}
+	format, err := NormalizeLogFormat(cfg.LogFormat)
+	if err != nil {
+		log.Errorf("log-format: %v, using text", err)
+	}
+	modules := make(map[string]bool)
+	for _, module := range NormalizeDebugModules(cfg.DebugModules) {
+		modules[module] = true
+	}
+
+	controlMu.Lock()
+	previous := control.level
+	control = logControl{level: level, modules: modules, format: format}
+	applyLoggerLevelLocked()
+	controlMu.Unlock()
+	if previous != level {
+		log.Infof("log level changed from %s to %s (debug=%t)", previous, level, cfg.Debug)
+	}
+}
+
+// SetLogLevelOverride forces level until ClearLogLevelOverride or the next ConfigureLogLevel.
+func SetLogLevelOverride(level log.Level) {
+	controlMu.Lock()
+	control.override = &level
+	applyLoggerLevelLocked()
+	controlMu.Unlock()
+	log.Infof("log level forced to %s", level)
+}
+
+// ClearLogLevelOverride restores the configured log level.
+func ClearLogLevelOverride() {
+	controlMu.Lock()
+	control.override = nil
+	applyLoggerLevelLocked()
+	level := control.level
+	controlMu.Unlock()
+	log.Infof("log level restored to %s", level)
+}
+
+// CurrentLogSettings returns the logging configuration in effect.
+func CurrentLogSettings() LogSettings {
+	controlMu.RLock()
+	defer controlMu.RUnlock()
+	settings := LogSettings{Level: control.level.String(), Format: control.format, DebugModules: []string{}}
+	for module := range control.modules {
+		settings.DebugModules = append(settings.DebugModules, module)
+	}
+	sort.Strings(settings.DebugModules)
+	if control.override != nil {
+		settings.Override = control.override.String()
+	}
+	return settings
+}
+
+func jsonFormatActive() bool {
+	controlMu.RLock()
+	defer controlMu.RUnlock()
+	return control.format == LogFormatJSON
+}
+
+// applyLoggerLevelLocked sets the logger to the most verbose level any entry may pass at; the
+// formatter drops debug entries of modules that are not enabled.
+func applyLoggerLevelLocked() {
+	level := control.effectiveLevel()
+	if len(control.modules) > 0 && level < log.DebugLevel {
+		level = log.DebugLevel
+	}
+	log.SetLevel(level)
+}
+
+func (c *logControl) effectiveLevel() log.Level {
+	if c.override != nil {
+		return *c.override
+	}
+	return c.level
+}
+
+// admits reports whether an entry written at level from the file of caller is logged.
+func (c *logControl) admits(level log.Level, caller *runtime.Frame) bool {
+	if level <= c.effectiveLevel() {
+		return true
+	}
+	if level > log.DebugLevel || len(c.modules) == 0 || caller == nil {
+		return false
+	}
+	for _, module := range callerModules(caller.File) {
+		if c.modules[module] {
+			return true
+		}
+	}
+	return false
+}
+
+// callerModules returns the package directories of a source file below internal/ or sdk/, e.g.
+// "runtime" and "executor" for internal/runtime/executor/claude_executor.go.
+func callerModules(file string) []string {
+	file = filepath.ToSlash(file)
+	root := max(strings.LastIndex(file, "/internal/"), strings.LastIndex(file, "/sdk/"))
+	if root < 0 {
+		return nil
+	}
+	dirs := strings.Split(filepath.ToSlash(filepath.Dir(file[root+1:])), "/")
+	return dirs[1:]
+}
+
+// controlFormatter drops entries the debug-module filter rejects and renders the rest in the
+// configured format.
+type controlFormatter struct {
+	text *LogFormatter
+	json *log.JSONFormatter
+}
+
+func newControlFormatter() *controlFormatter {
+	return &controlFormatter{
+		text: &LogFormatter{},
+		json: &log.JSONFormatter{
+			TimestampFormat: "2006-01-02T15:04:05.000Z07:00",
+			CallerPrettyfier: func(frame *runtime.Frame) (string, string) {
+				return "", fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+			},
+		},
+	}
+}
+
+// Format renders entry, or nothing when its module is filtered out.
+func (f *controlFormatter) Format(entry *log.Entry) ([]byte, error) {
+	controlMu.RLock()
+	admitted := control.admits(entry.Level, entry.Caller)
+	format := control.format
+	controlMu.RUnlock()
+	if !admitted {
+		return nil, nil
+	}
+	if format == LogFormatJSON {
+		return f.json.Format(entry)
+	}
+	return f.text.Format(entry)
+}
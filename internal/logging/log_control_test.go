@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestCallerModules(t *testing.T) {
+	cases := map[string]string{
+		"/src/app/internal/runtime/executor/claude_executor.go": "runtime,executor",
+		"/src/app/internal/translator/claude/gemini/request.go": "translator,claude,gemini",
+		"/src/app/sdk/cliproxy/auth/conductor.go":               "cliproxy,auth",
+		"/src/app/cmd/server/main.go":                           "",
+		"/go/pkg/mod/github.com/gin-gonic/gin/context.go":       "",
+	}
+	for file, want := range cases {
+		if got := strings.Join(callerModules(file), ","); got != want {
+			t.Errorf("callerModules(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestLogControlAdmitsDebugModules(t *testing.T) {
+	c := logControl{level: log.InfoLevel, modules: map[string]bool{"translator": true}}
+	translator := &runtime.Frame{File: "/src/internal/translator/claude/request.go"}
+	auth := &runtime.Frame{File: "/src/sdk/cliproxy/auth/conductor.go"}
+
+	if !c.admits(log.DebugLevel, translator) {
+		t.Error("debug entry of an enabled module was dropped")
+	}
+	if c.admits(log.DebugLevel, auth) {
+		t.Error("debug entry of another module was logged")
+	}
+	if !c.admits(log.InfoLevel, auth) {
+		t.Error("info entry was dropped")
+	}
+	if c.admits(log.TraceLevel, translator) {
+		t.Error("trace entry was logged")
+	}
+
+	debug := log.DebugLevel
+	c.override = &debug
+	if !c.admits(log.DebugLevel, auth) {
+		t.Error("forced debug level did not apply to every module")
+	}
+}
+
+func TestConfigureLogLevelFormatAndOverride(t *testing.T) {
+	logger := log.StandardLogger()
+	savedOut, savedLevel := logger.Out, logger.GetLevel()
+	defer func() {
+		logger.SetOutput(savedOut)
+		ConfigureLogLevel(&config.Config{})
+		logger.SetLevel(savedLevel)
+	}()
+
+	var buf bytes.Buffer
+	ConfigureLogLevel(&config.Config{LogLevel: "warn", LogFormat: "json", DebugModules: []string{" Translator "}})
+	logger.SetOutput(&buf)
+
+	settings := CurrentLogSettings()
+	if settings.Level != "warning" || settings.Format != LogFormatJSON || strings.Join(settings.DebugModules, ",") != "translator" {
+		t.Fatalf("settings = %+v", settings)
+	}
+	// This test file lives in internal/logging, outside the enabled module.
+	log.Debug("dropped debug")
+	log.Info("dropped info")
+	log.Warn("kept warning")
+
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line is not JSON: %q", line)
+		}
+		messages = append(messages, entry["msg"].(string))
+	}
+	if strings.Join(messages, "|") != "kept warning" {
+		t.Fatalf("logged %v", messages)
+	}
+
+	SetLogLevelOverride(log.DebugLevel)
+	if !logger.IsLevelEnabled(log.DebugLevel) || CurrentLogSettings().Override != "debug" {
+		t.Fatal("override did not raise the log level")
+	}
+	ClearLogLevelOverride()
+	if CurrentLogSettings().Override != "" {
+		t.Fatal("override was not cleared")
+	}
+}
@@ -15,15 +15,11 @@ const logDirCleanerInterval = time.Minute
 
 var logDirCleanerCancel context.CancelFunc
 
-func configureLogDirCleanerLocked(logDir string, maxTotalSizeMB int, protectedPath string) {
+func configureLogDirCleanerLocked(logDir string, maxTotalSizeMB int, maxAge time.Duration, protectedPath string) {
 	stopLogDirCleanerLocked()
 
-	if maxTotalSizeMB <= 0 {
-		return
-	}
-
-	maxBytes := int64(maxTotalSizeMB) * 1024 * 1024
-	if maxBytes <= 0 {
+	maxBytes := int64(max(maxTotalSizeMB, 0)) * 1024 * 1024
+	if maxBytes <= 0 && maxAge <= 0 {
 		return
 	}
 
@@ -34,7 +30,7 @@ func configureLogDirCleanerLocked(logDir string, maxTotalSizeMB int, protectedPa
 
 	ctx, cancel := context.WithCancel(context.Background())
 	logDirCleanerCancel = cancel
-	go runLogDirCleaner(ctx, filepath.Clean(dir), maxBytes, strings.TrimSpace(protectedPath))
+	go runLogDirCleaner(ctx, filepath.Clean(dir), maxBytes, maxAge, strings.TrimSpace(protectedPath))
 }
 
 func stopLogDirCleanerLocked() {
@@ -45,19 +41,28 @@ func stopLogDirCleanerLocked() {
 	logDirCleanerCancel = nil
 }
 
-func runLogDirCleaner(ctx context.Context, logDir string, maxBytes int64, protectedPath string) {
+func runLogDirCleaner(ctx context.Context, logDir string, maxBytes int64, maxAge time.Duration, protectedPath string) {
 	ticker := time.NewTicker(logDirCleanerInterval)
 	defer ticker.Stop()
 
 	cleanOnce := func() {
+		expired, errExpire := enforceLogDirMaxAge(logDir, maxAge, protectedPath, time.Now())
+		if errExpire != nil {
+			log.WithError(errExpire).Warn("logging: failed to enforce log file age limit")
+		} else if expired > 0 {
+			log.Debugf("logging: removed %d log file(s) older than %s", expired, maxAge)
+		}
 		deleted, errClean := enforceLogDirSizeLimit(logDir, maxBytes, protectedPath)
 		if errClean != nil {
 			log.WithError(errClean).Warn("logging: failed to enforce log directory size limit")
-			return
-		}
-		if deleted > 0 {
+		} else if deleted > 0 {
 			log.Debugf("logging: removed %d old log file(s) to enforce log directory size limit", deleted)
 		}
+		if expired+deleted > 0 {
+			if errCompact := compactRequestLogIndex(logDir); errCompact != nil {
+				log.WithError(errCompact).Warn("logging: failed to compact request log index")
+			}
+		}
 	}
 
 	cleanOnce()
@@ -156,11 +161,56 @@ func enforceLogDirSizeLimit(logDir string, maxBytes int64, protectedPath string)
 	return deleted, nil
 }
 
+// enforceLogDirMaxAge removes the log files in logDir last modified more than maxAge before now,
+// except protectedPath. It returns the number of files removed.
+func enforceLogDirMaxAge(logDir string, maxAge time.Duration, protectedPath string, now time.Time) (int, error) {
+	dir := strings.TrimSpace(logDir)
+	if maxAge <= 0 || dir == "" {
+		return 0, nil
+	}
+	dir = filepath.Clean(dir)
+
+	entries, errRead := os.ReadDir(dir)
+	if errRead != nil {
+		if os.IsNotExist(errRead) {
+			return 0, nil
+		}
+		return 0, errRead
+	}
+
+	protected := strings.TrimSpace(protectedPath)
+	if protected != "" {
+		protected = filepath.Clean(protected)
+	}
+
+	cutoff := now.Add(-maxAge)
+	deleted := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !isLogFileName(entry.Name()) {
+			continue
+		}
+		info, errInfo := entry.Info()
+		if errInfo != nil || !info.Mode().IsRegular() || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if protected != "" && path == protected {
+			continue
+		}
+		if errRemove := os.Remove(path); errRemove != nil {
+			log.WithError(errRemove).Warnf("logging: failed to remove expired log file: %s", entry.Name())
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
 func isLogFileName(name string) bool {
 	trimmed := strings.TrimSpace(name)
 	if trimmed == "" {
 		return false
 	}
 	lower := strings.ToLower(trimmed)
-	return strings.HasSuffix(lower, ".log") || strings.HasSuffix(lower, ".log.gz")
+	return strings.HasSuffix(lower, ".log") || strings.HasSuffix(lower, ".log.gz") || strings.HasSuffix(lower, ".log.zst")
 }
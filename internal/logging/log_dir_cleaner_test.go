@@ -68,3 +68,33 @@ func writeLogFile(t *testing.T, path string, size int, modTime time.Time) {
 		t.Fatalf("set times: %v", err)
 	}
 }
+
+func TestEnforceLogDirMaxAgeRemovesExpired(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Unix(10*24*3600, 0)
+
+	protected := filepath.Join(dir, "main.log")
+	writeLogFile(t, protected, 10, now.Add(-9*24*time.Hour))
+	writeLogFile(t, filepath.Join(dir, "v1-chat-completions-old.log.zst"), 10, now.Add(-8*24*time.Hour))
+	writeLogFile(t, filepath.Join(dir, "main-2025-01-01T00-00-00.000.log.gz"), 10, now.Add(-8*24*time.Hour))
+	writeLogFile(t, filepath.Join(dir, "v1-chat-completions-new.log"), 10, now.Add(-time.Hour))
+	writeLogFile(t, filepath.Join(dir, "notes.txt"), 10, now.Add(-9*24*time.Hour))
+
+	deleted, err := enforceLogDirMaxAge(dir, 7*24*time.Hour, protected, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleted != 2 {
+		t.Fatalf("expected 2 deleted files, got %d", deleted)
+	}
+	for _, name := range []string{"v1-chat-completions-old.log.zst", "main-2025-01-01T00-00-00.000.log.gz"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed, stat error: %v", name, err)
+		}
+	}
+	for _, name := range []string{"main.log", "v1-chat-completions-new.log", "notes.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("expected %s to remain, stat error: %v", name, err)
+		}
+	}
+}
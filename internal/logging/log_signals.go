@@ -0,0 +1,27 @@
+//go:build !windows
+
+package logging
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// WatchLogSignals switches the log level on signals for the lifetime of the process: SIGUSR1
+// forces debug logging for every module and SIGUSR2 restores the configured level.
+func WatchLogSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGUSR1 {
+				SetLogLevelOverride(log.DebugLevel)
+			} else {
+				ClearLogLevelOverride()
+			}
+		}
+	}()
+}
@@ -0,0 +1,5 @@
+package logging
+
+// WatchLogSignals does nothing on Windows, which has no SIGUSR1 or SIGUSR2; use the management
+// API to change the log level instead.
+func WatchLogSignals() {}
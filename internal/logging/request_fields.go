@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// requestFieldsKey is the context key for storing/retrieving request correlation fields.
+type requestFieldsKey struct{}
+
+// requestFields holds the correlation fields of one request. They are filled in as the request
+// is authenticated and routed, and attached to every entry logged through RequestEntry.
+type requestFields struct {
+	mu        sync.Mutex
+	apiKey    string
+	provider  string
+	model     string
+	authIndex string
+}
+
+// WithRequestFields returns a new context carrying empty correlation fields for SetRequestAPIKey
+// and SetRequestRoute to fill in.
+func WithRequestFields(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestFieldsKey{}, &requestFields{})
+}
+
+// InheritRequestFields returns ctx sharing the correlation fields of src, unless ctx already has
+// its own. Fields set through either context then show up in the logs of both.
+func InheritRequestFields(ctx, src context.Context) context.Context {
+	if requestFieldsFrom(ctx) != nil {
+		return ctx
+	}
+	if fields := requestFieldsFrom(src); fields != nil {
+		return context.WithValue(ctx, requestFieldsKey{}, fields)
+	}
+	return ctx
+}
+
+func requestFieldsFrom(ctx context.Context) *requestFields {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(requestFieldsKey{}).(*requestFields)
+	return fields
+}
+
+// SetRequestAPIKey records the label of the client API key that authenticated the request. The
+// label must not be the key itself.
+func SetRequestAPIKey(ctx context.Context, label string) {
+	if fields := requestFieldsFrom(ctx); fields != nil {
+		fields.mu.Lock()
+		fields.apiKey = label
+		fields.mu.Unlock()
+	}
+}
+
+// SetRequestRoute records the provider, model and upstream account serving the request. Retries
+// on another account overwrite the previous route.
+func SetRequestRoute(ctx context.Context, provider, model, authIndex string) {
+	if fields := requestFieldsFrom(ctx); fields != nil {
+		fields.mu.Lock()
+		fields.provider, fields.model, fields.authIndex = provider, model, authIndex
+		fields.mu.Unlock()
+	}
+}
+
+// RequestEntry returns a logrus entry carrying the request ID and, in the JSON log format, the
+// correlation fields known for the request in ctx. Without request data it returns a plain entry.
+func RequestEntry(ctx context.Context) *log.Entry {
+	entry := log.NewEntry(log.StandardLogger())
+	if ctx == nil {
+		return entry
+	}
+	data := log.Fields{}
+	if requestID := GetRequestID(ctx); requestID != "" {
+		data["request_id"] = requestID
+	}
+	// The text format keeps its compact layout; only JSON lines carry the correlation fields.
+	if fields := requestFieldsFrom(ctx); fields != nil && jsonFormatActive() {
+		fields.mu.Lock()
+		for key, value := range map[string]string{
+			"api_key":    fields.apiKey,
+			"provider":   fields.provider,
+			"model":      fields.model,
+			"auth_index": fields.authIndex,
+		} {
+			if value != "" {
+				data[key] = value
+			}
+		}
+		fields.mu.Unlock()
+	}
+	if len(data) == 0 {
+		return entry
+	}
+	return entry.WithFields(data)
+}
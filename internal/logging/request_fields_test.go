@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestRequestEntryCarriesCorrelationFields(t *testing.T) {
+	defer ConfigureLogLevel(&config.Config{})
+	ctx := WithRequestFields(WithRequestID(context.Background(), "abcd1234"))
+	SetRequestAPIKey(ctx, "sk-1...cdef")
+	// A context derived elsewhere shares the fields of the request.
+	execCtx := InheritRequestFields(context.Background(), ctx)
+	SetRequestRoute(execCtx, "claude", "claude-sonnet-4-5", "3f2a")
+
+	ConfigureLogLevel(&config.Config{})
+	if data := RequestEntry(ctx).Data; len(data) != 1 || data["request_id"] != "abcd1234" {
+		t.Fatalf("text format entry data = %v, want only the request id", data)
+	}
+
+	ConfigureLogLevel(&config.Config{LogFormat: LogFormatJSON})
+	data := RequestEntry(ctx).Data
+	want := map[string]string{
+		"request_id": "abcd1234",
+		"api_key":    "sk-1...cdef",
+		"provider":   "claude",
+		"model":      "claude-sonnet-4-5",
+		"auth_index": "3f2a",
+	}
+	for key, value := range want {
+		if data[key] != value {
+			t.Errorf("%s = %v, want %q", key, data[key], value)
+		}
+	}
+	if len(RequestEntry(context.Background()).Data) != 0 {
+		t.Error("entry without request data carries fields")
+	}
+}
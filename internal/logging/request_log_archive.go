@@ -0,0 +1,494 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// compressedLogSuffix is appended to request logs stored with zstd compression.
+	compressedLogSuffix = ".zst"
+	// RequestLogIndexDir is the directory inside the logs directory holding the request log
+	// index, one JSONL shard per UTC day so searches only read the days they cover.
+	RequestLogIndexDir = "request-logs-index"
+	// legacyRequestLogIndexFile is the single-file index written by earlier versions. It is
+	// still searched and compacted, but no longer appended to.
+	legacyRequestLogIndexFile = "request-logs-index.jsonl"
+
+	requestLogIndexDayLayout = "2006-01-02"
+	requestLogIndexSuffix    = ".jsonl"
+	// archiveQueueSize bounds the logs waiting for compression before archiving falls back to
+	// running on the caller's goroutine.
+	archiveQueueSize = 256
+)
+
+// requestLogIndexMu serializes appends to the index across loggers sharing a directory.
+var requestLogIndexMu sync.Mutex
+
+// RequestLogIndexEntry is one line of the request log index. It carries the metadata needed to
+// search stored request logs without decompressing them.
+type RequestLogIndexEntry struct {
+	RequestID  string    `json:"request-id"`
+	File       string    `json:"file"`
+	Timestamp  time.Time `json:"timestamp"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	Model      string    `json:"model,omitempty"`
+	Key        string    `json:"key,omitempty"`
+	Status     int       `json:"status"`
+	ErrorClass string    `json:"error-class,omitempty"`
+	Size       int64     `json:"size"`
+	StoredSize int64     `json:"stored-size"`
+}
+
+// RequestLogFilter selects index entries. Zero values match everything.
+type RequestLogFilter struct {
+	RequestID  string
+	Key        string
+	Model      string
+	ErrorClass string
+	Status     int
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+}
+
+// ErrorClass groups an HTTP status into a coarse class used to search for failures.
+func ErrorClass(status int) string {
+	switch {
+	case status == 0 || status < http.StatusBadRequest:
+		return ""
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "auth"
+	case status == http.StatusTooManyRequests:
+		return "rate_limit"
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return "timeout"
+	case status < http.StatusInternalServerError:
+		return "client"
+	default:
+		return "upstream"
+	}
+}
+
+// IsRequestLogFile reports whether name is a plain or compressed request log file.
+func IsRequestLogFile(name string) bool {
+	return strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".log"+compressedLogSuffix)
+}
+
+// OpenRequestLog opens a stored request log, decompressing zstd files transparently.
+func OpenRequestLog(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, compressedLogSuffix) {
+		return file, nil
+	}
+	decoder, err := zstd.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &zstdReadCloser{decoder: decoder, file: file}, nil
+}
+
+type zstdReadCloser struct {
+	decoder *zstd.Decoder
+	file    *os.File
+}
+
+func (r *zstdReadCloser) Read(p []byte) (int, error) { return r.decoder.Read(p) }
+
+func (r *zstdReadCloser) Close() error {
+	r.decoder.Close()
+	return r.file.Close()
+}
+
+// requestLogMeta is the metadata known when a request log is written.
+type requestLogMeta struct {
+	requestID string
+	timestamp time.Time
+	method    string
+	url       string
+	headers   map[string][]string
+	model     string
+	status    int
+}
+
+// requestModel extracts the requested model from a request body for the index.
+func requestModel(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	return gjson.GetBytes(body, "model").String()
+}
+
+// archiveJob is a finished request log waiting to be compressed and indexed.
+type archiveJob struct {
+	logsDir string
+	path    string
+	meta    requestLogMeta
+}
+
+var (
+	archiveQueue   = make(chan archiveJob, archiveQueueSize)
+	archiveOnce    sync.Once
+	archivePending sync.WaitGroup
+	// archiveEncoder is reused for every log. It is only touched by archiveMu holders.
+	archiveEncoder *zstd.Encoder
+	archiveMu      sync.Mutex
+)
+
+// enqueueRequestLogArchive schedules the plain log at path for compression and indexing on the
+// background archiver so the request path only pays for writing the plain file. When the queue
+// is full the log is archived on the caller's goroutine instead.
+func enqueueRequestLogArchive(logsDir, path string, meta requestLogMeta) {
+	archiveOnce.Do(func() { go runRequestLogArchiver() })
+	archivePending.Add(1)
+	job := archiveJob{logsDir: logsDir, path: path, meta: meta}
+	select {
+	case archiveQueue <- job:
+	default:
+		runArchiveJob(job)
+	}
+}
+
+func runRequestLogArchiver() {
+	for job := range archiveQueue {
+		runArchiveJob(job)
+	}
+}
+
+func runArchiveJob(job archiveJob) {
+	defer archivePending.Done()
+	if err := archiveRequestLog(job.logsDir, job.path, job.meta); err != nil {
+		log.WithError(err).Warn("failed to archive request log")
+	}
+}
+
+// waitForRequestLogArchives blocks until every scheduled log has been archived.
+func waitForRequestLogArchives() {
+	archivePending.Wait()
+}
+
+// archiveRequestLog replaces the plain log at path with a zstd-compressed copy and appends an
+// index entry for it. The plain file is kept when compression fails.
+func archiveRequestLog(logsDir, path string, meta requestLogMeta) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	storedPath := path + compressedLogSuffix
+	if err = compressFile(path, storedPath); err != nil {
+		_ = os.Remove(storedPath)
+		return fmt.Errorf("compress request log: %w", err)
+	}
+	if err = os.Remove(path); err != nil {
+		return err
+	}
+	storedInfo, err := os.Stat(storedPath)
+	if err != nil {
+		return err
+	}
+
+	entry := RequestLogIndexEntry{
+		RequestID:  meta.requestID,
+		File:       filepath.Base(storedPath),
+		Timestamp:  meta.timestamp.UTC(),
+		Method:     meta.method,
+		URL:        meta.url,
+		Model:      meta.model,
+		Key:        util.HashAPIKey(apiKeyFromHeaders(meta.headers)),
+		Status:     meta.status,
+		ErrorClass: ErrorClass(meta.status),
+		Size:       info.Size(),
+		StoredSize: storedInfo.Size(),
+	}
+	return appendRequestLogIndex(logsDir, entry)
+}
+
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+	if archiveEncoder == nil {
+		if archiveEncoder, err = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault)); err != nil {
+			_ = out.Close()
+			return err
+		}
+	}
+	archiveEncoder.Reset(out)
+	if _, err = io.Copy(archiveEncoder, in); err != nil {
+		_ = archiveEncoder.Close()
+		_ = out.Close()
+		return err
+	}
+	if err = archiveEncoder.Close(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// requestLogIndexShard returns the path of the index shard holding entries of day t.
+func requestLogIndexShard(logsDir string, t time.Time) string {
+	return filepath.Join(logsDir, RequestLogIndexDir, t.UTC().Format(requestLogIndexDayLayout)+requestLogIndexSuffix)
+}
+
+func appendRequestLogIndex(logsDir string, entry RequestLogIndexEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	requestLogIndexMu.Lock()
+	defer requestLogIndexMu.Unlock()
+	if err = os.MkdirAll(filepath.Join(logsDir, RequestLogIndexDir), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(requestLogIndexShard(logsDir, entry.Timestamp), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// requestLogIndexShards lists the index shards of logsDir, newest day first, skipping days
+// outside [since, until]. The legacy single-file index, when present, is listed last.
+func requestLogIndexShards(logsDir string, since, until time.Time) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(logsDir, RequestLogIndexDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var sinceDay, untilDay string
+	if !since.IsZero() {
+		sinceDay = since.UTC().Format(requestLogIndexDayLayout)
+	}
+	if !until.IsZero() {
+		untilDay = until.UTC().Format(requestLogIndexDayLayout)
+	}
+	days := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, requestLogIndexSuffix) {
+			continue
+		}
+		day := strings.TrimSuffix(name, requestLogIndexSuffix)
+		if _, errParse := time.Parse(requestLogIndexDayLayout, day); errParse != nil {
+			continue
+		}
+		if (sinceDay != "" && day < sinceDay) || (untilDay != "" && day > untilDay) {
+			continue
+		}
+		days = append(days, day)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(days)))
+	shards := make([]string, 0, len(days)+1)
+	for _, day := range days {
+		shards = append(shards, filepath.Join(logsDir, RequestLogIndexDir, day+requestLogIndexSuffix))
+	}
+	legacy := filepath.Join(logsDir, legacyRequestLogIndexFile)
+	if _, errStat := os.Stat(legacy); errStat == nil {
+		shards = append(shards, legacy)
+	}
+	return shards, nil
+}
+
+// SearchRequestLogIndex returns the index entries of logsDir matching filter, newest first.
+// Only the daily shards within the filter's time range are read, and reading stops once Limit
+// entries are found. Entries whose log file has since been removed by retention cleanup are
+// skipped.
+func SearchRequestLogIndex(logsDir string, filter RequestLogFilter) ([]RequestLogIndexEntry, error) {
+	requestLogIndexMu.Lock()
+	shards, err := requestLogIndexShards(logsDir, filter.Since, filter.Until)
+	requestLogIndexMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]RequestLogIndexEntry, 0)
+	for _, shard := range shards {
+		matches, errShard := searchRequestLogIndexShard(shard, filter)
+		if errShard != nil {
+			if os.IsNotExist(errShard) {
+				continue
+			}
+			return nil, errShard
+		}
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].Timestamp.After(matches[j].Timestamp) })
+		for _, entry := range matches {
+			if filter.Limit > 0 && len(out) >= filter.Limit {
+				return out, nil
+			}
+			if _, errStat := os.Stat(filepath.Join(logsDir, filepath.Base(entry.File))); errStat != nil {
+				continue
+			}
+			out = append(out, entry)
+		}
+	}
+	return out, nil
+}
+
+func searchRequestLogIndexShard(path string, filter RequestLogFilter) ([]RequestLogIndexEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var matches []RequestLogIndexEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry RequestLogIndexEntry
+		if errUnmarshal := json.Unmarshal(line, &entry); errUnmarshal != nil {
+			continue
+		}
+		if filter.matches(entry) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, scanner.Err()
+}
+
+func (f RequestLogFilter) matches(entry RequestLogIndexEntry) bool {
+	if f.RequestID != "" && entry.RequestID != f.RequestID {
+		return false
+	}
+	if f.Key != "" && entry.Key != f.Key {
+		return false
+	}
+	if f.Model != "" && !strings.EqualFold(entry.Model, f.Model) {
+		return false
+	}
+	if f.ErrorClass != "" && entry.ErrorClass != f.ErrorClass {
+		return false
+	}
+	if f.Status != 0 && entry.Status != f.Status {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// apiKeyFromHeaders extracts the client API key from the headers clients authenticate with.
+func apiKeyFromHeaders(headers map[string][]string) string {
+	header := http.Header(headers)
+	if auth := strings.TrimSpace(header.Get("Authorization")); auth != "" {
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+			return strings.TrimSpace(auth[7:])
+		}
+		return auth
+	}
+	for _, name := range []string{"X-Api-Key", "X-Goog-Api-Key"} {
+		if value := strings.TrimSpace(header.Get(name)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// compactRequestLogIndex rewrites the index shards of logsDir without the entries whose log
+// file no longer exists, keeping the index in step with retention cleanup. Shards left empty
+// are removed.
+func compactRequestLogIndex(logsDir string) error {
+	requestLogIndexMu.Lock()
+	defer requestLogIndexMu.Unlock()
+	shards, err := requestLogIndexShards(logsDir, time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	for _, shard := range shards {
+		if errCompact := compactRequestLogIndexShard(logsDir, shard); errCompact != nil {
+			return errCompact
+		}
+	}
+	return nil
+}
+
+func compactRequestLogIndexShard(logsDir, indexPath string) error {
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var kept bytes.Buffer
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		file := gjson.GetBytes(line, "file").String()
+		if file == "" {
+			continue
+		}
+		if _, errStat := os.Stat(filepath.Join(logsDir, filepath.Base(file))); errStat != nil {
+			continue
+		}
+		kept.Write(line)
+		kept.WriteByte('\n')
+	}
+	if kept.Len() == len(data) {
+		return nil
+	}
+	if kept.Len() == 0 {
+		return os.Remove(indexPath)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(indexPath), "request-logs-index-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err = tmp.Write(kept.Bytes()); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return os.Rename(tmpName, indexPath)
+}
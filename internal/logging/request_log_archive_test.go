@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+func TestFileRequestLogger_CompressesAndIndexes(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewFileRequestLogger(true, dir, "", 0)
+	logger.SetCompression(true)
+
+	headers := map[string][]string{"Authorization": {"Bearer client-key"}}
+	now := time.Now()
+	if err := logger.LogRequest("/v1/chat/completions", "POST", headers, []byte(`{"model":"gpt-5"}`), 429, nil, []byte("slow down"), nil, nil, nil, "req1", now, now); err != nil {
+		t.Fatalf("LogRequest: %v", err)
+	}
+	if err := logger.LogRequest("/v1/messages", "POST", nil, []byte(`{"model":"claude-x"}`), 200, nil, []byte("ok"), nil, nil, nil, "req2", now.Add(time.Second), now); err != nil {
+		t.Fatalf("LogRequest: %v", err)
+	}
+	waitForRequestLogArchives()
+
+	entries, err := SearchRequestLogIndex(dir, RequestLogFilter{Key: util.HashAPIKey("client-key")})
+	if err != nil {
+		t.Fatalf("SearchRequestLogIndex: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected one entry for the key, got %+v", entries)
+	}
+	entry := entries[0]
+	if entry.RequestID != "req1" || entry.Model != "gpt-5" || entry.ErrorClass != "rate_limit" || !strings.HasSuffix(entry.File, ".log.zst") {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+	if entry.Size <= 0 || entry.StoredSize <= 0 {
+		t.Fatalf("sizes not recorded: %+v", entry)
+	}
+	if _, errStat := os.Stat(filepath.Join(dir, strings.TrimSuffix(entry.File, ".zst"))); !os.IsNotExist(errStat) {
+		t.Fatalf("plain log should be replaced by the compressed copy")
+	}
+
+	reader, err := OpenRequestLog(filepath.Join(dir, entry.File))
+	if err != nil {
+		t.Fatalf("OpenRequestLog: %v", err)
+	}
+	content, err := io.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil || !strings.Contains(string(content), "slow down") {
+		t.Fatalf("decompressed log missing response (err %v): %s", err, content)
+	}
+
+	all, _ := SearchRequestLogIndex(dir, RequestLogFilter{})
+	if len(all) != 2 || all[0].RequestID != "req2" {
+		t.Fatalf("expected newest first, got %+v", all)
+	}
+	if limited, _ := SearchRequestLogIndex(dir, RequestLogFilter{Model: "CLAUDE-X", Limit: 5}); len(limited) != 1 || limited[0].RequestID != "req2" {
+		t.Fatalf("model filter failed: %+v", limited)
+	}
+
+	if err = os.Remove(filepath.Join(dir, all[0].File)); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err = compactRequestLogIndex(dir); err != nil {
+		t.Fatalf("compactRequestLogIndex: %v", err)
+	}
+	data, _ := os.ReadFile(requestLogIndexShard(dir, now))
+	if strings.Count(string(data), "\n") != 1 || strings.Contains(string(data), "req2") {
+		t.Fatalf("index not compacted: %s", data)
+	}
+}
+
+func TestSearchRequestLogIndex_ReadsOnlyShardsInRange(t *testing.T) {
+	dir := t.TempDir()
+	days := []time.Time{
+		time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC),
+		time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC),
+	}
+	for i, day := range days {
+		name := day.Format("20060102") + ".log.zst"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatalf("write log: %v", err)
+		}
+		entry := RequestLogIndexEntry{RequestID: string(rune('a' + i)), File: name, Timestamp: day, Status: 200}
+		if err := appendRequestLogIndex(dir, entry); err != nil {
+			t.Fatalf("appendRequestLogIndex: %v", err)
+		}
+	}
+	shards, err := requestLogIndexShards(dir, days[1].Add(-time.Hour), time.Time{})
+	if err != nil || len(shards) != 2 {
+		t.Fatalf("requestLogIndexShards = %v, %v; want the two newest days", shards, err)
+	}
+
+	entries, err := SearchRequestLogIndex(dir, RequestLogFilter{Since: days[1].Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("SearchRequestLogIndex: %v", err)
+	}
+	if len(entries) != 2 || entries[0].RequestID != "c" || entries[1].RequestID != "b" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if limited, _ := SearchRequestLogIndex(dir, RequestLogFilter{Limit: 1}); len(limited) != 1 || limited[0].RequestID != "c" {
+		t.Fatalf("limit not applied newest first: %+v", limited)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	cases := map[int]string{200: "", 400: "client", 401: "auth", 403: "auth", 408: "timeout", 429: "rate_limit", 500: "upstream", 504: "timeout"}
+	for status, want := range cases {
+		if got := ErrorClass(status); got != want {
+			t.Errorf("ErrorClass(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
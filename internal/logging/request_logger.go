@@ -135,6 +135,9 @@ type FileRequestLogger struct {
 
 	// errorLogsMaxFiles limits the number of error log files retained.
 	errorLogsMaxFiles int
+
+	// compress stores finished logs zstd-compressed and records them in the request log index.
+	compress atomic.Bool
 }
 
 // NewFileRequestLogger creates a new file-based request logger.
@@ -185,6 +188,11 @@ func (l *FileRequestLogger) SetErrorLogsMaxFiles(maxFiles int) {
 	l.errorLogsMaxFiles = maxFiles
 }
 
+// SetCompression toggles zstd-compressed storage and indexing of finished request logs.
+func (l *FileRequestLogger) SetCompression(enabled bool) {
+	l.compress.Store(enabled)
+}
+
 // LogRequest logs a complete non-streaming request/response cycle to a file.
 //
 // Parameters:
@@ -280,6 +288,11 @@ func (l *FileRequestLogger) logRequest(url, method string, requestHeaders map[st
 		return fmt.Errorf("failed to write log file: %w", writeErr)
 	}
 
+	if l.compress.Load() {
+		meta := requestLogMeta{requestID: requestID, timestamp: requestTimestamp, method: method, url: url, headers: requestHeaders, model: requestModel(body), status: statusCode}
+		enqueueRequestLogArchive(l.logsDir, filePath, meta)
+	}
+
 	if force && !l.enabled {
 		if errCleanup := l.cleanupOldErrorLogs(); errCleanup != nil {
 			log.WithError(errCleanup).Warn("failed to clean up old error logs")
@@ -337,6 +350,10 @@ func (l *FileRequestLogger) LogStreamingRequest(url, method string, headers map[
 	// Create streaming writer
 	writer := &FileStreamingLogWriter{
 		logFilePath:      filePath,
+		logsDir:          l.logsDir,
+		requestID:        requestID,
+		compress:         l.compress.Load(),
+		model:            requestModel(body),
 		url:              url,
 		method:           method,
 		timestamp:        time.Now(),
@@ -465,7 +482,7 @@ func (l *FileRequestLogger) cleanupOldErrorLogs() error {
 			continue
 		}
 		name := entry.Name()
-		if !strings.HasPrefix(name, "error-") || !strings.HasSuffix(name, ".log") {
+		if !strings.HasPrefix(name, "error-") || !IsRequestLogFile(name) {
 			continue
 		}
 		info, errInfo := entry.Info()
@@ -489,6 +506,9 @@ func (l *FileRequestLogger) cleanupOldErrorLogs() error {
 			log.WithError(errRemove).Warnf("failed to remove old error log: %s", file.name)
 		}
 	}
+	if l.compress.Load() {
+		return compactRequestLogIndex(l.logsDir)
+	}
 
 	return nil
 }
@@ -966,6 +986,18 @@ type FileStreamingLogWriter struct {
 	// logFilePath is the final log file path.
 	logFilePath string
 
+	// logsDir is the directory holding the request log index.
+	logsDir string
+
+	// requestID identifies the request in the index.
+	requestID string
+
+	// compress archives the finished log with zstd and indexes it.
+	compress bool
+
+	// model is the requested model recorded in the index.
+	model string
+
 	// url is the request URL (masked upstream in middleware).
 	url string
 
@@ -1142,6 +1174,10 @@ func (w *FileStreamingLogWriter) Close() error {
 	}
 
 	w.cleanupTempFiles()
+	if writeErr == nil && w.compress {
+		meta := requestLogMeta{requestID: w.requestID, timestamp: w.timestamp, method: w.method, url: w.url, headers: w.requestHeaders, model: w.model, status: w.responseStatus}
+		enqueueRequestLogArchive(w.logsDir, w.logFilePath, meta)
+	}
 	return writeErr
 }
 
@@ -0,0 +1,216 @@
+// Package netaccess admits or rejects inbound clients by address and resolves the client address
+// of requests relayed by trusted reverse proxies. Forwarded headers are honoured only when the
+// connection comes from a trusted proxy, so clients cannot choose the address they are judged by.
+package netaccess
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// clientIPGinKey holds the resolved client address of a request.
+const clientIPGinKey = "clientIP"
+
+var defaultHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+
+// settings is the active network access configuration.
+type settings struct {
+	// restricted is set when an allow list is configured, even if none of its entries parsed.
+	restricted bool
+	allow      []netip.Prefix
+	deny       []netip.Prefix
+	trusted    []netip.Prefix
+	headers    []string
+}
+
+var active atomic.Pointer[settings]
+
+// Configure loads the network-access settings of cfg. Invalid entries are logged and skipped.
+func Configure(cfg *config.Config) {
+	if cfg == nil {
+		active.Store(nil)
+		return
+	}
+	na := cfg.NetworkAccess
+	next := &settings{
+		allow:   parsePrefixes("allow", na.Allow),
+		deny:    parsePrefixes("deny", na.Deny),
+		trusted: parsePrefixes("trusted-proxies", na.TrustedProxies),
+	}
+	for _, entry := range na.Allow {
+		if strings.TrimSpace(entry) != "" {
+			next.restricted = true
+		}
+	}
+	for _, name := range na.ClientIPHeaders {
+		if name = strings.TrimSpace(name); name != "" {
+			next.headers = append(next.headers, name)
+		}
+	}
+	if len(next.headers) == 0 {
+		next.headers = defaultHeaders
+	}
+	if !next.restricted && len(next.deny) == 0 && len(next.trusted) == 0 {
+		active.Store(nil)
+		return
+	}
+	active.Store(next)
+	log.Infof("network-access: %d allowed, %d denied range(s), %d trusted proxy range(s)", len(next.allow), len(next.deny), len(next.trusted))
+}
+
+// parsePrefixes parses CIDR ranges and single addresses.
+func parsePrefixes(field string, entries []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				log.Errorf("network-access: %s: invalid range %q: %v", field, entry, err)
+				continue
+			}
+			if prefix.Addr().Is4In6() {
+				prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			log.Errorf("network-access: %s: invalid address %q: %v", field, entry, err)
+			continue
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes
+}
+
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware resolves the client address of every request and rejects clients outside the
+// allowed ranges with 403.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		st := active.Load()
+		ip := resolve(st, c.Request)
+		c.Set(clientIPGinKey, ip)
+		if st != nil && !st.admits(ip) {
+			log.WithFields(log.Fields{
+				"audit":     "network-access-denied",
+				"client_ip": ip,
+				"method":    c.Request.Method,
+				"path":      c.Request.URL.Path,
+			}).Warn("client address is not allowed")
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access denied"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// admits reports whether ip passes the deny and allow lists. Unparsable addresses only pass when
+// no allow list is set.
+func (s *settings) admits(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return !s.restricted
+	}
+	if contains(s.deny, addr) {
+		return false
+	}
+	return !s.restricted || contains(s.allow, addr)
+}
+
+// ClientIP returns the client address of the request in c: the connection peer, or for requests
+// relayed by a trusted proxy the address named in the forwarded headers.
+func ClientIP(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	if ip := c.GetString(clientIPGinKey); ip != "" {
+		return ip
+	}
+	return resolve(active.Load(), c.Request)
+}
+
+func resolve(st *settings, r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		return ""
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil {
+		return ""
+	}
+	peer = peer.Unmap()
+	if st == nil || !contains(st.trusted, peer) {
+		return peer.String()
+	}
+	// The first configured header present decides; a malformed chain falls back to the peer
+	// rather than to a later header.
+	for _, name := range st.headers {
+		values := r.Header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		if addr, ok := forwardedAddr(st.trusted, values); ok {
+			return addr.String()
+		}
+		break
+	}
+	return peer.String()
+}
+
+// forwardedAddr walks a forwarded chain from the closest hop and returns the first address that
+// is not a trusted proxy; hops further out were written by the client and may be forged. A chain
+// made up of trusted proxies only yields its outermost hop. A hop that does not parse ends the
+// walk without a result.
+func forwardedAddr(trusted []netip.Prefix, values []string) (netip.Addr, bool) {
+	var hops []string
+	for _, value := range values {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	var outermost netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := parseHop(hops[i])
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = addr.Unmap()
+		if !contains(trusted, addr) {
+			return addr, true
+		}
+		outermost = addr
+	}
+	return outermost, outermost.IsValid()
+}
+
+// parseHop parses one forwarded hop, which some proxies write with a port ("1.2.3.4:5678",
+// "[2001:db8::1]:443").
+func parseHop(hop string) (netip.Addr, error) {
+	hop = strings.TrimSpace(hop)
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr(), nil
+	}
+	return netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"))
+}
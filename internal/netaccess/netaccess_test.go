@@ -0,0 +1,89 @@
+package netaccess
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestClientIPHonoursTrustedProxiesOnly(t *testing.T) {
+	st := &settings{
+		trusted: parsePrefixes("trusted-proxies", []string{"10.0.0.0/8", "127.0.0.1"}),
+		headers: defaultHeaders,
+	}
+	cases := []struct {
+		name   string
+		remote string
+		xff    string
+		realIP string
+		want   string
+	}{
+		{name: "untrusted peer", remote: "203.0.113.5:1234", xff: "198.51.100.1", want: "203.0.113.5"},
+		{name: "trusted peer", remote: "127.0.0.1:1234", xff: "198.51.100.1", want: "198.51.100.1"},
+		{name: "forged hops are skipped", remote: "127.0.0.1:1234", xff: "1.2.3.4, 198.51.100.1, 10.1.2.3", want: "198.51.100.1"},
+		{name: "only proxies", remote: "127.0.0.1:1234", xff: "10.0.0.2, 10.0.0.1", want: "10.0.0.2"},
+		{name: "malformed header", remote: "127.0.0.1:1234", xff: "garbage", want: "127.0.0.1"},
+		{name: "malformed hop ends the walk", remote: "127.0.0.1:1234", xff: "198.51.100.1, garbage, 10.1.2.3", realIP: "1.2.3.4", want: "127.0.0.1"},
+		{name: "hops with ports", remote: "127.0.0.1:1234", xff: "198.51.100.1:5678, [2001:db8::1]:443", want: "2001:db8::1"},
+		{name: "real ip without forwarded for", remote: "127.0.0.1:1234", realIP: "198.51.100.7", want: "198.51.100.7"},
+		{name: "mapped peer", remote: "[::ffff:203.0.113.5]:1234", want: "203.0.113.5"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remote
+			if tc.xff != "" {
+				req.Header.Set("X-Forwarded-For", tc.xff)
+			}
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+			if got := resolve(st, req); got != tc.want {
+				t.Fatalf("resolve = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareAppliesAllowAndDeny(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{NetworkAccess: config.NetworkAccessConfig{
+		Allow:          []string{"192.168.0.0/16"},
+		Deny:           []string{"192.168.5.0/24"},
+		TrustedProxies: []string{"127.0.0.1"},
+	}}
+	Configure(cfg)
+	t.Cleanup(func() { Configure(nil) })
+
+	engine := gin.New()
+	engine.Use(Middleware())
+	engine.GET("/", func(c *gin.Context) { c.String(http.StatusOK, ClientIP(c)) })
+
+	cases := []struct {
+		remote string
+		xff    string
+		status int
+	}{
+		{remote: "192.168.1.1:1", status: http.StatusOK},
+		{remote: "192.168.5.1:1", status: http.StatusForbidden},
+		{remote: "8.8.8.8:1", status: http.StatusForbidden},
+		{remote: "127.0.0.1:1", xff: "192.168.1.7", status: http.StatusOK},
+		{remote: "127.0.0.1:1", xff: "8.8.8.8", status: http.StatusForbidden},
+		{remote: "8.8.8.8:1", xff: "192.168.1.7", status: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remote
+		if tc.xff != "" {
+			req.Header.Set("X-Forwarded-For", tc.xff)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s via %q: status %d, want %d", tc.remote, tc.xff, rec.Code, tc.status)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+// Package providerstatus polls upstream status pages (Atlassian Statuspage feeds such as
+// status.anthropic.com and the Google Cloud incidents feed) and exposes the active incidents per
+// provider. Routing uses Degraded to try other providers first during an incident, and the
+// management API reports the incidents to operators.
+package providerstatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// FormatStatuspage identifies Atlassian Statuspage summary.json feeds.
+	FormatStatuspage = "statuspage"
+	// FormatGoogleCloud identifies the status.cloud.google.com incidents.json feed.
+	FormatGoogleCloud = "google-cloud"
+
+	defaultInterval = 5 * time.Minute
+	minInterval     = time.Minute
+	fetchTimeout    = 15 * time.Second
+	maxFeedBytes    = 16 << 20
+)
+
+// Level grades the impact of the incidents affecting a provider.
+type Level int
+
+const (
+	LevelNone Level = iota
+	LevelMinor
+	LevelMajor
+	LevelCritical
+)
+
+// String returns the Statuspage name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelMinor:
+		return "minor"
+	case LevelMajor:
+		return "major"
+	case LevelCritical:
+		return "critical"
+	default:
+		return "none"
+	}
+}
+
+// MarshalJSON encodes the level by name.
+func (l Level) MarshalJSON() ([]byte, error) { return json.Marshal(l.String()) }
+
+func parseLevel(value string) Level {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "minor", "maintenance":
+		return LevelMinor
+	case "major":
+		return LevelMajor
+	case "critical":
+		return LevelCritical
+	default:
+		return LevelNone
+	}
+}
+
+// Incident is an unresolved incident or in-progress maintenance reported by a feed.
+type Incident struct {
+	Feed      string    `json:"feed"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status,omitempty"`
+	Level     Level     `json:"level"`
+	URL       string    `json:"url,omitempty"`
+	UpdatedAt time.Time `json:"updated-at,omitempty"`
+}
+
+// FeedStatus is the outcome of the most recent poll of one feed.
+type FeedStatus struct {
+	Name        string     `json:"name"`
+	URL         string     `json:"url"`
+	Providers   []string   `json:"providers"`
+	Level       Level      `json:"level"`
+	Description string     `json:"description,omitempty"`
+	Incidents   []Incident `json:"incidents"`
+	CheckedAt   time.Time  `json:"checked-at"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// ProviderStatus aggregates the feeds covering one provider.
+type ProviderStatus struct {
+	Level     Level      `json:"level"`
+	Incidents []Incident `json:"incidents"`
+}
+
+// DefaultFeeds returns the feeds polled when provider-status.feeds is empty.
+func DefaultFeeds() []config.ProviderStatusFeed {
+	return []config.ProviderStatusFeed{
+		{Name: "anthropic", Format: FormatStatuspage, URL: "https://status.anthropic.com/api/v2/summary.json", Providers: []string{"claude"}},
+		{Name: "openai", Format: FormatStatuspage, URL: "https://status.openai.com/api/v2/summary.json", Providers: []string{"codex"}},
+		{
+			Name:      "google-cloud",
+			Format:    FormatGoogleCloud,
+			URL:       "https://status.cloud.google.com/incidents.json",
+			Providers: []string{"gemini", "gemini-cli", "vertex", "aistudio", "antigravity"},
+			Products:  []string{"Gemini", "Vertex AI"},
+		},
+	}
+}
+
+// ParseStatuspage reads an Atlassian Statuspage summary.json document. The level is the highest
+// of the page indicator and the impact of each unresolved incident or in-progress maintenance.
+func ParseStatuspage(feed string, data []byte) (Level, string, []Incident, error) {
+	var doc struct {
+		Status struct {
+			Indicator   string `json:"indicator"`
+			Description string `json:"description"`
+		} `json:"status"`
+		Incidents []struct {
+			Name      string    `json:"name"`
+			Status    string    `json:"status"`
+			Impact    string    `json:"impact"`
+			Shortlink string    `json:"shortlink"`
+			UpdatedAt time.Time `json:"updated_at"`
+		} `json:"incidents"`
+		ScheduledMaintenances []struct {
+			Name      string    `json:"name"`
+			Status    string    `json:"status"`
+			Shortlink string    `json:"shortlink"`
+			UpdatedAt time.Time `json:"updated_at"`
+		} `json:"scheduled_maintenances"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return LevelNone, "", nil, fmt.Errorf("decode statuspage summary: %w", err)
+	}
+	level := parseLevel(doc.Status.Indicator)
+	incidents := make([]Incident, 0, len(doc.Incidents))
+	for _, item := range doc.Incidents {
+		if item.Status == "resolved" || item.Status == "postmortem" {
+			continue
+		}
+		incident := Incident{Feed: feed, Name: item.Name, Status: item.Status, Level: parseLevel(item.Impact), URL: item.Shortlink, UpdatedAt: item.UpdatedAt}
+		if incident.Level > level {
+			level = incident.Level
+		}
+		incidents = append(incidents, incident)
+	}
+	for _, item := range doc.ScheduledMaintenances {
+		if item.Status != "in_progress" && item.Status != "verifying" {
+			continue
+		}
+		incidents = append(incidents, Incident{Feed: feed, Name: item.Name, Status: "maintenance", Level: LevelMinor, URL: item.Shortlink, UpdatedAt: item.UpdatedAt})
+		if level < LevelMinor {
+			level = LevelMinor
+		}
+	}
+	return level, doc.Status.Description, incidents, nil
+}
+
+// ParseGoogleCloud reads the status.cloud.google.com incidents.json document and returns the
+// ongoing incidents that affect one of products (all incidents when products is empty).
+func ParseGoogleCloud(feed string, data []byte, products []string) (Level, []Incident, error) {
+	var doc []struct {
+		ID               string `json:"id"`
+		ExternalDesc     string `json:"external_desc"`
+		End              string `json:"end"`
+		StatusImpact     string `json:"status_impact"`
+		URI              string `json:"uri"`
+		Modified         string `json:"modified"`
+		AffectedProducts []struct {
+			Title string `json:"title"`
+		} `json:"affected_products"`
+		MostRecentUpdate struct {
+			Status string `json:"status"`
+		} `json:"most_recent_update"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return LevelNone, nil, fmt.Errorf("decode google cloud incidents: %w", err)
+	}
+	level := LevelNone
+	incidents := make([]Incident, 0)
+	for _, item := range doc {
+		if strings.TrimSpace(item.End) != "" {
+			continue
+		}
+		matched := len(products) == 0
+		for _, product := range item.AffectedProducts {
+			if matched {
+				break
+			}
+			for _, want := range products {
+				if want = strings.TrimSpace(want); want != "" && strings.Contains(strings.ToLower(product.Title), strings.ToLower(want)) {
+					matched = true
+					break
+				}
+			}
+		}
+		if !matched {
+			continue
+		}
+		incident := Incident{Feed: feed, Name: strings.TrimSpace(item.ExternalDesc), Status: strings.ToLower(item.MostRecentUpdate.Status)}
+		switch item.StatusImpact {
+		case "SERVICE_OUTAGE":
+			incident.Level = LevelCritical
+		case "SERVICE_DISRUPTION":
+			incident.Level = LevelMajor
+		default:
+			incident.Level = LevelMinor
+		}
+		if item.URI != "" {
+			incident.URL = "https://status.cloud.google.com/" + strings.TrimPrefix(item.URI, "/")
+		}
+		if modified, err := time.Parse(time.RFC3339, item.Modified); err == nil {
+			incident.UpdatedAt = modified
+		}
+		if incident.Level > level {
+			level = incident.Level
+		}
+		incidents = append(incidents, incident)
+	}
+	return level, incidents, nil
+}
+
+// PollFeed fetches and parses one feed. Failures are reported in FeedStatus.Error.
+func PollFeed(ctx context.Context, client *http.Client, feed config.ProviderStatusFeed) FeedStatus {
+	status := FeedStatus{Name: feed.Name, URL: feed.URL, Providers: normalizeProviders(feed.Providers), Incidents: []Incident{}, CheckedAt: time.Now().UTC()}
+	data, err := fetch(ctx, client, feed.URL)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	var incidents []Incident
+	switch strings.ToLower(strings.TrimSpace(feed.Format)) {
+	case FormatGoogleCloud:
+		status.Level, incidents, err = ParseGoogleCloud(feed.Name, data, feed.Products)
+	case FormatStatuspage, "":
+		status.Level, status.Description, incidents, err = ParseStatuspage(feed.Name, data)
+	default:
+		err = fmt.Errorf("unsupported feed format %q", feed.Format)
+	}
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Incidents = incidents
+	return status
+}
+
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Debugf("provider status: close response body: %v", errClose)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes))
+}
+
+func normalizeProviders(providers []string) []string {
+	out := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		if provider = strings.ToLower(strings.TrimSpace(provider)); provider != "" {
+			out = append(out, provider)
+		}
+	}
+	return out
+}
+
+// state is the published result of the latest poll round.
+type state struct {
+	feeds     []FeedStatus
+	providers map[string]ProviderStatus
+}
+
+var (
+	current atomic.Pointer[state]
+
+	pollerMu     sync.Mutex
+	pollerCancel context.CancelFunc
+)
+
+// Configure applies the provider-status settings, restarting the poller. Disabling it clears
+// the published status so routing stops using stale incidents.
+func Configure(cfg *config.Config) {
+	pollerMu.Lock()
+	defer pollerMu.Unlock()
+	if pollerCancel != nil {
+		pollerCancel()
+		pollerCancel = nil
+	}
+	if cfg == nil || !cfg.ProviderStatus.Enabled {
+		current.Store(nil)
+		return
+	}
+	feeds := cfg.ProviderStatus.Feeds
+	if len(feeds) == 0 {
+		feeds = DefaultFeeds()
+	}
+	interval := defaultInterval
+	if raw := strings.TrimSpace(cfg.ProviderStatus.Interval); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			interval = parsed
+		} else {
+			log.Warnf("provider status: invalid interval %q, using %s", raw, defaultInterval)
+		}
+	}
+	if interval < minInterval {
+		interval = minInterval
+	}
+	client := &http.Client{Timeout: fetchTimeout}
+	util.SetProxy(&cfg.SDKConfig, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pollerCancel = cancel
+	go run(ctx, client, feeds, interval)
+}
+
+func run(ctx context.Context, client *http.Client, feeds []config.ProviderStatusFeed, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		pollAll(ctx, client, feeds)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func pollAll(ctx context.Context, client *http.Client, feeds []config.ProviderStatusFeed) {
+	results := make([]FeedStatus, len(feeds))
+	var wg sync.WaitGroup
+	for i := range feeds {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = PollFeed(ctx, client, feeds[i])
+		}(i)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+	previous := current.Load()
+	next := buildState(results, previous)
+	logTransitions(previous, next)
+	current.Store(next)
+}
+
+// buildState aggregates feed results per provider. A feed that failed to poll keeps the status
+// from the previous round so a flaky status page does not clear an ongoing incident.
+func buildState(results []FeedStatus, previous *state) *state {
+	next := &state{feeds: results, providers: make(map[string]ProviderStatus)}
+	for i := range results {
+		feed := results[i]
+		if feed.Error != "" && previous != nil {
+			for _, old := range previous.feeds {
+				if old.Name == feed.Name && old.URL == feed.URL && old.Error == "" {
+					feed.Level, feed.Description, feed.Incidents = old.Level, old.Description, old.Incidents
+					results[i] = feed
+					break
+				}
+			}
+		}
+		for _, provider := range feed.Providers {
+			status := next.providers[provider]
+			if feed.Level > status.Level {
+				status.Level = feed.Level
+			}
+			status.Incidents = append(status.Incidents, feed.Incidents...)
+			next.providers[provider] = status
+		}
+	}
+	return next
+}
+
+func logTransitions(previous, next *state) {
+	names := make([]string, 0, len(next.providers))
+	for provider := range next.providers {
+		names = append(names, provider)
+	}
+	sort.Strings(names)
+	for _, provider := range names {
+		status := next.providers[provider]
+		before := LevelNone
+		if previous != nil {
+			before = previous.providers[provider].Level
+		}
+		if status.Level == before {
+			continue
+		}
+		if status.Level == LevelNone {
+			log.Infof("provider status: %s recovered", provider)
+			continue
+		}
+		summary := ""
+		if len(status.Incidents) > 0 {
+			summary = ": " + status.Incidents[0].Name
+		}
+		log.Warnf("provider status: %s reports a %s incident%s", provider, status.Level, summary)
+	}
+}
+
+// Feeds returns the latest poll results, or nil when the poller is disabled or has not run yet.
+func Feeds() []FeedStatus {
+	if s := current.Load(); s != nil {
+		return append([]FeedStatus(nil), s.feeds...)
+	}
+	return nil
+}
+
+// Providers returns the aggregated status per provider identifier.
+func Providers() map[string]ProviderStatus {
+	s := current.Load()
+	if s == nil {
+		return map[string]ProviderStatus{}
+	}
+	out := make(map[string]ProviderStatus, len(s.providers))
+	for provider, status := range s.providers {
+		out[provider] = status
+	}
+	return out
+}
+
+// Degraded reports whether provider currently has a major or critical incident.
+func Degraded(provider string) bool {
+	s := current.Load()
+	if s == nil {
+		return false
+	}
+	return s.providers[strings.ToLower(strings.TrimSpace(provider))].Level >= LevelMajor
+}
@@ -0,0 +1,85 @@
+package providerstatus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+const statuspageSummary = `{
+  "status": {"indicator": "minor", "description": "Minor Service Outage"},
+  "incidents": [
+    {"name": "Elevated errors on Claude Opus", "status": "investigating", "impact": "major", "shortlink": "https://stspg.io/x", "updated_at": "2026-01-02T03:04:05Z"},
+    {"name": "Old incident", "status": "resolved", "impact": "critical"}
+  ],
+  "scheduled_maintenances": [
+    {"name": "Database upgrade", "status": "scheduled"}
+  ]
+}`
+
+const googleIncidents = `[
+  {"external_desc": "Vertex Gemini API elevated latency", "status_impact": "SERVICE_DISRUPTION", "uri": "incidents/abc", "modified": "2026-01-02T03:04:05+00:00",
+   "affected_products": [{"title": "Vertex Gemini API"}], "most_recent_update": {"status": "SERVICE_DISRUPTION"}},
+  {"external_desc": "BigQuery outage", "status_impact": "SERVICE_OUTAGE", "affected_products": [{"title": "BigQuery"}]},
+  {"external_desc": "Resolved Gemini issue", "end": "2026-01-01T00:00:00+00:00", "status_impact": "SERVICE_OUTAGE", "affected_products": [{"title": "Gemini"}]}
+]`
+
+func TestParseStatuspage(t *testing.T) {
+	level, description, incidents, err := ParseStatuspage("anthropic", []byte(statuspageSummary))
+	if err != nil {
+		t.Fatalf("ParseStatuspage: %v", err)
+	}
+	if level != LevelMajor || description != "Minor Service Outage" {
+		t.Fatalf("level = %s, description = %q", level, description)
+	}
+	if len(incidents) != 1 || incidents[0].Name != "Elevated errors on Claude Opus" || incidents[0].URL != "https://stspg.io/x" {
+		t.Fatalf("unexpected incidents: %+v", incidents)
+	}
+}
+
+func TestParseGoogleCloud_FiltersProductsAndResolved(t *testing.T) {
+	level, incidents, err := ParseGoogleCloud("google-cloud", []byte(googleIncidents), []string{"gemini"})
+	if err != nil {
+		t.Fatalf("ParseGoogleCloud: %v", err)
+	}
+	if level != LevelMajor || len(incidents) != 1 {
+		t.Fatalf("level = %s, incidents = %+v", level, incidents)
+	}
+	if incidents[0].URL != "https://status.cloud.google.com/incidents/abc" {
+		t.Fatalf("url = %q", incidents[0].URL)
+	}
+	if level, _, _ = ParseGoogleCloud("google-cloud", []byte(googleIncidents), nil); level != LevelCritical {
+		t.Fatalf("expected unfiltered feed to report the outage, got %s", level)
+	}
+}
+
+func TestBuildState_AggregatesProvidersAndKeepsStatusOnPollError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(statuspageSummary))
+	}))
+	defer server.Close()
+	feed := config.ProviderStatusFeed{Name: "anthropic", Format: FormatStatuspage, URL: server.URL, Providers: []string{"Claude"}}
+
+	first := buildState([]FeedStatus{PollFeed(context.Background(), server.Client(), feed)}, nil)
+	if got := first.providers["claude"]; got.Level != LevelMajor || len(got.Incidents) != 1 {
+		t.Fatalf("unexpected provider status: %+v", got)
+	}
+
+	failed := FeedStatus{Name: "anthropic", URL: server.URL, Providers: []string{"claude"}, Error: "timeout"}
+	second := buildState([]FeedStatus{failed}, first)
+	if second.providers["claude"].Level != LevelMajor {
+		t.Fatalf("failed poll cleared the previous incident")
+	}
+	if second.feeds[0].Error != "timeout" {
+		t.Fatalf("poll error not reported")
+	}
+
+	current.Store(second)
+	defer current.Store(nil)
+	if !Degraded("claude") || Degraded("codex") {
+		t.Fatalf("unexpected Degraded results")
+	}
+}
@@ -0,0 +1,193 @@
+// Package quotawindow tracks the subscription usage windows that upstreams report per
+// credential, such as the rolling 5-hour and weekly windows of Claude OAuth accounts. Executors
+// record the windows parsed from response headers, the health API reports the remaining
+// estimates, and routing sends batch traffic to credentials with headroom.
+package quotawindow
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// StatusAllowed means the window still accepts requests.
+	StatusAllowed = "allowed"
+	// StatusAllowedWarning means the window accepts requests but is close to its limit.
+	StatusAllowedWarning = "allowed_warning"
+	// StatusRejected means the window is exhausted until it resets.
+	StatusRejected = "rejected"
+
+	// claudeSessionWindow is the name of the rolling 5-hour window of Claude subscriptions.
+	claudeSessionWindow = "5h"
+	claudeSessionLength = 5 * time.Hour
+)
+
+// Window is the last known state of one usage window of a credential.
+type Window struct {
+	Name   string `json:"name"`
+	Status string `json:"status,omitempty"`
+	// Utilization is the used fraction of the window between 0 and 1, negative when unknown.
+	Utilization float64   `json:"utilization"`
+	ResetsAt    time.Time `json:"resets_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Remaining estimates the unused fraction of the window at now. Windows past their reset are
+// assumed to be empty again.
+func (w Window) Remaining(now time.Time) float64 {
+	if !w.ResetsAt.IsZero() && !now.Before(w.ResetsAt) {
+		return 1
+	}
+	if w.Status == StatusRejected {
+		return 0
+	}
+	if w.Utilization < 0 {
+		return 1
+	}
+	return max(0, 1-w.Utilization)
+}
+
+var (
+	mu      sync.RWMutex
+	windows = make(map[string]map[string]Window)
+)
+
+// Record merges the given windows into the state of authID, replacing windows of the same name.
+func Record(authID string, updates []Window) {
+	if authID == "" || len(updates) == 0 {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	current := windows[authID]
+	if current == nil {
+		current = make(map[string]Window, len(updates))
+		windows[authID] = current
+	}
+	for _, window := range updates {
+		current[window.Name] = window
+	}
+}
+
+// Get returns the known windows of authID sorted by name, dropping windows that reset before
+// now.
+func Get(authID string, now time.Time) []Window {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]Window, 0, len(windows[authID]))
+	for _, window := range windows[authID] {
+		if !window.ResetsAt.IsZero() && !now.Before(window.ResetsAt) {
+			continue
+		}
+		out = append(out, window)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Headroom returns the smallest remaining fraction across the windows of authID and when the
+// tightest window resets. known is false when nothing was recorded for authID.
+func Headroom(authID string, now time.Time) (remaining float64, resetsAt time.Time, known bool) {
+	remaining = 1
+	for _, window := range Get(authID, now) {
+		if left := window.Remaining(now); !known || left < remaining {
+			remaining, resetsAt = left, window.ResetsAt
+		}
+		known = true
+	}
+	return remaining, resetsAt, known
+}
+
+// Forget drops the windows recorded for authID.
+func Forget(authID string) {
+	mu.Lock()
+	delete(windows, authID)
+	mu.Unlock()
+}
+
+// ParseClaudeHeaders extracts the unified rate-limit windows Anthropic reports for subscription
+// accounts, e.g. anthropic-ratelimit-unified-5h-utilization and -reset. A 429 response without
+// window headers marks the 5-hour window exhausted until Retry-After, or for a full window when
+// no Retry-After is given. Overloaded (529) responses only do so when they carry Retry-After,
+// since they usually reflect upstream load rather than the account's usage.
+func ParseClaudeHeaders(status int, header http.Header, now time.Time) []Window {
+	const prefix = "Anthropic-Ratelimit-Unified-"
+	byName := make(map[string]*Window)
+	window := func(name string) *Window {
+		w, ok := byName[name]
+		if !ok {
+			w = &Window{Name: name, Utilization: -1, UpdatedAt: now}
+			byName[name] = w
+		}
+		return w
+	}
+	for key, values := range header {
+		key = http.CanonicalHeaderKey(key)
+		if !strings.HasPrefix(key, prefix) || len(values) == 0 {
+			continue
+		}
+		value := strings.TrimSpace(values[0])
+		rest := strings.ToLower(strings.TrimPrefix(key, prefix))
+		name, field, ok := cutLast(rest, "-")
+		if !ok || name == "" || name == "overage" {
+			continue
+		}
+		switch field {
+		case "utilization":
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				window(name).Utilization = min(max(parsed, 0), 1)
+			}
+		case "reset":
+			if resetsAt, ok := parseReset(value); ok {
+				window(name).ResetsAt = resetsAt
+			}
+		case "status":
+			window(name).Status = strings.ToLower(value)
+		}
+	}
+
+	if len(byName) == 0 && (status == http.StatusTooManyRequests || status == 529) {
+		var resetsAt time.Time
+		if secs, err := strconv.Atoi(strings.TrimSpace(header.Get("Retry-After"))); err == nil && secs > 0 {
+			resetsAt = now.Add(time.Duration(secs) * time.Second)
+		} else if status == http.StatusTooManyRequests {
+			resetsAt = now.Add(claudeSessionLength)
+		} else {
+			return nil
+		}
+		return []Window{{Name: claudeSessionWindow, Status: StatusRejected, Utilization: 1, ResetsAt: resetsAt, UpdatedAt: now}}
+	}
+
+	out := make([]Window, 0, len(byName))
+	for _, w := range byName {
+		if w.Status == "" && w.Utilization < 0 && w.ResetsAt.IsZero() {
+			continue
+		}
+		out = append(out, *w)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// cutLast splits s around the last occurrence of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// parseReset accepts Unix seconds and RFC 3339 timestamps.
+func parseReset(value string) (time.Time, bool) {
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil && secs > 0 {
+		return time.Unix(secs, 0), true
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,45 @@
+package quotawindow
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseClaudeHeaders(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	header := http.Header{}
+	header.Set("anthropic-ratelimit-unified-status", "allowed")
+	header.Set("anthropic-ratelimit-unified-5h-status", "allowed_warning")
+	header.Set("anthropic-ratelimit-unified-5h-utilization", "0.9")
+	header.Set("anthropic-ratelimit-unified-5h-reset", strconv.FormatInt(now.Add(time.Hour).Unix(), 10))
+	header.Set("anthropic-ratelimit-unified-7d-utilization", "0.25")
+	header.Set("anthropic-ratelimit-unified-overage-status", "rejected")
+
+	windows := ParseClaudeHeaders(http.StatusOK, header, now)
+	if len(windows) != 2 || windows[0].Name != "5h" || windows[1].Name != "7d" {
+		t.Fatalf("unexpected windows: %+v", windows)
+	}
+	if windows[0].Status != StatusAllowedWarning || !windows[0].ResetsAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected 5h window: %+v", windows[0])
+	}
+
+	Record("auth-1", windows)
+	defer Forget("auth-1")
+	remaining, resetsAt, known := Headroom("auth-1", now)
+	if !known || remaining < 0.09 || remaining > 0.11 || !resetsAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("Headroom = %v, %v, %v", remaining, resetsAt, known)
+	}
+	if got := Get("auth-1", now.Add(2*time.Hour)); len(got) != 1 || got[0].Name != "7d" {
+		t.Fatalf("expired window should be dropped, got %+v", got)
+	}
+
+	limited := ParseClaudeHeaders(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"120"}}, now)
+	if len(limited) != 1 || limited[0].Status != StatusRejected || !limited[0].ResetsAt.Equal(now.Add(2*time.Minute)) {
+		t.Fatalf("unexpected windows for 429: %+v", limited)
+	}
+	if overloaded := ParseClaudeHeaders(529, http.Header{}, now); len(overloaded) != 0 {
+		t.Fatalf("overloaded responses without Retry-After must not mark a window: %+v", overloaded)
+	}
+}
@@ -0,0 +1,93 @@
+package registry
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// capabilityOverrides holds the active model capability overrides keyed by lower-cased model ID.
+// The map is replaced wholesale on every update so readers never observe a partial table.
+var capabilityOverrides atomic.Pointer[map[string]config.ModelCapability]
+
+// SetModelCapabilities atomically replaces the model capability override table.
+// Passing an empty slice clears all overrides.
+func SetModelCapabilities(entries []config.ModelCapability) {
+	normalized := config.NormalizeModelCapabilities(entries)
+	table := make(map[string]config.ModelCapability, len(normalized))
+	for _, entry := range normalized {
+		table[strings.ToLower(entry.ID)] = entry
+	}
+	capabilityOverrides.Store(&table)
+}
+
+// ModelCapabilityOverride returns the override configured for modelID through
+// model-capabilities, if any.
+func ModelCapabilityOverride(modelID string) (config.ModelCapability, bool) {
+	table := capabilityOverrides.Load()
+	if table == nil || len(*table) == 0 || modelID == "" {
+		return config.ModelCapability{}, false
+	}
+	entry, ok := (*table)[strings.ToLower(strings.TrimSpace(modelID))]
+	return entry, ok
+}
+
+// LookupModelPricing returns the token prices configured for modelID through model-capabilities.
+func LookupModelPricing(modelID string) (config.ModelPricing, bool) {
+	override, ok := ModelCapabilityOverride(modelID)
+	if !ok || override.Pricing == nil {
+		return config.ModelPricing{}, false
+	}
+	return *override.Pricing, true
+}
+
+// modelInfoFromCapability builds the model info of a model that is only known through its
+// capability override, e.g. a newly launched model not yet in the static definitions.
+func modelInfoFromCapability(modelID string) *ModelInfo {
+	if _, ok := ModelCapabilityOverride(modelID); !ok {
+		return nil
+	}
+	return applyModelCapabilityOverride(&ModelInfo{ID: strings.TrimSpace(modelID), Object: "model"})
+}
+
+// applyModelCapabilityOverride returns model with any configured capability override applied.
+// The original ModelInfo is never mutated; a clone is returned when an override matches.
+func applyModelCapabilityOverride(model *ModelInfo) *ModelInfo {
+	if model == nil {
+		return nil
+	}
+	override, ok := ModelCapabilityOverride(model.ID)
+	if !ok {
+		return model
+	}
+	out := cloneModelInfo(model)
+	if override.DisplayName != "" {
+		out.DisplayName = override.DisplayName
+	}
+	if override.Description != "" {
+		out.Description = override.Description
+	}
+	if override.ContextLength > 0 {
+		out.ContextLength = override.ContextLength
+	}
+	if override.MaxCompletionTokens > 0 {
+		out.MaxCompletionTokens = override.MaxCompletionTokens
+	}
+	if override.InputTokenLimit > 0 {
+		out.InputTokenLimit = override.InputTokenLimit
+	}
+	if override.OutputTokenLimit > 0 {
+		out.OutputTokenLimit = override.OutputTokenLimit
+	}
+	if override.Thinking != nil {
+		out.Thinking = &ThinkingSupport{
+			Min:            override.Thinking.Min,
+			Max:            override.Thinking.Max,
+			ZeroAllowed:    override.Thinking.ZeroAllowed,
+			DynamicAllowed: override.Thinking.DynamicAllowed,
+			Levels:         append([]string(nil), override.Thinking.Levels...),
+		}
+	}
+	return out
+}
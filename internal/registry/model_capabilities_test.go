@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestModelCapabilityOverrides_AppliedAndCleared(t *testing.T) {
+	t.Cleanup(func() { SetModelCapabilities(nil) })
+
+	r := newTestModelRegistry()
+	r.RegisterClient("client-1", "gemini", []*ModelInfo{{ID: "m-1", ContextLength: 1000, DisplayName: "Original"}})
+
+	SetModelCapabilities([]config.ModelCapability{{
+		ID:            "M-1",
+		ContextLength: 2000,
+		Thinking:      &config.ModelCapabilityThinking{Min: 1, Max: 10, Levels: []string{" High "}},
+	}})
+
+	listed := r.convertModelToMap(r.GetModelInfo("m-1", "gemini"), "openai")
+	if got := listed["context_length"]; got != 2000 {
+		t.Fatalf("expected overridden context_length 2000, got %v", got)
+	}
+	if got := listed["display_name"]; got != "Original" {
+		t.Fatalf("expected unset fields to be preserved, got %v", got)
+	}
+
+	info := applyModelCapabilityOverride(r.GetModelInfo("m-1", "gemini"))
+	if info.Thinking == nil || info.Thinking.Max != 10 || len(info.Thinking.Levels) != 1 || info.Thinking.Levels[0] != "high" {
+		t.Fatalf("unexpected thinking override: %+v", info.Thinking)
+	}
+	if original := r.GetModelInfo("m-1", "gemini"); original.ContextLength != 1000 || original.Thinking != nil {
+		t.Fatalf("registered model info was mutated: %+v", original)
+	}
+
+	SetModelCapabilities(nil)
+	listed = r.convertModelToMap(r.GetModelInfo("m-1", "gemini"), "openai")
+	if got := listed["context_length"]; got != 1000 {
+		t.Fatalf("expected context_length 1000 after clearing overrides, got %v", got)
+	}
+}
+
+func TestModelCapabilityOverrides_DefineUnregisteredModelAndPricing(t *testing.T) {
+	t.Cleanup(func() { SetModelCapabilities(nil) })
+
+	if LookupModelInfo("brand-new-model") != nil {
+		t.Fatal("expected unknown model to be absent before the override")
+	}
+	SetModelCapabilities([]config.ModelCapability{{
+		ID:            "brand-new-model",
+		ContextLength: 400000,
+		Thinking:      &config.ModelCapabilityThinking{Levels: []string{"low", "high"}},
+		Pricing:       &config.ModelPricing{Input: 1.25, Output: 10},
+	}})
+
+	info := LookupModelInfo("brand-new-model")
+	if info == nil || info.ContextLength != 400000 || info.Thinking == nil || len(info.Thinking.Levels) != 2 {
+		t.Fatalf("unexpected model info from override: %+v", info)
+	}
+	pricing, ok := LookupModelPricing("Brand-New-Model")
+	if !ok || pricing.Input != 1.25 || pricing.Output != 10 {
+		t.Fatalf("LookupModelPricing = %+v, %v", pricing, ok)
+	}
+}
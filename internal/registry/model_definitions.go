@@ -19,6 +19,9 @@ import (
 //   - codex
 //   - qwen
 //   - iflow
+//   - mistral
+//   - xai
+//   - deepseek
 //   - github-copilot
 //   - antigravity (returns static overrides only)
 func GetStaticModelDefinitionsByChannel(channel string) []*ModelInfo {
@@ -40,6 +43,12 @@ func GetStaticModelDefinitionsByChannel(channel string) []*ModelInfo {
 		return GetQwenModels()
 	case "iflow":
 		return GetIFlowModels()
+	case "mistral":
+		return GetMistralModels()
+	case "xai":
+		return GetXAIModels()
+	case "deepseek":
+		return GetDeepSeekModels()
 	case "github-copilot":
 		return GetGitHubCopilotModels()
 	case "antigravity":
@@ -86,6 +95,9 @@ func LookupStaticModelInfo(modelID string) *ModelInfo {
 		GetOpenAIModels(),
 		GetQwenModels(),
 		GetIFlowModels(),
+		GetMistralModels(),
+		GetXAIModels(),
+		GetDeepSeekModels(),
 	}
 	for _, models := range allModels {
 		for _, m := range models {
@@ -820,6 +820,108 @@ func GetIFlowModels() []*ModelInfo {
 	return models
 }
 
+// GetMistralModels returns the models served by Mistral La Plateforme API keys.
+func GetMistralModels() []*ModelInfo {
+	entries := []struct {
+		ID            string
+		DisplayName   string
+		Description   string
+		Created       int64
+		ContextLength int
+	}{
+		{ID: "mistral-large-latest", DisplayName: "Mistral Large", Description: "Mistral flagship general model", Created: 1731888000, ContextLength: 131072},
+		{ID: "mistral-medium-latest", DisplayName: "Mistral Medium", Description: "Mistral Medium frontier-class multimodal model", Created: 1746576000, ContextLength: 131072},
+		{ID: "mistral-small-latest", DisplayName: "Mistral Small", Description: "Mistral Small efficient multimodal model", Created: 1750291200, ContextLength: 131072},
+		{ID: "codestral-latest", DisplayName: "Codestral", Description: "Mistral coding model with fill-in-the-middle support", Created: 1753920000, ContextLength: 256000},
+		{ID: "devstral-medium-latest", DisplayName: "Devstral Medium", Description: "Mistral agentic coding model", Created: 1752710400, ContextLength: 131072},
+		{ID: "devstral-small-latest", DisplayName: "Devstral Small", Description: "Mistral compact agentic coding model", Created: 1752710400, ContextLength: 131072},
+		{ID: "magistral-medium-latest", DisplayName: "Magistral Medium", Description: "Mistral reasoning model", Created: 1749513600, ContextLength: 40960},
+		{ID: "magistral-small-latest", DisplayName: "Magistral Small", Description: "Mistral compact reasoning model", Created: 1749513600, ContextLength: 40960},
+		{ID: "ministral-8b-latest", DisplayName: "Ministral 8B", Description: "Mistral edge model", Created: 1728950400, ContextLength: 131072},
+		{ID: "pixtral-large-latest", DisplayName: "Pixtral Large", Description: "Mistral multimodal model", Created: 1731888000, ContextLength: 131072},
+	}
+	models := make([]*ModelInfo, 0, len(entries))
+	for _, entry := range entries {
+		models = append(models, &ModelInfo{
+			ID:                  entry.ID,
+			Object:              "model",
+			Created:             entry.Created,
+			OwnedBy:             "mistral",
+			Type:                "mistral",
+			DisplayName:         entry.DisplayName,
+			Description:         entry.Description,
+			ContextLength:       entry.ContextLength,
+			SupportedParameters: []string{"temperature", "top_p", "max_tokens", "stream", "stop", "tools", "tool_choice", "response_format", "random_seed"},
+		})
+	}
+	return models
+}
+
+// GetXAIModels returns the Grok models served by xAI API keys.
+func GetXAIModels() []*ModelInfo {
+	entries := []struct {
+		ID            string
+		DisplayName   string
+		Description   string
+		Created       int64
+		ContextLength int
+		Thinking      *ThinkingSupport
+	}{
+		{ID: "grok-4", DisplayName: "Grok 4", Description: "xAI flagship reasoning model with vision", Created: 1752192000, ContextLength: 256000},
+		{ID: "grok-4-fast-reasoning", DisplayName: "Grok 4 Fast", Description: "xAI fast reasoning model with vision", Created: 1758240000, ContextLength: 2000000},
+		{ID: "grok-4-fast-non-reasoning", DisplayName: "Grok 4 Fast Non-Reasoning", Description: "xAI fast model without reasoning", Created: 1758240000, ContextLength: 2000000},
+		{ID: "grok-code-fast-1", DisplayName: "Grok Code Fast 1", Description: "xAI agentic coding model", Created: 1756339200, ContextLength: 256000},
+		{ID: "grok-3", DisplayName: "Grok 3", Description: "xAI general model", Created: 1744156800, ContextLength: 131072},
+		{ID: "grok-3-mini", DisplayName: "Grok 3 Mini", Description: "xAI compact reasoning model with adjustable effort", Created: 1744156800, ContextLength: 131072, Thinking: &ThinkingSupport{Levels: []string{"low", "high"}}},
+		{ID: "grok-2-vision-1212", DisplayName: "Grok 2 Vision", Description: "xAI image understanding model", Created: 1734048000, ContextLength: 32768},
+	}
+	models := make([]*ModelInfo, 0, len(entries))
+	for _, entry := range entries {
+		models = append(models, &ModelInfo{
+			ID:            entry.ID,
+			Object:        "model",
+			Created:       entry.Created,
+			OwnedBy:       "xai",
+			Type:          "xai",
+			DisplayName:   entry.DisplayName,
+			Description:   entry.Description,
+			ContextLength: entry.ContextLength,
+			Thinking:      entry.Thinking,
+		})
+	}
+	return models
+}
+
+// GetDeepSeekModels returns the standard DeepSeek model definitions. Both models accept a
+// reasoning effort so thinking requests survive translation; the executor routes them to the
+// model that matches: deepseek-reasoner when thinking is requested and deepseek-chat when not.
+func GetDeepSeekModels() []*ModelInfo {
+	thinking := &ThinkingSupport{Levels: []string{"none", "low", "medium", "high"}}
+	entries := []struct {
+		ID          string
+		DisplayName string
+		Description string
+	}{
+		{ID: "deepseek-chat", DisplayName: "DeepSeek Chat", Description: "DeepSeek general model in non-thinking mode"},
+		{ID: "deepseek-reasoner", DisplayName: "DeepSeek Reasoner", Description: "DeepSeek general model in thinking mode"},
+	}
+	models := make([]*ModelInfo, 0, len(entries))
+	for _, entry := range entries {
+		models = append(models, &ModelInfo{
+			ID:            entry.ID,
+			Object:        "model",
+			Created:       1735689600,
+			OwnedBy:       "deepseek",
+			Type:          "deepseek",
+			DisplayName:   entry.DisplayName,
+			Description:   entry.Description,
+			ContextLength: 131072,
+			Thinking:      thinking,
+		})
+	}
+	return models
+}
+
 // AntigravityModelConfig captures static antigravity model overrides, including
 // Thinking budget limits and provider max completion tokens.
 type AntigravityModelConfig struct {
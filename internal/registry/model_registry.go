@@ -136,7 +136,8 @@ func GetGlobalRegistry() *ModelRegistry {
 	return globalRegistry
 }
 
-// LookupModelInfo searches dynamic registry (provider-specific > global) then static definitions.
+// LookupModelInfo searches dynamic registry (provider-specific > global), then static definitions,
+// then models defined only through model-capabilities overrides.
 func LookupModelInfo(modelID string, provider ...string) *ModelInfo {
 	modelID = strings.TrimSpace(modelID)
 	if modelID == "" {
@@ -149,9 +150,12 @@ func LookupModelInfo(modelID string, provider ...string) *ModelInfo {
 	}
 
 	if info := GetGlobalRegistry().GetModelInfo(modelID, p); info != nil {
-		return info
+		return applyModelCapabilityOverride(info)
 	}
-	return LookupStaticModelInfo(modelID)
+	if info := LookupStaticModelInfo(modelID); info != nil {
+		return applyModelCapabilityOverride(info)
+	}
+	return modelInfoFromCapability(modelID)
 }
 
 // SetHook sets an optional hook for observing model registration changes.
@@ -997,6 +1001,7 @@ func (r *ModelRegistry) convertModelToMap(model *ModelInfo, handlerType string)
 	if model == nil {
 		return nil
 	}
+	model = applyModelCapabilityOverride(model)
 
 	switch handlerType {
 	case "openai":
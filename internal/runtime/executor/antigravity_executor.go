@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	antigravityauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth/antigravity"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
@@ -36,16 +37,16 @@ import (
 )
 
 const (
-	antigravityBaseURLDaily        = "https://daily-cloudcode-pa.googleapis.com"
+	antigravityBaseURLDaily        = antigravityauth.GenerateEndpoint
 	antigravitySandboxBaseURLDaily = "https://daily-cloudcode-pa.sandbox.googleapis.com"
-	antigravityBaseURLProd         = "https://cloudcode-pa.googleapis.com"
+	antigravityBaseURLProd         = antigravityauth.APIEndpoint
 	antigravityCountTokensPath     = "/v1internal:countTokens"
 	antigravityStreamPath          = "/v1internal:streamGenerateContent"
 	antigravityGeneratePath        = "/v1internal:generateContent"
 	antigravityModelsPath          = "/v1internal:fetchAvailableModels"
-	antigravityClientID            = "1071006060591-tmhssin2h21lcre235vtolojh4g403ep.apps.googleusercontent.com"
-	antigravityClientSecret        = "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"
-	defaultAntigravityAgent        = "antigravity/1.104.0 darwin/arm64"
+	antigravityClientID            = antigravityauth.ClientID
+	antigravityClientSecret        = antigravityauth.ClientSecret
+	defaultAntigravityAgent        = antigravityauth.GenerateUserAgent
 	antigravityAuthType            = "antigravity"
 	refreshSkew                    = 3000 * time.Second
 	systemInstruction              = "You are Antigravity, a powerful agentic AI coding assistant designed by the Google Deepmind team working on Advanced Agentic Coding.You are pair programming with a USER to solve their coding task. The task may require creating a new codebase, modifying or debugging an existing codebase, or simply answering a question.**Absolute paths only****Proactiveness**"
@@ -146,7 +147,7 @@ func (e *AntigravityExecutor) Execute(ctx context.Context, auth *cliproxyauth.Au
 	}
 
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	translated = applyPayloadConfigWithRoot(e.cfg, baseModel, "antigravity", "request", translated, originalTranslated, requestedModel)
+	translated = applyPayloadConfigWithRoot(ctx, e.cfg, baseModel, "antigravity", "request", translated, originalTranslated, requestedModel)
 
 	baseURLs := antigravityBaseURLFallbackOrder(auth)
 	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
@@ -287,7 +288,7 @@ func (e *AntigravityExecutor) executeClaudeNonStream(ctx context.Context, auth *
 	}
 
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	translated = applyPayloadConfigWithRoot(e.cfg, baseModel, "antigravity", "request", translated, originalTranslated, requestedModel)
+	translated = applyPayloadConfigWithRoot(ctx, e.cfg, baseModel, "antigravity", "request", translated, originalTranslated, requestedModel)
 
 	baseURLs := antigravityBaseURLFallbackOrder(auth)
 	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
@@ -678,7 +679,7 @@ func (e *AntigravityExecutor) ExecuteStream(ctx context.Context, auth *cliproxya
 	}
 
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	translated = applyPayloadConfigWithRoot(e.cfg, baseModel, "antigravity", "request", translated, originalTranslated, requestedModel)
+	translated = applyPayloadConfigWithRoot(ctx, e.cfg, baseModel, "antigravity", "request", translated, originalTranslated, requestedModel)
 
 	baseURLs := antigravityBaseURLFallbackOrder(auth)
 	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
@@ -1320,6 +1321,9 @@ func (e *AntigravityExecutor) buildRequest(ctx context.Context, auth *cliproxyau
 
 	if strings.Contains(modelName, "claude") {
 		payload, _ = sjson.SetBytes(payload, "request.toolConfig.functionCallingConfig.mode", "VALIDATED")
+		var dropped []string
+		payload, dropped = stripAntigravityUnsupportedParams(payload)
+		reportDroppedParams(ctx, dropped)
 	} else {
 		payload, _ = sjson.DeleteBytes(payload, "request.generationConfig.maxOutputTokens")
 	}
@@ -1562,6 +1566,31 @@ func geminiToAntigravity(modelName string, payload []byte, projectID string) []b
 	return []byte(template)
 }
 
+// antigravityUnsupportedClaudeParams lists generationConfig fields that Claude models
+// served through Antigravity reject, keyed by their OpenAI request name.
+var antigravityUnsupportedClaudeParams = []struct {
+	path string
+	name string
+}{
+	{path: "request.generationConfig.seed", name: "seed"},
+	{path: "request.generationConfig.presencePenalty", name: "presence_penalty"},
+	{path: "request.generationConfig.frequencyPenalty", name: "frequency_penalty"},
+}
+
+// stripAntigravityUnsupportedParams removes sampling fields the Claude backend does not
+// accept and returns the names of the parameters that were dropped.
+func stripAntigravityUnsupportedParams(payload []byte) ([]byte, []string) {
+	var dropped []string
+	for _, param := range antigravityUnsupportedClaudeParams {
+		if !gjson.GetBytes(payload, param.path).Exists() {
+			continue
+		}
+		payload, _ = sjson.DeleteBytes(payload, param.path)
+		dropped = append(dropped, param.name)
+	}
+	return payload, dropped
+}
+
 func generateRequestID() string {
 	return "agent-" + uuid.NewString()
 }
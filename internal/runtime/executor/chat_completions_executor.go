@@ -0,0 +1,322 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencount"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/sjson"
+)
+
+// chatCompletionsExecutor is the shared implementation of API-key providers that speak a dialect
+// of OpenAI Chat Completions. Requests are translated to Chat Completions and passed through the
+// provider hooks, which adapt the body and responses to the provider's differences.
+type chatCompletionsExecutor struct {
+	cfg            *config.Config
+	provider       string
+	defaultBaseURL string
+	userAgent      string
+	// keyless allows requests without an API key, for local upstreams.
+	keyless bool
+
+	// normalizeRequest adapts a translated request body for the upstream model.
+	normalizeRequest func(body []byte, model string) []byte
+	// normalizeResponse adapts a non-streaming response before it is translated back.
+	normalizeResponse func(data []byte) []byte
+	// normalizeStreamLine adapts one SSE data line before it is translated back.
+	normalizeStreamLine func(line []byte) []byte
+	// resolveCompletion turns a non-streaming response into the final completion, e.g. by
+	// polling for results the upstream computes asynchronously.
+	resolveCompletion func(ctx context.Context, auth *cliproxyauth.Auth, data []byte) ([]byte, error)
+}
+
+// Identifier returns the provider key.
+func (e *chatCompletionsExecutor) Identifier() string { return e.provider }
+
+// PrepareRequest injects the API key and configured headers into the outgoing HTTP request.
+func (e *chatCompletionsExecutor) PrepareRequest(req *http.Request, auth *cliproxyauth.Auth) error {
+	if req == nil {
+		return nil
+	}
+	apiKey, _ := e.credentials(auth)
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	var attrs map[string]string
+	if auth != nil {
+		attrs = auth.Attributes
+	}
+	util.ApplyCustomHeadersFromAttrs(req, attrs)
+	return nil
+}
+
+// HttpRequest injects credentials into the request and executes it.
+func (e *chatCompletionsExecutor) HttpRequest(ctx context.Context, auth *cliproxyauth.Auth, req *http.Request) (*http.Response, error) {
+	if req == nil {
+		return nil, fmt.Errorf("%s executor: request is nil", e.provider)
+	}
+	if ctx == nil {
+		ctx = req.Context()
+	}
+	httpReq := req.WithContext(ctx)
+	if err := e.PrepareRequest(httpReq, auth); err != nil {
+		return nil, err
+	}
+	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
+	return httpClient.Do(httpReq)
+}
+
+// Execute performs a non-streaming chat completion request.
+func (e *chatCompletionsExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
+	if opts.Alt == "responses/compact" {
+		return resp, statusErr{code: http.StatusNotImplemented, msg: "/responses/compact not supported"}
+	}
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+
+	reporter := newUsageReporter(ctx, e.Identifier(), baseModel, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	body, err := e.prepareBody(ctx, req, opts, false)
+	if err != nil {
+		return resp, err
+	}
+	httpResp, err := e.send(ctx, auth, http.MethodPost, "/chat/completions", body, false)
+	if err != nil {
+		return resp, err
+	}
+	defer func() {
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("%s executor: close response body error: %v", e.provider, errClose)
+		}
+	}()
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		recordAPIResponseError(ctx, e.cfg, err)
+		return resp, err
+	}
+	appendAPIResponseChunk(ctx, e.cfg, data)
+	if e.resolveCompletion != nil {
+		if data, err = e.resolveCompletion(ctx, auth, data); err != nil {
+			return resp, err
+		}
+	}
+	reporter.publish(ctx, parseOpenAIUsage(data))
+	reporter.ensurePublished(ctx)
+
+	if e.normalizeResponse != nil {
+		data = e.normalizeResponse(data)
+	}
+	var param any
+	out := sdktranslator.TranslateNonStream(ctx, sdktranslator.FromString("openai"), opts.SourceFormat, req.Model, bytes.Clone(opts.OriginalRequest), body, data, &param)
+	resp = cliproxyexecutor.Response{Payload: []byte(out)}
+	return resp, nil
+}
+
+// ExecuteStream performs a streaming chat completion request.
+func (e *chatCompletionsExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (stream <-chan cliproxyexecutor.StreamChunk, err error) {
+	if opts.Alt == "responses/compact" {
+		return nil, statusErr{code: http.StatusNotImplemented, msg: "/responses/compact not supported"}
+	}
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+
+	reporter := newUsageReporter(ctx, e.Identifier(), baseModel, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	body, err := e.prepareBody(ctx, req, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := e.send(ctx, auth, http.MethodPost, "/chat/completions", body, true)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan cliproxyexecutor.StreamChunk)
+	stream = out
+	go func() {
+		defer close(out)
+		defer func() {
+			if errClose := httpResp.Body.Close(); errClose != nil {
+				log.Errorf("%s executor: close response body error: %v", e.provider, errClose)
+			}
+		}()
+
+		scanner := bufio.NewScanner(httpResp.Body)
+		scanner.Buffer(nil, 52_428_800) // 50MB
+		var param any
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			appendAPIResponseChunk(ctx, e.cfg, line)
+			if detail, ok := parseOpenAIStreamUsage(line); ok {
+				reporter.publish(ctx, detail)
+			}
+			if !bytes.HasPrefix(line, []byte("data:")) {
+				continue
+			}
+			line = bytes.Clone(line)
+			if e.normalizeStreamLine != nil {
+				line = e.normalizeStreamLine(line)
+			}
+			chunks := sdktranslator.TranslateStream(ctx, sdktranslator.FromString("openai"), opts.SourceFormat, req.Model, bytes.Clone(opts.OriginalRequest), body, line, &param)
+			for i := range chunks {
+				out <- cliproxyexecutor.StreamChunk{Payload: []byte(chunks[i])}
+			}
+		}
+		if errScan := scanner.Err(); errScan != nil {
+			recordAPIResponseError(ctx, e.cfg, errScan)
+			reporter.publishFailure(ctx)
+			out <- cliproxyexecutor.StreamChunk{Err: errScan}
+		}
+		reporter.ensurePublished(ctx)
+	}()
+	return stream, nil
+}
+
+// CountTokens estimates the prompt tokens of a request locally.
+func (e *chatCompletionsExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+
+	from := opts.SourceFormat
+	to := sdktranslator.FromString("openai")
+	body := sdktranslator.TranslateRequest(from, to, baseModel, bytes.Clone(req.Payload), false)
+
+	enc, err := tokencount.NewTokenizer(baseModel)
+	if err != nil {
+		return cliproxyexecutor.Response{}, fmt.Errorf("%s executor: tokenizer init failed: %w", e.provider, err)
+	}
+	count, err := tokencount.CountOpenAIChat(enc, body)
+	if err != nil {
+		return cliproxyexecutor.Response{}, fmt.Errorf("%s executor: token counting failed: %w", e.provider, err)
+	}
+
+	usageJSON := tokencount.OpenAIUsageJSON(count)
+	translated := sdktranslator.TranslateTokenCount(ctx, to, from, count, usageJSON)
+	return cliproxyexecutor.Response{Payload: []byte(translated)}, nil
+}
+
+// Refresh is a no-op for API-key credentials.
+func (e *chatCompletionsExecutor) Refresh(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.Auth, error) {
+	log.Debugf("%s executor: refresh called", e.provider)
+	_ = ctx
+	return auth, nil
+}
+
+// prepareBody translates the request to Chat Completions and applies the provider dialect.
+func (e *chatCompletionsExecutor) prepareBody(ctx context.Context, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, stream bool) ([]byte, error) {
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+	from := opts.SourceFormat
+	to := sdktranslator.FromString("openai")
+	originalPayload := bytes.Clone(req.Payload)
+	if len(opts.OriginalRequest) > 0 {
+		originalPayload = bytes.Clone(opts.OriginalRequest)
+	}
+	originalTranslated := sdktranslator.TranslateRequest(from, to, baseModel, originalPayload, stream)
+	body := sdktranslator.TranslateRequest(from, to, baseModel, bytes.Clone(req.Payload), stream)
+	body, _ = sjson.SetBytes(body, "model", baseModel)
+
+	body, err := thinking.ApplyThinking(body, req.Model, from.String(), to.String(), e.Identifier())
+	if err != nil {
+		return nil, err
+	}
+	requestedModel := payloadRequestedModel(opts, req.Model)
+	body = applyPayloadConfigWithRoot(ctx, e.cfg, baseModel, to.String(), "", body, originalTranslated, requestedModel)
+	if e.normalizeRequest != nil {
+		body = e.normalizeRequest(body, baseModel)
+	}
+	return body, nil
+}
+
+// send issues a request to path below the base URL and returns the response when it succeeded.
+// A nil body sends no payload.
+func (e *chatCompletionsExecutor) send(ctx context.Context, auth *cliproxyauth.Auth, method, path string, body []byte, stream bool) (*http.Response, error) {
+	apiKey, baseURL := e.credentials(auth)
+	if apiKey == "" && !e.keyless {
+		return nil, statusErr{code: http.StatusUnauthorized, msg: fmt.Sprintf("%s executor: missing api key", e.provider)}
+	}
+	url := strings.TrimSuffix(baseURL, "/") + path
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	httpReq.Header.Set("User-Agent", e.userAgent)
+	var attrs map[string]string
+	if auth != nil {
+		attrs = auth.Attributes
+	}
+	util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
+	if stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+		httpReq.Header.Set("Cache-Control", "no-cache")
+	} else {
+		httpReq.Header.Set("Accept", "application/json")
+	}
+	var authID, authLabel, authType, authValue string
+	if auth != nil {
+		authID = auth.ID
+		authLabel = auth.Label
+		authType, authValue = auth.AccountInfo()
+	}
+	recordAPIRequest(ctx, e.cfg, upstreamRequestLog{
+		URL:       url,
+		Method:    method,
+		Headers:   httpReq.Header.Clone(),
+		Body:      body,
+		Provider:  e.Identifier(),
+		AuthID:    authID,
+		AuthLabel: authLabel,
+		AuthType:  authType,
+		AuthValue: authValue,
+	})
+
+	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		recordAPIResponseError(ctx, e.cfg, err)
+		return nil, err
+	}
+	recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		b, _ := io.ReadAll(httpResp.Body)
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("%s executor: close response body error: %v", e.provider, errClose)
+		}
+		appendAPIResponseChunk(ctx, e.cfg, b)
+		logWithRequestID(ctx).Debugf("request error, error status: %d, error message: %s", httpResp.StatusCode, summarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
+		return nil, statusErr{code: httpResp.StatusCode, msg: string(b)}
+	}
+	return httpResp, nil
+}
+
+func (e *chatCompletionsExecutor) credentials(auth *cliproxyauth.Auth) (apiKey, baseURL string) {
+	if auth != nil && auth.Attributes != nil {
+		apiKey = strings.TrimSpace(auth.Attributes["api_key"])
+		baseURL = strings.TrimSpace(auth.Attributes["base_url"])
+	}
+	if baseURL == "" {
+		baseURL = e.defaultBaseURL
+	}
+	return apiKey, baseURL
+}
@@ -0,0 +1,62 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	deepSeekDefaultBaseURL = "https://api.deepseek.com"
+	deepSeekUserAgent      = "cli-proxy-deepseek"
+)
+
+// DeepSeekExecutor executes chat completions against the DeepSeek API using API keys.
+// Requests are translated to Chat Completions; reasoning output is returned to clients as
+// reasoning_content and stripped from the history sent back upstream.
+type DeepSeekExecutor struct {
+	*chatCompletionsExecutor
+}
+
+// NewDeepSeekExecutor constructs a new executor instance.
+func NewDeepSeekExecutor(cfg *config.Config) *DeepSeekExecutor {
+	return &DeepSeekExecutor{&chatCompletionsExecutor{
+		cfg:              cfg,
+		provider:         "deepseek",
+		defaultBaseURL:   deepSeekDefaultBaseURL,
+		userAgent:        deepSeekUserAgent,
+		normalizeRequest: normalizeDeepSeekRequest,
+	}}
+}
+
+// normalizeDeepSeekRequest adapts a Chat Completions body to DeepSeek. DeepSeek selects thinking
+// by model rather than by reasoning_effort, so a requested effort switches between deepseek-chat
+// and deepseek-reasoner. reasoning_content echoed back in the history is rejected upstream and
+// is removed, as are the logprobs options the reasoner does not accept.
+func normalizeDeepSeekRequest(body []byte, model string) []byte {
+	if effort := gjson.GetBytes(body, "reasoning_effort"); effort.Exists() {
+		switch strings.ToLower(model) {
+		case "deepseek-chat", "deepseek-reasoner":
+			model = "deepseek-reasoner"
+			if strings.EqualFold(strings.TrimSpace(effort.String()), "none") {
+				model = "deepseek-chat"
+			}
+			body, _ = sjson.SetBytes(body, "model", model)
+		}
+		body, _ = sjson.DeleteBytes(body, "reasoning_effort")
+	}
+	if strings.EqualFold(model, "deepseek-reasoner") {
+		for _, field := range []string{"logprobs", "top_logprobs"} {
+			body, _ = sjson.DeleteBytes(body, field)
+		}
+	}
+	for i, message := range gjson.GetBytes(body, "messages").Array() {
+		if message.Get("reasoning_content").Exists() {
+			body, _ = sjson.DeleteBytes(body, fmt.Sprintf("messages.%d.reasoning_content", i))
+		}
+	}
+	return body
+}
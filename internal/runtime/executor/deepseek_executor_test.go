@@ -0,0 +1,72 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestNormalizeDeepSeekRequest(t *testing.T) {
+	body := []byte(`{"model":"deepseek-chat","reasoning_effort":"high","logprobs":true,"messages":[{"role":"assistant","content":"a","reasoning_content":"r"}]}`)
+
+	reasoner := normalizeDeepSeekRequest(body, "deepseek-chat")
+	if gjson.GetBytes(reasoner, "model").String() != "deepseek-reasoner" || gjson.GetBytes(reasoner, "reasoning_effort").Exists() || gjson.GetBytes(reasoner, "logprobs").Exists() || gjson.GetBytes(reasoner, "messages.0.reasoning_content").Exists() {
+		t.Fatalf("reasoner body = %s", reasoner)
+	}
+	chat := normalizeDeepSeekRequest([]byte(`{"model":"deepseek-reasoner","reasoning_effort":"none"}`), "deepseek-reasoner")
+	if gjson.GetBytes(chat, "model").String() != "deepseek-chat" || gjson.GetBytes(chat, "reasoning_effort").Exists() {
+		t.Fatalf("chat body = %s", chat)
+	}
+	plain := normalizeDeepSeekRequest([]byte(`{"model":"deepseek-chat","logprobs":true}`), "deepseek-chat")
+	if gjson.GetBytes(plain, "model").String() != "deepseek-chat" || !gjson.GetBytes(plain, "logprobs").Exists() {
+		t.Fatalf("plain body = %s", plain)
+	}
+}
+
+func TestDeepSeekExecutorRoutesClaudeThinkingToReasoner(t *testing.T) {
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		gotBody, _ = io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, `{"id":"c1","model":"deepseek-reasoner","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","reasoning_content":"think","content":"Hi."}}],"usage":{"prompt_tokens":3,"completion_tokens":2}}`)
+	}))
+	defer server.Close()
+
+	executor := NewDeepSeekExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{Provider: "deepseek", Attributes: map[string]string{"api_key": "dk", "base_url": server.URL}}
+	payload := []byte(`{"model":"deepseek-chat","max_tokens":2048,"thinking":{"type":"enabled","budget_tokens":1024},"messages":[
+		{"role":"user","content":"hi"},
+		{"role":"assistant","content":[{"type":"thinking","thinking":"earlier","signature":"s"},{"type":"text","text":"hello"}]},
+		{"role":"user","content":"again"}
+	]}`)
+	resp, err := executor.Execute(context.Background(), auth, cliproxyexecutor.Request{Model: "deepseek-chat", Payload: payload}, cliproxyexecutor.Options{
+		SourceFormat:    sdktranslator.FromString("claude"),
+		OriginalRequest: payload,
+	})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if gjson.GetBytes(gotBody, "model").String() != "deepseek-reasoner" || gjson.GetBytes(gotBody, "reasoning_effort").Exists() {
+		t.Fatalf("upstream body = %s", gotBody)
+	}
+	for _, message := range gjson.GetBytes(gotBody, "messages").Array() {
+		if message.Get("reasoning_content").Exists() {
+			t.Fatalf("history kept reasoning: %s", gotBody)
+		}
+	}
+	out := gjson.ParseBytes(resp.Payload)
+	if out.Get("content.0.thinking").String() != "think" || out.Get("content.1.text").String() != "Hi." {
+		t.Fatalf("claude response = %s", resp.Payload)
+	}
+}
@@ -14,9 +14,11 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/gemini"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/geminicli"
@@ -48,6 +50,9 @@ var geminiOAuthScopes = []string{
 // GeminiCLIExecutor talks to the Cloud Code Assist endpoint using OAuth credentials from auth metadata.
 type GeminiCLIExecutor struct {
 	cfg *config.Config
+
+	// onProjectDiscovered persists a project id discovered for an auth without one.
+	onProjectDiscovered func(ctx context.Context, authID, projectID string)
 }
 
 // NewGeminiCLIExecutor creates a new Gemini CLI executor instance.
@@ -61,6 +66,12 @@ func NewGeminiCLIExecutor(cfg *config.Config) *GeminiCLIExecutor {
 	return &GeminiCLIExecutor{cfg: cfg}
 }
 
+// SetProjectDiscoveredHandler registers fn to persist project ids discovered for auths whose
+// metadata has none. Without a handler the id is only kept in memory.
+func (e *GeminiCLIExecutor) SetProjectDiscoveredHandler(fn func(ctx context.Context, authID, projectID string)) {
+	e.onProjectDiscovered = fn
+}
+
 // Identifier returns the executor identifier.
 func (e *GeminiCLIExecutor) Identifier() string { return "gemini-cli" }
 
@@ -133,7 +144,7 @@ func (e *GeminiCLIExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth
 
 	basePayload = fixGeminiCLIImageAspectRatio(baseModel, basePayload)
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	basePayload = applyPayloadConfigWithRoot(e.cfg, baseModel, "gemini", "request", basePayload, originalTranslated, requestedModel)
+	basePayload = applyPayloadConfigWithRoot(ctx, e.cfg, baseModel, "gemini", "request", basePayload, originalTranslated, requestedModel)
 
 	action := "generateContent"
 	if req.Metadata != nil {
@@ -142,13 +153,16 @@ func (e *GeminiCLIExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth
 		}
 	}
 
+	httpClient := newHTTPClient(ctx, e.cfg, auth, 0)
 	projectID := resolveGeminiProjectID(auth)
+	if projectID == "" && action != "countTokens" {
+		projectID = e.discoverProjectID(ctx, auth, httpClient, tokenSource)
+	}
 	models := cliPreviewFallbackOrder(baseModel)
 	if len(models) == 0 || models[0] != baseModel {
 		models = append([]string{baseModel}, models...)
 	}
 
-	httpClient := newHTTPClient(ctx, e.cfg, auth, 0)
 	respCtx := context.WithValue(ctx, "alt", opts.Alt)
 
 	var authID, authLabel, authType, authValue string
@@ -286,16 +300,19 @@ func (e *GeminiCLIExecutor) ExecuteStream(ctx context.Context, auth *cliproxyaut
 
 	basePayload = fixGeminiCLIImageAspectRatio(baseModel, basePayload)
 	requestedModel := payloadRequestedModel(opts, req.Model)
-	basePayload = applyPayloadConfigWithRoot(e.cfg, baseModel, "gemini", "request", basePayload, originalTranslated, requestedModel)
+	basePayload = applyPayloadConfigWithRoot(ctx, e.cfg, baseModel, "gemini", "request", basePayload, originalTranslated, requestedModel)
 
+	httpClient := newHTTPClient(ctx, e.cfg, auth, 0)
 	projectID := resolveGeminiProjectID(auth)
+	if projectID == "" {
+		projectID = e.discoverProjectID(ctx, auth, httpClient, tokenSource)
+	}
 
 	models := cliPreviewFallbackOrder(baseModel)
 	if len(models) == 0 || models[0] != baseModel {
 		models = append([]string{baseModel}, models...)
 	}
 
-	httpClient := newHTTPClient(ctx, e.cfg, auth, 0)
 	respCtx := context.WithValue(ctx, "alt", opts.Alt)
 
 	var authID, authLabel, authType, authValue string
@@ -694,6 +711,60 @@ func resolveGeminiProjectID(auth *cliproxyauth.Auth) string {
 	return strings.TrimSpace(stringValue(auth.Metadata, "project_id"))
 }
 
+// geminiDiscovery serializes project discovery and remembers discovered ids by auth ID, so
+// concurrent requests of a new account onboard it once and failures are not retried on every
+// request.
+var geminiDiscovery = struct {
+	sync.Mutex
+	projects map[string]string
+	failures map[string]time.Time
+}{projects: make(map[string]string), failures: make(map[string]time.Time)}
+
+const geminiDiscoveryRetryDelay = 5 * time.Minute
+
+// discoverProjectID resolves the project of an auth whose metadata has none through
+// loadCodeAssist/onboardUser, stores it in the auth metadata and hands it to the persistence
+// handler. It returns "" when discovery fails; the upstream then reports the missing project.
+func (e *GeminiCLIExecutor) discoverProjectID(ctx context.Context, auth *cliproxyauth.Auth, httpClient *http.Client, tokenSource oauth2.TokenSource) string {
+	if auth == nil || auth.ID == "" {
+		return ""
+	}
+	if _, virtual := auth.Runtime.(*geminicli.VirtualCredential); virtual {
+		return ""
+	}
+
+	geminiDiscovery.Lock()
+	defer geminiDiscovery.Unlock()
+	projectID := geminiDiscovery.projects[auth.ID]
+	if projectID == "" {
+		if failedAt, ok := geminiDiscovery.failures[auth.ID]; ok && time.Since(failedAt) < geminiDiscoveryRetryDelay {
+			return ""
+		}
+		tok, errTok := tokenSource.Token()
+		if errTok != nil {
+			return ""
+		}
+		discovered, errDiscover := gemini.DiscoverProject(ctx, httpClient, tok.AccessToken)
+		if errDiscover != nil {
+			geminiDiscovery.failures[auth.ID] = time.Now()
+			log.Warnf("gemini cli executor: discover project for %s failed: %v", auth.ID, errDiscover)
+			return ""
+		}
+		delete(geminiDiscovery.failures, auth.ID)
+		geminiDiscovery.projects[auth.ID] = discovered
+		projectID = discovered
+		log.Infof("gemini cli executor: discovered project %s for %s", projectID, auth.ID)
+		if e.onProjectDiscovered != nil {
+			e.onProjectDiscovered(context.WithoutCancel(ctx), auth.ID, projectID)
+		}
+	}
+	if auth.Metadata == nil {
+		auth.Metadata = make(map[string]any)
+	}
+	auth.Metadata["project_id"] = projectID
+	return projectID
+}
+
 func geminiOAuthMetadata(auth *cliproxyauth.Auth) map[string]any {
 	if auth == nil {
 		return nil
@@ -0,0 +1,181 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type geminiContextCacheEntry struct {
+	Name   string // empty when creation failed; the prefix is not retried until Expire
+	Expire time.Time
+}
+
+// geminiContextCache maps auth ID + cached prefix hash to a Gemini cachedContents resource.
+// Protected by geminiContextCacheMu.
+var (
+	geminiContextCache   = make(map[string]geminiContextCacheEntry)
+	geminiContextCacheMu sync.Mutex
+)
+
+// claudeSystemCacheTTL reports whether a Claude request marks any system block with
+// cache_control and returns the matching Gemini cache TTL ("5m" default, "1h" when requested).
+func claudeSystemCacheTTL(payload []byte) (time.Duration, bool) {
+	system := gjson.GetBytes(payload, "system")
+	if !system.IsArray() {
+		return 0, false
+	}
+	ttl := time.Duration(0)
+	system.ForEach(func(_, block gjson.Result) bool {
+		cacheControl := block.Get("cache_control")
+		if !cacheControl.Exists() {
+			return true
+		}
+		blockTTL := 5 * time.Minute
+		if cacheControl.Get("ttl").String() == "1h" {
+			blockTTL = time.Hour
+		}
+		if blockTTL > ttl {
+			ttl = blockTTL
+		}
+		return true
+	})
+	return ttl, ttl > 0
+}
+
+// applyGeminiContextCache honours Claude cache_control breakpoints on system blocks by moving
+// the system instruction and tool declarations into a Gemini cachedContents resource and
+// referencing it from the request. Gemini requires those fields to live in the cache rather
+// than the request, so the block order of the system prompt is kept intact inside the cache.
+// It only runs when gemini-context-cache is enabled, because cached contents are billed storage,
+// and only for generation calls: countTokens does not accept cachedContent. Any failure leaves
+// the request unchanged.
+func (e *GeminiExecutor) applyGeminiContextCache(ctx context.Context, auth *cliproxyauth.Auth, baseModel, action string, from sdktranslator.Format, sourcePayload, body []byte) []byte {
+	if e.cfg == nil || !e.cfg.GeminiContextCache || from != sdktranslator.FormatClaude {
+		return body
+	}
+	if action != "generateContent" && action != "streamGenerateContent" {
+		return body
+	}
+	ttl, ok := claudeSystemCacheTTL(sourcePayload)
+	if !ok {
+		return body
+	}
+	systemField := "system_instruction"
+	system := gjson.GetBytes(body, systemField)
+	if !system.Exists() {
+		systemField = "systemInstruction"
+		system = gjson.GetBytes(body, systemField)
+	}
+	if !system.Exists() || gjson.GetBytes(body, "cachedContent").Exists() {
+		return body
+	}
+
+	cacheBody := []byte(`{}`)
+	cacheBody, _ = sjson.SetBytes(cacheBody, "model", "models/"+baseModel)
+	cacheBody, _ = sjson.SetRawBytes(cacheBody, "systemInstruction", []byte(system.Raw))
+	for _, field := range []string{"tools", "toolConfig", "tool_config"} {
+		if value := gjson.GetBytes(body, field); value.Exists() {
+			cacheBody, _ = sjson.SetRawBytes(cacheBody, field, []byte(value.Raw))
+		}
+	}
+	authID := ""
+	if auth != nil {
+		authID = auth.ID
+	}
+	// Canonical hashing lets clients that serialize the same prefix differently share a cache.
+	key := authID + ":" + util.CanonicalHash(cacheBody)
+
+	name, cached := lookupGeminiContextCache(key)
+	if !cached {
+		var expire time.Time
+		var err error
+		name, expire, err = e.createGeminiContextCache(ctx, auth, cacheBody, ttl)
+		if err == nil {
+			// Stop reusing the cache shortly before it expires upstream.
+			storeGeminiContextCache(key, geminiContextCacheEntry{Name: name, Expire: expire.Add(-30 * time.Second)})
+		} else {
+			// Prompts below the model's minimum cacheable size are rejected; remember the
+			// failure so the same prefix does not trigger a create call on every request.
+			logWithRequestID(ctx).Debugf("gemini executor: context cache not created: %v", err)
+			storeGeminiContextCache(key, geminiContextCacheEntry{Expire: time.Now().Add(ttl)})
+			return body
+		}
+	}
+	if name == "" {
+		return body
+	}
+
+	out, _ := sjson.DeleteBytes(body, systemField)
+	for _, field := range []string{"tools", "toolConfig", "tool_config"} {
+		out, _ = sjson.DeleteBytes(out, field)
+	}
+	out, _ = sjson.SetBytes(out, "cachedContent", name)
+	return out
+}
+
+// createGeminiContextCache creates a cachedContents resource and returns its name and expiry.
+func (e *GeminiExecutor) createGeminiContextCache(ctx context.Context, auth *cliproxyauth.Auth, cacheBody []byte, ttl time.Duration) (string, time.Time, error) {
+	cacheBody, _ = sjson.SetBytes(cacheBody, "ttl", fmt.Sprintf("%ds", int(ttl.Seconds())))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, resolveGeminiBaseURL(auth)+"/"+glAPIVersion+"/cachedContents", bytes.NewReader(cacheBody))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	apiKey, bearer := geminiCreds(auth)
+	if apiKey != "" {
+		req.Header.Set("x-goog-api-key", apiKey)
+	} else if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	applyGeminiHeaders(req, auth)
+
+	resp, err := doGeminiAPIRequest(newProxyAwareHTTPClient(ctx, e.cfg, auth, 0), req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	name := gjson.GetBytes(resp.body, "name").String()
+	if name == "" {
+		return "", time.Time{}, fmt.Errorf("cachedContents response missing name")
+	}
+	expire := time.Now().Add(ttl)
+	if t, errParse := time.Parse(time.RFC3339Nano, gjson.GetBytes(resp.body, "expireTime").String()); errParse == nil {
+		expire = t
+	}
+	return name, expire, nil
+}
+
+func lookupGeminiContextCache(key string) (string, bool) {
+	geminiContextCacheMu.Lock()
+	defer geminiContextCacheMu.Unlock()
+	entry, ok := geminiContextCache[key]
+	if !ok {
+		return "", false
+	}
+	if entry.Expire.Before(time.Now()) {
+		delete(geminiContextCache, key)
+		return "", false
+	}
+	return entry.Name, true
+}
+
+func storeGeminiContextCache(key string, entry geminiContextCacheEntry) {
+	geminiContextCacheMu.Lock()
+	defer geminiContextCacheMu.Unlock()
+	now := time.Now()
+	for k, v := range geminiContextCache {
+		if v.Expire.Before(now) {
+			delete(geminiContextCache, k)
+		}
+	}
+	geminiContextCache[key] = entry
+}
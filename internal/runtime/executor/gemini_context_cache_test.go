@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestGeminiContextCacheFromClaudeBreakpoints(t *testing.T) {
+	var creates int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/cachedContents" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		atomic.AddInt32(&creates, 1)
+		body, _ := io.ReadAll(r.Body)
+		parts := gjson.GetBytes(body, "systemInstruction.parts").Array()
+		if len(parts) != 2 || parts[0].Get("text").String() != "first" || parts[1].Get("text").String() != "second" {
+			t.Errorf("system blocks not preserved in order: %s", body)
+		}
+		if got := gjson.GetBytes(body, "ttl").String(); got != "3600s" {
+			t.Errorf("expected ttl 3600s, got %q", got)
+		}
+		if !gjson.GetBytes(body, "tools").Exists() {
+			t.Errorf("expected tools to be part of the cached prefix")
+		}
+		_, _ = w.Write([]byte(`{"name":"cachedContents/xyz"}`))
+	}))
+	defer server.Close()
+
+	auth := &cliproxyauth.Auth{ID: "gemini-cache-test", Attributes: map[string]string{"api_key": "k", "base_url": server.URL}}
+	e := NewGeminiExecutor(&config.Config{GeminiContextCache: true})
+
+	claudeReq := []byte(`{"system":[{"type":"text","text":"first"},{"type":"text","text":"second","cache_control":{"type":"ephemeral","ttl":"1h"}}],"messages":[]}`)
+	body := []byte(`{"system_instruction":{"role":"user","parts":[{"text":"first"},{"text":"second"}]},"tools":[{"functionDeclarations":[]}],"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+
+	for i := 0; i < 2; i++ {
+		out := e.applyGeminiContextCache(context.Background(), auth, "gemini-2.5-pro", "streamGenerateContent", sdktranslator.FormatClaude, claudeReq, body)
+		if got := gjson.GetBytes(out, "cachedContent").String(); got != "cachedContents/xyz" {
+			t.Fatalf("expected cachedContent reference, got %q", got)
+		}
+		if gjson.GetBytes(out, "system_instruction").Exists() || gjson.GetBytes(out, "tools").Exists() {
+			t.Fatalf("cached fields must be removed from the request: %s", out)
+		}
+	}
+	if got := atomic.LoadInt32(&creates); got != 1 {
+		t.Fatalf("expected a single cache creation, got %d", got)
+	}
+
+	// Requests without breakpoints are left untouched.
+	plain := []byte(`{"system":[{"type":"text","text":"first"}]}`)
+	if out := e.applyGeminiContextCache(context.Background(), auth, "gemini-2.5-pro", "generateContent", sdktranslator.FormatClaude, plain, body); string(out) != string(body) {
+		t.Fatalf("expected request without cache_control to be unchanged")
+	}
+
+	// countTokens never references a cache.
+	if out := e.applyGeminiContextCache(context.Background(), auth, "gemini-2.5-pro", "countTokens", sdktranslator.FormatClaude, claudeReq, body); string(out) != string(body) {
+		t.Fatalf("expected countTokens request to be unchanged")
+	}
+}
+
+func TestGeminiContextCacheRequiresOptIn(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected cache creation %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	auth := &cliproxyauth.Auth{ID: "gemini-cache-off", Attributes: map[string]string{"api_key": "k", "base_url": server.URL}}
+	e := NewGeminiExecutor(&config.Config{})
+	claudeReq := []byte(`{"system":[{"type":"text","text":"first","cache_control":{"type":"ephemeral"}}],"messages":[]}`)
+	body := []byte(`{"system_instruction":{"parts":[{"text":"first"}]},"contents":[]}`)
+	for _, action := range []string{"generateContent", "streamGenerateContent"} {
+		if out := e.applyGeminiContextCache(context.Background(), auth, "gemini-2.5-pro", action, sdktranslator.FormatClaude, claudeReq, body); string(out) != string(body) {
+			t.Fatalf("%s: expected request to be unchanged without gemini-context-cache", action)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// geminiInlineRequestLimit is the request size above which inline attachments are
+	// moved to the Files API. Gemini rejects generateContent bodies larger than ~20MB.
+	geminiInlineRequestLimit = 20 << 20
+
+	// geminiFileDefaultTTL is used when the upload response has no expirationTime.
+	// Uploaded files are retained by Gemini for 48 hours.
+	geminiFileDefaultTTL = 47 * time.Hour
+
+	// geminiFileActivationTimeout bounds how long we wait for an upload to become ACTIVE.
+	geminiFileActivationTimeout = 60 * time.Second
+)
+
+type geminiUploadedFile struct {
+	URI    string
+	Expire time.Time
+}
+
+// geminiFileCache stores Files API URIs keyed by auth ID + content hash so identical
+// attachments are uploaded once per account. Protected by geminiFileCacheMu.
+var (
+	geminiFileCache   = make(map[string]geminiUploadedFile)
+	geminiFileCacheMu sync.Mutex
+)
+
+func getGeminiUploadedFile(key string) (geminiUploadedFile, bool) {
+	geminiFileCacheMu.Lock()
+	defer geminiFileCacheMu.Unlock()
+	file, ok := geminiFileCache[key]
+	if !ok {
+		return geminiUploadedFile{}, false
+	}
+	if file.Expire.Before(time.Now()) {
+		delete(geminiFileCache, key)
+		return geminiUploadedFile{}, false
+	}
+	return file, true
+}
+
+func setGeminiUploadedFile(key string, file geminiUploadedFile) {
+	geminiFileCacheMu.Lock()
+	defer geminiFileCacheMu.Unlock()
+	now := time.Now()
+	for k, v := range geminiFileCache {
+		if v.Expire.Before(now) {
+			delete(geminiFileCache, k)
+		}
+	}
+	geminiFileCache[key] = file
+}
+
+// geminiInlinePart locates an inlineData part inside a translated Gemini request.
+type geminiInlinePart struct {
+	path     string
+	field    string
+	mimeType string
+	data     string
+}
+
+// offloadLargeInlineData uploads inlineData attachments through the Gemini Files API when the
+// request body exceeds the inline size limit, replacing them with fileData references.
+// Largest attachments are uploaded first until the body fits. Upload failures are logged
+// and the original body is kept so the upstream can report the size error itself.
+func (e *GeminiExecutor) offloadLargeInlineData(ctx context.Context, auth *cliproxyauth.Auth, body []byte) []byte {
+	if len(body) <= geminiInlineRequestLimit {
+		return body
+	}
+	parts := collectGeminiInlineParts(body)
+	sort.SliceStable(parts, func(i, j int) bool { return len(parts[i].data) > len(parts[j].data) })
+
+	for _, part := range parts {
+		if len(body) <= geminiInlineRequestLimit {
+			break
+		}
+		uri, err := e.uploadGeminiFile(ctx, auth, part.mimeType, part.data)
+		if err != nil {
+			logWithRequestID(ctx).Warnf("gemini executor: files API upload failed, sending attachment inline: %v", err)
+			return body
+		}
+		updated, errDel := sjson.DeleteBytes(body, part.path+"."+part.field)
+		if errDel != nil {
+			continue
+		}
+		updated, _ = sjson.SetBytes(updated, part.path+".fileData.mimeType", part.mimeType)
+		updated, _ = sjson.SetBytes(updated, part.path+".fileData.fileUri", uri)
+		body = updated
+	}
+	return body
+}
+
+func collectGeminiInlineParts(body []byte) []geminiInlinePart {
+	var parts []geminiInlinePart
+	gjson.GetBytes(body, "contents").ForEach(func(contentIdx, content gjson.Result) bool {
+		content.Get("parts").ForEach(func(partIdx, part gjson.Result) bool {
+			field := "inlineData"
+			inline := part.Get(field)
+			if !inline.Exists() {
+				field = "inline_data"
+				inline = part.Get(field)
+			}
+			data := inline.Get("data").String()
+			if data == "" {
+				return true
+			}
+			mimeType := inline.Get("mimeType").String()
+			if mimeType == "" {
+				mimeType = inline.Get("mime_type").String()
+			}
+			parts = append(parts, geminiInlinePart{
+				path:     fmt.Sprintf("contents.%d.parts.%d", contentIdx.Int(), partIdx.Int()),
+				field:    field,
+				mimeType: mimeType,
+				data:     data,
+			})
+			return true
+		})
+		return true
+	})
+	return parts
+}
+
+// uploadGeminiFile uploads base64 data via the resumable Files API protocol and returns the file URI.
+func (e *GeminiExecutor) uploadGeminiFile(ctx context.Context, auth *cliproxyauth.Auth, mimeType, data string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("decode inline data: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	authID := ""
+	if auth != nil {
+		authID = auth.ID
+	}
+	cacheKey := authID + ":" + hex.EncodeToString(sum[:])
+	if cached, ok := getGeminiUploadedFile(cacheKey); ok {
+		return cached.URI, nil
+	}
+
+	apiKey, bearer := geminiCreds(auth)
+	baseURL := resolveGeminiBaseURL(auth)
+	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
+	setAuth := func(req *http.Request) {
+		if apiKey != "" {
+			req.Header.Set("x-goog-api-key", apiKey)
+		} else if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		applyGeminiHeaders(req, auth)
+	}
+
+	// Step 1: start a resumable upload session.
+	startBody, _ := sjson.SetBytes([]byte(`{"file":{}}`), "file.display_name", "cliproxy-"+hex.EncodeToString(sum[:8]))
+	startReq, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/upload/"+glAPIVersion+"/files", bytes.NewReader(startBody))
+	if err != nil {
+		return "", err
+	}
+	startReq.Header.Set("Content-Type", "application/json")
+	startReq.Header.Set("X-Goog-Upload-Protocol", "resumable")
+	startReq.Header.Set("X-Goog-Upload-Command", "start")
+	startReq.Header.Set("X-Goog-Upload-Header-Content-Length", fmt.Sprintf("%d", len(raw)))
+	startReq.Header.Set("X-Goog-Upload-Header-Content-Type", mimeType)
+	setAuth(startReq)
+	startResp, err := doGeminiAPIRequest(httpClient, startReq)
+	if err != nil {
+		return "", err
+	}
+	uploadURL := startResp.header.Get("X-Goog-Upload-URL")
+	if uploadURL == "" {
+		return "", fmt.Errorf("files API did not return an upload URL")
+	}
+
+	// Step 2: upload the bytes and finalize.
+	uploadReq, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(raw))
+	if err != nil {
+		return "", err
+	}
+	uploadReq.Header.Set("X-Goog-Upload-Offset", "0")
+	uploadReq.Header.Set("X-Goog-Upload-Command", "upload, finalize")
+	setAuth(uploadReq)
+	uploadResp, err := doGeminiAPIRequest(httpClient, uploadReq)
+	if err != nil {
+		return "", err
+	}
+	file := gjson.GetBytes(uploadResp.body, "file")
+	uri := file.Get("uri").String()
+	if uri == "" {
+		return "", fmt.Errorf("files API response missing file uri")
+	}
+
+	// Step 3: wait for processing to finish before referencing the file.
+	if state := file.Get("state").String(); state != "" && state != "ACTIVE" {
+		if err = e.waitGeminiFileActive(ctx, httpClient, baseURL, file.Get("name").String(), setAuth); err != nil {
+			return "", err
+		}
+	}
+
+	expire := time.Now().Add(geminiFileDefaultTTL)
+	if t, errParse := time.Parse(time.RFC3339Nano, file.Get("expirationTime").String()); errParse == nil {
+		expire = t.Add(-time.Hour)
+	}
+	setGeminiUploadedFile(cacheKey, geminiUploadedFile{URI: uri, Expire: expire})
+	log.Debugf("gemini executor: uploaded %d bytes (%s) to files API as %s", len(raw), mimeType, uri)
+	return uri, nil
+}
+
+func (e *GeminiExecutor) waitGeminiFileActive(ctx context.Context, httpClient *http.Client, baseURL, name string, setAuth func(*http.Request)) error {
+	if name == "" {
+		return fmt.Errorf("files API response missing file name")
+	}
+	deadline := time.Now().Add(geminiFileActivationTimeout)
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/"+glAPIVersion+"/"+strings.TrimPrefix(name, "/"), nil)
+		if err != nil {
+			return err
+		}
+		setAuth(req)
+		resp, err := doGeminiAPIRequest(httpClient, req)
+		if err != nil {
+			return err
+		}
+		switch state := gjson.GetBytes(resp.body, "state").String(); state {
+		case "ACTIVE":
+			return nil
+		case "FAILED":
+			return fmt.Errorf("files API processing failed for %s", name)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s to become active", name)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+type geminiAPIResponse struct {
+	header http.Header
+	body   []byte
+}
+
+func doGeminiAPIRequest(httpClient *http.Client, req *http.Request) (geminiAPIResponse, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return geminiAPIResponse{}, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("gemini executor: close response body error: %v", errClose)
+		}
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return geminiAPIResponse{}, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return geminiAPIResponse{}, statusErr{code: resp.StatusCode, msg: string(body)}
+	}
+	return geminiAPIResponse{header: resp.Header, body: body}, nil
+}
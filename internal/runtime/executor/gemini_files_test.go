@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+func TestGeminiOffloadLargeInlineData(t *testing.T) {
+	var uploads int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-goog-api-key") != "key-1" {
+			t.Errorf("missing api key on %s", r.URL.Path)
+		}
+		switch {
+		case r.URL.Path == "/upload/v1beta/files":
+			w.Header().Set("X-Goog-Upload-URL", server.URL+"/upload-session")
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/upload-session":
+			atomic.AddInt32(&uploads, 1)
+			_, _ = io.Copy(io.Discard, r.Body)
+			_, _ = w.Write([]byte(`{"file":{"name":"files/abc","uri":"https://files.example/abc","state":"ACTIVE"}}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	auth := &cliproxyauth.Auth{ID: "gemini-files-test", Attributes: map[string]string{"api_key": "key-1", "base_url": server.URL}}
+	e := NewGeminiExecutor(&config.Config{})
+
+	large := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 16<<20)))
+	body := []byte(`{"contents":[{"role":"user","parts":[{"text":"describe"},{"inlineData":{"mimeType":"video/mp4","data":""}},{"inlineData":{"mimeType":"image/png","data":"iVBORw0KGgo="}}]}]}`)
+	body, _ = sjson.SetBytes(body, "contents.0.parts.1.inlineData.data", large)
+
+	out := e.offloadLargeInlineData(context.Background(), auth, body)
+	if got := gjson.GetBytes(out, "contents.0.parts.1.fileData.fileUri").String(); got != "https://files.example/abc" {
+		t.Fatalf("expected large attachment to be replaced by fileData, got %q", got)
+	}
+	if gjson.GetBytes(out, "contents.0.parts.1.inlineData").Exists() {
+		t.Fatalf("inlineData should be removed from the offloaded part")
+	}
+	if got := gjson.GetBytes(out, "contents.0.parts.2.inlineData.data").String(); got != "iVBORw0KGgo=" {
+		t.Fatalf("small attachment should stay inline, got %q", got)
+	}
+
+	// Same content for the same auth is served from cache.
+	_ = e.offloadLargeInlineData(context.Background(), auth, body)
+	if got := atomic.LoadInt32(&uploads); got != 1 {
+		t.Fatalf("expected a single upload, got %d", got)
+	}
+}
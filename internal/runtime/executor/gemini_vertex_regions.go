@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+// vertexRegions returns the regions to try for model: those of the first matching
+// vertex-regions rule, or the credential's own location when no rule matches.
+func vertexRegions(cfg *config.Config, model, location string) []string {
+	if cfg != nil {
+		for _, rule := range cfg.VertexRegions {
+			matched := false
+			for _, pattern := range rule.Models {
+				if util.MatchWildcard(pattern, model) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+			regions := make([]string, 0, len(rule.Regions))
+			for _, region := range rule.Regions {
+				if region = strings.TrimSpace(region); region != "" {
+					regions = append(regions, region)
+				}
+			}
+			if len(regions) > 0 {
+				return regions
+			}
+		}
+	}
+	return []string{location}
+}
+
+// isVertexCapacityError reports whether a Vertex response means the region is out of capacity
+// for the model, so another region may still serve the request.
+func isVertexCapacityError(status int, body []byte) bool {
+	return status == http.StatusTooManyRequests && bytes.Contains(body, []byte("RESOURCE_EXHAUSTED"))
+}
+
+// doVertexRegionRequest posts body to endpoint(region) for each region in turn, moving on while
+// a region reports exhausted capacity. It returns the last response together with the region
+// that produced it; the caller owns the response body.
+func (e *GeminiVertexExecutor) doVertexRegionRequest(ctx context.Context, auth *cliproxyauth.Auth, saJSON []byte, regions []string, body []byte, endpoint func(region string) string) (*http.Response, string, error) {
+	token, errTok := vertexAccessToken(ctx, e.cfg, auth, saJSON)
+	if errTok != nil {
+		log.Errorf("vertex executor: access token error: %v", errTok)
+		return nil, "", statusErr{code: 500, msg: "internal server error"}
+	}
+
+	var authID, authLabel, authType, authValue string
+	if auth != nil {
+		authID = auth.ID
+		authLabel = auth.Label
+		authType, authValue = auth.AccountInfo()
+	}
+	httpClient := newProxyAwareHTTPClient(ctx, e.cfg, auth, 0)
+
+	for i, region := range regions {
+		url := endpoint(region)
+		httpReq, errNewReq := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if errNewReq != nil {
+			return nil, region, errNewReq
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+token)
+		}
+		applyGeminiHeaders(httpReq, auth)
+
+		recordAPIRequest(ctx, e.cfg, upstreamRequestLog{
+			URL:       url,
+			Method:    http.MethodPost,
+			Headers:   httpReq.Header.Clone(),
+			Body:      body,
+			Provider:  e.Identifier(),
+			AuthID:    authID,
+			AuthLabel: authLabel,
+			AuthType:  authType,
+			AuthValue: authValue,
+		})
+
+		httpResp, errDo := httpClient.Do(httpReq)
+		if errDo != nil {
+			recordAPIResponseError(ctx, e.cfg, errDo)
+			return nil, region, errDo
+		}
+		recordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+		if i == len(regions)-1 || httpResp.StatusCode != http.StatusTooManyRequests {
+			return httpResp, region, nil
+		}
+
+		b, _ := io.ReadAll(httpResp.Body)
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("vertex executor: close response body error: %v", errClose)
+		}
+		if !isVertexCapacityError(httpResp.StatusCode, b) {
+			httpResp.Body = io.NopCloser(bytes.NewReader(b))
+			return httpResp, region, nil
+		}
+		appendAPIResponseChunk(ctx, e.cfg, b)
+		logWithRequestID(ctx).Debugf("vertex executor: region %s out of capacity, trying %s", region, regions[i+1])
+	}
+	return nil, "", statusErr{code: http.StatusServiceUnavailable, msg: "no vertex region configured"}
+}
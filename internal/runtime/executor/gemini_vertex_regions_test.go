@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestVertexRegions(t *testing.T) {
+	cfg := &config.Config{VertexRegions: []config.VertexRegionRule{
+		{Models: []string{"gemini-2.5-pro"}, Regions: []string{"us-central1", " us-east5 ", "europe-west4"}},
+		{Models: []string{"gemini-3-*"}, Regions: []string{"global"}},
+		{Models: []string{"imagen-*"}, Regions: []string{" "}},
+	}}
+
+	cases := []struct {
+		model string
+		want  []string
+	}{
+		{"gemini-2.5-pro", []string{"us-central1", "us-east5", "europe-west4"}},
+		{"gemini-3-pro-preview", []string{"global"}},
+		{"imagen-4.0-generate-001", []string{"asia-northeast1"}},
+		{"gemini-2.5-flash", []string{"asia-northeast1"}},
+	}
+	for _, tc := range cases {
+		if got := vertexRegions(cfg, tc.model, "asia-northeast1"); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("vertexRegions(%q) = %v, want %v", tc.model, got, tc.want)
+		}
+	}
+	if got := vertexRegions(nil, "gemini-2.5-pro", "us-central1"); !reflect.DeepEqual(got, []string{"us-central1"}) {
+		t.Errorf("nil config: got %v", got)
+	}
+}
+
+func TestIsVertexCapacityError(t *testing.T) {
+	exhausted := []byte(`{"error":{"code":429,"message":"Resource exhausted","status":"RESOURCE_EXHAUSTED"}}`)
+	if !isVertexCapacityError(http.StatusTooManyRequests, exhausted) {
+		t.Fatal("expected RESOURCE_EXHAUSTED 429 to be a capacity error")
+	}
+	if isVertexCapacityError(http.StatusTooManyRequests, []byte(`{"error":{"status":"PERMISSION_DENIED"}}`)) {
+		t.Fatal("429 without RESOURCE_EXHAUSTED must not fail over")
+	}
+	if isVertexCapacityError(http.StatusBadRequest, exhausted) {
+		t.Fatal("non-429 status must not fail over")
+	}
+}
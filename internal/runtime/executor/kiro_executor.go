@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"net"
 	"net/http"
@@ -22,6 +23,7 @@ import (
 	"github.com/google/uuid"
 	kiroauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kiro"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencount"
 	kiroclaude "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/kiro/claude"
 	kirocommon "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/kiro/common"
 	kiroopenai "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/kiro/openai"
@@ -293,13 +295,7 @@ func getKiroPooledHTTPClient() *http.Client {
 // This provides the best of both worlds: custom proxy support + connection reuse.
 func newKiroHTTPClientWithPooling(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, timeout time.Duration) *http.Client {
 	// Check if a proxy is configured - if so, we need a custom client
-	var proxyURL string
-	if auth != nil {
-		proxyURL = strings.TrimSpace(auth.ProxyURL)
-	}
-	if proxyURL == "" && cfg != nil {
-		proxyURL = strings.TrimSpace(cfg.ProxyURL)
-	}
+	proxyURL := resolveProxyURL(cfg, auth)
 
 	// If proxy is configured, use the existing proxy-aware client (doesn't pool)
 	if proxyURL != "" {
@@ -312,13 +308,13 @@ func newKiroHTTPClientWithPooling(ctx context.Context, cfg *config.Config, auth
 
 	// If timeout is specified, we need to wrap the pooled transport with timeout
 	if timeout > 0 {
-		return &http.Client{
+		return withRequestID(withRetryPolicy(&http.Client{
 			Transport: pooledClient.Transport,
 			Timeout:   timeout,
-		}
+		}, cfg))
 	}
 
-	return pooledClient
+	return withRequestID(withRetryPolicy(pooledClient, cfg))
 }
 
 // kiroEndpointConfig bundles endpoint URL with its compatible Origin and AmzTarget values.
@@ -978,8 +974,8 @@ func (e *KiroExecutor) executeWithRetry(ctx context.Context, auth *cliproxyauth.
 
 			// 1. Estimate InputTokens if missing
 			if usageInfo.InputTokens == 0 {
-				if enc, encErr := getTokenizer(req.Model); encErr == nil {
-					if inp, countErr := countOpenAIChatTokens(enc, opts.OriginalRequest); countErr == nil {
+				if enc, encErr := tokencount.Tokenizer(req.Model); encErr == nil {
+					if inp, countErr := tokencount.CountOpenAIChat(enc, opts.OriginalRequest); countErr == nil {
 						usageInfo.InputTokens = inp
 					}
 				}
@@ -988,7 +984,7 @@ func (e *KiroExecutor) executeWithRetry(ctx context.Context, auth *cliproxyauth.
 			// 2. Estimate OutputTokens if missing and content is available
 			if usageInfo.OutputTokens == 0 && len(content) > 0 {
 				// Use tiktoken for more accurate output token calculation
-				if enc, encErr := getTokenizer(req.Model); encErr == nil {
+				if enc, encErr := tokencount.Tokenizer(req.Model); encErr == nil {
 					if tokenCount, countErr := enc.Count(content); countErr == nil {
 						usageInfo.OutputTokens = int64(tokenCount)
 					}
@@ -2250,7 +2246,10 @@ func (e *KiroExecutor) parseEventStream(body io.Reader) (string, []kiroclaude.Ki
 // - Prelude (12 bytes): total_length (4) + headers_length (4) + prelude_crc (4)
 // - Headers (variable): header entries
 // - Payload (variable): JSON data
-// - Message CRC (4 bytes): CRC32C of entire message (not validated, just skipped)
+// - Message CRC (4 bytes): CRC32 of the prelude, headers and payload
+//
+// Both CRCs are validated so a truncated or corrupted frame is reported instead of
+// being forwarded to the client as a partial event.
 func (e *KiroExecutor) readEventStreamMessage(reader *bufio.Reader) (*eventStreamMessage, *EventStreamError) {
 	// Read prelude (first 12 bytes: total_len + headers_len + prelude_crc)
 	prelude := make([]byte, 12)
@@ -2268,7 +2267,12 @@ func (e *KiroExecutor) readEventStreamMessage(reader *bufio.Reader) (*eventStrea
 
 	totalLength := binary.BigEndian.Uint32(prelude[0:4])
 	headersLength := binary.BigEndian.Uint32(prelude[4:8])
-	// Note: prelude[8:12] is prelude_crc - we read it but don't validate (no CRC check per requirements)
+	if preludeCRC := binary.BigEndian.Uint32(prelude[8:12]); crc32.ChecksumIEEE(prelude[0:8]) != preludeCRC {
+		return nil, &EventStreamError{
+			Type:    ErrStreamMalformed,
+			Message: fmt.Sprintf("prelude checksum mismatch (expected %08x)", preludeCRC),
+		}
+	}
 
 	// Boundary check: minimum frame size
 	if totalLength < minEventStreamFrameSize {
@@ -2307,6 +2311,16 @@ func (e *KiroExecutor) readEventStreamMessage(reader *bufio.Reader) (*eventStrea
 		}
 	}
 
+	// Validate message CRC, computed over the prelude and everything before the trailing CRC
+	messageCRC := binary.BigEndian.Uint32(remaining[len(remaining)-4:])
+	checksum := crc32.Update(crc32.ChecksumIEEE(prelude), crc32.IEEETable, remaining[:len(remaining)-4])
+	if checksum != messageCRC {
+		return nil, &EventStreamError{
+			Type:    ErrStreamMalformed,
+			Message: fmt.Sprintf("message checksum mismatch (expected %08x, got %08x)", messageCRC, checksum),
+		}
+	}
+
 	// Extract event type from headers
 	// Headers start at beginning of 'remaining', length is headersLength
 	var eventType string
@@ -2440,10 +2454,12 @@ func (e *KiroExecutor) extractEventTypeFromBytes(headers []byte) string {
 // Extracts stop_reason from upstream events when available.
 // thinkingEnabled controls whether <thinking> tags are parsed - only parse when request enabled thinking.
 func (e *KiroExecutor) streamToChannel(ctx context.Context, body io.Reader, out chan<- cliproxyexecutor.StreamChunk, targetFormat sdktranslator.Format, model string, originalReq, claudeBody []byte, reporter *usageReporter, thinkingEnabled bool) {
-	reader := bufio.NewReaderSize(body, 20*1024*1024) // 20MB buffer to match other providers
+	// Frames are length-prefixed and read with io.ReadFull, so a small buffer is enough and
+	// lets each event be forwarded as soon as it arrives.
+	reader := bufio.NewReaderSize(body, 64*1024)
 	var totalUsage usage.Detail
-	var hasToolUses bool              // Track if any tool uses were emitted
-	var upstreamStopReason string     // Track stop_reason from upstream events
+	var hasToolUses bool          // Track if any tool uses were emitted
+	var upstreamStopReason string // Track stop_reason from upstream events
 
 	// Tool use state tracking for input buffering and deduplication
 	processedIDs := make(map[string]bool)
@@ -2485,15 +2501,15 @@ func (e *KiroExecutor) streamToChannel(ctx context.Context, body io.Reader, out
 
 	// Pre-calculate input tokens from request if possible
 	// Kiro uses Claude format, so try Claude format first, then OpenAI format, then fallback
-	if enc, err := getTokenizer(model); err == nil {
+	if enc, err := tokencount.Tokenizer(model); err == nil {
 		var inputTokens int64
 		var countMethod string
 
 		// Try Claude format first (Kiro uses Claude API format)
-		if inp, err := countClaudeChatTokens(enc, claudeBody); err == nil && inp > 0 {
+		if inp, err := tokencount.CountClaudeChat(enc, claudeBody); err == nil && inp > 0 {
 			inputTokens = inp
 			countMethod = "claude"
-		} else if inp, err := countOpenAIChatTokens(enc, originalReq); err == nil && inp > 0 {
+		} else if inp, err := tokencount.CountOpenAIChat(enc, originalReq); err == nil && inp > 0 {
 			// Fallback to OpenAI format (for OpenAI-compatible requests)
 			inputTokens = inp
 			countMethod = "openai"
@@ -2864,7 +2880,7 @@ func (e *KiroExecutor) streamToChannel(ctx context.Context, body io.Reader, out
 				if shouldSendUsageUpdate {
 					// Calculate current output tokens using tiktoken
 					var currentOutputTokens int64
-					if enc, encErr := getTokenizer(model); encErr == nil {
+					if enc, encErr := tokencount.Tokenizer(model); encErr == nil {
 						if tokenCount, countErr := enc.Count(accumulatedContent.String()); countErr == nil {
 							currentOutputTokens = int64(tokenCount)
 						}
@@ -3548,7 +3564,7 @@ func (e *KiroExecutor) streamToChannel(ctx context.Context, body io.Reader, out
 	// Only use local estimation if server didn't provide usage (server-side usage takes priority)
 	if totalUsage.OutputTokens == 0 && accumulatedContent.Len() > 0 {
 		// Try to use tiktoken for accurate counting
-		if enc, err := getTokenizer(model); err == nil {
+		if enc, err := tokencount.Tokenizer(model); err == nil {
 			if tokenCount, countErr := enc.Count(accumulatedContent.String()); countErr == nil {
 				totalUsage.OutputTokens = int64(tokenCount)
 				log.Debugf("kiro: streamToChannel calculated output tokens using tiktoken: %d", totalUsage.OutputTokens)
@@ -3647,45 +3663,43 @@ func (e *KiroExecutor) streamToChannel(ctx context.Context, body io.Reader, out
 // CountTokens counts tokens locally using tiktoken since Kiro API doesn't expose a token counting endpoint.
 // This provides approximate token counts for client requests.
 func (e *KiroExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	from := opts.SourceFormat
+	to := sdktranslator.FromString("openai")
+
+	// Normalise the inbound request to OpenAI chat format so Claude and OpenAI
+	// payloads are counted the same way.
+	payload := sdktranslator.TranslateRequest(from, to, req.Model, bytes.Clone(req.Payload), false)
+
 	// Use tiktoken for local token counting
-	enc, err := getTokenizer(req.Model)
+	var totalTokens int64
+	enc, err := tokencount.Tokenizer(req.Model)
 	if err != nil {
 		log.Warnf("kiro: CountTokens failed to get tokenizer: %v, falling back to estimate", err)
 		// Fallback: estimate from payload size (roughly 4 chars per token)
-		estimatedTokens := len(req.Payload) / 4
-		if estimatedTokens == 0 && len(req.Payload) > 0 {
-			estimatedTokens = 1
+		totalTokens = int64(len(payload) / 4)
+		if totalTokens == 0 && len(payload) > 0 {
+			totalTokens = 1
 		}
-		return cliproxyexecutor.Response{
-			Payload: []byte(fmt.Sprintf(`{"count":%d}`, estimatedTokens)),
-		}, nil
-	}
-
-	// Try to count tokens from the request payload
-	var totalTokens int64
-
-	// Try OpenAI chat format first
-	if tokens, countErr := countOpenAIChatTokens(enc, req.Payload); countErr == nil && tokens > 0 {
+	} else if tokens, countErr := tokencount.CountOpenAIChat(enc, payload); countErr == nil && tokens > 0 {
 		totalTokens = tokens
 		log.Debugf("kiro: CountTokens counted %d tokens using OpenAI chat format", totalTokens)
-	} else {
+	} else if tokenCount, countErr := enc.Count(string(payload)); countErr == nil {
 		// Fallback: count raw payload tokens
-		if tokenCount, countErr := enc.Count(string(req.Payload)); countErr == nil {
-			totalTokens = int64(tokenCount)
-			log.Debugf("kiro: CountTokens counted %d tokens from raw payload", totalTokens)
-		} else {
-			// Final fallback: estimate from payload size
-			totalTokens = int64(len(req.Payload) / 4)
-			if totalTokens == 0 && len(req.Payload) > 0 {
-				totalTokens = 1
-			}
-			log.Debugf("kiro: CountTokens estimated %d tokens from payload size", totalTokens)
+		totalTokens = int64(tokenCount)
+		log.Debugf("kiro: CountTokens counted %d tokens from raw payload", totalTokens)
+	} else {
+		// Final fallback: estimate from payload size
+		totalTokens = int64(len(payload) / 4)
+		if totalTokens == 0 && len(payload) > 0 {
+			totalTokens = 1
 		}
+		log.Debugf("kiro: CountTokens estimated %d tokens from payload size", totalTokens)
 	}
 
-	return cliproxyexecutor.Response{
-		Payload: []byte(fmt.Sprintf(`{"count":%d}`, totalTokens)),
-	}, nil
+	// Render the count in the caller's format (e.g. {"input_tokens":N} for /v1/messages).
+	usageJSON := tokencount.OpenAIUsageJSON(totalTokens)
+	translated := sdktranslator.TranslateTokenCount(ctx, to, from, totalTokens, usageJSON)
+	return cliproxyexecutor.Response{Payload: []byte(translated)}, nil
 }
 
 // Refresh refreshes the Kiro OAuth token.
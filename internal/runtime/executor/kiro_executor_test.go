@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// encodeKiroEventFrame builds an AWS event-stream frame carrying a single :event-type header.
+func encodeKiroEventFrame(eventType string, payload []byte) []byte {
+	var headers bytes.Buffer
+	name := ":event-type"
+	headers.WriteByte(byte(len(name)))
+	headers.WriteString(name)
+	headers.WriteByte(7)
+	_ = binary.Write(&headers, binary.BigEndian, uint16(len(eventType)))
+	headers.WriteString(eventType)
+
+	total := uint32(12 + headers.Len() + len(payload) + 4)
+	var frame bytes.Buffer
+	_ = binary.Write(&frame, binary.BigEndian, total)
+	_ = binary.Write(&frame, binary.BigEndian, uint32(headers.Len()))
+	_ = binary.Write(&frame, binary.BigEndian, crc32.ChecksumIEEE(frame.Bytes()))
+	frame.Write(headers.Bytes())
+	frame.Write(payload)
+	_ = binary.Write(&frame, binary.BigEndian, crc32.ChecksumIEEE(frame.Bytes()))
+	return frame.Bytes()
+}
+
+func TestReadEventStreamMessage_DecodesFrames(t *testing.T) {
+	e := &KiroExecutor{}
+	var stream bytes.Buffer
+	stream.Write(encodeKiroEventFrame("assistantResponseEvent", []byte(`{"content":"hel"}`)))
+	stream.Write(encodeKiroEventFrame("assistantResponseEvent", []byte(`{"content":"lo"}`)))
+	reader := bufio.NewReader(&stream)
+
+	for _, want := range []string{`{"content":"hel"}`, `{"content":"lo"}`} {
+		msg, err := e.readEventStreamMessage(reader)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg == nil || msg.EventType != "assistantResponseEvent" || string(msg.Payload) != want {
+			t.Fatalf("unexpected message: %+v", msg)
+		}
+	}
+	if msg, err := e.readEventStreamMessage(reader); msg != nil || err != nil {
+		t.Fatalf("expected clean EOF, got msg=%+v err=%v", msg, err)
+	}
+}
+
+func TestReadEventStreamMessage_RejectsCorruptFrames(t *testing.T) {
+	e := &KiroExecutor{}
+	frame := encodeKiroEventFrame("assistantResponseEvent", []byte(`{"content":"hi"}`))
+
+	corruptPayload := append([]byte(nil), frame...)
+	corruptPayload[len(corruptPayload)-6] ^= 0xff
+	if _, err := e.readEventStreamMessage(bufio.NewReader(bytes.NewReader(corruptPayload))); err == nil || err.Type != ErrStreamMalformed {
+		t.Fatalf("expected malformed error for corrupted payload, got %v", err)
+	}
+
+	corruptPrelude := append([]byte(nil), frame...)
+	corruptPrelude[9] ^= 0xff
+	if _, err := e.readEventStreamMessage(bufio.NewReader(bytes.NewReader(corruptPrelude))); err == nil || err.Type != ErrStreamMalformed {
+		t.Fatalf("expected malformed error for corrupted prelude, got %v", err)
+	}
+}
+
+func TestKiroCountTokensUsesClaudeResponseShape(t *testing.T) {
+	e := &KiroExecutor{}
+	payload := []byte(`{"model":"claude-sonnet-4-5","system":"be brief","messages":[{"role":"user","content":"hello there"}]}`)
+	resp, err := e.CountTokens(context.Background(), nil, cliproxyexecutor.Request{Model: "claude-sonnet-4-5", Payload: payload}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatClaude})
+	if err != nil {
+		t.Fatalf("CountTokens returned error: %v", err)
+	}
+	if got := gjson.GetBytes(resp.Payload, "input_tokens").Int(); got <= 0 {
+		t.Fatalf("expected positive input_tokens, got %s", resp.Payload)
+	}
+}
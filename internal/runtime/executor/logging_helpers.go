@@ -22,6 +22,12 @@ const (
 	apiAttemptsKey = "API_UPSTREAM_ATTEMPTS"
 	apiRequestKey  = "API_REQUEST"
 	apiResponseKey = "API_RESPONSE"
+
+	// droppedParamsHeader lists request parameters that were not forwarded upstream.
+	droppedParamsHeader = "X-CLIProxy-Dropped-Params"
+
+	// contextTrimmedHeader reports the turns dropped to fit the model's context window.
+	contextTrimmedHeader = "X-CLIProxy-Context-Trimmed"
 )
 
 // upstreamRequestLog captures the outbound upstream request details for logging.
@@ -149,6 +155,7 @@ func recordAPIResponseError(ctx context.Context, cfg *config.Config, err error)
 
 // appendAPIResponseChunk appends an upstream response chunk to Gin context for request logging.
 func appendAPIResponseChunk(ctx context.Context, cfg *config.Config, chunk []byte) {
+	recordUsageOutput(ctx, chunk)
 	if cfg == nil || !cfg.RequestLog {
 		return
 	}
@@ -187,6 +194,20 @@ func ginContextFrom(ctx context.Context) *gin.Context {
 	return ginCtx
 }
 
+// reportDroppedParams surfaces request parameters that the upstream could not honour
+// as a response header so clients can tell they were silently ignored.
+func reportDroppedParams(ctx context.Context, params []string) {
+	if len(params) == 0 {
+		return
+	}
+	log.Debugf("dropped unsupported request parameters: %s", strings.Join(params, ", "))
+	ginCtx := ginContextFrom(ctx)
+	if ginCtx == nil || ginCtx.Writer == nil || ginCtx.Writer.Written() {
+		return
+	}
+	ginCtx.Header(droppedParamsHeader, strings.Join(params, ","))
+}
+
 func getAttempts(ginCtx *gin.Context) []*upstreamAttempt {
 	if ginCtx == nil {
 		return nil
@@ -377,15 +398,8 @@ func extractJSONErrorMessage(body []byte) string {
 	return ""
 }
 
-// logWithRequestID returns a logrus Entry with request_id field populated from context.
-// If no request ID is found in context, it returns the standard logger.
+// logWithRequestID returns a logrus Entry with request_id and correlation fields populated from context.
+// If no request data is found in context, it returns the standard logger.
 func logWithRequestID(ctx context.Context) *log.Entry {
-	if ctx == nil {
-		return log.NewEntry(log.StandardLogger())
-	}
-	requestID := logging.GetRequestID(ctx)
-	if requestID == "" {
-		return log.NewEntry(log.StandardLogger())
-	}
-	return log.WithField("request_id", requestID)
+	return logging.RequestEntry(ctx)
 }
@@ -0,0 +1,157 @@
+package executor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	mistralDefaultBaseURL = "https://api.mistral.ai/v1"
+	mistralUserAgent      = "cli-proxy-mistral"
+)
+
+// mistralUnsupportedFields lists Chat Completions fields Mistral rejects as extra inputs.
+var mistralUnsupportedFields = []string{
+	"stream_options", "store", "metadata", "user", "service_tier", "logit_bias",
+	"logprobs", "top_logprobs", "modalities", "audio", "reasoning_effort",
+}
+
+// MistralExecutor executes chat completions against Mistral La Plateforme using API keys.
+// Requests are translated to Chat Completions and adjusted to Mistral's dialect.
+type MistralExecutor struct {
+	*chatCompletionsExecutor
+}
+
+// NewMistralExecutor constructs a new executor instance.
+func NewMistralExecutor(cfg *config.Config) *MistralExecutor {
+	return &MistralExecutor{&chatCompletionsExecutor{
+		cfg:                 cfg,
+		provider:            "mistral",
+		defaultBaseURL:      mistralDefaultBaseURL,
+		userAgent:           mistralUserAgent,
+		normalizeRequest:    func(body []byte, _ string) []byte { return normalizeMistralRequest(body) },
+		normalizeResponse:   normalizeMistralResponse,
+		normalizeStreamLine: normalizeMistralStreamLine,
+	}}
+}
+
+// normalizeMistralRequest adapts a Chat Completions body to what Mistral accepts: fields it
+// rejects are dropped, renamed parameters are moved, tool call ids are rewritten to the nine
+// alphanumeric characters Mistral requires and json_schema formats get the name it requires.
+func normalizeMistralRequest(body []byte) []byte {
+	for _, field := range mistralUnsupportedFields {
+		body, _ = sjson.DeleteBytes(body, field)
+	}
+	if v := gjson.GetBytes(body, "max_completion_tokens"); v.Exists() {
+		if !gjson.GetBytes(body, "max_tokens").Exists() {
+			body, _ = sjson.SetRawBytes(body, "max_tokens", []byte(v.Raw))
+		}
+		body, _ = sjson.DeleteBytes(body, "max_completion_tokens")
+	}
+	if v := gjson.GetBytes(body, "seed"); v.Exists() {
+		if !gjson.GetBytes(body, "random_seed").Exists() {
+			body, _ = sjson.SetRawBytes(body, "random_seed", []byte(v.Raw))
+		}
+		body, _ = sjson.DeleteBytes(body, "seed")
+	}
+	if gjson.GetBytes(body, "response_format.type").String() == "json_schema" && gjson.GetBytes(body, "response_format.json_schema").Exists() && gjson.GetBytes(body, "response_format.json_schema.name").String() == "" {
+		body, _ = sjson.SetBytes(body, "response_format.json_schema.name", "response")
+	}
+
+	messages := gjson.GetBytes(body, "messages").Array()
+	for i, message := range messages {
+		if message.Get("reasoning_content").Exists() {
+			body, _ = sjson.DeleteBytes(body, fmt.Sprintf("messages.%d.reasoning_content", i))
+		}
+		for j, call := range message.Get("tool_calls").Array() {
+			if id := call.Get("id").String(); id != "" && !isMistralToolCallID(id) {
+				body, _ = sjson.SetBytes(body, fmt.Sprintf("messages.%d.tool_calls.%d.id", i, j), mistralToolCallID(id))
+			}
+		}
+		if id := message.Get("tool_call_id").String(); id != "" && !isMistralToolCallID(id) {
+			body, _ = sjson.SetBytes(body, fmt.Sprintf("messages.%d.tool_call_id", i), mistralToolCallID(id))
+		}
+	}
+	return body
+}
+
+func isMistralToolCallID(id string) bool {
+	if len(id) != 9 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// mistralToolCallID derives a stable nine character id from a foreign tool call id, so a call
+// and its result map to the same id.
+func mistralToolCallID(id string) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	sum := sha256.Sum256([]byte(id))
+	out := make([]byte, 9)
+	for i := range out {
+		out[i] = alphabet[int(sum[i])%len(alphabet)]
+	}
+	return string(out)
+}
+
+// normalizeMistralResponse rewrites the chunked content Mistral returns for reasoning models
+// into the reasoning_content and string content the OpenAI translators understand.
+func normalizeMistralResponse(data []byte) []byte {
+	for i, choice := range gjson.GetBytes(data, "choices").Array() {
+		data = normalizeMistralContent(data, fmt.Sprintf("choices.%d.message", i), choice.Get("message"))
+	}
+	return data
+}
+
+// normalizeMistralStreamLine applies normalizeMistralResponse to one SSE data line.
+func normalizeMistralStreamLine(line []byte) []byte {
+	payload := bytes.TrimSpace(line[len("data:"):])
+	if !gjson.ValidBytes(payload) {
+		return line
+	}
+	changed := false
+	for i, choice := range gjson.GetBytes(payload, "choices").Array() {
+		if choice.Get("delta.content").IsArray() {
+			payload = normalizeMistralContent(payload, fmt.Sprintf("choices.%d.delta", i), choice.Get("delta"))
+			changed = true
+		}
+	}
+	if !changed {
+		return line
+	}
+	return append([]byte("data: "), payload...)
+}
+
+func normalizeMistralContent(data []byte, path string, message gjson.Result) []byte {
+	content := message.Get("content")
+	if !content.IsArray() {
+		return data
+	}
+	var text, reasoning strings.Builder
+	for _, chunk := range content.Array() {
+		switch chunk.Get("type").String() {
+		case "text":
+			text.WriteString(chunk.Get("text").String())
+		case "thinking":
+			for _, part := range chunk.Get("thinking").Array() {
+				reasoning.WriteString(part.Get("text").String())
+			}
+		}
+	}
+	data, _ = sjson.SetBytes(data, path+".content", text.String())
+	if reasoning.Len() > 0 {
+		data, _ = sjson.SetBytes(data, path+".reasoning_content", reasoning.String())
+	}
+	return data
+}
@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestNormalizeMistralRequest(t *testing.T) {
+	body := []byte(`{"model":"mistral-large-latest","max_completion_tokens":100,"seed":7,"stream_options":{"include_usage":true},"user":"u1",
+		"response_format":{"type":"json_schema","json_schema":{"schema":{"type":"object"}}},
+		"messages":[
+			{"role":"user","content":"hi"},
+			{"role":"assistant","content":"","reasoning_content":"think","tool_calls":[{"id":"toolu_01ABCdef","type":"function","function":{"name":"read","arguments":"{}"}},{"id":"Ab3dE6gH9","type":"function","function":{"name":"list","arguments":"{}"}}]},
+			{"role":"tool","tool_call_id":"toolu_01ABCdef","content":"ok"},
+			{"role":"tool","tool_call_id":"Ab3dE6gH9","content":"ok"}
+		]}`)
+
+	out := normalizeMistralRequest(body)
+	for _, field := range []string{"max_completion_tokens", "seed", "stream_options", "user", "messages.1.reasoning_content"} {
+		if gjson.GetBytes(out, field).Exists() {
+			t.Errorf("%s not removed: %s", field, out)
+		}
+	}
+	if gjson.GetBytes(out, "max_tokens").Int() != 100 || gjson.GetBytes(out, "random_seed").Int() != 7 {
+		t.Fatalf("renamed fields missing: %s", out)
+	}
+	if gjson.GetBytes(out, "response_format.json_schema.name").String() != "response" {
+		t.Fatalf("json_schema name missing: %s", out)
+	}
+	rewritten := gjson.GetBytes(out, "messages.1.tool_calls.0.id").String()
+	if !isMistralToolCallID(rewritten) || gjson.GetBytes(out, "messages.2.tool_call_id").String() != rewritten {
+		t.Fatalf("tool call id not rewritten consistently: %s", out)
+	}
+	if gjson.GetBytes(out, "messages.1.tool_calls.1.id").String() != "Ab3dE6gH9" || gjson.GetBytes(out, "messages.3.tool_call_id").String() != "Ab3dE6gH9" {
+		t.Fatalf("valid tool call id changed: %s", out)
+	}
+}
+
+func TestMistralExecutorStreamsClaudeWithReasoningChunks(t *testing.T) {
+	var gotPath, gotAuthorization string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuthorization = r.Header.Get("Authorization")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, `data: {"id":"m1","model":"magistral-medium-latest","choices":[{"index":0,"delta":{"role":"assistant","content":[{"type":"thinking","thinking":[{"type":"text","text":"consider"}]}]}}]}`+"\n\n")
+		_, _ = io.WriteString(w, `data: {"id":"m1","model":"magistral-medium-latest","choices":[{"index":0,"delta":{"content":"Answer."}}]}`+"\n\n")
+		_, _ = io.WriteString(w, `data: {"id":"m1","model":"magistral-medium-latest","choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":4,"completion_tokens":3,"total_tokens":7}}`+"\n\n")
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	executor := NewMistralExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{Provider: "mistral", Attributes: map[string]string{"api_key": "mk", "base_url": server.URL + "/v1"}}
+	payload := []byte(`{"model":"magistral-medium-latest","max_tokens":64,"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	stream, err := executor.ExecuteStream(context.Background(), auth, cliproxyexecutor.Request{Model: "magistral-medium-latest", Payload: payload}, cliproxyexecutor.Options{
+		SourceFormat:    sdktranslator.FromString("claude"),
+		OriginalRequest: payload,
+		Stream:          true,
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream error: %v", err)
+	}
+	var out strings.Builder
+	for chunk := range stream {
+		if chunk.Err != nil {
+			t.Fatalf("stream error: %v", chunk.Err)
+		}
+		out.Write(chunk.Payload)
+	}
+
+	if gotPath != "/v1/chat/completions" || gotAuthorization != "Bearer mk" {
+		t.Fatalf("path = %q, authorization = %q", gotPath, gotAuthorization)
+	}
+	if gjson.GetBytes(gotBody, "messages.0.content").String() == "" || gjson.GetBytes(gotBody, "stream_options").Exists() {
+		t.Fatalf("upstream body = %s", gotBody)
+	}
+	for _, want := range []string{`"thinking":"consider"`, `"text":"Answer."`, `"stop_reason":"end_turn"`} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("stream missing %s:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const ollamaUserAgent = "cli-proxy-ollama"
+
+// ollamaOptions maps Chat Completions sampling fields onto Ollama model options.
+var ollamaOptions = [][2]string{
+	{"temperature", "temperature"},
+	{"top_p", "top_p"},
+	{"top_k", "top_k"},
+	{"seed", "seed"},
+	{"presence_penalty", "presence_penalty"},
+	{"frequency_penalty", "frequency_penalty"},
+	{"max_completion_tokens", "num_predict"},
+	{"max_tokens", "num_predict"},
+}
+
+// OllamaExecutor executes chat requests against an Ollama server through its native /api/chat
+// endpoint. Requests are translated to Chat Completions and then to Ollama's message format,
+// including native tool calls, thinking and keep_alive; responses are converted back to Chat
+// Completions before they are translated for the client.
+type OllamaExecutor struct {
+	*chatCompletionsExecutor
+}
+
+// NewOllamaExecutor constructs a new executor instance.
+func NewOllamaExecutor(cfg *config.Config) *OllamaExecutor {
+	return &OllamaExecutor{&chatCompletionsExecutor{
+		cfg:              cfg,
+		provider:         "ollama",
+		defaultBaseURL:   config.DefaultOllamaBaseURL,
+		userAgent:        ollamaUserAgent,
+		keyless:          true,
+		normalizeRequest: func(body []byte, _ string) []byte { return convertOpenAIToOllamaChat(body) },
+	}}
+}
+
+// Execute performs a non-streaming chat request.
+func (e *OllamaExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
+	if opts.Alt == "responses/compact" {
+		return resp, statusErr{code: http.StatusNotImplemented, msg: "/responses/compact not supported"}
+	}
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+
+	reporter := newUsageReporter(ctx, e.Identifier(), baseModel, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	body, err := e.prepareOllamaBody(ctx, auth, req, opts, false)
+	if err != nil {
+		return resp, err
+	}
+	httpResp, err := e.send(ctx, auth, http.MethodPost, "/api/chat", body, false)
+	if err != nil {
+		return resp, err
+	}
+	defer func() {
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("ollama executor: close response body error: %v", errClose)
+		}
+	}()
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		recordAPIResponseError(ctx, e.cfg, err)
+		return resp, err
+	}
+	appendAPIResponseChunk(ctx, e.cfg, data)
+	if msg := gjson.GetBytes(data, "error").String(); msg != "" {
+		return resp, statusErr{code: http.StatusBadGateway, msg: msg}
+	}
+	data = convertOllamaChatResponse(data)
+	reporter.publish(ctx, parseOpenAIUsage(data))
+	reporter.ensurePublished(ctx)
+
+	var param any
+	out := sdktranslator.TranslateNonStream(ctx, sdktranslator.FromString("openai"), opts.SourceFormat, req.Model, bytes.Clone(opts.OriginalRequest), body, data, &param)
+	resp = cliproxyexecutor.Response{Payload: []byte(out)}
+	return resp, nil
+}
+
+// ExecuteStream performs a streaming chat request. Ollama streams newline-delimited JSON, which
+// is converted to Chat Completions chunks.
+func (e *OllamaExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (stream <-chan cliproxyexecutor.StreamChunk, err error) {
+	if opts.Alt == "responses/compact" {
+		return nil, statusErr{code: http.StatusNotImplemented, msg: "/responses/compact not supported"}
+	}
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+
+	reporter := newUsageReporter(ctx, e.Identifier(), baseModel, auth)
+	defer reporter.trackFailure(ctx, &err)
+
+	body, err := e.prepareOllamaBody(ctx, auth, req, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := e.send(ctx, auth, http.MethodPost, "/api/chat", body, true)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan cliproxyexecutor.StreamChunk)
+	stream = out
+	go func() {
+		defer close(out)
+		defer func() {
+			if errClose := httpResp.Body.Close(); errClose != nil {
+				log.Errorf("ollama executor: close response body error: %v", errClose)
+			}
+		}()
+
+		scanner := bufio.NewScanner(httpResp.Body)
+		scanner.Buffer(nil, 52_428_800) // 50MB
+		state := newOllamaStreamState()
+		var param any
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			appendAPIResponseChunk(ctx, e.cfg, line)
+			if msg := gjson.GetBytes(line, "error").String(); msg != "" {
+				reporter.publishFailure(ctx)
+				out <- cliproxyexecutor.StreamChunk{Err: statusErr{code: http.StatusBadGateway, msg: msg}}
+				return
+			}
+			for _, chunk := range state.convert(line) {
+				if detail, ok := parseOpenAIStreamUsage(chunk); ok {
+					reporter.publish(ctx, detail)
+				}
+				translated := sdktranslator.TranslateStream(ctx, sdktranslator.FromString("openai"), opts.SourceFormat, req.Model, bytes.Clone(opts.OriginalRequest), body, chunk, &param)
+				for i := range translated {
+					out <- cliproxyexecutor.StreamChunk{Payload: []byte(translated[i])}
+				}
+			}
+		}
+		if errScan := scanner.Err(); errScan != nil {
+			recordAPIResponseError(ctx, e.cfg, errScan)
+			reporter.publishFailure(ctx)
+			out <- cliproxyexecutor.StreamChunk{Err: errScan}
+		}
+		reporter.ensurePublished(ctx)
+	}()
+	return stream, nil
+}
+
+// prepareOllamaBody builds the /api/chat body and applies the configured keep_alive unless the
+// client set one.
+func (e *OllamaExecutor) prepareOllamaBody(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, stream bool) ([]byte, error) {
+	body, err := e.prepareBody(ctx, req, opts, stream)
+	if err != nil {
+		return nil, err
+	}
+	body, _ = sjson.SetBytes(body, "stream", stream)
+	if auth != nil && auth.Attributes != nil && !gjson.GetBytes(body, "keep_alive").Exists() {
+		if keepAlive := strings.TrimSpace(auth.Attributes["keep_alive"]); keepAlive != "" {
+			body, _ = sjson.SetBytes(body, "keep_alive", keepAlive)
+		}
+	}
+	return body, nil
+}
+
+// convertOpenAIToOllamaChat converts a Chat Completions request into an Ollama /api/chat request.
+// Tool call arguments become JSON objects, tool results carry the name of the tool they answer,
+// data URL images move to the images field and reasoning_effort toggles think.
+func convertOpenAIToOllamaChat(body []byte) []byte {
+	out := []byte(`{"model":"","messages":[]}`)
+	out, _ = sjson.SetBytes(out, "model", gjson.GetBytes(body, "model").String())
+	if stream := gjson.GetBytes(body, "stream"); stream.Exists() {
+		out, _ = sjson.SetBytes(out, "stream", stream.Bool())
+	}
+
+	toolNames := make(map[string]string)
+	for _, message := range gjson.GetBytes(body, "messages").Array() {
+		role := message.Get("role").String()
+		if role == "developer" {
+			role = "system"
+		}
+		msg := []byte(`{"role":"","content":""}`)
+		msg, _ = sjson.SetBytes(msg, "role", role)
+		text, images := ollamaMessageContent(message.Get("content"))
+		msg, _ = sjson.SetBytes(msg, "content", text)
+		if len(images) > 0 {
+			msg, _ = sjson.SetBytes(msg, "images", images)
+		}
+		for _, call := range message.Get("tool_calls").Array() {
+			name := call.Get("function.name").String()
+			toolNames[call.Get("id").String()] = name
+			tc := []byte(`{"function":{"name":"","arguments":{}}}`)
+			tc, _ = sjson.SetBytes(tc, "function.name", name)
+			if args := gjson.Parse(call.Get("function.arguments").String()); args.IsObject() {
+				tc, _ = sjson.SetRawBytes(tc, "function.arguments", []byte(args.Raw))
+			}
+			msg, _ = sjson.SetRawBytes(msg, "tool_calls.-1", tc)
+		}
+		if role == "tool" {
+			id := message.Get("tool_call_id").String()
+			name, ok := toolNames[id]
+			if !ok {
+				if mapping, found := cache.LookupToolCallID(id); found {
+					name = mapping.Name
+				}
+			}
+			if name != "" {
+				msg, _ = sjson.SetBytes(msg, "tool_name", name)
+			}
+		}
+		out, _ = sjson.SetRawBytes(out, "messages.-1", msg)
+	}
+
+	if tools := gjson.GetBytes(body, "tools"); tools.IsArray() && len(tools.Array()) > 0 {
+		out, _ = sjson.SetRawBytes(out, "tools", []byte(tools.Raw))
+	}
+	for _, option := range ollamaOptions {
+		if v := gjson.GetBytes(body, option[0]); v.Exists() && !gjson.GetBytes(out, "options."+option[1]).Exists() {
+			out, _ = sjson.SetRawBytes(out, "options."+option[1], []byte(v.Raw))
+		}
+	}
+	if stop := gjson.GetBytes(body, "stop"); stop.Type == gjson.String {
+		out, _ = sjson.SetBytes(out, "options.stop", []string{stop.String()})
+	} else if stop.IsArray() {
+		out, _ = sjson.SetRawBytes(out, "options.stop", []byte(stop.Raw))
+	}
+	switch gjson.GetBytes(body, "response_format.type").String() {
+	case "json_object":
+		out, _ = sjson.SetBytes(out, "format", "json")
+	case "json_schema":
+		if schema := gjson.GetBytes(body, "response_format.json_schema.schema"); schema.IsObject() {
+			out, _ = sjson.SetRawBytes(out, "format", []byte(schema.Raw))
+		}
+	}
+	if effort := gjson.GetBytes(body, "reasoning_effort"); effort.Exists() {
+		out, _ = sjson.SetBytes(out, "think", !strings.EqualFold(strings.TrimSpace(effort.String()), "none"))
+	}
+	if keepAlive := gjson.GetBytes(body, "keep_alive"); keepAlive.Exists() {
+		out, _ = sjson.SetRawBytes(out, "keep_alive", []byte(keepAlive.Raw))
+	}
+	return out
+}
+
+// ollamaMessageContent returns the text of message content and the base64 data of its data URL
+// images, which is the only image form Ollama accepts.
+func ollamaMessageContent(content gjson.Result) (string, []string) {
+	if !content.IsArray() {
+		return content.String(), nil
+	}
+	var text strings.Builder
+	var images []string
+	for _, part := range content.Array() {
+		switch part.Get("type").String() {
+		case "text":
+			text.WriteString(part.Get("text").String())
+		case "image_url":
+			url := part.Get("image_url.url").String()
+			if i := strings.Index(url, ";base64,"); strings.HasPrefix(url, "data:") && i >= 0 {
+				images = append(images, url[i+len(";base64,"):])
+			}
+		}
+	}
+	return text.String(), images
+}
+
+// convertOllamaChatResponse converts a non-streaming /api/chat response into a Chat Completion.
+func convertOllamaChatResponse(data []byte) []byte {
+	root := gjson.ParseBytes(data)
+	out := []byte(`{"id":"","object":"chat.completion","created":0,"model":"","choices":[{"index":0,"message":{"role":"assistant","content":""},"finish_reason":"stop"}]}`)
+	out, _ = sjson.SetBytes(out, "id", fmt.Sprintf("chatcmpl-ollama-%d", time.Now().UnixNano()))
+	out, _ = sjson.SetBytes(out, "created", ollamaCreated(root))
+	out, _ = sjson.SetBytes(out, "model", root.Get("model").String())
+	out, _ = sjson.SetBytes(out, "choices.0.message.content", root.Get("message.content").String())
+	if reasoning := root.Get("message.thinking").String(); reasoning != "" {
+		out, _ = sjson.SetBytes(out, "choices.0.message.reasoning_content", reasoning)
+	}
+	calls := root.Get("message.tool_calls").Array()
+	for _, call := range calls {
+		out, _ = sjson.SetRawBytes(out, "choices.0.message.tool_calls.-1", ollamaToolCall(call, -1))
+	}
+	out, _ = sjson.SetBytes(out, "choices.0.finish_reason", ollamaFinishReason(root, len(calls) > 0))
+	out, _ = sjson.SetRawBytes(out, "usage", ollamaUsage(root))
+	return out
+}
+
+// ollamaStreamState converts the lines of one /api/chat stream into Chat Completions chunks.
+type ollamaStreamState struct {
+	id        string
+	started   bool
+	toolCalls int
+}
+
+func newOllamaStreamState() *ollamaStreamState {
+	return &ollamaStreamState{id: fmt.Sprintf("chatcmpl-ollama-%d", time.Now().UnixNano())}
+}
+
+// convert returns the SSE data lines for one stream line. The final line yields the finish
+// reason, a usage chunk and [DONE].
+func (s *ollamaStreamState) convert(line []byte) [][]byte {
+	root := gjson.ParseBytes(line)
+	chunk := []byte(`{"id":"","object":"chat.completion.chunk","created":0,"model":"","choices":[{"index":0,"delta":{},"finish_reason":null}]}`)
+	chunk, _ = sjson.SetBytes(chunk, "id", s.id)
+	chunk, _ = sjson.SetBytes(chunk, "created", ollamaCreated(root))
+	chunk, _ = sjson.SetBytes(chunk, "model", root.Get("model").String())
+	if !s.started {
+		chunk, _ = sjson.SetBytes(chunk, "choices.0.delta.role", "assistant")
+		s.started = true
+	}
+	if content := root.Get("message.content").String(); content != "" {
+		chunk, _ = sjson.SetBytes(chunk, "choices.0.delta.content", content)
+	}
+	if reasoning := root.Get("message.thinking").String(); reasoning != "" {
+		chunk, _ = sjson.SetBytes(chunk, "choices.0.delta.reasoning_content", reasoning)
+	}
+	for _, call := range root.Get("message.tool_calls").Array() {
+		chunk, _ = sjson.SetRawBytes(chunk, "choices.0.delta.tool_calls.-1", ollamaToolCall(call, s.toolCalls))
+		s.toolCalls++
+	}
+	if !root.Get("done").Bool() {
+		return [][]byte{append([]byte("data: "), chunk...)}
+	}
+
+	chunk, _ = sjson.SetBytes(chunk, "choices.0.finish_reason", ollamaFinishReason(root, s.toolCalls > 0))
+	usageChunk := []byte(`{"id":"","object":"chat.completion.chunk","created":0,"model":"","choices":[]}`)
+	usageChunk, _ = sjson.SetBytes(usageChunk, "id", s.id)
+	usageChunk, _ = sjson.SetBytes(usageChunk, "created", ollamaCreated(root))
+	usageChunk, _ = sjson.SetBytes(usageChunk, "model", root.Get("model").String())
+	usageChunk, _ = sjson.SetRawBytes(usageChunk, "usage", ollamaUsage(root))
+	return [][]byte{
+		append([]byte("data: "), chunk...),
+		append([]byte("data: "), usageChunk...),
+		[]byte("data: [DONE]"),
+	}
+}
+
+// ollamaToolCall converts an Ollama tool call into a Chat Completions tool call. Ollama has no
+// call ids, so one is generated and remembered for the matching tool result. A negative index
+// omits the streaming index field.
+func ollamaToolCall(call gjson.Result, index int) []byte {
+	name := call.Get("function.name").String()
+	args := call.Get("function.arguments").Raw
+	if args == "" {
+		args = "{}"
+	}
+	tc := []byte(`{"id":"","type":"function","function":{"name":"","arguments":""}}`)
+	if index >= 0 {
+		tc, _ = sjson.SetBytes(tc, "index", index)
+	}
+	tc, _ = sjson.SetBytes(tc, "id", cache.NewToolCallID("call_", name, call.Get("id").String()))
+	tc, _ = sjson.SetBytes(tc, "function.name", name)
+	tc, _ = sjson.SetBytes(tc, "function.arguments", args)
+	return tc
+}
+
+func ollamaFinishReason(root gjson.Result, toolCalls bool) string {
+	switch {
+	case toolCalls:
+		return "tool_calls"
+	case root.Get("done_reason").String() == "length":
+		return "length"
+	default:
+		return "stop"
+	}
+}
+
+func ollamaUsage(root gjson.Result) []byte {
+	prompt, completion := root.Get("prompt_eval_count").Int(), root.Get("eval_count").Int()
+	usage := []byte(`{"prompt_tokens":0,"completion_tokens":0,"total_tokens":0}`)
+	usage, _ = sjson.SetBytes(usage, "prompt_tokens", prompt)
+	usage, _ = sjson.SetBytes(usage, "completion_tokens", completion)
+	usage, _ = sjson.SetBytes(usage, "total_tokens", prompt+completion)
+	return usage
+}
+
+func ollamaCreated(root gjson.Result) int64 {
+	if created, err := time.Parse(time.RFC3339Nano, root.Get("created_at").String()); err == nil {
+		return created.Unix()
+	}
+	return time.Now().Unix()
+}
@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestOllamaExecutorConvertsClaudeToolCalls(t *testing.T) {
+	var gotBody []byte
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		gotBody, _ = io.ReadAll(r.Body)
+		gotAuth = r.Header.Get("Authorization")
+		_, _ = io.WriteString(w, `{"model":"qwen3:8b","created_at":"2025-01-01T00:00:00Z","message":{"role":"assistant","content":"","thinking":"need weather","tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"Paris"}}}]},"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":7}`)
+	}))
+	defer server.Close()
+
+	executor := NewOllamaExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{Provider: "ollama", Attributes: map[string]string{"base_url": server.URL, "keep_alive": "30m"}}
+	payload := []byte(`{"model":"qwen3:8b","max_tokens":512,"tools":[{"name":"get_weather","description":"Weather","input_schema":{"type":"object","properties":{"city":{"type":"string"}}}}],"messages":[
+		{"role":"user","content":"Weather in Rome?"},
+		{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Rome"}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"sunny"}]},
+		{"role":"user","content":"And Paris?"}
+	]}`)
+	resp, err := executor.Execute(context.Background(), auth, cliproxyexecutor.Request{Model: "qwen3:8b", Payload: payload}, cliproxyexecutor.Options{
+		SourceFormat:    sdktranslator.FromString("claude"),
+		OriginalRequest: payload,
+	})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+
+	body := gjson.ParseBytes(gotBody)
+	if gotAuth != "" || body.Get("stream").Bool() || body.Get("keep_alive").String() != "30m" || body.Get("options.num_predict").Int() != 512 {
+		t.Fatalf("upstream body = %s (auth %q)", gotBody, gotAuth)
+	}
+	if body.Get("tools.0.function.name").String() != "get_weather" {
+		t.Fatalf("tools = %s", body.Get("tools").Raw)
+	}
+	var call, result gjson.Result
+	for _, message := range body.Get("messages").Array() {
+		if message.Get("tool_calls").Exists() {
+			call = message.Get("tool_calls.0.function")
+		}
+		if message.Get("role").String() == "tool" {
+			result = message
+		}
+	}
+	if call.Get("arguments.city").String() != "Rome" || result.Get("tool_name").String() != "get_weather" || result.Get("content").String() != "sunny" {
+		t.Fatalf("history = %s", body.Get("messages").Raw)
+	}
+
+	out := gjson.ParseBytes(resp.Payload)
+	if out.Get("content.0.thinking").String() != "need weather" || out.Get("content.1.type").String() != "tool_use" || out.Get("content.1.input.city").String() != "Paris" || out.Get("stop_reason").String() != "tool_use" {
+		t.Fatalf("claude response = %s", resp.Payload)
+	}
+	if out.Get("usage.input_tokens").Int() != 12 || out.Get("usage.output_tokens").Int() != 7 {
+		t.Fatalf("usage = %s", out.Get("usage").Raw)
+	}
+}
+
+func TestOllamaExecutorStreamsChatCompletions(t *testing.T) {
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = io.WriteString(w, `{"model":"llama3.1","message":{"role":"assistant","content":"Hel"},"done":false}
+{"model":"llama3.1","message":{"role":"assistant","content":"lo"},"done":false}
+{"model":"llama3.1","message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":3,"eval_count":2}
+`)
+	}))
+	defer server.Close()
+
+	executor := NewOllamaExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{Provider: "ollama", Attributes: map[string]string{"base_url": server.URL}}
+	payload := []byte(`{"model":"llama3.1","stream":true,"keep_alive":"-1","stop":"END","messages":[{"role":"developer","content":"Be brief."},{"role":"user","content":"hi"}]}`)
+	stream, err := executor.ExecuteStream(context.Background(), auth, cliproxyexecutor.Request{Model: "llama3.1", Payload: payload}, cliproxyexecutor.Options{
+		SourceFormat:    sdktranslator.FromString("openai"),
+		OriginalRequest: payload,
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream error: %v", err)
+	}
+	var content, finish strings.Builder
+	var usage int64
+	for chunk := range stream {
+		if chunk.Err != nil {
+			t.Fatalf("stream error: %v", chunk.Err)
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(string(chunk.Payload), "data:"))
+		content.WriteString(gjson.Get(data, "choices.0.delta.content").String())
+		finish.WriteString(gjson.Get(data, "choices.0.finish_reason").String())
+		usage += gjson.Get(data, "usage.total_tokens").Int()
+	}
+
+	body := gjson.ParseBytes(gotBody)
+	if !body.Get("stream").Bool() || body.Get("keep_alive").String() != "-1" || body.Get("messages.0.role").String() != "system" || body.Get("options.stop.0").String() != "END" {
+		t.Fatalf("upstream body = %s", gotBody)
+	}
+	if content.String() != "Hello" || finish.String() != "length" || usage != 5 {
+		t.Fatalf("content = %q, finish = %q, usage = %d", content.String(), finish.String(), usage)
+	}
+}
@@ -0,0 +1,53 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestOpenAICompatExecutorTranslatesClaudeForKeylessUpstream(t *testing.T) {
+	var gotPath, gotAuthorization string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuthorization = r.Header.Get("Authorization")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","model":"qwen3","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","reasoning_content":"need the file","tool_calls":[{"id":"call_1","type":"function","function":{"name":"read","arguments":"{\"path\":\"a.go\"}"}}]}}],"usage":{"prompt_tokens":7,"completion_tokens":5}}`))
+	}))
+	defer server.Close()
+
+	executor := NewOpenAICompatExecutor("vllm", &config.Config{})
+	auth := &cliproxyauth.Auth{Provider: "vllm", Attributes: map[string]string{"base_url": server.URL + "/v1"}}
+	payload := []byte(`{"model":"qwen3","max_tokens":64,"messages":[{"role":"user","content":[{"type":"text","text":"open a.go"}]}],"tools":[{"name":"read","input_schema":{"type":"object"}}]}`)
+	resp, err := executor.Execute(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   "qwen3",
+		Payload: payload,
+	}, cliproxyexecutor.Options{
+		SourceFormat:    sdktranslator.FromString("claude"),
+		OriginalRequest: payload,
+	})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+
+	if gotPath != "/v1/chat/completions" || gotAuthorization != "" {
+		t.Fatalf("path = %q, authorization = %q", gotPath, gotAuthorization)
+	}
+	if gjson.GetBytes(gotBody, "messages.0.content.0.text").String() != "open a.go" || gjson.GetBytes(gotBody, "tools.0.function.name").String() != "read" {
+		t.Fatalf("upstream body = %s", gotBody)
+	}
+	out := gjson.ParseBytes(resp.Payload)
+	if out.Get("content.0.type").String() != "thinking" || out.Get("content.1.input.path").String() != "a.go" || out.Get("stop_reason").String() != "tool_use" {
+		t.Fatalf("claude response = %s", resp.Payload)
+	}
+}
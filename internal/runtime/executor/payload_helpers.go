@@ -1,11 +1,18 @@
 package executor
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/contextwindow"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/scripthook"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/sysprompt"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -16,7 +23,45 @@ import (
 // and restricts matches to the given protocol when supplied. Defaults are checked
 // against the original payload when provided. requestedModel carries the client-visible
 // model name before alias resolution so payload rules can target aliases precisely.
-func applyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string, payload, original []byte, requestedModel string) []byte {
+// Configured system prompts and, for Gemini requests, safety thresholds are applied first, in the
+// upstream's own format, so payload rules can still override them. Upstream request-scripts run last, on the fully translated
+// payload, and are cancelled together with the request ctx. Before them, requests too large for
+// the model's context window lose their oldest turns when context-window trimming is enabled.
+func applyPayloadConfigWithRoot(ctx context.Context, cfg *config.Config, model, protocol, root string, payload, original []byte, requestedModel string) []byte {
+	if cfg != nil && len(cfg.SystemPrompts.Rules) > 0 {
+		payload = sysprompt.Apply(cfg.SystemPrompts, protocol, root, model, requestedModel, apiKeyFromContext(ctx), payload, time.Now())
+	}
+	if protocol == "gemini" {
+		payload = applySafetySettings(ctx, cfg, model, root, payload, requestedModel)
+	}
+	payload = applyPayloadRules(cfg, model, protocol, root, payload, original, requestedModel)
+	payload = applyContextWindow(ctx, cfg, model, protocol, root, payload, requestedModel)
+	if cfg == nil || len(cfg.RequestScripts) == 0 {
+		return payload
+	}
+	return scripthook.Apply(ctx, cfg.RequestScripts, scripthook.StageUpstream, model, protocol, payload)
+}
+
+// applyContextWindow trims the oldest turns of a payload that would not fit the context window of
+// model and reports what was dropped in a response header.
+func applyContextWindow(ctx context.Context, cfg *config.Config, model, protocol, root string, payload []byte, requestedModel string) []byte {
+	if cfg == nil || !cfg.ContextWindow.Enabled {
+		return payload
+	}
+	out, res := contextwindow.Apply(ctx, cfg.ContextWindow, protocol, root, model, requestedModel, payload)
+	if !res.Trimmed() {
+		return payload
+	}
+	logWithRequestID(ctx).Infof("context window: dropped %d turns (%d messages) for %s, estimated tokens %d -> %d of %d budget",
+		res.DroppedTurns, res.DroppedMessages, model, res.TokensBefore, res.TokensAfter, res.Budget)
+	if ginCtx := ginContextFrom(ctx); ginCtx != nil && ginCtx.Writer != nil && !ginCtx.Writer.Written() {
+		ginCtx.Header(contextTrimmedHeader, fmt.Sprintf("turns=%d; messages=%d; estimated-tokens=%d->%d", res.DroppedTurns, res.DroppedMessages, res.TokensBefore, res.TokensAfter))
+	}
+	return out
+}
+
+// applyPayloadRules applies the payload default, override and filter rules.
+func applyPayloadRules(cfg *config.Config, model, protocol, root string, payload, original []byte, requestedModel string) []byte {
 	if cfg == nil || len(payload) == 0 {
 		return payload
 	}
@@ -164,7 +209,7 @@ func payloadModelRulesMatch(rules []config.PayloadModelRule, protocol string, mo
 			if ep := strings.TrimSpace(entry.Protocol); ep != "" && protocol != "" && !strings.EqualFold(ep, protocol) {
 				continue
 			}
-			if matchModelPattern(name, model) {
+			if util.MatchWildcard(name, model) {
 				return true
 			}
 		}
@@ -272,48 +317,3 @@ func payloadRequestedModel(opts cliproxyexecutor.Options, fallback string) strin
 		return fallback
 	}
 }
-
-// matchModelPattern performs simple wildcard matching where '*' matches zero or more characters.
-// Examples:
-//
-//	"*-5" matches "gpt-5"
-//	"gpt-*" matches "gpt-5" and "gpt-4"
-//	"gemini-*-pro" matches "gemini-2.5-pro" and "gemini-3-pro".
-func matchModelPattern(pattern, model string) bool {
-	pattern = strings.TrimSpace(pattern)
-	model = strings.TrimSpace(model)
-	if pattern == "" {
-		return false
-	}
-	if pattern == "*" {
-		return true
-	}
-	// Iterative glob-style matcher supporting only '*' wildcard.
-	pi, si := 0, 0
-	starIdx := -1
-	matchIdx := 0
-	for si < len(model) {
-		if pi < len(pattern) && (pattern[pi] == model[si]) {
-			pi++
-			si++
-			continue
-		}
-		if pi < len(pattern) && pattern[pi] == '*' {
-			starIdx = pi
-			matchIdx = si
-			pi++
-			continue
-		}
-		if starIdx != -1 {
-			pi = starIdx + 1
-			matchIdx++
-			si = matchIdx
-			continue
-		}
-		return false
-	}
-	for pi < len(pattern) && pattern[pi] == '*' {
-		pi++
-	}
-	return pi == len(pattern)
-}
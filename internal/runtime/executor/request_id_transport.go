@@ -0,0 +1,37 @@
+package executor
+
+import (
+	"net/http"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+)
+
+// requestIDRoundTripper forwards the proxy's request ID to the upstream in the X-Request-Id
+// header so a request can be matched with the provider's logs.
+type requestIDRoundTripper struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *requestIDRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if requestID := logging.GetRequestID(req.Context()); requestID != "" && req.Header.Get(logging.RequestIDHeader) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(logging.RequestIDHeader, requestID)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// withRequestID returns a copy of client that forwards the request ID. The original client is
+// never mutated because it may be shared.
+func withRequestID(client *http.Client) *http.Client {
+	if client == nil {
+		return nil
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *client
+	wrapped.Transport = &requestIDRoundTripper{base: base}
+	return &wrapped
+}
@@ -0,0 +1,184 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultRetryInitialBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff     = 10 * time.Second
+)
+
+var defaultRetryOnStatus = []int{
+	http.StatusRequestTimeout,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// retryRoundTripper replays requests on transient upstream failures according to retry-policy.
+// Only requests whose body can be recreated (GetBody) are replayed, so the translated payload
+// is resent byte for byte.
+type retryRoundTripper struct {
+	base             http.RoundTripper
+	maxAttempts      int
+	initialBackoff   time.Duration
+	maxBackoff       time.Duration
+	retryOn          map[int]struct{}
+	ignoreRetryAfter bool
+}
+
+// withRetryPolicy returns client wrapped with the configured retry policy, or client itself when
+// the policy is disabled. The original client is never mutated because it may be shared.
+func withRetryPolicy(client *http.Client, cfg *config.Config) *http.Client {
+	if client == nil || cfg == nil || cfg.RetryPolicy.MaxAttempts <= 1 {
+		return client
+	}
+	policy := cfg.RetryPolicy
+	rt := &retryRoundTripper{
+		base:             client.Transport,
+		maxAttempts:      policy.MaxAttempts,
+		initialBackoff:   parseRetryDuration(policy.InitialBackoff, defaultRetryInitialBackoff),
+		maxBackoff:       parseRetryDuration(policy.MaxBackoff, defaultRetryMaxBackoff),
+		retryOn:          make(map[int]struct{}),
+		ignoreRetryAfter: policy.IgnoreRetryAfter,
+	}
+	if rt.base == nil {
+		rt.base = http.DefaultTransport
+	}
+	retryOn := policy.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = defaultRetryOnStatus
+	}
+	for _, code := range retryOn {
+		rt.retryOn[code] = struct{}{}
+	}
+	wrapped := *client
+	wrapped.Transport = rt
+	return &wrapped
+}
+
+func parseRetryDuration(value string, fallback time.Duration) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		log.Warnf("retry-policy: invalid duration %q, using %s", value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.GetBody != nil {
+			body, errBody := req.GetBody()
+			if errBody != nil {
+				return nil, errBody
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+		resp, errRT := rt.base.RoundTrip(attemptReq)
+		if !replayable || attempt >= rt.maxAttempts || !rt.shouldRetry(req.Context(), resp, errRT) {
+			return resp, errRT
+		}
+		wait, ok := rt.backoff(attempt, resp)
+		if !ok {
+			return resp, errRT
+		}
+		if resp != nil {
+			log.Debugf("retry-policy: upstream returned %d, replaying request in %s (attempt %d/%d)", resp.StatusCode, wait, attempt+1, rt.maxAttempts)
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		} else {
+			log.Debugf("retry-policy: upstream request failed: %v, replaying in %s (attempt %d/%d)", errRT, wait, attempt+1, rt.maxAttempts)
+		}
+		if errWait := waitForRetry(req.Context(), wait); errWait != nil {
+			return nil, errWait
+		}
+	}
+}
+
+func (rt *retryRoundTripper) shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	if resp == nil {
+		return false
+	}
+	_, ok := rt.retryOn[resp.StatusCode]
+	return ok
+}
+
+// backoff returns the delay before the next attempt. It reports false when the upstream asks
+// to wait longer than max-backoff, so the caller can fail over to another credential instead.
+func (rt *retryRoundTripper) backoff(attempt int, resp *http.Response) (time.Duration, bool) {
+	if !rt.ignoreRetryAfter && resp != nil {
+		if wait, ok := parseRetryAfterHeader(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return wait, wait <= rt.maxBackoff
+		}
+	}
+	wait := rt.initialBackoff
+	for i := 1; i < attempt && wait < rt.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > rt.maxBackoff {
+		wait = rt.maxBackoff
+	}
+	return wait, true
+}
+
+// parseRetryAfterHeader parses a Retry-After value given in seconds or as an HTTP date.
+func parseRetryAfterHeader(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		wait := at.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+func waitForRetry(ctx context.Context, wait time.Duration) error {
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
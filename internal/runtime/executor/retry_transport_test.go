@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func TestWithRetryPolicy_ReplaysBodyOnTransientStatus(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"model":"m"}` {
+			t.Errorf("attempt %d body = %q", calls.Load()+1, body)
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{RetryPolicy: config.RetryPolicy{MaxAttempts: 3, InitialBackoff: "1ms"}}
+	client := withRetryPolicy(&http.Client{}, cfg)
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"model":"m"}`))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Fatalf("status = %d, calls = %d; want 200 after 2 calls", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestWithRetryPolicy_LongRetryAfterIsNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{RetryPolicy: config.RetryPolicy{MaxAttempts: 3, MaxBackoff: "1s"}}
+	resp, err := withRetryPolicy(&http.Client{}, cfg).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Fatalf("status = %d, calls = %d; want 503 after 1 call", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestWithRetryPolicy_DisabledReturnsSameClient(t *testing.T) {
+	client := &http.Client{}
+	if got := withRetryPolicy(client, &config.Config{}); got != client {
+		t.Fatalf("expected client to be returned unchanged when retry-policy is disabled")
+	}
+}
+
+func TestParseRetryAfterHeader(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if wait, ok := parseRetryAfterHeader("7", now); !ok || wait != 7*time.Second {
+		t.Fatalf("seconds: got %s, %v", wait, ok)
+	}
+	date := now.Add(30 * time.Second).Format(http.TimeFormat)
+	if wait, ok := parseRetryAfterHeader(date, now); !ok || wait != 30*time.Second {
+		t.Fatalf("http date: got %s, %v", wait, ok)
+	}
+	if _, ok := parseRetryAfterHeader("soon", now); ok {
+		t.Fatalf("expected invalid value to be rejected")
+	}
+}
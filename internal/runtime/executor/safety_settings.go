@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/common"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+// applySafetySettings applies the configured safety-settings thresholds to a Gemini request
+// whose fields live under root. Rules match the upstream model or the model the client
+// requested, and the client API key; later matching rules override earlier ones per category.
+func applySafetySettings(ctx context.Context, cfg *config.Config, model, root string, payload []byte, requestedModel string) []byte {
+	if cfg == nil || (len(cfg.SafetySettings.Thresholds) == 0 && len(cfg.SafetySettings.Rules) == 0) {
+		return payload
+	}
+	thresholds := make(map[string]string, len(cfg.SafetySettings.Thresholds))
+	for category, threshold := range cfg.SafetySettings.Thresholds {
+		thresholds[category] = threshold
+	}
+	candidates := payloadModelCandidates(model, requestedModel)
+	apiKey := strings.TrimSpace(apiKeyFromContext(ctx))
+	for i := range cfg.SafetySettings.Rules {
+		rule := &cfg.SafetySettings.Rules[i]
+		if !safetyRuleMatches(rule, candidates, apiKey) {
+			continue
+		}
+		for category, threshold := range rule.Thresholds {
+			thresholds[category] = threshold
+		}
+	}
+	return common.ApplySafetyThresholds(payload, buildPayloadPath(root, "safetySettings"), thresholds)
+}
+
+func safetyRuleMatches(rule *config.SafetySettingsRule, models []string, apiKey string) bool {
+	if len(rule.APIKeys) > 0 {
+		matched := false
+		for _, key := range rule.APIKeys {
+			if apiKey != "" && strings.TrimSpace(key) == apiKey {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(rule.Models) == 0 {
+		return true
+	}
+	for _, pattern := range rule.Models {
+		for _, model := range models {
+			if util.MatchWildcard(pattern, model) {
+				return true
+			}
+		}
+	}
+	return false
+}
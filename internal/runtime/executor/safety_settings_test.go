@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplySafetySettingsPerModelAndKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.SafetySettings = config.SafetySettingsConfig{
+		Thresholds: map[string]string{"HARM_CATEGORY_HARASSMENT": "BLOCK_ONLY_HIGH"},
+		Rules: []config.SafetySettingsRule{{
+			Models:     []string{"gemini-2.5-*"},
+			APIKeys:    []string{"team-key"},
+			Thresholds: map[string]string{"HARM_CATEGORY_HARASSMENT": "BLOCK_LOW_AND_ABOVE"},
+		}},
+	}
+	threshold := func(ctx context.Context, model string) string {
+		out := applySafetySettings(ctx, cfg, model, "request", []byte(`{"request":{}}`), "")
+		return gjson.GetBytes(out, `request.safetySettings.#(category=="HARM_CATEGORY_HARASSMENT").threshold`).String()
+	}
+
+	if got := threshold(context.Background(), "gemini-2.5-pro"); got != "BLOCK_ONLY_HIGH" {
+		t.Fatalf("global threshold = %q", got)
+	}
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("apiKey", "team-key")
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+	if got := threshold(ctx, "gemini-2.5-pro"); got != "BLOCK_LOW_AND_ABOVE" {
+		t.Fatalf("per-key threshold = %q", got)
+	}
+	if got := threshold(ctx, "gemini-3-pro-preview"); got != "BLOCK_ONLY_HIGH" {
+		t.Fatalf("rule for other models applied: %q", got)
+	}
+}
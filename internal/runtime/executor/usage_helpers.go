@@ -9,8 +9,12 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/netaccess"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencount"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -23,7 +27,62 @@ type usageReporter struct {
 	apiKey      string
 	source      string
 	requestedAt time.Time
+	tags        map[string]string
+	region      string
+	workload    string
+	tenant      string
+	clientIP    string
 	once        sync.Once
+
+	// promptFormat and prompt are the client request used to estimate tokens when the upstream
+	// reports none, and output collects the generated text of the current attempt.
+	promptFormat string
+	prompt       []byte
+	output       *usageOutput
+}
+
+// usageOutputContextKey is the gin context key holding the generated text of the current
+// upstream attempt, fed by appendAPIResponseChunk.
+const usageOutputContextKey = "usageOutput"
+
+// usageOutput accumulates the generated text of an upstream response for token estimation.
+type usageOutput struct {
+	mu   sync.Mutex
+	text strings.Builder
+	// closed stops collecting once the usage record has been published.
+	closed bool
+}
+
+// recordUsageOutput adds the generated text of an upstream response chunk to the usage output
+// of the current attempt.
+func recordUsageOutput(ctx context.Context, chunk []byte) {
+	ginCtx := ginContextFrom(ctx)
+	if ginCtx == nil || len(chunk) == 0 {
+		return
+	}
+	value, exists := ginCtx.Get(usageOutputContextKey)
+	if !exists {
+		return
+	}
+	output, ok := value.(*usageOutput)
+	if !ok || output == nil {
+		return
+	}
+	output.mu.Lock()
+	closed := output.closed
+	output.mu.Unlock()
+	if closed {
+		return
+	}
+	text := tokencount.ResponseText(chunk)
+	if text == "" {
+		return
+	}
+	output.mu.Lock()
+	if !output.closed {
+		output.text.WriteString(text)
+	}
+	output.mu.Unlock()
 }
 
 func newUsageReporter(ctx context.Context, provider, model string, auth *cliproxyauth.Auth) *usageReporter {
@@ -34,14 +93,38 @@ func newUsageReporter(ctx context.Context, provider, model string, auth *cliprox
 		requestedAt: time.Now(),
 		apiKey:      apiKey,
 		source:      resolveUsageSource(auth, apiKey),
+		tags:        usageTagsFromContext(ctx),
+		workload:    usageWorkloadFromContext(ctx),
+		tenant:      usageTenantFromContext(ctx),
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		reporter.clientIP = netaccess.ClientIP(ginCtx)
+		reporter.promptFormat = ginCtx.GetString("usagePromptFormat")
+		if prompt, ok := ginCtx.Get("usagePrompt"); ok {
+			reporter.prompt, _ = prompt.([]byte)
+		}
+		// Every attempt starts with a fresh output buffer.
+		reporter.output = &usageOutput{}
+		ginCtx.Set(usageOutputContextKey, reporter.output)
 	}
 	if auth != nil {
 		reporter.authID = auth.ID
 		reporter.authIndex = auth.EnsureIndex()
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok {
+			audit.SetUpstream(ginCtx, provider, reporter.authIndex)
+		}
 	}
 	return reporter
 }
 
+// setRegion records the upstream region that served the request. It must be called before the
+// record is published.
+func (r *usageReporter) setRegion(region string) {
+	if r != nil {
+		r.region = region
+	}
+}
+
 func (r *usageReporter) publish(ctx context.Context, detail usage.Detail) {
 	r.publishWithOutcome(ctx, detail, false)
 }
@@ -69,10 +152,15 @@ func (r *usageReporter) publishWithOutcome(ctx context.Context, detail usage.Det
 			detail.TotalTokens = total
 		}
 	}
-	if detail.InputTokens == 0 && detail.OutputTokens == 0 && detail.ReasoningTokens == 0 && detail.CachedTokens == 0 && detail.TotalTokens == 0 && !failed {
+	if detail == (usage.Detail{}) && !failed {
 		return
 	}
 	r.once.Do(func() {
+		estimated := false
+		if detail == (usage.Detail{}) {
+			detail, estimated = r.estimate(true)
+		}
+		r.closeOutput()
 		usage.PublishRecord(ctx, usage.Record{
 			Provider:    r.provider,
 			Model:       r.model,
@@ -83,6 +171,12 @@ func (r *usageReporter) publishWithOutcome(ctx context.Context, detail usage.Det
 			RequestedAt: r.requestedAt,
 			Failed:      failed,
 			Detail:      detail,
+			Tags:        r.tags,
+			Region:      r.region,
+			Workload:    r.workload,
+			Tenant:      r.tenant,
+			ClientIP:    r.clientIP,
+			Estimated:   estimated,
 		})
 	})
 }
@@ -96,6 +190,8 @@ func (r *usageReporter) ensurePublished(ctx context.Context) {
 		return
 	}
 	r.once.Do(func() {
+		detail, estimated := r.estimate(false)
+		r.closeOutput()
 		usage.PublishRecord(ctx, usage.Record{
 			Provider:    r.provider,
 			Model:       r.model,
@@ -105,11 +201,57 @@ func (r *usageReporter) ensurePublished(ctx context.Context) {
 			AuthIndex:   r.authIndex,
 			RequestedAt: r.requestedAt,
 			Failed:      false,
-			Detail:      usage.Detail{},
+			Detail:      detail,
+			Tags:        r.tags,
+			Region:      r.region,
+			Workload:    r.workload,
+			Tenant:      r.tenant,
+			ClientIP:    r.clientIP,
+			Estimated:   estimated,
 		})
 	})
 }
 
+// closeOutput stops collecting generated text and releases it.
+func (r *usageReporter) closeOutput() {
+	if r.output == nil {
+		return
+	}
+	r.output.mu.Lock()
+	r.output.closed = true
+	r.output.text.Reset()
+	r.output.mu.Unlock()
+}
+
+// estimate counts the tokens of the client request and the generated output locally, for
+// responses without usage metadata. Failed attempts are only estimated once they produced
+// output, since nothing was consumed otherwise.
+func (r *usageReporter) estimate(failed bool) (usage.Detail, bool) {
+	var completion string
+	if r.output != nil {
+		r.output.mu.Lock()
+		completion = r.output.text.String()
+		r.output.mu.Unlock()
+	}
+	if failed && strings.TrimSpace(completion) == "" {
+		return usage.Detail{}, false
+	}
+	var detail usage.Detail
+	if len(r.prompt) > 0 && r.promptFormat != "" {
+		if count, err := tokencount.CountPrompt(r.model, sdktranslator.FromString(r.promptFormat), r.prompt); err == nil {
+			detail.InputTokens = count
+		}
+	}
+	if count, err := tokencount.CountCompletion(r.model, completion); err == nil {
+		detail.OutputTokens = count
+	}
+	detail.TotalTokens = detail.InputTokens + detail.OutputTokens
+	if detail.TotalTokens == 0 {
+		return usage.Detail{}, false
+	}
+	return detail, true
+}
+
 func apiKeyFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return ""
@@ -131,6 +273,47 @@ func apiKeyFromContext(ctx context.Context) string {
 	return ""
 }
 
+// usageWorkloadFromContext returns the workload label assigned to the request by the API handler.
+func usageWorkloadFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return ""
+	}
+	return ginCtx.GetString("usageWorkload")
+}
+
+// usageTenantFromContext returns the tenant whose API key authenticated the request.
+func usageTenantFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return ""
+	}
+	return ginCtx.GetString("tenantID")
+}
+
+// usageTagsFromContext returns the request metadata tags captured by the API handler.
+func usageTagsFromContext(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return nil
+	}
+	if v, exists := ginCtx.Get("usageTags"); exists {
+		if tags, ok := v.(map[string]string); ok {
+			return tags
+		}
+	}
+	return nil
+}
+
 func resolveUsageSource(auth *cliproxyauth.Auth, ctxAPIKey string) string {
 	if auth != nil {
 		provider := strings.TrimSpace(auth.Provider)
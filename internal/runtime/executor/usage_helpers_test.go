@@ -1,6 +1,12 @@
 package executor
 
-import "testing"
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
 
 func TestParseOpenAIUsageChatCompletions(t *testing.T) {
 	data := []byte(`{"usage":{"prompt_tokens":1,"completion_tokens":2,"total_tokens":3,"prompt_tokens_details":{"cached_tokens":4},"completion_tokens_details":{"reasoning_tokens":5}}}`)
@@ -41,3 +47,36 @@ func TestParseOpenAIUsageResponses(t *testing.T) {
 		t.Fatalf("reasoning tokens = %d, want %d", detail.ReasoningTokens, 9)
 	}
 }
+
+func TestUsageReporterEstimatesMissingUsage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("usagePromptFormat", "openai")
+	ginCtx.Set("usagePrompt", []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"Tell me a short story about a lighthouse."}]}`))
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+
+	reporter := newUsageReporter(ctx, "gemini", "gpt-4o", nil)
+	if detail, ok := reporter.estimate(true); ok || detail.TotalTokens != 0 {
+		t.Fatalf("failed attempt without output was estimated: %+v", detail)
+	}
+
+	appendAPIResponseChunk(ctx, nil, []byte(`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Once upon a time, a keeper lit the lamp."}]}}]}`))
+	appendAPIResponseChunk(ctx, nil, []byte(`data: {"candidates":[{"content":{"parts":[{"functionCall":{"name":"save","args":{"title":"Lighthouse"}}}]}}]}`))
+
+	detail, ok := reporter.estimate(false)
+	if !ok {
+		t.Fatal("expected an estimate")
+	}
+	if detail.InputTokens <= 0 || detail.OutputTokens <= 0 {
+		t.Fatalf("estimate = %+v, want prompt and completion tokens", detail)
+	}
+	if detail.TotalTokens != detail.InputTokens+detail.OutputTokens {
+		t.Fatalf("total tokens = %d, want %d", detail.TotalTokens, detail.InputTokens+detail.OutputTokens)
+	}
+
+	reporter.closeOutput()
+	appendAPIResponseChunk(ctx, nil, []byte(`{"text":"ignored after publishing"}`))
+	if detail, _ := reporter.estimate(false); detail.OutputTokens != 0 {
+		t.Fatalf("output collected after the record was published: %+v", detail)
+	}
+}
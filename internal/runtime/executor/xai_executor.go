@@ -0,0 +1,126 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	xaiDefaultBaseURL = "https://api.x.ai/v1"
+	xaiUserAgent      = "cli-proxy-xai"
+	// xaiDeferredTimeout bounds how long a deferred completion is polled for.
+	xaiDeferredTimeout = 10 * time.Minute
+)
+
+// xaiDeferredPollInterval is the delay between polls of a deferred completion.
+var xaiDeferredPollInterval = 2 * time.Second
+
+// XAIExecutor executes chat completions against the xAI (Grok) API using API keys.
+// Requests are translated to Chat Completions; reasoning_effort is mapped onto the values each
+// Grok model accepts and deferred completions are polled until their result is ready.
+type XAIExecutor struct {
+	*chatCompletionsExecutor
+}
+
+// NewXAIExecutor constructs a new executor instance.
+func NewXAIExecutor(cfg *config.Config) *XAIExecutor {
+	e := &XAIExecutor{&chatCompletionsExecutor{
+		cfg:              cfg,
+		provider:         "xai",
+		defaultBaseURL:   xaiDefaultBaseURL,
+		userAgent:        xaiUserAgent,
+		normalizeRequest: normalizeXAIRequest,
+	}}
+	e.resolveCompletion = e.awaitDeferred
+	return e
+}
+
+// normalizeXAIRequest adapts a Chat Completions body to the Grok model it targets. Only the
+// grok-3-mini family accepts reasoning_effort, and only "low" or "high"; the other reasoning
+// models reject it together with the penalty and stop parameters.
+func normalizeXAIRequest(body []byte, model string) []byte {
+	model = strings.ToLower(model)
+	if effort := gjson.GetBytes(body, "reasoning_effort"); effort.Exists() {
+		if strings.HasPrefix(model, "grok-3-mini") {
+			body, _ = sjson.SetBytes(body, "reasoning_effort", xaiReasoningEffort(effort.String()))
+		} else {
+			body, _ = sjson.DeleteBytes(body, "reasoning_effort")
+		}
+	}
+	if xaiIsReasoningModel(model) {
+		for _, field := range []string{"presence_penalty", "frequency_penalty", "stop"} {
+			body, _ = sjson.DeleteBytes(body, field)
+		}
+	}
+	if gjson.GetBytes(body, "stream").Bool() {
+		body, _ = sjson.DeleteBytes(body, "deferred")
+	}
+	for i, message := range gjson.GetBytes(body, "messages").Array() {
+		if message.Get("reasoning_content").Exists() {
+			body, _ = sjson.DeleteBytes(body, fmt.Sprintf("messages.%d.reasoning_content", i))
+		}
+	}
+	return body
+}
+
+// xaiReasoningEffort maps an OpenAI reasoning effort onto the two levels Grok accepts.
+func xaiReasoningEffort(effort string) string {
+	switch strings.ToLower(strings.TrimSpace(effort)) {
+	case "medium", "high", "xhigh":
+		return "high"
+	default:
+		return "low"
+	}
+}
+
+func xaiIsReasoningModel(model string) bool {
+	if strings.Contains(model, "non-reasoning") {
+		return false
+	}
+	return strings.HasPrefix(model, "grok-4") || strings.HasPrefix(model, "grok-3-mini") || strings.HasPrefix(model, "grok-code")
+}
+
+// awaitDeferred polls the deferred completion named by a {"request_id": ...} response until xAI
+// returns its result. Other responses are returned unchanged.
+func (e *XAIExecutor) awaitDeferred(ctx context.Context, auth *cliproxyauth.Auth, data []byte) ([]byte, error) {
+	requestID := gjson.GetBytes(data, "request_id").String()
+	if requestID == "" || gjson.GetBytes(data, "choices").Exists() {
+		return data, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, xaiDeferredTimeout)
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, statusErr{code: http.StatusGatewayTimeout, msg: fmt.Sprintf("xai executor: deferred completion %s not ready: %v", requestID, ctx.Err())}
+		case <-time.After(xaiDeferredPollInterval):
+		}
+		httpResp, err := e.send(ctx, auth, http.MethodGet, "/chat/deferred-completion/"+requestID, nil, false)
+		if err != nil {
+			return nil, err
+		}
+		result, errRead := io.ReadAll(httpResp.Body)
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("xai executor: close response body error: %v", errClose)
+		}
+		if errRead != nil {
+			recordAPIResponseError(ctx, e.cfg, errRead)
+			return nil, errRead
+		}
+		if httpResp.StatusCode == http.StatusAccepted {
+			continue
+		}
+		appendAPIResponseChunk(ctx, e.cfg, result)
+		return result, nil
+	}
+}
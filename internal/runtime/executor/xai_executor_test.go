@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestNormalizeXAIRequest(t *testing.T) {
+	body := []byte(`{"reasoning_effort":"medium","presence_penalty":1,"stop":["x"],"messages":[{"role":"assistant","content":"a","reasoning_content":"r"}]}`)
+
+	mini := normalizeXAIRequest(body, "grok-3-mini")
+	if gjson.GetBytes(mini, "reasoning_effort").String() != "high" || gjson.GetBytes(mini, "presence_penalty").Exists() || gjson.GetBytes(mini, "messages.0.reasoning_content").Exists() {
+		t.Fatalf("grok-3-mini body = %s", mini)
+	}
+	grok4 := normalizeXAIRequest(body, "grok-4")
+	if gjson.GetBytes(grok4, "reasoning_effort").Exists() || gjson.GetBytes(grok4, "stop").Exists() {
+		t.Fatalf("grok-4 body = %s", grok4)
+	}
+	grok3 := normalizeXAIRequest(body, "grok-3")
+	if gjson.GetBytes(grok3, "reasoning_effort").Exists() || !gjson.GetBytes(grok3, "stop").Exists() {
+		t.Fatalf("grok-3 body = %s", grok3)
+	}
+}
+
+func TestXAIExecutorPollsDeferredCompletionForClaude(t *testing.T) {
+	previous := xaiDeferredPollInterval
+	xaiDeferredPollInterval = time.Millisecond
+	t.Cleanup(func() { xaiDeferredPollInterval = previous })
+
+	var gotBody []byte
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/chat/completions":
+			gotBody, _ = io.ReadAll(r.Body)
+			_, _ = io.WriteString(w, `{"request_id":"req-1"}`)
+		case "/v1/chat/deferred-completion/req-1":
+			polls++
+			if polls < 2 {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			_, _ = io.WriteString(w, `{"id":"c1","model":"grok-3-mini","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","reasoning_content":"short","content":"Hi."}}],"usage":{"prompt_tokens":3,"completion_tokens":2,"completion_tokens_details":{"reasoning_tokens":5}}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	executor := NewXAIExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{Provider: "xai", Attributes: map[string]string{"api_key": "xk", "base_url": server.URL + "/v1"}}
+	payload := []byte(`{"model":"grok-3-mini","max_tokens":2048,"deferred":true,"thinking":{"type":"enabled","budget_tokens":1024},"messages":[{"role":"user","content":"hi"}]}`)
+	resp, err := executor.Execute(context.Background(), auth, cliproxyexecutor.Request{Model: "grok-3-mini", Payload: payload}, cliproxyexecutor.Options{
+		SourceFormat:    sdktranslator.FromString("claude"),
+		OriginalRequest: payload,
+	})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if polls != 2 {
+		t.Fatalf("polls = %d, want 2", polls)
+	}
+	if gjson.GetBytes(gotBody, "reasoning_effort").String() != "low" {
+		t.Fatalf("upstream body = %s", gotBody)
+	}
+	out := gjson.ParseBytes(resp.Payload)
+	if out.Get("content.0.thinking").String() != "short" || out.Get("content.1.text").String() != "Hi." {
+		t.Fatalf("claude response = %s", resp.Payload)
+	}
+}
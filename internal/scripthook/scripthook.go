@@ -0,0 +1,186 @@
+// Package scripthook runs the request-scripts configured by operators. Scripts are external
+// programs, so any interpreter (Lua, Python, a shell) can be used without rebuilding the proxy.
+// Each run is bounded by a timeout, an output cap and, on Unix-like systems, a memory limit,
+// and starts with a minimal environment.
+package scripthook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// StageInbound runs scripts on the request as received from the client.
+	StageInbound = "inbound"
+	// StageUpstream runs scripts on the request after translation to the provider format.
+	StageUpstream = "upstream"
+
+	defaultTimeout        = 2 * time.Second
+	defaultMaxOutputBytes = 8 << 20
+)
+
+// Input is the document written to a script's stdin.
+type Input struct {
+	Stage  string          `json:"stage"`
+	Model  string          `json:"model"`
+	Format string          `json:"format"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// Apply runs every script configured for stage whose model filter matches model, feeding the
+// output of one script into the next. A failing script is logged and skipped, leaving the body
+// as it was before that script ran.
+func Apply(ctx context.Context, scripts []config.RequestScript, stage, model, format string, body []byte) []byte {
+	if len(scripts) == 0 || len(body) == 0 || !json.Valid(body) {
+		return body
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	out := body
+	for i := range scripts {
+		script := &scripts[i]
+		if !strings.EqualFold(strings.TrimSpace(script.Stage), stage) || !modelMatches(script.Models, model) {
+			continue
+		}
+		updated, err := Run(ctx, script, Input{Stage: stage, Model: model, Format: format, Body: out})
+		if err != nil {
+			log.Warnf("request script %s failed, keeping request unchanged: %v", scriptName(script), err)
+			continue
+		}
+		out = updated
+	}
+	return out
+}
+
+// Run executes a single script and returns the replacement body. Empty output returns
+// in.Body unchanged; output that is not valid JSON is an error.
+func Run(ctx context.Context, script *config.RequestScript, in Input) ([]byte, error) {
+	if script == nil || len(script.Command) == 0 || strings.TrimSpace(script.Command[0]) == "" {
+		return nil, errors.New("command is empty")
+	}
+	payload, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("encode input: %w", err)
+	}
+
+	timeout := defaultTimeout
+	if raw := strings.TrimSpace(script.Timeout); raw != "" {
+		parsed, errParse := time.ParseDuration(raw)
+		if errParse != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q", script.Timeout)
+		}
+		timeout = parsed
+	}
+	maxOutput := script.MaxOutputBytes
+	if maxOutput <= 0 {
+		maxOutput = defaultMaxOutputBytes
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	name, args := commandLine(script)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = minimalEnv()
+	cmd.Stdin = bytes.NewReader(payload)
+	stdout := &limitedBuffer{limit: maxOutput}
+	stderr := &limitedBuffer{limit: 4 << 10}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+
+	if err = cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("timed out after %s", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.buf.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	if stdout.exceeded {
+		return nil, fmt.Errorf("output exceeds %d bytes", maxOutput)
+	}
+	result := bytes.TrimSpace(stdout.buf.Bytes())
+	if len(result) == 0 {
+		return in.Body, nil
+	}
+	if !json.Valid(result) {
+		return nil, errors.New("output is not valid JSON")
+	}
+	return result, nil
+}
+
+// commandLine returns the program and arguments to execute. When a memory limit is configured
+// on Unix-like systems the command is wrapped in sh so ulimit applies to the script only.
+func commandLine(script *config.RequestScript) (string, []string) {
+	if script.MaxMemoryMB <= 0 || runtime.GOOS == "windows" {
+		return script.Command[0], script.Command[1:]
+	}
+	limitKB := strconv.Itoa(script.MaxMemoryMB * 1024)
+	args := append([]string{"-c", `ulimit -v ` + limitKB + ` && exec "$@"`, "sh"}, script.Command...)
+	return "/bin/sh", args
+}
+
+// minimalEnv passes only what interpreters need to start, so proxy secrets in the environment
+// are not exposed to scripts.
+func minimalEnv() []string {
+	env := make([]string, 0, 4)
+	for _, key := range []string{"PATH", "HOME", "SYSTEMROOT", "TMPDIR"} {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+func modelMatches(patterns []string, model string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if util.MatchWildcard(pattern, model) {
+			return true
+		}
+	}
+	return false
+}
+
+func scriptName(script *config.RequestScript) string {
+	if name := strings.TrimSpace(script.Name); name != "" {
+		return name
+	}
+	return strings.Join(script.Command, " ")
+}
+
+// limitedBuffer collects up to limit bytes and records whether more were written.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	remaining := b.limit - b.buf.Len()
+	if len(p) > remaining {
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+		b.exceeded = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
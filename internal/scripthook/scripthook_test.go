@@ -0,0 +1,88 @@
+package scripthook
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	return path
+}
+
+func TestApply_RewritesMatchingStageAndModel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not available on windows")
+	}
+	rewrite := writeScript(t, `cat >/dev/null; echo '{"model":"rewritten"}'`)
+	passthrough := writeScript(t, `cat >/dev/null`)
+	scripts := []config.RequestScript{
+		{Name: "other-stage", Stage: StageInbound, Command: []string{"/bin/sh", rewrite}},
+		{Name: "other-model", Stage: StageUpstream, Command: []string{"/bin/sh", rewrite}, Models: []string{"gemini-*"}},
+		{Name: "noop", Stage: StageUpstream, Command: []string{"/bin/sh", passthrough}},
+	}
+
+	body := []byte(`{"model":"claude-x"}`)
+	if got := Apply(context.Background(), scripts, StageUpstream, "claude-x", "claude", body); string(got) != string(body) {
+		t.Fatalf("expected body unchanged, got %s", got)
+	}
+	if got := Apply(context.Background(), scripts, StageInbound, "claude-x", "openai", body); string(got) != `{"model":"rewritten"}` {
+		t.Fatalf("expected rewritten body, got %s", got)
+	}
+}
+
+func TestRun_ReceivesInputDocument(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not available on windows")
+	}
+	echo := writeScript(t, `cat`)
+	out, err := Run(context.Background(), &config.RequestScript{Command: []string{"/bin/sh", echo}}, Input{Stage: StageInbound, Model: "m", Format: "openai", Body: []byte(`{"a":1}`)})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := `{"stage":"inbound","model":"m","format":"openai","body":{"a":1}}`; string(out) != want {
+		t.Fatalf("input = %s, want %s", out, want)
+	}
+}
+
+func TestRun_Limits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not available on windows")
+	}
+	in := Input{Stage: StageUpstream, Body: []byte(`{}`)}
+
+	slow := writeScript(t, `sleep 5`)
+	if _, err := Run(context.Background(), &config.RequestScript{Command: []string{"/bin/sh", slow}, Timeout: "100ms"}, in); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+
+	large := writeScript(t, `cat >/dev/null; printf '{"x":"%0100d"}' 0`)
+	if _, err := Run(context.Background(), &config.RequestScript{Command: []string{"/bin/sh", large}, MaxOutputBytes: 16}, in); err == nil || !strings.Contains(err.Error(), "output exceeds") {
+		t.Fatalf("expected output limit error, got %v", err)
+	}
+
+	invalid := writeScript(t, `cat >/dev/null; echo not-json`)
+	if _, err := Run(context.Background(), &config.RequestScript{Command: []string{"/bin/sh", invalid}}, in); err == nil {
+		t.Fatalf("expected invalid JSON error")
+	}
+
+	env := writeScript(t, `cat >/dev/null; printf '{"secret":"%s"}' "$CLIPROXY_TEST_SECRET"`)
+	t.Setenv("CLIPROXY_TEST_SECRET", "leak")
+	out, err := Run(context.Background(), &config.RequestScript{Command: []string{"/bin/sh", env}, MaxMemoryMB: 256}, in)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if string(out) != `{"secret":""}` {
+		t.Fatalf("environment leaked to script: %s", out)
+	}
+}
@@ -0,0 +1,392 @@
+// Package selfupdate checks the project's GitHub releases for newer versions and can replace
+// the running binary with the release asset for the current platform.
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultReleaseURL is the GitHub API endpoint describing the latest release.
+	DefaultReleaseURL = "https://api.github.com/repos/router-for-me/CLIProxyAPIPlus/releases/latest"
+
+	httpUserAgent     = "CLIProxyAPIPlus-updater"
+	binaryName        = "cli-proxy-api-plus"
+	checksumAssetName = "checksums.txt"
+	maxAssetSize      = 256 << 20
+)
+
+// ErrNoUpdate is returned by Update when the running binary is already at the latest version.
+var ErrNoUpdate = errors.New("already running the latest version")
+
+// verifyBinary smoke-tests a downloaded binary before it replaces the running one.
+var verifyBinary = func(ctx context.Context, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	return exec.CommandContext(ctx, path, "-h").Run()
+}
+
+// Asset describes a downloadable file attached to a release.
+type Asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+	Digest             string `json:"digest"`
+}
+
+// Release describes a published release.
+type Release struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	HTMLURL     string    `json:"html_url"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []Asset   `json:"assets"`
+}
+
+// Version returns the release version, preferring the tag name.
+func (r *Release) Version() string {
+	if r == nil {
+		return ""
+	}
+	if version := strings.TrimSpace(r.TagName); version != "" {
+		return version
+	}
+	return strings.TrimSpace(r.Name)
+}
+
+// NewHTTPClient returns a client for release requests that honours the global proxy setting.
+func NewHTTPClient(proxyURL string, timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if proxyURL = strings.TrimSpace(proxyURL); proxyURL != "" {
+		util.SetProxy(&sdkconfig.SDKConfig{ProxyURL: proxyURL}, client)
+	}
+	return client
+}
+
+// FetchLatest retrieves the latest release metadata. An empty releaseURL uses DefaultReleaseURL.
+func FetchLatest(ctx context.Context, client *http.Client, releaseURL string) (*Release, error) {
+	if strings.TrimSpace(releaseURL) == "" {
+		releaseURL = DefaultReleaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releaseURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create release request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", httpUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("execute release request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected release status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var release Release
+	if err = json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("decode release response: %w", err)
+	}
+	if release.Version() == "" {
+		return nil, fmt.Errorf("release response is missing a version")
+	}
+	return &release, nil
+}
+
+// IsNewer reports whether latest is a newer version than current. Versions are compared by
+// their numeric components, so "v6.6.1-0" and "6.6.1-0-plus" are equal. Development builds
+// without a version number never report an update.
+func IsNewer(current, latest string) bool {
+	cur := versionNumbers(current)
+	next := versionNumbers(latest)
+	if len(cur) == 0 || len(next) == 0 {
+		return false
+	}
+	for i := 0; i < len(cur) || i < len(next); i++ {
+		var a, b int
+		if i < len(cur) {
+			a = cur[i]
+		}
+		if i < len(next) {
+			b = next[i]
+		}
+		if a != b {
+			return b > a
+		}
+	}
+	return false
+}
+
+func versionNumbers(version string) []int {
+	fields := strings.FieldsFunc(version, func(r rune) bool { return r < '0' || r > '9' })
+	numbers := make([]int, 0, len(fields))
+	for _, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+// CheckAndLog fetches the latest release and logs when it is newer than the running binary.
+// It is used for the opt-in startup check and never fails the caller.
+func CheckAndLog(ctx context.Context, proxyURL string) {
+	release, err := FetchLatest(ctx, NewHTTPClient(proxyURL, 10*time.Second), "")
+	if err != nil {
+		log.Debugf("update check failed: %v", err)
+		return
+	}
+	if IsNewer(buildinfo.Version, release.Version()) {
+		log.Infof("a new version is available: %s (running %s). See %s or run with -update", release.Version(), buildinfo.Version, release.HTMLURL)
+	}
+}
+
+// Update installs the latest release over the binary at exePath. The archive checksum is
+// verified against the release's checksums.txt (or the asset digest reported by GitHub),
+// the extracted binary is smoke-tested, and the previous binary is kept as <exe>.old.
+// It returns the installed version.
+func Update(ctx context.Context, client *http.Client, releaseURL, exePath string) (string, error) {
+	release, err := FetchLatest(ctx, client, releaseURL)
+	if err != nil {
+		return "", err
+	}
+	if !IsNewer(buildinfo.Version, release.Version()) {
+		return release.Version(), ErrNoUpdate
+	}
+
+	asset, err := selectAsset(release.Assets, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
+	expected := parseDigest(asset.Digest)
+	if checksums := findAsset(release.Assets, checksumAssetName); checksums != nil {
+		data, errDownload := download(ctx, client, checksums.BrowserDownloadURL)
+		if errDownload != nil {
+			return "", fmt.Errorf("download checksums: %w", errDownload)
+		}
+		if sum := lookupChecksum(data, asset.Name); sum != "" {
+			expected = sum
+		}
+	}
+	if expected == "" {
+		return "", fmt.Errorf("no checksum published for %s, refusing to install", asset.Name)
+	}
+
+	archive, err := download(ctx, client, asset.BrowserDownloadURL)
+	if err != nil {
+		return "", fmt.Errorf("download %s: %w", asset.Name, err)
+	}
+	sum := sha256.Sum256(archive)
+	if got := hex.EncodeToString(sum[:]); got != expected {
+		return "", fmt.Errorf("checksum mismatch for %s: expected %s got %s", asset.Name, expected, got)
+	}
+
+	binary, err := extractBinary(asset.Name, archive)
+	if err != nil {
+		return "", err
+	}
+	if err = replaceExecutable(ctx, exePath, binary); err != nil {
+		return "", err
+	}
+	return release.Version(), nil
+}
+
+// selectAsset picks the release archive built for goos/goarch.
+func selectAsset(assets []Asset, goos, goarch string) (*Asset, error) {
+	suffix := ".tar.gz"
+	if goos == "windows" {
+		suffix = ".zip"
+	}
+	platform := "_" + goos + "_" + goarch
+	for i := range assets {
+		name := strings.ToLower(assets[i].Name)
+		if strings.HasSuffix(name, suffix) && strings.Contains(name, platform) {
+			return &assets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no release asset for %s/%s", goos, goarch)
+}
+
+func findAsset(assets []Asset, name string) *Asset {
+	for i := range assets {
+		if strings.EqualFold(assets[i].Name, name) {
+			return &assets[i]
+		}
+	}
+	return nil
+}
+
+// lookupChecksum returns the sha256 recorded for name in a goreleaser checksums.txt file.
+func lookupChecksum(data []byte, name string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == name {
+			return strings.ToLower(fields[0])
+		}
+	}
+	return ""
+}
+
+func parseDigest(digest string) string {
+	digest = strings.TrimSpace(digest)
+	if idx := strings.Index(digest, ":"); idx >= 0 {
+		digest = digest[idx+1:]
+	}
+	return strings.ToLower(strings.TrimSpace(digest))
+}
+
+func download(ctx context.Context, client *http.Client, downloadURL string) ([]byte, error) {
+	if strings.TrimSpace(downloadURL) == "" {
+		return nil, fmt.Errorf("empty download url")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", httpUserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected download status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAssetSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxAssetSize {
+		return nil, fmt.Errorf("asset exceeds %d bytes", maxAssetSize)
+	}
+	return data, nil
+}
+
+// extractBinary returns the server binary contained in a release archive.
+func extractBinary(archiveName string, data []byte) ([]byte, error) {
+	if strings.HasSuffix(strings.ToLower(archiveName), ".zip") {
+		reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("open zip archive: %w", err)
+		}
+		for _, file := range reader.File {
+			if !isBinaryEntry(file.Name) || file.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer func() {
+				_ = rc.Close()
+			}()
+			return io.ReadAll(io.LimitReader(rc, maxAssetSize))
+		}
+		return nil, fmt.Errorf("binary %s not found in %s", binaryName, archiveName)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("open gzip archive: %w", err)
+	}
+	defer func() {
+		_ = gz.Close()
+	}()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read tar archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg && isBinaryEntry(header.Name) {
+			return io.ReadAll(io.LimitReader(tr, maxAssetSize))
+		}
+	}
+	return nil, fmt.Errorf("binary %s not found in %s", binaryName, archiveName)
+}
+
+func isBinaryEntry(name string) bool {
+	base := filepath.Base(filepath.ToSlash(name))
+	return base == binaryName || base == binaryName+".exe"
+}
+
+// replaceExecutable swaps the binary at exePath for data. The current binary is kept as
+// <exe>.old for manual rollback and is moved back automatically if the swap fails.
+func replaceExecutable(ctx context.Context, exePath string, data []byte) error {
+	info, err := os.Stat(exePath)
+	if err != nil {
+		return fmt.Errorf("stat executable: %w", err)
+	}
+	dir := filepath.Dir(exePath)
+	tmp, err := os.CreateTemp(dir, ".cli-proxy-api-update-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		_ = os.Remove(tmpName)
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write new binary: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("write new binary: %w", err)
+	}
+	if err = os.Chmod(tmpName, info.Mode().Perm()|0o100); err != nil {
+		return fmt.Errorf("chmod new binary: %w", err)
+	}
+	if err = verifyBinary(ctx, tmpName); err != nil {
+		return fmt.Errorf("new binary failed to start: %w", err)
+	}
+
+	backup := exePath + ".old"
+	_ = os.Remove(backup)
+	if err = os.Rename(exePath, backup); err != nil {
+		return fmt.Errorf("back up current binary: %w", err)
+	}
+	if err = os.Rename(tmpName, exePath); err != nil {
+		if errRollback := os.Rename(backup, exePath); errRollback != nil {
+			return fmt.Errorf("install new binary: %w (rollback failed: %v, previous binary left at %s)", err, errRollback, backup)
+		}
+		return fmt.Errorf("install new binary: %w (previous binary restored)", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
+)
+
+func TestIsNewer(t *testing.T) {
+	cases := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"6.6.1-0-plus", "v6.6.2-0", true},
+		{"6.6.1-0-plus", "v6.6.1-0", false},
+		{"6.6.10-0-plus", "v6.6.9-0", false},
+		{"6.6.1-0-plus", "v6.6.1-1", true},
+		{"dev", "v6.6.2-0", false},
+	}
+	for _, tc := range cases {
+		if got := IsNewer(tc.current, tc.latest); got != tc.want {
+			t.Errorf("IsNewer(%q, %q) = %v, want %v", tc.current, tc.latest, got, tc.want)
+		}
+	}
+}
+
+func TestSelectAsset(t *testing.T) {
+	assets := []Asset{
+		{Name: "checksums.txt"},
+		{Name: "CLIProxyAPIPlus_6.6.2-0_linux_amd64.tar.gz"},
+		{Name: "CLIProxyAPIPlus_6.6.2-0_windows_amd64.zip"},
+	}
+	asset, err := selectAsset(assets, "windows", "amd64")
+	if err != nil || asset.Name != "CLIProxyAPIPlus_6.6.2-0_windows_amd64.zip" {
+		t.Fatalf("selectAsset windows = %+v, %v", asset, err)
+	}
+	if _, err = selectAsset(assets, "darwin", "arm64"); err == nil {
+		t.Fatalf("expected error for missing platform")
+	}
+}
+
+func TestUpdate_VerifiesChecksumAndSwapsBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("tar.gz fixture does not apply to windows assets")
+	}
+	archive := buildTarGz(t, map[string]string{"README.md": "readme", binaryName: "new-binary"})
+	sum := sha256.Sum256(archive)
+	assetName := "CLIProxyAPIPlus_9.9.9-0_" + runtime.GOOS + "_" + runtime.GOARCH + ".tar.gz"
+
+	checksum := hex.EncodeToString(sum[:])
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/release":
+			_ = json.NewEncoder(w).Encode(Release{
+				TagName: "v9.9.9-0",
+				Assets: []Asset{
+					{Name: assetName, BrowserDownloadURL: server.URL + "/asset"},
+					{Name: checksumAssetName, BrowserDownloadURL: server.URL + "/checksums"},
+				},
+			})
+		case "/asset":
+			_, _ = w.Write(archive)
+		case "/checksums":
+			_, _ = w.Write([]byte(checksum + "  " + assetName + "\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	prevVersion, prevVerify := buildinfo.Version, verifyBinary
+	buildinfo.Version = "6.0.0-0-plus"
+	verifyBinary = func(context.Context, string) error { return nil }
+	t.Cleanup(func() { buildinfo.Version, verifyBinary = prevVersion, prevVerify })
+
+	exePath := filepath.Join(t.TempDir(), binaryName)
+	if err := os.WriteFile(exePath, []byte("old-binary"), 0o755); err != nil {
+		t.Fatalf("write exe: %v", err)
+	}
+
+	version, err := Update(context.Background(), server.Client(), server.URL+"/release", exePath)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if version != "v9.9.9-0" {
+		t.Fatalf("version = %q", version)
+	}
+	if data, _ := os.ReadFile(exePath); string(data) != "new-binary" {
+		t.Fatalf("exe contents = %q", data)
+	}
+	if data, _ := os.ReadFile(exePath + ".old"); string(data) != "old-binary" {
+		t.Fatalf("backup contents = %q", data)
+	}
+
+	// A corrupted archive must be rejected before anything is replaced.
+	archive = append(archive, 0)
+	if _, err = Update(context.Background(), server.Client(), server.URL+"/release", exePath); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if data, _ := os.ReadFile(exePath); string(data) != "new-binary" {
+		t.Fatalf("exe modified after failed update: %q", data)
+	}
+}
+
+func buildTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("tar write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar close: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,323 @@
+// Package sysprompt applies the system-prompts rules configured by operators. Rules add to or
+// replace the system prompt of an upstream request after translation, in the upstream's own
+// representation: Chat Completions messages, Responses instructions, Codex developer input,
+// Claude system blocks or Gemini systemInstruction parts.
+package sysprompt
+
+import (
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// ModePrepend places the prompt before the client's system prompt.
+	ModePrepend = "prepend"
+	// ModeAppend places the prompt after the client's system prompt.
+	ModeAppend = "append"
+	// ModeOverride replaces the client's system prompt.
+	ModeOverride = "override"
+
+	// claudeCodeIdentity is the first system block cloaked Claude requests must keep.
+	claudeCodeIdentity = "You are Claude Code, Anthropic's official CLI for Claude."
+)
+
+// Vars are the values available to prompt templates.
+type Vars struct {
+	Now     time.Time
+	Model   string
+	KeyName string
+}
+
+// Apply injects the prompts of every rule matching the model and apiKey into body, an upstream
+// request in protocol whose fields live under root (e.g. "request" for Gemini CLI envelopes).
+// Rules match either the upstream model or the model the client requested, so aliases work.
+// Requests in unsupported protocols or with invalid JSON are returned unchanged.
+func Apply(cfg config.SystemPromptConfig, protocol, root, model, requestedModel, apiKey string, body []byte, now time.Time) []byte {
+	if len(cfg.Rules) == 0 || len(body) == 0 || !gjson.ValidBytes(body) {
+		return body
+	}
+	if requestedModel == "" {
+		requestedModel = model
+	}
+	candidates := []string{baseModelName(model), baseModelName(requestedModel)}
+	vars := Vars{Now: now.UTC(), Model: requestedModel, KeyName: keyName(cfg.KeyNames, apiKey)}
+	if root != "" && !strings.HasSuffix(root, ".") {
+		root += "."
+	}
+	out := body
+	for i := range cfg.Rules {
+		rule := &cfg.Rules[i]
+		if strings.TrimSpace(rule.Prompt) == "" || !modelMatches(rule.Models, candidates) || !keyMatches(rule.APIKeys, apiKey) {
+			continue
+		}
+		out = inject(protocol, root, normalizeMode(rule.Mode), Render(rule.Prompt, vars), out)
+	}
+	return out
+}
+
+func baseModelName(model string) string {
+	return strings.TrimPrefix(thinking.ParseSuffix(strings.TrimSpace(model)).ModelName, "models/")
+}
+
+// Render expands the template variables in prompt.
+func Render(prompt string, vars Vars) string {
+	if !strings.Contains(prompt, "{{") {
+		return prompt
+	}
+	return strings.NewReplacer(
+		"{{date}}", vars.Now.Format("2006-01-02"),
+		"{{datetime}}", vars.Now.Format(time.RFC3339),
+		"{{model}}", vars.Model,
+		"{{key_name}}", vars.KeyName,
+	).Replace(prompt)
+}
+
+func keyName(names map[string]string, apiKey string) string {
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return "anonymous"
+	}
+	if name := strings.TrimSpace(names[apiKey]); name != "" {
+		return name
+	}
+	return util.HashAPIKey(apiKey)
+}
+
+func normalizeMode(mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case ModeAppend:
+		return ModeAppend
+	case ModeOverride:
+		return ModeOverride
+	default:
+		return ModePrepend
+	}
+}
+
+func inject(protocol, root, mode, prompt string, body []byte) []byte {
+	switch protocol {
+	case "openai":
+		return injectChatMessages(mode, prompt, body)
+	case "openai-response":
+		return injectResponses(mode, prompt, body)
+	case "codex":
+		return injectCodex(mode, prompt, body)
+	case "claude":
+		return injectClaude(mode, prompt, body)
+	case "gemini", "gemini-cli", "antigravity":
+		return injectGemini(mode, prompt, body, root)
+	}
+	return body
+}
+
+// injectChatMessages edits the leading system/developer messages of a Chat Completions request.
+// Legacy completions requests have no messages and are left unchanged.
+func injectChatMessages(mode, prompt string, body []byte) []byte {
+	return injectMessage(mode, "messages", map[string]any{"role": "system", "content": prompt}, body)
+}
+
+// injectMessage inserts message into the array at path: first for prepend and override, after
+// the leading system/developer items for append. Override drops the other system/developer items.
+func injectMessage(mode, path string, message any, body []byte) []byte {
+	items := gjson.GetBytes(body, path)
+	if !items.IsArray() {
+		return body
+	}
+	var kept []any
+	leading := 0
+	counting := true
+	for _, item := range items.Array() {
+		role := item.Get("role").String()
+		isSystem := role == "system" || role == "developer"
+		if counting && isSystem {
+			leading++
+		} else {
+			counting = false
+		}
+		if mode == ModeOverride && isSystem {
+			continue
+		}
+		kept = append(kept, rawJSON(item.Raw))
+	}
+	var out []any
+	switch mode {
+	case ModeOverride, ModePrepend:
+		out = append([]any{message}, kept...)
+	case ModeAppend:
+		out = append(out, kept[:leading]...)
+		out = append(out, message)
+		out = append(out, kept[leading:]...)
+	}
+	updated, err := sjson.SetBytes(body, path, out)
+	if err != nil {
+		return body
+	}
+	return updated
+}
+
+// injectResponses edits the instructions of a Responses request. Override also drops system and
+// developer items from the input.
+func injectResponses(mode, prompt string, body []byte) []byte {
+	out := body
+	if mode == ModeOverride {
+		if input := gjson.GetBytes(out, "input"); input.IsArray() {
+			var kept []any
+			for _, item := range input.Array() {
+				if role := item.Get("role").String(); role == "system" || role == "developer" {
+					continue
+				}
+				kept = append(kept, rawJSON(item.Raw))
+			}
+			if kept == nil {
+				kept = []any{}
+			}
+			out, _ = sjson.SetBytes(out, "input", kept)
+		}
+	}
+	updated, err := sjson.SetBytes(out, "instructions", joinText(mode, gjson.GetBytes(out, "instructions").String(), prompt))
+	if err != nil {
+		return body
+	}
+	return updated
+}
+
+// injectCodex adds a developer message to the input of a Codex request. The Codex backend keeps
+// its own instructions, so client system prompts travel as developer messages there too.
+func injectCodex(mode, prompt string, body []byte) []byte {
+	message := map[string]any{
+		"type":    "message",
+		"role":    "developer",
+		"content": []any{map[string]any{"type": "input_text", "text": prompt}},
+	}
+	return injectMessage(mode, "input", message, body)
+}
+
+// injectClaude edits the system field, keeping the client's choice of a string or content blocks.
+// A leading Claude Code identity block added by cloaking stays first, even on override.
+func injectClaude(mode, prompt string, body []byte) []byte {
+	system := gjson.GetBytes(body, "system")
+	existing := system.Array()
+	var identity []any
+	if system.IsArray() && len(existing) > 0 && existing[0].Get("text").String() == claudeCodeIdentity {
+		identity = []any{rawJSON(existing[0].Raw)}
+		existing = existing[1:]
+	}
+	block := map[string]any{"type": "text", "text": prompt}
+	if mode == ModeOverride && identity != nil {
+		updated, err := sjson.SetBytes(body, "system", append(identity, block))
+		if err != nil {
+			return body
+		}
+		return updated
+	}
+	if mode != ModeOverride && system.IsArray() {
+		blocks := make([]any, 0, len(existing)+2)
+		blocks = append(blocks, identity...)
+		if mode == ModePrepend {
+			blocks = append(blocks, block)
+		}
+		for _, existing := range existing {
+			blocks = append(blocks, rawJSON(existing.Raw))
+		}
+		if mode == ModeAppend {
+			blocks = append(blocks, block)
+		}
+		updated, err := sjson.SetBytes(body, "system", blocks)
+		if err != nil {
+			return body
+		}
+		return updated
+	}
+	updated, err := sjson.SetBytes(body, "system", joinText(mode, system.String(), prompt))
+	if err != nil {
+		return body
+	}
+	return updated
+}
+
+// injectGemini edits the systemInstruction parts, accepting the snake_case spelling as input.
+func injectGemini(mode, prompt string, body []byte, root string) []byte {
+	path := root + "systemInstruction"
+	existing := gjson.GetBytes(body, path)
+	out := body
+	if snake := gjson.GetBytes(body, root+"system_instruction"); snake.Exists() {
+		if !existing.Exists() {
+			existing = snake
+		}
+		out, _ = sjson.DeleteBytes(out, root+"system_instruction")
+	}
+	part := map[string]any{"text": prompt}
+	parts := []any{part}
+	if mode != ModeOverride {
+		parts = parts[:0]
+		if mode == ModePrepend {
+			parts = append(parts, part)
+		}
+		for _, existingPart := range existing.Get("parts").Array() {
+			parts = append(parts, rawJSON(existingPart.Raw))
+		}
+		if mode == ModeAppend {
+			parts = append(parts, part)
+		}
+	}
+	instruction := map[string]any{"parts": parts}
+	if role := existing.Get("role").String(); role != "" {
+		instruction["role"] = role
+	}
+	updated, err := sjson.SetBytes(out, path, instruction)
+	if err != nil {
+		return body
+	}
+	return updated
+}
+
+func joinText(mode, existing, prompt string) string {
+	if mode == ModeOverride || strings.TrimSpace(existing) == "" {
+		return prompt
+	}
+	if mode == ModeAppend {
+		return existing + "\n\n" + prompt
+	}
+	return prompt + "\n\n" + existing
+}
+
+// rawJSON embeds an existing JSON value unchanged when marshalled by sjson.
+type rawJSON string
+
+func (r rawJSON) MarshalJSON() ([]byte, error) { return []byte(r), nil }
+
+func keyMatches(keys []string, apiKey string) bool {
+	if len(keys) == 0 {
+		return true
+	}
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return false
+	}
+	for _, key := range keys {
+		if strings.TrimSpace(key) == apiKey {
+			return true
+		}
+	}
+	return false
+}
+
+func modelMatches(patterns []string, models []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		for _, model := range models {
+			if util.MatchWildcard(pattern, model) {
+				return true
+			}
+		}
+	}
+	return false
+}
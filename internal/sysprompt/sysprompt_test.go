@@ -0,0 +1,141 @@
+package sysprompt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+var testNow = time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
+func TestApplyPrependsToChatMessages(t *testing.T) {
+	cfg := config.SystemPromptConfig{
+		KeyNames: map[string]string{"key-a": "team-a"},
+		Rules:    []config.SystemPromptRule{{Prompt: "Date {{date}} for {{key_name}} on {{model}}"}},
+	}
+	body := []byte(`{"model":"gpt-5","messages":[{"role":"system","content":"client"},{"role":"user","content":"hi"}]}`)
+	out := Apply(cfg, "openai", "", "gpt-5", "", "key-a", body, testNow)
+	messages := gjson.GetBytes(out, "messages").Array()
+	if len(messages) != 3 {
+		t.Fatalf("messages = %d, want 3: %s", len(messages), out)
+	}
+	if got := messages[0].Get("content").String(); got != "Date 2026-03-04 for team-a on gpt-5" {
+		t.Fatalf("injected prompt = %q", got)
+	}
+	if got := messages[1].Get("content").String(); got != "client" {
+		t.Fatalf("client system prompt = %q, want client", got)
+	}
+}
+
+func TestApplyAppendAfterLeadingSystemMessages(t *testing.T) {
+	cfg := config.SystemPromptConfig{Rules: []config.SystemPromptRule{{Mode: "append", Prompt: "policy"}}}
+	body := []byte(`{"messages":[{"role":"developer","content":"client"},{"role":"user","content":"hi"}]}`)
+	out := Apply(cfg, "openai", "", "gpt-5", "", "", body, testNow)
+	if got := gjson.GetBytes(out, "messages.1.content").String(); got != "policy" {
+		t.Fatalf("messages.1.content = %q, want policy: %s", got, out)
+	}
+	if got := gjson.GetBytes(out, "messages.2.role").String(); got != "user" {
+		t.Fatalf("messages.2.role = %q, want user", got)
+	}
+}
+
+func TestApplyOverrideClaudeSystem(t *testing.T) {
+	cfg := config.SystemPromptConfig{Rules: []config.SystemPromptRule{{Models: []string{"claude-*"}, Mode: "override", Prompt: "only this"}}}
+	body := []byte(`{"system":[{"type":"text","text":"client"}],"messages":[]}`)
+	out := Apply(cfg, "claude", "", "claude-sonnet-4-5(high)", "", "", body, testNow)
+	if got := gjson.GetBytes(out, "system").String(); got != "only this" {
+		t.Fatalf("system = %q, want only this", got)
+	}
+}
+
+func TestApplyPrependKeepsClaudeBlocks(t *testing.T) {
+	cfg := config.SystemPromptConfig{Rules: []config.SystemPromptRule{{Prompt: "policy"}}}
+	body := []byte(`{"system":[{"type":"text","text":"client","cache_control":{"type":"ephemeral"}}]}`)
+	out := Apply(cfg, "claude", "", "claude-sonnet-4-5", "", "", body, testNow)
+	blocks := gjson.GetBytes(out, "system").Array()
+	if len(blocks) != 2 || blocks[0].Get("text").String() != "policy" || !blocks[1].Get("cache_control").Exists() {
+		t.Fatalf("unexpected system blocks: %s", out)
+	}
+}
+
+func TestApplyGeminiAndResponses(t *testing.T) {
+	cfg := config.SystemPromptConfig{Rules: []config.SystemPromptRule{{Mode: "append", Prompt: "policy"}}}
+
+	gemini := Apply(cfg, "gemini", "", "gemini-2.5-pro", "", "", []byte(`{"system_instruction":{"parts":[{"text":"client"}]},"contents":[]}`), testNow)
+	if gjson.GetBytes(gemini, "system_instruction").Exists() {
+		t.Fatalf("expected snake_case field to be normalized: %s", gemini)
+	}
+	if got := gjson.GetBytes(gemini, "systemInstruction.parts.1.text").String(); got != "policy" {
+		t.Fatalf("gemini appended part = %q, want policy", got)
+	}
+
+	cli := Apply(cfg, "gemini-cli", "request", "gemini-2.5-pro", "", "", []byte(`{"request":{"contents":[]}}`), testNow)
+	if got := gjson.GetBytes(cli, "request.systemInstruction.parts.0.text").String(); got != "policy" {
+		t.Fatalf("gemini-cli part = %q, want policy", got)
+	}
+
+	responses := Apply(cfg, "openai-response", "", "gpt-5", "", "", []byte(`{"instructions":"client","input":"hi"}`), testNow)
+	if got := gjson.GetBytes(responses, "instructions").String(); got != "client\n\npolicy" {
+		t.Fatalf("instructions = %q", got)
+	}
+}
+
+func TestApplySkipsNonMatchingRules(t *testing.T) {
+	cfg := config.SystemPromptConfig{Rules: []config.SystemPromptRule{
+		{Models: []string{"claude-*"}, Prompt: "claude only"},
+		{APIKeys: []string{"key-a"}, Prompt: "key-a only"},
+	}}
+	body := []byte(`{"messages":[{"role":"user","content":"hi"}]}`)
+	out := Apply(cfg, "openai", "", "gpt-5", "", "key-b", body, testNow)
+	if string(out) != string(body) {
+		t.Fatalf("expected unchanged body, got %s", out)
+	}
+}
+
+func TestRenderUnnamedKeyUsesHash(t *testing.T) {
+	cfg := config.SystemPromptConfig{Rules: []config.SystemPromptRule{{Prompt: "{{key_name}} {{datetime}}"}}}
+	out := Apply(cfg, "openai", "", "gpt-5", "", "secret-key", []byte(`{"messages":[]}`), testNow)
+	got := gjson.GetBytes(out, "messages.0.content").String()
+	if strings.Contains(got, "secret-key") {
+		t.Fatalf("raw API key leaked into prompt: %q", got)
+	}
+	if !strings.HasSuffix(got, " 2026-03-04T05:06:07Z") {
+		t.Fatalf("rendered prompt = %q", got)
+	}
+}
+
+func TestApplyMatchesRequestedAlias(t *testing.T) {
+	cfg := config.SystemPromptConfig{Rules: []config.SystemPromptRule{{Models: []string{"team-model"}, Prompt: "{{model}}"}}}
+	out := Apply(cfg, "openai", "", "upstream-model", "team-model", "", []byte(`{"messages":[]}`), testNow)
+	if got := gjson.GetBytes(out, "messages.0.content").String(); got != "team-model" {
+		t.Fatalf("alias rule prompt = %q, want team-model", got)
+	}
+}
+
+func TestApplyCodexDeveloperMessage(t *testing.T) {
+	cfg := config.SystemPromptConfig{Rules: []config.SystemPromptRule{{Mode: "override", Prompt: "policy"}}}
+	body := []byte(`{"instructions":"codex","input":[{"type":"message","role":"developer","content":[{"type":"input_text","text":"client"}]},{"type":"message","role":"user","content":[]}]}`)
+	out := Apply(cfg, "codex", "", "gpt-5", "", "", body, testNow)
+	if got := gjson.GetBytes(out, "instructions").String(); got != "codex" {
+		t.Fatalf("codex instructions must be kept, got %q", got)
+	}
+	input := gjson.GetBytes(out, "input").Array()
+	if len(input) != 2 || input[0].Get("content.0.text").String() != "policy" || input[1].Get("role").String() != "user" {
+		t.Fatalf("unexpected codex input: %s", out)
+	}
+}
+
+func TestApplyKeepsClaudeCodeIdentityFirst(t *testing.T) {
+	body := []byte(`{"system":[{"type":"text","text":"` + claudeCodeIdentity + `"},{"type":"text","text":"client"}]}`)
+	for _, mode := range []string{ModePrepend, ModeOverride} {
+		cfg := config.SystemPromptConfig{Rules: []config.SystemPromptRule{{Mode: mode, Prompt: "policy"}}}
+		out := Apply(cfg, "claude", "", "claude-sonnet-4-5", "", "", body, testNow)
+		blocks := gjson.GetBytes(out, "system").Array()
+		if len(blocks) < 2 || blocks[0].Get("text").String() != claudeCodeIdentity || blocks[1].Get("text").String() != "policy" {
+			t.Fatalf("%s: unexpected system blocks: %s", mode, out)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+// Package telemetry implements the opt-in anonymous telemetry reporter. When enabled it counts
+// requests and failures per provider and error responses per class, and periodically POSTs the
+// aggregate counters together with the build version and platform to the configured endpoint.
+// Reports never carry request content, model names, API keys, account identifiers or addresses.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultInterval = 24 * time.Hour
+	minInterval     = time.Hour
+	sendTimeout     = 30 * time.Second
+
+	// otherProvider collects providers outside the built-in set, such as OpenAI-compatible
+	// upstreams whose configured names could identify the deployment.
+	otherProvider = "openai-compatibility"
+)
+
+// knownProviders are the provider identifiers reported as-is.
+var knownProviders = map[string]struct{}{
+	"aistudio":       {},
+	"antigravity":    {},
+	"claude":         {},
+	"codex":          {},
+	"gemini":         {},
+	"gemini-cli":     {},
+	"github-copilot": {},
+	"iflow":          {},
+	"kiro":           {},
+	"qwen":           {},
+	"vertex":         {},
+}
+
+// ProviderCounts holds the request and failure counts of one provider.
+type ProviderCounts struct {
+	Requests int64 `json:"requests"`
+	Failures int64 `json:"failures"`
+}
+
+// Report is the payload sent to the telemetry endpoint.
+type Report struct {
+	Version         string                    `json:"version"`
+	OS              string                    `json:"os"`
+	Arch            string                    `json:"arch"`
+	IntervalSeconds int64                     `json:"interval_seconds"`
+	Providers       map[string]ProviderCounts `json:"providers"`
+	Errors          map[string]int64          `json:"errors"`
+}
+
+// Counters accumulates the aggregate counters between two reports.
+type Counters struct {
+	mu        sync.Mutex
+	providers map[string]ProviderCounts
+	errors    map[string]int64
+}
+
+// NewCounters returns empty counters.
+func NewCounters() *Counters {
+	return &Counters{providers: make(map[string]ProviderCounts), errors: make(map[string]int64)}
+}
+
+// RecordRequest counts one upstream request for provider.
+func (c *Counters) RecordRequest(provider string, failed bool) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if provider == "" {
+		return
+	}
+	if _, ok := knownProviders[provider]; !ok {
+		provider = otherProvider
+	}
+	c.mu.Lock()
+	counts := c.providers[provider]
+	counts.Requests++
+	if failed {
+		counts.Failures++
+	}
+	c.providers[provider] = counts
+	c.mu.Unlock()
+}
+
+// RecordError counts one error response returned to a client with the given HTTP status.
+func (c *Counters) RecordError(status int) {
+	c.mu.Lock()
+	c.errors[ErrorClass(status)]++
+	c.mu.Unlock()
+}
+
+// take returns the current counters and resets them.
+func (c *Counters) take() (map[string]ProviderCounts, map[string]int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	providers, errors := c.providers, c.errors
+	c.providers = make(map[string]ProviderCounts)
+	c.errors = make(map[string]int64)
+	return providers, errors
+}
+
+// restore adds counters back after a failed report so they are sent with the next one.
+func (c *Counters) restore(providers map[string]ProviderCounts, errors map[string]int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for provider, counts := range providers {
+		current := c.providers[provider]
+		current.Requests += counts.Requests
+		current.Failures += counts.Failures
+		c.providers[provider] = current
+	}
+	for class, count := range errors {
+		c.errors[class] += count
+	}
+}
+
+// Report builds the next report and resets the counters.
+func (c *Counters) Report(interval time.Duration) Report {
+	providers, errors := c.take()
+	return Report{
+		Version:         buildinfo.Version,
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+		IntervalSeconds: int64(interval / time.Second),
+		Providers:       providers,
+		Errors:          errors,
+	}
+}
+
+// ErrorClass maps an HTTP status to the coarse error class reported by telemetry.
+func ErrorClass(status int) string {
+	switch {
+	case status == http.StatusBadRequest:
+		return "bad_request"
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "unauthorized"
+	case status == http.StatusNotFound:
+		return "not_found"
+	case status == http.StatusRequestEntityTooLarge:
+		return "too_large"
+	case status == http.StatusTooManyRequests:
+		return "rate_limited"
+	case status >= 400 && status < 500:
+		return "client_error"
+	case status == http.StatusBadGateway || status == http.StatusServiceUnavailable:
+		return "upstream_unavailable"
+	case status == http.StatusGatewayTimeout:
+		return "timeout"
+	default:
+		return "upstream_error"
+	}
+}
+
+var (
+	enabled  atomic.Bool
+	counters = NewCounters()
+
+	reporterMu     sync.Mutex
+	reporterCancel context.CancelFunc
+)
+
+func init() {
+	coreusage.RegisterPlugin(plugin{})
+}
+
+// plugin feeds usage records into the counters while telemetry is enabled.
+type plugin struct{}
+
+// HandleUsage implements coreusage.Plugin.
+func (plugin) HandleUsage(_ context.Context, record coreusage.Record) {
+	if enabled.Load() {
+		counters.RecordRequest(record.Provider, record.Failed)
+	}
+}
+
+// RecordError counts an error response with the given HTTP status while telemetry is enabled.
+func RecordError(status int) {
+	if enabled.Load() {
+		counters.RecordError(status)
+	}
+}
+
+// Configure applies the telemetry settings, restarting the reporter. Disabling telemetry stops
+// counting and drops the counters collected so far.
+func Configure(cfg *config.Config) {
+	reporterMu.Lock()
+	defer reporterMu.Unlock()
+	if reporterCancel != nil {
+		reporterCancel()
+		reporterCancel = nil
+	}
+	if cfg == nil || !cfg.Telemetry.Enabled {
+		enabled.Store(false)
+		counters.take()
+		return
+	}
+	endpoint := strings.TrimSpace(cfg.Telemetry.Endpoint)
+	if endpoint == "" {
+		log.Warn("telemetry: enabled without an endpoint, not reporting")
+		enabled.Store(false)
+		return
+	}
+	interval := defaultInterval
+	if raw := strings.TrimSpace(cfg.Telemetry.Interval); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			interval = parsed
+		} else {
+			log.Warnf("telemetry: invalid interval %q, using %s", raw, defaultInterval)
+		}
+	}
+	if interval < minInterval {
+		interval = minInterval
+	}
+	client := &http.Client{Timeout: sendTimeout}
+	util.SetProxy(&cfg.SDKConfig, client)
+
+	enabled.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	reporterCancel = cancel
+	go run(ctx, client, endpoint, interval)
+	log.Infof("telemetry: reporting anonymous aggregate counters every %s", interval)
+}
+
+func run(ctx context.Context, client *http.Client, endpoint string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report := counters.Report(interval)
+			if err := Send(ctx, client, endpoint, report); err != nil {
+				log.Debugf("telemetry: %v", err)
+				counters.restore(report.Providers, report.Errors)
+			}
+		}
+	}
+}
+
+// Send POSTs report to endpoint as JSON.
+func Send(ctx context.Context, client *http.Client, endpoint string, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("encode report: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send report: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("send report: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
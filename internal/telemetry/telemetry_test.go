@@ -0,0 +1,68 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCountersReportAndReset(t *testing.T) {
+	c := NewCounters()
+	c.RecordRequest("claude", false)
+	c.RecordRequest("Claude", true)
+	c.RecordRequest("my-private-upstream", false)
+	c.RecordError(http.StatusTooManyRequests)
+	c.RecordError(http.StatusBadGateway)
+	c.RecordError(http.StatusTooManyRequests)
+
+	report := c.Report(time.Hour)
+	if got := report.Providers["claude"]; got.Requests != 2 || got.Failures != 1 {
+		t.Fatalf("claude counts = %+v", got)
+	}
+	if _, ok := report.Providers["my-private-upstream"]; ok {
+		t.Fatal("custom provider name must not be reported")
+	}
+	if got := report.Providers[otherProvider].Requests; got != 1 {
+		t.Fatalf("%s requests = %d, want 1", otherProvider, got)
+	}
+	if report.Errors["rate_limited"] != 2 || report.Errors["upstream_unavailable"] != 1 {
+		t.Fatalf("errors = %v", report.Errors)
+	}
+	if report.IntervalSeconds != 3600 {
+		t.Fatalf("interval = %d", report.IntervalSeconds)
+	}
+
+	next := c.Report(time.Hour)
+	if len(next.Providers) != 0 || len(next.Errors) != 0 {
+		t.Fatalf("counters not reset: %+v", next)
+	}
+
+	c.restore(report.Providers, report.Errors)
+	if got := c.Report(time.Hour).Providers["claude"].Requests; got != 2 {
+		t.Fatalf("restored claude requests = %d, want 2", got)
+	}
+}
+
+func TestSendPostsJSON(t *testing.T) {
+	var received Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("decode report: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	report := Report{Version: "v1", Providers: map[string]ProviderCounts{"codex": {Requests: 3}}}
+	if err := Send(context.Background(), server.Client(), server.URL, report); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if received.Version != "v1" || received.Providers["codex"].Requests != 3 {
+		t.Fatalf("received = %+v", received)
+	}
+}
@@ -0,0 +1,81 @@
+package tenant
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultFileName = "tenants.json"
+
+var (
+	active         atomic.Pointer[Store]
+	configureMu    sync.Mutex
+	registerPlugin sync.Once
+)
+
+// Configure applies the tenants settings. The store is reopened only when its file changes, so
+// quota windows survive unrelated config reloads.
+func Configure(cfg *config.Config) {
+	configureMu.Lock()
+	defer configureMu.Unlock()
+	if cfg == nil || !cfg.Tenants.Enabled {
+		active.Store(nil)
+		return
+	}
+	path := resolvePath(cfg)
+	if current := active.Load(); current != nil && current.Path() == path {
+		return
+	}
+	store, err := Open(path)
+	if err != nil {
+		log.Errorf("tenants: %v", err)
+		active.Store(nil)
+		return
+	}
+	active.Store(store)
+	registerPlugin.Do(func() {
+		coreusage.RegisterPlugin(usagePlugin{})
+	})
+	log.Infof("tenants: loaded %d tenant(s) from %s", len(store.List()), path)
+}
+
+// Active returns the tenant store, or nil when tenants are disabled.
+func Active() *Store { return active.Load() }
+
+func resolvePath(cfg *config.Config) string {
+	if file := strings.TrimSpace(cfg.Tenants.File); file != "" {
+		return file
+	}
+	if base := util.WritablePath(); base != "" {
+		return filepath.Join(base, defaultFileName)
+	}
+	if authDir, err := util.ResolveAuthDir(cfg.AuthDir); err == nil && authDir != "" {
+		return filepath.Join(authDir, defaultFileName)
+	}
+	return defaultFileName
+}
+
+// usagePlugin charges the tokens of tenant requests against their quota.
+type usagePlugin struct{}
+
+// HandleUsage implements coreusage.Plugin.
+func (usagePlugin) HandleUsage(_ context.Context, record coreusage.Record) {
+	store := active.Load()
+	if store == nil || record.Tenant == "" {
+		return
+	}
+	tokens := record.Detail.TotalTokens
+	if tokens == 0 {
+		tokens = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+	}
+	store.ChargeTokens(record.Tenant, tokens, time.Now())
+}
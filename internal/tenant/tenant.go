@@ -0,0 +1,521 @@
+// Package tenant implements self-serve client API keys grouped by tenant. Operators create
+// tenants and issue or revoke their keys through the management API; each tenant carries
+// labels, an optional model allowlist and an optional request/token quota. Keys are stored only
+// as SHA-256 digests and the plaintext is returned once, when the key is issued. Requests made
+// with a tenant key are authenticated under the principal "tenant:<tenant id>/<key id>" and their
+// usage records carry the tenant id, so usage can be invoiced per tenant.
+package tenant
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+)
+
+const (
+	// ProviderName identifies tenant keys as the access provider of a request.
+	ProviderName = "tenant"
+
+	principalPrefix = "tenant:"
+	keyPrefix       = "cpt-"
+	idPrefix        = "tn-"
+	keyIDPrefix     = "key-"
+)
+
+var (
+	// ErrNotFound is returned when a tenant or key does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrQuotaExceeded is returned when a tenant has used up its quota for the current period.
+	ErrQuotaExceeded = errors.New("tenant quota exceeded")
+)
+
+// Quota limits the requests and tokens a tenant may use per period.
+type Quota struct {
+	// MaxRequests caps the requests per period. 0 means unlimited.
+	MaxRequests int64 `json:"max-requests,omitempty"`
+	// MaxTokens caps the total tokens per period. 0 means unlimited.
+	MaxTokens int64 `json:"max-tokens,omitempty"`
+	// Period is the quota window as a Go duration, e.g. "24h". Empty means the window never resets.
+	Period string `json:"period,omitempty"`
+}
+
+// Key is an issued client API key. Hash is never returned by the management API.
+type Key struct {
+	ID        string     `json:"id"`
+	Prefix    string     `json:"prefix"`
+	Label     string     `json:"label,omitempty"`
+	Hash      string     `json:"hash,omitempty"`
+	CreatedAt time.Time  `json:"created-at"`
+	RevokedAt *time.Time `json:"revoked-at,omitempty"`
+}
+
+// Tenant groups client API keys with shared labels, model access and quota.
+type Tenant struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	AllowedModels []string          `json:"allowed-models,omitempty"`
+	Quota         Quota             `json:"quota"`
+	CreatedAt     time.Time         `json:"created-at"`
+	Keys          []Key             `json:"keys"`
+}
+
+// Spec holds the tenant fields set on create or update. Nil fields are left unchanged.
+type Spec struct {
+	Name          *string            `json:"name"`
+	Labels        *map[string]string `json:"labels"`
+	AllowedModels *[]string          `json:"allowed-models"`
+	Quota         *Quota             `json:"quota"`
+}
+
+// Usage reports a tenant's consumption in the current quota window.
+type Usage struct {
+	WindowStart time.Time `json:"window-start"`
+	Requests    int64     `json:"requests"`
+	Tokens      int64     `json:"tokens"`
+}
+
+type keyRef struct {
+	tenantID string
+	keyID    string
+}
+
+type quotaWindow struct {
+	start    time.Time
+	requests int64
+	tokens   int64
+}
+
+// Store persists tenants in a JSON file and indexes their active keys by digest. Quota
+// consumption is kept in memory, so a restart starts a new window.
+type Store struct {
+	path string
+
+	mu      sync.RWMutex
+	tenants map[string]*Tenant
+	byHash  map[string]keyRef
+	windows map[string]*quotaWindow
+}
+
+// Open loads the tenants stored at path. A missing file yields an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:    path,
+		tenants: make(map[string]*Tenant),
+		windows: make(map[string]*quotaWindow),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("tenant: read %s: %w", path, err)
+	}
+	if len(data) > 0 {
+		var tenants []*Tenant
+		if err = json.Unmarshal(data, &tenants); err != nil {
+			return nil, fmt.Errorf("tenant: parse %s: %w", path, err)
+		}
+		for _, t := range tenants {
+			if t != nil && t.ID != "" {
+				s.tenants[t.ID] = t
+			}
+		}
+	}
+	s.reindex()
+	return s, nil
+}
+
+// Path returns the file backing the store.
+func (s *Store) Path() string { return s.path }
+
+func (s *Store) reindex() {
+	s.byHash = make(map[string]keyRef)
+	for _, t := range s.tenants {
+		for _, key := range t.Keys {
+			if key.RevokedAt == nil && key.Hash != "" {
+				s.byHash[key.Hash] = keyRef{tenantID: t.ID, keyID: key.ID}
+			}
+		}
+	}
+}
+
+func (s *Store) save() error {
+	tenants := make([]*Tenant, 0, len(s.tenants))
+	for _, t := range s.tenants {
+		tenants = append(tenants, t)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].CreatedAt.Before(tenants[j].CreatedAt) })
+	data, err := json.MarshalIndent(tenants, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// view returns a copy of t without key digests.
+func view(t *Tenant) Tenant {
+	out := *t
+	out.Labels = make(map[string]string, len(t.Labels))
+	for k, v := range t.Labels {
+		out.Labels[k] = v
+	}
+	out.AllowedModels = append([]string(nil), t.AllowedModels...)
+	out.Keys = make([]Key, len(t.Keys))
+	for i, key := range t.Keys {
+		key.Hash = ""
+		out.Keys[i] = key
+	}
+	return out
+}
+
+// List returns all tenants ordered by creation time.
+func (s *Store) List() []Tenant {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Tenant, 0, len(s.tenants))
+	for _, t := range s.tenants {
+		out = append(out, view(t))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// Get returns the tenant with id.
+func (s *Store) Get(id string) (Tenant, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.tenants[id]
+	if !ok {
+		return Tenant{}, false
+	}
+	return view(t), true
+}
+
+// Create adds a tenant. Name is required.
+func (s *Store) Create(spec Spec) (Tenant, error) {
+	t := &Tenant{ID: newID(idPrefix), CreatedAt: time.Now().UTC()}
+	if err := apply(t, spec); err != nil {
+		return Tenant{}, err
+	}
+	if t.Name == "" {
+		return Tenant{}, fmt.Errorf("name is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tenants[t.ID] = t
+	if err := s.save(); err != nil {
+		delete(s.tenants, t.ID)
+		return Tenant{}, err
+	}
+	return view(t), nil
+}
+
+// Update changes the fields set in spec.
+func (s *Store) Update(id string, spec Spec) (Tenant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tenants[id]
+	if !ok {
+		return Tenant{}, ErrNotFound
+	}
+	updated := view(t)
+	updated.Keys = t.Keys
+	if err := apply(&updated, spec); err != nil {
+		return Tenant{}, err
+	}
+	if updated.Name == "" {
+		return Tenant{}, fmt.Errorf("name is required")
+	}
+	s.tenants[id] = &updated
+	if err := s.save(); err != nil {
+		s.tenants[id] = t
+		return Tenant{}, err
+	}
+	if updated.Quota != t.Quota {
+		delete(s.windows, id)
+	}
+	return view(&updated), nil
+}
+
+// Delete removes a tenant and with it all of its keys.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tenants[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.tenants, id)
+	if err := s.save(); err != nil {
+		s.tenants[id] = t
+		return err
+	}
+	delete(s.windows, id)
+	s.reindex()
+	return nil
+}
+
+func apply(t *Tenant, spec Spec) error {
+	if spec.Name != nil {
+		t.Name = strings.TrimSpace(*spec.Name)
+	}
+	if spec.Labels != nil {
+		t.Labels = make(map[string]string, len(*spec.Labels))
+		for k, v := range *spec.Labels {
+			if k = strings.TrimSpace(k); k != "" {
+				t.Labels[k] = strings.TrimSpace(v)
+			}
+		}
+	}
+	if spec.AllowedModels != nil {
+		t.AllowedModels = t.AllowedModels[:0:0]
+		for _, model := range *spec.AllowedModels {
+			if model = strings.TrimSpace(model); model != "" {
+				t.AllowedModels = append(t.AllowedModels, model)
+			}
+		}
+	}
+	if spec.Quota != nil {
+		quota := *spec.Quota
+		quota.Period = strings.TrimSpace(quota.Period)
+		if quota.MaxRequests < 0 || quota.MaxTokens < 0 {
+			return fmt.Errorf("quota limits must be >= 0")
+		}
+		if quota.Period != "" {
+			if period, err := time.ParseDuration(quota.Period); err != nil || period <= 0 {
+				return fmt.Errorf("invalid quota period %q", quota.Period)
+			}
+		}
+		t.Quota = quota
+	}
+	return nil
+}
+
+// IssueKey creates a key for the tenant and returns it together with its plaintext, which is
+// not stored and cannot be retrieved later.
+func (s *Store) IssueKey(tenantID, label string) (Key, string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return Key{}, "", err
+	}
+	secret := keyPrefix + hex.EncodeToString(buf)
+	key := Key{
+		ID:        newID(keyIDPrefix),
+		Prefix:    secret[:len(keyPrefix)+8],
+		Label:     strings.TrimSpace(label),
+		Hash:      hashKey(secret),
+		CreatedAt: time.Now().UTC(),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tenants[tenantID]
+	if !ok {
+		return Key{}, "", ErrNotFound
+	}
+	t.Keys = append(t.Keys, key)
+	if err := s.save(); err != nil {
+		t.Keys = t.Keys[:len(t.Keys)-1]
+		return Key{}, "", err
+	}
+	s.byHash[key.Hash] = keyRef{tenantID: tenantID, keyID: key.ID}
+	key.Hash = ""
+	return key, secret, nil
+}
+
+// RevokeKey disables a key. Revoked keys stay listed for auditing.
+func (s *Store) RevokeKey(tenantID, keyID string) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tenants[tenantID]
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+	for i := range t.Keys {
+		if t.Keys[i].ID != keyID {
+			continue
+		}
+		if t.Keys[i].RevokedAt == nil {
+			now := time.Now().UTC()
+			t.Keys[i].RevokedAt = &now
+			if err := s.save(); err != nil {
+				t.Keys[i].RevokedAt = nil
+				return Key{}, err
+			}
+			delete(s.byHash, t.Keys[i].Hash)
+		}
+		key := t.Keys[i]
+		key.Hash = ""
+		return key, nil
+	}
+	return Key{}, ErrNotFound
+}
+
+// Identifier implements sdkaccess.Provider.
+func (s *Store) Identifier() string { return ProviderName }
+
+// Authenticate implements sdkaccess.Provider. Keys that do not belong to a tenant are left to
+// the other providers.
+func (s *Store) Authenticate(_ context.Context, r *http.Request) (*sdkaccess.Result, error) {
+	if s == nil || r == nil {
+		return nil, sdkaccess.ErrNotHandled
+	}
+	for _, candidate := range candidateKeys(r) {
+		if !strings.HasPrefix(candidate.value, keyPrefix) {
+			continue
+		}
+		s.mu.RLock()
+		ref, ok := s.byHash[hashKey(candidate.value)]
+		s.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		return &sdkaccess.Result{
+			Provider:  ProviderName,
+			Principal: principalPrefix + ref.tenantID + "/" + ref.keyID,
+			Metadata: map[string]string{
+				"source": candidate.source,
+				"tenant": ref.tenantID,
+			},
+		}, nil
+	}
+	return nil, sdkaccess.ErrNotHandled
+}
+
+// TenantID returns the tenant of an authenticated principal.
+func TenantID(principal string) (string, bool) {
+	rest, ok := strings.CutPrefix(principal, principalPrefix)
+	if !ok {
+		return "", false
+	}
+	id, _, ok := strings.Cut(rest, "/")
+	return id, ok && id != ""
+}
+
+// AllowedModels returns the model allowlist of the tenant owning principal and whether one
+// applies.
+func (s *Store) AllowedModels(principal string) ([]string, bool) {
+	id, ok := TenantID(principal)
+	if !ok || s == nil {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.tenants[id]
+	if !ok || len(t.AllowedModels) == 0 {
+		return nil, false
+	}
+	return t.AllowedModels, true
+}
+
+// Admit counts a request against the tenant's quota, failing with ErrQuotaExceeded once the
+// request or token limit of the current window is reached.
+func (s *Store) Admit(tenantID string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tenants[tenantID]
+	if !ok {
+		return ErrNotFound
+	}
+	if t.Quota.MaxRequests == 0 && t.Quota.MaxTokens == 0 {
+		return nil
+	}
+	w := s.window(t, now)
+	if (t.Quota.MaxRequests > 0 && w.requests >= t.Quota.MaxRequests) || (t.Quota.MaxTokens > 0 && w.tokens >= t.Quota.MaxTokens) {
+		return ErrQuotaExceeded
+	}
+	w.requests++
+	return nil
+}
+
+// ChargeTokens adds tokens to the tenant's current quota window.
+func (s *Store) ChargeTokens(tenantID string, tokens int64, now time.Time) {
+	if tokens <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.tenants[tenantID]; ok && t.Quota.MaxTokens > 0 {
+		s.window(t, now).tokens += tokens
+	}
+}
+
+// Usage returns the tenant's consumption in the current quota window.
+func (s *Store) Usage(tenantID string, now time.Time) (Usage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tenants[tenantID]
+	if !ok {
+		return Usage{}, false
+	}
+	w := s.window(t, now)
+	return Usage{WindowStart: w.start, Requests: w.requests, Tokens: w.tokens}, true
+}
+
+// window returns the current quota window of t, starting a new one when the period elapsed.
+func (s *Store) window(t *Tenant, now time.Time) *quotaWindow {
+	w, ok := s.windows[t.ID]
+	if !ok {
+		w = &quotaWindow{start: now}
+		s.windows[t.ID] = w
+	}
+	if period, err := time.ParseDuration(t.Quota.Period); err == nil && period > 0 && now.Sub(w.start) >= period {
+		*w = quotaWindow{start: now}
+	}
+	return w
+}
+
+func hashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// newID returns prefix followed by 16 random hex characters.
+func newID(prefix string) string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return prefix + hex.EncodeToString(buf)
+}
+
+type keyCandidate struct {
+	value  string
+	source string
+}
+
+// candidateKeys returns the API keys presented by r in the places client API keys are accepted.
+func candidateKeys(r *http.Request) []keyCandidate {
+	bearer := r.Header.Get("Authorization")
+	if scheme, token, ok := strings.Cut(bearer, " "); ok && strings.EqualFold(scheme, "bearer") {
+		bearer = strings.TrimSpace(token)
+	}
+	candidates := []keyCandidate{
+		{bearer, "authorization"},
+		{r.Header.Get("X-Goog-Api-Key"), "x-goog-api-key"},
+		{r.Header.Get("X-Api-Key"), "x-api-key"},
+	}
+	if r.URL != nil {
+		query := r.URL.Query()
+		candidates = append(candidates,
+			keyCandidate{query.Get("key"), "query-key"},
+			keyCandidate{query.Get("auth_token"), "query-auth-token"},
+		)
+	}
+	return candidates
+}
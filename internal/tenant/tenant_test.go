@@ -0,0 +1,89 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreIssueAuthenticateRevoke(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	name := "acme"
+	models := []string{"gpt-*"}
+	created, err := store.Create(Spec{Name: &name, AllowedModels: &models})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	key, secret, err := store.IssueKey(created.ID, "ci")
+	if err != nil {
+		t.Fatalf("IssueKey: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), secret) {
+		t.Fatal("plaintext key written to disk")
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	result, err := reopened.Authenticate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if id, ok := TenantID(result.Principal); !ok || id != created.ID || result.Metadata["tenant"] != created.ID {
+		t.Fatalf("result = %+v", result)
+	}
+	if allowed, ok := reopened.AllowedModels(result.Principal); !ok || allowed[0] != "gpt-*" {
+		t.Fatalf("AllowedModels = %v, %t", allowed, ok)
+	}
+
+	if _, err = reopened.RevokeKey(created.ID, key.ID); err != nil {
+		t.Fatalf("RevokeKey: %v", err)
+	}
+	if _, err = reopened.Authenticate(context.Background(), req); err == nil {
+		t.Fatal("revoked key still authenticates")
+	}
+}
+
+func TestStoreQuotaWindow(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "tenants.json"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	name := "acme"
+	created, err := store.Create(Spec{Name: &name, Quota: &Quota{MaxRequests: 2, MaxTokens: 100, Period: "1h"}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if err = store.Admit(created.ID, now); err != nil {
+			t.Fatalf("Admit %d: %v", i, err)
+		}
+	}
+	if err = store.Admit(created.ID, now); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("third Admit = %v, want ErrQuotaExceeded", err)
+	}
+
+	later := now.Add(time.Hour)
+	if err = store.Admit(created.ID, later); err != nil {
+		t.Fatalf("Admit in new window: %v", err)
+	}
+	store.ChargeTokens(created.ID, 100, later)
+	if err = store.Admit(created.ID, later); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Admit after token limit = %v, want ErrQuotaExceeded", err)
+	}
+}
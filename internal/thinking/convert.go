@@ -92,6 +92,23 @@ func ConvertBudgetToLevel(budget int) (string, bool) {
 	}
 }
 
+// ConvertBudgetToGeminiLevel maps a positive budget onto the coarse Gemini 3 thinking levels:
+//   - 1-1024     → low
+//   - 1025-8192  → medium
+//   - 8193+      → high
+//
+// Callers clamp the result to the levels the target model supports.
+func ConvertBudgetToGeminiLevel(budget int) ThinkingLevel {
+	switch {
+	case budget <= ThresholdLow:
+		return LevelLow
+	case budget <= ThresholdMedium:
+		return LevelMedium
+	default:
+		return LevelHigh
+	}
+}
+
 // ModelCapability describes the thinking format support of a model.
 type ModelCapability int
 
@@ -34,7 +34,8 @@ import (
 // Auto-conversion behavior:
 //   - Budget-only model + Level config → Level converted to Budget
 //   - Level-only model + Budget config → Budget converted to Level
-//   - Hybrid model → preserve original format
+//   - Hybrid model → preserve original format, except that Claude budget_tokens sent to Gemini 3
+//     are mapped onto thinking levels, matching how OpenAI reasoning_effort reaches those models
 func ValidateConfig(config ThinkingConfig, modelInfo *registry.ModelInfo, fromFormat, toFormat string, fromSuffix bool) (*ThinkingConfig, error) {
 	fromFormat, toFormat = strings.ToLower(strings.TrimSpace(fromFormat)), strings.ToLower(strings.TrimSpace(toFormat))
 	model := "unknown"
@@ -86,6 +87,14 @@ func ValidateConfig(config ThinkingConfig, modelInfo *registry.ModelInfo, fromFo
 			config.Budget = 0
 		}
 	case CapabilityHybrid:
+		// Claude clients can only express thinking as budget_tokens while Gemini 3 models think in
+		// discrete levels, so body budgets are mapped onto the model's levels. Explicit suffix
+		// budgets are kept as numbers.
+		if fromFormat == "claude" && isGeminiFamily(toFormat) && !fromSuffix && config.Mode == ModeBudget && config.Budget > 0 {
+			config.Mode = ModeLevel
+			config.Level = clampLevel(ConvertBudgetToGeminiLevel(config.Budget), modelInfo, toFormat)
+			config.Budget = 0
+		}
 	}
 
 	if config.Mode == ModeLevel && config.Level == LevelNone {
@@ -0,0 +1,317 @@
+package thoughtfilter
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// openAI rewrites a Chat Completions response or chunk. Thinking arrives in reasoning_content
+// and, for models that inline it, as tagged text in content.
+func (f *StreamFilter) openAI(data []byte) []byte {
+	choices := gjson.GetBytes(data, "choices")
+	if !choices.IsArray() {
+		return data
+	}
+	for i, choice := range choices.Array() {
+		field := "delta"
+		if !choice.Get(field).Exists() {
+			field = "message"
+			if !choice.Get(field).Exists() {
+				continue
+			}
+		}
+		path := "choices." + itoa(i) + "." + field
+		msg := choice.Get(field)
+		st := f.choice(choiceIndex(choice, "index", i))
+		final := f.complete || choice.Get("finish_reason").String() != ""
+		content := msg.Get("content")
+		reasoning := msg.Get("reasoning_content")
+		hasText := content.Type == gjson.String || (final && st.split.pending != "")
+
+		switch f.policy.mode {
+		case ModeStrip:
+			if reasoning.Exists() {
+				data, _ = sjson.DeleteBytes(data, path+".reasoning_content")
+			}
+			if hasText {
+				outside, _ := st.split.feed(content.String(), final)
+				data, _ = sjson.SetBytes(data, path+".content", outside)
+			}
+		case ModeWrap:
+			var text strings.Builder
+			if r := reasoning.String(); r != "" {
+				if !st.inThought {
+					text.WriteString(f.policy.openTag)
+					st.inThought = true
+				}
+				text.WriteString(r)
+			}
+			if reasoning.Exists() {
+				data, _ = sjson.DeleteBytes(data, path+".reasoning_content")
+			}
+			if st.inThought && (content.String() != "" || final || msg.Get("tool_calls").Exists()) {
+				text.WriteString(f.policy.closeTag)
+				st.inThought = false
+			}
+			text.WriteString(content.String())
+			if text.Len() > 0 || content.Type == gjson.String {
+				data, _ = sjson.SetBytes(data, path+".content", text.String())
+			}
+		case ModeReasoning:
+			if hasText {
+				outside, inside := st.split.feed(content.String(), final)
+				data, _ = sjson.SetBytes(data, path+".content", outside)
+				if inside != "" {
+					data, _ = sjson.SetBytes(data, path+".reasoning_content", reasoning.String()+inside)
+				}
+			}
+		}
+	}
+	return data
+}
+
+// claude rewrites a Messages response or stream event. Stripped blocks are removed and the
+// indexes of the following blocks are shifted so the client sees a contiguous sequence. The
+// reasoning mode leaves Claude responses unchanged because thinking already has its own blocks.
+func (f *StreamFilter) claude(ev event) []event {
+	if f.policy.mode == ModeReasoning {
+		return []event{ev}
+	}
+	data := ev.data
+	switch gjson.GetBytes(data, "type").String() {
+	case "message":
+		return []event{{name: ev.name, data: f.claudeMessage(data)}}
+	case "content_block_start":
+		index := gjson.GetBytes(data, "index").Int()
+		blockType := gjson.GetBytes(data, "content_block.type").String()
+		if blockType == "redacted_thinking" || (blockType == "thinking" && f.policy.mode == ModeStrip) {
+			f.droppedBlocks[index] = true
+			f.droppedCount++
+			return nil
+		}
+		out := index - f.droppedCount
+		f.blockIndex[index] = out
+		data, _ = sjson.SetBytes(data, "index", out)
+		if blockType != "thinking" {
+			return []event{{name: ev.name, data: data}}
+		}
+		f.wrappedBlocks[index] = true
+		data, _ = sjson.SetRawBytes(data, "content_block", []byte(`{"type":"text","text":""}`))
+		return []event{
+			{name: ev.name, data: data},
+			f.claudeTextDelta(ev.name, out, f.policy.openTag),
+		}
+	case "content_block_delta":
+		index := gjson.GetBytes(data, "index").Int()
+		if f.droppedBlocks[index] {
+			return nil
+		}
+		out, ok := f.blockIndex[index]
+		if !ok {
+			out = index
+		}
+		data, _ = sjson.SetBytes(data, "index", out)
+		deltaType := gjson.GetBytes(data, "delta.type").String()
+		switch {
+		case f.wrappedBlocks[index] && deltaType == "signature_delta":
+			return nil
+		case f.wrappedBlocks[index] && deltaType == "thinking_delta":
+			return []event{f.claudeTextDelta(ev.name, out, gjson.GetBytes(data, "delta.thinking").String())}
+		case f.policy.mode == ModeStrip && deltaType == "text_delta":
+			outside, _ := f.choice(index).split.feed(gjson.GetBytes(data, "delta.text").String(), false)
+			if outside == "" {
+				return nil
+			}
+			data, _ = sjson.SetBytes(data, "delta.text", outside)
+		}
+		return []event{{name: ev.name, data: data}}
+	case "content_block_stop":
+		index := gjson.GetBytes(data, "index").Int()
+		if f.droppedBlocks[index] {
+			delete(f.droppedBlocks, index)
+			return nil
+		}
+		out, ok := f.blockIndex[index]
+		if !ok {
+			out = index
+		}
+		data, _ = sjson.SetBytes(data, "index", out)
+		stop := event{name: ev.name, data: data}
+		if f.wrappedBlocks[index] {
+			delete(f.wrappedBlocks, index)
+			return []event{f.claudeTextDelta(deltaName(ev.name), out, f.policy.closeTag), stop}
+		}
+		if st, ok := f.choices[index]; ok && f.policy.mode == ModeStrip {
+			delete(f.choices, index)
+			if outside, _ := st.split.feed("", true); outside != "" {
+				return []event{f.claudeTextDelta(deltaName(ev.name), out, outside), stop}
+			}
+		}
+		return []event{stop}
+	}
+	return []event{ev}
+}
+
+// claudeMessage rewrites the content of a complete Messages response.
+func (f *StreamFilter) claudeMessage(data []byte) []byte {
+	content := gjson.GetBytes(data, "content")
+	if !content.IsArray() {
+		return data
+	}
+	blocks := make([]string, 0, len(content.Array()))
+	for _, block := range content.Array() {
+		raw := block.Raw
+		switch block.Get("type").String() {
+		case "redacted_thinking":
+			continue
+		case "thinking":
+			if f.policy.mode == ModeStrip {
+				continue
+			}
+			raw, _ = sjson.Set(`{"type":"text"}`, "text", f.policy.openTag+block.Get("thinking").String()+f.policy.closeTag)
+		case "text":
+			if f.policy.mode == ModeStrip {
+				split := tagSplitter{open: f.policy.openTag, close: f.policy.closeTag}
+				outside, _ := split.feed(block.Get("text").String(), true)
+				raw, _ = sjson.Set(raw, "text", outside)
+			}
+		}
+		blocks = append(blocks, raw)
+	}
+	data, _ = sjson.SetRawBytes(data, "content", []byte("["+strings.Join(blocks, ",")+"]"))
+	return data
+}
+
+func (f *StreamFilter) claudeTextDelta(name string, index int64, text string) event {
+	data, _ := sjson.SetBytes([]byte(`{"type":"content_block_delta","delta":{"type":"text_delta"}}`), "index", index)
+	data, _ = sjson.SetBytes(data, "delta.text", text)
+	return event{name: deltaName(name), data: data}
+}
+
+// deltaName returns the SSE event name of an inserted delta: named like its neighbours when the
+// stream uses event lines and unnamed otherwise.
+func deltaName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return "content_block_delta"
+}
+
+// gemini rewrites a Gemini response or chunk. Thinking arrives as parts marked thought:true and,
+// for models that inline it, as tagged text in text parts. Gemini CLI wraps the response in a
+// "response" envelope.
+func (f *StreamFilter) gemini(data []byte) []byte {
+	prefix := ""
+	if f.format == "gemini-cli" && gjson.GetBytes(data, "response").Exists() {
+		prefix = "response."
+	}
+	candidates := gjson.GetBytes(data, prefix+"candidates")
+	if !candidates.IsArray() {
+		return data
+	}
+	for i, candidate := range candidates.Array() {
+		st := f.choice(choiceIndex(candidate, "index", i))
+		final := f.complete || candidate.Get("finishReason").String() != ""
+		parts := candidate.Get("content.parts")
+		out := make([]string, 0, len(parts.Array())+1)
+		for _, part := range parts.Array() {
+			raw := part.Raw
+			thought := part.Get("thought").Bool()
+			text := part.Get("text")
+			switch f.policy.mode {
+			case ModeStrip:
+				if thought {
+					continue
+				}
+				if text.Exists() {
+					outside, _ := st.split.feed(text.String(), false)
+					if outside == "" && textOnly(part) {
+						continue
+					}
+					raw, _ = sjson.Set(raw, "text", outside)
+				}
+			case ModeWrap:
+				if thought {
+					wrapped := text.String()
+					if !st.inThought {
+						wrapped = f.policy.openTag + wrapped
+						st.inThought = true
+					}
+					raw, _ = sjson.Delete(raw, "thought")
+					raw, _ = sjson.Set(raw, "text", wrapped)
+					out = append(out, raw)
+					continue
+				}
+				if st.inThought {
+					if text.Exists() {
+						raw, _ = sjson.Set(raw, "text", f.policy.closeTag+text.String())
+					} else {
+						out = append(out, geminiTextPart(f.policy.closeTag, false))
+					}
+					st.inThought = false
+				}
+			case ModeReasoning:
+				if !thought && text.Exists() {
+					outside, inside := st.split.feed(text.String(), false)
+					if inside != "" {
+						out = append(out, geminiTextPart(inside, true))
+					}
+					if outside == "" && textOnly(part) {
+						continue
+					}
+					raw, _ = sjson.Set(raw, "text", outside)
+				}
+			}
+			out = append(out, raw)
+		}
+		if final {
+			switch f.policy.mode {
+			case ModeWrap:
+				if st.inThought {
+					out = append(out, geminiTextPart(f.policy.closeTag, false))
+					st.inThought = false
+				}
+			default:
+				outside, inside := st.split.feed("", true)
+				if inside != "" && f.policy.mode == ModeReasoning {
+					out = append(out, geminiTextPart(inside, true))
+				}
+				if outside != "" {
+					out = append(out, geminiTextPart(outside, false))
+				}
+			}
+		}
+		if parts.Exists() || len(out) > 0 {
+			data, _ = sjson.SetRawBytes(data, prefix+"candidates."+itoa(i)+".content.parts", []byte("["+strings.Join(out, ",")+"]"))
+		}
+	}
+	return data
+}
+
+func geminiTextPart(text string, thought bool) string {
+	raw, _ := sjson.Set(`{}`, "text", text)
+	if thought {
+		raw, _ = sjson.Set(raw, "thought", true)
+	}
+	return raw
+}
+
+// textOnly reports whether part carries nothing besides its text.
+func textOnly(part gjson.Result) bool {
+	only := true
+	part.ForEach(func(key, _ gjson.Result) bool {
+		only = key.String() == "text"
+		return only
+	})
+	return only
+}
+
+// choiceIndex returns the index field of a choice or candidate, or its position when absent.
+func choiceIndex(result gjson.Result, field string, position int) int64 {
+	if index := result.Get(field); index.Exists() {
+		return index.Int()
+	}
+	return int64(position)
+}
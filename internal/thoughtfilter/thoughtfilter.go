@@ -0,0 +1,293 @@
+// Package thoughtfilter rewrites model thinking in responses before they reach the client. A
+// policy strips thinking, returns it as regular text between configurable tags, or moves tagged
+// thinking found in the text into the format's reasoning field. Complete bodies and stream
+// chunks of the OpenAI Chat Completions, Claude Messages and Gemini formats are supported;
+// other formats pass through unchanged.
+package thoughtfilter
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// ModeStrip drops thinking from responses.
+	ModeStrip = "strip"
+	// ModeWrap returns thinking as regular text between the policy's tags.
+	ModeWrap = "wrap"
+	// ModeReasoning moves text between the policy's tags into the format's reasoning field.
+	ModeReasoning = "reasoning"
+
+	defaultOpenTag  = "<thinking>"
+	defaultCloseTag = "</thinking>"
+)
+
+// Policy is a compiled ThinkingOutputPolicy.
+type Policy struct {
+	mode     string
+	openTag  string
+	closeTag string
+}
+
+// Set is a compiled thinking-output configuration. It is built once per configuration load and
+// is safe for concurrent use.
+type Set struct {
+	policies []*Policy
+	keys     []map[string]struct{}
+}
+
+// Compile compiles policies. Policies with an unknown mode are logged and skipped.
+func Compile(policies []config.ThinkingOutputPolicy) *Set {
+	if len(policies) == 0 {
+		return nil
+	}
+	set := &Set{}
+	for i := range policies {
+		cfg := &policies[i]
+		mode := strings.ToLower(strings.TrimSpace(cfg.Mode))
+		if mode != ModeStrip && mode != ModeWrap && mode != ModeReasoning {
+			log.Warnf("thinking-output: unknown mode %q, policy skipped", cfg.Mode)
+			continue
+		}
+		p := &Policy{mode: mode, openTag: cfg.OpenTag, closeTag: cfg.CloseTag}
+		if p.openTag == "" {
+			p.openTag = defaultOpenTag
+		}
+		if p.closeTag == "" {
+			p.closeTag = defaultCloseTag
+		}
+		var keys map[string]struct{}
+		for _, key := range cfg.APIKeys {
+			if key = strings.TrimSpace(key); key != "" {
+				if keys == nil {
+					keys = make(map[string]struct{}, len(cfg.APIKeys))
+				}
+				keys[key] = struct{}{}
+			}
+		}
+		set.policies = append(set.policies, p)
+		set.keys = append(set.keys, keys)
+	}
+	if len(set.policies) == 0 {
+		return nil
+	}
+	return set
+}
+
+// Select returns the policy for apiKey: the first policy listing the key, otherwise the first
+// policy without api-keys. It returns nil when no policy applies.
+func (s *Set) Select(apiKey string) *Policy {
+	if s == nil {
+		return nil
+	}
+	apiKey = strings.TrimSpace(apiKey)
+	var fallback *Policy
+	for i, keys := range s.keys {
+		if keys == nil {
+			if fallback == nil {
+				fallback = s.policies[i]
+			}
+			continue
+		}
+		if _, ok := keys[apiKey]; ok && apiKey != "" {
+			return s.policies[i]
+		}
+	}
+	return fallback
+}
+
+// FilterResponse rewrites the thinking of a complete response body in format.
+func (p *Policy) FilterResponse(format string, payload []byte) []byte {
+	filter := p.NewStreamFilter(format)
+	if filter == nil {
+		return payload
+	}
+	filter.complete = true
+	return filter.Filter(payload)
+}
+
+// StreamFilter rewrites the thinking of one response, chunk by chunk. It keeps the state needed
+// when thinking spans chunks, such as an open wrap tag or a tag split across two chunks.
+type StreamFilter struct {
+	policy *Policy
+	format string
+	// complete marks a filter for a whole response, where held-back text is flushed at once.
+	complete bool
+
+	// choices tracks OpenAI choices and Gemini candidates by index.
+	choices map[int64]*choiceState
+
+	// Claude content blocks by upstream index.
+	droppedBlocks map[int64]bool
+	wrappedBlocks map[int64]bool
+	droppedCount  int64
+	blockIndex    map[int64]int64
+}
+
+type choiceState struct {
+	inThought bool
+	split     tagSplitter
+}
+
+// NewStreamFilter returns a filter for one response in format (the client's handler type), or
+// nil when p is nil or the format is not supported.
+func (p *Policy) NewStreamFilter(format string) *StreamFilter {
+	if p == nil {
+		return nil
+	}
+	switch format {
+	case "openai", "claude", "gemini", "gemini-cli":
+	default:
+		return nil
+	}
+	return &StreamFilter{
+		policy:        p,
+		format:        format,
+		choices:       make(map[int64]*choiceState),
+		droppedBlocks: make(map[int64]bool),
+		wrappedBlocks: make(map[int64]bool),
+		blockIndex:    make(map[int64]int64),
+	}
+}
+
+func (f *StreamFilter) choice(index int64) *choiceState {
+	st := f.choices[index]
+	if st == nil {
+		st = &choiceState{split: tagSplitter{open: f.policy.openTag, close: f.policy.closeTag}}
+		f.choices[index] = st
+	}
+	return st
+}
+
+// event is one JSON document of a response, with its SSE event name when it had one.
+type event struct {
+	name string
+	data []byte
+}
+
+// Filter rewrites one chunk, which may be bare JSON or SSE lines. It returns nil when every
+// document in the chunk was dropped.
+func (f *StreamFilter) Filter(chunk []byte) []byte {
+	if f == nil || len(chunk) == 0 {
+		return chunk
+	}
+	trimmed := strings.TrimSpace(string(chunk))
+	if strings.HasPrefix(trimmed, "{") && gjson.Valid(trimmed) {
+		events := f.rewrite(event{data: []byte(trimmed)})
+		if len(events) == 0 {
+			return nil
+		}
+		parts := make([]string, len(events))
+		for i, ev := range events {
+			parts[i] = string(ev.data)
+		}
+		suffix := strings.TrimPrefix(string(chunk), strings.TrimRightFunc(string(chunk), unicode.IsSpace))
+		return []byte(strings.Join(parts, "\n") + suffix)
+	}
+
+	lines := strings.Split(string(chunk), "\n")
+	out := make([]string, 0, len(lines))
+	eventLine, eventName := "", ""
+	kept := false
+	for _, line := range lines {
+		trimmedLine := strings.TrimLeft(line, " ")
+		if name, ok := strings.CutPrefix(trimmedLine, "event:"); ok {
+			eventLine, eventName = line, strings.TrimSpace(name)
+			continue
+		}
+		rest, ok := strings.CutPrefix(trimmedLine, "data:")
+		data := strings.TrimSpace(rest)
+		if !ok || !strings.HasPrefix(data, "{") || !gjson.Valid(data) {
+			if eventLine != "" {
+				out = append(out, eventLine)
+				eventLine, eventName = "", ""
+			}
+			out = append(out, line)
+			if strings.TrimSpace(line) != "" {
+				kept = true
+			}
+			continue
+		}
+		for i, ev := range f.rewrite(event{name: eventName, data: []byte(data)}) {
+			if i > 0 {
+				out = append(out, "")
+			}
+			if ev.name != "" {
+				out = append(out, "event: "+ev.name)
+			}
+			out = append(out, "data: "+string(ev.data))
+			kept = true
+		}
+		eventLine, eventName = "", ""
+	}
+	if !kept {
+		return nil
+	}
+	return []byte(strings.Join(out, "\n"))
+}
+
+func (f *StreamFilter) rewrite(ev event) []event {
+	switch f.format {
+	case "openai":
+		return []event{{name: ev.name, data: f.openAI(ev.data)}}
+	case "claude":
+		return f.claude(ev)
+	default:
+		return []event{{name: ev.name, data: f.gemini(ev.data)}}
+	}
+}
+
+// tagSplitter separates text between an open and a close tag from the text outside, across
+// chunks. A possible partial tag at the end of a chunk is held back until the next one.
+type tagSplitter struct {
+	open    string
+	close   string
+	inside  bool
+	pending string
+}
+
+// feed returns the text of chunk outside and inside the tags. final flushes held-back text.
+func (s *tagSplitter) feed(chunk string, final bool) (outside, inside string) {
+	buf := s.pending + chunk
+	s.pending = ""
+	var out, in strings.Builder
+	write := func(text string) {
+		if s.inside {
+			in.WriteString(text)
+		} else {
+			out.WriteString(text)
+		}
+	}
+	for buf != "" {
+		tag := s.open
+		if s.inside {
+			tag = s.close
+		}
+		if i := strings.Index(buf, tag); i >= 0 {
+			write(buf[:i])
+			buf = buf[i+len(tag):]
+			s.inside = !s.inside
+			continue
+		}
+		keep := 0
+		if !final {
+			for k := min(len(tag)-1, len(buf)); k > 0; k-- {
+				if strings.HasSuffix(buf, tag[:k]) {
+					keep = k
+					break
+				}
+			}
+		}
+		write(buf[:len(buf)-keep])
+		s.pending = buf[len(buf)-keep:]
+		buf = ""
+	}
+	return out.String(), in.String()
+}
+
+func itoa(i int) string { return strconv.Itoa(i) }
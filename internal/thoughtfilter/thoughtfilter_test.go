@@ -0,0 +1,170 @@
+package thoughtfilter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func policy(t *testing.T, mode string) *Policy {
+	t.Helper()
+	p := Compile([]config.ThinkingOutputPolicy{{Mode: mode}}).Select("")
+	if p == nil {
+		t.Fatalf("no policy for mode %q", mode)
+	}
+	return p
+}
+
+func TestSelectAndUnknownMode(t *testing.T) {
+	set := Compile([]config.ThinkingOutputPolicy{
+		{Mode: "bogus"},
+		{Mode: "wrap"},
+		{Mode: "strip", APIKeys: []string{"key-a"}},
+	})
+	if got := set.Select("key-a").mode; got != ModeStrip {
+		t.Fatalf("key-a mode = %q, want strip", got)
+	}
+	if got := set.Select("key-b").mode; got != ModeWrap {
+		t.Fatalf("key-b mode = %q, want wrap", got)
+	}
+	if Compile([]config.ThinkingOutputPolicy{{Mode: "bogus"}}) != nil {
+		t.Fatal("expected no set when every policy is invalid")
+	}
+}
+
+func TestTagSplitterAcrossChunks(t *testing.T) {
+	s := tagSplitter{open: "<thinking>", close: "</thinking>"}
+	var outside, inside string
+	for _, chunk := range []string{"Hi <thin", "king>ponder", "ing</thi", "nking> answer <"} {
+		o, i := s.feed(chunk, false)
+		outside += o
+		inside += i
+	}
+	o, _ := s.feed("", true)
+	outside += o
+	if outside != "Hi  answer <" || inside != "pondering" {
+		t.Fatalf("outside %q inside %q", outside, inside)
+	}
+}
+
+func TestOpenAIStream(t *testing.T) {
+	chunks := []string{
+		`{"choices":[{"index":0,"delta":{"reasoning_content":"let me think"}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"Answer"}}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+	}
+	run := func(mode string) string {
+		f := policy(t, mode).NewStreamFilter("openai")
+		var text, reasoning strings.Builder
+		for _, c := range chunks {
+			out := f.Filter([]byte(c))
+			text.WriteString(gjson.GetBytes(out, "choices.0.delta.content").String())
+			reasoning.WriteString(gjson.GetBytes(out, "choices.0.delta.reasoning_content").String())
+		}
+		return text.String() + "|" + reasoning.String()
+	}
+	if got := run(ModeStrip); got != "Answer|" {
+		t.Fatalf("strip = %q", got)
+	}
+	if got := run(ModeWrap); got != "<thinking>let me think</thinking>Answer|" {
+		t.Fatalf("wrap = %q", got)
+	}
+
+	f := policy(t, ModeReasoning).NewStreamFilter("openai")
+	var text, reasoning string
+	for _, c := range []string{
+		`{"choices":[{"index":0,"delta":{"content":"<think"}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"ing>hmm</thinking>Hello"}}]}`,
+	} {
+		out := f.Filter([]byte(c))
+		text += gjson.GetBytes(out, "choices.0.delta.content").String()
+		reasoning += gjson.GetBytes(out, "choices.0.delta.reasoning_content").String()
+	}
+	if text != "Hello" || reasoning != "hmm" {
+		t.Fatalf("reasoning: text %q reasoning %q", text, reasoning)
+	}
+}
+
+func TestClaudeStreamStripRenumbersBlocks(t *testing.T) {
+	f := policy(t, ModeStrip).NewStreamFilter("claude")
+	events := []string{
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"thinking\",\"thinking\":\"\"}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"thinking_delta\",\"thinking\":\"hmm\"}}\n\n",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n",
+	}
+	var out []string
+	for _, e := range events {
+		if got := f.Filter([]byte(e)); got != nil {
+			out = append(out, string(got))
+		}
+	}
+	if len(out) != 2 {
+		t.Fatalf("got %d events, want 2: %q", len(out), out)
+	}
+	for _, e := range out {
+		if !strings.Contains(e, `"index":0`) || strings.Contains(e, "thinking") {
+			t.Fatalf("unexpected event %q", e)
+		}
+	}
+}
+
+func TestClaudeStreamWrap(t *testing.T) {
+	f := policy(t, ModeWrap).NewStreamFilter("claude")
+	var text strings.Builder
+	for _, e := range []string{
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"hmm"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`,
+		`{"type":"content_block_stop","index":0}`,
+	} {
+		for _, line := range strings.Split(string(f.Filter([]byte(e))), "\n") {
+			if gjson.Get(line, "delta.type").String() == "text_delta" {
+				text.WriteString(gjson.Get(line, "delta.text").String())
+			}
+		}
+	}
+	if text.String() != "<thinking>hmm</thinking>" {
+		t.Fatalf("wrapped text = %q", text.String())
+	}
+}
+
+func TestClaudeMessage(t *testing.T) {
+	body := `{"type":"message","content":[{"type":"thinking","thinking":"hmm","signature":"s"},{"type":"redacted_thinking","data":"x"},{"type":"text","text":"Hi"}]}`
+	if got := gjson.GetBytes(policy(t, ModeStrip).FilterResponse("claude", []byte(body)), "content").Raw; got != `[{"type":"text","text":"Hi"}]` {
+		t.Fatalf("strip = %s", got)
+	}
+	if got := gjson.GetBytes(policy(t, ModeWrap).FilterResponse("claude", []byte(body)), "content.0.text").String(); got != "<thinking>hmm</thinking>" {
+		t.Fatalf("wrap = %q", got)
+	}
+}
+
+func TestGemini(t *testing.T) {
+	body := `{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"hmm","thought":true},{"text":"Hi"}]},"finishReason":"STOP"}]}}`
+	if got := gjson.GetBytes(policy(t, ModeStrip).FilterResponse("gemini-cli", []byte(body)), "response.candidates.0.content.parts").Raw; got != `[{"text":"Hi"}]` {
+		t.Fatalf("strip = %s", got)
+	}
+	if got := gjson.GetBytes(policy(t, ModeWrap).FilterResponse("gemini-cli", []byte(body)), "response.candidates.0.content.parts").Raw; got != `[{"text":"<thinking>hmm"},{"text":"</thinking>Hi"}]` {
+		t.Fatalf("wrap = %s", got)
+	}
+
+	tagged := `{"candidates":[{"content":{"parts":[{"text":"<thinking>hmm</thinking>Hi"}]},"finishReason":"STOP"}]}`
+	out := policy(t, ModeReasoning).FilterResponse("gemini", []byte(tagged))
+	if got := gjson.GetBytes(out, "candidates.0.content.parts").Raw; got != `[{"text":"hmm","thought":true},{"text":"Hi"}]` {
+		t.Fatalf("reasoning = %s", got)
+	}
+}
+
+func TestUnsupportedFormatPassesThrough(t *testing.T) {
+	body := []byte(`{"output":[{"type":"reasoning"}]}`)
+	if got := policy(t, ModeStrip).FilterResponse("openai-response", body); string(got) != string(body) {
+		t.Fatalf("got %s", got)
+	}
+	var nilPolicy *Policy
+	if nilPolicy.NewStreamFilter("openai").Filter(body) == nil {
+		t.Fatal("nil filter must pass chunks through")
+	}
+}
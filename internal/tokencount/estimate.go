@@ -0,0 +1,111 @@
+package tokencount
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// CountPrompt approximates the prompt tokens of a request in the given source format by
+// translating it to Chat Completions and counting the result.
+func CountPrompt(model string, from sdktranslator.Format, payload []byte) (int64, error) {
+	baseModel := thinking.ParseSuffix(model).ModelName
+	to := sdktranslator.FromString("openai")
+	body := payload
+	if from != to {
+		body = sdktranslator.TranslateRequest(from, to, baseModel, bytes.Clone(payload), false)
+	}
+	enc, err := Tokenizer(baseModel)
+	if err != nil {
+		return 0, fmt.Errorf("tokenizer init failed: %w", err)
+	}
+	return CountOpenAIChat(enc, body)
+}
+
+// CountCompletion approximates the tokens of generated text.
+func CountCompletion(model, text string) (int64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	enc, err := Tokenizer(thinking.ParseSuffix(model).ModelName)
+	if err != nil {
+		return 0, fmt.Errorf("tokenizer init failed: %w", err)
+	}
+	count, err := enc.Count(text)
+	if err != nil {
+		return 0, err
+	}
+	return int64(count), nil
+}
+
+// responseTextKeys are the fields that carry generated text in the response and stream event
+// shapes of the supported providers.
+var responseTextKeys = map[string]bool{
+	"text":              true,
+	"content":           true,
+	"thinking":          true,
+	"reasoning":         true,
+	"reasoning_content": true,
+	"refusal":           true,
+	"arguments":         true,
+	"partial_json":      true,
+	"delta":             true,
+}
+
+// responseArgumentKeys are the fields that carry generated tool arguments as JSON objects.
+var responseArgumentKeys = map[string]bool{
+	"args":  true,
+	"input": true,
+}
+
+// ResponseText extracts the generated text, reasoning and tool arguments from an upstream
+// response body or a chunk of a streamed response, in any of the supported provider formats.
+// SSE framing is stripped, and Responses API summary events that repeat already streamed deltas
+// are skipped so text is not counted twice.
+func ResponseText(data []byte) string {
+	var out strings.Builder
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if after, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			line = bytes.TrimSpace(after)
+		}
+		if len(line) == 0 || !gjson.ValidBytes(line) {
+			continue
+		}
+		root := gjson.ParseBytes(line)
+		if eventType := root.Get("type").String(); strings.HasSuffix(eventType, ".done") || eventType == "response.completed" || eventType == "response.incomplete" {
+			continue
+		}
+		collectResponseText(root, "", &out)
+	}
+	return out.String()
+}
+
+func collectResponseText(value gjson.Result, key string, out *strings.Builder) {
+	switch {
+	case value.Type == gjson.String:
+		if responseTextKeys[key] && value.Str != "" {
+			out.WriteString(value.Str)
+			out.WriteByte('\n')
+		}
+	case value.IsArray():
+		for _, item := range value.Array() {
+			collectResponseText(item, key, out)
+		}
+	case value.IsObject():
+		value.ForEach(func(k, v gjson.Result) bool {
+			if responseArgumentKeys[k.Str] && v.IsObject() {
+				out.WriteString(v.Raw)
+				out.WriteByte('\n')
+				return true
+			}
+			collectResponseText(v, k.Str, out)
+			return true
+		})
+	}
+}
@@ -1,12 +1,16 @@
-package executor
+// Package tokencount estimates prompt token counts locally with tiktoken for providers and
+// handlers that cannot ask the upstream to count.
+package tokencount
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
 	"github.com/tidwall/gjson"
 	"github.com/tiktoken-go/tokenizer"
 )
@@ -33,16 +37,16 @@ func (tw *TokenizerWrapper) Count(text string) (int, error) {
 	return count, nil
 }
 
-// getTokenizer returns a cached tokenizer for the given model.
+// Tokenizer returns a cached tokenizer for the given model.
 // This improves performance by avoiding repeated tokenizer creation.
-func getTokenizer(model string) (*TokenizerWrapper, error) {
+func Tokenizer(model string) (*TokenizerWrapper, error) {
 	// Check cache first
 	if cached, ok := tokenizerCache.Load(model); ok {
 		return cached.(*TokenizerWrapper), nil
 	}
 
 	// Cache miss, create new tokenizer
-	wrapper, err := tokenizerForModel(model)
+	wrapper, err := NewTokenizer(model)
 	if err != nil {
 		return nil, err
 	}
@@ -52,9 +56,9 @@ func getTokenizer(model string) (*TokenizerWrapper, error) {
 	return actual.(*TokenizerWrapper), nil
 }
 
-// tokenizerForModel returns a tokenizer codec suitable for an OpenAI-style model id.
+// NewTokenizer returns a tokenizer codec suitable for an OpenAI-style model id.
 // For Claude models, applies a 1.1 adjustment factor since tiktoken may underestimate.
-func tokenizerForModel(model string) (*TokenizerWrapper, error) {
+func NewTokenizer(model string) (*TokenizerWrapper, error) {
 	sanitized := strings.ToLower(strings.TrimSpace(model))
 
 	// Claude models use cl100k_base with 1.1 adjustment factor
@@ -103,8 +107,8 @@ func tokenizerForModel(model string) (*TokenizerWrapper, error) {
 	return &TokenizerWrapper{Codec: enc, AdjustmentFactor: 1.0}, nil
 }
 
-// countOpenAIChatTokens approximates prompt tokens for OpenAI chat completions payloads.
-func countOpenAIChatTokens(enc *TokenizerWrapper, payload []byte) (int64, error) {
+// CountOpenAIChat approximates prompt tokens for OpenAI chat completions payloads.
+func CountOpenAIChat(enc *TokenizerWrapper, payload []byte) (int64, error) {
 	if enc == nil {
 		return 0, fmt.Errorf("encoder is nil")
 	}
@@ -140,10 +144,10 @@ func countOpenAIChatTokens(enc *TokenizerWrapper, payload []byte) (int64, error)
 	return int64(count) + int64(imageTokens), nil
 }
 
-// countClaudeChatTokens approximates prompt tokens for Claude API chat completions payloads.
+// CountClaudeChat approximates prompt tokens for Claude API chat completions payloads.
 // This handles Claude's message format with system, messages, and tools.
 // Image tokens are estimated based on image dimensions when available.
-func countClaudeChatTokens(enc *TokenizerWrapper, payload []byte) (int64, error) {
+func CountClaudeChat(enc *TokenizerWrapper, payload []byte) (int64, error) {
 	if enc == nil {
 		return 0, fmt.Errorf("encoder is nil")
 	}
@@ -330,8 +334,20 @@ func collectClaudeTools(tools gjson.Result, segments *[]string) {
 	})
 }
 
-// buildOpenAIUsageJSON returns a minimal usage structure understood by downstream translators.
-func buildOpenAIUsageJSON(count int64) []byte {
+// Estimate counts the prompt tokens of a request locally with tiktoken and renders the
+// result in the token count response shape of the source format. It is used when the upstream
+// provider cannot count tokens itself.
+func Estimate(ctx context.Context, model string, from sdktranslator.Format, payload []byte) ([]byte, error) {
+	count, err := CountPrompt(model, from, payload)
+	if err != nil {
+		return nil, fmt.Errorf("token counting failed: %w", err)
+	}
+	to := sdktranslator.FromString("openai")
+	return []byte(sdktranslator.TranslateTokenCount(ctx, to, from, count, OpenAIUsageJSON(count))), nil
+}
+
+// OpenAIUsageJSON returns a minimal usage structure understood by downstream translators.
+func OpenAIUsageJSON(count int64) []byte {
 	return []byte(fmt.Sprintf(`{"usage":{"prompt_tokens":%d,"completion_tokens":0,"total_tokens":%d}}`, count, count))
 }
 
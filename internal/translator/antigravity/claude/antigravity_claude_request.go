@@ -71,6 +71,7 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 
 	messagesResult := gjson.GetBytes(rawJSON, "messages")
 	if messagesResult.IsArray() {
+		toolNames := common.ClaudeToolUseNames(messagesResult)
 		messageResults := messagesResult.Array()
 		numMessages := len(messageResults)
 		for i := 0; i < numMessages; i++ {
@@ -198,7 +199,7 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 							}
 
 							if functionID != "" {
-								partJSON, _ = sjson.Set(partJSON, "functionCall.id", functionID)
+								partJSON, _ = sjson.Set(partJSON, "functionCall.id", cache.UpstreamToolCallIDOr(functionID))
 							}
 							partJSON, _ = sjson.Set(partJSON, "functionCall.name", functionName)
 							partJSON, _ = sjson.SetRaw(partJSON, "functionCall.args", argsRaw)
@@ -207,15 +208,11 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 					} else if contentTypeResult.Type == gjson.String && contentTypeResult.String() == "tool_result" {
 						toolCallID := contentResult.Get("tool_use_id").String()
 						if toolCallID != "" {
-							funcName := toolCallID
-							toolCallIDs := strings.Split(toolCallID, "-")
-							if len(toolCallIDs) > 1 {
-								funcName = strings.Join(toolCallIDs[0:len(toolCallIDs)-2], "-")
-							}
-							functionResponseResult := contentResult.Get("content")
+							funcName := cache.ToolCallName(toolCallID, toolNames)
+							functionResponseResult, imageParts := common.SplitClaudeToolResultImages(contentResult.Get("content"))
 
 							functionResponseJSON := `{}`
-							functionResponseJSON, _ = sjson.Set(functionResponseJSON, "id", toolCallID)
+							functionResponseJSON, _ = sjson.Set(functionResponseJSON, "id", cache.UpstreamToolCallIDOr(toolCallID))
 							functionResponseJSON, _ = sjson.Set(functionResponseJSON, "name", funcName)
 
 							responseData := ""
@@ -239,12 +236,19 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 							partJSON := `{}`
 							partJSON, _ = sjson.SetRaw(partJSON, "functionResponse", functionResponseJSON)
 							clientContentJSON, _ = sjson.SetRaw(clientContentJSON, "parts.-1", partJSON)
+							for _, imagePart := range imageParts {
+								clientContentJSON, _ = sjson.SetRaw(clientContentJSON, "parts.-1", imagePart)
+							}
 						}
-					} else if contentTypeResult.Type == gjson.String && contentTypeResult.String() == "image" {
+					} else if contentTypeResult.Type == gjson.String && (contentTypeResult.String() == "image" || contentTypeResult.String() == "audio") {
 						sourceResult := contentResult.Get("source")
 						if sourceResult.Get("type").String() == "base64" {
 							inlineDataJSON := `{}`
-							if mimeType := sourceResult.Get("media_type").String(); mimeType != "" {
+							mimeType := sourceResult.Get("media_type").String()
+							if contentTypeResult.String() == "audio" {
+								mimeType = common.AudioMimeType(mimeType)
+							}
+							if mimeType != "" {
 								inlineDataJSON, _ = sjson.Set(inlineDataJSON, "mime_type", mimeType)
 							}
 							if data := sourceResult.Get("data").String(); data != "" {
@@ -400,7 +404,7 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 	}
 
 	outBytes := []byte(out)
-	outBytes = common.AttachDefaultSafetySettings(outBytes, "request.safetySettings")
+	outBytes = common.AttachSafetySettings(outBytes, inputRawJSON, "request.safetySettings")
 
 	return outBytes
 }
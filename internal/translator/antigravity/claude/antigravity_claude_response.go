@@ -11,8 +11,6 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"sync/atomic"
-	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
 	log "github.com/sirupsen/logrus"
@@ -39,14 +37,12 @@ type Params struct {
 	HasSentFinalEvents   bool   // Indicates if final content/message events have been sent
 	HasToolUse           bool   // Indicates if tool use was observed in the stream
 	HasContent           bool   // Tracks whether any content (text, thinking, or tool use) has been output
+	MaxOutputTokens      int64  // maxOutputTokens forwarded upstream (Claude models only), used to infer truncation when finishReason is missing
 
 	// Signature caching support
 	CurrentThinkingText strings.Builder // Accumulates thinking text for signature caching
 }
 
-// toolUseIDCounter provides a process-wide unique counter for tool use identifiers.
-var toolUseIDCounter uint64
-
 // ConvertAntigravityResponseToClaude performs sophisticated streaming response format conversion.
 // This function implements a complex state machine that translates backend client responses
 // into Claude Code-compatible Server-Sent Events (SSE) format. It manages different response types
@@ -69,6 +65,7 @@ func ConvertAntigravityResponseToClaude(_ context.Context, _ string, originalReq
 			HasFirstResponse: false,
 			ResponseType:     0,
 			ResponseIndex:    0,
+			MaxOutputTokens:  enforcedMaxOutputTokens(requestRawJSON),
 		}
 	}
 	modelName := gjson.GetBytes(requestRawJSON, "model").String()
@@ -256,7 +253,7 @@ func ConvertAntigravityResponseToClaude(_ context.Context, _ string, originalReq
 
 				// Create the tool use block with unique ID and function details
 				data := fmt.Sprintf(`{"type":"content_block_start","index":%d,"content_block":{"type":"tool_use","id":"","name":"","input":{}}}`, params.ResponseIndex)
-				data, _ = sjson.Set(data, "content_block.id", fmt.Sprintf("%s-%d-%d", fcName, time.Now().UnixNano(), atomic.AddUint64(&toolUseIDCounter, 1)))
+				data, _ = sjson.Set(data, "content_block.id", cache.NewToolCallID("toolu_", fcName, functionCallResult.Get("id").String()))
 				data, _ = sjson.Set(data, "content_block.name", fcName)
 				output = output + fmt.Sprintf("data: %s\n\n\n", data)
 
@@ -344,6 +341,15 @@ func appendFinalEvents(params *Params, output *string, force bool) {
 	params.HasSentFinalEvents = true
 }
 
+// enforcedMaxOutputTokens returns the maxOutputTokens the upstream actually applied. The executor
+// strips the limit for non-Claude models, so it cannot signal truncation for them.
+func enforcedMaxOutputTokens(requestRawJSON []byte) int64 {
+	if !strings.Contains(gjson.GetBytes(requestRawJSON, "model").String(), "claude") {
+		return 0
+	}
+	return gjson.GetBytes(requestRawJSON, "request.generationConfig.maxOutputTokens").Int()
+}
+
 func resolveStopReason(params *Params) string {
 	if params.HasToolUse {
 		return "tool_use"
@@ -354,6 +360,11 @@ func resolveStopReason(params *Params) string {
 		return "max_tokens"
 	case "STOP", "FINISH_REASON_UNSPECIFIED", "UNKNOWN":
 		return "end_turn"
+	case "":
+		// The stream ended without a finishReason; infer truncation from the token counts.
+		if params.MaxOutputTokens > 0 && params.CandidatesTokenCount+params.ThoughtsTokenCount >= params.MaxOutputTokens {
+			return "max_tokens"
+		}
 	}
 
 	return "end_turn"
@@ -414,7 +425,6 @@ func ConvertAntigravityResponseToClaudeNonStream(_ context.Context, _ string, or
 	textBuilder := strings.Builder{}
 	thinkingBuilder := strings.Builder{}
 	thinkingSignature := ""
-	toolIDCounter := 0
 	hasToolCall := false
 
 	flushText := func() {
@@ -473,9 +483,8 @@ func ConvertAntigravityResponseToClaudeNonStream(_ context.Context, _ string, or
 				hasToolCall = true
 
 				name := functionCall.Get("name").String()
-				toolIDCounter++
 				toolBlock := `{"type":"tool_use","id":"","name":"","input":{}}`
-				toolBlock, _ = sjson.Set(toolBlock, "id", fmt.Sprintf("tool_%d", toolIDCounter))
+				toolBlock, _ = sjson.Set(toolBlock, "id", cache.NewToolCallID("toolu_", name, functionCall.Get("id").String()))
 				toolBlock, _ = sjson.Set(toolBlock, "name", name)
 
 				if args := functionCall.Get("args"); args.Exists() && args.Raw != "" && gjson.Valid(args.Raw) && args.IsObject() {
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/common"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
@@ -73,6 +74,35 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 		}
 	}
 
+	// Stop sequences: OpenAI accepts either a single string or an array of strings.
+	if stop := gjson.GetBytes(rawJSON, "stop"); stop.Exists() {
+		var stopSequences []string
+		if stop.IsArray() {
+			stop.ForEach(func(_, value gjson.Result) bool {
+				if s := value.String(); s != "" {
+					stopSequences = append(stopSequences, s)
+				}
+				return true
+			})
+		} else if stop.Type == gjson.String && stop.String() != "" {
+			stopSequences = append(stopSequences, stop.String())
+		}
+		if len(stopSequences) > 0 {
+			out, _ = sjson.SetBytes(out, "request.generationConfig.stopSequences", stopSequences)
+		}
+	}
+
+	// Seed and penalties. Models that reject these are filtered by the executor.
+	if seed := gjson.GetBytes(rawJSON, "seed"); seed.Exists() && seed.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, "request.generationConfig.seed", seed.Int())
+	}
+	if pp := gjson.GetBytes(rawJSON, "presence_penalty"); pp.Exists() && pp.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, "request.generationConfig.presencePenalty", pp.Num)
+	}
+	if fp := gjson.GetBytes(rawJSON, "frequency_penalty"); fp.Exists() && fp.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, "request.generationConfig.frequencyPenalty", fp.Num)
+	}
+
 	// Map OpenAI modalities -> Gemini CLI request.generationConfig.responseModalities
 	// e.g. "modalities": ["image", "text"] -> ["IMAGE", "TEXT"]
 	if mods := gjson.GetBytes(rawJSON, "modalities"); mods.Exists() && mods.IsArray() {
@@ -146,24 +176,11 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 			content := m.Get("content")
 
 			if (role == "system" || role == "developer") && len(arr) > 1 {
-				// system -> request.systemInstruction as a user message style
-				if content.Type == gjson.String {
-					out, _ = sjson.SetBytes(out, "request.systemInstruction.role", "user")
-					out, _ = sjson.SetBytes(out, fmt.Sprintf("request.systemInstruction.parts.%d.text", systemPartIndex), content.String())
-					systemPartIndex++
-				} else if content.IsObject() && content.Get("type").String() == "text" {
+				// system and developer messages are merged, in order, into one system instruction
+				for _, text := range common.OpenAISystemTexts(content) {
 					out, _ = sjson.SetBytes(out, "request.systemInstruction.role", "user")
-					out, _ = sjson.SetBytes(out, fmt.Sprintf("request.systemInstruction.parts.%d.text", systemPartIndex), content.Get("text").String())
+					out, _ = sjson.SetBytes(out, fmt.Sprintf("request.systemInstruction.parts.%d.text", systemPartIndex), text)
 					systemPartIndex++
-				} else if content.IsArray() {
-					contents := content.Array()
-					if len(contents) > 0 {
-						out, _ = sjson.SetBytes(out, "request.systemInstruction.role", "user")
-						for j := 0; j < len(contents); j++ {
-							out, _ = sjson.SetBytes(out, fmt.Sprintf("request.systemInstruction.parts.%d.text", systemPartIndex), contents[j].Get("text").String())
-							systemPartIndex++
-						}
-					}
 				}
 			} else if role == "user" || ((role == "system" || role == "developer") && len(arr) == 1) {
 				// Build single user content node to avoid splitting into multiple contents
@@ -182,17 +199,12 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 							}
 							p++
 						case "image_url":
-							imageURL := item.Get("image_url.url").String()
-							if len(imageURL) > 5 {
-								pieces := strings.SplitN(imageURL[5:], ";", 2)
-								if len(pieces) == 2 && len(pieces[1]) > 7 {
-									mime := pieces[0]
-									data := pieces[1][7:]
-									node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".inlineData.mime_type", mime)
-									node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".inlineData.data", data)
-									node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".thoughtSignature", geminiCLIFunctionThoughtSignature)
-									p++
-								}
+							// data: URLs are inlined directly; https URLs were already inlined by the handler when remote-media is enabled.
+							if mime, data, ok := common.ParseImageURL(item.Get("image_url.url").String()); ok {
+								node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".inlineData.mime_type", mime)
+								node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".inlineData.data", data)
+								node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".thoughtSignature", geminiCLIFunctionThoughtSignature)
+								p++
 							}
 						case "file":
 							filename := item.Get("file.filename").String()
@@ -208,6 +220,16 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 							} else {
 								log.Warnf("Unknown file name extension '%s' in user message, skip", ext)
 							}
+						case "input_audio":
+							audioData := item.Get("input_audio.data").String()
+							format := item.Get("input_audio.format").String()
+							if mimeType := common.AudioMimeType(format); mimeType != "" && audioData != "" {
+								node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".inlineData.mime_type", mimeType)
+								node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".inlineData.data", audioData)
+								p++
+							} else {
+								log.Warnf("Unsupported input_audio format '%s' in user message, skip", format)
+							}
 						}
 					}
 				}
@@ -257,7 +279,7 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 						fid := tc.Get("id").String()
 						fname := tc.Get("function.name").String()
 						fargs := tc.Get("function.arguments").String()
-						node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".functionCall.id", fid)
+						node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".functionCall.id", cache.UpstreamToolCallIDOr(fid))
 						node, _ = sjson.SetBytes(node, "parts."+itoa(p)+".functionCall.name", fname)
 						if gjson.Valid(fargs) {
 							node, _ = sjson.SetRawBytes(node, "parts."+itoa(p)+".functionCall.args", []byte(fargs))
@@ -277,7 +299,7 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 					pp := 0
 					for _, fid := range fIDs {
 						if name, ok := tcID2Name[fid]; ok {
-							toolNode, _ = sjson.SetBytes(toolNode, "parts."+itoa(pp)+".functionResponse.id", fid)
+							toolNode, _ = sjson.SetBytes(toolNode, "parts."+itoa(pp)+".functionResponse.id", cache.UpstreamToolCallIDOr(fid))
 							toolNode, _ = sjson.SetBytes(toolNode, "parts."+itoa(pp)+".functionResponse.name", name)
 							resp := toolResponses[fid]
 							if resp == "" {
@@ -411,7 +433,7 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 		}
 	}
 
-	return common.AttachDefaultSafetySettings(out, "request.safetySettings")
+	return common.AttachSafetySettings(out, inputRawJSON, "request.safetySettings")
 }
 
 // itoa converts int to string without strconv import for few usages.
@@ -0,0 +1,80 @@
+package chat_completions
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertOpenAIRequestToAntigravity_SamplingParams(t *testing.T) {
+	input := []byte(`{
+		"model":"gemini-2.5-pro",
+		"messages":[{"role":"user","content":"hi"}],
+		"stop":["END","STOP"],
+		"seed":42,
+		"presence_penalty":0.5,
+		"frequency_penalty":-0.25
+	}`)
+
+	out := ConvertOpenAIRequestToAntigravity("gemini-2.5-pro", input, false)
+
+	stops := gjson.GetBytes(out, "request.generationConfig.stopSequences").Array()
+	if len(stops) != 2 || stops[0].String() != "END" || stops[1].String() != "STOP" {
+		t.Fatalf("unexpected stopSequences: %s", gjson.GetBytes(out, "request.generationConfig.stopSequences").Raw)
+	}
+	if got := gjson.GetBytes(out, "request.generationConfig.seed").Int(); got != 42 {
+		t.Errorf("expected seed 42, got %d", got)
+	}
+	if got := gjson.GetBytes(out, "request.generationConfig.presencePenalty").Float(); got != 0.5 {
+		t.Errorf("expected presencePenalty 0.5, got %v", got)
+	}
+	if got := gjson.GetBytes(out, "request.generationConfig.frequencyPenalty").Float(); got != -0.25 {
+		t.Errorf("expected frequencyPenalty -0.25, got %v", got)
+	}
+}
+
+func TestConvertOpenAIRequestToAntigravity_StopString(t *testing.T) {
+	input := []byte(`{"messages":[{"role":"user","content":"hi"}],"stop":"\n\n"}`)
+
+	out := ConvertOpenAIRequestToAntigravity("gemini-2.5-pro", input, false)
+
+	stops := gjson.GetBytes(out, "request.generationConfig.stopSequences").Array()
+	if len(stops) != 1 || stops[0].String() != "\n\n" {
+		t.Fatalf("unexpected stopSequences: %s", gjson.GetBytes(out, "request.generationConfig.stopSequences").Raw)
+	}
+}
+
+func TestConvertOpenAIRequestToAntigravity_InputAudio(t *testing.T) {
+	input := []byte(`{"messages":[{"role":"user","content":[
+		{"type":"text","text":"transcribe"},
+		{"type":"input_audio","input_audio":{"data":"UklGRg==","format":"wav"}}
+	]}]}`)
+
+	out := ConvertOpenAIRequestToAntigravity("gemini-2.5-pro", input, false)
+
+	part := gjson.GetBytes(out, "request.contents.0.parts.1.inlineData")
+	if got := part.Get("mime_type").String(); got != "audio/wav" {
+		t.Fatalf("expected audio/wav mime type, got %q (parts: %s)", got, gjson.GetBytes(out, "request.contents.0.parts").Raw)
+	}
+	if got := part.Get("data").String(); got != "UklGRg==" {
+		t.Fatalf("expected audio data to be forwarded, got %q", got)
+	}
+}
+
+func TestConvertOpenAIRequestToAntigravity_MergesSystemAndDeveloper(t *testing.T) {
+	input := []byte(`{"messages":[
+		{"role":"system","content":[{"type":"text","text":"First","cache_control":{"type":"ephemeral"}},"Second"]},
+		{"role":"developer","content":"Third"},
+		{"role":"user","content":"hi"}
+	]}`)
+
+	out := ConvertOpenAIRequestToAntigravity("gemini-2.5-pro", input, false)
+
+	parts := gjson.GetBytes(out, "request.systemInstruction.parts").Array()
+	if len(parts) != 3 || parts[0].Get("text").String() != "First" || parts[1].Get("text").String() != "Second" || parts[2].Get("text").String() != "Third" {
+		t.Fatalf("unexpected systemInstruction parts: %s", gjson.GetBytes(out, "request.systemInstruction.parts").Raw)
+	}
+	if got := gjson.GetBytes(out, "request.contents.#").Int(); got != 1 {
+		t.Fatalf("expected only the user message in contents, got %d", got)
+	}
+}
@@ -9,33 +9,49 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sort"
 	"strings"
-	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
 	. "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/openai/chat-completions"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
 
 // convertCliResponseToOpenAIChatParams holds parameters for response conversion.
+// Per-candidate state is keyed by the candidate index so that requests with n > 1
+// are fanned out into independent OpenAI choices.
 type convertCliResponseToOpenAIChatParams struct {
 	UnixTimestamp        int64
-	FunctionIndex        int
-	SawToolCall          bool   // Tracks if any tool call was seen in the entire stream
-	UpstreamFinishReason string // Caches the upstream finish reason for final chunk
+	FunctionIndex        map[int]int
+	SawToolCall          map[int]bool                 // Tracks if any tool call was seen in the entire stream
+	UpstreamFinishReason map[int]string               // Caches the upstream finish reason for final chunk
+	FinishSent           map[int]bool                 // Candidates seen so far, true once a finish_reason was emitted
+	CompletionTokens     int64                        // Last reported candidatesTokenCount, used to detect truncation
+	LastTemplate         string                       // Chunk template (id/model/created) used to synthesize a terminal chunk
+	OpenToolCalls        map[int]*antigravityToolCall // Tool calls whose arguments are still streaming, per candidate
+	ImageIndex           map[int]int                  // Number of images emitted so far, per candidate
 }
 
-// functionCallIDCounter provides a process-wide unique counter for function call identifiers.
-var functionCallIDCounter uint64
+// antigravityToolCall tracks a function call whose arguments arrive over several parts
+// (partialArgs with willContinue), so they can be forwarded as OpenAI argument fragments.
+type antigravityToolCall struct {
+	index      int
+	args       string // arguments object assembled so far
+	emitted    string // argument text already sent to the client
+	openString string // sjson path of a string value that is still being streamed
+}
 
 // ConvertAntigravityResponseToOpenAI translates a single chunk of a streaming response from the
 // Gemini CLI API format to the OpenAI Chat Completions streaming format.
 // It processes various Gemini CLI event types and transforms them into OpenAI-compatible JSON responses.
 // The function handles text content, tool calls, reasoning content, and usage metadata, outputting
 // responses that match the OpenAI API format. It supports incremental updates for streaming responses.
+// When the upstream returns several candidates, one chunk is emitted per candidate with the
+// matching choice index.
 //
 // Parameters:
 //   - ctx: The context for the request, used for cancellation and timeout handling
@@ -47,14 +63,30 @@ var functionCallIDCounter uint64
 //   - []string: A slice of strings, each containing an OpenAI-compatible JSON response
 func ConvertAntigravityResponseToOpenAI(_ context.Context, _ string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) []string {
 	if *param == nil {
-		*param = &convertCliResponseToOpenAIChatParams{
-			UnixTimestamp: 0,
-			FunctionIndex: 0,
-		}
+		*param = &convertCliResponseToOpenAIChatParams{}
+	}
+	params := (*param).(*convertCliResponseToOpenAIChatParams)
+	if params.FunctionIndex == nil {
+		params.FunctionIndex = make(map[int]int)
+	}
+	if params.SawToolCall == nil {
+		params.SawToolCall = make(map[int]bool)
+	}
+	if params.UpstreamFinishReason == nil {
+		params.UpstreamFinishReason = make(map[int]string)
+	}
+	if params.FinishSent == nil {
+		params.FinishSent = make(map[int]bool)
+	}
+	if params.OpenToolCalls == nil {
+		params.OpenToolCalls = make(map[int]*antigravityToolCall)
+	}
+	if params.ImageIndex == nil {
+		params.ImageIndex = make(map[int]int)
 	}
 
 	if bytes.Equal(rawJSON, []byte("[DONE]")) {
-		return []string{}
+		return finalizeAntigravityOpenAIStream(params, requestRawJSON)
 	}
 
 	// Initialize the OpenAI SSE template.
@@ -69,28 +101,26 @@ func ConvertAntigravityResponseToOpenAI(_ context.Context, _ string, originalReq
 	if createTimeResult := gjson.GetBytes(rawJSON, "response.createTime"); createTimeResult.Exists() {
 		t, err := time.Parse(time.RFC3339Nano, createTimeResult.String())
 		if err == nil {
-			(*param).(*convertCliResponseToOpenAIChatParams).UnixTimestamp = t.Unix()
+			params.UnixTimestamp = t.Unix()
 		}
-		template, _ = sjson.Set(template, "created", (*param).(*convertCliResponseToOpenAIChatParams).UnixTimestamp)
+		template, _ = sjson.Set(template, "created", params.UnixTimestamp)
 	} else {
-		template, _ = sjson.Set(template, "created", (*param).(*convertCliResponseToOpenAIChatParams).UnixTimestamp)
+		template, _ = sjson.Set(template, "created", params.UnixTimestamp)
 	}
 
 	// Extract and set the response ID.
 	if responseIDResult := gjson.GetBytes(rawJSON, "response.responseId"); responseIDResult.Exists() {
 		template, _ = sjson.Set(template, "id", responseIDResult.String())
 	}
-
-	// Cache the finish reason - do NOT set it in output yet (will be set on final chunk)
-	if finishReasonResult := gjson.GetBytes(rawJSON, "response.candidates.0.finishReason"); finishReasonResult.Exists() {
-		(*param).(*convertCliResponseToOpenAIChatParams).UpstreamFinishReason = strings.ToUpper(finishReasonResult.String())
-	}
+	params.LastTemplate = template
 
 	// Extract and set usage metadata (token counts).
-	if usageResult := gjson.GetBytes(rawJSON, "response.usageMetadata"); usageResult.Exists() {
+	usageResult := gjson.GetBytes(rawJSON, "response.usageMetadata")
+	if usageResult.Exists() {
 		cachedTokenCount := usageResult.Get("cachedContentTokenCount").Int()
 		if candidatesTokenCountResult := usageResult.Get("candidatesTokenCount"); candidatesTokenCountResult.Exists() {
 			template, _ = sjson.Set(template, "usage.completion_tokens", candidatesTokenCountResult.Int())
+			params.CompletionTokens = candidatesTokenCountResult.Int()
 		}
 		if totalTokenCountResult := usageResult.Get("totalTokenCount"); totalTokenCountResult.Exists() {
 			template, _ = sjson.Set(template, "usage.total_tokens", totalTokenCountResult.Int())
@@ -111,112 +141,372 @@ func ConvertAntigravityResponseToOpenAI(_ context.Context, _ string, originalReq
 		}
 	}
 
-	// Process the main content part of the response.
-	partsResult := gjson.GetBytes(rawJSON, "response.candidates.0.content.parts")
-	if partsResult.IsArray() {
-		partResults := partsResult.Array()
-		for i := 0; i < len(partResults); i++ {
-			partResult := partResults[i]
-			partTextResult := partResult.Get("text")
-			functionCallResult := partResult.Get("functionCall")
-			thoughtSignatureResult := partResult.Get("thoughtSignature")
-			if !thoughtSignatureResult.Exists() {
-				thoughtSignatureResult = partResult.Get("thought_signature")
-			}
-			inlineDataResult := partResult.Get("inlineData")
-			if !inlineDataResult.Exists() {
-				inlineDataResult = partResult.Get("inline_data")
-			}
+	candidatesResult := gjson.GetBytes(rawJSON, "response.candidates")
+	if !candidatesResult.IsArray() || len(candidatesResult.Array()) == 0 {
+		// Usage-only chunk: close out every candidate whose finish reason was cached earlier.
+		if !usageResult.Exists() || len(params.UpstreamFinishReason) == 0 {
+			return []string{template}
+		}
+		indices := make([]int, 0, len(params.UpstreamFinishReason))
+		for candidateIndex := range params.UpstreamFinishReason {
+			indices = append(indices, candidateIndex)
+		}
+		sort.Ints(indices)
+		out := make([]string, 0, len(indices))
+		for _, candidateIndex := range indices {
+			chunk, _ := sjson.Set(template, "choices.0.index", candidateIndex)
+			out = append(out, applyAntigravityFinishReason(chunk, params, candidateIndex))
+		}
+		return out
+	}
 
-			hasThoughtSignature := thoughtSignatureResult.Exists() && thoughtSignatureResult.String() != ""
-			hasContentPayload := partTextResult.Exists() || functionCallResult.Exists() || inlineDataResult.Exists()
+	candidates := candidatesResult.Array()
+	out := make([]string, 0, len(candidates))
+	for position, candidate := range candidates {
+		candidateIndex := position
+		if indexResult := candidate.Get("index"); indexResult.Exists() {
+			candidateIndex = int(indexResult.Int())
+		}
+		chunk, _ := sjson.Set(template, "choices.0.index", candidateIndex)
+		if _, seen := params.FinishSent[candidateIndex]; !seen {
+			params.FinishSent[candidateIndex] = false
+		}
 
-			// Ignore encrypted thoughtSignature but keep any actual content in the same part.
-			if hasThoughtSignature && !hasContentPayload {
-				continue
-			}
+		// Cache the finish reason - do NOT set it in output yet (will be set on final chunk)
+		if finishReasonResult := candidate.Get("finishReason"); finishReasonResult.Exists() {
+			params.UpstreamFinishReason[candidateIndex] = strings.ToUpper(finishReasonResult.String())
+		}
 
-			if partTextResult.Exists() {
-				textContent := partTextResult.String()
+		chunks := convertAntigravityCandidateParts(chunk, candidate.Get("content.parts"), params, candidateIndex)
 
-				// Handle text content, distinguishing between regular content and reasoning/thoughts.
-				if partResult.Get("thought").Bool() {
-					template, _ = sjson.Set(template, "choices.0.delta.reasoning_content", textContent)
-				} else {
-					template, _ = sjson.Set(template, "choices.0.delta.content", textContent)
-				}
-				template, _ = sjson.Set(template, "choices.0.delta.role", "assistant")
-			} else if functionCallResult.Exists() {
-				// Handle function call content.
-				(*param).(*convertCliResponseToOpenAIChatParams).SawToolCall = true // Persist across chunks
-				toolCallsResult := gjson.Get(template, "choices.0.delta.tool_calls")
-				functionCallIndex := (*param).(*convertCliResponseToOpenAIChatParams).FunctionIndex
-				(*param).(*convertCliResponseToOpenAIChatParams).FunctionIndex++
-				if toolCallsResult.Exists() && toolCallsResult.IsArray() {
-					functionCallIndex = len(toolCallsResult.Array())
-				} else {
-					template, _ = sjson.SetRaw(template, "choices.0.delta.tool_calls", `[]`)
-				}
+		// Determine finish_reason only on the final chunk (has both finishReason and usage metadata)
+		if usageResult.Exists() {
+			last := len(chunks) - 1
+			chunks[last] = applyAntigravityFinishReason(chunks[last], params, candidateIndex)
+		}
+		out = append(out, chunks...)
+	}
+	return out
+}
 
-				functionCallTemplate := `{"id": "","index": 0,"type": "function","function": {"name": "","arguments": ""}}`
-				fcName := functionCallResult.Get("name").String()
-				functionCallTemplate, _ = sjson.Set(functionCallTemplate, "id", fmt.Sprintf("%s-%d-%d", fcName, time.Now().UnixNano(), atomic.AddUint64(&functionCallIDCounter, 1)))
-				functionCallTemplate, _ = sjson.Set(functionCallTemplate, "index", functionCallIndex)
-				functionCallTemplate, _ = sjson.Set(functionCallTemplate, "function.name", fcName)
-				if fcArgsResult := functionCallResult.Get("args"); fcArgsResult.Exists() {
-					functionCallTemplate, _ = sjson.Set(functionCallTemplate, "function.arguments", fcArgsResult.Raw)
-				}
-				template, _ = sjson.Set(template, "choices.0.delta.role", "assistant")
-				template, _ = sjson.SetRaw(template, "choices.0.delta.tool_calls.-1", functionCallTemplate)
-			} else if inlineDataResult.Exists() {
-				data := inlineDataResult.Get("data").String()
-				if data == "" {
-					continue
-				}
-				mimeType := inlineDataResult.Get("mimeType").String()
-				if mimeType == "" {
-					mimeType = inlineDataResult.Get("mime_type").String()
-				}
-				if mimeType == "" {
-					mimeType = "image/png"
-				}
-				imageURL := fmt.Sprintf("data:%s;base64,%s", mimeType, data)
-				imagesResult := gjson.Get(template, "choices.0.delta.images")
-				if !imagesResult.Exists() || !imagesResult.IsArray() {
-					template, _ = sjson.SetRaw(template, "choices.0.delta.images", `[]`)
+// convertAntigravityCandidateParts converts the content parts of a single candidate into one
+// or more chunks based on template. Consecutive parts of the same kind share a chunk; whenever
+// the kind changes (e.g. thought -> text -> functionCall) a new chunk is started so that clients
+// receive reasoning, content and tool calls in upstream order without thoughts leaking into content.
+func convertAntigravityCandidateParts(template string, partsResult gjson.Result, params *convertCliResponseToOpenAIChatParams, candidateIndex int) []string {
+	if !partsResult.IsArray() {
+		return []string{template}
+	}
+	var chunks []string
+	currentKind := ""
+	flushSegment := func() {
+		if currentKind != "" {
+			// Usage metadata is only reported once, on the last chunk of the candidate.
+			segment, _ := sjson.Delete(template, "usage")
+			chunks = append(chunks, segment)
+			template, _ = sjson.SetRaw(template, "choices.0.delta", `{"role":null,"content":null,"reasoning_content":null,"tool_calls":null}`)
+		}
+		currentKind = ""
+	}
+	startSegment := func(kind string) {
+		if currentKind != kind {
+			flushSegment()
+		}
+		currentKind = kind
+	}
+	partResults := partsResult.Array()
+	for i := 0; i < len(partResults); i++ {
+		partResult := partResults[i]
+		partTextResult := partResult.Get("text")
+		functionCallResult := partResult.Get("functionCall")
+		thoughtSignatureResult := partResult.Get("thoughtSignature")
+		if !thoughtSignatureResult.Exists() {
+			thoughtSignatureResult = partResult.Get("thought_signature")
+		}
+		inlineDataResult := partResult.Get("inlineData")
+		if !inlineDataResult.Exists() {
+			inlineDataResult = partResult.Get("inline_data")
+		}
+
+		hasThoughtSignature := thoughtSignatureResult.Exists() && thoughtSignatureResult.String() != ""
+		hasContentPayload := partTextResult.Exists() || functionCallResult.Exists() || inlineDataResult.Exists()
+
+		// Ignore encrypted thoughtSignature but keep any actual content in the same part.
+		if hasThoughtSignature && !hasContentPayload {
+			continue
+		}
+
+		if partTextResult.Exists() {
+			textContent := partTextResult.String()
+
+			// Handle text content, distinguishing between regular content and reasoning/thoughts.
+			// Consecutive parts of the same kind are concatenated rather than overwritten.
+			field := "choices.0.delta.content"
+			kind := "content"
+			if partResult.Get("thought").Bool() {
+				field = "choices.0.delta.reasoning_content"
+				kind = "reasoning"
+			}
+			startSegment(kind)
+			template, _ = sjson.Set(template, field, gjson.Get(template, field).String()+textContent)
+			template, _ = sjson.Set(template, "choices.0.delta.role", "assistant")
+		} else if functionCallResult.Exists() {
+			// Handle function call content. Every tool call delta gets its own chunk so that the
+			// header (id and name) reaches the client before the arguments stream in.
+			params.SawToolCall[candidateIndex] = true // Persist across chunks
+			for _, toolCallDelta := range antigravityToolCallDeltas(params, candidateIndex, functionCallResult) {
+				if currentKind == "tool_calls" {
+					flushSegment()
 				}
-				imageIndex := len(gjson.Get(template, "choices.0.delta.images").Array())
-				imagePayload := `{"type":"image_url","image_url":{"url":""}}`
-				imagePayload, _ = sjson.Set(imagePayload, "index", imageIndex)
-				imagePayload, _ = sjson.Set(imagePayload, "image_url.url", imageURL)
+				startSegment("tool_calls")
+				template, _ = sjson.SetRaw(template, "choices.0.delta.tool_calls", "[]")
 				template, _ = sjson.Set(template, "choices.0.delta.role", "assistant")
-				template, _ = sjson.SetRaw(template, "choices.0.delta.images.-1", imagePayload)
+				template, _ = sjson.SetRaw(template, "choices.0.delta.tool_calls.-1", toolCallDelta)
+			}
+		} else if inlineDataResult.Exists() {
+			data := inlineDataResult.Get("data").String()
+			// Image models emit draft images as thoughts; only the final images are returned.
+			if data == "" || partResult.Get("thought").Bool() {
+				continue
+			}
+			mimeType := inlineDataResult.Get("mimeType").String()
+			if mimeType == "" {
+				mimeType = inlineDataResult.Get("mime_type").String()
+			}
+			if mimeType == "" {
+				mimeType = "image/png"
+			}
+			startSegment("images")
+			imageURL := fmt.Sprintf("data:%s;base64,%s", mimeType, data)
+			imagesResult := gjson.Get(template, "choices.0.delta.images")
+			if !imagesResult.Exists() || !imagesResult.IsArray() {
+				template, _ = sjson.SetRaw(template, "choices.0.delta.images", `[]`)
 			}
+			// The index is the image's position in the whole message, not in this chunk.
+			imageIndex := params.ImageIndex[candidateIndex]
+			params.ImageIndex[candidateIndex]++
+			imagePayload := `{"type":"image_url","image_url":{"url":""}}`
+			imagePayload, _ = sjson.Set(imagePayload, "index", imageIndex)
+			imagePayload, _ = sjson.Set(imagePayload, "image_url.url", imageURL)
+			template, _ = sjson.Set(template, "choices.0.delta.role", "assistant")
+			template, _ = sjson.SetRaw(template, "choices.0.delta.images.-1", imagePayload)
 		}
 	}
+	return append(chunks, template)
+}
 
-	// Determine finish_reason only on the final chunk (has both finishReason and usage metadata)
-	params := (*param).(*convertCliResponseToOpenAIChatParams)
-	upstreamFinishReason := params.UpstreamFinishReason
-	sawToolCall := params.SawToolCall
+// antigravityToolCallDeltas converts a functionCall part into OpenAI tool_calls deltas. A part
+// carrying a name starts a new call and yields the header delta with id, name and empty arguments.
+// The arguments follow as fragments: complete args in one fragment, streamed partialArgs as the
+// stable prefix of the arguments object assembled so far, and the remainder once the call ends.
+func antigravityToolCallDeltas(params *convertCliResponseToOpenAIChatParams, candidateIndex int, functionCallResult gjson.Result) []string {
+	var deltas []string
+	call := params.OpenToolCalls[candidateIndex]
+	if fcName := functionCallResult.Get("name").String(); fcName != "" || call == nil {
+		if call != nil {
+			// The previous call was never closed; flush what is left of its arguments.
+			if fragment := call.pendingArguments(true); fragment != "" {
+				deltas = append(deltas, antigravityArgumentsDelta(call.index, fragment))
+			}
+		}
+		call = &antigravityToolCall{index: params.FunctionIndex[candidateIndex]}
+		params.FunctionIndex[candidateIndex]++
+
+		header := `{"id":"","index":0,"type":"function","function":{"name":"","arguments":""}}`
+		header, _ = sjson.Set(header, "id", cache.NewToolCallID("call_", fcName, functionCallResult.Get("id").String()))
+		header, _ = sjson.Set(header, "index", call.index)
+		header, _ = sjson.Set(header, "function.name", fcName)
+		deltas = append(deltas, header)
+	}
+
+	if argsResult := functionCallResult.Get("args"); argsResult.IsObject() {
+		call.args = argsResult.Raw
+		call.openString = ""
+	}
+	for _, partial := range functionCallResult.Get("partialArgs").Array() {
+		call.applyPartialArg(partial)
+	}
+
+	complete := !functionCallResult.Get("willContinue").Bool()
+	if complete {
+		delete(params.OpenToolCalls, candidateIndex)
+	} else {
+		params.OpenToolCalls[candidateIndex] = call
+	}
+	if fragment := call.pendingArguments(complete); fragment != "" {
+		deltas = append(deltas, antigravityArgumentsDelta(call.index, fragment))
+	}
+	return deltas
+}
 
-	usageExists := gjson.GetBytes(rawJSON, "response.usageMetadata").Exists()
-	isFinalChunk := upstreamFinishReason != "" && usageExists
+func antigravityArgumentsDelta(index int, fragment string) string {
+	delta := `{"index":0,"function":{"arguments":""}}`
+	delta, _ = sjson.Set(delta, "index", index)
+	delta, _ = sjson.Set(delta, "function.arguments", fragment)
+	return delta
+}
 
-	if isFinalChunk {
-		var finishReason string
-		if sawToolCall {
-			finishReason = "tool_calls"
-		} else if upstreamFinishReason == "MAX_TOKENS" {
-			finishReason = "max_tokens"
-		} else {
-			finishReason = "stop"
+// applyPartialArg merges one streamed argument value into the arguments object. String values
+// flagged with willContinue are appended to by the following partial for the same path.
+func (c *antigravityToolCall) applyPartialArg(partial gjson.Result) {
+	path := antigravityJSONPathToSJSON(partial.Get("jsonPath").String())
+	if path == "" {
+		return
+	}
+	if c.args == "" {
+		c.args = "{}"
+	}
+	switch {
+	case partial.Get("stringValue").Exists():
+		value := partial.Get("stringValue").String()
+		if c.openString == path {
+			value = gjson.Get(c.args, path).String() + value
 		}
-		template, _ = sjson.Set(template, "choices.0.finish_reason", finishReason)
-		template, _ = sjson.Set(template, "choices.0.native_finish_reason", strings.ToLower(upstreamFinishReason))
+		c.args, _ = sjson.Set(c.args, path, value)
+		c.openString = ""
+		if partial.Get("willContinue").Bool() {
+			c.openString = path
+		}
+		return
+	case partial.Get("numberValue").Exists():
+		c.args, _ = sjson.SetRaw(c.args, path, partial.Get("numberValue").Raw)
+	case partial.Get("boolValue").Exists():
+		c.args, _ = sjson.Set(c.args, path, partial.Get("boolValue").Bool())
+	case partial.Get("nullValue").Exists():
+		c.args, _ = sjson.SetRaw(c.args, path, "null")
+	default:
+		return
 	}
+	c.openString = ""
+}
 
-	return []string{template}
+// pendingArguments returns the argument text not yet sent to the client. While the call is
+// still streaming only the stable prefix is released: closing brackets, and the closing quote
+// of a string that is still growing, are held back until later parts confirm them.
+func (c *antigravityToolCall) pendingArguments(complete bool) string {
+	text := c.args
+	if text == "" {
+		text = "{}"
+	}
+	if !complete {
+		text = strings.TrimRight(text, "}]")
+		if c.openString != "" {
+			text = strings.TrimSuffix(text, `"`)
+		}
+	}
+	if !strings.HasPrefix(text, c.emitted) {
+		// Upstream rewrote a value that was already sent; fragments cannot be retracted.
+		log.Debugf("antigravity openai response: tool call %d arguments diverged from streamed prefix", c.index)
+		return ""
+	}
+	fragment := text[len(c.emitted):]
+	c.emitted = text
+	return fragment
+}
+
+// antigravityJSONPathToSJSON converts a partialArgs JSON path such as "$.items[0].name" or
+// "$['a.b']" into the equivalent sjson path. Unsupported paths yield "".
+func antigravityJSONPathToSJSON(jsonPath string) string {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(jsonPath), "$")
+	if !ok {
+		return ""
+	}
+	escaper := strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`)
+	var segments []string
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "['") || strings.HasPrefix(rest, `["`):
+			end := strings.Index(rest[2:], string(rest[1])+"]")
+			if end < 0 {
+				return ""
+			}
+			segments = append(segments, escaper.Replace(rest[2:2+end]))
+			rest = rest[2+end+2:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return ""
+			}
+			segments = append(segments, rest[1:end])
+			rest = rest[end+1:]
+		case rest[0] == '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			segments = append(segments, escaper.Replace(rest[:end]))
+			rest = rest[end:]
+		default:
+			return ""
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+// applyAntigravityFinishReason sets the OpenAI finish_reason for candidateIndex when an
+// upstream finish reason has been cached for it.
+func applyAntigravityFinishReason(template string, params *convertCliResponseToOpenAIChatParams, candidateIndex int) string {
+	upstreamFinishReason := params.UpstreamFinishReason[candidateIndex]
+	if upstreamFinishReason == "" {
+		return template
+	}
+	var finishReason string
+	if params.SawToolCall[candidateIndex] {
+		finishReason = "tool_calls"
+	} else if upstreamFinishReason == "MAX_TOKENS" {
+		finishReason = "length"
+	} else {
+		finishReason = "stop"
+	}
+	template, _ = sjson.Set(template, "choices.0.finish_reason", finishReason)
+	template, _ = sjson.Set(template, "choices.0.native_finish_reason", strings.ToLower(upstreamFinishReason))
+	params.FinishSent[candidateIndex] = true
+	return template
+}
+
+// finalizeAntigravityOpenAIStream synthesizes a terminal chunk for every candidate that never
+// received a finish_reason, which happens when the upstream stream ends without a finishReason
+// or without the trailing usage chunk. Truncation is inferred from the reported completion
+// tokens reaching maxOutputTokens, which is only meaningful for Claude models: the executor
+// strips the limit for every other model, so the upstream never enforced it.
+func finalizeAntigravityOpenAIStream(params *convertCliResponseToOpenAIChatParams, requestRawJSON []byte) []string {
+	if params.LastTemplate == "" {
+		return []string{}
+	}
+	indices := make([]int, 0, len(params.FinishSent))
+	for candidateIndex, sent := range params.FinishSent {
+		if !sent {
+			indices = append(indices, candidateIndex)
+		}
+	}
+	if len(indices) == 0 {
+		return []string{}
+	}
+	sort.Ints(indices)
+
+	inferredReason := "STOP"
+	if maxOutput := enforcedMaxOutputTokens(requestRawJSON); maxOutput > 0 && params.CompletionTokens >= maxOutput {
+		inferredReason = "MAX_TOKENS"
+	}
+	out := make([]string, 0, len(indices))
+	for _, candidateIndex := range indices {
+		if params.UpstreamFinishReason[candidateIndex] == "" {
+			params.UpstreamFinishReason[candidateIndex] = inferredReason
+		}
+		chunk, _ := sjson.Set(params.LastTemplate, "choices.0.index", candidateIndex)
+		out = append(out, applyAntigravityFinishReason(chunk, params, candidateIndex))
+	}
+	return out
+}
+
+// enforcedMaxOutputTokens returns the maxOutputTokens the upstream actually applied, or 0 when the
+// executor removed the limit before sending the request.
+func enforcedMaxOutputTokens(requestRawJSON []byte) int64 {
+	if !strings.Contains(gjson.GetBytes(requestRawJSON, "model").String(), "claude") {
+		return 0
+	}
+	return gjson.GetBytes(requestRawJSON, "request.generationConfig.maxOutputTokens").Int()
 }
 
 // ConvertAntigravityResponseToOpenAINonStream converts a non-streaming Gemini CLI response to a non-streaming OpenAI response.
@@ -15,13 +15,15 @@ func TestFinishReasonToolCallsNotOverwritten(t *testing.T) {
 	chunk1 := []byte(`{"response":{"candidates":[{"content":{"parts":[{"functionCall":{"name":"list_files","args":{"path":"."}}}]}}]}}`)
 	result1 := ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, chunk1, &param)
 
-	// Verify chunk1 has no finish_reason (null)
-	if len(result1) != 1 {
-		t.Fatalf("Expected 1 result from chunk1, got %d", len(result1))
+	// Verify chunk1 (tool call header and arguments) has no finish_reason (null)
+	if len(result1) != 2 {
+		t.Fatalf("Expected 2 results from chunk1, got %d", len(result1))
 	}
-	fr1 := gjson.Get(result1[0], "choices.0.finish_reason")
-	if fr1.Exists() && fr1.String() != "" && fr1.Type.String() != "Null" {
-		t.Errorf("Expected finish_reason to be null in chunk1, got: %v", fr1.String())
+	for _, result := range result1 {
+		fr1 := gjson.Get(result, "choices.0.finish_reason")
+		if fr1.Exists() && fr1.String() != "" && fr1.Type.String() != "Null" {
+			t.Errorf("Expected finish_reason to be null in chunk1, got: %v", fr1.String())
+		}
 	}
 
 	// Chunk 2: Contains finishReason STOP + usage (final chunk, no functionCall)
@@ -76,10 +78,10 @@ func TestFinishReasonMaxTokens(t *testing.T) {
 	chunk2 := []byte(`{"response":{"candidates":[{"finishReason":"MAX_TOKENS"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":100,"totalTokenCount":110}}}`)
 	result2 := ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, chunk2, &param)
 
-	// Verify finish_reason is "max_tokens"
+	// Verify finish_reason is "length"
 	fr := gjson.Get(result2[0], "choices.0.finish_reason").String()
-	if fr != "max_tokens" {
-		t.Errorf("Expected finish_reason 'max_tokens', got: %s", fr)
+	if fr != "length" {
+		t.Errorf("Expected finish_reason 'length', got: %s", fr)
 	}
 }
 
@@ -126,3 +128,215 @@ func TestNoFinishReasonOnIntermediateChunks(t *testing.T) {
 		t.Errorf("Expected no finish_reason on intermediate chunk, got: %v", fr2)
 	}
 }
+
+func TestMultipleCandidatesFanOutToChoices(t *testing.T) {
+	ctx := context.Background()
+	var param any
+
+	chunk := []byte(`{"response":{"candidates":[{"content":{"parts":[{"text":"first"}]}},{"index":1,"content":{"parts":[{"text":"second"}]}}]}}`)
+	results := ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, chunk, &param)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results for 2 candidates, got %d", len(results))
+	}
+	for i, want := range []string{"first", "second"} {
+		if got := gjson.Get(results[i], "choices.0.index").Int(); got != int64(i) {
+			t.Errorf("Expected choice index %d, got %d", i, got)
+		}
+		if got := gjson.Get(results[i], "choices.0.delta.content").String(); got != want {
+			t.Errorf("Expected content %q, got %q", want, got)
+		}
+	}
+
+	final := []byte(`{"response":{"candidates":[{"index":0,"finishReason":"STOP"},{"index":1,"finishReason":"MAX_TOKENS"}],"usageMetadata":{"promptTokenCount":1,"candidatesTokenCount":2,"totalTokenCount":3}}}`)
+	results = ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, final, &param)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 final results, got %d", len(results))
+	}
+	if got := gjson.Get(results[0], "choices.0.finish_reason").String(); got != "stop" {
+		t.Errorf("Expected finish_reason 'stop' for choice 0, got %q", got)
+	}
+	if got := gjson.Get(results[1], "choices.0.finish_reason").String(); got != "length" {
+		t.Errorf("Expected finish_reason 'length' for choice 1, got %q", got)
+	}
+}
+
+func TestInterleavedThoughtTextAndToolCallsKeepOrder(t *testing.T) {
+	ctx := context.Background()
+	var param any
+
+	// Gemini 3 style stream: a signed thought, visible text, a second thought, then two parallel tool calls.
+	stream := [][]byte{
+		[]byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"**Planning** I need the file list","thought":true,"thoughtSignature":"c2ln"}]}}],"modelVersion":"gemini-3-pro-preview","responseId":"r1"}}`),
+		[]byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":" first.","thought":true},{"text":"Let me look."},{"text":" Checking both dirs.","thought":true},{"functionCall":{"name":"list_files","args":{"path":"a"}},"thoughtSignature":"c2ln"}]}}],"modelVersion":"gemini-3-pro-preview","responseId":"r1"}}`),
+		[]byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"list_files","args":{"path":"b"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":12,"candidatesTokenCount":30,"thoughtsTokenCount":20,"totalTokenCount":62},"modelVersion":"gemini-3-pro-preview","responseId":"r1"}}`),
+	}
+
+	var chunks []string
+	for _, raw := range stream {
+		chunks = append(chunks, ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, raw, &param)...)
+	}
+
+	type delta struct{ kind, value string }
+	var got []delta
+	for _, chunk := range chunks {
+		d := gjson.Get(chunk, "choices.0.delta")
+		if v := d.Get("reasoning_content"); v.Type == gjson.String {
+			got = append(got, delta{"reasoning", v.String()})
+		}
+		if v := d.Get("content"); v.Type == gjson.String {
+			got = append(got, delta{"content", v.String()})
+		}
+		for _, tc := range d.Get("tool_calls").Array() {
+			got = append(got, delta{"tool", tc.Get("index").String() + ":" + tc.Get("function.arguments").String()})
+		}
+	}
+
+	want := []delta{
+		{"reasoning", "**Planning** I need the file list"},
+		{"reasoning", " first."},
+		{"content", "Let me look."},
+		{"reasoning", " Checking both dirs."},
+		{"tool", `0:`},
+		{"tool", `0:{"path":"a"}`},
+		{"tool", `1:`},
+		{"tool", `1:{"path":"b"}`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected deltas: %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("delta %d: got %+v, want %+v (all: %+v)", i, got[i], want[i], got)
+		}
+	}
+
+	last := chunks[len(chunks)-1]
+	if fr := gjson.Get(last, "choices.0.finish_reason").String(); fr != "tool_calls" {
+		t.Errorf("expected finish_reason tool_calls on last chunk, got %q", fr)
+	}
+	for _, chunk := range chunks[:len(chunks)-1] {
+		if gjson.Get(chunk, "usage").Exists() {
+			t.Errorf("usage should only be reported on the last chunk: %s", chunk)
+		}
+	}
+}
+
+func TestDoneSynthesizesFinishWhenUpstreamOmitsIt(t *testing.T) {
+	ctx := context.Background()
+
+	// Stream ends without finishReason or usage chunk.
+	var param any
+	ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, []byte(`{"response":{"candidates":[{"content":{"parts":[{"text":"partial"}]}}],"responseId":"r1"}}`), &param)
+	tail := ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, []byte("[DONE]"), &param)
+	if len(tail) != 1 {
+		t.Fatalf("expected one synthesized terminal chunk, got %d", len(tail))
+	}
+	if fr := gjson.Get(tail[0], "choices.0.finish_reason").String(); fr != "stop" {
+		t.Errorf("expected synthesized finish_reason stop, got %q", fr)
+	}
+	if id := gjson.Get(tail[0], "id").String(); id != "r1" {
+		t.Errorf("expected synthesized chunk to keep response id, got %q", id)
+	}
+
+	// Usage reached maxOutputTokens but no finishReason was sent.
+	param = nil
+	request := []byte(`{"model":"claude-sonnet-4-5","request":{"generationConfig":{"maxOutputTokens":5}}}`)
+	ConvertAntigravityResponseToOpenAI(ctx, "model", nil, request, []byte(`{"response":{"candidates":[{"content":{"parts":[{"text":"cut"}]}}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":5,"totalTokenCount":8}}}`), &param)
+	tail = ConvertAntigravityResponseToOpenAI(ctx, "model", nil, request, []byte("[DONE]"), &param)
+	if len(tail) != 1 || gjson.Get(tail[0], "choices.0.finish_reason").String() != "length" {
+		t.Fatalf("expected synthesized length finish, got %v", tail)
+	}
+
+	// Non-Claude models never receive maxOutputTokens upstream, so it cannot signal truncation.
+	param = nil
+	request = []byte(`{"model":"gemini-3-pro-preview","request":{"generationConfig":{"maxOutputTokens":5}}}`)
+	ConvertAntigravityResponseToOpenAI(ctx, "model", nil, request, []byte(`{"response":{"candidates":[{"content":{"parts":[{"text":"long"}]}}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":9,"totalTokenCount":12}}}`), &param)
+	tail = ConvertAntigravityResponseToOpenAI(ctx, "model", nil, request, []byte("[DONE]"), &param)
+	if len(tail) != 1 || gjson.Get(tail[0], "choices.0.finish_reason").String() != "stop" {
+		t.Fatalf("expected synthesized stop finish for a non-Claude model, got %v", tail)
+	}
+
+	// A finish already emitted upstream is not repeated.
+	param = nil
+	ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, []byte(`{"response":{"candidates":[{"content":{"parts":[{"text":"ok"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":1,"candidatesTokenCount":1,"totalTokenCount":2}}}`), &param)
+	if tail = ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, []byte("[DONE]"), &param); len(tail) != 0 {
+		t.Fatalf("expected no synthesized chunk after upstream finish, got %v", tail)
+	}
+}
+
+func TestStreamedFunctionCallArgumentsAreForwardedIncrementally(t *testing.T) {
+	ctx := context.Background()
+	var param any
+
+	stream := [][]byte{
+		[]byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"write_file","id":"fc1","willContinue":true}}]}}],"responseId":"r1"}}`),
+		[]byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"partialArgs":[{"jsonPath":"$.path","stringValue":"a.txt"},{"jsonPath":"$.content","stringValue":"hel","willContinue":true}],"willContinue":true}}]}}],"responseId":"r1"}}`),
+		[]byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"partialArgs":[{"jsonPath":"$.content","stringValue":"lo \"x\""}],"willContinue":true}}]}}],"responseId":"r1"}}`),
+		[]byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"partialArgs":[{"jsonPath":"$.opts['mode.v']","numberValue":2}]}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":1,"candidatesTokenCount":2,"totalTokenCount":3},"responseId":"r1"}}`),
+	}
+
+	var fragments []string
+	arguments := ""
+	for _, raw := range stream {
+		for _, chunk := range ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, raw, &param) {
+			for _, tc := range gjson.Get(chunk, "choices.0.delta.tool_calls").Array() {
+				if tc.Get("index").Int() != 0 {
+					t.Fatalf("unexpected tool call index in %s", chunk)
+				}
+				if name := tc.Get("function.name").String(); name != "" && name != "write_file" {
+					t.Fatalf("unexpected tool name %q", name)
+				}
+				fragment := tc.Get("function.arguments").String()
+				fragments = append(fragments, fragment)
+				arguments += fragment
+			}
+		}
+	}
+
+	if len(fragments) < 4 || fragments[0] != "" {
+		t.Fatalf("expected a header followed by several argument fragments, got %q", fragments)
+	}
+	if !gjson.Valid(arguments) {
+		t.Fatalf("concatenated arguments are not valid JSON: %s", arguments)
+	}
+	parsed := gjson.Parse(arguments)
+	if parsed.Get("path").String() != "a.txt" || parsed.Get("content").String() != `hello "x"` || parsed.Get(`opts.mode\.v`).Int() != 2 {
+		t.Fatalf("unexpected arguments: %s", arguments)
+	}
+}
+
+func TestImageOutputIsReturnedAsImages(t *testing.T) {
+	ctx := context.Background()
+	var param any
+
+	stream := [][]byte{
+		[]byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"inlineData":{"mimeType":"image/png","data":"ZHJhZnQ="},"thought":true},{"text":"Here you go"}]}}],"responseId":"r1"}}`),
+		[]byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"inlineData":{"mimeType":"image/png","data":"b25l"}}]}}],"responseId":"r1"}}`),
+		[]byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"inlineData":{"mimeType":"image/jpeg","data":"dHdv"}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":1,"candidatesTokenCount":2,"totalTokenCount":3},"responseId":"r1"}}`),
+	}
+	var images []gjson.Result
+	for _, raw := range stream {
+		for _, chunk := range ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, raw, &param) {
+			images = append(images, gjson.Get(chunk, "choices.0.delta.images").Array()...)
+		}
+	}
+	if len(images) != 2 {
+		t.Fatalf("expected 2 final images, got %d: %v", len(images), images)
+	}
+	if images[0].Get("index").Int() != 0 || images[0].Get("image_url.url").String() != "data:image/png;base64,b25l" {
+		t.Errorf("unexpected first image: %s", images[0].Raw)
+	}
+	if images[1].Get("index").Int() != 1 || images[1].Get("image_url.url").String() != "data:image/jpeg;base64,dHdv" {
+		t.Errorf("unexpected second image: %s", images[1].Raw)
+	}
+
+	var nonStreamParam any
+	out := ConvertAntigravityResponseToOpenAINonStream(ctx, "model", nil, nil, []byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"inlineData":{"mimeType":"image/png","data":"ZHJhZnQ="},"thought":true},{"text":"Here you go"},{"inlineData":{"mimeType":"image/png","data":"b25l"}}]},"finishReason":"STOP"}],"responseId":"r1"}}`), &nonStreamParam)
+	messageImages := gjson.Get(out, "choices.0.message.images").Array()
+	if len(messageImages) != 1 || messageImages[0].Get("image_url.url").String() != "data:image/png;base64,b25l" {
+		t.Fatalf("unexpected non-stream images: %s", out)
+	}
+	if got := gjson.Get(out, "choices.0.message.content").String(); got != "Here you go" {
+		t.Errorf("unexpected non-stream content %q", got)
+	}
+}
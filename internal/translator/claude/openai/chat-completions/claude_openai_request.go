@@ -65,6 +65,17 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 
 	root := gjson.ParseBytes(rawJSON)
 
+	// Derive the user component from a client-supplied end-user identifier so requests from the
+	// same end user share an id. Only its hash is sent upstream; the raw value may be an email.
+	endUser := root.Get("metadata.user_id").String()
+	if endUser == "" {
+		endUser = root.Get("user").String()
+	}
+	if endUser != "" {
+		sum := sha256.Sum256([]byte(endUser))
+		out, _ = sjson.Set(out, "metadata.user_id", fmt.Sprintf("user_%s_account_%s_session_%s", hex.EncodeToString(sum[:]), account, session))
+	}
+
 	// Convert OpenAI reasoning_effort to Claude thinking config.
 	if v := root.Get("reasoning_effort"); v.Exists() {
 		effort := strings.ToLower(strings.TrimSpace(v.String()))
@@ -143,26 +154,33 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 			contentResult := message.Get("content")
 
 			switch role {
-			case "system":
+			case "system", "developer":
 				if systemMessageIndex == -1 {
 					systemMsg := `{"role":"user","content":[]}`
 					out, _ = sjson.SetRaw(out, "messages.-1", systemMsg)
 					systemMessageIndex = messageIndex
 					messageIndex++
 				}
-				if contentResult.Exists() && contentResult.Type == gjson.String && contentResult.String() != "" {
+				// Multiple system and developer messages are merged, in order, into one message. Array
+				// content may mix plain strings with text parts that carry cache_control.
+				segments := []gjson.Result{contentResult}
+				if contentResult.IsArray() {
+					segments = contentResult.Array()
+				}
+				for _, part := range segments {
+					text := part.String()
+					if part.IsObject() {
+						if part.Get("type").String() != "text" {
+							continue
+						}
+						text = part.Get("text").String()
+					}
+					if text == "" {
+						continue
+					}
 					textPart := `{"type":"text","text":""}`
-					textPart, _ = sjson.Set(textPart, "text", contentResult.String())
+					textPart, _ = sjson.Set(textPart, "text", text)
 					out, _ = sjson.SetRaw(out, fmt.Sprintf("messages.%d.content.-1", systemMessageIndex), textPart)
-				} else if contentResult.Exists() && contentResult.IsArray() {
-					contentResult.ForEach(func(_, part gjson.Result) bool {
-						if part.Get("type").String() == "text" {
-							textPart := `{"type":"text","text":""}`
-							textPart, _ = sjson.Set(textPart, "text", part.Get("text").String())
-							out, _ = sjson.SetRaw(out, fmt.Sprintf("messages.%d.content.-1", systemMessageIndex), textPart)
-						}
-						return true
-					})
 				}
 			case "user", "assistant":
 				msg := `{"role":"","content":[]}`
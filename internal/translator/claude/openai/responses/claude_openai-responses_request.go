@@ -53,6 +53,17 @@ func ConvertOpenAIResponsesRequestToClaude(modelName string, inputRawJSON []byte
 
 	root := gjson.ParseBytes(rawJSON)
 
+	// Derive the user component from a client-supplied end-user identifier so requests from the
+	// same end user share an id. Only its hash is sent upstream; the raw value may be an email.
+	endUser := root.Get("metadata.user_id").String()
+	if endUser == "" {
+		endUser = root.Get("user").String()
+	}
+	if endUser != "" {
+		sum := sha256.Sum256([]byte(endUser))
+		out, _ = sjson.Set(out, "metadata.user_id", fmt.Sprintf("user_%s_account_%s_session_%s", hex.EncodeToString(sum[:]), account, session))
+	}
+
 	// Convert OpenAI Responses reasoning.effort to Claude thinking config.
 	if v := root.Get("reasoning.effort"); v.Exists() {
 		effort := strings.ToLower(strings.TrimSpace(v.String()))
@@ -111,7 +122,7 @@ func ConvertOpenAIResponsesRequestToClaude(modelName string, inputRawJSON []byte
 	if instructionsText == "" {
 		if input := root.Get("input"); input.Exists() && input.IsArray() {
 			input.ForEach(func(_, item gjson.Result) bool {
-				if strings.EqualFold(item.Get("role").String(), "system") {
+				if isResponsesInstructionRole(item.Get("role").String()) {
 					var builder strings.Builder
 					if parts := item.Get("content"); parts.Exists() && parts.IsArray() {
 						parts.ForEach(func(_, part gjson.Result) bool {
@@ -142,7 +153,7 @@ func ConvertOpenAIResponsesRequestToClaude(modelName string, inputRawJSON []byte
 	// input array processing
 	if input := root.Get("input"); input.Exists() && input.IsArray() {
 		input.ForEach(func(_, item gjson.Result) bool {
-			if extractedFromSystem && strings.EqualFold(item.Get("role").String(), "system") {
+			if extractedFromSystem && isResponsesInstructionRole(item.Get("role").String()) {
 				return true
 			}
 			typ := item.Get("type").String()
@@ -338,3 +349,9 @@ func ConvertOpenAIResponsesRequestToClaude(modelName string, inputRawJSON []byte
 
 	return []byte(out)
 }
+
+// isResponsesInstructionRole reports whether an input item carries instructions; newer OpenAI
+// clients send "developer" where older ones used "system".
+func isResponsesInstructionRole(role string) bool {
+	return strings.EqualFold(role, "system") || strings.EqualFold(role, "developer")
+}
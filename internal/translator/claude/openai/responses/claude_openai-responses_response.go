@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -25,7 +26,8 @@ type claudeToResponsesState struct {
 	FuncNames   map[int]string // index -> function name
 	FuncCallIDs map[int]string // index -> call id
 	// message text aggregation
-	TextBuf strings.Builder
+	TextBuf  strings.Builder
+	MsgIndex int
 	// reasoning state
 	ReasoningActive    bool
 	ReasoningItemID    string
@@ -36,6 +38,9 @@ type claudeToResponsesState struct {
 	InputTokens  int64
 	OutputTokens int64
 	UsageSeen    bool
+	// OutputItems holds the finished message and reasoning items by content block index, so
+	// response.output keeps the order in which Claude produced them.
+	OutputItems map[int]string
 }
 
 var dataTag = []byte("data:")
@@ -57,7 +62,7 @@ func emitEvent(event string, payload string) string {
 // ConvertClaudeResponseToOpenAIResponses converts Claude SSE to OpenAI Responses SSE events.
 func ConvertClaudeResponseToOpenAIResponses(ctx context.Context, modelName string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) []string {
 	if *param == nil {
-		*param = &claudeToResponsesState{FuncArgsBuf: make(map[int]*strings.Builder), FuncNames: make(map[int]string), FuncCallIDs: make(map[int]string)}
+		*param = &claudeToResponsesState{FuncArgsBuf: make(map[int]*strings.Builder), FuncNames: make(map[int]string), FuncCallIDs: make(map[int]string), OutputItems: make(map[int]string)}
 	}
 	st := (*param).(*claudeToResponsesState)
 
@@ -91,6 +96,7 @@ func ConvertClaudeResponseToOpenAIResponses(ctx context.Context, modelName strin
 			st.FuncArgsBuf = make(map[int]*strings.Builder)
 			st.FuncNames = make(map[int]string)
 			st.FuncCallIDs = make(map[int]string)
+			st.OutputItems = make(map[int]string)
 			st.InputTokens = 0
 			st.OutputTokens = 0
 			st.UsageSeen = false
@@ -127,15 +133,19 @@ func ConvertClaudeResponseToOpenAIResponses(ctx context.Context, modelName strin
 		if typ == "text" {
 			// open message item + content part
 			st.InTextBlock = true
-			st.CurrentMsgID = fmt.Sprintf("msg_%s_0", st.ResponseID)
+			st.MsgIndex = idx
+			st.TextBuf.Reset()
+			st.CurrentMsgID = fmt.Sprintf("msg_%s_%d", st.ResponseID, idx)
 			item := `{"type":"response.output_item.added","sequence_number":0,"output_index":0,"item":{"id":"","type":"message","status":"in_progress","content":[],"role":"assistant"}}`
 			item, _ = sjson.Set(item, "sequence_number", nextSeq())
+			item, _ = sjson.Set(item, "output_index", idx)
 			item, _ = sjson.Set(item, "item.id", st.CurrentMsgID)
 			out = append(out, emitEvent("response.output_item.added", item))
 
 			part := `{"type":"response.content_part.added","sequence_number":0,"item_id":"","output_index":0,"content_index":0,"part":{"type":"output_text","annotations":[],"logprobs":[],"text":""}}`
 			part, _ = sjson.Set(part, "sequence_number", nextSeq())
 			part, _ = sjson.Set(part, "item_id", st.CurrentMsgID)
+			part, _ = sjson.Set(part, "output_index", idx)
 			out = append(out, emitEvent("response.content_part.added", part))
 		} else if typ == "tool_use" {
 			st.InFuncBlock = true
@@ -184,6 +194,7 @@ func ConvertClaudeResponseToOpenAIResponses(ctx context.Context, modelName strin
 				msg := `{"type":"response.output_text.delta","sequence_number":0,"item_id":"","output_index":0,"content_index":0,"delta":"","logprobs":[]}`
 				msg, _ = sjson.Set(msg, "sequence_number", nextSeq())
 				msg, _ = sjson.Set(msg, "item_id", st.CurrentMsgID)
+				msg, _ = sjson.Set(msg, "output_index", st.MsgIndex)
 				msg, _ = sjson.Set(msg, "delta", t.String())
 				out = append(out, emitEvent("response.output_text.delta", msg))
 				// aggregate text for response.output
@@ -222,15 +233,19 @@ func ConvertClaudeResponseToOpenAIResponses(ctx context.Context, modelName strin
 			done := `{"type":"response.output_text.done","sequence_number":0,"item_id":"","output_index":0,"content_index":0,"text":"","logprobs":[]}`
 			done, _ = sjson.Set(done, "sequence_number", nextSeq())
 			done, _ = sjson.Set(done, "item_id", st.CurrentMsgID)
+			done, _ = sjson.Set(done, "output_index", st.MsgIndex)
 			out = append(out, emitEvent("response.output_text.done", done))
 			partDone := `{"type":"response.content_part.done","sequence_number":0,"item_id":"","output_index":0,"content_index":0,"part":{"type":"output_text","annotations":[],"logprobs":[],"text":""}}`
 			partDone, _ = sjson.Set(partDone, "sequence_number", nextSeq())
 			partDone, _ = sjson.Set(partDone, "item_id", st.CurrentMsgID)
+			partDone, _ = sjson.Set(partDone, "output_index", st.MsgIndex)
 			out = append(out, emitEvent("response.content_part.done", partDone))
 			final := `{"type":"response.output_item.done","sequence_number":0,"output_index":0,"item":{"id":"","type":"message","status":"completed","content":[{"type":"output_text","text":""}],"role":"assistant"}}`
 			final, _ = sjson.Set(final, "sequence_number", nextSeq())
+			final, _ = sjson.Set(final, "output_index", st.MsgIndex)
 			final, _ = sjson.Set(final, "item.id", st.CurrentMsgID)
 			out = append(out, emitEvent("response.output_item.done", final))
+			st.OutputItems[st.MsgIndex] = responsesMessageItem(st.CurrentMsgID, st.TextBuf.String())
 			st.InTextBlock = false
 		} else if st.InFuncBlock {
 			args := "{}"
@@ -268,6 +283,7 @@ func ConvertClaudeResponseToOpenAIResponses(ctx context.Context, modelName strin
 			partDone, _ = sjson.Set(partDone, "output_index", st.ReasoningIndex)
 			partDone, _ = sjson.Set(partDone, "part.text", full)
 			out = append(out, emitEvent("response.reasoning_summary_part.done", partDone))
+			st.OutputItems[st.ReasoningIndex] = responsesReasoningItem(st.ReasoningItemID, full)
 			st.ReasoningActive = false
 			st.ReasoningPartAdded = false
 		}
@@ -357,52 +373,45 @@ func ConvertClaudeResponseToOpenAIResponses(ctx context.Context, modelName strin
 
 		// Build response.output from aggregated state
 		outputsWrapper := `{"arr":[]}`
-		// reasoning item (if any)
-		if st.ReasoningBuf.Len() > 0 || st.ReasoningPartAdded {
-			item := `{"id":"","type":"reasoning","summary":[{"type":"summary_text","text":""}]}`
-			item, _ = sjson.Set(item, "id", st.ReasoningItemID)
-			item, _ = sjson.Set(item, "summary.0.text", st.ReasoningBuf.String())
-			outputsWrapper, _ = sjson.SetRaw(outputsWrapper, "arr.-1", item)
+		// Blocks Claude did not close still belong in the output.
+		if st.ReasoningActive {
+			st.OutputItems[st.ReasoningIndex] = responsesReasoningItem(st.ReasoningItemID, st.ReasoningBuf.String())
 		}
-		// assistant message item (if any text)
-		if st.TextBuf.Len() > 0 || st.InTextBlock || st.CurrentMsgID != "" {
-			item := `{"id":"","type":"message","status":"completed","content":[{"type":"output_text","annotations":[],"logprobs":[],"text":""}],"role":"assistant"}`
-			item, _ = sjson.Set(item, "id", st.CurrentMsgID)
-			item, _ = sjson.Set(item, "content.0.text", st.TextBuf.String())
-			outputsWrapper, _ = sjson.SetRaw(outputsWrapper, "arr.-1", item)
+		if st.InTextBlock {
+			st.OutputItems[st.MsgIndex] = responsesMessageItem(st.CurrentMsgID, st.TextBuf.String())
+		}
+		// Items in content block order, so interleaved text, reasoning and tool calls keep the
+		// order the model produced them in.
+		idxs := make([]int, 0, len(st.OutputItems)+len(st.FuncArgsBuf))
+		for idx := range st.OutputItems {
+			idxs = append(idxs, idx)
 		}
-		// function_call items (in ascending index order for determinism)
-		if len(st.FuncArgsBuf) > 0 {
-			// collect indices
-			idxs := make([]int, 0, len(st.FuncArgsBuf))
-			for idx := range st.FuncArgsBuf {
+		for idx := range st.FuncArgsBuf {
+			if _, ok := st.OutputItems[idx]; !ok {
 				idxs = append(idxs, idx)
 			}
-			// simple sort (small N), avoid adding new imports
-			for i := 0; i < len(idxs); i++ {
-				for j := i + 1; j < len(idxs); j++ {
-					if idxs[j] < idxs[i] {
-						idxs[i], idxs[j] = idxs[j], idxs[i]
-					}
-				}
-			}
-			for _, idx := range idxs {
-				args := ""
-				if b := st.FuncArgsBuf[idx]; b != nil {
-					args = b.String()
-				}
-				callID := st.FuncCallIDs[idx]
-				name := st.FuncNames[idx]
-				if callID == "" && st.CurrentFCID != "" {
-					callID = st.CurrentFCID
-				}
-				item := `{"id":"","type":"function_call","status":"completed","arguments":"","call_id":"","name":""}`
-				item, _ = sjson.Set(item, "id", fmt.Sprintf("fc_%s", callID))
-				item, _ = sjson.Set(item, "arguments", args)
-				item, _ = sjson.Set(item, "call_id", callID)
-				item, _ = sjson.Set(item, "name", name)
+		}
+		sort.Ints(idxs)
+		for _, idx := range idxs {
+			if item, ok := st.OutputItems[idx]; ok {
 				outputsWrapper, _ = sjson.SetRaw(outputsWrapper, "arr.-1", item)
+				continue
+			}
+			args := ""
+			if b := st.FuncArgsBuf[idx]; b != nil {
+				args = b.String()
 			}
+			callID := st.FuncCallIDs[idx]
+			name := st.FuncNames[idx]
+			if callID == "" && st.CurrentFCID != "" {
+				callID = st.CurrentFCID
+			}
+			item := `{"id":"","type":"function_call","status":"completed","arguments":"","call_id":"","name":""}`
+			item, _ = sjson.Set(item, "id", fmt.Sprintf("fc_%s", callID))
+			item, _ = sjson.Set(item, "arguments", args)
+			item, _ = sjson.Set(item, "call_id", callID)
+			item, _ = sjson.Set(item, "name", name)
+			outputsWrapper, _ = sjson.SetRaw(outputsWrapper, "arr.-1", item)
 		}
 		if gjson.Get(outputsWrapper, "arr.#").Int() > 0 {
 			completed, _ = sjson.SetRaw(completed, "response.output", gjson.Get(outputsWrapper, "arr").Raw)
@@ -459,18 +468,21 @@ func ConvertClaudeResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 
 	// Aggregation state
 	var (
-		responseID      string
-		createdAt       int64
-		currentMsgID    string
-		currentFCID     string
-		textBuf         strings.Builder
-		reasoningBuf    strings.Builder
-		reasoningActive bool
-		reasoningItemID string
-		inputTokens     int64
-		outputTokens    int64
+		responseID   string
+		createdAt    int64
+		currentFCID  string
+		reasoningBuf strings.Builder
+		inputTokens  int64
+		outputTokens int64
 	)
 
+	// Per-index text and thinking aggregation
+	type textState struct {
+		thinking bool
+		text     strings.Builder
+	}
+	textBlocks := make(map[int]*textState)
+
 	// Per-index tool call aggregation
 	type toolState struct {
 		id   string
@@ -503,7 +515,7 @@ func ConvertClaudeResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 			typ := cb.Get("type").String()
 			switch typ {
 			case "text":
-				currentMsgID = "msg_" + responseID + "_0"
+				textBlocks[idx] = &textState{}
 			case "tool_use":
 				currentFCID = cb.Get("id").String()
 				name := cb.Get("name").String()
@@ -514,8 +526,7 @@ func ConvertClaudeResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 					toolCalls[idx].name = name
 				}
 			case "thinking":
-				reasoningActive = true
-				reasoningItemID = fmt.Sprintf("rs_%s_%d", responseID, idx)
+				textBlocks[idx] = &textState{thinking: true}
 			}
 
 		case "content_block_delta":
@@ -527,7 +538,9 @@ func ConvertClaudeResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 			switch dt {
 			case "text_delta":
 				if t := d.Get("text"); t.Exists() {
-					textBuf.WriteString(t.String())
+					if block := textBlocks[int(root.Get("index").Int())]; block != nil && !block.thinking {
+						block.text.WriteString(t.String())
+					}
 				}
 			case "input_json_delta":
 				if pj := d.Get("partial_json"); pj.Exists() {
@@ -538,8 +551,9 @@ func ConvertClaudeResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 					toolCalls[idx].args.WriteString(pj.String())
 				}
 			case "thinking_delta":
-				if reasoningActive {
+				if block := textBlocks[int(root.Get("index").Int())]; block != nil && block.thinking {
 					if t := d.Get("thinking"); t.Exists() {
+						block.text.WriteString(t.String())
 						reasoningBuf.WriteString(t.String())
 					}
 				}
@@ -627,45 +641,40 @@ func ConvertClaudeResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 	}
 
 	// Build output array
+	// Items follow the content block order of the Claude response.
 	outputsWrapper := `{"arr":[]}`
-	if reasoningBuf.Len() > 0 {
-		item := `{"id":"","type":"reasoning","summary":[{"type":"summary_text","text":""}]}`
-		item, _ = sjson.Set(item, "id", reasoningItemID)
-		item, _ = sjson.Set(item, "summary.0.text", reasoningBuf.String())
-		outputsWrapper, _ = sjson.SetRaw(outputsWrapper, "arr.-1", item)
+	idxs := make([]int, 0, len(textBlocks)+len(toolCalls))
+	for i := range textBlocks {
+		idxs = append(idxs, i)
 	}
-	if currentMsgID != "" || textBuf.Len() > 0 {
-		item := `{"id":"","type":"message","status":"completed","content":[{"type":"output_text","annotations":[],"logprobs":[],"text":""}],"role":"assistant"}`
-		item, _ = sjson.Set(item, "id", currentMsgID)
-		item, _ = sjson.Set(item, "content.0.text", textBuf.String())
-		outputsWrapper, _ = sjson.SetRaw(outputsWrapper, "arr.-1", item)
-	}
-	if len(toolCalls) > 0 {
-		// Preserve index order
-		idxs := make([]int, 0, len(toolCalls))
-		for i := range toolCalls {
+	for i := range toolCalls {
+		if _, ok := textBlocks[i]; !ok {
 			idxs = append(idxs, i)
 		}
-		for i := 0; i < len(idxs); i++ {
-			for j := i + 1; j < len(idxs); j++ {
-				if idxs[j] < idxs[i] {
-					idxs[i], idxs[j] = idxs[j], idxs[i]
+	}
+	sort.Ints(idxs)
+	for _, i := range idxs {
+		if block := textBlocks[i]; block != nil {
+			if block.thinking {
+				if block.text.Len() > 0 {
+					outputsWrapper, _ = sjson.SetRaw(outputsWrapper, "arr.-1", responsesReasoningItem(fmt.Sprintf("rs_%s_%d", responseID, i), block.text.String()))
 				}
+				continue
 			}
+			outputsWrapper, _ = sjson.SetRaw(outputsWrapper, "arr.-1", responsesMessageItem(fmt.Sprintf("msg_%s_%d", responseID, i), block.text.String()))
+			continue
 		}
-		for _, i := range idxs {
-			st := toolCalls[i]
-			args := st.args.String()
-			if args == "" {
-				args = "{}"
-			}
-			item := `{"id":"","type":"function_call","status":"completed","arguments":"","call_id":"","name":""}`
-			item, _ = sjson.Set(item, "id", fmt.Sprintf("fc_%s", st.id))
-			item, _ = sjson.Set(item, "arguments", args)
-			item, _ = sjson.Set(item, "call_id", st.id)
-			item, _ = sjson.Set(item, "name", st.name)
-			outputsWrapper, _ = sjson.SetRaw(outputsWrapper, "arr.-1", item)
+		st := toolCalls[i]
+		args := st.args.String()
+		if args == "" {
+			args = "{}"
 		}
+		item := `{"id":"","type":"function_call","status":"completed","arguments":"","call_id":"","name":""}`
+		item, _ = sjson.Set(item, "id", fmt.Sprintf("fc_%s", st.id))
+		item, _ = sjson.Set(item, "arguments", args)
+		item, _ = sjson.Set(item, "call_id", st.id)
+		item, _ = sjson.Set(item, "name", st.name)
+		outputsWrapper, _ = sjson.SetRaw(outputsWrapper, "arr.-1", item)
 	}
 	if gjson.Get(outputsWrapper, "arr.#").Int() > 0 {
 		out, _ = sjson.SetRaw(out, "output", gjson.Get(outputsWrapper, "arr").Raw)
@@ -686,3 +695,17 @@ func ConvertClaudeResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 
 	return out
 }
+
+func responsesMessageItem(id, text string) string {
+	item := `{"id":"","type":"message","status":"completed","content":[{"type":"output_text","annotations":[],"logprobs":[],"text":""}],"role":"assistant"}`
+	item, _ = sjson.Set(item, "id", id)
+	item, _ = sjson.Set(item, "content.0.text", text)
+	return item
+}
+
+func responsesReasoningItem(id, text string) string {
+	item := `{"id":"","type":"reasoning","summary":[{"type":"summary_text","text":""}]}`
+	item, _ = sjson.Set(item, "id", id)
+	item, _ = sjson.Set(item, "summary.0.text", text)
+	return item
+}
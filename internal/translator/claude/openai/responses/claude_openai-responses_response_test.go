@@ -0,0 +1,61 @@
+package responses
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+var interleavedClaudeStream = []string{
+	`data: {"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":5}}}`,
+	`data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+	`data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"plan"}}`,
+	`data: {"type":"content_block_stop","index":0}`,
+	`data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+	`data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Looking it up."}}`,
+	`data: {"type":"content_block_stop","index":1}`,
+	`data: {"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_1","name":"lookup","input":{}}}`,
+	`data: {"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{}"}}`,
+	`data: {"type":"content_block_stop","index":2}`,
+	`data: {"type":"content_block_start","index":3,"content_block":{"type":"text","text":""}}`,
+	`data: {"type":"content_block_delta","index":3,"delta":{"type":"text_delta","text":"Done."}}`,
+	`data: {"type":"content_block_stop","index":3}`,
+	`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}`,
+	`data: {"type":"message_stop"}`,
+}
+
+func assertInterleavedOutput(t *testing.T, output gjson.Result) {
+	t.Helper()
+	var types []string
+	for _, item := range output.Array() {
+		types = append(types, item.Get("type").String())
+	}
+	want := []string{"reasoning", "message", "function_call", "message"}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Fatalf("output types = %v, want %v", types, want)
+	}
+	if output.Get("1.content.0.text").String() != "Looking it up." || output.Get("3.content.0.text").String() != "Done." {
+		t.Fatalf("message texts not kept apart: %s", output.Raw)
+	}
+}
+
+func TestConvertClaudeResponseToOpenAIResponses_InterleavedOutputOrdering(t *testing.T) {
+	var param any
+	var completed gjson.Result
+	for _, line := range interleavedClaudeStream {
+		for _, chunk := range ConvertClaudeResponseToOpenAIResponses(context.Background(), "claude", nil, nil, []byte(line), &param) {
+			if data, ok := strings.CutPrefix(chunk, "event: response.completed\ndata: "); ok {
+				completed = gjson.Parse(data)
+			}
+		}
+	}
+	assertInterleavedOutput(t, completed.Get("response.output"))
+}
+
+func TestConvertClaudeResponseToOpenAIResponsesNonStream_InterleavedOutputOrdering(t *testing.T) {
+	raw := []byte(strings.Join(interleavedClaudeStream, "\n"))
+	out := ConvertClaudeResponseToOpenAIResponsesNonStream(context.Background(), "claude", nil, nil, raw, nil)
+	assertInterleavedOutput(t, gjson.Get(out, "output"))
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -164,6 +165,13 @@ func ConvertClaudeRequestToCodex(modelName string, inputRawJSON []byte, _ bool)
 			}
 		}
 
+		// A trailing assistant message prefills the response. The Responses API cannot continue
+		// an output message, so the continuation is requested explicitly.
+		if _, ok := util.ClaudePrefill(rawJSON); ok {
+			instruction := `{"type":"message","role":"user","content":[{"type":"input_text","text":""}]}`
+			instruction, _ = sjson.Set(instruction, "content.0.text", util.PrefillContinueInstruction)
+			template, _ = sjson.SetRaw(template, "input.-1", instruction)
+		}
 	}
 
 	// Convert tools declarations to the expected format for the Codex API.
@@ -0,0 +1,33 @@
+package claude
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertClaudeRequestToCodex_PrefillWithTools(t *testing.T) {
+	input := []byte(`{
+		"tools": [{"name": "get_weather", "description": "Weather", "input_schema": {"type": "object"}}],
+		"messages": [
+			{"role": "user", "content": "What is the weather in Paris? Answer in JSON."},
+			{"role": "assistant", "content": [{"type": "text", "text": "{\"city\": \"Paris\","}]}
+		]
+	}`)
+
+	out := ConvertClaudeRequestToCodex("gpt-5", input, false)
+	items := gjson.GetBytes(out, "input").Array()
+	if len(items) != 3 {
+		t.Fatalf("input items = %d, want 3: %s", len(items), out)
+	}
+	if items[1].Get("role").String() != "assistant" || items[1].Get("content.0.type").String() != "output_text" {
+		t.Errorf("prefill item = %s", items[1].Raw)
+	}
+	if items[2].Get("role").String() != "user" || items[2].Get("content.0.text").String() != util.PrefillContinueInstruction {
+		t.Errorf("continuation item = %s", items[2].Raw)
+	}
+	if gjson.GetBytes(out, "tools.0.name").String() != "get_weather" {
+		t.Errorf("tools not preserved: %s", out)
+	}
+}
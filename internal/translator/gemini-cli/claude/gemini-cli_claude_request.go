@@ -7,8 +7,8 @@ package claude
 
 import (
 	"bytes"
-	"strings"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/common"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -67,6 +67,7 @@ func ConvertClaudeRequestToCLI(modelName string, inputRawJSON []byte, _ bool) []
 
 	// contents
 	if messagesResult := gjson.GetBytes(rawJSON, "messages"); messagesResult.IsArray() {
+		toolNames := common.ClaudeToolUseNames(messagesResult)
 		messagesResult.ForEach(func(_, messageResult gjson.Result) bool {
 			roleResult := messageResult.Get("role")
 			if roleResult.Type != gjson.String {
@@ -97,6 +98,9 @@ func ConvertClaudeRequestToCLI(modelName string, inputRawJSON []byte, _ bool) []
 							part := `{"thoughtSignature":"","functionCall":{"name":"","args":{}}}`
 							part, _ = sjson.Set(part, "thoughtSignature", geminiCLIClaudeThoughtSignature)
 							part, _ = sjson.Set(part, "functionCall.name", functionName)
+							if upstreamID := cache.UpstreamToolCallID(contentResult.Get("id").String()); upstreamID != "" {
+								part, _ = sjson.Set(part, "functionCall.id", upstreamID)
+							}
 							part, _ = sjson.SetRaw(part, "functionCall.args", functionArgs)
 							contentJSON, _ = sjson.SetRaw(contentJSON, "parts.-1", part)
 						}
@@ -106,16 +110,24 @@ func ConvertClaudeRequestToCLI(modelName string, inputRawJSON []byte, _ bool) []
 						if toolCallID == "" {
 							return true
 						}
-						funcName := toolCallID
-						toolCallIDs := strings.Split(toolCallID, "-")
-						if len(toolCallIDs) > 1 {
-							funcName = strings.Join(toolCallIDs[0:len(toolCallIDs)-1], "-")
-						}
-						responseData := contentResult.Get("content").Raw
+						funcName := cache.ToolCallName(toolCallID, toolNames)
+						resultContent, imageParts := common.SplitClaudeToolResultImages(contentResult.Get("content"))
+						responseData := resultContent.Raw
 						part := `{"functionResponse":{"name":"","response":{"result":""}}}`
 						part, _ = sjson.Set(part, "functionResponse.name", funcName)
+						if upstreamID := cache.UpstreamToolCallID(toolCallID); upstreamID != "" {
+							part, _ = sjson.Set(part, "functionResponse.id", upstreamID)
+						}
 						part, _ = sjson.Set(part, "functionResponse.response.result", responseData)
 						contentJSON, _ = sjson.SetRaw(contentJSON, "parts.-1", part)
+						for _, imagePart := range imageParts {
+							contentJSON, _ = sjson.SetRaw(contentJSON, "parts.-1", imagePart)
+						}
+
+					case "audio":
+						if part, ok := common.ClaudeAudioPart(contentResult); ok {
+							contentJSON, _ = sjson.SetRaw(contentJSON, "parts.-1", part)
+						}
 					}
 					return true
 				})
@@ -179,7 +191,7 @@ func ConvertClaudeRequestToCLI(modelName string, inputRawJSON []byte, _ bool) []
 	}
 
 	outBytes := []byte(out)
-	outBytes = common.AttachDefaultSafetySettings(outBytes, "request.safetySettings")
+	outBytes = common.AttachSafetySettings(outBytes, inputRawJSON, "request.safetySettings")
 
 	return outBytes
 }
@@ -11,9 +11,8 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"sync/atomic"
-	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -22,15 +21,13 @@ import (
 // This structure tracks the current state of the response translation process to ensure
 // proper sequencing of SSE events and transitions between different content types.
 type Params struct {
-	HasFirstResponse bool // Indicates if the initial message_start event has been sent
-	ResponseType     int  // Current response type: 0=none, 1=content, 2=thinking, 3=function
-	ResponseIndex    int  // Index counter for content blocks in the streaming response
-	HasContent       bool // Tracks whether any content (text, thinking, or tool use) has been output
+	HasFirstResponse  bool   // Indicates if the initial message_start event has been sent
+	ResponseType      int    // Current response type: 0=none, 1=content, 2=thinking, 3=function
+	ResponseIndex     int    // Index counter for content blocks in the streaming response
+	HasContent        bool   // Tracks whether any content (text, thinking, or tool use) has been output
+	ThinkingSignature string // Signature of the open thinking block, sent when the block closes
 }
 
-// toolUseIDCounter provides a process-wide unique counter for tool use identifiers.
-var toolUseIDCounter uint64
-
 // ConvertGeminiCLIResponseToClaude performs sophisticated streaming response format conversion.
 // This function implements a complex state machine that translates backend client responses
 // into Claude Code-compatible Server-Sent Events (SSE) format. It manages different response types
@@ -103,6 +100,14 @@ func ConvertGeminiCLIResponseToClaude(_ context.Context, _ string, originalReque
 			partTextResult := partResult.Get("text")
 			functionCallResult := partResult.Get("functionCall")
 
+			// Gemini signs its reasoning on a thought part or, for some models, on the first part
+			// that follows the thoughts; either way it belongs to the open thinking block.
+			if signature := partThoughtSignature(partResult); signature != "" {
+				if partResult.Get("thought").Bool() || (*param).(*Params).ResponseType == 2 {
+					(*param).(*Params).ThinkingSignature = signature
+				}
+			}
+
 			// Handle text content (both regular content and thinking)
 			if partTextResult.Exists() {
 				// Process thinking content (internal reasoning)
@@ -117,11 +122,7 @@ func ConvertGeminiCLIResponseToClaude(_ context.Context, _ string, originalReque
 						// Transition from another state to thinking
 						// First, close any existing content block
 						if (*param).(*Params).ResponseType != 0 {
-							if (*param).(*Params).ResponseType == 2 {
-								// output = output + "event: content_block_delta\n"
-								// output = output + fmt.Sprintf(`data: {"type":"content_block_delta","index":%d,"delta":{"type":"signature_delta","signature":null}}`, (*param).(*Params).ResponseIndex)
-								// output = output + "\n\n\n"
-							}
+							output = output + thinkingSignatureEvent((*param).(*Params))
 							output = output + "event: content_block_stop\n"
 							output = output + fmt.Sprintf(`data: {"type":"content_block_stop","index":%d}`, (*param).(*Params).ResponseIndex)
 							output = output + "\n\n\n"
@@ -150,11 +151,7 @@ func ConvertGeminiCLIResponseToClaude(_ context.Context, _ string, originalReque
 						// Transition from another state to text content
 						// First, close any existing content block
 						if (*param).(*Params).ResponseType != 0 {
-							if (*param).(*Params).ResponseType == 2 {
-								// output = output + "event: content_block_delta\n"
-								// output = output + fmt.Sprintf(`data: {"type":"content_block_delta","index":%d,"delta":{"type":"signature_delta","signature":null}}`, (*param).(*Params).ResponseIndex)
-								// output = output + "\n\n\n"
-							}
+							output = output + thinkingSignatureEvent((*param).(*Params))
 							output = output + "event: content_block_stop\n"
 							output = output + fmt.Sprintf(`data: {"type":"content_block_stop","index":%d}`, (*param).(*Params).ResponseIndex)
 							output = output + "\n\n\n"
@@ -188,12 +185,8 @@ func ConvertGeminiCLIResponseToClaude(_ context.Context, _ string, originalReque
 					(*param).(*Params).ResponseType = 0
 				}
 
-				// Special handling for thinking state transition
-				if (*param).(*Params).ResponseType == 2 {
-					// output = output + "event: content_block_delta\n"
-					// output = output + fmt.Sprintf(`data: {"type":"content_block_delta","index":%d,"delta":{"type":"signature_delta","signature":null}}`, (*param).(*Params).ResponseIndex)
-					// output = output + "\n\n\n"
-				}
+				// Attach the signature of a thinking block before closing it
+				output = output + thinkingSignatureEvent((*param).(*Params))
 
 				// Close any other existing content block
 				if (*param).(*Params).ResponseType != 0 {
@@ -209,7 +202,7 @@ func ConvertGeminiCLIResponseToClaude(_ context.Context, _ string, originalReque
 
 				// Create the tool use block with unique ID and function details
 				data := fmt.Sprintf(`{"type":"content_block_start","index":%d,"content_block":{"type":"tool_use","id":"","name":"","input":{}}}`, (*param).(*Params).ResponseIndex)
-				data, _ = sjson.Set(data, "content_block.id", fmt.Sprintf("%s-%d-%d", fcName, time.Now().UnixNano(), atomic.AddUint64(&toolUseIDCounter, 1)))
+				data, _ = sjson.Set(data, "content_block.id", cache.NewToolCallID("toolu_", fcName, functionCallResult.Get("id").String()))
 				data, _ = sjson.Set(data, "content_block.name", fcName)
 				output = output + fmt.Sprintf("data: %s\n\n\n", data)
 
@@ -231,6 +224,7 @@ func ConvertGeminiCLIResponseToClaude(_ context.Context, _ string, originalReque
 			// Only send final events if we have actually output content
 			if (*param).(*Params).HasContent {
 				// Close the final content block
+				output = output + thinkingSignatureEvent((*param).(*Params))
 				output = output + "event: content_block_stop\n"
 				output = output + fmt.Sprintf(`data: {"type":"content_block_stop","index":%d}`, (*param).(*Params).ResponseIndex)
 				output = output + "\n\n\n"
@@ -289,7 +283,7 @@ func ConvertGeminiCLIResponseToClaudeNonStream(_ context.Context, _ string, orig
 	parts := root.Get("response.candidates.0.content.parts")
 	textBuilder := strings.Builder{}
 	thinkingBuilder := strings.Builder{}
-	toolIDCounter := 0
+	thinkingSignature := ""
 	hasToolCall := false
 
 	flushText := func() {
@@ -308,12 +302,19 @@ func ConvertGeminiCLIResponseToClaudeNonStream(_ context.Context, _ string, orig
 		}
 		block := `{"type":"thinking","thinking":""}`
 		block, _ = sjson.Set(block, "thinking", thinkingBuilder.String())
+		if thinkingSignature != "" {
+			block, _ = sjson.Set(block, "signature", thinkingSignature)
+		}
 		out, _ = sjson.SetRaw(out, "content.-1", block)
 		thinkingBuilder.Reset()
+		thinkingSignature = ""
 	}
 
 	if parts.IsArray() {
 		for _, part := range parts.Array() {
+			if signature := partThoughtSignature(part); signature != "" && (part.Get("thought").Bool() || thinkingBuilder.Len() > 0) {
+				thinkingSignature = signature
+			}
 			if text := part.Get("text"); text.Exists() && text.String() != "" {
 				if part.Get("thought").Bool() {
 					flushText()
@@ -331,9 +332,8 @@ func ConvertGeminiCLIResponseToClaudeNonStream(_ context.Context, _ string, orig
 				hasToolCall = true
 
 				name := functionCall.Get("name").String()
-				toolIDCounter++
 				toolBlock := `{"type":"tool_use","id":"","name":"","input":{}}`
-				toolBlock, _ = sjson.Set(toolBlock, "id", fmt.Sprintf("tool_%d", toolIDCounter))
+				toolBlock, _ = sjson.Set(toolBlock, "id", cache.NewToolCallID("toolu_", name, functionCall.Get("id").String()))
 				toolBlock, _ = sjson.Set(toolBlock, "name", name)
 				inputRaw := "{}"
 				if args := functionCall.Get("args"); args.Exists() && gjson.Valid(args.Raw) && args.IsObject() {
@@ -373,6 +373,26 @@ func ConvertGeminiCLIResponseToClaudeNonStream(_ context.Context, _ string, orig
 	return out
 }
 
+// partThoughtSignature returns the thought signature of a Gemini part, or "" when it has none.
+func partThoughtSignature(part gjson.Result) string {
+	signature := part.Get("thoughtSignature")
+	if !signature.Exists() {
+		signature = part.Get("thought_signature")
+	}
+	return signature.String()
+}
+
+// thinkingSignatureEvent returns the signature_delta event closing the open thinking block, or ""
+// when no thinking block is open or Gemini did not sign it.
+func thinkingSignatureEvent(params *Params) string {
+	if params.ResponseType != 2 || params.ThinkingSignature == "" {
+		return ""
+	}
+	data, _ := sjson.Set(fmt.Sprintf(`{"type":"content_block_delta","index":%d,"delta":{"type":"signature_delta","signature":""}}`, params.ResponseIndex), "delta.signature", params.ThinkingSignature)
+	params.ThinkingSignature = ""
+	return fmt.Sprintf("event: content_block_delta\ndata: %s\n\n\n", data)
+}
+
 func ClaudeTokenCount(ctx context.Context, count int64) string {
 	return fmt.Sprintf(`{"input_tokens":%d}`, count)
 }
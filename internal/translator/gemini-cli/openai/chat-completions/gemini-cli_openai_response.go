@@ -10,28 +10,29 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"sync/atomic"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
 	. "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/openai/chat-completions"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
 
 // convertCliResponseToOpenAIChatParams holds parameters for response conversion.
+// FunctionIndex is tracked per candidate so requests with n > 1 number each choice's tool calls
+// independently.
 type convertCliResponseToOpenAIChatParams struct {
 	UnixTimestamp int64
-	FunctionIndex int
+	FunctionIndex map[int]int
 }
 
-// functionCallIDCounter provides a process-wide unique counter for function call identifiers.
-var functionCallIDCounter uint64
-
 // ConvertCliResponseToOpenAI translates a single chunk of a streaming response from the
 // Gemini CLI API format to the OpenAI Chat Completions streaming format.
 // It processes various Gemini CLI event types and transforms them into OpenAI-compatible JSON responses.
 // The function handles text content, tool calls, reasoning content, and usage metadata, outputting
 // responses that match the OpenAI API format. It supports incremental updates for streaming responses.
+// Each candidate becomes its own chunk with the matching choice index, so requests with n > 1
+// receive every candidate.
 //
 // Parameters:
 //   - ctx: The context for the request, used for cancellation and timeout handling
@@ -45,7 +46,7 @@ func ConvertCliResponseToOpenAI(_ context.Context, _ string, originalRequestRawJ
 	if *param == nil {
 		*param = &convertCliResponseToOpenAIChatParams{
 			UnixTimestamp: 0,
-			FunctionIndex: 0,
+			FunctionIndex: make(map[int]int),
 		}
 	}
 
@@ -77,12 +78,6 @@ func ConvertCliResponseToOpenAI(_ context.Context, _ string, originalRequestRawJ
 		template, _ = sjson.Set(template, "id", responseIDResult.String())
 	}
 
-	// Extract and set the finish reason.
-	if finishReasonResult := gjson.GetBytes(rawJSON, "response.candidates.0.finishReason"); finishReasonResult.Exists() {
-		template, _ = sjson.Set(template, "choices.0.finish_reason", strings.ToLower(finishReasonResult.String()))
-		template, _ = sjson.Set(template, "choices.0.native_finish_reason", strings.ToLower(finishReasonResult.String()))
-	}
-
 	// Extract and set usage metadata (token counts).
 	if usageResult := gjson.GetBytes(rawJSON, "response.usageMetadata"); usageResult.Exists() {
 		if candidatesTokenCountResult := usageResult.Get("candidatesTokenCount"); candidatesTokenCountResult.Exists() {
@@ -99,8 +94,40 @@ func ConvertCliResponseToOpenAI(_ context.Context, _ string, originalRequestRawJ
 		}
 	}
 
+	params := (*param).(*convertCliResponseToOpenAIChatParams)
+	candidates := gjson.GetBytes(rawJSON, "response.candidates").Array()
+	if len(candidates) <= 1 {
+		return []string{convertCliCandidateToOpenAI(template, gjson.GetBytes(rawJSON, "response.candidates.0"), 0, params)}
+	}
+	// Usage covers the whole response, so only the first candidate's chunk carries it.
+	withoutUsage, _ := sjson.Delete(template, "usage")
+	chunks := make([]string, 0, len(candidates))
+	for position, candidate := range candidates {
+		choiceIndex := position
+		if indexResult := candidate.Get("index"); indexResult.Exists() {
+			choiceIndex = int(indexResult.Int())
+		}
+		base := withoutUsage
+		if position == 0 {
+			base = template
+		}
+		chunks = append(chunks, convertCliCandidateToOpenAI(base, candidate, choiceIndex, params))
+	}
+	return chunks
+}
+
+// convertCliCandidateToOpenAI fills the choice of template with one Gemini candidate.
+func convertCliCandidateToOpenAI(template string, candidate gjson.Result, choiceIndex int, params *convertCliResponseToOpenAIChatParams) string {
+	template, _ = sjson.Set(template, "choices.0.index", choiceIndex)
+
+	// Extract and set the finish reason.
+	if finishReasonResult := candidate.Get("finishReason"); finishReasonResult.Exists() {
+		template, _ = sjson.Set(template, "choices.0.finish_reason", strings.ToLower(finishReasonResult.String()))
+		template, _ = sjson.Set(template, "choices.0.native_finish_reason", strings.ToLower(finishReasonResult.String()))
+	}
+
 	// Process the main content part of the response.
-	partsResult := gjson.GetBytes(rawJSON, "response.candidates.0.content.parts")
+	partsResult := candidate.Get("content.parts")
 	hasFunctionCall := false
 	if partsResult.IsArray() {
 		partResults := partsResult.Array()
@@ -139,8 +166,8 @@ func ConvertCliResponseToOpenAI(_ context.Context, _ string, originalRequestRawJ
 				// Handle function call content.
 				hasFunctionCall = true
 				toolCallsResult := gjson.Get(template, "choices.0.delta.tool_calls")
-				functionCallIndex := (*param).(*convertCliResponseToOpenAIChatParams).FunctionIndex
-				(*param).(*convertCliResponseToOpenAIChatParams).FunctionIndex++
+				functionCallIndex := params.FunctionIndex[choiceIndex]
+				params.FunctionIndex[choiceIndex]++
 				if toolCallsResult.Exists() && toolCallsResult.IsArray() {
 					functionCallIndex = len(toolCallsResult.Array())
 				} else {
@@ -149,7 +176,7 @@ func ConvertCliResponseToOpenAI(_ context.Context, _ string, originalRequestRawJ
 
 				functionCallTemplate := `{"id": "","index": 0,"type": "function","function": {"name": "","arguments": ""}}`
 				fcName := functionCallResult.Get("name").String()
-				functionCallTemplate, _ = sjson.Set(functionCallTemplate, "id", fmt.Sprintf("%s-%d-%d", fcName, time.Now().UnixNano(), atomic.AddUint64(&functionCallIDCounter, 1)))
+				functionCallTemplate, _ = sjson.Set(functionCallTemplate, "id", cache.NewToolCallID("call_", fcName, functionCallResult.Get("id").String()))
 				functionCallTemplate, _ = sjson.Set(functionCallTemplate, "index", functionCallIndex)
 				functionCallTemplate, _ = sjson.Set(functionCallTemplate, "function.name", fcName)
 				if fcArgsResult := functionCallResult.Get("args"); fcArgsResult.Exists() {
@@ -189,7 +216,7 @@ func ConvertCliResponseToOpenAI(_ context.Context, _ string, originalRequestRawJ
 		template, _ = sjson.Set(template, "choices.0.native_finish_reason", "tool_calls")
 	}
 
-	return []string{template}
+	return template
 }
 
 // ConvertCliResponseToOpenAINonStream converts a non-streaming Gemini CLI response to a non-streaming OpenAI response.
@@ -0,0 +1,45 @@
+package chat_completions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertCliResponseToOpenAI_SingleCandidate(t *testing.T) {
+	var param any
+	raw := []byte(`{"response":{"responseId":"r1","candidates":[{"content":{"parts":[{"text":"hi"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":1,"totalTokenCount":4}}}`)
+	chunks := ConvertCliResponseToOpenAI(context.Background(), "gemini-2.5-pro", nil, nil, raw, &param)
+	if len(chunks) != 1 {
+		t.Fatalf("chunks = %d, want 1", len(chunks))
+	}
+	chunk := gjson.Parse(chunks[0])
+	if chunk.Get("choices.0.index").Int() != 0 || chunk.Get("choices.0.delta.content").String() != "hi" || chunk.Get("choices.0.finish_reason").String() != "stop" {
+		t.Fatalf("unexpected chunk: %s", chunks[0])
+	}
+	if chunk.Get("usage.total_tokens").Int() != 4 {
+		t.Fatalf("usage missing: %s", chunks[0])
+	}
+}
+
+func TestConvertCliResponseToOpenAI_FansOutCandidates(t *testing.T) {
+	var param any
+	raw := []byte(`{"response":{"candidates":[{"content":{"parts":[{"functionCall":{"name":"a","args":{}}}]}},{"index":1,"content":{"parts":[{"functionCall":{"name":"b","args":{}}}]}}],"usageMetadata":{"totalTokenCount":9}}}`)
+	chunks := ConvertCliResponseToOpenAI(context.Background(), "gemini-2.5-pro", nil, nil, raw, &param)
+	if len(chunks) != 2 {
+		t.Fatalf("chunks = %d, want 2", len(chunks))
+	}
+	for i, chunk := range chunks {
+		parsed := gjson.Parse(chunk)
+		if parsed.Get("choices.0.index").Int() != int64(i) {
+			t.Fatalf("chunk %d has choice index %d", i, parsed.Get("choices.0.index").Int())
+		}
+		if parsed.Get("choices.0.delta.tool_calls.0.index").Int() != 0 || parsed.Get("choices.0.finish_reason").String() != "tool_calls" {
+			t.Fatalf("chunk %d tool call not numbered per choice: %s", i, chunk)
+		}
+	}
+	if !gjson.Get(chunks[0], "usage").Exists() || gjson.Get(chunks[1], "usage").Exists() {
+		t.Fatalf("usage should only be on the first chunk: %v", chunks)
+	}
+}
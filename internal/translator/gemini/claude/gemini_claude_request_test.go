@@ -0,0 +1,80 @@
+package claude
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertClaudeRequestToGemini_PrefillWithTools(t *testing.T) {
+	input := []byte(`{
+		"tools": [{"name": "get_weather", "description": "Weather", "input_schema": {"type": "object"}}],
+		"messages": [
+			{"role": "user", "content": "What is the weather in Paris? Answer in JSON."},
+			{"role": "assistant", "content": [{"type": "text", "text": "{\"city\": \"Paris\","}]}
+		]
+	}`)
+
+	out := ConvertClaudeRequestToGemini("gemini-2.5-pro", input, false)
+	contents := gjson.GetBytes(out, "contents").Array()
+	if len(contents) != 2 {
+		t.Fatalf("contents = %d, want 2: %s", len(contents), out)
+	}
+	// Gemini continues from a trailing model turn natively.
+	if contents[1].Get("role").String() != "model" || contents[1].Get("parts.0.text").String() != `{"city": "Paris",` {
+		t.Errorf("prefill content = %s", contents[1].Raw)
+	}
+	if gjson.GetBytes(out, "tools.0.functionDeclarations.0.name").String() != "get_weather" {
+		t.Errorf("tools not preserved: %s", out)
+	}
+}
+
+func TestConvertClaudeRequestToGemini_ToolResultImages(t *testing.T) {
+	input := []byte(`{
+		"messages": [
+			{"role": "assistant", "content": [{"type": "tool_use", "id": "screenshot-1", "name": "screenshot", "input": {}}]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "screenshot-1", "content": [
+				{"type": "text", "text": "captured"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}}
+			]}]}
+		]
+	}`)
+
+	out := ConvertClaudeRequestToGemini("gemini-2.5-pro", input, false)
+	parts := gjson.GetBytes(out, "contents.1.parts").Array()
+	if len(parts) != 2 {
+		t.Fatalf("parts = %d, want 2: %s", len(parts), out)
+	}
+	result := parts[0].Get("functionResponse.response.result").String()
+	if parts[0].Get("functionResponse.name").String() != "screenshot" || gjson.Get(result, "#").Int() != 1 {
+		t.Errorf("function response = %s", parts[0].Raw)
+	}
+	if parts[1].Get("inlineData.mime_type").String() != "image/png" || parts[1].Get("inlineData.data").String() != "iVBORw0KGgo=" {
+		t.Errorf("image part = %s", parts[1].Raw)
+	}
+}
+
+func TestConvertClaudeRequestToGemini_ToolCallIDRoundTrip(t *testing.T) {
+	upstream := []byte(`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"id":"fc-42","name":"get_weather","args":{"city":"Paris"}}}]},"finishReason":"STOP"}]}`)
+	response := ConvertGeminiResponseToClaudeNonStream(context.Background(), "", nil, nil, upstream, nil)
+	toolUseID := gjson.Get(response, "content.0.id").String()
+	if !strings.HasPrefix(toolUseID, "toolu_") {
+		t.Fatalf("tool_use id = %q, want toolu_ prefix", toolUseID)
+	}
+
+	input := []byte(`{"messages":[
+		{"role":"user","content":"weather?"},
+		{"role":"assistant","content":[{"type":"tool_use","id":"` + toolUseID + `","name":"get_weather","input":{"city":"Paris"}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"` + toolUseID + `","content":"sunny"}]}
+	]}`)
+	out := ConvertClaudeRequestToGemini("gemini-2.5-pro", input, false)
+	if got := gjson.GetBytes(out, "contents.1.parts.0.functionCall.id").String(); got != "fc-42" {
+		t.Errorf("functionCall.id = %q, want fc-42", got)
+	}
+	response2 := gjson.GetBytes(out, "contents.2.parts.0.functionResponse")
+	if response2.Get("name").String() != "get_weather" || response2.Get("id").String() != "fc-42" {
+		t.Errorf("functionResponse = %s", response2.Raw)
+	}
+}
@@ -0,0 +1,99 @@
+package claude
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// sseEvents returns the data payloads of the SSE events in chunks.
+func sseEvents(chunks []string) []gjson.Result {
+	var events []gjson.Result
+	for _, chunk := range chunks {
+		for _, line := range strings.Split(chunk, "\n") {
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				events = append(events, gjson.Parse(data))
+			}
+		}
+	}
+	return events
+}
+
+func TestConvertGeminiResponseToClaude_ThinkingSignature(t *testing.T) {
+	var param any
+	var chunks []string
+	for _, raw := range []string{
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Let me think","thought":true}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":" harder","thought":true,"thoughtSignature":"sig-1"}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Answer"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":1}}`,
+	} {
+		chunks = append(chunks, ConvertGeminiResponseToClaude(context.Background(), "gemini-2.5-pro", nil, nil, []byte(raw), &param)...)
+	}
+
+	var types []string
+	for _, event := range sseEvents(chunks) {
+		eventType := event.Get("type").String()
+		if delta := event.Get("delta.type"); delta.Exists() && eventType == "content_block_delta" {
+			eventType += ":" + delta.String()
+		}
+		types = append(types, eventType)
+		if event.Get("delta.type").String() == "signature_delta" {
+			if event.Get("index").Int() != 0 || event.Get("delta.signature").String() != "sig-1" {
+				t.Errorf("signature delta = %s", event.Raw)
+			}
+		}
+	}
+	want := []string{
+		"message_start",
+		"content_block_start", "content_block_delta:thinking_delta", "content_block_delta:thinking_delta",
+		"content_block_delta:signature_delta", "content_block_stop",
+		"content_block_start", "content_block_delta:text_delta", "content_block_stop",
+		"message_delta",
+	}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v, want %v", types, want)
+	}
+}
+
+func TestConvertGeminiResponseToClaude_SignatureOnFollowingPart(t *testing.T) {
+	var param any
+	var chunks []string
+	for _, raw := range []string{
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Planning","thought":true}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"lookup","args":{}},"thoughtSignature":"sig-2"}]},"finishReason":"STOP"}],"usageMetadata":{"candidatesTokenCount":1}}`,
+	} {
+		chunks = append(chunks, ConvertGeminiResponseToClaude(context.Background(), "gemini-2.5-pro", nil, nil, []byte(raw), &param)...)
+	}
+
+	found := false
+	for _, event := range sseEvents(chunks) {
+		if event.Get("delta.type").String() == "signature_delta" {
+			found = true
+			if event.Get("index").Int() != 0 || event.Get("delta.signature").String() != "sig-2" {
+				t.Errorf("signature delta = %s", event.Raw)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("no signature delta in %q", chunks)
+	}
+}
+
+func TestConvertGeminiResponseToClaudeNonStream_ThinkingSignature(t *testing.T) {
+	raw := `{"candidates":[{"content":{"role":"model","parts":[
+		{"text":"Reasoning","thought":true},
+		{"text":"","thought":true,"thoughtSignature":"sig-3"},
+		{"text":"Done"}
+	]},"finishReason":"STOP"}]}`
+
+	out := ConvertGeminiResponseToClaudeNonStream(context.Background(), "gemini-2.5-pro", nil, nil, []byte(raw), nil)
+	thinking := gjson.Get(out, "content.0")
+	if thinking.Get("type").String() != "thinking" || thinking.Get("thinking").String() != "Reasoning" || thinking.Get("signature").String() != "sig-3" {
+		t.Errorf("thinking block = %s", thinking.Raw)
+	}
+	if gjson.Get(out, "content.1.text").String() != "Done" {
+		t.Errorf("text block = %s", gjson.Get(out, "content.1").Raw)
+	}
+}
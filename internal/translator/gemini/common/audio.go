@@ -0,0 +1,58 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// AudioMimeType maps an OpenAI input_audio format (e.g. "wav", "mp3") to the MIME type
+// accepted by Gemini inlineData parts. Full MIME types are passed through unchanged.
+// It returns an empty string when the format is not a supported audio type.
+func AudioMimeType(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if strings.HasPrefix(format, "audio/") {
+		return format
+	}
+	switch format {
+	case "wav", "wave":
+		return "audio/wav"
+	case "mp3", "mpeg":
+		return "audio/mp3"
+	case "aiff", "aif":
+		return "audio/aiff"
+	case "aac":
+		return "audio/aac"
+	case "ogg", "opus":
+		return "audio/ogg"
+	case "flac":
+		return "audio/flac"
+	case "webm":
+		return "audio/webm"
+	case "m4a", "mp4":
+		return "audio/mp4"
+	case "pcm16", "pcm":
+		return "audio/pcm"
+	default:
+		return ""
+	}
+}
+
+// ClaudeAudioPart converts an Anthropic "audio" content block with a base64 source into a Gemini
+// inlineData part. It reports false when the block has no data or an unsupported media type.
+func ClaudeAudioPart(block gjson.Result) (string, bool) {
+	source := block.Get("source")
+	if source.Get("type").String() != "base64" {
+		return "", false
+	}
+	mimeType := AudioMimeType(source.Get("media_type").String())
+	data := source.Get("data").String()
+	if mimeType == "" || data == "" {
+		return "", false
+	}
+	part := `{"inlineData":{"mime_type":"","data":""}}`
+	part, _ = sjson.Set(part, "inlineData.mime_type", mimeType)
+	part, _ = sjson.Set(part, "inlineData.data", data)
+	return part, true
+}
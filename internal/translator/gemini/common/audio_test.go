@@ -0,0 +1,26 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestClaudeAudioPart(t *testing.T) {
+	part, ok := ClaudeAudioPart(gjson.Parse(`{"type":"audio","source":{"type":"base64","media_type":"wav","data":"AAAA"}}`))
+	if !ok {
+		t.Fatal("expected base64 audio to convert")
+	}
+	if got := gjson.Get(part, "inlineData.mime_type").String(); got != "audio/wav" {
+		t.Fatalf("mime_type = %q, want audio/wav", got)
+	}
+	if got := gjson.Get(part, "inlineData.data").String(); got != "AAAA" {
+		t.Fatalf("data = %q, want AAAA", got)
+	}
+	if _, ok = ClaudeAudioPart(gjson.Parse(`{"type":"audio","source":{"type":"url","url":"https://example.com/a.wav"}}`)); ok {
+		t.Fatal("expected url sources to be skipped")
+	}
+	if _, ok = ClaudeAudioPart(gjson.Parse(`{"type":"audio","source":{"type":"base64","media_type":"video/mp4","data":"AAAA"}}`)); ok {
+		t.Fatal("expected unsupported media types to be skipped")
+	}
+}
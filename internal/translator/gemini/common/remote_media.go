@@ -0,0 +1,316 @@
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	defaultRemoteMediaMaxSizeMB      = 20
+	defaultRemoteMediaTimeoutSeconds = 15
+	remoteMediaCacheTTL              = 10 * time.Minute
+	remoteMediaCacheMaxBytes         = 128 << 20
+)
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which net.IP.IsPrivate does not
+// cover but is not publicly routable either.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// remoteMediaFetcher downloads remote media referenced by URL so it can be sent as inlineData.
+type remoteMediaFetcher struct {
+	client       *http.Client
+	maxBytes     int64
+	allowedHosts []string
+	cache        *remoteMediaCache
+}
+
+var remoteMedia atomic.Pointer[remoteMediaFetcher]
+
+// ConfigureRemoteMedia applies the remote media settings from cfg.
+// Fetching stays disabled unless cfg.RemoteMedia.Enable is true.
+func ConfigureRemoteMedia(cfg *config.Config) {
+	if cfg == nil || !cfg.RemoteMedia.Enable {
+		remoteMedia.Store(nil)
+		return
+	}
+	settings := cfg.RemoteMedia
+	maxSizeMB := settings.MaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultRemoteMediaMaxSizeMB
+	}
+	timeout := settings.TimeoutSeconds
+	if timeout <= 0 {
+		timeout = defaultRemoteMediaTimeoutSeconds
+	}
+	hosts := make([]string, 0, len(settings.AllowedHosts))
+	for _, host := range settings.AllowedHosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: time.Duration(timeout) * time.Second, Control: rejectNonPublicAddress}
+	remoteMedia.Store(&remoteMediaFetcher{
+		client: &http.Client{
+			Timeout:   time.Duration(timeout) * time.Second,
+			Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 10 * time.Second},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 3 {
+					return fmt.Errorf("too many redirects")
+				}
+				return nil
+			},
+		},
+		maxBytes:     int64(maxSizeMB) << 20,
+		allowedHosts: hosts,
+		cache:        newRemoteMediaCache(remoteMediaCacheTTL, remoteMediaCacheMaxBytes),
+	})
+}
+
+// ParseImageURL resolves a data: image URL into a MIME type and base64 payload. Remote URLs are
+// inlined before translation by InlineRemoteMedia, so ok is false for anything but data: URLs.
+func ParseImageURL(rawURL string) (mimeType, data string, ok bool) {
+	rawURL = strings.TrimSpace(rawURL)
+	if !strings.HasPrefix(rawURL, "data:") {
+		if strings.HasPrefix(strings.ToLower(rawURL), "https://") {
+			log.Debugf("remote media not inlined, dropping %s", rawURL)
+		}
+		return "", "", false
+	}
+	meta, payload, found := strings.Cut(rawURL[5:], ",")
+	if !found || payload == "" {
+		return "", "", false
+	}
+	mimeType, _, _ = strings.Cut(meta, ";")
+	if !strings.HasSuffix(meta, ";base64") {
+		decoded, err := url.PathUnescape(payload)
+		if err != nil {
+			return "", "", false
+		}
+		payload = base64.StdEncoding.EncodeToString([]byte(decoded))
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return mimeType, payload, true
+}
+
+// RemoteMediaEnabled reports whether remote media fetching is configured.
+func RemoteMediaEnabled() bool {
+	return remoteMedia.Load() != nil
+}
+
+// InlineRemoteMedia downloads the https image URLs of an OpenAI Chat Completions ("openai") or
+// Responses ("openai-response") request and replaces them with data: URLs, so the Gemini-family
+// translators can send them as inline data. Downloads are bound to ctx and cached, so retries,
+// token counts and conversation turns resending the same URL do not fetch it again. URLs that
+// cannot be fetched are left unchanged.
+func InlineRemoteMedia(ctx context.Context, format string, rawJSON []byte) []byte {
+	fetcher := remoteMedia.Load()
+	if fetcher == nil || len(rawJSON) == 0 {
+		return rawJSON
+	}
+	var root string
+	var urlPaths []string
+	switch format {
+	case "openai":
+		root, urlPaths = "messages", []string{"image_url.url"}
+	case "openai-response":
+		root, urlPaths = "input", []string{"image_url", "url"}
+	default:
+		return rawJSON
+	}
+	out := rawJSON
+	gjson.GetBytes(rawJSON, root).ForEach(func(messageIndex, message gjson.Result) bool {
+		message.Get("content").ForEach(func(itemIndex, item gjson.Result) bool {
+			var rawURL, urlPath string
+			for _, candidate := range urlPaths {
+				if rawURL = strings.TrimSpace(item.Get(candidate).String()); rawURL != "" {
+					urlPath = candidate
+					break
+				}
+			}
+			if !strings.HasPrefix(strings.ToLower(rawURL), "https://") {
+				return true
+			}
+			dataURL, err := fetcher.dataURL(ctx, rawURL)
+			if err != nil {
+				log.Warnf("failed to fetch remote media %s: %v", rawURL, err)
+				return true
+			}
+			path := fmt.Sprintf("%s.%d.content.%d.%s", root, messageIndex.Int(), itemIndex.Int(), urlPath)
+			if updated, errSet := sjson.SetBytes(out, path, dataURL); errSet == nil {
+				out = updated
+			}
+			return true
+		})
+		return true
+	})
+	return out
+}
+
+// dataURL returns rawURL's content as a data: URL, serving repeated URLs from the cache.
+func (f *remoteMediaFetcher) dataURL(ctx context.Context, rawURL string) (string, error) {
+	if cached, ok := f.cache.get(rawURL); ok {
+		return cached, nil
+	}
+	mimeType, raw, err := f.fetch(ctx, rawURL)
+	if err != nil {
+		return "", err
+	}
+	dataURL := "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(raw)
+	f.cache.put(rawURL, dataURL)
+	return dataURL, nil
+}
+
+func (f *remoteMediaFetcher) fetch(ctx context.Context, rawURL string) (string, []byte, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", nil, err
+	}
+	if !f.hostAllowed(parsed.Hostname()) {
+		return "", nil, fmt.Errorf("host %q is not in remote-media.allowed-hosts", parsed.Hostname())
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("response body close error: %v", errClose)
+		}
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > f.maxBytes {
+		return "", nil, fmt.Errorf("content length %d exceeds limit of %d bytes", resp.ContentLength, f.maxBytes)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if int64(len(body)) > f.maxBytes {
+		return "", nil, fmt.Errorf("body exceeds limit of %d bytes", f.maxBytes)
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = http.DetectContentType(body)
+		mimeType, _, _ = mime.ParseMediaType(mimeType)
+	}
+	if !strings.HasPrefix(mimeType, "image/") && mimeType != "application/pdf" {
+		return "", nil, fmt.Errorf("unsupported content type %q", mimeType)
+	}
+	return mimeType, body, nil
+}
+
+func (f *remoteMediaFetcher) hostAllowed(host string) bool {
+	if len(f.allowedHosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, allowed := range f.allowedHosts {
+		if suffix, isWildcard := strings.CutPrefix(allowed, "*."); isWildcard {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectNonPublicAddress prevents remote media downloads from reaching loopback, private,
+// carrier-grade NAT or link-local addresses, including via DNS rebinding or redirects.
+func rejectNonPublicAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("address %s is not publicly routable", host)
+	}
+	return nil
+}
+
+// remoteMediaCache keeps recently inlined media as data: URLs, bounded by age and total size.
+type remoteMediaCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	maxBytes int
+	size     int
+	entries  map[string]remoteMediaCacheEntry
+	order    []string
+}
+
+type remoteMediaCacheEntry struct {
+	dataURL string
+	expires time.Time
+}
+
+func newRemoteMediaCache(ttl time.Duration, maxBytes int) *remoteMediaCache {
+	return &remoteMediaCache{ttl: ttl, maxBytes: maxBytes, entries: make(map[string]remoteMediaCacheEntry)}
+}
+
+func (c *remoteMediaCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return "", false
+	}
+	return entry.dataURL, true
+}
+
+// put stores dataURL, evicting the oldest entries until the cache fits its size budget.
+func (c *remoteMediaCache) put(key, dataURL string) {
+	if len(dataURL) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing, ok := c.entries[key]; ok {
+		c.size -= len(existing.dataURL)
+	} else {
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = remoteMediaCacheEntry{dataURL: dataURL, expires: time.Now().Add(c.ttl)}
+	c.size += len(dataURL)
+	for c.size > c.maxBytes && len(c.order) > 0 {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		if entry, ok := c.entries[oldest]; ok {
+			c.size -= len(entry.dataURL)
+			delete(c.entries, oldest)
+		}
+	}
+}
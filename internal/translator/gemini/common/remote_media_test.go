@@ -0,0 +1,139 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestParseImageURL_DataURL(t *testing.T) {
+	mime, data, ok := ParseImageURL("data:image/png;base64,iVBORw0KGgo=")
+	if !ok || mime != "image/png" || data != "iVBORw0KGgo=" {
+		t.Fatalf("unexpected result: ok=%v mime=%q data=%q", ok, mime, data)
+	}
+
+	mime, data, ok = ParseImageURL("data:text/plain,hi%20there")
+	if !ok || mime != "text/plain" || data != "aGkgdGhlcmU=" {
+		t.Fatalf("unexpected result for non-base64 data URL: ok=%v mime=%q data=%q", ok, mime, data)
+	}
+}
+
+func TestParseImageURL_IgnoresRemoteURLs(t *testing.T) {
+	if _, _, ok := ParseImageURL("https://example.com/cat.png"); ok {
+		t.Fatalf("expected remote URL to be left to InlineRemoteMedia")
+	}
+}
+
+func TestRemoteMediaFetcher_RefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png"))
+	}))
+	defer server.Close()
+
+	t.Cleanup(func() { ConfigureRemoteMedia(nil) })
+	ConfigureRemoteMedia(&config.Config{RemoteMedia: config.RemoteMediaConfig{Enable: true}})
+
+	_, _, err := remoteMedia.Load().fetch(context.Background(), server.URL+"/cat.png")
+	if err == nil || !strings.Contains(err.Error(), "not publicly routable") {
+		t.Fatalf("expected loopback address to be refused, got %v", err)
+	}
+}
+
+func TestRejectNonPublicAddress(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1:443":      false,
+		"10.1.2.3:443":       false,
+		"100.64.0.1:443":     false,
+		"100.127.255.1:443":  false,
+		"169.254.169.254:80": false,
+		"[::1]:443":          false,
+		"100.128.0.1:443":    true,
+		"8.8.8.8:443":        true,
+	}
+	for address, public := range cases {
+		err := rejectNonPublicAddress("tcp", address, nil)
+		if (err == nil) != public {
+			t.Errorf("rejectNonPublicAddress(%q) error = %v, want public=%v", address, err, public)
+		}
+	}
+}
+
+func TestInlineRemoteMedia_InlinesAndCaches(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png"))
+	}))
+	defer server.Close()
+
+	t.Cleanup(func() { remoteMedia.Store(nil) })
+	remoteMedia.Store(&remoteMediaFetcher{
+		client:   server.Client(),
+		maxBytes: 1 << 20,
+		cache:    newRemoteMediaCache(time.Minute, 1<<20),
+	})
+
+	chat := []byte(`{"messages":[{"role":"user","content":[{"type":"text","text":"hi"},{"type":"image_url","image_url":{"url":"` + server.URL + `/cat.png"}}]}]}`)
+	out := InlineRemoteMedia(context.Background(), "openai", chat)
+	if got := gjson.GetBytes(out, "messages.0.content.1.image_url.url").String(); got != "data:image/png;base64,cG5n" {
+		t.Fatalf("unexpected chat image url %q", got)
+	}
+
+	responses := []byte(`{"input":[{"role":"user","content":[{"type":"input_image","image_url":"` + server.URL + `/cat.png"}]}]}`)
+	out = InlineRemoteMedia(context.Background(), "openai-response", responses)
+	if got := gjson.GetBytes(out, "input.0.content.0.image_url").String(); got != "data:image/png;base64,cG5n" {
+		t.Fatalf("unexpected responses image url %q", got)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("expected the second request to be served from cache, got %d fetches", hits.Load())
+	}
+}
+
+func TestInlineRemoteMedia_HonorsContext(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png"))
+	}))
+	defer server.Close()
+
+	t.Cleanup(func() { remoteMedia.Store(nil) })
+	remoteMedia.Store(&remoteMediaFetcher{
+		client:   server.Client(),
+		maxBytes: 1 << 20,
+		cache:    newRemoteMediaCache(time.Minute, 1<<20),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	chat := []byte(`{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"` + server.URL + `/cat.png"}}]}]}`)
+	out := InlineRemoteMedia(ctx, "openai", chat)
+	if string(out) != string(chat) {
+		t.Fatalf("expected a cancelled request to leave the payload unchanged, got %s", out)
+	}
+}
+
+func TestRemoteMediaFetcher_HostAllowed(t *testing.T) {
+	f := &remoteMediaFetcher{allowedHosts: []string{"images.example.com", "*.cdn.example.org"}}
+	cases := map[string]bool{
+		"images.example.com":  true,
+		"IMAGES.example.com":  true,
+		"a.cdn.example.org":   true,
+		"cdn.example.org":     false,
+		"evil.example.com":    false,
+		"images.example.com.": false,
+	}
+	for host, want := range cases {
+		if got := f.hostAllowed(host); got != want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
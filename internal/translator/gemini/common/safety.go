@@ -1,6 +1,10 @@
 package common
 
 import (
+	"sort"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -45,3 +49,98 @@ func AttachDefaultSafetySettings(rawJSON []byte, path string) []byte {
 
 	return out
 }
+
+// AttachSafetySettings copies Gemini-style safety settings that OpenAI or Claude clients send as
+// "safety_settings" (or "safetySettings") in source to path, keeping only valid categories and
+// thresholds. Without usable client settings the default safety settings are attached.
+func AttachSafetySettings(rawJSON, source []byte, path string) []byte {
+	if gjson.GetBytes(rawJSON, path).Exists() {
+		return rawJSON
+	}
+	requested := gjson.GetBytes(source, "safety_settings")
+	if !requested.Exists() {
+		requested = gjson.GetBytes(source, "safetySettings")
+	}
+	var settings []map[string]string
+	for _, entry := range requested.Array() {
+		category, okCategory := config.NormalizeSafetyCategory(entry.Get("category").String())
+		threshold, okThreshold := config.NormalizeSafetyThreshold(entry.Get("threshold").String())
+		if !okCategory || !okThreshold {
+			log.Debugf("safety settings: ignoring invalid entry %s", entry.Raw)
+			continue
+		}
+		settings = append(settings, map[string]string{"category": category, "threshold": threshold})
+	}
+	if len(settings) == 0 {
+		return AttachDefaultSafetySettings(rawJSON, path)
+	}
+	out, err := sjson.SetBytes(rawJSON, path, settings)
+	if err != nil {
+		return rawJSON
+	}
+	return out
+}
+
+// ApplySafetyThresholds applies configured category thresholds to the safety settings at path.
+// Requests carrying the default safety settings, or none, get the defaults with the configured
+// thresholds replacing them. Settings a client chose explicitly are kept; configured thresholds
+// only fill in the categories the client left out.
+func ApplySafetyThresholds(rawJSON []byte, path string, thresholds map[string]string) []byte {
+	if len(thresholds) == 0 {
+		return rawJSON
+	}
+	current := gjson.GetBytes(rawJSON, path)
+	clientChosen := current.Exists() && !isDefaultSafetySettings(current)
+
+	var settings []map[string]string
+	index := make(map[string]int)
+	if clientChosen {
+		for _, entry := range current.Array() {
+			category := entry.Get("category").String()
+			index[category] = len(settings)
+			settings = append(settings, map[string]string{"category": category, "threshold": entry.Get("threshold").String()})
+		}
+	} else {
+		for _, entry := range DefaultSafetySettings() {
+			index[entry["category"]] = len(settings)
+			settings = append(settings, entry)
+		}
+	}
+
+	categories := make([]string, 0, len(thresholds))
+	for category := range thresholds {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		pos, exists := index[category]
+		switch {
+		case !exists:
+			index[category] = len(settings)
+			settings = append(settings, map[string]string{"category": category, "threshold": thresholds[category]})
+		case !clientChosen:
+			settings[pos]["threshold"] = thresholds[category]
+		}
+	}
+
+	out, err := sjson.SetBytes(rawJSON, path, settings)
+	if err != nil {
+		return rawJSON
+	}
+	return out
+}
+
+// isDefaultSafetySettings reports whether settings equal DefaultSafetySettings.
+func isDefaultSafetySettings(settings gjson.Result) bool {
+	defaults := DefaultSafetySettings()
+	entries := settings.Array()
+	if len(entries) != len(defaults) {
+		return false
+	}
+	for i, entry := range entries {
+		if entry.Get("category").String() != defaults[i]["category"] || entry.Get("threshold").String() != defaults[i]["threshold"] {
+			return false
+		}
+	}
+	return true
+}
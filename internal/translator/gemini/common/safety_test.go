@@ -0,0 +1,41 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestAttachSafetySettingsFromClient(t *testing.T) {
+	source := []byte(`{"safety_settings":[{"category":"HARASSMENT","threshold":"block_only_high"},{"category":"HARM_CATEGORY_UNKNOWN","threshold":"OFF"}]}`)
+	out := AttachSafetySettings([]byte(`{}`), source, "request.safetySettings")
+	settings := gjson.GetBytes(out, "request.safetySettings").Array()
+	if len(settings) != 1 || settings[0].Get("category").String() != "HARM_CATEGORY_HARASSMENT" || settings[0].Get("threshold").String() != "BLOCK_ONLY_HIGH" {
+		t.Fatalf("unexpected safety settings: %s", out)
+	}
+
+	out = AttachSafetySettings([]byte(`{}`), []byte(`{"safety_settings":[{"category":"bogus","threshold":"OFF"}]}`), "safetySettings")
+	if got := len(gjson.GetBytes(out, "safetySettings").Array()); got != len(DefaultSafetySettings()) {
+		t.Fatalf("expected defaults without valid client settings, got %s", out)
+	}
+}
+
+func TestApplySafetyThresholds(t *testing.T) {
+	thresholds := map[string]string{"HARM_CATEGORY_HARASSMENT": "BLOCK_LOW_AND_ABOVE", "HARM_CATEGORY_HATE_SPEECH": "BLOCK_ONLY_HIGH"}
+
+	defaults := AttachDefaultSafetySettings([]byte(`{}`), "safetySettings")
+	out := ApplySafetyThresholds(defaults, "safetySettings", thresholds)
+	if got := gjson.GetBytes(out, `safetySettings.#(category=="HARM_CATEGORY_HARASSMENT").threshold`).String(); got != "BLOCK_LOW_AND_ABOVE" {
+		t.Fatalf("configured threshold did not replace the default: %s", out)
+	}
+	if got := gjson.GetBytes(out, `safetySettings.#(category=="HARM_CATEGORY_DANGEROUS_CONTENT").threshold`).String(); got != "OFF" {
+		t.Fatalf("unconfigured category lost its default: %s", out)
+	}
+
+	client := []byte(`{"safetySettings":[{"category":"HARM_CATEGORY_HARASSMENT","threshold":"BLOCK_NONE"}]}`)
+	out = ApplySafetyThresholds(client, "safetySettings", thresholds)
+	settings := gjson.GetBytes(out, "safetySettings").Array()
+	if len(settings) != 2 || settings[0].Get("threshold").String() != "BLOCK_NONE" || settings[1].Get("category").String() != "HARM_CATEGORY_HATE_SPEECH" {
+		t.Fatalf("client settings should win and missing categories be filled: %s", out)
+	}
+}
@@ -0,0 +1,31 @@
+package common
+
+import "github.com/tidwall/gjson"
+
+// OpenAISystemTexts returns the text segments of an OpenAI system or developer message content:
+// a string, a single text part, or an array of text parts (which may carry cache_control) and
+// plain strings. Non-text and empty segments are skipped.
+func OpenAISystemTexts(content gjson.Result) []string {
+	var texts []string
+	add := func(item gjson.Result) {
+		text := item.String()
+		if item.IsObject() {
+			if item.Get("type").String() != "text" {
+				return
+			}
+			text = item.Get("text").String()
+		}
+		if text != "" {
+			texts = append(texts, text)
+		}
+	}
+	switch {
+	case content.IsArray():
+		for _, item := range content.Array() {
+			add(item)
+		}
+	case content.IsObject() || content.Type == gjson.String:
+		add(content)
+	}
+	return texts
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ClaudeImagePart converts an Anthropic "image" content block with a base64 source into a Gemini
+// inlineData part. It reports false when the block has no data or media type.
+func ClaudeImagePart(block gjson.Result) (string, bool) {
+	source := block.Get("source")
+	if source.Get("type").String() != "base64" {
+		return "", false
+	}
+	mimeType := source.Get("media_type").String()
+	data := source.Get("data").String()
+	if mimeType == "" || data == "" {
+		return "", false
+	}
+	part := `{"inlineData":{"mime_type":"","data":""}}`
+	part, _ = sjson.Set(part, "inlineData.mime_type", mimeType)
+	part, _ = sjson.Set(part, "inlineData.data", data)
+	return part, true
+}
+
+// SplitClaudeToolResultImages separates the image blocks of an Anthropic tool_result content
+// array from the rest. It returns the content without the images together with one Gemini
+// inlineData part per image, to be sent next to the functionResponse since Gemini function
+// responses carry no media. Content without images is returned unchanged.
+func SplitClaudeToolResultImages(content gjson.Result) (gjson.Result, []string) {
+	if !content.IsArray() {
+		return content, nil
+	}
+	var images []string
+	remaining := `[]`
+	content.ForEach(func(_, block gjson.Result) bool {
+		if block.Get("type").String() == "image" {
+			if part, ok := ClaudeImagePart(block); ok {
+				images = append(images, part)
+				return true
+			}
+		}
+		remaining, _ = sjson.SetRaw(remaining, "-1", block.Raw)
+		return true
+	})
+	if len(images) == 0 {
+		return content, nil
+	}
+	return gjson.Parse(remaining), images
+}
+
+// ClaudeToolUseNames maps the IDs of the tool_use blocks in an Anthropic messages array to their
+// function names, so tool_result blocks can be matched with the call they answer.
+func ClaudeToolUseNames(messages gjson.Result) map[string]string {
+	names := make(map[string]string)
+	messages.ForEach(func(_, message gjson.Result) bool {
+		message.Get("content").ForEach(func(_, block gjson.Result) bool {
+			if block.Get("type").String() == "tool_use" {
+				if id, name := block.Get("id").String(), block.Get("name").String(); id != "" && name != "" {
+					names[id] = name
+				}
+			}
+			return true
+		})
+		return true
+	})
+	return names
+}
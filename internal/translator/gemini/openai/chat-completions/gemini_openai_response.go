@@ -10,9 +10,9 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"sync/atomic"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
 	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -25,9 +25,6 @@ type convertGeminiResponseToOpenAIChatParams struct {
 	FunctionIndex map[int]int
 }
 
-// functionCallIDCounter provides a process-wide unique counter for function call identifiers.
-var functionCallIDCounter uint64
-
 // ConvertGeminiResponseToOpenAI translates a single chunk of a streaming response from the
 // Gemini API format to the OpenAI Chat Completions streaming format.
 // It processes various Gemini event types and transforms them into OpenAI-compatible JSON responses.
@@ -121,12 +118,16 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 
 	// Iterate over all candidates to support candidate_count > 1.
 	if candidates.IsArray() {
-		candidates.ForEach(func(_, candidate gjson.Result) bool {
+		candidates.ForEach(func(position, candidate gjson.Result) bool {
 			// Clone the template for the current candidate.
 			template := baseTemplate
 
-			// Set the specific index for this candidate.
-			candidateIndex := int(candidate.Get("index").Int())
+			// Set the specific index for this candidate, falling back to its array position
+			// because Gemini omits the index field for the first candidate.
+			candidateIndex := int(position.Int())
+			if indexResult := candidate.Get("index"); indexResult.Exists() {
+				candidateIndex = int(indexResult.Int())
+			}
 			template, _ = sjson.Set(template, "choices.0.index", candidateIndex)
 
 			// Extract and set the finish reason.
@@ -187,7 +188,7 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 
 						functionCallTemplate := `{"id": "","index": 0,"type": "function","function": {"name": "","arguments": ""}}`
 						fcName := functionCallResult.Get("name").String()
-						functionCallTemplate, _ = sjson.Set(functionCallTemplate, "id", fmt.Sprintf("%s-%d-%d", fcName, time.Now().UnixNano(), atomic.AddUint64(&functionCallIDCounter, 1)))
+						functionCallTemplate, _ = sjson.Set(functionCallTemplate, "id", cache.NewToolCallID("call_", fcName, functionCallResult.Get("id").String()))
 						functionCallTemplate, _ = sjson.Set(functionCallTemplate, "index", functionCallIndex)
 						functionCallTemplate, _ = sjson.Set(functionCallTemplate, "function.name", fcName)
 						if fcArgsResult := functionCallResult.Get("args"); fcArgsResult.Exists() {
@@ -303,12 +304,16 @@ func ConvertGeminiResponseToOpenAINonStream(_ context.Context, _ string, origina
 	// Process the main content part of the response for all candidates.
 	candidates := gjson.GetBytes(rawJSON, "candidates")
 	if candidates.IsArray() {
-		candidates.ForEach(func(_, candidate gjson.Result) bool {
+		candidates.ForEach(func(position, candidate gjson.Result) bool {
 			// Construct a single Choice object.
 			choiceTemplate := `{"index":0,"message":{"role":"assistant","content":null,"reasoning_content":null,"tool_calls":null},"finish_reason":null,"native_finish_reason":null}`
 
 			// Set the index for this choice.
-			choiceTemplate, _ = sjson.Set(choiceTemplate, "index", candidate.Get("index").Int())
+			choiceIndex := position.Int()
+			if indexResult := candidate.Get("index"); indexResult.Exists() {
+				choiceIndex = indexResult.Int()
+			}
+			choiceTemplate, _ = sjson.Set(choiceTemplate, "index", choiceIndex)
 
 			// Set finish reason.
 			if finishReasonResult := candidate.Get("finishReason"); finishReasonResult.Exists() {
@@ -348,7 +353,7 @@ func ConvertGeminiResponseToOpenAINonStream(_ context.Context, _ string, origina
 						}
 						functionCallItemTemplate := `{"id": "","type": "function","function": {"name": "","arguments": ""}}`
 						fcName := functionCallResult.Get("name").String()
-						functionCallItemTemplate, _ = sjson.Set(functionCallItemTemplate, "id", fmt.Sprintf("%s-%d-%d", fcName, time.Now().UnixNano(), atomic.AddUint64(&functionCallIDCounter, 1)))
+						functionCallItemTemplate, _ = sjson.Set(functionCallItemTemplate, "id", cache.NewToolCallID("call_", fcName, functionCallResult.Get("id").String()))
 						functionCallItemTemplate, _ = sjson.Set(functionCallItemTemplate, "function.name", fcName)
 						if fcArgsResult := functionCallResult.Get("args"); fcArgsResult.Exists() {
 							functionCallItemTemplate, _ = sjson.Set(functionCallItemTemplate, "function.arguments", fcArgsResult.Raw)
@@ -357,7 +362,8 @@ func ConvertGeminiResponseToOpenAINonStream(_ context.Context, _ string, origina
 						choiceTemplate, _ = sjson.SetRaw(choiceTemplate, "message.tool_calls.-1", functionCallItemTemplate)
 					} else if inlineDataResult.Exists() {
 						data := inlineDataResult.Get("data").String()
-						if data != "" {
+						// Draft images of image models are reported as thoughts and are not returned.
+						if data != "" && !partResult.Get("thought").Bool() {
 							mimeType := inlineDataResult.Get("mimeType").String()
 							if mimeType == "" {
 								mimeType = inlineDataResult.Get("mime_type").String()
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"strings"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/common"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -117,7 +118,9 @@ func ConvertOpenAIResponsesRequestToGemini(modelName string, inputRawJSON []byte
 
 			switch itemType {
 			case "message":
-				if strings.EqualFold(itemRole, "system") {
+				// "developer" is the newer spelling of "system"; both are appended after
+				// top-level instructions in the order they appear.
+				if strings.EqualFold(itemRole, "system") || strings.EqualFold(itemRole, "developer") {
 					if contentArray := item.Get("content"); contentArray.Exists() && contentArray.IsArray() {
 						var builder strings.Builder
 						contentArray.ForEach(func(_, contentItem gjson.Result) bool {
@@ -132,6 +135,10 @@ func ConvertOpenAIResponsesRequestToGemini(modelName string, inputRawJSON []byte
 							systemInstr := `{"parts":[{"text":""}]}`
 							systemInstr, _ = sjson.Set(systemInstr, "parts.0.text", builder.String())
 							out, _ = sjson.SetRaw(out, "system_instruction", systemInstr)
+						} else if builder.Len() > 0 {
+							part := `{"text":""}`
+							part, _ = sjson.Set(part, "text", builder.String())
+							out, _ = sjson.SetRaw(out, "system_instruction.parts.-1", part)
 						}
 					}
 					continue
@@ -201,32 +208,10 @@ func ConvertOpenAIResponsesRequestToGemini(modelName string, inputRawJSON []byte
 							if imageURL == "" {
 								imageURL = contentItem.Get("url").String()
 							}
-							if imageURL != "" {
-								mimeType := "application/octet-stream"
-								data := ""
-								if strings.HasPrefix(imageURL, "data:") {
-									trimmed := strings.TrimPrefix(imageURL, "data:")
-									mediaAndData := strings.SplitN(trimmed, ";base64,", 2)
-									if len(mediaAndData) == 2 {
-										if mediaAndData[0] != "" {
-											mimeType = mediaAndData[0]
-										}
-										data = mediaAndData[1]
-									} else {
-										mediaAndData = strings.SplitN(trimmed, ",", 2)
-										if len(mediaAndData) == 2 {
-											if mediaAndData[0] != "" {
-												mimeType = mediaAndData[0]
-											}
-											data = mediaAndData[1]
-										}
-									}
-								}
-								if data != "" {
-									partJSON = `{"inline_data":{"mime_type":"","data":""}}`
-									partJSON, _ = sjson.Set(partJSON, "inline_data.mime_type", mimeType)
-									partJSON, _ = sjson.Set(partJSON, "inline_data.data", data)
-								}
+							if mimeType, data, ok := common.ParseImageURL(imageURL); ok {
+								partJSON = `{"inline_data":{"mime_type":"","data":""}}`
+								partJSON, _ = sjson.Set(partJSON, "inline_data.mime_type", mimeType)
+								partJSON, _ = sjson.Set(partJSON, "inline_data.data", data)
 							}
 						}
 
@@ -248,7 +233,7 @@ func ConvertOpenAIResponsesRequestToGemini(modelName string, inputRawJSON []byte
 				functionCall := `{"functionCall":{"name":"","args":{}}}`
 				functionCall, _ = sjson.Set(functionCall, "functionCall.name", name)
 				functionCall, _ = sjson.Set(functionCall, "thoughtSignature", geminiResponsesThoughtSignature)
-				functionCall, _ = sjson.Set(functionCall, "functionCall.id", item.Get("call_id").String())
+				functionCall, _ = sjson.Set(functionCall, "functionCall.id", cache.UpstreamToolCallIDOr(item.Get("call_id").String()))
 
 				// Parse arguments JSON string and set as args object
 				if arguments != "" {
@@ -271,6 +256,9 @@ func ConvertOpenAIResponsesRequestToGemini(modelName string, inputRawJSON []byte
 				// We need to extract the function name from the previous function_call
 				// For now, we'll use a placeholder or extract from context if available
 				functionName := "unknown" // This should ideally be matched with the corresponding function_call
+				if mapping, ok := cache.LookupToolCallID(callID); ok && mapping.Name != "" {
+					functionName = mapping.Name
+				}
 
 				// Find the corresponding function call name by matching call_id
 				// We need to look back through the input array to find the matching call
@@ -285,7 +273,7 @@ func ConvertOpenAIResponsesRequestToGemini(modelName string, inputRawJSON []byte
 				}
 
 				functionResponse, _ = sjson.Set(functionResponse, "functionResponse.name", functionName)
-				functionResponse, _ = sjson.Set(functionResponse, "functionResponse.id", callID)
+				functionResponse, _ = sjson.Set(functionResponse, "functionResponse.id", cache.UpstreamToolCallIDOr(callID))
 
 				// Set the raw JSON output directly (preserves string encoding)
 				if outputRaw != "" && outputRaw != "null" {
@@ -414,6 +402,6 @@ func ConvertOpenAIResponsesRequestToGemini(modelName string, inputRawJSON []byte
 	}
 
 	result := []byte(out)
-	result = common.AttachDefaultSafetySettings(result, "safetySettings")
+	result = common.AttachSafetySettings(result, inputRawJSON, "safetySettings")
 	return result
 }
@@ -8,6 +8,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/cache"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -22,8 +23,8 @@ type geminiToResponsesState struct {
 	MsgOpened    bool
 	MsgClosed    bool
 	MsgIndex     int
+	MsgCount     int
 	CurrentMsgID string
-	TextBuf      strings.Builder
 	ItemTextBuf  strings.Builder
 
 	// reasoning aggregation
@@ -40,14 +41,15 @@ type geminiToResponsesState struct {
 	FuncNames   map[int]string
 	FuncCallIDs map[int]string
 	FuncDone    map[int]bool
+
+	// DoneItems holds the finished reasoning and message items by output_index. The model may
+	// interleave several of each with its function calls.
+	DoneItems map[int]string
 }
 
 // responseIDCounter provides a process-wide unique counter for synthesized response identifiers.
 var responseIDCounter uint64
 
-// funcCallIDCounter provides a process-wide unique counter for function call identifiers.
-var funcCallIDCounter uint64
-
 func pickRequestJSON(originalRequestRawJSON, requestRawJSON []byte) []byte {
 	if len(originalRequestRawJSON) > 0 && gjson.ValidBytes(originalRequestRawJSON) {
 		return originalRequestRawJSON
@@ -108,6 +110,9 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 	if st.FuncDone == nil {
 		st.FuncDone = make(map[int]bool)
 	}
+	if st.DoneItems == nil {
+		st.DoneItems = make(map[int]string)
+	}
 
 	if bytes.HasPrefix(rawJSON, []byte("data:")) {
 		rawJSON = bytes.TrimSpace(rawJSON[5:])
@@ -157,6 +162,11 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 		itemDone, _ = sjson.Set(itemDone, "item.summary.0.text", full)
 		out = append(out, emitEvent("response.output_item.done", itemDone))
 
+		item := `{"id":"","type":"reasoning","encrypted_content":"","summary":[{"type":"summary_text","text":""}]}`
+		item, _ = sjson.Set(item, "id", st.ReasoningItemID)
+		item, _ = sjson.Set(item, "encrypted_content", st.ReasoningEnc)
+		item, _ = sjson.Set(item, "summary.0.text", full)
+		st.DoneItems[st.ReasoningIndex] = item
 		st.ReasoningClosed = true
 	}
 
@@ -187,6 +197,10 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 		final, _ = sjson.Set(final, "item.content.0.text", fullText)
 		out = append(out, emitEvent("response.output_item.done", final))
 
+		item := `{"id":"","type":"message","status":"completed","content":[{"type":"output_text","annotations":[],"logprobs":[],"text":""}],"role":"assistant"}`
+		item, _ = sjson.Set(item, "id", st.CurrentMsgID)
+		item, _ = sjson.Set(item, "content.0.text", fullText)
+		st.DoneItems[st.MsgIndex] = item
 		st.MsgClosed = true
 	}
 
@@ -229,9 +243,17 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 		parts.ForEach(func(_, part gjson.Result) bool {
 			// Reasoning text
 			if part.Get("thought").Bool() {
-				if st.ReasoningClosed {
-					// Ignore any late thought chunks after reasoning is finalized.
-					return true
+				if st.ReasoningClosed || (st.MsgOpened && !st.MsgClosed) {
+					// Reasoning that resumes after other output becomes a new reasoning item, keeping
+					// the order the model produced. Late signature-only chunks are ignored.
+					if part.Get("text").String() == "" {
+						return true
+					}
+					finalizeMessage()
+					st.ReasoningOpened = false
+					st.ReasoningClosed = false
+					st.ReasoningBuf.Reset()
+					st.ReasoningEnc = ""
 				}
 				if sig := part.Get("thoughtSignature"); sig.Exists() && sig.String() != "" && sig.String() != geminiResponsesThoughtSignature {
 					st.ReasoningEnc = sig.String()
@@ -271,11 +293,17 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 			if t := part.Get("text"); t.Exists() && t.String() != "" {
 				// Before emitting non-reasoning outputs, finalize reasoning if open.
 				finalizeReasoning()
+				if st.MsgClosed {
+					// Text following a function call starts a new message item.
+					st.MsgOpened = false
+					st.MsgClosed = false
+				}
 				if !st.MsgOpened {
 					st.MsgOpened = true
 					st.MsgIndex = st.NextIndex
 					st.NextIndex++
-					st.CurrentMsgID = fmt.Sprintf("msg_%s_0", st.ResponseID)
+					st.CurrentMsgID = fmt.Sprintf("msg_%s_%d", st.ResponseID, st.MsgCount)
+					st.MsgCount++
 					item := `{"type":"response.output_item.added","sequence_number":0,"output_index":0,"item":{"id":"","type":"message","status":"in_progress","content":[],"role":"assistant"}}`
 					item, _ = sjson.Set(item, "sequence_number", nextSeq())
 					item, _ = sjson.Set(item, "output_index", st.MsgIndex)
@@ -288,7 +316,6 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 					out = append(out, emitEvent("response.content_part.added", partAdded))
 					st.ItemTextBuf.Reset()
 				}
-				st.TextBuf.WriteString(t.String())
 				st.ItemTextBuf.WriteString(t.String())
 				msg := `{"type":"response.output_text.delta","sequence_number":0,"item_id":"","output_index":0,"content_index":0,"delta":"","logprobs":[]}`
 				msg, _ = sjson.Set(msg, "sequence_number", nextSeq())
@@ -313,7 +340,7 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 					st.FuncArgsBuf[idx] = &strings.Builder{}
 				}
 				if st.FuncCallIDs[idx] == "" {
-					st.FuncCallIDs[idx] = fmt.Sprintf("call_%d_%d", time.Now().UnixNano(), atomic.AddUint64(&funcCallIDCounter, 1))
+					st.FuncCallIDs[idx] = cache.NewToolCallID("call_", name, fc.Get("id").String())
 				}
 				st.FuncNames[idx] = name
 
@@ -496,18 +523,7 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 		// Compose outputs in output_index order.
 		outputsWrapper := `{"arr":[]}`
 		for idx := 0; idx < st.NextIndex; idx++ {
-			if st.ReasoningOpened && idx == st.ReasoningIndex {
-				item := `{"id":"","type":"reasoning","encrypted_content":"","summary":[{"type":"summary_text","text":""}]}`
-				item, _ = sjson.Set(item, "id", st.ReasoningItemID)
-				item, _ = sjson.Set(item, "encrypted_content", st.ReasoningEnc)
-				item, _ = sjson.Set(item, "summary.0.text", st.ReasoningBuf.String())
-				outputsWrapper, _ = sjson.SetRaw(outputsWrapper, "arr.-1", item)
-				continue
-			}
-			if st.MsgOpened && idx == st.MsgIndex {
-				item := `{"id":"","type":"message","status":"completed","content":[{"type":"output_text","annotations":[],"logprobs":[],"text":""}],"role":"assistant"}`
-				item, _ = sjson.Set(item, "id", st.CurrentMsgID)
-				item, _ = sjson.Set(item, "content.0.text", st.TextBuf.String())
+			if item, ok := st.DoneItems[idx]; ok {
 				outputsWrapper, _ = sjson.SetRaw(outputsWrapper, "arr.-1", item)
 				continue
 			}
@@ -657,11 +673,14 @@ func ConvertGeminiResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 		resp, _ = sjson.Set(resp, "model", v.String())
 	}
 
-	// Build outputs from candidates[0].content.parts
+	// Build outputs from candidates[0].content.parts, keeping the order of reasoning, text and
+	// function calls: each run of thoughts or text becomes one item.
 	var reasoningText strings.Builder
 	var reasoningEncrypted string
 	var messageText strings.Builder
 	var haveMessage bool
+	reasoningCount, messageCount := 0, 0
+	rid := strings.TrimPrefix(id, "resp_")
 
 	haveOutput := false
 	ensureOutput := func() {
@@ -675,10 +694,45 @@ func ConvertGeminiResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 		ensureOutput()
 		resp, _ = sjson.SetRaw(resp, "output.-1", itemJSON)
 	}
+	flushReasoning := func() {
+		if reasoningText.Len() == 0 && reasoningEncrypted == "" {
+			return
+		}
+		itemID := fmt.Sprintf("rs_%s", rid)
+		if reasoningCount > 0 {
+			itemID = fmt.Sprintf("rs_%s_%d", rid, reasoningCount)
+		}
+		reasoningCount++
+		itemJSON := `{"id":"","type":"reasoning","encrypted_content":""}`
+		itemJSON, _ = sjson.Set(itemJSON, "id", itemID)
+		itemJSON, _ = sjson.Set(itemJSON, "encrypted_content", reasoningEncrypted)
+		if reasoningText.Len() > 0 {
+			summaryJSON := `{"type":"summary_text","text":""}`
+			summaryJSON, _ = sjson.Set(summaryJSON, "text", reasoningText.String())
+			itemJSON, _ = sjson.SetRaw(itemJSON, "summary", "[]")
+			itemJSON, _ = sjson.SetRaw(itemJSON, "summary.-1", summaryJSON)
+		}
+		appendOutput(itemJSON)
+		reasoningText.Reset()
+		reasoningEncrypted = ""
+	}
+	flushMessage := func() {
+		if !haveMessage {
+			return
+		}
+		itemJSON := `{"id":"","type":"message","status":"completed","content":[{"type":"output_text","annotations":[],"logprobs":[],"text":""}],"role":"assistant"}`
+		itemJSON, _ = sjson.Set(itemJSON, "id", fmt.Sprintf("msg_%s_%d", rid, messageCount))
+		itemJSON, _ = sjson.Set(itemJSON, "content.0.text", messageText.String())
+		appendOutput(itemJSON)
+		messageCount++
+		messageText.Reset()
+		haveMessage = false
+	}
 
 	if parts := root.Get("candidates.0.content.parts"); parts.Exists() && parts.IsArray() {
 		parts.ForEach(func(_, p gjson.Result) bool {
 			if p.Get("thought").Bool() {
+				flushMessage()
 				if t := p.Get("text"); t.Exists() {
 					reasoningText.WriteString(t.String())
 				}
@@ -688,14 +742,17 @@ func ConvertGeminiResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 				return true
 			}
 			if t := p.Get("text"); t.Exists() && t.String() != "" {
+				flushReasoning()
 				messageText.WriteString(t.String())
 				haveMessage = true
 				return true
 			}
 			if fc := p.Get("functionCall"); fc.Exists() {
+				flushReasoning()
+				flushMessage()
 				name := fc.Get("name").String()
 				args := fc.Get("args")
-				callID := fmt.Sprintf("call_%x_%d", time.Now().UnixNano(), atomic.AddUint64(&funcCallIDCounter, 1))
+				callID := cache.NewToolCallID("call_", name, fc.Get("id").String())
 				itemJSON := `{"id":"","type":"function_call","status":"completed","arguments":"","call_id":"","name":""}`
 				itemJSON, _ = sjson.Set(itemJSON, "id", fmt.Sprintf("fc_%s", callID))
 				itemJSON, _ = sjson.Set(itemJSON, "call_id", callID)
@@ -712,28 +769,8 @@ func ConvertGeminiResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 		})
 	}
 
-	// Reasoning output item
-	if reasoningText.Len() > 0 || reasoningEncrypted != "" {
-		rid := strings.TrimPrefix(id, "resp_")
-		itemJSON := `{"id":"","type":"reasoning","encrypted_content":""}`
-		itemJSON, _ = sjson.Set(itemJSON, "id", fmt.Sprintf("rs_%s", rid))
-		itemJSON, _ = sjson.Set(itemJSON, "encrypted_content", reasoningEncrypted)
-		if reasoningText.Len() > 0 {
-			summaryJSON := `{"type":"summary_text","text":""}`
-			summaryJSON, _ = sjson.Set(summaryJSON, "text", reasoningText.String())
-			itemJSON, _ = sjson.SetRaw(itemJSON, "summary", "[]")
-			itemJSON, _ = sjson.SetRaw(itemJSON, "summary.-1", summaryJSON)
-		}
-		appendOutput(itemJSON)
-	}
-
-	// Assistant message output item
-	if haveMessage {
-		itemJSON := `{"id":"","type":"message","status":"completed","content":[{"type":"output_text","annotations":[],"logprobs":[],"text":""}],"role":"assistant"}`
-		itemJSON, _ = sjson.Set(itemJSON, "id", fmt.Sprintf("msg_%s_0", strings.TrimPrefix(id, "resp_")))
-		itemJSON, _ = sjson.Set(itemJSON, "content.0.text", messageText.String())
-		appendOutput(itemJSON)
-	}
+	flushReasoning()
+	flushMessage()
 
 	// usage mapping
 	if um := root.Get("usageMetadata"); um.Exists() {
@@ -351,3 +351,76 @@ func TestConvertGeminiResponseToOpenAIResponses_ResponseOutputOrdering(t *testin
 		t.Fatalf("expected response.completed after message added: msgAdded=%d completed=%d", posMsgAdded, posCompleted)
 	}
 }
+
+func TestConvertGeminiResponseToOpenAIResponses_InterleavedOutputOrdering(t *testing.T) {
+	in := []string{
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"plan","thought":true}]}}],"responseId":"req_3"}`,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Looking it up."}]}}],"responseId":"req_3"}`,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"lookup","args":{}}}]}}],"responseId":"req_3"}`,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"check result","thought":true}]}}],"responseId":"req_3"}`,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Done."}]},"finishReason":"STOP"}],"responseId":"req_3"}`,
+	}
+
+	var param any
+	var out []string
+	for _, line := range in {
+		out = append(out, ConvertGeminiResponseToOpenAIResponses(context.Background(), "test-model", nil, nil, []byte(line), &param)...)
+	}
+
+	var added []string
+	var completed gjson.Result
+	for _, chunk := range out {
+		ev, data := parseSSEEvent(t, chunk)
+		switch ev {
+		case "response.output_item.added":
+			added = append(added, data.Get("item.type").String())
+		case "response.completed":
+			completed = data
+		}
+	}
+
+	want := []string{"reasoning", "message", "function_call", "reasoning", "message"}
+	if strings.Join(added, ",") != strings.Join(want, ",") {
+		t.Fatalf("added items = %v, want %v", added, want)
+	}
+	output := completed.Get("response.output").Array()
+	if len(output) != len(want) {
+		t.Fatalf("response.output = %s", completed.Get("response.output").Raw)
+	}
+	for i, item := range output {
+		if item.Get("type").String() != want[i] {
+			t.Fatalf("response.output[%d] = %s, want %s", i, item.Raw, want[i])
+		}
+	}
+	if output[1].Get("content.0.text").String() != "Looking it up." || output[4].Get("content.0.text").String() != "Done." {
+		t.Fatalf("message texts not kept apart: %s", completed.Get("response.output").Raw)
+	}
+	if output[3].Get("summary.0.text").String() != "check result" {
+		t.Fatalf("second reasoning item = %s", output[3].Raw)
+	}
+	if output[1].Get("id").String() == output[4].Get("id").String() {
+		t.Fatalf("message items share id %s", output[1].Get("id").String())
+	}
+}
+
+func TestConvertGeminiResponseToOpenAIResponsesNonStream_InterleavedOutputOrdering(t *testing.T) {
+	raw := []byte(`{"responseId":"req_4","candidates":[{"content":{"role":"model","parts":[
+		{"text":"plan","thought":true},
+		{"text":"Looking it up."},
+		{"functionCall":{"name":"lookup","args":{}}},
+		{"text":"Done."}
+	]},"finishReason":"STOP"}]}`)
+
+	out := ConvertGeminiResponseToOpenAIResponsesNonStream(context.Background(), "test-model", nil, nil, raw, nil)
+	var types []string
+	for _, item := range gjson.Get(out, "output").Array() {
+		types = append(types, item.Get("type").String())
+	}
+	want := []string{"reasoning", "message", "function_call", "message"}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Fatalf("output types = %v, want %v", types, want)
+	}
+	if gjson.Get(out, "output.3.content.0.text").String() != "Done." {
+		t.Fatalf("final message = %s", gjson.Get(out, "output.3").Raw)
+	}
+}
@@ -0,0 +1,265 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+)
+
+// update rewrites the expected files from the current translator output:
+//
+//	go test ./internal/translator -run Golden -update
+var update = flag.Bool("update", false, "rewrite the translator golden files")
+
+// goldenModel is the model name every fixture is translated for.
+const goldenModel = "test-model"
+
+// goldenDir holds the fixtures. Client requests live in inputs/requests/<client format>/ and are
+// translated to every upstream format registered for that client format. Upstream responses live
+// in inputs/responses/<upstream format>/ and are translated back to every client format. The
+// results are compared with expected/requests/<client>-<upstream>/ and
+// expected/responses/<upstream>-<client>/.
+var goldenDir = filepath.Join("testdata", "golden")
+
+var goldenFormats = []string{OpenAI, OpenaiResponse, Claude, Gemini, GeminiCLI, Codex, Antigravity, Kiro}
+
+type direction struct {
+	client   string
+	upstream string
+}
+
+// registeredDirections returns every client/upstream pair with a registered translator.
+func registeredDirections() []direction {
+	var out []direction
+	for _, client := range goldenFormats {
+		for _, upstream := range goldenFormats {
+			if sdktranslator.HasResponseTransformer(sdktranslator.FromString(client), sdktranslator.FromString(upstream)) {
+				out = append(out, direction{client: client, upstream: upstream})
+			}
+		}
+	}
+	return out
+}
+
+type fixture struct {
+	name string
+	data []byte
+}
+
+func readFixtures(t testing.TB, dir string) []fixture {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatalf("list fixtures in %s: %v", dir, err)
+	}
+	sort.Strings(paths)
+	var out []fixture
+	for _, path := range paths {
+		data, errRead := os.ReadFile(path)
+		if errRead != nil {
+			t.Fatalf("read fixture: %v", errRead)
+		}
+		out = append(out, fixture{name: strings.TrimSuffix(filepath.Base(path), ".json"), data: data})
+	}
+	return out
+}
+
+// fixtureStream reports whether a request fixture asks for a streaming response.
+func fixtureStream(data []byte) bool {
+	var body struct {
+		Stream bool `json:"stream"`
+	}
+	_ = json.Unmarshal(data, &body)
+	return body.Stream
+}
+
+func TestTranslatorGoldenRequests(t *testing.T) {
+	for _, dir := range registeredDirections() {
+		inputs := readFixtures(t, filepath.Join(goldenDir, "inputs", "requests", dir.client))
+		if len(inputs) == 0 {
+			t.Errorf("no request fixtures for client format %s", dir.client)
+			continue
+		}
+		for _, input := range inputs {
+			t.Run(dir.client+"-"+dir.upstream+"/"+input.name, func(t *testing.T) {
+				out := sdktranslator.TranslateRequest(sdktranslator.FromString(dir.client), sdktranslator.FromString(dir.upstream), goldenModel, bytes.Clone(input.data), fixtureStream(input.data))
+				checkGolden(t, filepath.Join(goldenDir, "expected", "requests", dir.client+"-"+dir.upstream, input.name+".json"), out, input.data)
+			})
+		}
+	}
+}
+
+func TestTranslatorGoldenResponses(t *testing.T) {
+	for _, dir := range registeredDirections() {
+		responses := readFixtures(t, filepath.Join(goldenDir, "inputs", "responses", dir.upstream))
+		if len(responses) == 0 {
+			t.Errorf("no response fixtures for upstream format %s", dir.upstream)
+			continue
+		}
+		// Responses are translated in the context of the client's basic request.
+		original, err := os.ReadFile(filepath.Join(goldenDir, "inputs", "requests", dir.client, "basic.json"))
+		if err != nil {
+			t.Errorf("read basic request of %s: %v", dir.client, err)
+			continue
+		}
+		client, upstream := sdktranslator.FromString(dir.client), sdktranslator.FromString(dir.upstream)
+		translated := sdktranslator.TranslateRequest(client, upstream, goldenModel, bytes.Clone(original), false)
+		for _, response := range responses {
+			t.Run(dir.upstream+"-"+dir.client+"/"+response.name, func(t *testing.T) {
+				var param any
+				out := sdktranslator.TranslateNonStream(context.Background(), upstream, client, goldenModel, original, translated, bytes.Clone(response.data), &param)
+				checkGolden(t, filepath.Join(goldenDir, "expected", "responses", dir.upstream+"-"+dir.client, response.name+".json"), []byte(out), response.data)
+			})
+		}
+	}
+}
+
+// checkGolden compares out with the golden file at path, or rewrites the file with -update.
+// Values generated by the translator are normalized against input first.
+func checkGolden(t *testing.T, path string, out, input []byte) {
+	t.Helper()
+	got := normalizeGenerated(formatGolden(out), input)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run with -update to accept it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// formatGolden indents JSON output, keeping its key order, and stores anything else as a JSON
+// string.
+func formatGolden(out []byte) []byte {
+	var buf bytes.Buffer
+	if len(bytes.TrimSpace(out)) > 0 && json.Valid(out) {
+		_ = json.Indent(&buf, bytes.TrimSpace(out), "", "  ")
+	} else {
+		quoted, _ := json.Marshal(string(out))
+		buf.Write(quoted)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+var (
+	generatedIDPattern   = regexp.MustCompile(`"(id|call_id|tool_call_id|tool_use_id|user_id)": "([^"]+)"`)
+	generatedTimePattern = regexp.MustCompile(`"(created|created_at)": ([0-9]+)`)
+)
+
+// normalizeGenerated replaces the identifiers and timestamps that the translators generate, i.e.
+// those not copied from input. Each distinct identifier becomes <id-N> in order of appearance so
+// references between tool calls and their results can still be checked; timestamps become 0.
+func normalizeGenerated(formatted, input []byte) []byte {
+	ids := make(map[string]string)
+	formatted = generatedIDPattern.ReplaceAllFunc(formatted, func(match []byte) []byte {
+		parts := generatedIDPattern.FindSubmatch(match)
+		value := string(parts[2])
+		if bytes.Contains(input, parts[2]) {
+			return match
+		}
+		placeholder, ok := ids[value]
+		if !ok {
+			placeholder = "<id-" + strconv.Itoa(len(ids)+1) + ">"
+			ids[value] = placeholder
+		}
+		return []byte(`"` + string(parts[1]) + `": "` + placeholder + `"`)
+	})
+	return generatedTimePattern.ReplaceAllFunc(formatted, func(match []byte) []byte {
+		parts := generatedTimePattern.FindSubmatch(match)
+		if bytes.Contains(input, parts[2]) {
+			return match
+		}
+		return []byte(`"` + string(parts[1]) + `": 0`)
+	})
+}
+
+// malformedSeeds are structurally wrong payloads every translator has to survive.
+var malformedSeeds = []string{
+	``,
+	`{`,
+	`null`,
+	`[]`,
+	`{"messages":"hello"}`,
+	`{"messages":[null,{"role":"user","content":{"type":"text"}}]}`,
+	`{"messages":[{"role":"user","content":[{"type":"tool_result","content":[{"type":"image"},null,7]}]}]}`,
+	`{"messages":[{"role":"assistant","tool_calls":[{"function":null}]}]}`,
+	`{"input":[{"type":"function_call_output"}],"tools":[{}]}`,
+	`{"contents":[{"parts":null},{"role":"model","parts":[{"functionCall":{}}]}]}`,
+	`{"request":{"contents":"x"}}`,
+	`{"candidates":[{"content":{"parts":[{"functionCall":null}]}}]}`,
+	`{"type":"response.completed","response":{"output":[{"type":"function_call"}]}}`,
+	`{"content":[{"type":"tool_use","input":"x"}],"usage":null}`,
+}
+
+// FuzzTranslateRequest checks that no request translator panics on malformed input. The
+// direction argument picks the translator, so each execution exercises one of them.
+func FuzzTranslateRequest(f *testing.F) {
+	directions := registeredDirections()
+	for i, dir := range directions {
+		for _, input := range readFixtures(f, filepath.Join(goldenDir, "inputs", "requests", dir.client)) {
+			f.Add(input.data, false, uint8(i))
+		}
+		for _, seed := range malformedSeeds {
+			f.Add([]byte(seed), true, uint8(i))
+		}
+	}
+	quietLogs(f)
+	f.Fuzz(func(t *testing.T, data []byte, stream bool, direction uint8) {
+		dir := directions[int(direction)%len(directions)]
+		sdktranslator.TranslateRequest(sdktranslator.FromString(dir.client), sdktranslator.FromString(dir.upstream), goldenModel, data, stream)
+	})
+}
+
+// FuzzTranslateResponse checks that no response translator panics on malformed upstream
+// responses, streamed or not.
+func FuzzTranslateResponse(f *testing.F) {
+	directions := registeredDirections()
+	for i, dir := range directions {
+		for _, response := range readFixtures(f, filepath.Join(goldenDir, "inputs", "responses", dir.upstream)) {
+			f.Add(response.data, uint8(i))
+		}
+		for _, seed := range malformedSeeds {
+			f.Add([]byte(seed), uint8(i))
+		}
+	}
+	quietLogs(f)
+	f.Fuzz(func(t *testing.T, data []byte, direction uint8) {
+		dir := directions[int(direction)%len(directions)]
+		client, upstream := sdktranslator.FromString(dir.client), sdktranslator.FromString(dir.upstream)
+		var param any
+		sdktranslator.TranslateNonStream(context.Background(), upstream, client, goldenModel, nil, nil, bytes.Clone(data), &param)
+		param = nil
+		sdktranslator.TranslateStream(context.Background(), upstream, client, goldenModel, nil, nil, append([]byte("data: "), data...), &param)
+		sdktranslator.TranslateStream(context.Background(), upstream, client, goldenModel, nil, nil, bytes.Clone(data), &param)
+	})
+}
+
+// quietLogs discards the warnings translators log about malformed input while fuzzing.
+func quietLogs(f *testing.F) {
+	out := log.StandardLogger().Out
+	log.SetOutput(io.Discard)
+	f.Cleanup(func() { log.SetOutput(out) })
+}
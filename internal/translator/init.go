@@ -1,6 +1,9 @@
 package translator
 
 import (
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/claude/gemini"
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/claude/gemini-cli"
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/claude/openai/chat-completions"
@@ -35,5 +38,12 @@ import (
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/antigravity/openai/responses"
 
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/kiro/claude"
+	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/kiro/gemini"
 	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/kiro/openai"
 )
+
+func init() {
+	sdktranslator.SetRequestGate(func(from sdktranslator.Format, model string, rawJSON []byte) []byte {
+		return util.GateRequestFeatures(from.String(), model, rawJSON)
+	})
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/google/uuid"
 	kirocommon "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/kiro/common"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
 )
@@ -634,9 +635,14 @@ func processMessages(messages gjson.Result, modelID, origin string) ([]KiroHisto
 				history = append(history, KiroHistoryMessage{
 					AssistantResponseMessage: &assistantMsg,
 				})
-				// Create a "Continue" user message as currentMessage
+				// Create a "Continue" user message as currentMessage. A trailing assistant text is a
+				// response prefill, which Kiro cannot continue natively, so ask for the continuation.
+				content := "Continue"
+				if len(assistantMsg.ToolUses) == 0 && strings.TrimSpace(assistantMsg.Content) != "" {
+					content = util.PrefillContinueInstruction
+				}
 				currentUserMsg = &KiroUserInputMessage{
-					Content: "Continue",
+					Content: content,
 					ModelID: modelID,
 					Origin:  origin,
 				}
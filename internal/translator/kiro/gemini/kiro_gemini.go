@@ -0,0 +1,82 @@
+// Package gemini provides translation between the Gemini native API and Kiro formats.
+// The Kiro executor builds its upstream payload from Claude-format requests and emits
+// Claude-compatible SSE events, so this package reuses the Gemini <-> Claude translators.
+package gemini
+
+import (
+	"bytes"
+	"context"
+
+	claudegemini "github.com/router-for-me/CLIProxyAPI/v6/internal/translator/claude/gemini"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ConvertGeminiRequestToKiro converts a Gemini generateContent request into the Claude
+// format consumed by the Kiro payload builder.
+func ConvertGeminiRequestToKiro(modelName string, inputRawJSON []byte, stream bool) []byte {
+	return claudegemini.ConvertGeminiRequestToClaude(modelName, inputRawJSON, stream)
+}
+
+// ConvertKiroStreamToGemini converts a Kiro streaming event to Gemini streamGenerateContent chunks.
+// Kiro events carry an "event:" line before the data line, which the Claude translator does not expect.
+func ConvertKiroStreamToGemini(ctx context.Context, model string, originalRequest, request, rawResponse []byte, param *any) []string {
+	var data []byte
+	for _, line := range bytes.Split(rawResponse, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, []byte("data:")) {
+			data = line
+			break
+		}
+	}
+	if data == nil {
+		return []string{}
+	}
+	return claudegemini.ConvertClaudeResponseToGemini(ctx, model, originalRequest, request, data, param)
+}
+
+// ConvertKiroNonStreamToGemini converts a Kiro non-streaming response, which is a complete
+// Claude message, to a Gemini generateContent response.
+func ConvertKiroNonStreamToGemini(_ context.Context, model string, _, _, rawResponse []byte, _ *any) string {
+	response := gjson.ParseBytes(rawResponse)
+
+	out := `{"candidates":[{"content":{"role":"model","parts":[]},"finishReason":"STOP"}],"usageMetadata":{},"modelVersion":"","responseId":""}`
+	out, _ = sjson.Set(out, "modelVersion", model)
+	out, _ = sjson.Set(out, "responseId", response.Get("id").String())
+
+	response.Get("content").ForEach(func(_, block gjson.Result) bool {
+		switch block.Get("type").String() {
+		case "text":
+			if text := block.Get("text").String(); text != "" {
+				part := `{"text":""}`
+				part, _ = sjson.Set(part, "text", text)
+				out, _ = sjson.SetRaw(out, "candidates.0.content.parts.-1", part)
+			}
+		case "thinking":
+			if thinking := block.Get("thinking").String(); thinking != "" {
+				part := `{"thought":true,"text":""}`
+				part, _ = sjson.Set(part, "text", thinking)
+				out, _ = sjson.SetRaw(out, "candidates.0.content.parts.-1", part)
+			}
+		case "tool_use":
+			part := `{"functionCall":{"name":"","args":{}}}`
+			part, _ = sjson.Set(part, "functionCall.name", block.Get("name").String())
+			if input := block.Get("input"); input.IsObject() {
+				part, _ = sjson.SetRaw(part, "functionCall.args", input.Raw)
+			}
+			out, _ = sjson.SetRaw(out, "candidates.0.content.parts.-1", part)
+		}
+		return true
+	})
+
+	if response.Get("stop_reason").String() == "max_tokens" {
+		out, _ = sjson.Set(out, "candidates.0.finishReason", "MAX_TOKENS")
+	}
+
+	inputTokens := response.Get("usage.input_tokens").Int()
+	outputTokens := response.Get("usage.output_tokens").Int()
+	out, _ = sjson.Set(out, "usageMetadata.promptTokenCount", inputTokens)
+	out, _ = sjson.Set(out, "usageMetadata.candidatesTokenCount", outputTokens)
+	out, _ = sjson.Set(out, "usageMetadata.totalTokenCount", inputTokens+outputTokens)
+	return out
+}
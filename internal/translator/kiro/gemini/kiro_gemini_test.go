@@ -0,0 +1,43 @@
+package gemini
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertKiroStreamToGemini(t *testing.T) {
+	var param any
+	events := []string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"model\":\"claude-sonnet-4-5\"}}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}",
+	}
+	var chunks []string
+	for _, event := range events {
+		chunks = append(chunks, ConvertKiroStreamToGemini(context.Background(), "claude-sonnet-4-5", nil, nil, []byte(event), &param)...)
+	}
+	if len(chunks) != 1 {
+		t.Fatalf("expected one chunk, got %d: %v", len(chunks), chunks)
+	}
+	if got := gjson.Get(chunks[0], "candidates.0.content.parts.0.text").String(); got != "Hello" {
+		t.Fatalf("unexpected text %q in %s", got, chunks[0])
+	}
+}
+
+func TestConvertKiroNonStreamToGemini(t *testing.T) {
+	raw := []byte(`{"id":"msg_1","content":[{"type":"thinking","thinking":"plan"},{"type":"text","text":"done"},{"type":"tool_use","id":"t1","name":"lookup","input":{"q":"x"}}],"stop_reason":"tool_use","usage":{"input_tokens":3,"output_tokens":4}}`)
+	out := ConvertKiroNonStreamToGemini(context.Background(), "claude-sonnet-4-5", nil, nil, raw, nil)
+
+	parts := gjson.Get(out, "candidates.0.content.parts").Array()
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %s", out)
+	}
+	if !parts[0].Get("thought").Bool() || parts[1].Get("text").String() != "done" || parts[2].Get("functionCall.args.q").String() != "x" {
+		t.Fatalf("unexpected parts: %s", out)
+	}
+	if got := gjson.Get(out, "usageMetadata.totalTokenCount").Int(); got != 7 {
+		t.Fatalf("expected total tokens 7, got %d", got)
+	}
+}
@@ -341,7 +341,7 @@ func extractSystemPromptFromOpenAI(messages gjson.Result) string {
 
 	var systemParts []string
 	for _, msg := range messages.Array() {
-		if msg.Get("role").String() == "system" {
+		if role := msg.Get("role").String(); role == "system" || role == "developer" {
 			content := msg.Get("content")
 			if content.Type == gjson.String {
 				systemParts = append(systemParts, content.String())
@@ -475,7 +475,7 @@ func processOpenAIMessages(messages gjson.Result, modelID, origin string) ([]Kir
 		isLastMessage := i == len(messagesArray)-1
 
 		switch role {
-		case "system":
+		case "system", "developer":
 			// System messages are handled separately via extractSystemPromptFromOpenAI
 			continue
 
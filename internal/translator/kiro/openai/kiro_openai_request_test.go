@@ -3,6 +3,8 @@ package openai
 import (
 	"encoding/json"
 	"testing"
+
+	"github.com/tidwall/gjson"
 )
 
 // TestToolResultsAttachedToCurrentMessage verifies that tool results from "tool" role messages
@@ -384,3 +386,18 @@ func TestAssistantEndsConversation(t *testing.T) {
 		t.Error("Expected a 'Continue' message to be created when assistant is last")
 	}
 }
+
+// TestDeveloperRoleTreatedAsSystem verifies that "developer" messages sent by newer OpenAI
+// clients contribute to the system prompt in message order alongside "system" messages.
+func TestDeveloperRoleTreatedAsSystem(t *testing.T) {
+	raw := []byte(`[
+		{"role": "system", "content": "You are helpful."},
+		{"role": "developer", "content": [{"type": "text", "text": "Answer in French."}]},
+		{"role": "user", "content": "Hello"}
+	]`)
+
+	got := extractSystemPromptFromOpenAI(gjson.ParseBytes(raw))
+	if got != "You are helpful.\nAnswer in French." {
+		t.Fatalf("unexpected system prompt %q", got)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -257,6 +258,14 @@ func ConvertClaudeRequestToOpenAI(modelName string, inputRawJSON []byte, stream
 		})
 	}
 
+	// A trailing assistant message prefills the response. Chat Completions backends answer it as a
+	// finished turn, so the continuation is requested explicitly.
+	if _, ok := util.ClaudePrefill(rawJSON); ok {
+		instruction := `{"role":"user","content":""}`
+		instruction, _ = sjson.Set(instruction, "content", util.PrefillContinueInstruction)
+		messagesJSON, _ = sjson.SetRaw(messagesJSON, "-1", instruction)
+	}
+
 	// Set messages
 	if gjson.Parse(messagesJSON).IsArray() && len(gjson.Parse(messagesJSON).Array()) > 0 {
 		out, _ = sjson.SetRaw(out, "messages", messagesJSON)
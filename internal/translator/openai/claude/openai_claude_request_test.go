@@ -3,6 +3,7 @@ package claude
 import (
 	"testing"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	"github.com/tidwall/gjson"
 )
 
@@ -190,10 +191,10 @@ func TestConvertClaudeRequestToOpenAI_ThinkingToReasoningContent(t *testing.T) {
 				return
 			}
 
-			// Check the last non-system message
+			// Check the last non-system message, skipping the prefill continuation turn
 			var targetMsg gjson.Result
 			for i := len(messages) - 1; i >= 0; i-- {
-				if messages[i].Get("role").String() != "system" {
+				if messages[i].Get("role").String() != "system" && messages[i].Get("content").String() != util.PrefillContinueInstruction {
 					targetMsg = messages[i]
 					break
 				}
@@ -588,3 +589,39 @@ func TestConvertClaudeRequestToOpenAI_AssistantThinkingToolUseThinkingSplit(t *t
 		t.Fatalf("Expected reasoning_content %q, got %q", "t1\n\nt2", got)
 	}
 }
+
+func TestConvertClaudeRequestToOpenAI_PrefillWithTools(t *testing.T) {
+	input := []byte(`{
+		"model": "claude-3-opus",
+		"tools": [{"name": "get_weather", "description": "Weather", "input_schema": {"type": "object"}}],
+		"messages": [
+			{"role": "user", "content": "What is the weather in Paris? Answer in JSON."},
+			{"role": "assistant", "content": [{"type": "text", "text": "{\"city\": \"Paris\","}]}
+		]
+	}`)
+
+	out := ConvertClaudeRequestToOpenAI("gpt-4o", input, false)
+	messages := gjson.GetBytes(out, "messages").Array()
+	if len(messages) != 3 {
+		t.Fatalf("messages = %d, want 3: %s", len(messages), out)
+	}
+	if messages[1].Get("role").String() != "assistant" || messages[1].Get("content.0.text").String() != `{"city": "Paris",` {
+		t.Errorf("prefill message = %s", messages[1].Raw)
+	}
+	if messages[2].Get("role").String() != "user" || messages[2].Get("content").String() != util.PrefillContinueInstruction {
+		t.Errorf("continuation message = %s", messages[2].Raw)
+	}
+	if gjson.GetBytes(out, "tools.0.function.name").String() != "get_weather" {
+		t.Errorf("tools not preserved: %s", out)
+	}
+
+	// A trailing tool call is a regular turn, not a prefill.
+	toolTurn := []byte(`{"messages":[
+		{"role":"user","content":"Weather?"},
+		{"role":"assistant","content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"t1","name":"get_weather","input":{}}]}
+	]}`)
+	out = ConvertClaudeRequestToOpenAI("gpt-4o", toolTurn, false)
+	if last := gjson.GetBytes(out, "messages.@reverse.0.role").String(); last != "assistant" {
+		t.Errorf("last role = %q, want assistant", last)
+	}
+}
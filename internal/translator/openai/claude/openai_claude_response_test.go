@@ -0,0 +1,50 @@
+package claude
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertOpenAIResponseToClaude_ReasoningFieldAlias(t *testing.T) {
+	request := []byte(`{"model":"claude-sonnet-4","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	chunks := []string{
+		`data: {"id":"gen-1","model":"vendor/model","choices":[{"index":0,"delta":{"role":"assistant","reasoning":"weigh options"}}]}`,
+		`data: {"id":"gen-1","model":"vendor/model","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":1}"}}]}}]}`,
+		`data: {"id":"gen-1","model":"vendor/model","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`data: [DONE]`,
+	}
+
+	var param any
+	var events []string
+	for _, chunk := range chunks {
+		events = append(events, ConvertOpenAIResponseToClaude(context.Background(), "", request, nil, []byte(chunk), &param)...)
+	}
+	stream := strings.Join(events, "")
+	for _, want := range []string{`"type":"thinking_delta","thinking":"weigh options"`, `"type":"tool_use","id":"call_1","name":"lookup"`, `"stop_reason":"tool_use"`} {
+		if !strings.Contains(stream, want) {
+			t.Fatalf("stream missing %s:\n%s", want, stream)
+		}
+	}
+}
+
+func TestConvertOpenAIResponseToClaudeNonStream_ReasoningFieldAlias(t *testing.T) {
+	raw := []byte(`{"id":"gen-1","model":"vendor/model","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","reasoning":"weigh options","content":"Checking.","tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":1}"}}]}}],"usage":{"prompt_tokens":3,"completion_tokens":4}}`)
+
+	out := gjson.Parse(ConvertOpenAIResponseToClaudeNonStream(context.Background(), "", nil, nil, raw, nil))
+	content := out.Get("content").Array()
+	if len(content) != 3 {
+		t.Fatalf("content = %s", out.Get("content").Raw)
+	}
+	if content[0].Get("type").String() != "thinking" || content[0].Get("thinking").String() != "weigh options" {
+		t.Fatalf("thinking block = %s", content[0].Raw)
+	}
+	if content[2].Get("type").String() != "tool_use" || content[2].Get("input.q").Int() != 1 {
+		t.Fatalf("tool_use block = %s", content[2].Raw)
+	}
+	if out.Get("stop_reason").String() != "tool_use" {
+		t.Fatalf("stop_reason = %s", out.Get("stop_reason").String())
+	}
+}
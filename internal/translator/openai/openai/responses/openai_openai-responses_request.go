@@ -68,6 +68,10 @@ func ConvertOpenAIResponsesRequestToOpenAIChatCompletions(modelName string, inpu
 			case "message", "":
 				// Handle regular message conversion
 				role := item.Get("role").String()
+				if role == "developer" {
+					// Most OpenAI-compatible upstreams only understand "system".
+					role = "system"
+				}
 				message := `{"role":"","content":""}`
 				message, _ = sjson.Set(message, "role", role)
 
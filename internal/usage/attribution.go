@@ -0,0 +1,161 @@
+package usage
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+const (
+	// AttributionKey records usage under the raw client API key (legacy behaviour).
+	AttributionKey = "key"
+	// AttributionHash records usage under a truncated SHA-256 digest of the client API key.
+	AttributionHash = "hash"
+	// AttributionProvider records usage under the access provider that authenticated the key.
+	AttributionProvider = "provider"
+)
+
+// attributionState holds the active attribution mode and the lookup table used to
+// map raw client keys to their owning access provider.
+type attributionState struct {
+	mode         string
+	keyProviders map[string]string
+}
+
+var attribution atomic.Pointer[attributionState]
+
+func init() {
+	attribution.Store(&attributionState{mode: AttributionKey})
+}
+
+// NormalizeAttributionMode returns the canonical attribution mode for the supplied value.
+// Unknown or empty values fall back to AttributionKey.
+func NormalizeAttributionMode(mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case AttributionHash, "hashed", "sha256":
+		return AttributionHash
+	case AttributionProvider, "label", "tenant":
+		return AttributionProvider
+	default:
+		return AttributionKey
+	}
+}
+
+// ConfigureAttribution applies the usage attribution settings from cfg.
+// Existing statistics recorded under raw client keys are re-keyed to the new mode.
+func ConfigureAttribution(cfg *config.Config) {
+	state := &attributionState{mode: AttributionKey, keyProviders: make(map[string]string)}
+	if cfg != nil {
+		state.mode = NormalizeAttributionMode(cfg.UsageAttribution)
+		for _, key := range cfg.APIKeys {
+			if key = strings.TrimSpace(key); key != "" {
+				state.keyProviders[key] = config.DefaultAccessProviderName
+			}
+		}
+		for i := range cfg.Access.Providers {
+			provider := &cfg.Access.Providers[i]
+			name := strings.TrimSpace(provider.Name)
+			if name == "" {
+				name = config.DefaultAccessProviderName
+			}
+			for _, key := range provider.APIKeys {
+				if key = strings.TrimSpace(key); key != "" {
+					state.keyProviders[key] = name
+				}
+			}
+		}
+	}
+	attribution.Store(state)
+	defaultRequestStatistics.migrateAttribution()
+}
+
+// AttributionMode reports the active attribution mode.
+func AttributionMode() string { return attribution.Load().mode }
+
+// attributeAPIKey converts a raw client API key into the identifier stored in statistics.
+// accessProvider is the provider name resolved during authentication, when known.
+func attributeAPIKey(apiKey, accessProvider string) string {
+	state := attribution.Load()
+	switch state.mode {
+	case AttributionHash:
+		return util.HashAPIKey(apiKey)
+	case AttributionProvider:
+		if accessProvider != "" {
+			return accessProvider
+		}
+		if name, ok := state.keyProviders[apiKey]; ok {
+			return name
+		}
+		return util.HashAPIKey(apiKey)
+	default:
+		return apiKey
+	}
+}
+
+// attributeStoredName re-attributes an existing statistics key when it is a known raw
+// client API key. Other identifiers (e.g. request paths) are returned unchanged.
+func attributeStoredName(apiName string) string {
+	state := attribution.Load()
+	if state.mode == AttributionKey {
+		return apiName
+	}
+	if _, isKey := state.keyProviders[apiName]; !isKey {
+		return apiName
+	}
+	return attributeAPIKey(apiName, "")
+}
+
+// migrateAttribution re-keys statistics that were stored under a raw client API key
+// so that switching modes does not leave plaintext secrets in the store.
+func (s *RequestStatistics) migrateAttribution() {
+	if s == nil {
+		return
+	}
+	if state := attribution.Load(); state.mode == AttributionKey || len(state.keyProviders) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for apiName, stats := range s.apis {
+		target := attributeStoredName(apiName)
+		if target == apiName {
+			continue
+		}
+		delete(s.apis, apiName)
+		existing, ok := s.apis[target]
+		if !ok || existing == nil {
+			s.apis[target] = stats
+			continue
+		}
+		mergeAPIStats(existing, stats)
+	}
+}
+
+// mergeAPIStats folds src into dst, preserving per-model request details.
+func mergeAPIStats(dst, src *apiStats) {
+	if src == nil {
+		return
+	}
+	if dst.Models == nil {
+		dst.Models = make(map[string]*modelStats)
+	}
+	dst.TotalRequests += src.TotalRequests
+	dst.TotalTokens += src.TotalTokens
+	for modelName, srcModel := range src.Models {
+		if srcModel == nil {
+			continue
+		}
+		dstModel, ok := dst.Models[modelName]
+		if !ok || dstModel == nil {
+			dst.Models[modelName] = srcModel
+			continue
+		}
+		dstModel.TotalRequests += srcModel.TotalRequests
+		dstModel.TotalTokens += srcModel.TotalTokens
+		dstModel.Details = append(dstModel.Details, srcModel.Details...)
+	}
+}
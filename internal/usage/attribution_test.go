@@ -0,0 +1,60 @@
+package usage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestConfigureAttribution_HashMigratesRawKeys(t *testing.T) {
+	t.Cleanup(func() { ConfigureAttribution(nil) })
+	ConfigureAttribution(nil)
+
+	stats := defaultRequestStatistics
+	stats.mu.Lock()
+	delete(stats.apis, "sk-secret")
+	stats.mu.Unlock()
+
+	stats.Record(context.Background(), coreusage.Record{APIKey: "sk-secret", Model: "m", Detail: coreusage.Detail{TotalTokens: 3}})
+	if _, ok := stats.Snapshot().APIs["sk-secret"]; !ok {
+		t.Fatalf("expected raw key attribution before migration")
+	}
+
+	cfg := &config.Config{UsageAttribution: "hash"}
+	cfg.APIKeys = []string{"sk-secret"}
+	ConfigureAttribution(cfg)
+
+	snapshot := stats.Snapshot()
+	if _, ok := snapshot.APIs["sk-secret"]; ok {
+		t.Fatalf("raw key still present after switching to hash attribution")
+	}
+	hashed := util.HashAPIKey("sk-secret")
+	if !strings.HasPrefix(hashed, util.HashedAPIKeyPrefix) {
+		t.Fatalf("unexpected hashed identifier %q", hashed)
+	}
+	if got := snapshot.APIs[hashed].TotalTokens; got != 3 {
+		t.Fatalf("expected migrated tokens 3, got %d", got)
+	}
+}
+
+func TestAttributeAPIKey_ProviderMode(t *testing.T) {
+	t.Cleanup(func() { ConfigureAttribution(nil) })
+
+	cfg := &config.Config{UsageAttribution: "provider"}
+	cfg.Access.Providers = []config.AccessProvider{{Name: "team-a", Type: config.AccessProviderTypeConfigAPIKey, APIKeys: []string{"k1"}}}
+	ConfigureAttribution(cfg)
+
+	if got := attributeAPIKey("k1", ""); got != "team-a" {
+		t.Fatalf("expected team-a, got %q", got)
+	}
+	if got := attributeAPIKey("k1", "resolved"); got != "resolved" {
+		t.Fatalf("expected access provider from context to win, got %q", got)
+	}
+	if got := attributeAPIKey("unknown", ""); !strings.HasPrefix(got, util.HashedAPIKeyPrefix) {
+		t.Fatalf("expected unknown key to be hashed, got %q", got)
+	}
+}
@@ -0,0 +1,64 @@
+package usage
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxInvoiceDays bounds the date range of a single invoice.
+const maxInvoiceDays = 366
+
+// Invoice totals the usage of one tenant over a date range, split by model. Costs use the
+// model pricing in effect when each day's aggregates were computed.
+type Invoice struct {
+	Tenant string                 `json:"tenant"`
+	From   string                 `json:"from"`
+	To     string                 `json:"to"`
+	Totals UsageTotals            `json:"totals"`
+	Models map[string]UsageTotals `json:"models"`
+	Days   map[string]UsageTotals `json:"days"`
+}
+
+// TenantInvoice builds the invoice of tenantID for the days from to to (YYYY-MM-DD, inclusive).
+// Past days come from the snapshot store when available, so invoices survive restarts once
+// usage snapshots are enabled.
+func TenantInvoice(store *SnapshotStore, stats *RequestStatistics, tenantID, from, to string) (Invoice, error) {
+	start, err := time.Parse(snapshotDateLayout, from)
+	if err != nil {
+		return Invoice{}, fmt.Errorf("invalid from %q, expected YYYY-MM-DD", from)
+	}
+	end, err := time.Parse(snapshotDateLayout, to)
+	if err != nil {
+		return Invoice{}, fmt.Errorf("invalid to %q, expected YYYY-MM-DD", to)
+	}
+	if end.Before(start) {
+		return Invoice{}, fmt.Errorf("to must not be before from")
+	}
+	if end.Sub(start) >= maxInvoiceDays*24*time.Hour {
+		return Invoice{}, fmt.Errorf("date range exceeds %d days", maxInvoiceDays)
+	}
+	invoice := Invoice{
+		Tenant: tenantID,
+		From:   from,
+		To:     to,
+		Models: make(map[string]UsageTotals),
+		Days:   make(map[string]UsageTotals),
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format(snapshotDateLayout)
+		snapshot, errSnapshot := SnapshotForDate(store, stats, date)
+		if errSnapshot != nil {
+			return Invoice{}, errSnapshot
+		}
+		var dayTotals UsageTotals
+		for model, totals := range snapshot.Tenants[tenantID] {
+			invoice.Models[model] = invoice.Models[model].plus(totals)
+			dayTotals = dayTotals.plus(totals)
+		}
+		if dayTotals.Requests > 0 {
+			invoice.Days[date] = dayTotals
+			invoice.Totals = invoice.Totals.plus(dayTotals)
+		}
+	}
+	return invoice, nil
+}
@@ -91,9 +91,22 @@ type modelStats struct {
 type RequestDetail struct {
 	Timestamp time.Time  `json:"timestamp"`
 	Source    string     `json:"source"`
+	Provider  string     `json:"provider,omitempty"`
 	AuthIndex string     `json:"auth_index"`
 	Tokens    TokenStats `json:"tokens"`
 	Failed    bool       `json:"failed"`
+	// Tags holds selected client request metadata, e.g. metadata.user_id.
+	Tags map[string]string `json:"tags,omitempty"`
+	// Region is the upstream region that served the request, e.g. a Vertex AI location.
+	Region string `json:"region,omitempty"`
+	// Workload is the request's workload label, e.g. "chat", "code" or "agent".
+	Workload string `json:"workload,omitempty"`
+	// Tenant is the id of the tenant whose API key made the request.
+	Tenant string `json:"tenant,omitempty"`
+	// ClientIP is the address of the client that made the request.
+	ClientIP string `json:"client_ip,omitempty"`
+	// Estimated marks token counts estimated locally because the upstream reported none.
+	Estimated bool `json:"estimated,omitempty"`
 }
 
 // TokenStats captures the token usage breakdown for a request.
@@ -118,6 +131,9 @@ type StatisticsSnapshot struct {
 	RequestsByHour map[string]int64 `json:"requests_by_hour"`
 	TokensByDay    map[string]int64 `json:"tokens_by_day"`
 	TokensByHour   map[string]int64 `json:"tokens_by_hour"`
+
+	// Workloads splits requests and tokens by workload label.
+	Workloads map[string]UsageTotals `json:"workloads,omitempty"`
 }
 
 // APISnapshot summarises metrics for a single API key.
@@ -165,7 +181,9 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 	detail := normaliseDetail(record.Detail)
 	totalTokens := detail.TotalTokens
 	statsKey := record.APIKey
-	if statsKey == "" {
+	if statsKey != "" {
+		statsKey = attributeAPIKey(statsKey, resolveAccessProvider(ctx))
+	} else {
 		statsKey = resolveAPIIdentifier(ctx, record)
 	}
 	failed := record.Failed
@@ -199,9 +217,16 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 	s.updateAPIStats(stats, modelName, RequestDetail{
 		Timestamp: timestamp,
 		Source:    record.Source,
+		Provider:  record.Provider,
 		AuthIndex: record.AuthIndex,
 		Tokens:    detail,
 		Failed:    failed,
+		Tags:      record.Tags,
+		Region:    record.Region,
+		Workload:  record.Workload,
+		Tenant:    record.Tenant,
+		ClientIP:  record.ClientIP,
+		Estimated: record.Estimated,
 	})
 
 	s.requestsByDay[dayKey]++
@@ -239,6 +264,7 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 	result.TotalTokens = s.totalTokens
 
 	result.APIs = make(map[string]APISnapshot, len(s.apis))
+	result.Workloads = make(map[string]UsageTotals)
 	for apiName, stats := range s.apis {
 		apiSnapshot := APISnapshot{
 			TotalRequests: stats.TotalRequests,
@@ -248,6 +274,9 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 		for modelName, modelStatsValue := range stats.Models {
 			requestDetails := make([]RequestDetail, len(modelStatsValue.Details))
 			copy(requestDetails, modelStatsValue.Details)
+			for _, detail := range requestDetails {
+				addTotals(result.Workloads, workloadKey(detail), detail, detailCost(modelName, detail))
+			}
 			apiSnapshot.Models[modelName] = ModelSnapshot{
 				TotalRequests: modelStatsValue.TotalRequests,
 				TotalTokens:   modelStatsValue.TotalTokens,
@@ -314,7 +343,7 @@ func (s *RequestStatistics) MergeSnapshot(snapshot StatisticsSnapshot) MergeResu
 	}
 
 	for apiName, apiSnapshot := range snapshot.APIs {
-		apiName = strings.TrimSpace(apiName)
+		apiName = attributeStoredName(strings.TrimSpace(apiName))
 		if apiName == "" {
 			continue
 		}
@@ -419,6 +448,17 @@ func resolveAPIIdentifier(ctx context.Context, record coreusage.Record) string {
 	return "unknown"
 }
 
+func resolveAccessProvider(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return ""
+	}
+	return ginCtx.GetString("accessProvider")
+}
+
 func resolveSuccess(ctx context.Context) bool {
 	if ctx == nil {
 		return true
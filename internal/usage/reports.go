@@ -0,0 +1,319 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ReportFormatJSON renders reports as JSON.
+	ReportFormatJSON = "json"
+	// ReportFormatCSV renders reports as CSV with one row per dimension value.
+	ReportFormatCSV = "csv"
+
+	reportScheduleDaily  = "daily"
+	reportScheduleWeekly = "weekly"
+
+	defaultReportDir    = "usage-reports"
+	reportStateFile     = ".last-report"
+	reportCheckInterval = time.Hour
+	reportSendTimeout   = time.Minute
+	maxReportDays       = 366
+)
+
+// Report summarises usage over a date range per API key, model and provider.
+type Report struct {
+	From        string                 `json:"from"`
+	To          string                 `json:"to"`
+	GeneratedAt time.Time              `json:"generated_at"`
+	Totals      UsageTotals            `json:"totals"`
+	APIs        map[string]UsageTotals `json:"apis"`
+	Models      map[string]UsageTotals `json:"models"`
+	Providers   map[string]UsageTotals `json:"providers"`
+}
+
+// BuildReport sums the daily snapshots from from to to (YYYY-MM-DD, inclusive). Past days are
+// read from store when available and today comes from live statistics.
+func BuildReport(store *SnapshotStore, stats *RequestStatistics, from, to string) (Report, error) {
+	start, err := time.Parse(snapshotDateLayout, from)
+	if err != nil {
+		return Report{}, fmt.Errorf("invalid from %q, expected YYYY-MM-DD", from)
+	}
+	end, err := time.Parse(snapshotDateLayout, to)
+	if err != nil {
+		return Report{}, fmt.Errorf("invalid to %q, expected YYYY-MM-DD", to)
+	}
+	if end.Before(start) {
+		return Report{}, fmt.Errorf("to must not be before from")
+	}
+	if end.Sub(start) >= maxReportDays*24*time.Hour {
+		return Report{}, fmt.Errorf("date range exceeds %d days", maxReportDays)
+	}
+	report := Report{
+		From:        from,
+		To:          to,
+		GeneratedAt: time.Now().UTC(),
+		APIs:        make(map[string]UsageTotals),
+		Models:      make(map[string]UsageTotals),
+		Providers:   make(map[string]UsageTotals),
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		snapshot, errSnapshot := SnapshotForDate(store, stats, day.Format(snapshotDateLayout))
+		if errSnapshot != nil {
+			return Report{}, errSnapshot
+		}
+		report.Totals = report.Totals.plus(snapshot.Totals)
+		mergeTotals(report.APIs, snapshot.APIs)
+		mergeTotals(report.Models, snapshot.Models)
+		mergeTotals(report.Providers, snapshot.Providers)
+	}
+	return report, nil
+}
+
+func mergeTotals(dst, src map[string]UsageTotals) {
+	for key, totals := range src {
+		dst[key] = dst[key].plus(totals)
+	}
+}
+
+// CSV renders the report with one row per API key, model and provider, preceded by the totals.
+func (r Report) CSV() []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"from", "to", "dimension", "name", "requests", "failures", "input_tokens", "output_tokens", "total_tokens", "cost_usd"})
+	row := func(dimension, name string, t UsageTotals) {
+		_ = w.Write([]string{
+			r.From, r.To, dimension, name,
+			strconv.FormatInt(t.Requests, 10),
+			strconv.FormatInt(t.Failures, 10),
+			strconv.FormatInt(t.InputTokens, 10),
+			strconv.FormatInt(t.OutputTokens, 10),
+			strconv.FormatInt(t.TotalTokens, 10),
+			strconv.FormatFloat(t.Cost, 'f', 6, 64),
+		})
+	}
+	row("total", "", r.Totals)
+	for _, dim := range []struct {
+		name   string
+		values map[string]UsageTotals
+	}{{"api", r.APIs}, {"model", r.Models}, {"provider", r.Providers}} {
+		names := make([]string, 0, len(dim.values))
+		for name := range dim.values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			row(dim.name, name, dim.values[name])
+		}
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+// Encode renders the report in format ("json" or "csv") and returns the content type.
+func (r Report) Encode(format string) ([]byte, string, error) {
+	if format == ReportFormatCSV {
+		return r.CSV(), "text/csv", nil
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	return data, "application/json", err
+}
+
+// ReportPeriod returns the most recent completed period of schedule before now: the previous
+// day for "daily" and the previous Monday to Sunday for "weekly".
+func ReportPeriod(schedule string, now time.Time) (string, string) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if schedule == reportScheduleWeekly {
+		sinceMonday := (int(today.Weekday()) + 6) % 7
+		weekStart := today.AddDate(0, 0, -sinceMonday)
+		return weekStart.AddDate(0, 0, -7).Format(snapshotDateLayout), weekStart.AddDate(0, 0, -1).Format(snapshotDateLayout)
+	}
+	yesterday := today.AddDate(0, 0, -1).Format(snapshotDateLayout)
+	return yesterday, yesterday
+}
+
+// reportSettings is the normalised usage-reports configuration.
+type reportSettings struct {
+	schedule string
+	format   string
+	dir      string
+	webhook  string
+	s3       config.UsageReportS3Config
+	client   *http.Client
+}
+
+var (
+	reportMu     sync.Mutex
+	reportCancel context.CancelFunc
+)
+
+// ConfigureReports applies the usage-reports settings, restarting the scheduler. The scheduler
+// checks hourly for a completed period that has not been reported yet, so a period missed while
+// the proxy was down is reported once it is back.
+func ConfigureReports(cfg *config.Config) {
+	reportMu.Lock()
+	defer reportMu.Unlock()
+	if reportCancel != nil {
+		reportCancel()
+		reportCancel = nil
+	}
+	if cfg == nil || !cfg.UsageReports.Enabled {
+		return
+	}
+	settings := &reportSettings{
+		schedule: reportScheduleDaily,
+		format:   ReportFormatJSON,
+		dir:      resolveReportDir(cfg),
+		webhook:  strings.TrimSpace(cfg.UsageReports.Webhook),
+		s3:       cfg.UsageReports.S3,
+		client:   &http.Client{Timeout: reportSendTimeout},
+	}
+	if strings.EqualFold(strings.TrimSpace(cfg.UsageReports.Schedule), reportScheduleWeekly) {
+		settings.schedule = reportScheduleWeekly
+	}
+	if strings.EqualFold(strings.TrimSpace(cfg.UsageReports.Format), ReportFormatCSV) {
+		settings.format = ReportFormatCSV
+	}
+	util.SetProxy(&cfg.SDKConfig, settings.client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reportCancel = cancel
+	go runReportScheduler(ctx, settings)
+}
+
+func resolveReportDir(cfg *config.Config) string {
+	if dir := strings.TrimSpace(cfg.UsageReports.Dir); dir != "" {
+		return dir
+	}
+	if base := util.WritablePath(); base != "" {
+		return filepath.Join(base, defaultReportDir)
+	}
+	if authDir, err := util.ResolveAuthDir(cfg.AuthDir); err == nil && authDir != "" {
+		return filepath.Join(authDir, defaultReportDir)
+	}
+	return defaultReportDir
+}
+
+func runReportScheduler(ctx context.Context, settings *reportSettings) {
+	ticker := time.NewTicker(reportCheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := settings.reportDue(ctx, time.Now()); err != nil {
+			log.Warnf("usage reports: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reportDue produces the report of the latest completed period unless it was already delivered.
+func (s *reportSettings) reportDue(ctx context.Context, now time.Time) error {
+	from, to := ReportPeriod(s.schedule, now)
+	period := s.schedule + ":" + from + ":" + to
+	statePath := filepath.Join(s.dir, reportStateFile)
+	if last, err := os.ReadFile(statePath); err == nil && strings.TrimSpace(string(last)) == period {
+		return nil
+	}
+	report, err := BuildReport(Snapshots(), defaultRequestStatistics, from, to)
+	if err != nil {
+		return err
+	}
+	data, contentType, err := report.Encode(s.format)
+	if err != nil {
+		return err
+	}
+	name := "usage-" + from
+	if to != from {
+		name += "_" + to
+	}
+	name += "." + s.format
+	if err = s.deliver(ctx, name, data, contentType); err != nil {
+		return err
+	}
+	if err = os.WriteFile(statePath, []byte(period), 0o600); err != nil {
+		return fmt.Errorf("record delivered report: %w", err)
+	}
+	log.Infof("usage reports: delivered %s", name)
+	return nil
+}
+
+// deliver writes the report to the report directory, then sends it to the webhook and the S3
+// bucket when configured.
+func (s *reportSettings) deliver(ctx context.Context, name string, data []byte, contentType string) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("create report dir: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, 0o600); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if s.webhook != "" {
+		if err := postReport(ctx, s.client, s.webhook, data, contentType); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(s.s3.Bucket) != "" {
+		if err := uploadReport(ctx, s.s3, name, data, contentType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func postReport(ctx context.Context, client *http.Client, url string, data []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func uploadReport(ctx context.Context, cfg config.UsageReportS3Config, name string, data []byte, contentType string) error {
+	options := &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	}
+	if cfg.PathStyle {
+		options.BucketLookup = minio.BucketLookupPath
+	}
+	client, err := minio.New(cfg.Endpoint, options)
+	if err != nil {
+		return fmt.Errorf("s3: create client: %w", err)
+	}
+	key := cfg.Prefix + name
+	if _, err = client.PutObject(ctx, cfg.Bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: contentType}); err != nil {
+		return fmt.Errorf("s3: put %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package usage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestBuildReportSumsDaysAndRendersCSV(t *testing.T) {
+	prev := StatisticsEnabled()
+	SetStatisticsEnabled(true)
+	t.Cleanup(func() { SetStatisticsEnabled(prev) })
+
+	stats := NewRequestStatistics()
+	dayOne := time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local)
+	stats.Record(context.Background(), coreusage.Record{APIKey: "k1", Model: "m1", Provider: "claude", RequestedAt: dayOne, Detail: coreusage.Detail{TotalTokens: 10}})
+	stats.Record(context.Background(), coreusage.Record{APIKey: "k1", Model: "m1", Provider: "claude", RequestedAt: dayOne.AddDate(0, 0, 1), Detail: coreusage.Detail{TotalTokens: 20}})
+	stats.Record(context.Background(), coreusage.Record{APIKey: "k2", Model: "m2", Provider: "gemini", RequestedAt: dayOne.AddDate(0, 0, 5), Detail: coreusage.Detail{TotalTokens: 40}})
+
+	report, err := BuildReport(nil, stats, "2026-03-02", "2026-03-03")
+	if err != nil {
+		t.Fatalf("BuildReport: %v", err)
+	}
+	if report.Totals.Requests != 2 || report.Models["m1"].TotalTokens != 30 {
+		t.Fatalf("report = %+v", report)
+	}
+	if _, ok := report.Models["m2"]; ok {
+		t.Fatal("report includes a day outside the range")
+	}
+
+	csv := string(report.CSV())
+	if !strings.HasPrefix(csv, "from,to,dimension,name,requests") {
+		t.Fatalf("csv header = %q", csv)
+	}
+	if !strings.Contains(csv, "2026-03-02,2026-03-03,model,m1,2,0,0,0,30,") {
+		t.Fatalf("csv missing model row:\n%s", csv)
+	}
+	if strings.Contains(csv, ",k1,") {
+		t.Fatalf("csv contains a raw API key:\n%s", csv)
+	}
+
+	if _, err = BuildReport(nil, stats, "2026-03-03", "2026-03-02"); err == nil {
+		t.Fatal("expected error for reversed range")
+	}
+}
+
+func TestReportPeriod(t *testing.T) {
+	wednesday := time.Date(2026, 3, 11, 9, 0, 0, 0, time.Local)
+	if from, to := ReportPeriod(reportScheduleDaily, wednesday); from != "2026-03-10" || to != "2026-03-10" {
+		t.Fatalf("daily = %s..%s", from, to)
+	}
+	if from, to := ReportPeriod(reportScheduleWeekly, wednesday); from != "2026-03-02" || to != "2026-03-08" {
+		t.Fatalf("weekly = %s..%s", from, to)
+	}
+	monday := time.Date(2026, 3, 9, 0, 30, 0, 0, time.Local)
+	if from, to := ReportPeriod(reportScheduleWeekly, monday); from != "2026-03-02" || to != "2026-03-08" {
+		t.Fatalf("weekly on monday = %s..%s", from, to)
+	}
+}
@@ -0,0 +1,599 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	snapshotDateLayout = "2006-01-02"
+	snapshotInterval   = time.Hour
+	defaultSnapshotDir = "usage-snapshots"
+	snapshotFileSuffix = ".json"
+	snapshotFileMode   = 0o600
+)
+
+// UsageTotals aggregates request and token counts for one dimension value.
+type UsageTotals struct {
+	Requests     int64 `json:"requests"`
+	Failures     int64 `json:"failures"`
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	TotalTokens  int64 `json:"total_tokens"`
+	// Cost is the estimated spend in USD, priced with the model-capabilities pricing in effect
+	// when the totals were computed. Models without pricing add nothing.
+	Cost float64 `json:"cost,omitempty"`
+}
+
+func (t *UsageTotals) add(detail RequestDetail, cost float64) {
+	t.Requests++
+	t.Cost += cost
+	if detail.Failed {
+		t.Failures++
+	}
+	t.InputTokens += detail.Tokens.InputTokens
+	t.OutputTokens += detail.Tokens.OutputTokens
+	t.TotalTokens += detail.Tokens.TotalTokens
+}
+
+func (t UsageTotals) plus(other UsageTotals) UsageTotals {
+	return UsageTotals{
+		Requests:     t.Requests + other.Requests,
+		Failures:     t.Failures + other.Failures,
+		InputTokens:  t.InputTokens + other.InputTokens,
+		OutputTokens: t.OutputTokens + other.OutputTokens,
+		TotalTokens:  t.TotalTokens + other.TotalTokens,
+		Cost:         t.Cost + other.Cost,
+	}
+}
+
+func (t UsageTotals) sub(other UsageTotals) UsageTotals {
+	return UsageTotals{
+		Requests:     t.Requests - other.Requests,
+		Failures:     t.Failures - other.Failures,
+		InputTokens:  t.InputTokens - other.InputTokens,
+		OutputTokens: t.OutputTokens - other.OutputTokens,
+		TotalTokens:  t.TotalTokens - other.TotalTokens,
+		Cost:         t.Cost - other.Cost,
+	}
+}
+
+// DailySnapshot holds the usage aggregates of a single day, split by API key, model, provider
+// and workload.
+type DailySnapshot struct {
+	Date        string                 `json:"date"`
+	GeneratedAt time.Time              `json:"generated_at"`
+	Totals      UsageTotals            `json:"totals"`
+	APIs        map[string]UsageTotals `json:"apis"`
+	Models      map[string]UsageTotals `json:"models"`
+	Providers   map[string]UsageTotals `json:"providers"`
+	Workloads   map[string]UsageTotals `json:"workloads,omitempty"`
+	// Tenants splits the usage of tenant API keys by tenant id and model.
+	Tenants map[string]map[string]UsageTotals `json:"tenants,omitempty"`
+	// Auths splits requests and tokens by upstream credential (auth index).
+	Auths map[string]UsageTotals `json:"auths,omitempty"`
+	// Hours holds the hourly rollups of the day keyed by hour ("00" to "23", local time).
+	Hours map[string]HourlyRollup `json:"hours,omitempty"`
+}
+
+// HourlyRollup aggregates the requests of one hour per API key, model and credential.
+type HourlyRollup struct {
+	Totals UsageTotals            `json:"totals"`
+	APIs   map[string]UsageTotals `json:"apis"`
+	Models map[string]UsageTotals `json:"models"`
+	Auths  map[string]UsageTotals `json:"auths"`
+}
+
+func (r *HourlyRollup) add(apiName, model string, detail RequestDetail, cost float64) {
+	if r.APIs == nil {
+		r.APIs = make(map[string]UsageTotals)
+		r.Models = make(map[string]UsageTotals)
+		r.Auths = make(map[string]UsageTotals)
+	}
+	r.Totals.add(detail, cost)
+	addTotals(r.APIs, apiName, detail, cost)
+	addTotals(r.Models, model, detail, cost)
+	addTotals(r.Auths, authKey(detail), detail, cost)
+}
+
+// TotalsDiff compares the aggregates of one dimension value between two snapshots.
+type TotalsDiff struct {
+	From  UsageTotals `json:"from"`
+	To    UsageTotals `json:"to"`
+	Delta UsageTotals `json:"delta"`
+}
+
+// SnapshotDiff reports per key, model, provider and workload deltas between two days.
+type SnapshotDiff struct {
+	From      string                `json:"from"`
+	To        string                `json:"to"`
+	Totals    TotalsDiff            `json:"totals"`
+	APIs      map[string]TotalsDiff `json:"apis"`
+	Models    map[string]TotalsDiff `json:"models"`
+	Providers map[string]TotalsDiff `json:"providers"`
+	Workloads map[string]TotalsDiff `json:"workloads,omitempty"`
+}
+
+// DailySnapshot computes the aggregates of all recorded requests that happened on date
+// (YYYY-MM-DD, local time). Client API keys appear only as hashed identifiers.
+func (s *RequestStatistics) DailySnapshot(date string) DailySnapshot {
+	snapshot := DailySnapshot{
+		Date:        date,
+		GeneratedAt: time.Now().UTC(),
+		APIs:        make(map[string]UsageTotals),
+		Models:      make(map[string]UsageTotals),
+		Providers:   make(map[string]UsageTotals),
+		Workloads:   make(map[string]UsageTotals),
+		Tenants:     make(map[string]map[string]UsageTotals),
+		Auths:       make(map[string]UsageTotals),
+		Hours:       make(map[string]HourlyRollup),
+	}
+	if s == nil {
+		return snapshot
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for apiName, stats := range s.apis {
+		for modelName, modelStatsValue := range stats.Models {
+			for _, detail := range modelStatsValue.Details {
+				if detail.Timestamp.Local().Format(snapshotDateLayout) != date {
+					continue
+				}
+				provider := detail.Provider
+				if provider == "" {
+					provider = "unknown"
+				}
+				cost := detailCost(modelName, detail)
+				snapshotName := snapshotAPIName(apiName)
+				snapshot.Totals.add(detail, cost)
+				addTotals(snapshot.APIs, snapshotName, detail, cost)
+				addTotals(snapshot.Auths, authKey(detail), detail, cost)
+				hour := formatHour(detail.Timestamp.Local().Hour())
+				rollup := snapshot.Hours[hour]
+				rollup.add(snapshotName, modelName, detail, cost)
+				snapshot.Hours[hour] = rollup
+				addTotals(snapshot.Models, modelName, detail, cost)
+				addTotals(snapshot.Providers, provider, detail, cost)
+				addTotals(snapshot.Workloads, workloadKey(detail), detail, cost)
+				if detail.Tenant != "" {
+					models := snapshot.Tenants[detail.Tenant]
+					if models == nil {
+						models = make(map[string]UsageTotals)
+						snapshot.Tenants[detail.Tenant] = models
+					}
+					addTotals(models, modelName, detail, cost)
+				}
+			}
+		}
+	}
+	return snapshot
+}
+
+// snapshotAPIName returns the identifier a statistics key is stored under in a snapshot. Raw
+// client API keys are hashed whatever the attribution mode; request paths ("POST /v1/...") and
+// identifiers that are already hashed or provider names are kept.
+func snapshotAPIName(apiName string) string {
+	state := attribution.Load()
+	if _, isKey := state.keyProviders[apiName]; isKey {
+		return util.HashAPIKey(apiName)
+	}
+	if state.mode != AttributionKey || apiName == "unknown" || strings.HasPrefix(apiName, "/") || strings.Contains(apiName, " /") {
+		return apiName
+	}
+	return util.HashAPIKey(apiName)
+}
+
+// hashSnapshotAPIs re-keys snapshots written before API keys were hashed.
+func hashSnapshotAPIs(apis map[string]UsageTotals) map[string]UsageTotals {
+	out := make(map[string]UsageTotals, len(apis))
+	for name, totals := range apis {
+		key := snapshotAPIName(name)
+		out[key] = out[key].plus(totals)
+	}
+	return out
+}
+
+func addTotals(m map[string]UsageTotals, key string, detail RequestDetail, cost float64) {
+	totals := m[key]
+	totals.add(detail, cost)
+	m[key] = totals
+}
+
+// detailCost estimates the spend of one request from the pricing configured for model.
+func detailCost(model string, detail RequestDetail) float64 {
+	pricing, ok := registry.LookupModelPricing(model)
+	if !ok {
+		return 0
+	}
+	cached := detail.Tokens.CachedTokens
+	uncached := max(detail.Tokens.InputTokens-cached, 0)
+	cachedPrice := pricing.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = pricing.Input
+	}
+	output := detail.Tokens.OutputTokens + detail.Tokens.ReasoningTokens
+	return (float64(uncached)*pricing.Input + float64(cached)*cachedPrice + float64(output)*pricing.Output) / 1_000_000
+}
+
+// authKey returns the credential index of detail, grouping requests without one.
+func authKey(detail RequestDetail) string {
+	if detail.AuthIndex == "" {
+		return "unknown"
+	}
+	return detail.AuthIndex
+}
+
+// workloadKey returns the workload label of detail, grouping unclassified requests.
+func workloadKey(detail RequestDetail) string {
+	if detail.Workload == "" {
+		return "unclassified"
+	}
+	return detail.Workload
+}
+
+// DiffSnapshots returns the change from one daily snapshot to another.
+func DiffSnapshots(from, to DailySnapshot) SnapshotDiff {
+	return SnapshotDiff{
+		From:      from.Date,
+		To:        to.Date,
+		Totals:    TotalsDiff{From: from.Totals, To: to.Totals, Delta: to.Totals.sub(from.Totals)},
+		APIs:      diffTotals(from.APIs, to.APIs),
+		Models:    diffTotals(from.Models, to.Models),
+		Providers: diffTotals(from.Providers, to.Providers),
+		Workloads: diffTotals(from.Workloads, to.Workloads),
+	}
+}
+
+func diffTotals(from, to map[string]UsageTotals) map[string]TotalsDiff {
+	out := make(map[string]TotalsDiff, len(from)+len(to))
+	for key, value := range from {
+		out[key] = TotalsDiff{From: value, To: to[key], Delta: to[key].sub(value)}
+	}
+	for key, value := range to {
+		if _, ok := from[key]; !ok {
+			out[key] = TotalsDiff{To: value, Delta: value}
+		}
+	}
+	return out
+}
+
+// SnapshotStore persists daily snapshots as one JSON file per day.
+type SnapshotStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewSnapshotStore returns a store writing to dir.
+func NewSnapshotStore(dir string) *SnapshotStore { return &SnapshotStore{dir: dir} }
+
+// Dir returns the directory holding the snapshots.
+func (st *SnapshotStore) Dir() string { return st.dir }
+
+// Save writes snapshot unless a stored snapshot of the same day already counts more requests,
+// which happens when in-memory statistics were lost to a restart after the earlier save.
+func (st *SnapshotStore) Save(snapshot DailySnapshot) error {
+	if _, err := time.Parse(snapshotDateLayout, snapshot.Date); err != nil {
+		return fmt.Errorf("invalid snapshot date %q", snapshot.Date)
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if existing, err := st.load(snapshot.Date); err == nil && existing.Totals.Requests > snapshot.Totals.Requests {
+		return nil
+	}
+	if err := os.MkdirAll(st.dir, 0o755); err != nil {
+		return fmt.Errorf("create snapshot dir: %w", err)
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(st.dir, "snapshot-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		_ = os.Remove(tmpName)
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpName, snapshotFileMode); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, st.path(snapshot.Date))
+}
+
+// Load reads the snapshot stored for date.
+func (st *SnapshotStore) Load(date string) (DailySnapshot, error) {
+	if _, err := time.Parse(snapshotDateLayout, date); err != nil {
+		return DailySnapshot{}, fmt.Errorf("invalid snapshot date %q", date)
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.load(date)
+}
+
+func (st *SnapshotStore) load(date string) (DailySnapshot, error) {
+	var snapshot DailySnapshot
+	data, err := os.ReadFile(st.path(date))
+	if err != nil {
+		return snapshot, err
+	}
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, err
+	}
+	// Older releases wrote world-readable files; tighten them as they are read.
+	if info, errStat := os.Stat(st.path(date)); errStat == nil && info.Mode().Perm() != snapshotFileMode {
+		_ = os.Chmod(st.path(date), snapshotFileMode)
+	}
+	snapshot.APIs = hashSnapshotAPIs(snapshot.APIs)
+	for hour, rollup := range snapshot.Hours {
+		rollup.APIs = hashSnapshotAPIs(rollup.APIs)
+		snapshot.Hours[hour] = rollup
+	}
+	return snapshot, nil
+}
+
+// List returns the dates with a stored snapshot in ascending order.
+func (st *SnapshotStore) List() ([]string, error) {
+	entries, err := os.ReadDir(st.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	dates := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, snapshotFileSuffix) {
+			continue
+		}
+		date := strings.TrimSuffix(name, snapshotFileSuffix)
+		if _, errParse := time.Parse(snapshotDateLayout, date); errParse == nil {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+	return dates, nil
+}
+
+// Prune deletes snapshots older than retentionDays days. Zero or negative values keep everything.
+func (st *SnapshotStore) Prune(retentionDays int, now time.Time) {
+	if retentionDays <= 0 {
+		return
+	}
+	dates, err := st.List()
+	if err != nil {
+		return
+	}
+	cutoff := now.AddDate(0, 0, -retentionDays).Format(snapshotDateLayout)
+	for _, date := range dates {
+		if date < cutoff {
+			if errRemove := os.Remove(st.path(date)); errRemove != nil {
+				log.Debugf("usage snapshots: failed to remove %s: %v", date, errRemove)
+			}
+		}
+	}
+}
+
+func (st *SnapshotStore) path(date string) string {
+	return filepath.Join(st.dir, date+snapshotFileSuffix)
+}
+
+type snapshotSettings struct {
+	store         *SnapshotStore
+	retentionDays int
+	// detailRetention bounds how long per-request details stay in memory. 0 keeps them.
+	detailRetention time.Duration
+}
+
+// minDetailRetention keeps details long enough for the current and previous day's snapshots to
+// be finalised from them.
+const minDetailRetention = 48 * time.Hour
+
+var (
+	snapshotState     atomic.Pointer[snapshotSettings]
+	snapshotSchedOnce sync.Once
+)
+
+// ConfigureSnapshots applies the usage-snapshots settings from cfg. The first time snapshots are
+// enabled it restores the usage totals from the stored snapshots and starts the hourly writer.
+func ConfigureSnapshots(cfg *config.Config) {
+	if cfg == nil || !cfg.UsageSnapshots.Enabled {
+		snapshotState.Store(nil)
+		return
+	}
+	settings := &snapshotSettings{
+		store:         NewSnapshotStore(resolveSnapshotDir(cfg)),
+		retentionDays: cfg.UsageSnapshots.RetentionDays,
+	}
+	if raw := strings.TrimSpace(cfg.UsageSnapshots.DetailRetention); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err != nil || parsed <= 0 {
+			log.Warnf("usage snapshots: invalid detail-retention %q, keeping details", raw)
+		} else {
+			settings.detailRetention = max(parsed, minDetailRetention)
+		}
+	}
+	snapshotState.Store(settings)
+	snapshotSchedOnce.Do(func() {
+		if days, err := defaultRequestStatistics.RestoreTotals(Snapshots()); err != nil {
+			log.Warnf("usage snapshots: failed to restore totals: %v", err)
+		} else if days > 0 {
+			log.Infof("usage snapshots: restored totals from %d day(s)", days)
+		}
+		go runSnapshotScheduler(context.Background())
+	})
+}
+
+// Snapshots returns the active snapshot store, or nil when snapshots are disabled.
+func Snapshots() *SnapshotStore {
+	if settings := snapshotState.Load(); settings != nil {
+		return settings.store
+	}
+	return nil
+}
+
+func resolveSnapshotDir(cfg *config.Config) string {
+	if dir := strings.TrimSpace(cfg.UsageSnapshots.Dir); dir != "" {
+		return dir
+	}
+	if base := util.WritablePath(); base != "" {
+		return filepath.Join(base, defaultSnapshotDir)
+	}
+	if authDir, err := util.ResolveAuthDir(cfg.AuthDir); err == nil && authDir != "" {
+		return filepath.Join(authDir, defaultSnapshotDir)
+	}
+	return defaultSnapshotDir
+}
+
+func runSnapshotScheduler(ctx context.Context) {
+	ticker := time.NewTicker(snapshotInterval)
+	defer ticker.Stop()
+	lastDay := time.Now().Format(snapshotDateLayout)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			today := now.Format(snapshotDateLayout)
+			if today != lastDay {
+				// Finalise the previous day before moving on.
+				writeSnapshot(lastDay, now)
+				lastDay = today
+			}
+			writeSnapshot(today, now)
+		}
+	}
+}
+
+// writeSnapshot saves the snapshot for date using the shared statistics store.
+func writeSnapshot(date string, now time.Time) {
+	settings := snapshotState.Load()
+	if settings == nil {
+		return
+	}
+	snapshot := defaultRequestStatistics.DailySnapshot(date)
+	if snapshot.Totals.Requests == 0 {
+		return
+	}
+	if err := settings.store.Save(snapshot); err != nil {
+		log.Warnf("usage snapshots: failed to save %s: %v", date, err)
+		return
+	}
+	settings.store.Prune(settings.retentionDays, now)
+	if settings.detailRetention > 0 {
+		if pruned := defaultRequestStatistics.PruneDetails(now.Add(-settings.detailRetention)); pruned > 0 {
+			log.Debugf("usage snapshots: dropped %d request detail(s) older than %s", pruned, settings.detailRetention)
+		}
+	}
+}
+
+// PruneDetails drops the per-request details recorded before cutoff and returns how many were
+// dropped. Request and token counters are kept; the dropped requests stay covered by the daily
+// snapshots and their hourly rollups.
+func (s *RequestStatistics) PruneDetails(cutoff time.Time) int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pruned := 0
+	for _, stats := range s.apis {
+		for _, modelStatsValue := range stats.Models {
+			kept := modelStatsValue.Details[:0]
+			for _, detail := range modelStatsValue.Details {
+				if detail.Timestamp.Before(cutoff) {
+					pruned++
+					continue
+				}
+				kept = append(kept, detail)
+			}
+			clear(modelStatsValue.Details[len(kept):])
+			modelStatsValue.Details = kept
+		}
+	}
+	return pruned
+}
+
+// RestoreTotals seeds the request and token counters of s, including the per-hour counters,
+// from the stored daily snapshots and returns the number of days restored. Each snapshot is a
+// precomputed aggregate of one day with its hourly rollups, so the cost grows with the number of
+// days kept rather than the number of requests served. Per-key and per-request details are not
+// restored.
+func (s *RequestStatistics) RestoreTotals(store *SnapshotStore) (int, error) {
+	if s == nil || store == nil {
+		return 0, nil
+	}
+	dates, err := store.List()
+	if err != nil {
+		return 0, err
+	}
+	restored := 0
+	for _, date := range dates {
+		snapshot, errLoad := store.Load(date)
+		if errLoad != nil {
+			log.Debugf("usage snapshots: failed to load %s: %v", date, errLoad)
+			continue
+		}
+		totals := snapshot.Totals
+		s.mu.Lock()
+		s.totalRequests += totals.Requests
+		s.successCount += totals.Requests - totals.Failures
+		s.failureCount += totals.Failures
+		s.totalTokens += totals.TotalTokens
+		s.requestsByDay[date] += totals.Requests
+		s.tokensByDay[date] += totals.TotalTokens
+		for hourKey, rollup := range snapshot.Hours {
+			hour, errHour := strconv.Atoi(hourKey)
+			if errHour != nil || hour < 0 || hour > 23 {
+				continue
+			}
+			s.requestsByHour[hour] += rollup.Totals.Requests
+			s.tokensByHour[hour] += rollup.Totals.TotalTokens
+		}
+		s.mu.Unlock()
+		restored++
+	}
+	return restored, nil
+}
+
+// SnapshotForDate returns the snapshot for date. Today's snapshot is computed from live
+// statistics; earlier days are read from the store, falling back to live statistics when the
+// day was never persisted.
+func SnapshotForDate(store *SnapshotStore, stats *RequestStatistics, date string) (DailySnapshot, error) {
+	if _, err := time.Parse(snapshotDateLayout, date); err != nil {
+		return DailySnapshot{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+	}
+	live := stats.DailySnapshot(date)
+	if date == time.Now().Format(snapshotDateLayout) || store == nil {
+		return live, nil
+	}
+	stored, err := store.Load(date)
+	if err != nil || stored.Totals.Requests < live.Totals.Requests {
+		return live, nil
+	}
+	return stored, nil
+}
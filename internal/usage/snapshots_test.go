@@ -0,0 +1,189 @@
+package usage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestDailySnapshotAndDiff(t *testing.T) {
+	prev := StatisticsEnabled()
+	SetStatisticsEnabled(true)
+	t.Cleanup(func() { SetStatisticsEnabled(prev) })
+
+	stats := NewRequestStatistics()
+	dayOne := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	dayTwo := dayOne.AddDate(0, 0, 7)
+	stats.Record(context.Background(), coreusage.Record{APIKey: "k1", Model: "m1", Provider: "claude", RequestedAt: dayOne, Detail: coreusage.Detail{TotalTokens: 10}})
+	stats.Record(context.Background(), coreusage.Record{APIKey: "k1", Model: "m1", Provider: "claude", RequestedAt: dayTwo, Workload: coreusage.WorkloadAgent, Detail: coreusage.Detail{TotalTokens: 25}})
+	stats.Record(context.Background(), coreusage.Record{APIKey: "k2", Model: "m2", Provider: "gemini", RequestedAt: dayTwo, Failed: true, Detail: coreusage.Detail{TotalTokens: 5}})
+
+	from := stats.DailySnapshot("2026-03-01")
+	to := stats.DailySnapshot("2026-03-08")
+	if from.Totals.Requests != 1 || to.Totals.Requests != 2 || to.Totals.Failures != 1 {
+		t.Fatalf("unexpected totals: from %+v to %+v", from.Totals, to.Totals)
+	}
+
+	if _, raw := to.APIs["k1"]; raw {
+		t.Fatalf("snapshots must not contain raw API keys: %v", to.APIs)
+	}
+	diff := DiffSnapshots(from, to)
+	if got := diff.APIs[util.HashAPIKey("k1")].Delta.TotalTokens; got != 15 {
+		t.Fatalf("k1 token delta = %d, want 15", got)
+	}
+	if got := diff.Models["m2"]; got.From.Requests != 0 || got.Delta.Requests != 1 {
+		t.Fatalf("m2 diff = %+v", got)
+	}
+	if got := diff.Providers["gemini"].Delta.Failures; got != 1 {
+		t.Fatalf("gemini failure delta = %d, want 1", got)
+	}
+	if got := diff.Workloads["agent"].Delta.TotalTokens; got != 25 {
+		t.Fatalf("agent token delta = %d, want 25", got)
+	}
+	if got := stats.Snapshot().Workloads["unclassified"].Requests; got != 2 {
+		t.Fatalf("unclassified requests = %d, want 2", got)
+	}
+}
+
+func TestSnapshotStore(t *testing.T) {
+	store := NewSnapshotStore(t.TempDir())
+	full := DailySnapshot{Date: "2026-03-01", Totals: UsageTotals{Requests: 5}}
+	if err := store.Save(full); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	// A smaller snapshot of the same day, e.g. after a restart, must not replace the stored one.
+	if err := store.Save(DailySnapshot{Date: "2026-03-01", Totals: UsageTotals{Requests: 2}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := store.Load("2026-03-01")
+	if err != nil || loaded.Totals.Requests != 5 {
+		t.Fatalf("Load = %+v, %v", loaded.Totals, err)
+	}
+	info, err := os.Stat(filepath.Join(store.Dir(), "2026-03-01.json"))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("snapshot file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	// Snapshots written before keys were hashed are re-keyed when read.
+	legacy := []byte(`{"date":"2026-02-01","totals":{"requests":1},"apis":{"sk-raw":{"requests":1}}}`)
+	if err = os.WriteFile(filepath.Join(store.Dir(), "2026-02-01.json"), legacy, 0o600); err != nil {
+		t.Fatalf("write legacy snapshot: %v", err)
+	}
+	if loaded, err = store.Load("2026-02-01"); err != nil || loaded.APIs[util.HashAPIKey("sk-raw")].Requests != 1 || len(loaded.APIs) != 1 {
+		t.Fatalf("legacy Load = %+v, %v", loaded.APIs, err)
+	}
+	if err = os.Remove(filepath.Join(store.Dir(), "2026-02-01.json")); err != nil {
+		t.Fatalf("remove legacy snapshot: %v", err)
+	}
+
+	if err = store.Save(DailySnapshot{Date: "2026-01-01", Totals: UsageTotals{Requests: 1}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	store.Prune(30, time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local))
+	dates, err := store.List()
+	if err != nil || len(dates) != 1 || dates[0] != "2026-03-01" {
+		t.Fatalf("List after prune = %v, %v", dates, err)
+	}
+	if err = store.Save(DailySnapshot{Date: "../escape"}); err == nil {
+		t.Fatalf("expected invalid date to be rejected")
+	}
+}
+
+func TestRestoreTotalsFromSnapshots(t *testing.T) {
+	store := NewSnapshotStore(t.TempDir())
+	for _, snapshot := range []DailySnapshot{
+		{Date: "2026-03-01", Totals: UsageTotals{Requests: 5, Failures: 1, TotalTokens: 100}},
+		{Date: "2026-03-02", Totals: UsageTotals{Requests: 3, TotalTokens: 40}},
+	} {
+		if err := store.Save(snapshot); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	stats := NewRequestStatistics()
+	days, err := stats.RestoreTotals(store)
+	if err != nil || days != 2 {
+		t.Fatalf("RestoreTotals = %d, %v; want 2 days", days, err)
+	}
+	snapshot := stats.Snapshot()
+	if snapshot.TotalRequests != 8 || snapshot.SuccessCount != 7 || snapshot.FailureCount != 1 || snapshot.TotalTokens != 140 {
+		t.Fatalf("unexpected restored totals: %+v", snapshot)
+	}
+	if got := snapshot.RequestsByDay["2026-03-02"]; got != 3 {
+		t.Fatalf("requests on 2026-03-02 = %d, want 3", got)
+	}
+	if got := snapshot.TokensByDay["2026-03-01"]; got != 100 {
+		t.Fatalf("tokens on 2026-03-01 = %d, want 100", got)
+	}
+}
+
+func TestDailySnapshotEstimatesCostFromPricing(t *testing.T) {
+	prev := StatisticsEnabled()
+	SetStatisticsEnabled(true)
+	t.Cleanup(func() {
+		SetStatisticsEnabled(prev)
+		registry.SetModelCapabilities(nil)
+	})
+	registry.SetModelCapabilities([]config.ModelCapability{{ID: "priced", Pricing: &config.ModelPricing{Input: 2, Output: 10, CachedInput: 1}}})
+
+	stats := NewRequestStatistics()
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	stats.Record(context.Background(), coreusage.Record{APIKey: "k1", Model: "priced", RequestedAt: day, Detail: coreusage.Detail{InputTokens: 1_000_000, CachedTokens: 500_000, OutputTokens: 100_000}})
+	stats.Record(context.Background(), coreusage.Record{APIKey: "k1", Model: "unpriced", RequestedAt: day, Detail: coreusage.Detail{InputTokens: 1_000_000}})
+
+	snapshot := stats.DailySnapshot("2026-03-01")
+	// 0.5M uncached * $2 + 0.5M cached * $1 + 0.1M output * $10 = $2.5
+	if got := snapshot.Totals.Cost; got < 2.4999 || got > 2.5001 {
+		t.Fatalf("total cost = %v, want 2.5", got)
+	}
+	if got := snapshot.Models["unpriced"].Cost; got != 0 {
+		t.Fatalf("unpriced model cost = %v, want 0", got)
+	}
+}
+
+func TestHourlyRollupsRestoreAndDetailPruning(t *testing.T) {
+	prev := StatisticsEnabled()
+	SetStatisticsEnabled(true)
+	t.Cleanup(func() { SetStatisticsEnabled(prev) })
+
+	stats := NewRequestStatistics()
+	morning := time.Date(2026, 3, 1, 9, 15, 0, 0, time.Local)
+	stats.Record(context.Background(), coreusage.Record{APIKey: "k1", Model: "m1", AuthIndex: "a1", RequestedAt: morning, Detail: coreusage.Detail{TotalTokens: 10}})
+	stats.Record(context.Background(), coreusage.Record{APIKey: "k1", Model: "m1", AuthIndex: "a2", RequestedAt: morning.Add(5 * time.Hour), Detail: coreusage.Detail{TotalTokens: 30}})
+
+	snapshot := stats.DailySnapshot("2026-03-01")
+	if got := snapshot.Hours["09"]; got.Totals.Requests != 1 || got.Auths["a1"].TotalTokens != 10 || got.Models["m1"].Requests != 1 {
+		t.Fatalf("09h rollup = %+v", got)
+	}
+	if got := snapshot.Auths["a2"].TotalTokens; got != 30 {
+		t.Fatalf("a2 tokens = %d, want 30", got)
+	}
+
+	store := NewSnapshotStore(t.TempDir())
+	if err := store.Save(snapshot); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	restored := NewRequestStatistics()
+	if _, err := restored.RestoreTotals(store); err != nil {
+		t.Fatalf("RestoreTotals: %v", err)
+	}
+	byHour := restored.Snapshot().RequestsByHour
+	if byHour["09"] != 1 || byHour["14"] != 1 {
+		t.Fatalf("restored requests by hour = %v", byHour)
+	}
+
+	if pruned := stats.PruneDetails(morning.Add(time.Hour)); pruned != 1 {
+		t.Fatalf("pruned = %d, want 1", pruned)
+	}
+	after := stats.Snapshot()
+	if after.TotalRequests != 2 || len(after.APIs["k1"].Models["m1"].Details) != 1 {
+		t.Fatalf("after pruning: total %d, details %d", after.TotalRequests, len(after.APIs["k1"].Models["m1"].Details))
+	}
+}
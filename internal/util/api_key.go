@@ -0,0 +1,22 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// HashedAPIKeyPrefix marks client API key identifiers produced by HashAPIKey.
+const HashedAPIKeyPrefix = "sha256:"
+
+// HashAPIKey returns the stable, non-reversible identifier used wherever a client API key would
+// otherwise be stored or logged, such as usage statistics and the request log index. Values that
+// are already hashed are returned unchanged; an empty key yields an empty identifier.
+func HashAPIKey(apiKey string) string {
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" || strings.HasPrefix(apiKey, HashedAPIKeyPrefix) {
+		return apiKey
+	}
+	sum := sha256.Sum256([]byte(apiKey))
+	return HashedAPIKeyPrefix + hex.EncodeToString(sum[:8])
+}
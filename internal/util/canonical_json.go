@@ -0,0 +1,136 @@
+package util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/sjson"
+)
+
+// CanonicalJSON rewrites a JSON document into a canonical form so that semantically identical
+// documents compare equal byte for byte: object keys are sorted, insignificant whitespace is
+// removed, numbers use their shortest form (1.0, 1e0 and 1 all become 1) and strings are
+// re-encoded without HTML escaping. Fields named by strip (sjson paths such as "metadata" or
+// "generationConfig.seed") are removed before canonicalization.
+func CanonicalJSON(data []byte, strip ...string) ([]byte, error) {
+	for _, path := range strip {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		stripped, err := sjson.DeleteBytes(data, path)
+		if err != nil {
+			return nil, err
+		}
+		data = stripped
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("canonical json: trailing data after document")
+	}
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CanonicalHash returns the hex SHA-256 of the canonical form of data. Documents that are not
+// valid JSON are hashed as is so callers always get a usable key.
+func CanonicalHash(data []byte, strip ...string) string {
+	canonical, err := CanonicalJSON(data, strip...)
+	if err != nil {
+		canonical = data
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeCanonical(buf *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		buf.WriteString(canonicalNumber(v))
+	case string:
+		return writeCanonicalString(buf, v)
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonicalString(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return errors.New("canonical json: unsupported value")
+	}
+	return nil
+}
+
+func writeCanonicalString(buf *bytes.Buffer, s string) error {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(s); err != nil {
+		return err
+	}
+	// Encode appends a newline.
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// canonicalNumber formats a JSON number in its shortest form. Integers that do not fit a
+// float64 exactly keep their literal digits so large IDs are not rounded.
+func canonicalNumber(n json.Number) string {
+	literal := n.String()
+	if !strings.ContainsAny(literal, ".eE") {
+		if i, err := strconv.ParseInt(literal, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+		return strings.TrimPrefix(literal, "+")
+	}
+	f, err := strconv.ParseFloat(literal, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return literal
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,47 @@
+package util
+
+import "testing"
+
+func TestCanonicalJSON(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		strip []string
+		want  string
+	}{
+		{"sorted keys and whitespace", `{ "b": 1, "a": {"d": [1, 2], "c": true} }`, nil, `{"a":{"c":true,"d":[1,2]},"b":1}`},
+		{"numbers", `[1.0, 1e0, -0.0, 0.50, 1.5e-3, 12345678901234567890]`, nil, `[1,1,0,0.5,0.0015,12345678901234567890]`},
+		{"strings keep html", `{"s":"<a> & é"}`, nil, `{"s":"<a> & é"}`},
+		{"strip fields", `{"model":"m","stream":true,"metadata":{"user_id":"u"},"messages":[]}`, []string{"stream", "metadata"}, `{"messages":[],"model":"m"}`},
+		{"strip nested path", `{"generationConfig":{"seed":7,"temperature":0.2}}`, []string{"generationConfig.seed"}, `{"generationConfig":{"temperature":0.2}}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CanonicalJSON([]byte(tc.input), tc.strip...)
+			if err != nil {
+				t.Fatalf("CanonicalJSON: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Fatalf("CanonicalJSON = %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := CanonicalJSON([]byte(`{"a":1} {"b":2}`)); err == nil {
+		t.Fatalf("expected error for trailing data")
+	}
+}
+
+func TestCanonicalHash(t *testing.T) {
+	a := CanonicalHash([]byte(`{"model":"m","temperature":1.0,"user":"alice"}`), "user")
+	b := CanonicalHash([]byte(`{"temperature":1,"model":"m","user":"bob"}`), "user")
+	if a != b {
+		t.Fatalf("semantically identical requests hashed differently")
+	}
+	if CanonicalHash([]byte(`{"model":"other"}`)) == a {
+		t.Fatalf("different requests hashed identically")
+	}
+	if CanonicalHash([]byte("not json")) == "" {
+		t.Fatalf("expected hash for invalid JSON")
+	}
+}
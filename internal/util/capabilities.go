@@ -0,0 +1,321 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ModelCapabilities describes what a model supports. It is the single place translators and
+// model listings consult, built from the registry model info and the model-capabilities overrides.
+type ModelCapabilities struct {
+	// Known reports whether the model is known to the registry at all.
+	Known bool
+	// Vision reports whether the model accepts image input.
+	Vision bool
+	// Tools reports whether the model supports tool/function calling.
+	Tools bool
+	// Thinking reports whether the model supports a thinking budget or reasoning effort.
+	Thinking bool
+	// JSONSchema reports whether the model accepts JSON-schema constrained output.
+	JSONSchema bool
+	// MaxContext is the context window in tokens, zero when unknown.
+	MaxContext int
+	// MaxOutputTokens is the maximum completion length in tokens, zero when unknown.
+	MaxOutputTokens int
+	// InputModalities lists the accepted input modalities, e.g. "text" and "image".
+	InputModalities []string
+	// OutputModalities lists the produced output modalities.
+	OutputModalities []string
+
+	// The declared flags report whether the matching capability was set explicitly through
+	// model-capabilities. Only declared capabilities are enforced on requests; inferred ones
+	// are advisory and only shown in model listings.
+	visionDeclared     bool
+	toolsDeclared      bool
+	jsonSchemaDeclared bool
+}
+
+// visionModelMarkers are lower-cased ID fragments of model families that accept image input.
+var visionModelMarkers = []string{"gemini", "claude", "gpt-4o", "gpt-4.1", "gpt-5", "o3", "o4", "vision", "-vl", "grok-4"}
+
+// imageOutputMarkers are lower-cased ID fragments of models that generate images.
+var imageOutputMarkers = []string{"-image", "imagen"}
+
+// LookupModelCapabilities returns the capabilities of model. Thinking suffixes such as
+// "(high)" and a "models/" prefix are ignored.
+func LookupModelCapabilities(model string) ModelCapabilities {
+	model = strings.TrimPrefix(strings.TrimSpace(thinking.ParseSuffix(strings.TrimSpace(model)).ModelName), "models/")
+	if model == "" {
+		return ModelCapabilities{}
+	}
+	return CapabilitiesOf(registry.LookupModelInfo(model))
+}
+
+// CapabilitiesOf derives the capabilities of a registered model. Vision and tool support are
+// inferred from the model family and supported parameters unless overridden through
+// model-capabilities.
+func CapabilitiesOf(info *registry.ModelInfo) ModelCapabilities {
+	if info == nil {
+		return ModelCapabilities{}
+	}
+	id := strings.ToLower(info.ID)
+	caps := ModelCapabilities{
+		Known:           true,
+		Thinking:        info.Thinking != nil,
+		MaxContext:      max(info.ContextLength, info.InputTokenLimit),
+		MaxOutputTokens: max(info.MaxCompletionTokens, info.OutputTokenLimit),
+	}
+
+	for _, marker := range visionModelMarkers {
+		if strings.Contains(id, marker) {
+			caps.Vision = true
+			break
+		}
+	}
+
+	caps.Tools = !strings.Contains(id, "embedding") && !strings.Contains(id, "imagen")
+	if len(info.SupportedParameters) > 0 {
+		caps.Tools = false
+		for _, param := range info.SupportedParameters {
+			if param == "tools" {
+				caps.Tools = true
+				break
+			}
+		}
+	}
+	caps.JSONSchema = caps.Tools
+
+	if override, ok := registry.ModelCapabilityOverride(info.ID); ok {
+		if override.Vision != nil {
+			caps.Vision, caps.visionDeclared = *override.Vision, true
+		}
+		if override.Tools != nil {
+			caps.Tools, caps.toolsDeclared = *override.Tools, true
+		}
+		if override.JSONSchema != nil {
+			caps.JSONSchema, caps.jsonSchemaDeclared = *override.JSONSchema, true
+		}
+	}
+
+	caps.InputModalities = []string{"text"}
+	if caps.Vision {
+		caps.InputModalities = append(caps.InputModalities, "image")
+	}
+	caps.OutputModalities = []string{"text"}
+	for _, marker := range imageOutputMarkers {
+		if strings.Contains(id, marker) {
+			caps.OutputModalities = append(caps.OutputModalities, "image")
+			break
+		}
+	}
+	return caps
+}
+
+// Map renders the capabilities for model listings, including the configured pricing of modelID.
+func (c ModelCapabilities) Map(modelID string) map[string]any {
+	out := map[string]any{
+		"vision":            c.Vision,
+		"tools":             c.Tools,
+		"thinking":          c.Thinking,
+		"json_schema":       c.JSONSchema,
+		"input_modalities":  c.InputModalities,
+		"output_modalities": c.OutputModalities,
+	}
+	if c.MaxContext > 0 {
+		out["max_context"] = c.MaxContext
+	}
+	if c.MaxOutputTokens > 0 {
+		out["max_output"] = c.MaxOutputTokens
+	}
+	if pricing, ok := registry.LookupModelPricing(modelID); ok {
+		out["pricing"] = pricing
+	}
+	return out
+}
+
+// AnnotateModelCapabilities adds a "capabilities" entry to every model of a listing. Models are
+// identified by their "id", or by their "name" for Gemini-style listings.
+func AnnotateModelCapabilities(models []map[string]any) []map[string]any {
+	for _, model := range models {
+		id, _ := model["id"].(string)
+		if id == "" {
+			id, _ = model["name"].(string)
+		}
+		id = strings.TrimPrefix(id, "models/")
+		if id == "" {
+			continue
+		}
+		if caps := LookupModelCapabilities(id); caps.Known {
+			model["capabilities"] = caps.Map(id)
+		}
+	}
+	return models
+}
+
+// omittedImageText replaces image parts sent to a model without image input.
+const omittedImageText = "[image omitted: the model does not accept image input]"
+
+// GateRequestFeatures adapts a request in the given source format to the capabilities of model.
+// Tools, image parts and JSON-schema output are only removed when the model declares them
+// unsupported through model-capabilities, and token limits above the model's maximum output are
+// lowered to it. Unknown models and unknown formats are returned unchanged.
+func GateRequestFeatures(format, model string, rawJSON []byte) []byte {
+	caps := LookupModelCapabilities(model)
+	if !caps.Known || !gjson.ValidBytes(rawJSON) {
+		return rawJSON
+	}
+
+	prefix := ""
+	switch format {
+	case "gemini-cli":
+		format, prefix = "gemini", "request."
+	case "openai", "openai-response", "claude", "gemini":
+	default:
+		return rawJSON
+	}
+
+	if caps.toolsDeclared && !caps.Tools {
+		rawJSON = gateTools(format, prefix, model, rawJSON)
+	}
+	if caps.visionDeclared && !caps.Vision {
+		rawJSON = gateImages(format, prefix, model, rawJSON)
+	}
+	if caps.jsonSchemaDeclared && !caps.JSONSchema {
+		rawJSON = gateJSONSchema(format, prefix, model, rawJSON)
+	}
+	if caps.MaxOutputTokens > 0 {
+		rawJSON = clampOutputTokens(format, prefix, model, caps.MaxOutputTokens, rawJSON)
+	}
+	return rawJSON
+}
+
+func gateTools(format, prefix, model string, rawJSON []byte) []byte {
+	fields := []string{"tools", "tool_choice", "parallel_tool_calls"}
+	if format == "gemini" {
+		fields = []string{"tools", "toolConfig"}
+	}
+	removed := false
+	for _, field := range fields {
+		if gjson.GetBytes(rawJSON, prefix+field).Exists() {
+			rawJSON, _ = sjson.DeleteBytes(rawJSON, prefix+field)
+			removed = true
+		}
+	}
+	if removed {
+		log.Debugf("capabilities: dropped tools from request for model %s without tool support", model)
+	}
+	return rawJSON
+}
+
+func gateImages(format, prefix, model string, rawJSON []byte) []byte {
+	var listPath, partsField string
+	var isImage func(gjson.Result) bool
+	var placeholder string
+	switch format {
+	case "openai":
+		listPath, partsField = "messages", "content"
+		isImage = func(part gjson.Result) bool { return part.Get("type").String() == "image_url" }
+		placeholder = `{"type":"text","text":""}`
+	case "openai-response":
+		listPath, partsField = "input", "content"
+		isImage = func(part gjson.Result) bool { return part.Get("type").String() == "input_image" }
+		placeholder = `{"type":"input_text","text":""}`
+	case "claude":
+		listPath, partsField = "messages", "content"
+		isImage = func(part gjson.Result) bool { return part.Get("type").String() == "image" }
+		placeholder = `{"type":"text","text":""}`
+	case "gemini":
+		listPath, partsField = prefix+"contents", "parts"
+		isImage = func(part gjson.Result) bool {
+			for _, field := range []string{"inlineData", "inline_data", "fileData", "file_data"} {
+				data := part.Get(field)
+				if !data.Exists() {
+					continue
+				}
+				mime := data.Get("mimeType").String()
+				if mime == "" {
+					mime = data.Get("mime_type").String()
+				}
+				return strings.HasPrefix(strings.ToLower(mime), "image/")
+			}
+			return false
+		}
+		placeholder = `{"text":""}`
+	}
+	placeholder, _ = sjson.Set(placeholder, "text", omittedImageText)
+
+	replaced := 0
+	for i, item := range gjson.GetBytes(rawJSON, listPath).Array() {
+		for j, part := range item.Get(partsField).Array() {
+			if !isImage(part) {
+				continue
+			}
+			rawJSON, _ = sjson.SetRawBytes(rawJSON, fmt.Sprintf("%s.%d.%s.%d", listPath, i, partsField, j), []byte(placeholder))
+			replaced++
+		}
+	}
+	if replaced > 0 {
+		log.Debugf("capabilities: replaced %d image part(s) for model %s without image input", replaced, model)
+	}
+	return rawJSON
+}
+
+func gateJSONSchema(format, prefix, model string, rawJSON []byte) []byte {
+	switch format {
+	case "openai":
+		if gjson.GetBytes(rawJSON, "response_format.type").String() != "json_schema" {
+			return rawJSON
+		}
+		rawJSON, _ = sjson.SetRawBytes(rawJSON, "response_format", []byte(`{"type":"json_object"}`))
+	case "openai-response":
+		if gjson.GetBytes(rawJSON, "text.format.type").String() != "json_schema" {
+			return rawJSON
+		}
+		rawJSON, _ = sjson.SetRawBytes(rawJSON, "text.format", []byte(`{"type":"json_object"}`))
+	case "gemini":
+		removed := false
+		for _, field := range []string{"responseSchema", "responseJsonSchema", "response_schema", "response_json_schema"} {
+			path := prefix + "generationConfig." + field
+			if gjson.GetBytes(rawJSON, path).Exists() {
+				rawJSON, _ = sjson.DeleteBytes(rawJSON, path)
+				removed = true
+			}
+		}
+		if !removed {
+			return rawJSON
+		}
+	default:
+		return rawJSON
+	}
+	log.Debugf("capabilities: downgraded JSON-schema output to plain JSON for model %s", model)
+	return rawJSON
+}
+
+func clampOutputTokens(format, prefix, model string, limit int, rawJSON []byte) []byte {
+	var fields []string
+	switch format {
+	case "openai":
+		fields = []string{"max_tokens", "max_completion_tokens"}
+	case "openai-response":
+		fields = []string{"max_output_tokens"}
+	case "claude":
+		fields = []string{"max_tokens"}
+	case "gemini":
+		fields = []string{prefix + "generationConfig.maxOutputTokens"}
+	}
+	for _, field := range fields {
+		value := gjson.GetBytes(rawJSON, field)
+		if value.Type != gjson.Number || value.Int() <= int64(limit) {
+			continue
+		}
+		rawJSON, _ = sjson.SetBytes(rawJSON, field, limit)
+		log.Debugf("capabilities: lowered %s from %d to %d for model %s", field, value.Int(), limit, model)
+	}
+	return rawJSON
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/tidwall/gjson"
+)
+
+func TestCapabilitiesOf(t *testing.T) {
+	t.Cleanup(func() { registry.SetModelCapabilities(nil) })
+
+	caps := CapabilitiesOf(&registry.ModelInfo{ID: "gemini-2.5-pro", InputTokenLimit: 1048576, OutputTokenLimit: 65536, Thinking: &registry.ThinkingSupport{Max: 32768}})
+	if !caps.Vision || !caps.Tools || !caps.Thinking || !caps.JSONSchema {
+		t.Fatalf("unexpected capabilities: %+v", caps)
+	}
+	listed := caps.Map("gemini-2.5-pro")
+	if listed["max_context"] != 1048576 || listed["max_output"] != 65536 {
+		t.Fatalf("unexpected limits: %v", listed)
+	}
+
+	caps = CapabilitiesOf(&registry.ModelInfo{ID: "qwen3-coder-plus", SupportedParameters: []string{"temperature"}})
+	if caps.Vision || caps.Tools || len(caps.InputModalities) != 1 {
+		t.Fatalf("expected no vision/tools for text-only model without tools parameter: %+v", caps)
+	}
+
+	vision := true
+	registry.SetModelCapabilities([]config.ModelCapability{{ID: "qwen3-coder-plus", Vision: &vision, Pricing: &config.ModelPricing{Input: 1}}})
+	caps = CapabilitiesOf(&registry.ModelInfo{ID: "qwen3-coder-plus"})
+	if !caps.Vision || caps.InputModalities[1] != "image" {
+		t.Fatalf("expected vision override to apply: %+v", caps)
+	}
+	if _, ok := caps.Map("qwen3-coder-plus")["pricing"]; !ok {
+		t.Fatal("expected pricing in capability listing")
+	}
+}
+
+func TestGateRequestFeatures(t *testing.T) {
+	t.Cleanup(func() { registry.SetModelCapabilities(nil) })
+	no := false
+	registry.SetModelCapabilities([]config.ModelCapability{{
+		ID:                  "text-only",
+		MaxCompletionTokens: 4096,
+		Vision:              &no,
+		Tools:               &no,
+		JSONSchema:          &no,
+	}})
+
+	openai := []byte(`{"model":"text-only","max_tokens":100000,"tools":[{"type":"function","function":{"name":"f"}}],"tool_choice":"auto","response_format":{"type":"json_schema","json_schema":{}},"messages":[{"role":"user","content":[{"type":"text","text":"hi"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AA"}}]}]}`)
+	out := GateRequestFeatures("openai", "text-only(high)", openai)
+	if gjson.GetBytes(out, "tools").Exists() || gjson.GetBytes(out, "tool_choice").Exists() {
+		t.Fatalf("tools were not dropped: %s", out)
+	}
+	if got := gjson.GetBytes(out, "messages.0.content.1.text").String(); got != omittedImageText {
+		t.Fatalf("image part was not replaced: %s", out)
+	}
+	if got := gjson.GetBytes(out, "response_format.type").String(); got != "json_object" {
+		t.Fatalf("response_format.type = %q", got)
+	}
+	if got := gjson.GetBytes(out, "max_tokens").Int(); got != 4096 {
+		t.Fatalf("max_tokens = %d, want 4096", got)
+	}
+
+	geminiCLI := []byte(`{"request":{"contents":[{"role":"user","parts":[{"inlineData":{"mimeType":"image/png","data":"AA"}},{"inlineData":{"mimeType":"application/pdf","data":"AA"}}]}],"tools":[{}],"generationConfig":{"responseSchema":{},"maxOutputTokens":10}}}`)
+	out = GateRequestFeatures("gemini-cli", "text-only", geminiCLI)
+	if gjson.GetBytes(out, "request.tools").Exists() || gjson.GetBytes(out, "request.generationConfig.responseSchema").Exists() {
+		t.Fatalf("gemini-cli request was not gated: %s", out)
+	}
+	if !gjson.GetBytes(out, "request.contents.0.parts.0.text").Exists() || !gjson.GetBytes(out, "request.contents.0.parts.1.inlineData").Exists() {
+		t.Fatalf("only image parts should be replaced: %s", out)
+	}
+	if got := gjson.GetBytes(out, "request.generationConfig.maxOutputTokens").Int(); got != 10 {
+		t.Fatalf("limits below the maximum must be kept, got %d", got)
+	}
+
+	unknown := []byte(`{"model":"unknown","tools":[{}],"max_tokens":999999}`)
+	if out = GateRequestFeatures("openai", "unknown-model-xyz", unknown); string(out) != string(unknown) {
+		t.Fatalf("unknown model request changed: %s", out)
+	}
+}
@@ -0,0 +1,40 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// PrefillContinueInstruction is sent as a final user turn to backends that cannot continue from a
+// trailing assistant message, so the model resumes the prefilled text instead of starting over.
+const PrefillContinueInstruction = "Continue your previous message exactly from where it ends. Do not repeat any of it, " +
+	"do not add a preamble and do not acknowledge this instruction; output only the continuation."
+
+// ClaudePrefill reports whether a Claude Messages request ends with an assistant message that
+// prefills the response, and returns the prefilled text. Assistant messages carrying tool_use
+// blocks are regular turns, not prefills.
+func ClaudePrefill(rawJSON []byte) (string, bool) {
+	messages := gjson.GetBytes(rawJSON, "messages").Array()
+	if len(messages) == 0 {
+		return "", false
+	}
+	last := messages[len(messages)-1]
+	if last.Get("role").String() != "assistant" {
+		return "", false
+	}
+	content := last.Get("content")
+	if content.Type == gjson.String {
+		return content.String(), strings.TrimSpace(content.String()) != ""
+	}
+	var text strings.Builder
+	for _, part := range content.Array() {
+		switch part.Get("type").String() {
+		case "text":
+			text.WriteString(part.Get("text").String())
+		case "tool_use", "server_tool_use":
+			return "", false
+		}
+	}
+	return text.String(), strings.TrimSpace(text.String()) != ""
+}
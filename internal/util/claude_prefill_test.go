@@ -0,0 +1,25 @@
+package util
+
+import "testing"
+
+func TestClaudePrefill(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		want string
+		ok   bool
+	}{
+		{"string prefill", `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"Sure:"}]}`, "Sure:", true},
+		{"block prefill", `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"thinking","thinking":"x"},{"type":"text","text":"{"}]}]}`, "{", true},
+		{"user last", `{"messages":[{"role":"assistant","content":"a"},{"role":"user","content":"b"}]}`, "", false},
+		{"tool use", `{"messages":[{"role":"assistant","content":[{"type":"text","text":"a"},{"type":"tool_use","id":"1","name":"f","input":{}}]}]}`, "", false},
+		{"blank", `{"messages":[{"role":"assistant","content":"  "}]}`, "", false},
+		{"no messages", `{}`, "", false},
+	}
+	for _, tc := range cases {
+		got, ok := ClaudePrefill([]byte(tc.body))
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Errorf("%s: ClaudePrefill = %q, %v; want %q, %v", tc.name, got, ok, tc.want, tc.ok)
+		}
+	}
+}
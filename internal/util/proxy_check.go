@@ -0,0 +1,73 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const proxyCheckTimeout = 5 * time.Second
+
+// CheckProxy validates proxyURL and opens a TCP connection to the proxy server. "direct" always
+// passes.
+func CheckProxy(ctx context.Context, proxyURL string) error {
+	if err := config.ValidateProxyURL(proxyURL); err != nil {
+		return err
+	}
+	if strings.EqualFold(strings.TrimSpace(proxyURL), config.ProxyDirect) {
+		return nil
+	}
+	parsed, _ := url.Parse(strings.TrimSpace(proxyURL))
+	host := parsed.Host
+	if parsed.Port() == "" {
+		port := "1080"
+		switch parsed.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+		host = net.JoinHostPort(parsed.Hostname(), port)
+	}
+	ctx, cancel := context.WithTimeout(ctx, proxyCheckTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return fmt.Errorf("connect to proxy %s: %w", host, err)
+	}
+	return conn.Close()
+}
+
+// CheckConfiguredProxies checks the global proxy-url and every provider-proxies entry and logs the
+// result, so an unreachable proxy is reported at startup rather than on the first request.
+func CheckConfiguredProxies(ctx context.Context, cfg *config.Config) {
+	if cfg == nil {
+		return
+	}
+	check := func(name, proxyURL string) {
+		if err := CheckProxy(ctx, proxyURL); err != nil {
+			log.Warnf("proxy check: %s: %v", name, err)
+			return
+		}
+		log.Debugf("proxy check: %s reachable", name)
+	}
+	if proxyURL := strings.TrimSpace(cfg.ProxyURL); proxyURL != "" {
+		check("proxy-url", proxyURL)
+	}
+	providers := make([]string, 0, len(cfg.ProviderProxies))
+	for provider := range cfg.ProviderProxies {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	for _, provider := range providers {
+		check("provider-proxies."+provider, cfg.ProviderProxies[provider])
+	}
+}
@@ -0,0 +1,50 @@
+package util
+
+import "strings"
+
+// MatchWildcard reports whether value matches pattern, where '*' matches zero or more
+// characters. Matching is case-sensitive and surrounding whitespace is ignored; an empty
+// pattern matches nothing. It is the shared matcher for model patterns in config.
+// Examples:
+//
+//	"*-5" matches "gpt-5"
+//	"gpt-*" matches "gpt-5" and "gpt-4"
+//	"gemini-*-pro" matches "gemini-2.5-pro" and "gemini-3-pro".
+func MatchWildcard(pattern, value string) bool {
+	pattern = strings.TrimSpace(pattern)
+	value = strings.TrimSpace(value)
+	if pattern == "" {
+		return false
+	}
+	if pattern == "*" {
+		return true
+	}
+	// Iterative glob-style matcher supporting only '*' wildcard.
+	pi, si := 0, 0
+	starIdx := -1
+	matchIdx := 0
+	for si < len(value) {
+		if pi < len(pattern) && pattern[pi] == value[si] {
+			pi++
+			si++
+			continue
+		}
+		if pi < len(pattern) && pattern[pi] == '*' {
+			starIdx = pi
+			matchIdx = si
+			pi++
+			continue
+		}
+		if starIdx != -1 {
+			pi = starIdx + 1
+			matchIdx++
+			si = matchIdx
+			continue
+		}
+		return false
+	}
+	for pi < len(pattern) && pattern[pi] == '*' {
+		pi++
+	}
+	return pi == len(pattern)
+}
@@ -0,0 +1,27 @@
+package util
+
+import "testing"
+
+func TestMatchWildcard(t *testing.T) {
+	cases := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"*", "anything", true},
+		{"gpt-*", "gpt-5", true},
+		{"*-pro", "gemini-2.5-pro", true},
+		{"gemini-*-pro", "gemini-3-pro", true},
+		{"gemini-*-pro", "gemini-2.5-flash", false},
+		{"gpt-5", "gpt-5-codex", false},
+		{"a*a", "a", false},
+		{" exact ", "exact", true},
+		{"GPT-5", "gpt-5", false},
+		{"", "", false},
+	}
+	for _, tc := range cases {
+		if got := MatchWildcard(tc.pattern, tc.value); got != tc.want {
+			t.Errorf("MatchWildcard(%q, %q) = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+	}
+}
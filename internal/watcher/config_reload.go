@@ -5,6 +5,7 @@ package watcher
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"os"
 	"reflect"
 	"time"
@@ -14,9 +15,12 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher/diff"
 	"gopkg.in/yaml.v3"
 
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	log "github.com/sirupsen/logrus"
 )
 
+var errEmptyConfig = errors.New("config file is empty")
+
 func (w *Watcher) stopConfigReloadTimer() {
 	w.configReloadMu.Lock()
 	if w.configReloadTimer != nil {
@@ -40,18 +44,72 @@ func (w *Watcher) scheduleConfigReload() {
 	})
 }
 
-func (w *Watcher) reloadConfigIfChanged() {
+// configHash hashes the config file together with the files it includes. It fails when the
+// config file cannot be read or is empty, which happens mid-write.
+func (w *Watcher) configHash() (string, error) {
 	data, err := os.ReadFile(w.configPath)
 	if err != nil {
-		log.Errorf("failed to read config file for hash check: %v", err)
-		return
+		return "", err
 	}
 	if len(data) == 0 {
+		return "", errEmptyConfig
+	}
+	h := sha256.New()
+	h.Write(data)
+	w.clientsMutex.RLock()
+	sources := w.config.SourceFiles()
+	w.clientsMutex.RUnlock()
+	for _, path := range sources {
+		// A missing include hashes as empty; the reload reports it.
+		included, _ := os.ReadFile(path)
+		h.Write([]byte(path))
+		h.Write(included)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// watchConfigSources adds the files included by cfg to the file watcher.
+func (w *Watcher) watchConfigSources(cfg *config.Config) {
+	for _, path := range cfg.SourceFiles() {
+		normalized := w.normalizeAuthPath(path)
+		w.clientsMutex.Lock()
+		_, watched := w.configSources[normalized]
+		if !watched {
+			if w.configSources == nil {
+				w.configSources = make(map[string]struct{})
+			}
+			w.configSources[normalized] = struct{}{}
+		}
+		w.clientsMutex.Unlock()
+		if watched {
+			continue
+		}
+		if errAdd := w.watcher.Add(path); errAdd != nil {
+			log.Warnf("failed to watch included config file %s: %v", path, errAdd)
+			continue
+		}
+		log.Debugf("watching included config file: %s", path)
+	}
+}
+
+// isConfigSource reports whether the normalized path is a watched included config file.
+func (w *Watcher) isConfigSource(normalizedPath string) bool {
+	w.clientsMutex.RLock()
+	defer w.clientsMutex.RUnlock()
+	_, ok := w.configSources[normalizedPath]
+	return ok
+}
+
+func (w *Watcher) reloadConfigIfChanged() {
+	newHash, err := w.configHash()
+	if errors.Is(err, errEmptyConfig) {
 		log.Debugf("ignoring empty config file write event")
 		return
 	}
-	sum := sha256.Sum256(data)
-	newHash := hex.EncodeToString(sum[:])
+	if err != nil {
+		log.Errorf("failed to read config file for hash check: %v", err)
+		return
+	}
 
 	w.clientsMutex.RLock()
 	currentHash := w.lastConfigHash
@@ -64,11 +122,10 @@ func (w *Watcher) reloadConfigIfChanged() {
 	log.Infof("config file changed, reloading: %s", w.configPath)
 	if w.reloadConfig() {
 		finalHash := newHash
-		if updatedData, errRead := os.ReadFile(w.configPath); errRead == nil && len(updatedData) > 0 {
-			sumUpdated := sha256.Sum256(updatedData)
-			finalHash = hex.EncodeToString(sumUpdated[:])
-		} else if errRead != nil {
-			log.WithError(errRead).Debug("failed to compute updated config hash after reload")
+		if updatedHash, errHash := w.configHash(); errHash == nil {
+			finalHash = updatedHash
+		} else if !errors.Is(errHash, errEmptyConfig) {
+			log.WithError(errHash).Debug("failed to compute updated config hash after reload")
 		}
 		w.clientsMutex.Lock()
 		w.lastConfigHash = finalHash
@@ -78,6 +135,12 @@ func (w *Watcher) reloadConfigIfChanged() {
 }
 
 func (w *Watcher) reloadConfig() bool {
+	return w.reloadConfigWithRescan(false)
+}
+
+// reloadConfigWithRescan loads the config file and reloads clients. When forceRescan is set the
+// auth directory is rescanned even if its location did not change.
+func (w *Watcher) reloadConfigWithRescan(forceRescan bool) bool {
 	log.Debug("=========================== CONFIG RELOAD ============================")
 	log.Debugf("starting config reload from: %s", w.configPath)
 
@@ -103,13 +166,14 @@ func (w *Watcher) reloadConfig() bool {
 	w.oldConfigYaml, _ = yaml.Marshal(newConfig)
 	w.config = newConfig
 	w.clientsMutex.Unlock()
+	w.watchConfigSources(newConfig)
 
 	var affectedOAuthProviders []string
 	if oldConfig != nil {
 		_, affectedOAuthProviders = diff.DiffOAuthExcludedModelChanges(oldConfig.OAuthExcludedModels, newConfig.OAuthExcludedModels)
 	}
 
-	util.SetLogLevel(newConfig)
+	logging.ConfigureLogLevel(newConfig)
 	if oldConfig != nil && oldConfig.Debug != newConfig.Debug {
 		log.Debugf("log level updated - debug mode changed from %t to %t", oldConfig.Debug, newConfig.Debug)
 	}
@@ -126,7 +190,7 @@ func (w *Watcher) reloadConfig() bool {
 		}
 	}
 
-	authDirChanged := oldConfig == nil || oldConfig.AuthDir != newConfig.AuthDir
+	authDirChanged := forceRescan || oldConfig == nil || oldConfig.AuthDir != newConfig.AuthDir
 	forceAuthRefresh := oldConfig != nil && (oldConfig.ForceModelPrefix != newConfig.ForceModelPrefix || !reflect.DeepEqual(oldConfig.OAuthModelAlias, newConfig.OAuthModelAlias))
 
 	log.Infof("config successfully reloaded, triggering client reload")
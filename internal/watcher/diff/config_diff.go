@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
@@ -27,12 +28,63 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.Debug != newCfg.Debug {
 		changes = append(changes, fmt.Sprintf("debug: %t -> %t", oldCfg.Debug, newCfg.Debug))
 	}
+	if oldCfg.LogLevel != newCfg.LogLevel {
+		changes = append(changes, fmt.Sprintf("log-level: %s -> %s", oldCfg.LogLevel, newCfg.LogLevel))
+	}
+	if !reflect.DeepEqual(oldCfg.DebugModules, newCfg.DebugModules) {
+		changes = append(changes, fmt.Sprintf("debug-modules: %v -> %v", oldCfg.DebugModules, newCfg.DebugModules))
+	}
+	if oldCfg.LogFormat != newCfg.LogFormat {
+		changes = append(changes, fmt.Sprintf("log-format: %s -> %s", oldCfg.LogFormat, newCfg.LogFormat))
+	}
 	if oldCfg.LoggingToFile != newCfg.LoggingToFile {
 		changes = append(changes, fmt.Sprintf("logging-to-file: %t -> %t", oldCfg.LoggingToFile, newCfg.LoggingToFile))
 	}
 	if oldCfg.UsageStatisticsEnabled != newCfg.UsageStatisticsEnabled {
 		changes = append(changes, fmt.Sprintf("usage-statistics-enabled: %t -> %t", oldCfg.UsageStatisticsEnabled, newCfg.UsageStatisticsEnabled))
 	}
+	if oldCfg.AuthGuard != newCfg.AuthGuard {
+		changes = append(changes, fmt.Sprintf("auth-guard: enabled %t -> %t, max-failures %d -> %d", oldCfg.AuthGuard.Enabled, newCfg.AuthGuard.Enabled, oldCfg.AuthGuard.MaxFailures, newCfg.AuthGuard.MaxFailures))
+	}
+	if !reflect.DeepEqual(oldCfg.NetworkAccess, newCfg.NetworkAccess) {
+		changes = append(changes, fmt.Sprintf("network-access: allow %d -> %d, deny %d -> %d, trusted-proxies %d -> %d", len(oldCfg.NetworkAccess.Allow), len(newCfg.NetworkAccess.Allow), len(oldCfg.NetworkAccess.Deny), len(newCfg.NetworkAccess.Deny), len(oldCfg.NetworkAccess.TrustedProxies), len(newCfg.NetworkAccess.TrustedProxies)))
+	}
+	if !reflect.DeepEqual(oldCfg.RequestLimits, newCfg.RequestLimits) {
+		changes = append(changes, fmt.Sprintf("request-limits: max-body-size-mb %d -> %d, max-inline-data-mb %d -> %d, endpoints %d -> %d", oldCfg.RequestLimits.MaxBodySizeMB, newCfg.RequestLimits.MaxBodySizeMB, oldCfg.RequestLimits.MaxInlineDataMB, newCfg.RequestLimits.MaxInlineDataMB, len(oldCfg.RequestLimits.Endpoints), len(newCfg.RequestLimits.Endpoints)))
+	}
+	if !reflect.DeepEqual(oldCfg.ProviderStatus, newCfg.ProviderStatus) {
+		changes = append(changes, fmt.Sprintf("provider-status: enabled %t -> %t, feeds %d -> %d", oldCfg.ProviderStatus.Enabled, newCfg.ProviderStatus.Enabled, len(oldCfg.ProviderStatus.Feeds), len(newCfg.ProviderStatus.Feeds)))
+	}
+	if !reflect.DeepEqual(oldCfg.TLS.ClientAuth, newCfg.TLS.ClientAuth) {
+		changes = append(changes, fmt.Sprintf("tls.client-auth: mode %q -> %q, identities %d -> %d", oldCfg.TLS.ClientAuth.Mode, newCfg.TLS.ClientAuth.Mode, len(oldCfg.TLS.ClientAuth.Identities), len(newCfg.TLS.ClientAuth.Identities)))
+	}
+	if !reflect.DeepEqual(oldCfg.TLS.ACME, newCfg.TLS.ACME) {
+		changes = append(changes, fmt.Sprintf("tls.acme: domains %d -> %d (restart required)", len(oldCfg.TLS.ACME.Domains), len(newCfg.TLS.ACME.Domains)))
+	}
+	if oldCfg.AuditLog != newCfg.AuditLog {
+		changes = append(changes, fmt.Sprintf("audit-log: enabled %t -> %t, max-entries %d -> %d", oldCfg.AuditLog.Enabled, newCfg.AuditLog.Enabled, oldCfg.AuditLog.MaxEntries, newCfg.AuditLog.MaxEntries))
+	}
+	if oldCfg.Tenants != newCfg.Tenants {
+		changes = append(changes, fmt.Sprintf("tenants: enabled %t -> %t", oldCfg.Tenants.Enabled, newCfg.Tenants.Enabled))
+	}
+	if oldCfg.Telemetry != newCfg.Telemetry {
+		changes = append(changes, fmt.Sprintf("telemetry: enabled %t -> %t, interval %s -> %s", oldCfg.Telemetry.Enabled, newCfg.Telemetry.Enabled, oldCfg.Telemetry.Interval, newCfg.Telemetry.Interval))
+	}
+	if oldCfg.UsageSnapshots != newCfg.UsageSnapshots {
+		changes = append(changes, fmt.Sprintf("usage-snapshots: enabled %t -> %t, retention-days %d -> %d", oldCfg.UsageSnapshots.Enabled, newCfg.UsageSnapshots.Enabled, oldCfg.UsageSnapshots.RetentionDays, newCfg.UsageSnapshots.RetentionDays))
+	}
+	if oldCfg.UsageReports != newCfg.UsageReports {
+		changes = append(changes, fmt.Sprintf("usage-reports: enabled %t -> %t, schedule %s -> %s", oldCfg.UsageReports.Enabled, newCfg.UsageReports.Enabled, oldCfg.UsageReports.Schedule, newCfg.UsageReports.Schedule))
+	}
+	if oldCfg.Batch != newCfg.Batch {
+		changes = append(changes, fmt.Sprintf("batch: enabled %t -> %t, concurrency %d -> %d", oldCfg.Batch.Enabled, newCfg.Batch.Enabled, oldCfg.Batch.Concurrency, newCfg.Batch.Concurrency))
+	}
+	if oldCfg.GRPC != newCfg.GRPC {
+		changes = append(changes, fmt.Sprintf("grpc: enabled %t -> %t, inference %t -> %t", oldCfg.GRPC.Enabled, newCfg.GRPC.Enabled, oldCfg.GRPC.Inference, newCfg.GRPC.Inference))
+	}
+	if oldCfg.UsageAttribution != newCfg.UsageAttribution {
+		changes = append(changes, fmt.Sprintf("usage-attribution: %s -> %s", oldCfg.UsageAttribution, newCfg.UsageAttribution))
+	}
 	if oldCfg.DisableCooling != newCfg.DisableCooling {
 		changes = append(changes, fmt.Sprintf("disable-cooling: %t -> %t", oldCfg.DisableCooling, newCfg.DisableCooling))
 	}
@@ -42,18 +94,52 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.LogsMaxTotalSizeMB != newCfg.LogsMaxTotalSizeMB {
 		changes = append(changes, fmt.Sprintf("logs-max-total-size-mb: %d -> %d", oldCfg.LogsMaxTotalSizeMB, newCfg.LogsMaxTotalSizeMB))
 	}
+	if oldCfg.LogRotation != newCfg.LogRotation {
+		changes = append(changes, fmt.Sprintf("log-rotation: max-size-mb=%d max-backups=%d max-age-days=%d compress=%t -> max-size-mb=%d max-backups=%d max-age-days=%d compress=%t",
+			oldCfg.LogRotation.MaxSizeMB, oldCfg.LogRotation.MaxBackups, oldCfg.LogRotation.MaxAgeDays, oldCfg.LogRotation.Compress,
+			newCfg.LogRotation.MaxSizeMB, newCfg.LogRotation.MaxBackups, newCfg.LogRotation.MaxAgeDays, newCfg.LogRotation.Compress))
+	}
 	if oldCfg.ErrorLogsMaxFiles != newCfg.ErrorLogsMaxFiles {
 		changes = append(changes, fmt.Sprintf("error-logs-max-files: %d -> %d", oldCfg.ErrorLogsMaxFiles, newCfg.ErrorLogsMaxFiles))
 	}
+	if oldCfg.RequestLogCompression != newCfg.RequestLogCompression {
+		changes = append(changes, fmt.Sprintf("request-log-compression: %t -> %t", oldCfg.RequestLogCompression, newCfg.RequestLogCompression))
+	}
+	if oldCfg.CheckForUpdates != newCfg.CheckForUpdates {
+		changes = append(changes, fmt.Sprintf("check-for-updates: %t -> %t", oldCfg.CheckForUpdates, newCfg.CheckForUpdates))
+	}
 	if oldCfg.RequestRetry != newCfg.RequestRetry {
 		changes = append(changes, fmt.Sprintf("request-retry: %d -> %d", oldCfg.RequestRetry, newCfg.RequestRetry))
 	}
 	if oldCfg.MaxRetryInterval != newCfg.MaxRetryInterval {
 		changes = append(changes, fmt.Sprintf("max-retry-interval: %d -> %d", oldCfg.MaxRetryInterval, newCfg.MaxRetryInterval))
 	}
+	if !reflect.DeepEqual(oldCfg.RetryPolicy, newCfg.RetryPolicy) {
+		changes = append(changes, fmt.Sprintf("retry-policy: max-attempts %d -> %d, retry-on %v -> %v", oldCfg.RetryPolicy.MaxAttempts, newCfg.RetryPolicy.MaxAttempts, oldCfg.RetryPolicy.RetryOn, newCfg.RetryPolicy.RetryOn))
+	}
 	if oldCfg.ProxyURL != newCfg.ProxyURL {
 		changes = append(changes, fmt.Sprintf("proxy-url: %s -> %s", formatProxyURL(oldCfg.ProxyURL), formatProxyURL(newCfg.ProxyURL)))
 	}
+	if oldCfg.OAuthCallback != newCfg.OAuthCallback {
+		changes = append(changes, fmt.Sprintf("oauth-callback: host %q -> %q, port-range %d -> %d", oldCfg.OAuthCallback.Host, newCfg.OAuthCallback.Host, oldCfg.OAuthCallback.PortRange, newCfg.OAuthCallback.PortRange))
+	}
+	if !reflect.DeepEqual(oldCfg.ProviderProxies, newCfg.ProviderProxies) {
+		providers := make([]string, 0, len(oldCfg.ProviderProxies)+len(newCfg.ProviderProxies))
+		for provider := range oldCfg.ProviderProxies {
+			providers = append(providers, provider)
+		}
+		for provider := range newCfg.ProviderProxies {
+			if _, ok := oldCfg.ProviderProxies[provider]; !ok {
+				providers = append(providers, provider)
+			}
+		}
+		sort.Strings(providers)
+		for _, provider := range providers {
+			if o, n := oldCfg.ProviderProxies[provider], newCfg.ProviderProxies[provider]; o != n {
+				changes = append(changes, fmt.Sprintf("provider-proxies.%s: %s -> %s", provider, formatProxyURL(o), formatProxyURL(n)))
+			}
+		}
+	}
 	if oldCfg.WebsocketAuth != newCfg.WebsocketAuth {
 		changes = append(changes, fmt.Sprintf("ws-auth: %t -> %t", oldCfg.WebsocketAuth, newCfg.WebsocketAuth))
 	}
@@ -63,6 +149,69 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.NonStreamKeepAliveInterval != newCfg.NonStreamKeepAliveInterval {
 		changes = append(changes, fmt.Sprintf("nonstream-keepalive-interval: %d -> %d", oldCfg.NonStreamKeepAliveInterval, newCfg.NonStreamKeepAliveInterval))
 	}
+	if oldCfg.Streaming.IdleTimeoutSeconds != newCfg.Streaming.IdleTimeoutSeconds {
+		changes = append(changes, fmt.Sprintf("streaming.idle-timeout-seconds: %d -> %d", oldCfg.Streaming.IdleTimeoutSeconds, newCfg.Streaming.IdleTimeoutSeconds))
+	}
+	if oldCfg.Streaming.MaxDurationSeconds != newCfg.Streaming.MaxDurationSeconds {
+		changes = append(changes, fmt.Sprintf("streaming.max-duration-seconds: %d -> %d", oldCfg.Streaming.MaxDurationSeconds, newCfg.Streaming.MaxDurationSeconds))
+	}
+	if oldCfg.Streaming.KeepAliveBeforeFirstChunk != newCfg.Streaming.KeepAliveBeforeFirstChunk {
+		changes = append(changes, fmt.Sprintf("streaming.keepalive-before-first-chunk: %t -> %t", oldCfg.Streaming.KeepAliveBeforeFirstChunk, newCfg.Streaming.KeepAliveBeforeFirstChunk))
+	}
+	if !reflect.DeepEqual(oldCfg.UsageMetadataKeys, newCfg.UsageMetadataKeys) {
+		changes = append(changes, fmt.Sprintf("usage-metadata-keys: %v -> %v", oldCfg.UsageMetadataKeys, newCfg.UsageMetadataKeys))
+	}
+	if oldCfg.StrictRequestFields != newCfg.StrictRequestFields {
+		changes = append(changes, fmt.Sprintf("strict-request-fields: %t -> %t", oldCfg.StrictRequestFields, newCfg.StrictRequestFields))
+	}
+	if !reflect.DeepEqual(oldCfg.Logprobs, newCfg.Logprobs) {
+		changes = append(changes, fmt.Sprintf("logprobs: updated (%d -> %d rules)", len(oldCfg.Logprobs), len(newCfg.Logprobs)))
+	}
+	if !reflect.DeepEqual(oldCfg.SafetySettings, newCfg.SafetySettings) {
+		changes = append(changes, fmt.Sprintf("safety-settings: updated (%d -> %d rules)", len(oldCfg.SafetySettings.Rules), len(newCfg.SafetySettings.Rules)))
+	}
+	if !reflect.DeepEqual(oldCfg.APIKeyModels, newCfg.APIKeyModels) {
+		changes = append(changes, fmt.Sprintf("api-key-models: updated (%d -> %d entries)", len(oldCfg.APIKeyModels), len(newCfg.APIKeyModels)))
+	}
+	if !reflect.DeepEqual(oldCfg.APIKeyProtocols, newCfg.APIKeyProtocols) {
+		changes = append(changes, fmt.Sprintf("api-key-protocols: updated (%d -> %d entries)", len(oldCfg.APIKeyProtocols), len(newCfg.APIKeyProtocols)))
+	}
+	if !reflect.DeepEqual(oldCfg.APIKeyBudgets, newCfg.APIKeyBudgets) {
+		changes = append(changes, fmt.Sprintf("api-key-budgets: updated (%d -> %d entries)", len(oldCfg.APIKeyBudgets), len(newCfg.APIKeyBudgets)))
+	}
+	if oldCfg.TrafficPause.Paused != newCfg.TrafficPause.Paused {
+		changes = append(changes, fmt.Sprintf("traffic-pause.paused: %t -> %t", oldCfg.TrafficPause.Paused, newCfg.TrafficPause.Paused))
+	}
+	if !reflect.DeepEqual(oldCfg.TrafficPause.Providers, newCfg.TrafficPause.Providers) {
+		changes = append(changes, fmt.Sprintf("traffic-pause.providers: %v -> %v", oldCfg.TrafficPause.Providers, newCfg.TrafficPause.Providers))
+	}
+	if oldCfg.TrafficPause.RetryAfter != newCfg.TrafficPause.RetryAfter {
+		changes = append(changes, fmt.Sprintf("traffic-pause.retry-after: %d -> %d", oldCfg.TrafficPause.RetryAfter, newCfg.TrafficPause.RetryAfter))
+	}
+	if !reflect.DeepEqual(oldCfg.LatencyRouting, newCfg.LatencyRouting) {
+		changes = append(changes, fmt.Sprintf("latency-routing: chains %d -> %d", len(oldCfg.LatencyRouting.Chains), len(newCfg.LatencyRouting.Chains)))
+	}
+	if !reflect.DeepEqual(oldCfg.CanaryRouting, newCfg.CanaryRouting) {
+		changes = append(changes, fmt.Sprintf("canary-routing: routes %d -> %d", len(oldCfg.CanaryRouting), len(newCfg.CanaryRouting)))
+	}
+	if !reflect.DeepEqual(oldCfg.RequestQueue, newCfg.RequestQueue) {
+		changes = append(changes, fmt.Sprintf("request-queue: enabled %t -> %t, max-wait %s -> %s, api-keys %d -> %d", oldCfg.RequestQueue.Enabled, newCfg.RequestQueue.Enabled, oldCfg.RequestQueue.MaxWait, newCfg.RequestQueue.MaxWait, len(oldCfg.RequestQueue.APIKeys), len(newCfg.RequestQueue.APIKeys)))
+	}
+	if !reflect.DeepEqual(oldCfg.RequestScripts, newCfg.RequestScripts) {
+		changes = append(changes, fmt.Sprintf("request-scripts: updated (%d -> %d entries)", len(oldCfg.RequestScripts), len(newCfg.RequestScripts)))
+	}
+	if !reflect.DeepEqual(oldCfg.SystemPrompts, newCfg.SystemPrompts) {
+		changes = append(changes, fmt.Sprintf("system-prompts: updated (%d -> %d rules)", len(oldCfg.SystemPrompts.Rules), len(newCfg.SystemPrompts.Rules)))
+	}
+	if !reflect.DeepEqual(oldCfg.ContextWindow, newCfg.ContextWindow) {
+		changes = append(changes, fmt.Sprintf("context-window: enabled %t -> %t", oldCfg.ContextWindow.Enabled, newCfg.ContextWindow.Enabled))
+	}
+	if !reflect.DeepEqual(oldCfg.ThinkingOutput, newCfg.ThinkingOutput) {
+		changes = append(changes, fmt.Sprintf("thinking-output: updated (%d -> %d policies)", len(oldCfg.ThinkingOutput), len(newCfg.ThinkingOutput)))
+	}
+	if !reflect.DeepEqual(oldCfg.Guardrails, newCfg.Guardrails) {
+		changes = append(changes, fmt.Sprintf("guardrails: updated (%d -> %d policies)", len(oldCfg.Guardrails), len(newCfg.Guardrails)))
+	}
 
 	// Quota-exceeded behavior
 	if oldCfg.QuotaExceeded.SwitchProject != newCfg.QuotaExceeded.SwitchProject {
@@ -75,6 +224,27 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.Routing.Strategy != newCfg.Routing.Strategy {
 		changes = append(changes, fmt.Sprintf("routing.strategy: %s -> %s", oldCfg.Routing.Strategy, newCfg.Routing.Strategy))
 	}
+	if oldCfg.Routing.SessionAffinity != newCfg.Routing.SessionAffinity {
+		changes = append(changes, fmt.Sprintf("routing.session-affinity: %t -> %t", oldCfg.Routing.SessionAffinity, newCfg.Routing.SessionAffinity))
+	}
+	if oldCfg.Routing.SessionAffinityTTL != newCfg.Routing.SessionAffinityTTL {
+		changes = append(changes, fmt.Sprintf("routing.session-affinity-ttl: %s -> %s", oldCfg.Routing.SessionAffinityTTL, newCfg.Routing.SessionAffinityTTL))
+	}
+	if oldCfg.Routing.MaxConcurrencyPerAuth != newCfg.Routing.MaxConcurrencyPerAuth {
+		changes = append(changes, fmt.Sprintf("routing.max-concurrency-per-auth: %d -> %d", oldCfg.Routing.MaxConcurrencyPerAuth, newCfg.Routing.MaxConcurrencyPerAuth))
+	}
+	if !reflect.DeepEqual(oldCfg.Routing.ProviderMaxConcurrency, newCfg.Routing.ProviderMaxConcurrency) {
+		changes = append(changes, "routing.provider-max-concurrency: updated")
+	}
+	if oldCfg.Routing.ConcurrencyWait != newCfg.Routing.ConcurrencyWait {
+		changes = append(changes, fmt.Sprintf("routing.concurrency-wait: %s -> %s", oldCfg.Routing.ConcurrencyWait, newCfg.Routing.ConcurrencyWait))
+	}
+	if !reflect.DeepEqual(oldCfg.Routing.AccountCaps, newCfg.Routing.AccountCaps) {
+		changes = append(changes, fmt.Sprintf("routing.account-caps: updated (%d -> %d entries)", len(oldCfg.Routing.AccountCaps), len(newCfg.Routing.AccountCaps)))
+	}
+	if !reflect.DeepEqual(oldCfg.Routing.LabelRules, newCfg.Routing.LabelRules) {
+		changes = append(changes, fmt.Sprintf("routing.label-rules: updated (%d -> %d entries)", len(oldCfg.Routing.LabelRules), len(newCfg.Routing.LabelRules)))
+	}
 
 	// API keys (redacted) and counts
 	if len(oldCfg.APIKeys) != len(newCfg.APIKeys) {
@@ -197,6 +367,11 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 		}
 	}
 
+	// Mistral, xAI, DeepSeek keys and Ollama servers (do not print key material)
+	for _, provider := range config.APIKeyProviders {
+		changes = append(changes, diffProviderKeys(provider, *provider.Keys(oldCfg), *provider.Keys(newCfg))...)
+	}
+
 	// AmpCode settings (redacted where needed)
 	oldAmpURL := strings.TrimSpace(oldCfg.AmpCode.UpstreamURL)
 	newAmpURL := strings.TrimSpace(newCfg.AmpCode.UpstreamURL)
@@ -236,6 +411,24 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if entries, _ := DiffOAuthModelAliasChanges(oldCfg.OAuthModelAlias, newCfg.OAuthModelAlias); len(entries) > 0 {
 		changes = append(changes, entries...)
 	}
+	if oldCfg.GeminiContextCache != newCfg.GeminiContextCache {
+		changes = append(changes, fmt.Sprintf("gemini-context-cache: %t -> %t", oldCfg.GeminiContextCache, newCfg.GeminiContextCache))
+	}
+	if oldCfg.RemoteMedia.Enable != newCfg.RemoteMedia.Enable {
+		changes = append(changes, fmt.Sprintf("remote-media.enable: %t -> %t", oldCfg.RemoteMedia.Enable, newCfg.RemoteMedia.Enable))
+	}
+	if oldCfg.RemoteMedia.MaxSizeMB != newCfg.RemoteMedia.MaxSizeMB {
+		changes = append(changes, fmt.Sprintf("remote-media.max-size-mb: %d -> %d", oldCfg.RemoteMedia.MaxSizeMB, newCfg.RemoteMedia.MaxSizeMB))
+	}
+	if oldCfg.RemoteMedia.TimeoutSeconds != newCfg.RemoteMedia.TimeoutSeconds {
+		changes = append(changes, fmt.Sprintf("remote-media.timeout-seconds: %d -> %d", oldCfg.RemoteMedia.TimeoutSeconds, newCfg.RemoteMedia.TimeoutSeconds))
+	}
+	if !reflect.DeepEqual(oldCfg.RemoteMedia.AllowedHosts, newCfg.RemoteMedia.AllowedHosts) {
+		changes = append(changes, fmt.Sprintf("remote-media.allowed-hosts: updated (%d -> %d entries)", len(oldCfg.RemoteMedia.AllowedHosts), len(newCfg.RemoteMedia.AllowedHosts)))
+	}
+	if !reflect.DeepEqual(oldCfg.ModelCapabilities, newCfg.ModelCapabilities) {
+		changes = append(changes, fmt.Sprintf("model-capabilities: updated (%d -> %d entries)", len(oldCfg.ModelCapabilities), len(newCfg.ModelCapabilities)))
+	}
 
 	// Remote management (never print the key)
 	if oldCfg.RemoteManagement.AllowRemote != newCfg.RemoteManagement.AllowRemote {
@@ -268,6 +461,10 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 		}
 	}
 
+	if !reflect.DeepEqual(oldCfg.VertexRegions, newCfg.VertexRegions) {
+		changes = append(changes, fmt.Sprintf("vertex-regions: %d -> %d rules", len(oldCfg.VertexRegions), len(newCfg.VertexRegions)))
+	}
+
 	// Vertex-compatible API keys
 	if len(oldCfg.VertexCompatAPIKey) != len(newCfg.VertexCompatAPIKey) {
 		changes = append(changes, fmt.Sprintf("vertex-api-key count: %d -> %d", len(oldCfg.VertexCompatAPIKey), len(newCfg.VertexCompatAPIKey)))
@@ -388,3 +585,44 @@ func equalUpstreamAPIKeys(a, b []config.AmpUpstreamAPIKeyEntry) bool {
 	}
 	return true
 }
+
+// diffProviderKeys describes the changes between two entry lists of a config.APIKeyProviders
+// provider without printing key material.
+func diffProviderKeys(provider config.APIKeyProvider, oldKeys, newKeys []config.ProviderKey) []string {
+	if len(oldKeys) != len(newKeys) {
+		return []string{fmt.Sprintf("%s count: %d -> %d", provider.ConfigKey, len(oldKeys), len(newKeys))}
+	}
+	var changes []string
+	name := provider.Name
+	for i := range oldKeys {
+		o := oldKeys[i]
+		n := newKeys[i]
+		if strings.TrimSpace(o.BaseURL) != strings.TrimSpace(n.BaseURL) {
+			changes = append(changes, fmt.Sprintf("%s[%d].base-url: %s -> %s", name, i, strings.TrimSpace(o.BaseURL), strings.TrimSpace(n.BaseURL)))
+		}
+		if strings.TrimSpace(o.ProxyURL) != strings.TrimSpace(n.ProxyURL) {
+			changes = append(changes, fmt.Sprintf("%s[%d].proxy-url: %s -> %s", name, i, formatProxyURL(o.ProxyURL), formatProxyURL(n.ProxyURL)))
+		}
+		if strings.TrimSpace(o.Prefix) != strings.TrimSpace(n.Prefix) {
+			changes = append(changes, fmt.Sprintf("%s[%d].prefix: %s -> %s", name, i, strings.TrimSpace(o.Prefix), strings.TrimSpace(n.Prefix)))
+		}
+		if strings.TrimSpace(o.APIKey) != strings.TrimSpace(n.APIKey) {
+			changes = append(changes, fmt.Sprintf("%s[%d].api-key: updated", name, i))
+		}
+		if strings.TrimSpace(o.KeepAlive) != strings.TrimSpace(n.KeepAlive) {
+			changes = append(changes, fmt.Sprintf("%s[%d].keep-alive: %s -> %s", name, i, strings.TrimSpace(o.KeepAlive), strings.TrimSpace(n.KeepAlive)))
+		}
+		if !equalStringMap(o.Headers, n.Headers) {
+			changes = append(changes, fmt.Sprintf("%s[%d].headers: updated", name, i))
+		}
+		if ComputeProviderModelsHash(o.Models) != ComputeProviderModelsHash(n.Models) {
+			changes = append(changes, fmt.Sprintf("%s[%d].models: updated (%d -> %d entries)", name, i, len(o.Models), len(n.Models)))
+		}
+		oldExcluded := SummarizeExcludedModels(o.ExcludedModels)
+		newExcluded := SummarizeExcludedModels(n.ExcludedModels)
+		if oldExcluded.hash != newExcluded.hash {
+			changes = append(changes, fmt.Sprintf("%s[%d].excluded-models: updated (%d -> %d entries)", name, i, oldExcluded.count, newExcluded.count))
+		}
+	}
+	return changes
+}
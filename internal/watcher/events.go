@@ -33,6 +33,10 @@ func (w *Watcher) start(ctx context.Context) error {
 		return errAddConfig
 	}
 	log.Debugf("watching config file: %s", w.configPath)
+	w.clientsMutex.RLock()
+	cfg := w.config
+	w.clientsMutex.RUnlock()
+	w.watchConfigSources(cfg)
 
 	if errAddAuthDir := w.watcher.Add(w.authDir); errAddAuthDir != nil {
 		log.Errorf("failed to watch auth directory %s: %v", w.authDir, errAddAuthDir)
@@ -43,6 +47,7 @@ func (w *Watcher) start(ctx context.Context) error {
 	w.watchKiroIDETokenFile()
 
 	go w.processEvents(ctx)
+	w.watchReloadSignal(ctx)
 
 	w.reloadClients(true, nil, false)
 	return nil
@@ -94,7 +99,7 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 	normalizedName := w.normalizeAuthPath(event.Name)
 	normalizedConfigPath := w.normalizeAuthPath(w.configPath)
 	normalizedAuthDir := w.normalizeAuthPath(w.authDir)
-	isConfigEvent := normalizedName == normalizedConfigPath && event.Op&configOps != 0
+	isConfigEvent := (normalizedName == normalizedConfigPath || w.isConfigSource(normalizedName)) && event.Op&configOps != 0
 	authOps := fsnotify.Create | fsnotify.Write | fsnotify.Remove | fsnotify.Rename
 	isAuthJSON := strings.HasPrefix(normalizedName, normalizedAuthDir) && strings.HasSuffix(normalizedName, ".json") && event.Op&authOps != 0
 	isKiroIDEToken := w.isKiroIDETokenFile(event.Name) && event.Op&authOps != 0
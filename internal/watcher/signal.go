@@ -0,0 +1,47 @@
+// signal.go implements SIGHUP-triggered reloads of the config file and auth directory.
+// It complements fsnotify for environments where file events are unreliable (e.g. mounted volumes).
+package watcher
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Reload re-reads the config file and rescans the auth directory immediately, bypassing the
+// content-hash checks used for file events. Existing accounts keep serving in-flight requests;
+// removed ones are disabled rather than torn down.
+func (w *Watcher) Reload() {
+	w.stopConfigReloadTimer()
+	if !w.reloadConfigWithRescan(true) {
+		log.Warn("config reload failed, keeping current config and rescanning auth directory")
+		w.reloadClients(true, nil, false)
+		return
+	}
+	if hash, errHash := w.configHash(); errHash == nil {
+		w.clientsMutex.Lock()
+		w.lastConfigHash = hash
+		w.clientsMutex.Unlock()
+	}
+}
+
+// watchReloadSignal triggers Reload whenever the process receives SIGHUP until ctx is done.
+func (w *Watcher) watchReloadSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				log.Info("SIGHUP received, reloading config and auth directory")
+				w.Reload()
+			}
+		}
+	}()
+}
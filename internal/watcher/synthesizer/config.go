@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	kiroauth "github.com/router-for-me/CLIProxyAPI/v6/internal/auth/kiro"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher/diff"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	log "github.com/sirupsen/logrus"
@@ -33,6 +34,8 @@ func (s *ConfigSynthesizer) Synthesize(ctx *SynthesisContext) ([]*coreauth.Auth,
 	out = append(out, s.synthesizeClaudeKeys(ctx)...)
 	// Codex API Keys
 	out = append(out, s.synthesizeCodexKeys(ctx)...)
+	// Mistral, xAI, DeepSeek API Keys and Ollama servers
+	out = append(out, s.synthesizeProviderKeys(ctx)...)
 	// Kiro (AWS CodeWhisperer)
 	out = append(out, s.synthesizeKiroKeys(ctx)...)
 	// OpenAI-compat
@@ -186,6 +189,72 @@ func (s *ConfigSynthesizer) synthesizeCodexKeys(ctx *SynthesisContext) []*coreau
 	return out
 }
 
+// synthesizeProviderKeys creates Auth entries for the providers configured through
+// config.ProviderKey entries, such as Mistral API keys and Ollama servers.
+func (s *ConfigSynthesizer) synthesizeProviderKeys(ctx *SynthesisContext) []*coreauth.Auth {
+	cfg := ctx.Config
+	now := ctx.Now
+	idGen := ctx.IDGenerator
+
+	out := make([]*coreauth.Auth, 0)
+	for _, provider := range config.APIKeyProviders {
+		kind, label := provider.Name+":apikey", provider.Name+"-apikey"
+		if provider.Keyless {
+			kind, label = provider.Name+":server", provider.Name
+		}
+		for _, entry := range *provider.Keys(cfg) {
+			key := strings.TrimSpace(entry.APIKey)
+			if provider.Keyless && len(entry.Models) == 0 || !provider.Keyless && key == "" {
+				continue
+			}
+			prefix := strings.TrimSpace(entry.Prefix)
+			base := strings.TrimSpace(entry.BaseURL)
+			if base == "" {
+				base = provider.DefaultBaseURL
+			}
+			parts := []string{key, base}
+			if provider.Keyless {
+				// Keyless servers are identified by their address first.
+				parts = []string{base, key}
+			}
+			id, token := idGen.Next(kind, parts...)
+			attrs := map[string]string{
+				"source": fmt.Sprintf("config:%s[%s]", provider.Name, token),
+			}
+			if key != "" {
+				attrs["api_key"] = key
+			}
+			if base != "" {
+				attrs["base_url"] = base
+			}
+			if keepAlive := strings.TrimSpace(entry.KeepAlive); keepAlive != "" {
+				attrs["keep_alive"] = keepAlive
+			}
+			if entry.Priority != 0 {
+				attrs["priority"] = strconv.Itoa(entry.Priority)
+			}
+			if hash := diff.ComputeProviderModelsHash(entry.Models); hash != "" {
+				attrs["models_hash"] = hash
+			}
+			addConfigHeadersToAttrs(entry.Headers, attrs)
+			a := &coreauth.Auth{
+				ID:         id,
+				Provider:   provider.Name,
+				Label:      label,
+				Prefix:     prefix,
+				Status:     coreauth.StatusActive,
+				ProxyURL:   strings.TrimSpace(entry.ProxyURL),
+				Attributes: attrs,
+				CreatedAt:  now,
+				UpdatedAt:  now,
+			}
+			ApplyAuthExcludedModelsMeta(a, cfg, entry.ExcludedModels, "apikey")
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
 // synthesizeOpenAICompat creates Auth entries for OpenAI-compatible providers.
 func (s *ConfigSynthesizer) synthesizeOpenAICompat(ctx *SynthesisContext) []*coreauth.Auth {
 	cfg := ctx.Config
@@ -287,6 +287,117 @@ func TestConfigSynthesizer_CodexKeys_SkipsEmptyAndHeaders(t *testing.T) {
 	}
 }
 
+func TestConfigSynthesizer_MistralKeys(t *testing.T) {
+	synth := NewConfigSynthesizer()
+	ctx := &SynthesisContext{
+		Config: &config.Config{
+			MistralKey: []config.ProviderKey{
+				{APIKey: "  "}, // whitespace, should be skipped
+				{
+					APIKey:  "mistral-key-123",
+					BaseURL: "https://codestral.mistral.ai/v1",
+					Models:  []config.ProviderModel{{Name: "codestral-latest", Alias: "codestral"}},
+				},
+			},
+		},
+		Now:         time.Now(),
+		IDGenerator: NewStableIDGenerator(),
+	}
+
+	auths, err := synth.Synthesize(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(auths) != 1 {
+		t.Fatalf("expected 1 auth, got %d", len(auths))
+	}
+	if auths[0].Provider != "mistral" || auths[0].Label != "mistral-apikey" {
+		t.Errorf("expected mistral provider and label, got %s/%s", auths[0].Provider, auths[0].Label)
+	}
+	if auths[0].Attributes["base_url"] != "https://codestral.mistral.ai/v1" || auths[0].Attributes["models_hash"] == "" {
+		t.Errorf("unexpected attributes: %v", auths[0].Attributes)
+	}
+}
+
+func TestConfigSynthesizer_XAIKeys(t *testing.T) {
+	synth := NewConfigSynthesizer()
+	ctx := &SynthesisContext{
+		Config: &config.Config{
+			XAIKey: []config.ProviderKey{{APIKey: "xai-key-123", Prefix: "team", ProxyURL: "http://proxy.local"}},
+		},
+		Now:         time.Now(),
+		IDGenerator: NewStableIDGenerator(),
+	}
+
+	auths, err := synth.Synthesize(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(auths) != 1 {
+		t.Fatalf("expected 1 auth, got %d", len(auths))
+	}
+	if auths[0].Provider != "xai" || auths[0].Label != "xai-apikey" || auths[0].Prefix != "team" {
+		t.Errorf("unexpected auth: %s/%s/%s", auths[0].Provider, auths[0].Label, auths[0].Prefix)
+	}
+	if auths[0].ProxyURL != "http://proxy.local" || auths[0].Attributes["api_key"] != "xai-key-123" {
+		t.Errorf("unexpected auth settings: %s %v", auths[0].ProxyURL, auths[0].Attributes)
+	}
+}
+
+func TestConfigSynthesizer_DeepSeekKeys(t *testing.T) {
+	synth := NewConfigSynthesizer()
+	ctx := &SynthesisContext{
+		Config: &config.Config{
+			DeepSeekKey: []config.ProviderKey{{APIKey: "sk-deepseek-123", Prefix: "team", ProxyURL: "http://proxy.local"}},
+		},
+		Now:         time.Now(),
+		IDGenerator: NewStableIDGenerator(),
+	}
+
+	auths, err := synth.Synthesize(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(auths) != 1 {
+		t.Fatalf("expected 1 auth, got %d", len(auths))
+	}
+	if auths[0].Provider != "deepseek" || auths[0].Label != "deepseek-apikey" || auths[0].Prefix != "team" {
+		t.Errorf("unexpected auth: %s/%s/%s", auths[0].Provider, auths[0].Label, auths[0].Prefix)
+	}
+	if auths[0].ProxyURL != "http://proxy.local" || auths[0].Attributes["api_key"] != "sk-deepseek-123" {
+		t.Errorf("unexpected auth settings: %s %v", auths[0].ProxyURL, auths[0].Attributes)
+	}
+}
+
+func TestConfigSynthesizer_OllamaKeys(t *testing.T) {
+	synth := NewConfigSynthesizer()
+	ctx := &SynthesisContext{
+		Config: &config.Config{
+			OllamaKey: []config.ProviderKey{
+				{KeepAlive: "30m", Models: []config.ProviderModel{{Name: "llama3.1", Alias: "local"}}},
+				{BaseURL: "http://gpu.local:11434"},
+			},
+		},
+		Now:         time.Now(),
+		IDGenerator: NewStableIDGenerator(),
+	}
+
+	auths, err := synth.Synthesize(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(auths) != 1 {
+		t.Fatalf("expected 1 auth, got %d", len(auths))
+	}
+	if auths[0].Provider != "ollama" || auths[0].Label != "ollama" {
+		t.Errorf("unexpected auth: %s/%s", auths[0].Provider, auths[0].Label)
+	}
+	attrs := auths[0].Attributes
+	if attrs["base_url"] != config.DefaultOllamaBaseURL || attrs["keep_alive"] != "30m" || attrs["api_key"] != "" || attrs["models_hash"] == "" {
+		t.Errorf("unexpected attributes: %v", attrs)
+	}
+}
+
 func TestConfigSynthesizer_OpenAICompat(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -40,6 +40,7 @@ type Watcher struct {
 	lastAuthHashes    map[string]string
 	lastRemoveTimes   map[string]time.Time
 	lastConfigHash    string
+	configSources     map[string]struct{}
 	authQueue         chan<- AuthUpdate
 	currentAuths      map[string]*coreauth.Auth
 	runtimeAuths      map[string]*coreauth.Auth
@@ -1488,3 +1488,47 @@ func TestScheduleProcessEventsStopsOnContextDone(t *testing.T) {
 func hexString(data []byte) string {
 	return strings.ToLower(fmt.Sprintf("%x", data))
 }
+
+func TestReloadForcesConfigReloadAndAuthRescan(t *testing.T) {
+	tmpDir := t.TempDir()
+	authDir := filepath.Join(tmpDir, "auth")
+	if err := os.MkdirAll(authDir, 0o755); err != nil {
+		t.Fatalf("failed to create auth dir: %v", err)
+	}
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	data, err := yaml.Marshal(&config.Config{Port: 8080, AuthDir: authDir})
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+	if err = os.WriteFile(configPath, data, 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	reloads := 0
+	w := &Watcher{
+		configPath:     configPath,
+		authDir:        authDir,
+		reloadCallback: func(*config.Config) { reloads++ },
+	}
+	w.reloadConfigIfChanged()
+	if reloads != 1 {
+		t.Fatalf("expected initial reload, got %d", reloads)
+	}
+
+	authPath := filepath.Join(authDir, "new.json")
+	if err = os.WriteFile(authPath, []byte(`{"type":"claude","email":"a@example.com"}`), 0o600); err != nil {
+		t.Fatalf("failed to write auth file: %v", err)
+	}
+
+	// Unchanged config content still reloads and picks up the new auth file.
+	w.Reload()
+	if reloads != 2 {
+		t.Fatalf("expected forced reload to trigger callback, got %d", reloads)
+	}
+	w.clientsMutex.RLock()
+	_, tracked := w.lastAuthHashes[w.normalizeAuthPath(authPath)]
+	w.clientsMutex.RUnlock()
+	if !tracked {
+		t.Fatalf("expected auth directory rescan to track %s", authPath)
+	}
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// CanarySplitStats reports the traffic a canary split has served since the process started.
+type CanarySplitStats struct {
+	Alias    string `json:"alias"`
+	Model    string `json:"model"`
+	Weight   int    `json:"weight"`
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"`
+	// ErrorRate is Errors divided by Requests.
+	ErrorRate float64 `json:"error-rate"`
+	// AvgLatencyMs is the mean latency of successful requests: the total for non-streaming
+	// requests, the time to first payload for streams.
+	AvgLatencyMs int64 `json:"avg-latency-ms"`
+	// Labels is the credential label selector of the split, if any.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type canaryCounters struct {
+	requests  int64
+	errors    int64
+	successes int64
+	latency   time.Duration
+}
+
+var (
+	canaryMu    sync.Mutex
+	canaryStats = make(map[string]*canaryCounters)
+)
+
+func canaryStatsKey(alias, model string, labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, strings.TrimSpace(key)+"="+strings.ToLower(strings.TrimSpace(value)))
+	}
+	sort.Strings(pairs)
+	return strings.ToLower(alias) + "\x00" + strings.ToLower(model) + "\x00" + strings.Join(pairs, ",")
+}
+
+// canaryChoice is the split that serves a request for a canary alias.
+type canaryChoice struct {
+	alias  string
+	model  string
+	labels map[string]string
+	once   sync.Once
+}
+
+// addMetadata restricts the request to the credentials of the split, when it has a label
+// selector. A nil choice adds nothing.
+func (c *canaryChoice) addMetadata(meta map[string]any) {
+	if c == nil || len(c.labels) == 0 {
+		return
+	}
+	meta[coreexecutor.AuthLabelsMetadataKey] = c.labels
+}
+
+// record counts the outcome of the request once; later calls are ignored. A nil choice records
+// nothing.
+func (c *canaryChoice) record(errMsg *interfaces.ErrorMessage, latency time.Duration) {
+	if c == nil {
+		return
+	}
+	c.once.Do(func() {
+		canaryMu.Lock()
+		defer canaryMu.Unlock()
+		key := canaryStatsKey(c.alias, c.model, c.labels)
+		counters := canaryStats[key]
+		if counters == nil {
+			counters = &canaryCounters{}
+			canaryStats[key] = counters
+		}
+		counters.requests++
+		if errMsg != nil {
+			counters.errors++
+			return
+		}
+		counters.successes++
+		counters.latency += latency
+	})
+}
+
+// CanaryStats returns the traffic of every split of routes.
+func CanaryStats(routes []config.CanaryRoute) []CanarySplitStats {
+	canaryMu.Lock()
+	defer canaryMu.Unlock()
+	out := make([]CanarySplitStats, 0)
+	for _, route := range routes {
+		alias := strings.TrimSpace(route.Alias)
+		for _, split := range route.Splits {
+			model := strings.TrimSpace(split.Model)
+			stats := CanarySplitStats{Alias: alias, Model: model, Weight: split.Weight, Labels: split.Labels}
+			if counters := canaryStats[canaryStatsKey(alias, model, split.Labels)]; counters != nil {
+				stats.Requests = counters.requests
+				stats.Errors = counters.errors
+				if counters.requests > 0 {
+					stats.ErrorRate = float64(counters.errors) / float64(counters.requests)
+				}
+				if counters.successes > 0 {
+					stats.AvgLatencyMs = (counters.latency / time.Duration(counters.successes)).Milliseconds()
+				}
+			}
+			out = append(out, stats)
+		}
+	}
+	return out
+}
+
+// ResetCanaryStats clears the counters of all splits.
+func ResetCanaryStats() {
+	canaryMu.Lock()
+	canaryStats = make(map[string]*canaryCounters)
+	canaryMu.Unlock()
+}
+
+// applyCanaryRouting picks the model that serves a request for a canary alias. Splits the
+// caller may not use, that no provider serves or whose labels no credential carries are skipped; among the rest one is chosen with a
+// probability proportional to its weight, or by hashing the conversation when the route is
+// sticky. The substitution is reported in response headers. Without a matching route, or when
+// no split is usable, the request keeps its model and the returned choice is nil.
+func (h *BaseAPIHandler) applyCanaryRouting(ctx context.Context, modelName string, rawJSON []byte) (string, []byte, *canaryChoice) {
+	if h == nil || h.Cfg == nil || ctx == nil || len(h.Cfg.CanaryRouting) == 0 {
+		return modelName, rawJSON, nil
+	}
+	route := canaryRoute(h.Cfg.CanaryRouting, modelName)
+	if route == nil {
+		return modelName, rawJSON, nil
+	}
+
+	var (
+		candidates []config.CanarySplit
+		total      int
+	)
+	for _, split := range route.Splits {
+		split.Model = strings.TrimSpace(split.Model)
+		if split.Model == "" || split.Weight <= 0 {
+			continue
+		}
+		if h.checkModelAccess(ctx, split.Model) != nil {
+			continue
+		}
+		if _, _, errMsg := h.getRequestDetails(split.Model); errMsg != nil {
+			continue
+		}
+		if len(split.Labels) > 0 && !h.AuthManager.HasAuthWithLabels(split.Labels) {
+			continue
+		}
+		candidates = append(candidates, split)
+		total += split.Weight
+	}
+	if total == 0 {
+		return modelName, rawJSON, nil
+	}
+
+	var pick int
+	if key := conversationSessionKey(ctx, rawJSON); route.Sticky && key != "" {
+		hasher := fnv.New64a()
+		_, _ = hasher.Write([]byte(strings.ToLower(modelName) + "\x00" + key))
+		pick = int(hasher.Sum64() % uint64(total))
+	} else {
+		pick = rand.Intn(total)
+	}
+	chosen := candidates[len(candidates)-1]
+	for _, split := range candidates {
+		if pick < split.Weight {
+			chosen = split
+			break
+		}
+		pick -= split.Weight
+	}
+
+	choice := &canaryChoice{alias: strings.TrimSpace(route.Alias), model: chosen.Model, labels: chosen.Labels}
+	if strings.EqualFold(chosen.Model, modelName) {
+		return modelName, rawJSON, choice
+	}
+	if gjson.GetBytes(rawJSON, "model").Exists() {
+		if updated, err := sjson.SetBytes(rawJSON, "model", chosen.Model); err == nil {
+			rawJSON = updated
+		}
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		ginCtx.Header("X-CPA-Requested-Model", modelName)
+		ginCtx.Header("X-CPA-Served-Model", chosen.Model)
+	}
+	log.Debugf("canary routing: serving %s with %s", modelName, chosen.Model)
+	return chosen.Model, rawJSON, choice
+}
+
+func canaryRoute(routes []config.CanaryRoute, modelName string) *config.CanaryRoute {
+	for i := range routes {
+		if strings.EqualFold(strings.TrimSpace(routes[i].Alias), modelName) {
+			return &routes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyCanaryRouting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	modelRegistry := registry.GetGlobalRegistry()
+	modelRegistry.RegisterClient("test-canary-routing", "gemini", []*registry.ModelInfo{
+		{ID: "canary-stable"}, {ID: "canary-next"},
+	})
+	t.Cleanup(func() { modelRegistry.UnregisterClient("test-canary-routing") })
+	t.Cleanup(ResetCanaryStats)
+
+	cfg := &sdkconfig.SDKConfig{CanaryRouting: []sdkconfig.CanaryRoute{{
+		Alias: "canary-stable",
+		Splits: []sdkconfig.CanarySplit{
+			{Model: "canary-stable", Weight: 75},
+			{Model: "canary-next", Weight: 25},
+			{Model: "canary-unserved", Weight: 1000},
+		},
+	}}}
+	h := NewBaseAPIHandlers(cfg, coreauth.NewManager(nil, nil, nil))
+	newCtx := func() (context.Context, *httptest.ResponseRecorder) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		return context.WithValue(context.Background(), "gin", c), recorder
+	}
+
+	served := map[string]int{}
+	for i := 0; i < 2000; i++ {
+		ctx, recorder := newCtx()
+		model, body, choice := h.applyCanaryRouting(ctx, "canary-stable", []byte(`{"model":"canary-stable"}`))
+		if choice == nil {
+			t.Fatal("expected a canary choice")
+		}
+		if gjson.GetBytes(body, "model").String() != model {
+			t.Fatalf("body model %s does not match served model %s", body, model)
+		}
+		if model == "canary-next" && recorder.Header().Get("X-CPA-Served-Model") != "canary-next" {
+			t.Fatal("served model header missing")
+		}
+		served[model]++
+		var errMsg *interfaces.ErrorMessage
+		if model == "canary-next" && i%2 == 0 {
+			errMsg = &interfaces.ErrorMessage{StatusCode: http.StatusBadGateway, Error: errors.New("upstream failed")}
+		}
+		choice.record(errMsg, 10*time.Millisecond)
+		choice.record(nil, time.Second) // later outcomes of the same request are ignored
+	}
+	if served["canary-unserved"] != 0 {
+		t.Fatal("a split without providers must never be chosen")
+	}
+	if share := float64(served["canary-next"]) / 2000; share < 0.2 || share > 0.3 {
+		t.Fatalf("canary share = %.3f, want about 0.25", share)
+	}
+
+	stats := CanaryStats(cfg.CanaryRouting)
+	if len(stats) != 3 {
+		t.Fatalf("expected stats for 3 splits, got %+v", stats)
+	}
+	next := stats[1]
+	if next.Requests != int64(served["canary-next"]) || next.Errors == 0 || next.ErrorRate < 0.4 || next.ErrorRate > 0.6 || next.AvgLatencyMs != 10 {
+		t.Fatalf("unexpected canary split stats: %+v", next)
+	}
+	if stats[0].Errors != 0 || stats[0].Requests != int64(served["canary-stable"]) {
+		t.Fatalf("unexpected stable split stats: %+v", stats[0])
+	}
+
+	if model, _, choice := h.applyCanaryRouting(context.Background(), "other-model", []byte(`{}`)); model != "other-model" || choice != nil {
+		t.Fatal("models without a route must not be rerouted")
+	}
+}
+
+func TestApplyCanaryRoutingSticky(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	modelRegistry := registry.GetGlobalRegistry()
+	modelRegistry.RegisterClient("test-canary-sticky", "gemini", []*registry.ModelInfo{
+		{ID: "sticky-a"}, {ID: "sticky-b"},
+	})
+	t.Cleanup(func() { modelRegistry.UnregisterClient("test-canary-sticky") })
+	t.Cleanup(ResetCanaryStats)
+
+	cfg := &sdkconfig.SDKConfig{CanaryRouting: []sdkconfig.CanaryRoute{{
+		Alias:  "sticky-alias",
+		Sticky: true,
+		Splits: []sdkconfig.CanarySplit{{Model: "sticky-a", Weight: 50}, {Model: "sticky-b", Weight: 50}},
+	}}}
+	h := NewBaseAPIHandlers(cfg, coreauth.NewManager(nil, nil, nil))
+
+	served := map[string]bool{}
+	for conversation := 0; conversation < 20; conversation++ {
+		body := []byte(fmt.Sprintf(`{"model":"sticky-alias","messages":[{"role":"user","content":"conversation %d"}]}`, conversation))
+		first, _, _ := h.applyCanaryRouting(context.Background(), "sticky-alias", body)
+		for turn := 0; turn < 5; turn++ {
+			if model, _, _ := h.applyCanaryRouting(context.Background(), "sticky-alias", body); model != first {
+				t.Fatalf("conversation %d moved from %s to %s", conversation, first, model)
+			}
+		}
+		served[first] = true
+	}
+	if !served["sticky-a"] || !served["sticky-b"] {
+		t.Fatalf("expected conversations on both splits, got %v", served)
+	}
+}
+
+func TestApplyCanaryRoutingLabels(t *testing.T) {
+	modelRegistry := registry.GetGlobalRegistry()
+	modelRegistry.RegisterClient("test-canary-labels", "gemini", []*registry.ModelInfo{{ID: "labels-model"}})
+	t.Cleanup(func() { modelRegistry.UnregisterClient("test-canary-labels") })
+	t.Cleanup(ResetCanaryStats)
+
+	cfg := &sdkconfig.SDKConfig{CanaryRouting: []sdkconfig.CanaryRoute{{
+		Alias:  "labels-model",
+		Splits: []sdkconfig.CanarySplit{{Model: "labels-model", Weight: 100, Labels: map[string]string{"pool": "canary"}}},
+	}}}
+	manager := coreauth.NewManager(nil, nil, nil)
+	h := NewBaseAPIHandlers(cfg, manager)
+
+	if _, _, choice := h.applyCanaryRouting(context.Background(), "labels-model", []byte(`{}`)); choice != nil {
+		t.Fatal("a split whose labels no credential carries must be skipped")
+	}
+
+	auth := &coreauth.Auth{ID: "canary-pool", Provider: "gemini"}
+	auth.SetLabels(map[string]string{"pool": "canary"})
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	_, _, choice := h.applyCanaryRouting(context.Background(), "labels-model", []byte(`{}`))
+	meta := map[string]any{}
+	choice.addMetadata(meta)
+	if labels, _ := meta[coreexecutor.AuthLabelsMetadataKey].(map[string]string); labels["pool"] != "canary" {
+		t.Fatalf("metadata = %v, want the split's label selector", meta)
+	}
+}
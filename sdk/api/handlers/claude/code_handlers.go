@@ -10,7 +10,6 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -19,6 +18,7 @@ import (
 	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
 	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
@@ -53,7 +53,7 @@ func (h *ClaudeCodeAPIHandler) HandlerType() string {
 func (h *ClaudeCodeAPIHandler) Models() []map[string]any {
 	// Get dynamic models from the global registry
 	modelRegistry := registry.GetGlobalRegistry()
-	return modelRegistry.GetAvailableModels("claude")
+	return util.AnnotateModelCapabilities(modelRegistry.GetAvailableModels("claude"))
 }
 
 // ClaudeMessages handles Claude-compatible streaming chat completions.
@@ -128,7 +128,7 @@ func (h *ClaudeCodeAPIHandler) ClaudeCountTokens(c *gin.Context) {
 // Parameters:
 //   - c: The Gin context for the request.
 func (h *ClaudeCodeAPIHandler) ClaudeModels(c *gin.Context) {
-	models := h.Models()
+	models := h.FilterModelsForCaller(c, h.Models())
 	firstID := ""
 	lastID := ""
 	if len(models) > 0 {
@@ -234,11 +234,17 @@ func (h *ClaudeCodeAPIHandler) handleStreamingResponse(c *gin.Context, rawJSON [
 	}
 
 	// Peek at the first chunk to determine success or failure before setting headers
+	keepAlive := h.FirstChunkKeepAlive()
 	for {
 		select {
 		case <-c.Request.Context().Done():
 			cliCancel(c.Request.Context().Err())
 			return
+		case <-keepAlive:
+			setSSEHeaders()
+			handlers.WriteSSEKeepAlive(c, flusher)
+			h.forwardClaudeStream(c, flusher, handlers.NewSSEFramer(h.HandlerType()), func(err error) { cliCancel(err) }, dataChan, errChan)
+			return
 		case errMsg, ok := <-errChan:
 			if !ok {
 				// Err channel closed cleanly; wait for data channel.
@@ -266,58 +272,46 @@ func (h *ClaudeCodeAPIHandler) handleStreamingResponse(c *gin.Context, rawJSON [
 			setSSEHeaders()
 
 			// Write the first chunk
-			if len(chunk) > 0 {
-				_, _ = c.Writer.Write(chunk)
+			framer := handlers.NewSSEFramer(h.HandlerType())
+			if out := framer.Frame(chunk); len(out) > 0 {
+				_, _ = c.Writer.Write(out)
 				flusher.Flush()
 			}
 
 			// Continue streaming the rest
-			h.forwardClaudeStream(c, flusher, func(err error) { cliCancel(err) }, dataChan, errChan)
+			h.forwardClaudeStream(c, flusher, framer, func(err error) { cliCancel(err) }, dataChan, errChan)
 			return
 		}
 	}
 }
 
-func (h *ClaudeCodeAPIHandler) forwardClaudeStream(c *gin.Context, flusher http.Flusher, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage) {
+func (h *ClaudeCodeAPIHandler) forwardClaudeStream(c *gin.Context, flusher http.Flusher, framer *handlers.SSEFramer, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage) {
 	h.ForwardStream(c, flusher, cancel, data, errs, handlers.StreamForwardOptions{
 		WriteChunk: func(chunk []byte) {
-			if len(chunk) == 0 {
-				return
+			if out := framer.Frame(chunk); len(out) > 0 {
+				_, _ = c.Writer.Write(out)
 			}
-			_, _ = c.Writer.Write(chunk)
 		},
 		WriteTerminalError: func(errMsg *interfaces.ErrorMessage) {
 			if errMsg == nil {
 				return
 			}
+			_, _ = c.Writer.Write(framer.Flush())
 			status := http.StatusInternalServerError
 			if errMsg.StatusCode > 0 {
 				status = errMsg.StatusCode
 			}
 			c.Status(status)
 
-			errorBytes, _ := json.Marshal(h.toClaudeError(errMsg))
+			errText := http.StatusText(status)
+			if errMsg.Error != nil && errMsg.Error.Error() != "" {
+				errText = errMsg.Error.Error()
+			}
+			errorBytes := handlers.BuildErrorResponseBodyForFormat(h.HandlerType(), status, errText)
 			_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", errorBytes)
 		},
-	})
-}
-
-type claudeErrorDetail struct {
-	Type    string `json:"type"`
-	Message string `json:"message"`
-}
-
-type claudeErrorResponse struct {
-	Type  string            `json:"type"`
-	Error claudeErrorDetail `json:"error"`
-}
-
-func (h *ClaudeCodeAPIHandler) toClaudeError(msg *interfaces.ErrorMessage) claudeErrorResponse {
-	return claudeErrorResponse{
-		Type: "error",
-		Error: claudeErrorDetail{
-			Type:    "api_error",
-			Message: msg.Error.Error(),
+		WriteDone: func() {
+			_, _ = c.Writer.Write(framer.Flush())
 		},
-	}
+	})
 }
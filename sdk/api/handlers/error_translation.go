@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/tidwall/gjson"
+)
+
+// inboundFormatKey is the gin context key holding the handler type of the current request.
+const inboundFormatKey = "inboundFormat"
+
+// upstreamError is the provider-neutral view of an upstream error body.
+type upstreamError struct {
+	message    string
+	errType    string
+	code       string
+	retryAfter time.Duration
+}
+
+// parseUpstreamError extracts the message, type, code and retry delay from an OpenAI, Anthropic
+// or Gemini error body. Plain-text bodies become the message as is.
+func parseUpstreamError(errText string) upstreamError {
+	trimmed := strings.TrimSpace(errText)
+	if !gjson.Valid(trimmed) {
+		return upstreamError{message: trimmed}
+	}
+	root := gjson.Parse(trimmed)
+	if root.IsArray() {
+		// Gemini streaming endpoints sometimes wrap the error object in an array.
+		root = root.Get("0")
+	}
+	errNode := root.Get("error")
+	if !errNode.Exists() {
+		errNode = root
+	}
+	if errNode.Type == gjson.String {
+		return upstreamError{message: errNode.String()}
+	}
+
+	out := upstreamError{message: errNode.Get("message").String()}
+	if out.message == "" {
+		out.message = trimmed
+	}
+	// Gemini uses a canonical status string and a numeric code; OpenAI and Anthropic use a type.
+	if status := errNode.Get("status"); status.Type == gjson.String {
+		out.errType = status.String()
+	} else {
+		out.errType = errNode.Get("type").String()
+	}
+	if code := errNode.Get("code"); code.Type == gjson.String {
+		out.code = code.String()
+	}
+	errNode.Get("details").ForEach(func(_, detail gjson.Result) bool {
+		if strings.HasSuffix(detail.Get(`@type`).String(), "google.rpc.RetryInfo") {
+			if delay, err := time.ParseDuration(detail.Get("retryDelay").String()); err == nil {
+				out.retryAfter = delay
+			}
+			return false
+		}
+		return true
+	})
+	return out
+}
+
+// BuildErrorResponseBodyForFormat renders an error in the error shape of the inbound protocol
+// identified by format (a handler type such as "openai", "claude" or "gemini"). Upstream error
+// bodies already in that shape are returned unchanged; bodies from other providers are converted
+// so clients never see provider-native errors. Unknown formats use the OpenAI shape.
+func BuildErrorResponseBodyForFormat(format string, status int, errText string) []byte {
+	if status <= 0 {
+		status = http.StatusInternalServerError
+	}
+	if strings.TrimSpace(errText) == "" {
+		errText = http.StatusText(status)
+	}
+	trimmed := strings.TrimSpace(errText)
+	switch format {
+	case constant.Claude:
+		if isClaudeErrorBody(trimmed) {
+			return []byte(trimmed)
+		}
+		return buildClaudeErrorBody(status, parseUpstreamError(trimmed))
+	case constant.Gemini, constant.GeminiCLI:
+		if isGeminiErrorBody(trimmed) {
+			return []byte(trimmed)
+		}
+		return buildGeminiErrorBody(status, parseUpstreamError(trimmed))
+	default:
+		if isOpenAIErrorBody(trimmed) || !gjson.Valid(trimmed) {
+			return BuildErrorResponseBody(status, trimmed)
+		}
+		return BuildErrorResponseBody(status, parseUpstreamError(trimmed).message)
+	}
+}
+
+// UpstreamRetryAfter returns the retry delay an upstream error body asks for, such as the
+// RetryInfo detail of a Gemini quota error.
+func UpstreamRetryAfter(errText string) (time.Duration, bool) {
+	retryAfter := parseUpstreamError(errText).retryAfter
+	return retryAfter, retryAfter > 0
+}
+
+func inboundFormat(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	return c.GetString(inboundFormatKey)
+}
+
+// setRetryAfterHeader preserves an upstream retry delay as a Retry-After header unless one is
+// already present.
+func setRetryAfterHeader(header http.Header, errText string) {
+	if header == nil || header.Get("Retry-After") != "" {
+		return
+	}
+	if retryAfter, ok := UpstreamRetryAfter(errText); ok {
+		seconds := int((retryAfter + time.Second - 1) / time.Second)
+		header.Set("Retry-After", strconv.Itoa(seconds))
+	}
+}
+
+func isOpenAIErrorBody(body string) bool {
+	errNode := gjson.Get(body, "error")
+	return errNode.IsObject() && errNode.Get("message").Exists() && !errNode.Get("status").Exists() && gjson.Get(body, "type").String() != "error"
+}
+
+func isClaudeErrorBody(body string) bool {
+	return gjson.Get(body, "type").String() == "error" && gjson.Get(body, "error.type").Exists()
+}
+
+func isGeminiErrorBody(body string) bool {
+	errNode := gjson.Get(body, "error")
+	return errNode.IsObject() && errNode.Get("code").Type == gjson.Number && errNode.Get("status").Exists()
+}
+
+func buildClaudeErrorBody(status int, upstream upstreamError) []byte {
+	errType := "api_error"
+	switch status {
+	case http.StatusBadRequest:
+		errType = "invalid_request_error"
+	case http.StatusUnauthorized:
+		errType = "authentication_error"
+	case http.StatusForbidden:
+		errType = "permission_error"
+	case http.StatusNotFound:
+		errType = "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		errType = "request_too_large"
+	case http.StatusTooManyRequests:
+		errType = "rate_limit_error"
+	case http.StatusServiceUnavailable, 529:
+		errType = "overloaded_error"
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"type": "error",
+		"error": map[string]any{
+			"type":    errType,
+			"message": upstream.message,
+		},
+	})
+	return payload
+}
+
+func buildGeminiErrorBody(status int, upstream upstreamError) []byte {
+	statusName := "INTERNAL"
+	switch status {
+	case http.StatusBadRequest:
+		statusName = "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		statusName = "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		statusName = "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		statusName = "NOT_FOUND"
+	case http.StatusConflict:
+		statusName = "ABORTED"
+	case http.StatusTooManyRequests:
+		statusName = "RESOURCE_EXHAUSTED"
+	case http.StatusNotImplemented:
+		statusName = "UNIMPLEMENTED"
+	case http.StatusServiceUnavailable:
+		statusName = "UNAVAILABLE"
+	case http.StatusGatewayTimeout:
+		statusName = "DEADLINE_EXCEEDED"
+	}
+	errObj := map[string]any{
+		"code":    status,
+		"message": upstream.message,
+		"status":  statusName,
+	}
+	if upstream.retryAfter > 0 {
+		errObj["details"] = []map[string]any{{
+			"@type":      "type.googleapis.com/google.rpc.RetryInfo",
+			"retryDelay": strconv.FormatFloat(upstream.retryAfter.Seconds(), 'f', -1, 64) + "s",
+		}}
+	}
+	payload, _ := json.Marshal(map[string]any{"error": errObj})
+	return payload
+}
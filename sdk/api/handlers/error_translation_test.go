@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/tidwall/gjson"
+)
+
+const geminiQuotaError = `{"error":{"code":429,"message":"Quota exceeded","status":"RESOURCE_EXHAUSTED","details":[{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"17s"}]}}`
+
+const claudeOverloadedError = `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`
+
+func TestBuildErrorResponseBodyForFormat(t *testing.T) {
+	cases := []struct {
+		name   string
+		format string
+		status int
+		input  string
+		checks map[string]string
+	}{
+		{"gemini to openai", "openai", 429, geminiQuotaError, map[string]string{"error.message": "Quota exceeded", "error.type": "rate_limit_error", "error.code": "rate_limit_exceeded"}},
+		{"gemini to claude", "claude", 429, geminiQuotaError, map[string]string{"type": "error", "error.type": "rate_limit_error", "error.message": "Quota exceeded"}},
+		{"gemini passthrough", "gemini", 429, geminiQuotaError, map[string]string{"error.status": "RESOURCE_EXHAUSTED", "error.details.0.retryDelay": "17s"}},
+		{"claude to gemini", "gemini", 503, claudeOverloadedError, map[string]string{"error.code": "503", "error.status": "UNAVAILABLE", "error.message": "Overloaded"}},
+		{"claude to responses", "openai-response", 503, claudeOverloadedError, map[string]string{"error.message": "Overloaded", "error.type": "server_error"}},
+		{"claude passthrough", "claude", 529, claudeOverloadedError, map[string]string{"error.type": "overloaded_error"}},
+		{"plain text to claude", "claude", 400, "bad input", map[string]string{"error.type": "invalid_request_error", "error.message": "bad input"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := BuildErrorResponseBodyForFormat(tc.format, tc.status, tc.input)
+			for path, want := range tc.checks {
+				if got := gjson.GetBytes(body, path).String(); got != want {
+					t.Errorf("%s = %q, want %q (body %s)", path, got, want, body)
+				}
+			}
+		})
+	}
+}
+
+func TestUpstreamRetryAfter(t *testing.T) {
+	if delay, ok := UpstreamRetryAfter(geminiQuotaError); !ok || delay != 17*time.Second {
+		t.Fatalf("retry after = %s, %v", delay, ok)
+	}
+	if _, ok := UpstreamRetryAfter(claudeOverloadedError); ok {
+		t.Fatalf("expected no retry delay for claude error")
+	}
+}
+
+func TestWriteErrorResponse_UsesInboundFormatAndRetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Set(inboundFormatKey, "claude")
+
+	h := &BaseAPIHandler{}
+	h.WriteErrorResponse(c, &interfaces.ErrorMessage{StatusCode: http.StatusTooManyRequests, Error: errors.New(geminiQuotaError)})
+
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d", recorder.Code)
+	}
+	if got := recorder.Header().Get("Retry-After"); got != "17" {
+		t.Fatalf("Retry-After = %q, want 17", got)
+	}
+	if got := gjson.Get(recorder.Body.String(), "error.type").String(); got != "rate_limit_error" {
+		t.Fatalf("error.type = %q, body %s", got, recorder.Body.String())
+	}
+}
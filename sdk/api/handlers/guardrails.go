@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/guardrail"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	"golang.org/x/net/context"
+)
+
+// guardrailPolicies holds the guardrail policies compiled from the current configuration.
+var guardrailPolicies atomic.Pointer[guardrail.Set]
+
+// configureGuardrails compiles policies once per configuration load, so requests only select
+// from the compiled set.
+func configureGuardrails(policies []config.GuardrailPolicy) {
+	guardrailPolicies.Store(guardrail.Compile(policies))
+}
+
+// applyRequestGuardrails checks the client request against the caller's guardrail policy and
+// returns the policy, for filtering the response, together with the redacted request body.
+func (h *BaseAPIHandler) applyRequestGuardrails(ctx context.Context, rawJSON []byte) (*guardrail.Policy, []byte, *interfaces.ErrorMessage) {
+	policies := guardrailPolicies.Load()
+	if h == nil || policies == nil {
+		return nil, rawJSON, nil
+	}
+	var apiKey string
+	if ctx != nil {
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok {
+			apiKey = callerAPIKey(ginCtx)
+		}
+	}
+	policy := policies.Select(apiKey)
+	if policy == nil {
+		return nil, rawJSON, nil
+	}
+	out, err := policy.CheckRequest(ctx, rawJSON, h.moderate)
+	if err != nil {
+		return nil, nil, guardrailError(err)
+	}
+	return policy, out, nil
+}
+
+// moderate runs a guardrail moderation request through the auth manager. It bypasses the
+// handler pipeline, so moderation calls are not themselves subject to guardrails or quotas.
+func (h *BaseAPIHandler) moderate(ctx context.Context, model string, request []byte) ([]byte, error) {
+	providers, normalizedModel, errMsg := h.getRequestDetails(model)
+	if errMsg != nil {
+		return nil, errMsg.Error
+	}
+	req := coreexecutor.Request{Model: normalizedModel, Payload: request}
+	opts := coreexecutor.Options{
+		OriginalRequest: request,
+		SourceFormat:    sdktranslator.FromString("openai"),
+	}
+	resp, err := h.AuthManager.Execute(ctx, providers, req, opts)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Payload, nil
+}
+
+// filterGuardrailResponse applies the response side of policy to a complete response body.
+func filterGuardrailResponse(policy *guardrail.Policy, payload []byte) ([]byte, *interfaces.ErrorMessage) {
+	if policy == nil {
+		return payload, nil
+	}
+	out, err := policy.FilterResponse(payload)
+	if err != nil {
+		return nil, guardrailError(err)
+	}
+	return out, nil
+}
+
+// filterGuardrailStream applies the response side of a policy to one stream chunk.
+func filterGuardrailStream(filter *guardrail.StreamFilter, chunk []byte) ([]byte, *interfaces.ErrorMessage) {
+	if filter == nil {
+		return chunk, nil
+	}
+	out, err := filter.Filter(chunk)
+	if err != nil {
+		return nil, guardrailError(err)
+	}
+	return out, nil
+}
+
+func guardrailError(err error) *interfaces.ErrorMessage {
+	status := http.StatusInternalServerError
+	var violation *guardrail.Violation
+	if errors.As(err, &violation) {
+		status = violation.StatusCode()
+	}
+	return &interfaces.ErrorMessage{StatusCode: status, Error: err}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// echoExecutor answers every request with the last user message, recording what it received.
+type echoExecutor struct {
+	mu       sync.Mutex
+	payloads []string
+}
+
+func (e *echoExecutor) Identifier() string { return "guard-provider" }
+
+func (e *echoExecutor) Execute(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	e.mu.Lock()
+	e.payloads = append(e.payloads, string(req.Payload))
+	e.mu.Unlock()
+	text := gjson.GetBytes(req.Payload, "messages.@reverse.0.content").String()
+	return coreexecutor.Response{Payload: []byte(`{"choices":[{"message":{"role":"assistant","content":"` + text + `"}}]}`)}, nil
+}
+
+func (e *echoExecutor) ExecuteStream(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	ch := make(chan coreexecutor.StreamChunk, 2)
+	ch <- coreexecutor.StreamChunk{Payload: []byte(`{"choices":[{"delta":{"content":"write to ops@example.com"}}]}`)}
+	ch <- coreexecutor.StreamChunk{Payload: []byte(`{"choices":[{"delta":{"content":"the forbidden word"}}]}`)}
+	close(ch)
+	return ch, nil
+}
+
+func (e *echoExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *echoExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (e *echoExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented"}
+}
+
+func newGuardrailHandler(t *testing.T, policies []sdkconfig.GuardrailPolicy) (*BaseAPIHandler, *echoExecutor) {
+	t.Helper()
+	executor := &echoExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "auth-guard", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "guard-model"}, {ID: "mod-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+	return NewBaseAPIHandlers(&sdkconfig.SDKConfig{Guardrails: policies}, manager), executor
+}
+
+func guardrailContext(apiKey string) context.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("apiKey", apiKey)
+	return context.WithValue(context.Background(), "gin", c)
+}
+
+func TestGuardrailsNonStreaming(t *testing.T) {
+	h, executor := newGuardrailHandler(t, []sdkconfig.GuardrailPolicy{
+		{Name: "default", Blocklist: []string{"forbidden"}},
+		{Name: "pii", APIKeys: []string{"pii-key"}, RedactPII: []string{"email"}, ModerationModel: "mod-model"},
+	})
+
+	_, errMsg := h.ExecuteWithAuthManager(guardrailContext("other-key"), "openai", "guard-model", []byte(`{"model":"guard-model","messages":[{"role":"user","content":"a forbidden topic"}]}`), "")
+	if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest || !strings.Contains(errMsg.Error.Error(), `policy "default"`) {
+		t.Fatalf("blocked request error = %+v", errMsg)
+	}
+
+	resp, errMsg := h.ExecuteWithAuthManager(guardrailContext("pii-key"), "openai", "guard-model", []byte(`{"model":"guard-model","messages":[{"role":"user","content":"ALLOW me@example.com"}]}`), "")
+	if errMsg != nil {
+		t.Fatalf("redacted request error = %v", errMsg.Error)
+	}
+	// The echo executor answers the moderation call with the prompt, which starts with ALLOW.
+	if len(executor.payloads) != 2 || gjson.Get(executor.payloads[0], "model").String() != "mod-model" {
+		t.Fatalf("executor payloads = %v", executor.payloads)
+	}
+	if got := gjson.Get(executor.payloads[1], "messages.0.content").String(); got != "ALLOW [REDACTED_EMAIL]" {
+		t.Fatalf("upstream prompt = %q", got)
+	}
+	if got := gjson.GetBytes(resp, "choices.0.message.content").String(); got != "ALLOW [REDACTED_EMAIL]" {
+		t.Fatalf("response = %s", resp)
+	}
+}
+
+func TestGuardrailsStreaming(t *testing.T) {
+	h, _ := newGuardrailHandler(t, []sdkconfig.GuardrailPolicy{{Blocklist: []string{"forbidden"}, RedactPII: []string{"email"}}})
+
+	data, errs := h.ExecuteStreamWithAuthManager(guardrailContext(""), "openai", "guard-model", []byte(`{"model":"guard-model","messages":[{"role":"user","content":"hi"}]}`), "")
+	var chunks []string
+	for chunk := range data {
+		chunks = append(chunks, string(chunk))
+	}
+	if len(chunks) != 1 || gjson.Get(chunks[0], "choices.0.delta.content").String() != "write to [REDACTED_EMAIL]" {
+		t.Fatalf("chunks = %v", chunks)
+	}
+	errMsg := <-errs
+	if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest || !strings.Contains(errMsg.Error.Error(), "response blocked") {
+		t.Fatalf("stream error = %+v", errMsg)
+	}
+}
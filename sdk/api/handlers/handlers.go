@@ -14,14 +14,20 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/audit"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/telemetry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tokencount"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
 	"golang.org/x/net/context"
 )
 
@@ -140,19 +146,128 @@ func StreamingBootstrapRetries(cfg *config.SDKConfig) int {
 	return retries
 }
 
+// StreamingIdleTimeout returns how long a stream may wait for the next upstream chunk.
+// Returning 0 disables the idle timeout (default when unset).
+func StreamingIdleTimeout(cfg *config.SDKConfig) time.Duration {
+	if cfg == nil || cfg.Streaming.IdleTimeoutSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(cfg.Streaming.IdleTimeoutSeconds) * time.Second
+}
+
+// StreamingMaxDuration returns how long a stream may run in total.
+// Returning 0 disables the limit (default when unset).
+func StreamingMaxDuration(cfg *config.SDKConfig) time.Duration {
+	if cfg == nil || cfg.Streaming.MaxDurationSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(cfg.Streaming.MaxDurationSeconds) * time.Second
+}
+
 func requestExecutionMetadata(ctx context.Context) map[string]any {
 	// Idempotency-Key is an optional client-supplied header used to correlate retries.
 	// It is forwarded as execution metadata; when absent we generate a UUID.
 	key := ""
+	batch := false
 	if ctx != nil {
 		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
 			key = strings.TrimSpace(ginCtx.GetHeader("Idempotency-Key"))
+			batch = coreexecutor.IsBatchRequest(ginCtx.Request.Context())
 		}
 	}
 	if key == "" {
 		key = uuid.NewString()
 	}
-	return map[string]any{idempotencyKeyMetadataKey: key}
+	meta := map[string]any{idempotencyKeyMetadataKey: key}
+	if batch {
+		meta[coreexecutor.BatchMetadataKey] = true
+	}
+	if prefs, ok := requestProviderPreferences(ctx); ok {
+		meta[coreexecutor.ProviderOrderMetadataKey] = prefs.Order
+	}
+	return meta
+}
+
+// usageTagsContextKey is the gin context key holding request metadata tags for usage records.
+const usageTagsContextKey = "usageTags"
+
+// maxUsageTagLength bounds the length of a single recorded tag value.
+const maxUsageTagLength = 256
+
+// captureUsageTags stores the request metadata fields listed in usage-metadata-keys on the
+// gin context so executors can attach them to the usage records they publish.
+func (h *BaseAPIHandler) captureUsageTags(ctx context.Context, rawJSON []byte) {
+	if h == nil || h.Cfg == nil || len(h.Cfg.UsageMetadataKeys) == 0 || ctx == nil {
+		return
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return
+	}
+	tags := make(map[string]string, len(h.Cfg.UsageMetadataKeys))
+	for _, key := range h.Cfg.UsageMetadataKeys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		value := gjson.GetBytes(rawJSON, "metadata."+gjson.Escape(key))
+		if !value.Exists() {
+			value = gjson.GetBytes(rawJSON, gjson.Escape(key))
+		}
+		if value.Type != gjson.String && value.Type != gjson.Number {
+			continue
+		}
+		text := strings.TrimSpace(value.String())
+		if text == "" {
+			continue
+		}
+		if len(text) > maxUsageTagLength {
+			text = text[:maxUsageTagLength]
+		}
+		tags[key] = text
+	}
+	if len(tags) > 0 {
+		ginCtx.Set(usageTagsContextKey, tags)
+	}
+}
+
+// usageWorkloadContextKey is the gin context key holding the workload label for usage records.
+const usageWorkloadContextKey = "usageWorkload"
+
+// captureWorkload labels the request with the active usage classifier (chat, code, agent) and
+// stores the label on the gin context for the usage records of the request.
+func captureWorkload(ctx context.Context, rawJSON []byte) {
+	if ctx == nil {
+		return
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return
+	}
+	if workload := coreusage.ClassifyRequest(rawJSON); workload != "" {
+		ginCtx.Set(usageWorkloadContextKey, workload)
+	}
+}
+
+// Gin context keys holding the client request for the usage fallback estimator, which counts
+// tokens locally when the upstream reports none.
+const (
+	usagePromptFormatContextKey = "usagePromptFormat"
+	usagePromptContextKey       = "usagePrompt"
+)
+
+// captureUsagePrompt stores the client request on the gin context so usage records can fall
+// back to estimated prompt tokens.
+func captureUsagePrompt(ctx context.Context, handlerType string, rawJSON []byte) {
+	if ctx == nil {
+		return
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return
+	}
+	ginCtx.Set(usagePromptFormatContextKey, handlerType)
+	ginCtx.Set(usagePromptContextKey, rawJSON)
 }
 
 func mergeMetadata(base, overlay map[string]any) map[string]any {
@@ -194,6 +309,12 @@ func NewBaseAPIHandlers(cfg *config.SDKConfig, authManager *coreauth.Manager) *B
 		Cfg:         cfg,
 		AuthManager: authManager,
 	}
+	if cfg != nil {
+		configureTokenBudgets(cfg.APIKeyBudgets)
+		configureGuardrails(cfg.Guardrails)
+		configureThinkingOutput(cfg.ThinkingOutput)
+		SetTrafficPaused(cfg.TrafficPause.Paused)
+	}
 	return h
 }
 
@@ -203,7 +324,15 @@ func NewBaseAPIHandlers(cfg *config.SDKConfig, authManager *coreauth.Manager) *B
 // Parameters:
 //   - clients: The new slice of AI service clients
 //   - cfg: The new application configuration
-func (h *BaseAPIHandler) UpdateClients(cfg *config.SDKConfig) { h.Cfg = cfg }
+func (h *BaseAPIHandler) UpdateClients(cfg *config.SDKConfig) {
+	h.Cfg = cfg
+	if cfg != nil {
+		configureTokenBudgets(cfg.APIKeyBudgets)
+		configureGuardrails(cfg.Guardrails)
+		configureThinkingOutput(cfg.ThinkingOutput)
+		SetTrafficPaused(cfg.TrafficPause.Paused)
+	}
+}
 
 // GetAlt extracts the 'alt' parameter from the request query string.
 // It checks both 'alt' and '$alt' parameters and returns the appropriate value.
@@ -256,18 +385,25 @@ func (h *BaseAPIHandler) GetContextWithCancel(handler interfaces.APIHandler, c *
 			parentCtx = logging.WithRequestID(parentCtx, requestID)
 		}
 	}
+	if requestCtx != nil {
+		parentCtx = logging.InheritRequestFields(parentCtx, requestCtx)
+	}
 	newCtx, cancel := context.WithCancel(parentCtx)
 	if requestCtx != nil && requestCtx != parentCtx {
+		cancelCtx := newCtx
 		go func() {
 			select {
 			case <-requestCtx.Done():
 				cancel()
-			case <-newCtx.Done():
+			case <-cancelCtx.Done():
 			}
 		}()
 	}
 	newCtx = context.WithValue(newCtx, "gin", c)
 	newCtx = context.WithValue(newCtx, "handler", handler)
+	if c != nil && handler != nil {
+		c.Set(inboundFormatKey, handler.HandlerType())
+	}
 	return newCtx, func(params ...interface{}) {
 		if h.Cfg.RequestLog && len(params) == 1 {
 			if existing, exists := c.Get("API_RESPONSE"); exists {
@@ -383,15 +519,68 @@ func appendAPIResponse(c *gin.Context, data []byte) {
 	c.Set("API_RESPONSE", bytes.Clone(data))
 }
 
+// auditModel records the model requested by the client for the request's audit entry.
+func auditModel(ctx context.Context, modelName string) {
+	if ctx == nil {
+		return
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok {
+		audit.SetModel(ginCtx, modelName)
+	}
+}
+
 // ExecuteWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+	rawJSON, fallbacks := applyOpenRouterExtensions(ctx, handlerType, modelName, rawJSON)
+	if len(fallbacks) > 0 {
+		return h.executeWithModelFallback(ctx, handlerType, modelName, fallbacks, rawJSON, alt)
+	}
+	auditModel(ctx, modelName)
+	if errMsg := h.validateRequest(handlerType, rawJSON, false); errMsg != nil {
+		return nil, errMsg
+	}
+	if errMsg := h.checkModelAccess(ctx, modelName); errMsg != nil {
+		return nil, errMsg
+	}
+	rawJSON, errMsg := h.applyLogprobsPolicy(ctx, handlerType, modelName, rawJSON)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	if errMsg := h.checkTokenBudget(ctx); errMsg != nil {
+		return nil, errMsg
+	}
+	modelName, rawJSON, canary := h.applyCanaryRouting(ctx, modelName, rawJSON)
+	modelName, rawJSON = h.applyLatencyBudget(ctx, modelName, rawJSON, false)
 	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
 	if errMsg != nil {
 		return nil, errMsg
 	}
+	if providers, errMsg = restrictProviders(ctx, modelName, providers); errMsg != nil {
+		return nil, errMsg
+	}
+	if providers, errMsg = h.applyTrafficPause(providers); errMsg != nil {
+		return nil, errMsg
+	}
+	if errMsg = h.admitRequest(ctx, providers, normalizedModel); errMsg != nil {
+		return nil, errMsg
+	}
+	defer trackInFlight()()
+	rawJSON = h.applyInboundScripts(ctx, handlerType, normalizedModel, rawJSON)
+	rawJSON = applyRemoteMedia(ctx, handlerType, providers, rawJSON)
+	policy, rawJSON, errMsg := h.applyRequestGuardrails(ctx, rawJSON)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	h.captureUsageTags(ctx, rawJSON)
+	captureWorkload(ctx, rawJSON)
+	captureUsagePrompt(ctx, handlerType, rawJSON)
 	reqMeta := requestExecutionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
+	if sessionKey := h.sessionKey(ctx, rawJSON); sessionKey != "" {
+		reqMeta[coreexecutor.SessionKeyMetadataKey] = sessionKey
+	}
+	canary.addMetadata(reqMeta)
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
 		Payload: cloneBytes(rawJSON),
@@ -403,6 +592,7 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 		SourceFormat:    sdktranslator.FromString(handlerType),
 	}
 	opts.Metadata = reqMeta
+	started := time.Now()
 	resp, err := h.AuthManager.Execute(ctx, providers, req, opts)
 	if err != nil {
 		status := http.StatusInternalServerError
@@ -417,20 +607,46 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 				addon = hdr.Clone()
 			}
 		}
-		return nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+		errMsg = &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+		canary.record(errMsg, 0)
+		return nil, errMsg
 	}
-	return cloneBytes(resp.Payload), nil
+	canary.record(nil, time.Since(started))
+	h.recordModelLatency(modelName, time.Since(started), false)
+	payload, errMsg := filterGuardrailResponse(policy, cloneBytes(resp.Payload))
+	if errMsg != nil {
+		return nil, errMsg
+	}
+	return thinkingOutputPolicy(ctx).FilterResponse(handlerType, payload), nil
 }
 
 // ExecuteCountWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+	auditModel(ctx, modelName)
+	if errMsg := h.validateRequest(handlerType, rawJSON, false); errMsg != nil {
+		return nil, errMsg
+	}
+	if errMsg := h.checkModelAccess(ctx, modelName); errMsg != nil {
+		return nil, errMsg
+	}
 	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
 	if errMsg != nil {
 		return nil, errMsg
 	}
+	if providers, errMsg = h.applyTrafficPause(providers); errMsg != nil {
+		return nil, errMsg
+	}
+	providers = preferNativeCountProviders(handlerType, providers)
+	// Counting never reroutes, but the budget extension field must not reach the upstream.
+	_, rawJSON = requestLatencyBudget(ctx, rawJSON)
+	rawJSON = h.applyInboundScripts(ctx, handlerType, normalizedModel, rawJSON)
+	rawJSON = applyRemoteMedia(ctx, handlerType, providers, rawJSON)
 	reqMeta := requestExecutionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
+	if sessionKey := h.sessionKey(ctx, rawJSON); sessionKey != "" {
+		reqMeta[coreexecutor.SessionKeyMetadataKey] = sessionKey
+	}
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
 		Payload: cloneBytes(rawJSON),
@@ -444,6 +660,14 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 	opts.Metadata = reqMeta
 	resp, err := h.AuthManager.ExecuteCount(ctx, providers, req, opts)
 	if err != nil {
+		// Fall back to a local estimate only when the upstream has no counting endpoint. Other
+		// failures (auth, quota, bad requests) are reported so they are not masked by a guess.
+		if countNotSupported(err) {
+			if estimate, errEstimate := tokencount.Estimate(ctx, normalizedModel, opts.SourceFormat, rawJSON); errEstimate == nil {
+				log.Debugf("count tokens: upstream cannot count (%v), using local estimate", err)
+				return estimate, nil
+			}
+		}
 		status := http.StatusInternalServerError
 		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
 			if code := se.StatusCode(); code > 0 {
@@ -461,18 +685,102 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 	return cloneBytes(resp.Payload), nil
 }
 
+// countNotSupported reports whether a token count failed because the upstream cannot count
+// tokens at all, as opposed to rejecting or failing this particular request.
+func countNotSupported(err error) bool {
+	if se, ok := err.(interface{ StatusCode() int }); ok && se.StatusCode() == http.StatusNotImplemented {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "not supported") || strings.Contains(msg, "not implemented")
+}
+
+// preferNativeCountProviders narrows token counting to the providers that speak the inbound
+// format natively when the model is also served by them, e.g. Claude /v1/messages/count_tokens
+// goes to a Claude upstream rather than being approximated through a Gemini route.
+func preferNativeCountProviders(handlerType string, providers []string) []string {
+	if handlerType != "claude" || len(providers) < 2 {
+		return providers
+	}
+	native := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		if provider == "claude" {
+			native = append(native, provider)
+		}
+	}
+	if len(native) == 0 {
+		return providers
+	}
+	return native
+}
+
 // ExecuteStreamWithAuthManager executes a streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (<-chan []byte, <-chan *interfaces.ErrorMessage) {
+	rawJSON, fallbacks := applyOpenRouterExtensions(ctx, handlerType, modelName, rawJSON)
+	if len(fallbacks) > 0 {
+		return h.streamWithModelFallback(ctx, handlerType, modelName, fallbacks, rawJSON, alt)
+	}
+	auditModel(ctx, modelName)
+	errMsg := h.validateRequest(handlerType, rawJSON, true)
+	if errMsg == nil {
+		errMsg = h.checkModelAccess(ctx, modelName)
+	}
+	if errMsg == nil {
+		rawJSON, errMsg = h.applyLogprobsPolicy(ctx, handlerType, modelName, rawJSON)
+	}
+	if errMsg == nil {
+		errMsg = h.checkTokenBudget(ctx)
+	}
+	var canary *canaryChoice
+	if errMsg == nil {
+		modelName, rawJSON, canary = h.applyCanaryRouting(ctx, modelName, rawJSON)
+		modelName, rawJSON = h.applyLatencyBudget(ctx, modelName, rawJSON, true)
+	}
+	if errMsg != nil {
+		errChan := make(chan *interfaces.ErrorMessage, 1)
+		errChan <- errMsg
+		close(errChan)
+		return nil, errChan
+	}
 	providers, normalizedModel, errMsg := h.getRequestDetails(modelName)
+	if errMsg == nil {
+		providers, errMsg = restrictProviders(ctx, modelName, providers)
+	}
+	if errMsg == nil {
+		providers, errMsg = h.applyTrafficPause(providers)
+	}
+	if errMsg == nil {
+		errMsg = h.admitRequest(ctx, providers, normalizedModel)
+	}
+	if errMsg != nil {
+		errChan := make(chan *interfaces.ErrorMessage, 1)
+		errChan <- errMsg
+		close(errChan)
+		return nil, errChan
+	}
+	releaseInFlight := trackInFlight()
+	rawJSON = h.applyInboundScripts(ctx, handlerType, normalizedModel, rawJSON)
+	rawJSON = applyRemoteMedia(ctx, handlerType, providers, rawJSON)
+	policy, rawJSON, errMsg := h.applyRequestGuardrails(ctx, rawJSON)
 	if errMsg != nil {
+		releaseInFlight()
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		errChan <- errMsg
 		close(errChan)
 		return nil, errChan
 	}
+	streamFilter := policy.NewStreamFilter()
+	thinkingFilter := thinkingOutputPolicy(ctx).NewStreamFilter(handlerType)
+	h.captureUsageTags(ctx, rawJSON)
+	captureWorkload(ctx, rawJSON)
+	captureUsagePrompt(ctx, handlerType, rawJSON)
 	reqMeta := requestExecutionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = normalizedModel
+	if sessionKey := h.sessionKey(ctx, rawJSON); sessionKey != "" {
+		reqMeta[coreexecutor.SessionKeyMetadataKey] = sessionKey
+	}
+	canary.addMetadata(reqMeta)
 	req := coreexecutor.Request{
 		Model:   normalizedModel,
 		Payload: cloneBytes(rawJSON),
@@ -484,8 +792,10 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 		SourceFormat:    sdktranslator.FromString(handlerType),
 	}
 	opts.Metadata = reqMeta
+	started := time.Now()
 	chunks, err := h.AuthManager.ExecuteStream(ctx, providers, req, opts)
 	if err != nil {
+		releaseInFlight()
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		status := http.StatusInternalServerError
 		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
@@ -499,15 +809,20 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 				addon = hdr.Clone()
 			}
 		}
-		errChan <- &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+		errMsg = &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+		canary.record(errMsg, 0)
+		errChan <- errMsg
 		close(errChan)
 		return nil, errChan
 	}
 	dataChan := make(chan []byte)
 	errChan := make(chan *interfaces.ErrorMessage, 1)
+	tracker := newStreamEventTracker(ctx, handlerType, modelName)
 	go func() {
 		defer close(dataChan)
 		defer close(errChan)
+		defer releaseInFlight()
+		defer tracker.end(nil)
 		sentPayload := false
 		bootstrapRetries := 0
 		maxBootstrapRetries := StreamingBootstrapRetries(h.Cfg)
@@ -538,6 +853,31 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 			}
 		}
 
+		// Idle and total-duration timers end streams whose upstream stalls; a nil channel never fires.
+		var idleC, deadlineC <-chan time.Time
+		idleTimeout := StreamingIdleTimeout(h.Cfg)
+		var idleTimer *time.Timer
+		if idleTimeout > 0 {
+			idleTimer = time.NewTimer(idleTimeout)
+			defer idleTimer.Stop()
+			idleC = idleTimer.C
+		}
+		if maxDuration := StreamingMaxDuration(h.Cfg); maxDuration > 0 {
+			deadline := time.NewTimer(maxDuration)
+			defer deadline.Stop()
+			deadlineC = deadline.C
+		}
+		var done <-chan struct{}
+		if ctx != nil {
+			done = ctx.Done()
+		}
+		timeout := func(err error) {
+			msg := &interfaces.ErrorMessage{StatusCode: http.StatusGatewayTimeout, Error: err}
+			canary.record(msg, 0)
+			tracker.end(msg)
+			_ = sendErr(msg)
+		}
+
 		bootstrapEligible := func(err error) bool {
 			status := statusFromError(err)
 			if status == 0 {
@@ -557,18 +897,23 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 			for {
 				var chunk coreexecutor.StreamChunk
 				var ok bool
-				if ctx != nil {
-					select {
-					case <-ctx.Done():
-						return
-					case chunk, ok = <-chunks:
-					}
-				} else {
-					chunk, ok = <-chunks
+				select {
+				case <-done:
+					return
+				case <-idleC:
+					timeout(fmt.Errorf("stream timed out: no data from upstream for %s", idleTimeout))
+					return
+				case <-deadlineC:
+					timeout(fmt.Errorf("stream timed out: exceeded the maximum stream duration of %s", StreamingMaxDuration(h.Cfg)))
+					return
+				case chunk, ok = <-chunks:
 				}
 				if !ok {
 					return
 				}
+				if idleTimer != nil {
+					idleTimer.Reset(idleTimeout)
+				}
 				if chunk.Err != nil {
 					streamErr := chunk.Err
 					// Safe bootstrap recovery: if the upstream fails before any payload bytes are sent,
@@ -597,14 +942,45 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 							addon = hdr.Clone()
 						}
 					}
-					_ = sendErr(&interfaces.ErrorMessage{StatusCode: status, Error: streamErr, Addon: addon})
+					streamErrMsg := &interfaces.ErrorMessage{StatusCode: status, Error: streamErr, Addon: addon}
+					canary.record(streamErrMsg, 0)
+					tracker.end(streamErrMsg)
+					_ = sendErr(streamErrMsg)
 					return
 				}
 				if len(chunk.Payload) > 0 {
+					payload, blockedMsg := filterGuardrailStream(streamFilter, chunk.Payload)
+					if blockedMsg != nil {
+						tracker.end(blockedMsg)
+						_ = sendErr(blockedMsg)
+						return
+					}
+					chunk.Payload = thinkingFilter.Filter(payload)
+					if len(chunk.Payload) == 0 {
+						continue
+					}
+					limitReached, abortMsg := tracker.chunk(chunk.Payload)
+					if abortMsg != nil {
+						_ = sendErr(abortMsg)
+						return
+					}
+					if !sentPayload {
+						canary.record(nil, time.Since(started))
+						h.recordModelLatency(modelName, time.Since(started), true)
+					}
 					sentPayload = true
 					if okSendData := sendData(cloneBytes(chunk.Payload)); !okSendData {
 						return
 					}
+					if limitReached {
+						// A stream plugin (e.g. a token budget) asked to truncate the response.
+						if final := tracker.limitChunk(); final != nil {
+							_ = sendData(final)
+						} else {
+							_ = sendErr(&interfaces.ErrorMessage{StatusCode: http.StatusTooManyRequests, Error: tracker.event.Err})
+						}
+						return
+					}
 				}
 			}
 		}
@@ -681,6 +1057,7 @@ func cloneMetadata(src map[string]any) map[string]any {
 }
 
 // WriteErrorResponse writes an error message to the response writer using the HTTP status embedded in the message.
+// The body uses the error shape of the request's inbound protocol.
 func (h *BaseAPIHandler) WriteErrorResponse(c *gin.Context, msg *interfaces.ErrorMessage) {
 	status := http.StatusInternalServerError
 	if msg != nil && msg.StatusCode > 0 {
@@ -705,7 +1082,9 @@ func (h *BaseAPIHandler) WriteErrorResponse(c *gin.Context, msg *interfaces.Erro
 		}
 	}
 
-	body := BuildErrorResponseBody(status, errText)
+	setRetryAfterHeader(c.Writer.Header(), errText)
+	telemetry.RecordError(status)
+	body := BuildErrorResponseBodyForFormat(inboundFormat(c), status, errText)
 	// Append first to preserve upstream response logs, then drop duplicate payloads if already recorded.
 	var previous []byte
 	if existing, exists := c.Get("API_RESPONSE"); exists {
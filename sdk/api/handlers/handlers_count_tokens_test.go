@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPreferNativeCountProviders(t *testing.T) {
+	cases := []struct {
+		handlerType string
+		providers   []string
+		want        []string
+	}{
+		{"claude", []string{"gemini", "claude", "antigravity"}, []string{"claude"}},
+		{"claude", []string{"gemini", "antigravity"}, []string{"gemini", "antigravity"}},
+		{"openai", []string{"gemini", "claude"}, []string{"gemini", "claude"}},
+		{"claude", []string{"kiro"}, []string{"kiro"}},
+	}
+	for _, tc := range cases {
+		if got := preferNativeCountProviders(tc.handlerType, tc.providers); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("preferNativeCountProviders(%q, %v) = %v, want %v", tc.handlerType, tc.providers, got, tc.want)
+		}
+	}
+}
+
+type countStatusError struct{ code int }
+
+func (e countStatusError) Error() string   { return http.StatusText(e.code) }
+func (e countStatusError) StatusCode() int { return e.code }
+
+func TestCountNotSupported(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{countStatusError{http.StatusNotImplemented}, true},
+		{errors.New("count tokens not supported by provider"), true},
+		{errors.New("CountTokens not implemented"), true},
+		{countStatusError{http.StatusUnauthorized}, false},
+		{countStatusError{http.StatusTooManyRequests}, false},
+		{countStatusError{http.StatusBadRequest}, false},
+		{errors.New("connection reset by peer"), false},
+	}
+	for _, tc := range cases {
+		if got := countNotSupported(tc.err); got != tc.want {
+			t.Errorf("countNotSupported(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"golang.org/x/net/context"
+)
+
+func TestCaptureUsageTags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+
+	h := &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{UsageMetadataKeys: []string{"user_id", "user", "team"}}}
+	h.captureUsageTags(ctx, []byte(`{"metadata":{"user_id":"end-user-1","team":{"nested":true}},"user":"openai-user"}`))
+
+	v, ok := ginCtx.Get(usageTagsContextKey)
+	if !ok {
+		t.Fatalf("expected usage tags on gin context")
+	}
+	want := map[string]string{"user_id": "end-user-1", "user": "openai-user"}
+	if got := v.(map[string]string); !reflect.DeepEqual(got, want) {
+		t.Fatalf("tags = %v, want %v", got, want)
+	}
+}
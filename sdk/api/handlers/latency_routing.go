@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	defaultLatencyMinSamples = 20
+	defaultLatencyWindow     = 200
+
+	// latencyBudgetHeader carries the client's latency budget.
+	latencyBudgetHeader = "X-Latency-Budget"
+	// latencyBudgetField is the request body extension field carrying the budget in milliseconds.
+	latencyBudgetField = "latency_budget_ms"
+)
+
+// latencyWindow is a ring buffer of recent request latencies for one model.
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+// latencyTracker records recent successful request latencies per model.
+type latencyTracker struct {
+	mu      sync.Mutex
+	windows map[string]*latencyWindow
+}
+
+var (
+	// modelLatencies holds total latencies of non-streaming requests.
+	modelLatencies = &latencyTracker{}
+	// modelFirstByteLatencies holds time to first payload of streaming requests, which is what
+	// a streaming client's budget is spent waiting for.
+	modelFirstByteLatencies = &latencyTracker{}
+)
+
+func latencyTrackerFor(stream bool) *latencyTracker {
+	if stream {
+		return modelFirstByteLatencies
+	}
+	return modelLatencies
+}
+
+func (t *latencyTracker) record(model string, d time.Duration, size int) {
+	if model == "" || d <= 0 {
+		return
+	}
+	if size <= 0 {
+		size = defaultLatencyWindow
+	}
+	key := strings.ToLower(model)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.windows == nil {
+		t.windows = make(map[string]*latencyWindow)
+	}
+	w := t.windows[key]
+	if w == nil {
+		w = &latencyWindow{}
+		t.windows[key] = w
+	}
+	if len(w.samples) < size {
+		w.samples = append(w.samples, d)
+		return
+	}
+	if len(w.samples) > size {
+		w.samples = w.samples[len(w.samples)-size:]
+		w.next = 0
+	}
+	w.samples[w.next%size] = d
+	w.next = (w.next + 1) % size
+}
+
+// p95 returns the 95th percentile latency of model, or false with fewer than minSamples samples.
+func (t *latencyTracker) p95(model string, minSamples int) (time.Duration, bool) {
+	t.mu.Lock()
+	w := t.windows[strings.ToLower(model)]
+	var samples []time.Duration
+	if w != nil {
+		samples = append(samples, w.samples...)
+	}
+	t.mu.Unlock()
+	if len(samples) == 0 || len(samples) < minSamples {
+		return 0, false
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	idx := (len(samples)*95+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return samples[idx], true
+}
+
+// parseLatencyBudget accepts a Go duration ("8s", "1500ms") or a number of milliseconds.
+func parseLatencyBudget(raw string) time.Duration {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0
+	}
+	if ms, err := strconv.ParseFloat(raw, 64); err == nil {
+		if ms <= 0 {
+			return 0
+		}
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
+
+// requestLatencyBudget returns the latency budget of a request and the body with the budget
+// extension field removed, so upstreams never see it.
+func requestLatencyBudget(ctx context.Context, rawJSON []byte) (time.Duration, []byte) {
+	var budget time.Duration
+	if field := gjson.GetBytes(rawJSON, latencyBudgetField); field.Exists() {
+		budget = parseLatencyBudget(field.String())
+		if stripped, err := sjson.DeleteBytes(rawJSON, latencyBudgetField); err == nil {
+			rawJSON = stripped
+		}
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil && ginCtx.Request != nil {
+		if header := parseLatencyBudget(ginCtx.GetHeader(latencyBudgetHeader)); header > 0 {
+			budget = header
+		}
+	}
+	return budget, rawJSON
+}
+
+// applyLatencyBudget picks the model that serves a request with a latency budget. When the
+// requested model's recent p95 exceeds the budget, the first fallback of its downgrade chain
+// that fits (or has too few samples to judge) and that the caller may use is substituted; the
+// substitution is reported in response headers. Streaming requests are judged by time to first
+// payload. It returns the model to serve and the body.
+func (h *BaseAPIHandler) applyLatencyBudget(ctx context.Context, modelName string, rawJSON []byte, stream bool) (string, []byte) {
+	if h == nil || h.Cfg == nil || ctx == nil {
+		return modelName, rawJSON
+	}
+	budget, rawJSON := requestLatencyBudget(ctx, rawJSON)
+	routing := h.Cfg.LatencyRouting
+	if budget <= 0 || len(routing.Chains) == 0 {
+		return modelName, rawJSON
+	}
+	chain := downgradeChain(routing, modelName)
+	if chain == nil {
+		return modelName, rawJSON
+	}
+	minSamples := routing.MinSamples
+	if minSamples <= 0 {
+		minSamples = defaultLatencyMinSamples
+	}
+	tracker := latencyTrackerFor(stream)
+	preferredP95, known := tracker.p95(modelName, minSamples)
+	if !known || preferredP95 <= budget {
+		return modelName, rawJSON
+	}
+
+	for _, candidate := range chain.Fallbacks {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" || strings.EqualFold(candidate, modelName) {
+			continue
+		}
+		if p95, ok := tracker.p95(candidate, minSamples); ok && p95 > budget {
+			continue
+		}
+		if h.checkModelAccess(ctx, candidate) != nil {
+			continue
+		}
+		if _, _, errMsg := h.getRequestDetails(candidate); errMsg != nil {
+			continue
+		}
+		if gjson.GetBytes(rawJSON, "model").Exists() {
+			if updated, err := sjson.SetBytes(rawJSON, "model", candidate); err == nil {
+				rawJSON = updated
+			}
+		}
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+			ginCtx.Header("X-CPA-Requested-Model", modelName)
+			ginCtx.Header("X-CPA-Served-Model", candidate)
+		}
+		log.Debugf("latency routing: %s p95 %s exceeds budget %s, serving %s", modelName, preferredP95, budget, candidate)
+		return candidate, rawJSON
+	}
+	return modelName, rawJSON
+}
+
+func downgradeChain(routing config.LatencyRoutingConfig, modelName string) *config.ModelDowngradeChain {
+	for i := range routing.Chains {
+		if strings.EqualFold(strings.TrimSpace(routing.Chains[i].Model), modelName) {
+			return &routing.Chains[i]
+		}
+	}
+	return nil
+}
+
+// chainMember reports whether modelName is the preferred model or a fallback of any chain.
+func chainMember(routing config.LatencyRoutingConfig, modelName string) bool {
+	for i := range routing.Chains {
+		if strings.EqualFold(strings.TrimSpace(routing.Chains[i].Model), modelName) {
+			return true
+		}
+		for _, fallback := range routing.Chains[i].Fallbacks {
+			if strings.EqualFold(strings.TrimSpace(fallback), modelName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recordModelLatency feeds the latency of a successful request into the downgrade decisions:
+// the total for non-streaming requests, the time to first payload for streams. Only models
+// that take part in a configured downgrade chain are tracked.
+func (h *BaseAPIHandler) recordModelLatency(modelName string, d time.Duration, stream bool) {
+	if h == nil || h.Cfg == nil || !chainMember(h.Cfg.LatencyRouting, modelName) {
+		return
+	}
+	latencyTrackerFor(stream).record(modelName, d, h.Cfg.LatencyRouting.Window)
+}
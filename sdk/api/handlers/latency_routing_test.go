@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestLatencyTrackerP95(t *testing.T) {
+	tracker := &latencyTracker{}
+	for i := 1; i <= 100; i++ {
+		tracker.record("m", time.Duration(i)*time.Millisecond, 50)
+	}
+	// Only the latest 50 samples (51ms..100ms) are kept.
+	if p95, ok := tracker.p95("M", 20); !ok || p95 != 98*time.Millisecond {
+		t.Fatalf("p95 = %v, %v; want 98ms", p95, ok)
+	}
+	if _, ok := tracker.p95("m", 60); ok {
+		t.Fatal("p95 must not be trusted with fewer than min samples")
+	}
+}
+
+func TestParseLatencyBudget(t *testing.T) {
+	for raw, want := range map[string]time.Duration{"1500": 1500 * time.Millisecond, "8s": 8 * time.Second, "": 0, "-1": 0, "soon": 0} {
+		if got := parseLatencyBudget(raw); got != want {
+			t.Errorf("parseLatencyBudget(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestApplyLatencyBudget(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	modelRegistry := registry.GetGlobalRegistry()
+	modelRegistry.RegisterClient("test-latency-routing", "claude", []*registry.ModelInfo{
+		{ID: "lr-slow"}, {ID: "lr-medium"}, {ID: "lr-fast"},
+	})
+	t.Cleanup(func() { modelRegistry.UnregisterClient("test-latency-routing") })
+
+	cfg := &sdkconfig.SDKConfig{LatencyRouting: sdkconfig.LatencyRoutingConfig{
+		MinSamples: 2,
+		Chains:     []sdkconfig.ModelDowngradeChain{{Model: "lr-slow", Fallbacks: []string{"lr-medium", "lr-fast"}}},
+	}}
+	h := NewBaseAPIHandlers(cfg, coreauth.NewManager(nil, nil, nil))
+	for i := 0; i < 3; i++ {
+		h.recordModelLatency("lr-slow", 20*time.Second, false)
+		h.recordModelLatency("lr-medium", 12*time.Second, false)
+		h.recordModelLatency("lr-fast", 2*time.Second, false)
+	}
+
+	newCtx := func(header string) (context.Context, *httptest.ResponseRecorder) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+		if header != "" {
+			c.Request.Header.Set(latencyBudgetHeader, header)
+		}
+		return context.WithValue(context.Background(), "gin", c), recorder
+	}
+
+	ctx, recorder := newCtx("")
+	model, body := h.applyLatencyBudget(ctx, "lr-slow", []byte(`{"model":"lr-slow","latency_budget_ms":5000}`), false)
+	if model != "lr-fast" || gjson.GetBytes(body, "model").String() != "lr-fast" {
+		t.Fatalf("expected downgrade to lr-fast, got %s %s", model, body)
+	}
+	if gjson.GetBytes(body, latencyBudgetField).Exists() {
+		t.Fatal("budget field must be stripped")
+	}
+	if got := recorder.Header().Get("X-CPA-Served-Model"); got != "lr-fast" {
+		t.Fatalf("served model header = %q", got)
+	}
+
+	ctx, _ = newCtx("15s")
+	if model, _ = h.applyLatencyBudget(ctx, "lr-slow", []byte(`{"model":"lr-slow"}`), false); model != "lr-medium" {
+		t.Fatalf("expected lr-medium within 15s budget, got %s", model)
+	}
+
+	ctx, _ = newCtx("30s")
+	if model, _ = h.applyLatencyBudget(ctx, "lr-slow", []byte(`{"model":"lr-slow"}`), false); model != "lr-slow" {
+		t.Fatalf("model within budget must not change, got %s", model)
+	}
+
+	ctx, _ = newCtx("")
+	if model, _ = h.applyLatencyBudget(ctx, "lr-slow", []byte(`{"model":"lr-slow"}`), false); model != "lr-slow" {
+		t.Fatalf("requests without a budget must not change, got %s", model)
+	}
+	ctx, _ = newCtx("5s")
+	if model, _ = h.applyLatencyBudget(ctx, "lr-slow", []byte(`{"model":"lr-slow"}`), true); model != "lr-slow" {
+		t.Fatalf("streams are judged by first-byte latency only, got %s", model)
+	}
+
+	h.recordModelLatency("lr-unrelated", time.Second, false)
+	if _, ok := modelLatencies.p95("lr-unrelated", 1); ok {
+		t.Fatal("models outside downgrade chains must not be tracked")
+	}
+}
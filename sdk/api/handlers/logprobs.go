@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	logprobsPassthrough = "passthrough"
+	logprobsStrip       = "strip"
+	logprobsReject      = "reject"
+)
+
+// logprobsMode returns the configured logprobs mode for modelName, passthrough by default.
+func (h *BaseAPIHandler) logprobsMode(modelName string) string {
+	if h == nil || h.Cfg == nil {
+		return logprobsPassthrough
+	}
+	modelName = strings.TrimPrefix(thinking.ParseSuffix(modelName).ModelName, "models/")
+	for _, rule := range h.Cfg.Logprobs {
+		matched := len(rule.Models) == 0
+		for _, pattern := range rule.Models {
+			if util.MatchWildcard(strings.ToLower(pattern), strings.ToLower(modelName)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		switch mode := strings.ToLower(strings.TrimSpace(rule.Mode)); mode {
+		case logprobsStrip, logprobsReject:
+			return mode
+		default:
+			return logprobsPassthrough
+		}
+	}
+	return logprobsPassthrough
+}
+
+// applyLogprobsPolicy handles the logprobs and top_logprobs fields of a Chat Completions
+// request according to the logprobs rules. Stripped requests carry a Warning header in the
+// response so the client learns why no logprobs come back.
+func (h *BaseAPIHandler) applyLogprobsPolicy(ctx context.Context, handlerType, modelName string, rawJSON []byte) ([]byte, *interfaces.ErrorMessage) {
+	if handlerType != "openai" {
+		return rawJSON, nil
+	}
+	logprobs := gjson.GetBytes(rawJSON, "logprobs")
+	topLogprobs := gjson.GetBytes(rawJSON, "top_logprobs")
+	if logprobs.Type != gjson.True && !topLogprobs.Exists() {
+		return rawJSON, nil
+	}
+	switch h.logprobsMode(modelName) {
+	case logprobsReject:
+		return nil, &interfaces.ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      fmt.Errorf("logprobs are not supported for model %s", modelName),
+		}
+	case logprobsStrip:
+		rawJSON, _ = sjson.DeleteBytes(rawJSON, "logprobs")
+		rawJSON, _ = sjson.DeleteBytes(rawJSON, "top_logprobs")
+		log.Debugf("logprobs stripped from request for model %s", modelName)
+		if ctx != nil {
+			if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+				ginCtx.Header("Warning", fmt.Sprintf(`299 - "logprobs are not supported for model %s and were ignored"`, modelName))
+			}
+		}
+	}
+	return rawJSON, nil
+}
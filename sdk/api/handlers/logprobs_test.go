@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyLogprobsPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{Logprobs: []sdkconfig.LogprobsRule{
+		{Models: []string{"gpt-*"}, Mode: "passthrough"},
+		{Models: []string{"gemini-*"}, Mode: "strip"},
+		{Models: []string{"claude-*"}, Mode: "reject"},
+	}}}
+	raw := []byte(`{"model":"m","logprobs":true,"top_logprobs":3,"messages":[]}`)
+
+	out, errMsg := h.applyLogprobsPolicy(context.Background(), "openai", "gpt-5", raw)
+	if errMsg != nil || string(out) != string(raw) {
+		t.Fatalf("passthrough changed request: %s, %v", out, errMsg)
+	}
+
+	recorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+	out, errMsg = h.applyLogprobsPolicy(ctx, "openai", "gemini-2.5-pro", raw)
+	if errMsg != nil {
+		t.Fatalf("strip returned error: %v", errMsg.Error)
+	}
+	if gjson.GetBytes(out, "logprobs").Exists() || gjson.GetBytes(out, "top_logprobs").Exists() {
+		t.Fatalf("strip kept logprobs fields: %s", out)
+	}
+	if warning := recorder.Header().Get("Warning"); !strings.Contains(warning, "logprobs") {
+		t.Fatalf("Warning header = %q", warning)
+	}
+
+	if _, errMsg = h.applyLogprobsPolicy(context.Background(), "openai", "claude-sonnet-4-5", raw); errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("reject returned %+v, want 400", errMsg)
+	}
+	if _, errMsg = h.applyLogprobsPolicy(context.Background(), "openai", "claude-sonnet-4-5", []byte(`{"logprobs":false}`)); errMsg != nil {
+		t.Fatalf("requests without logprobs must not be rejected: %v", errMsg.Error)
+	}
+	if _, errMsg = h.applyLogprobsPolicy(context.Background(), "claude", "claude-sonnet-4-5", raw); errMsg != nil {
+		t.Fatalf("non-OpenAI formats must be ignored: %v", errMsg.Error)
+	}
+}
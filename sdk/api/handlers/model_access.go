@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/tenant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"golang.org/x/net/context"
+)
+
+// allowedModelPatterns returns the allowlist for apiKey and whether one applies. Tenant keys use
+// their tenant's allowed models; other keys use api-key-models.
+func (h *BaseAPIHandler) allowedModelPatterns(apiKey string) ([]string, bool) {
+	if h == nil || h.Cfg == nil || apiKey == "" {
+		return nil, false
+	}
+	if models, ok := tenant.Active().AllowedModels(apiKey); ok {
+		return models, true
+	}
+	for _, entry := range h.Cfg.APIKeyModels {
+		if strings.TrimSpace(entry.APIKey) == apiKey {
+			return entry.Models, true
+		}
+	}
+	return nil, false
+}
+
+// callerAPIKey returns the client API key authenticated for the request, if any.
+func callerAPIKey(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	if v, exists := c.Get("apiKey"); exists {
+		if key, ok := v.(string); ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// modelAllowed reports whether apiKey may use modelName. Thinking suffixes are ignored.
+func (h *BaseAPIHandler) modelAllowed(apiKey, modelName string) bool {
+	patterns, restricted := h.allowedModelPatterns(apiKey)
+	if !restricted {
+		return true
+	}
+	modelName = strings.TrimPrefix(thinking.ParseSuffix(modelName).ModelName, "models/")
+	for _, pattern := range patterns {
+		if util.MatchWildcard(strings.ToLower(pattern), strings.ToLower(modelName)) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterModelsForCaller removes models the caller's API key is not allowed to use from a
+// model listing. Listings are returned unchanged for unrestricted keys.
+func (h *BaseAPIHandler) FilterModelsForCaller(c *gin.Context, models []map[string]any) []map[string]any {
+	apiKey := callerAPIKey(c)
+	if _, restricted := h.allowedModelPatterns(apiKey); !restricted {
+		return models
+	}
+	out := make([]map[string]any, 0, len(models))
+	for _, model := range models {
+		id, _ := model["id"].(string)
+		if id == "" {
+			id, _ = model["name"].(string)
+		}
+		if h.modelAllowed(apiKey, id) {
+			out = append(out, model)
+		}
+	}
+	return out
+}
+
+// checkModelAccess rejects requests for models outside the caller's api-key-models allowlist.
+// Disallowed models are reported as not found so restricted keys cannot probe for them.
+func (h *BaseAPIHandler) checkModelAccess(ctx context.Context, modelName string) *interfaces.ErrorMessage {
+	if ctx == nil {
+		return nil
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || h.modelAllowed(callerAPIKey(ginCtx), modelName) {
+		return nil
+	}
+	return &interfaces.ErrorMessage{
+		StatusCode: http.StatusNotFound,
+		Error:      fmt.Errorf("model %s is not available for this API key", modelName),
+	}
+}
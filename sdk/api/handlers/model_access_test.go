@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"golang.org/x/net/context"
+)
+
+func TestFilterModelsForCaller(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{APIKeyModels: []config.APIKeyModelAccess{
+		{APIKey: "restricted", Models: []string{"gemini-*", "claude-sonnet-4-5"}},
+	}}}
+	models := []map[string]any{
+		{"id": "gemini-2.5-pro"},
+		{"id": "claude-sonnet-4-5"},
+		{"id": "gpt-5"},
+		{"name": "models/gemini-2.5-flash"},
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("apiKey", "restricted")
+	if got := h.FilterModelsForCaller(c, models); len(got) != 3 {
+		t.Fatalf("expected 3 allowed models, got %v", got)
+	}
+
+	c.Set("apiKey", "open")
+	if got := h.FilterModelsForCaller(c, models); len(got) != len(models) {
+		t.Fatalf("unrestricted keys must see every model, got %v", got)
+	}
+}
+
+func TestCheckModelAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{APIKeyModels: []config.APIKeyModelAccess{
+		{APIKey: "restricted", Models: []string{"gemini-*"}},
+	}}}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("apiKey", "restricted")
+	ctx := context.WithValue(context.Background(), "gin", c)
+
+	if errMsg := h.checkModelAccess(ctx, "gemini-2.5-pro(high)"); errMsg != nil {
+		t.Fatalf("expected thinking suffix to be ignored, got %v", errMsg.Error)
+	}
+	errMsg := h.checkModelAccess(ctx, "gpt-5")
+	if errMsg == nil || errMsg.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for disallowed model, got %+v", errMsg)
+	}
+}
+
+func TestModelAllowedPatterns(t *testing.T) {
+	h := &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{APIKeyModels: []config.APIKeyModelAccess{
+		{APIKey: "restricted", Models: []string{"gemini-*-pro", "GPT-5"}},
+	}}}
+	cases := []struct {
+		model string
+		want  bool
+	}{
+		{"gemini-3-pro", true},
+		{"gemini-3-flash", false},
+		{"gpt-5", true},
+		{"gpt-5-codex", false},
+	}
+	for _, tc := range cases {
+		if got := h.modelAllowed("restricted", tc.model); got != tc.want {
+			t.Errorf("modelAllowed(%q) = %v, want %v", tc.model, got, tc.want)
+		}
+	}
+}
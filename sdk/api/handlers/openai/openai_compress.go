@@ -0,0 +1,207 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// defaultCompressKeepLast is the number of recent messages kept verbatim by default.
+	defaultCompressKeepLast = 4
+
+	compressionPrompt = "You compress conversations between a user and an AI assistant. Write a concise summary " +
+		"of the transcript that lets the assistant continue the conversation without it: keep the user's goals, " +
+		"decisions made, facts and constraints established, files, identifiers and values referenced, tool results " +
+		"that still matter, and open tasks. Omit pleasantries and superseded details. Reply with the summary only."
+
+	summaryPrefix = "Summary of the earlier conversation:\n\n"
+)
+
+// CompressConversation handles POST /v1/conversations/compress. It summarizes the older turns of a
+// Chat Completions message history with the requested model and returns the summary together with
+// a shortened history in which the summary replaces those turns. System messages stay verbatim in
+// their original positions, as do the last keep_last messages; the kept tail is extended back to a
+// user message so tool calls keep their results. A history with no user message to start the kept
+// tail at is refused.
+//
+// Request body:
+//   - model: the model that writes the summary
+//   - messages: the Chat Completions history
+//   - keep_last: recent messages to keep verbatim (default 4)
+//   - instructions: optional guidance appended to the summarization prompt
+//   - max_summary_tokens: optional output limit for the summary
+func (h *OpenAIAPIHandler) CompressConversation(c *gin.Context) {
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		writeCompressError(c, fmt.Sprintf("Invalid request: %v", err))
+		return
+	}
+	if !gjson.ValidBytes(rawJSON) {
+		writeCompressError(c, "Invalid request: body must be JSON")
+		return
+	}
+	modelName := strings.TrimSpace(gjson.GetBytes(rawJSON, "model").String())
+	if modelName == "" {
+		writeCompressError(c, "model is required")
+		return
+	}
+	messages := gjson.GetBytes(rawJSON, "messages")
+	if !messages.IsArray() {
+		writeCompressError(c, "messages must be an array")
+		return
+	}
+	keepLast := defaultCompressKeepLast
+	if v := gjson.GetBytes(rawJSON, "keep_last"); v.Exists() {
+		if v.Int() < 0 {
+			writeCompressError(c, "keep_last must not be negative")
+			return
+		}
+		keepLast = int(v.Int())
+	}
+
+	all := messages.Array()
+	var conversation []gjson.Result
+	for _, message := range all {
+		if !isSystemMessage(message) {
+			conversation = append(conversation, message)
+		}
+	}
+	split := compressionSplit(conversation, keepLast)
+	if split == 0 && len(conversation) > keepLast {
+		writeCompressError(c, "messages cannot be compressed: no user message starts a turn before the last keep_last messages")
+		return
+	}
+
+	out := `{"object":"conversation.compression","model":"","summary":"","summarized_messages":0,"messages":[]}`
+	out, _ = sjson.Set(out, "model", modelName)
+	out, _ = sjson.Set(out, "summarized_messages", split)
+	if split == 0 {
+		for _, message := range all {
+			out, _ = sjson.SetRaw(out, "messages.-1", message.Raw)
+		}
+		c.Header("Content-Type", "application/json")
+		_, _ = c.Writer.Write([]byte(out))
+		return
+	}
+
+	prompt := compressionPrompt
+	if extra := strings.TrimSpace(gjson.GetBytes(rawJSON, "instructions").String()); extra != "" {
+		prompt += "\n\n" + extra
+	}
+	request := `{"model":"","stream":false,"messages":[{"role":"system","content":""},{"role":"user","content":""}]}`
+	request, _ = sjson.Set(request, "model", modelName)
+	request, _ = sjson.Set(request, "messages.0.content", prompt)
+	request, _ = sjson.Set(request, "messages.1.content", renderTranscript(conversation[:split]))
+	if v := gjson.GetBytes(rawJSON, "max_summary_tokens"); v.Int() > 0 {
+		request, _ = sjson.Set(request, "max_tokens", v.Int())
+	}
+
+	c.Header("Content-Type", "application/json")
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	resp, errMsg := h.ExecuteWithAuthManager(cliCtx, h.HandlerType(), modelName, []byte(request), "")
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+	summary := strings.TrimSpace(gjson.GetBytes(resp, "choices.0.message.content").String())
+
+	out, _ = sjson.Set(out, "summary", summary)
+	summaryMessage := `{"role":"user","content":""}`
+	summaryMessage, _ = sjson.Set(summaryMessage, "content", summaryPrefix+summary)
+	// The summary takes the place of the first summarized message; system messages stay put.
+	summarized := 0
+	for _, message := range all {
+		if summarized < split && !isSystemMessage(message) {
+			if summarized == 0 {
+				out, _ = sjson.SetRaw(out, "messages.-1", summaryMessage)
+			}
+			summarized++
+			continue
+		}
+		out, _ = sjson.SetRaw(out, "messages.-1", message.Raw)
+	}
+	if usage := gjson.GetBytes(resp, "usage"); usage.Exists() {
+		out, _ = sjson.SetRaw(out, "usage", usage.Raw)
+	}
+	_, _ = c.Writer.Write([]byte(out))
+	cliCancel()
+}
+
+func writeCompressError(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+		Error: handlers.ErrorDetail{
+			Message: message,
+			Type:    "invalid_request_error",
+		},
+	})
+}
+
+func isSystemMessage(message gjson.Result) bool {
+	role := message.Get("role").String()
+	return role == "system" || role == "developer"
+}
+
+// compressionSplit returns how many leading messages of conversation are summarized: all but the
+// last keepLast, moved back to the start of a user turn so the kept tail begins with a user
+// message. It returns 0 when there is nothing to summarize.
+func compressionSplit(conversation []gjson.Result, keepLast int) int {
+	split := len(conversation) - keepLast
+	if split <= 0 || split >= len(conversation) {
+		return max(split, 0)
+	}
+	for split > 0 && conversation[split].Get("role").String() != "user" {
+		split--
+	}
+	return split
+}
+
+// renderTranscript renders messages as plain text for the summarizing model.
+func renderTranscript(messages []gjson.Result) string {
+	var b strings.Builder
+	b.WriteString("Transcript to summarize:\n")
+	for _, message := range messages {
+		role := message.Get("role").String()
+		b.WriteString("\n[")
+		b.WriteString(role)
+		if name := message.Get("name").String(); name != "" {
+			b.WriteString(" ")
+			b.WriteString(name)
+		}
+		b.WriteString("]\n")
+		if text := messageText(message.Get("content")); text != "" {
+			b.WriteString(text)
+			b.WriteString("\n")
+		}
+		for _, call := range message.Get("tool_calls").Array() {
+			fmt.Fprintf(&b, "(calls tool %s with %s)\n", call.Get("function.name").String(), call.Get("function.arguments").String())
+		}
+	}
+	return b.String()
+}
+
+// messageText returns the text of string content or of the text parts of array content.
+func messageText(content gjson.Result) string {
+	if content.Type == gjson.String {
+		return strings.TrimSpace(content.String())
+	}
+	var parts []string
+	for _, part := range content.Array() {
+		switch part.Get("type").String() {
+		case "text", "input_text", "output_text":
+			if text := strings.TrimSpace(part.Get("text").String()); text != "" {
+				parts = append(parts, text)
+			}
+		case "image_url", "input_image":
+			parts = append(parts, "(image)")
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,170 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+type summaryExecutor struct {
+	payload []byte
+	calls   int
+}
+
+func (e *summaryExecutor) Identifier() string { return "test-summary-provider" }
+
+func (e *summaryExecutor) Execute(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	e.calls++
+	e.payload = req.Payload
+	return coreexecutor.Response{Payload: []byte(`{"choices":[{"message":{"role":"assistant","content":" The user is fixing a parser bug. "}}],"usage":{"prompt_tokens":40,"completion_tokens":8}}`)}, nil
+}
+
+func (e *summaryExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *summaryExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *summaryExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *summaryExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newCompressRouter(t *testing.T) (*gin.Engine, *summaryExecutor) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	executor := &summaryExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "compress-auth", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "summary-model"}})
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+	})
+
+	h := NewOpenAIAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	router := gin.New()
+	router.POST("/v1/conversations/compress", h.CompressConversation)
+	return router, executor
+}
+
+func postCompress(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/conversations/compress", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestCompressConversation(t *testing.T) {
+	router, executor := newCompressRouter(t)
+	resp := postCompress(router, `{"model":"summary-model","keep_last":2,"messages":[
+		{"role":"system","content":"You are a coding agent."},
+		{"role":"user","content":"The parser drops trailing commas."},
+		{"role":"assistant","content":"Let me look.","tool_calls":[{"id":"c1","type":"function","function":{"name":"read_file","arguments":"{\"path\":\"parser.go\"}"}}]},
+		{"role":"tool","tool_call_id":"c1","content":"func parse() {}"},
+		{"role":"assistant","content":"Found it."},
+		{"role":"user","content":"Fix it please."},
+		{"role":"assistant","content":"Done."}
+	]}`)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", resp.Code, resp.Body.String())
+	}
+	out := gjson.Parse(resp.Body.String())
+	if out.Get("summary").String() != "The user is fixing a parser bug." || out.Get("summarized_messages").Int() != 4 {
+		t.Fatalf("response = %s", out.Raw)
+	}
+	messages := out.Get("messages").Array()
+	if len(messages) != 4 || messages[0].Get("role").String() != "system" || messages[2].Get("content").String() != "Fix it please." {
+		t.Fatalf("messages = %s", out.Get("messages").Raw)
+	}
+	if !strings.HasSuffix(messages[1].Get("content").String(), "The user is fixing a parser bug.") {
+		t.Fatalf("summary message = %s", messages[1].Raw)
+	}
+	if out.Get("usage.completion_tokens").Int() != 8 {
+		t.Fatalf("usage = %s", out.Get("usage").Raw)
+	}
+
+	transcript := gjson.GetBytes(executor.payload, "messages.1.content").String()
+	if !strings.Contains(transcript, "trailing commas") || !strings.Contains(transcript, "calls tool read_file") || strings.Contains(transcript, "Fix it please.") {
+		t.Fatalf("transcript = %q", transcript)
+	}
+}
+
+func TestCompressConversationShortHistory(t *testing.T) {
+	router, executor := newCompressRouter(t)
+	resp := postCompress(router, `{"model":"summary-model","messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]}`)
+
+	if resp.Code != http.StatusOK || executor.calls != 0 {
+		t.Fatalf("status = %d, calls = %d", resp.Code, executor.calls)
+	}
+	out := gjson.Parse(resp.Body.String())
+	if out.Get("summarized_messages").Int() != 0 || len(out.Get("messages").Array()) != 2 {
+		t.Fatalf("response = %s", out.Raw)
+	}
+}
+
+func TestCompressConversationKeepsSystemMessagesInPlace(t *testing.T) {
+	router, _ := newCompressRouter(t)
+	resp := postCompress(router, `{"model":"summary-model","keep_last":2,"messages":[
+		{"role":"user","content":"First question."},
+		{"role":"assistant","content":"First answer."},
+		{"role":"developer","content":"Answer in French from now on."},
+		{"role":"user","content":"Second question."},
+		{"role":"assistant","content":"Deuxieme reponse."}
+	]}`)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", resp.Code, resp.Body.String())
+	}
+	var roles []string
+	for _, message := range gjson.Get(resp.Body.String(), "messages").Array() {
+		roles = append(roles, message.Get("role").String())
+	}
+	if strings.Join(roles, ",") != "user,developer,user,assistant" {
+		t.Fatalf("roles = %v", roles)
+	}
+}
+
+func TestCompressConversationWithoutUserBoundary(t *testing.T) {
+	router, executor := newCompressRouter(t)
+	resp := postCompress(router, `{"model":"summary-model","keep_last":2,"messages":[
+		{"role":"user","content":"Run the tests."},
+		{"role":"assistant","tool_calls":[{"id":"c1","type":"function","function":{"name":"run","arguments":"{}"}}]},
+		{"role":"tool","tool_call_id":"c1","content":"ok"},
+		{"role":"assistant","content":"All green."}
+	]}`)
+
+	if resp.Code != http.StatusBadRequest || executor.calls != 0 {
+		t.Fatalf("status = %d, calls = %d, body = %s", resp.Code, executor.calls, resp.Body.String())
+	}
+}
+
+func TestCompressConversationValidation(t *testing.T) {
+	router, _ := newCompressRouter(t)
+	for _, body := range []string{`{"messages":[]}`, `{"model":"summary-model"}`, `{"model":"summary-model","messages":[],"keep_last":-1}`} {
+		if resp := postCompress(router, body); resp.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d", body, resp.Code)
+		}
+	}
+}
@@ -0,0 +1,675 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// realtimeReadLimit caps the size of a single client event.
+const realtimeReadLimit = 16 << 20
+
+// OpenAIRealtimeAPIHandler serves a text subset of the OpenAI Realtime API over WebSocket:
+// session configuration, conversation items (text messages and function call outputs),
+// responses with streamed text and function calls, and response cancellation. Each response is
+// executed as a streaming chat completion, so any configured upstream, Gemini included, can back
+// a realtime session. Audio input and output are not supported.
+type OpenAIRealtimeAPIHandler struct {
+	*handlers.BaseAPIHandler
+	upgrader websocket.Upgrader
+}
+
+// NewOpenAIRealtimeAPIHandler creates a new OpenAI Realtime API handlers instance.
+func NewOpenAIRealtimeAPIHandler(apiHandlers *handlers.BaseAPIHandler) *OpenAIRealtimeAPIHandler {
+	return &OpenAIRealtimeAPIHandler{
+		BaseAPIHandler: apiHandlers,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			Subprotocols:    []string{"realtime"},
+			CheckOrigin:     func(*http.Request) bool { return true },
+		},
+	}
+}
+
+// HandlerType returns the identifier for this handler implementation. Realtime responses are
+// executed in the OpenAI Chat Completions format.
+func (h *OpenAIRealtimeAPIHandler) HandlerType() string {
+	return OpenAI
+}
+
+// Models returns the OpenAI-compatible model metadata supported by this handler.
+func (h *OpenAIRealtimeAPIHandler) Models() []map[string]any {
+	return util.AnnotateModelCapabilities(registry.GetGlobalRegistry().GetAvailableModels("openai"))
+}
+
+// Realtime handles GET /v1/realtime?model=... and upgrades the connection to a realtime session.
+// Clients sending "OpenAI-Beta: realtime=v1" get the beta event names; others get the GA names.
+func (h *OpenAIRealtimeAPIHandler) Realtime(c *gin.Context) {
+	model := strings.TrimSpace(c.Query("model"))
+	if model == "" {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{Error: handlers.ErrorDetail{
+			Message: "Missing required parameter: 'model'.",
+			Type:    "invalid_request_error",
+		}})
+		return
+	}
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Debugf("realtime: upgrade failed: %v", err)
+		return
+	}
+	conn.SetReadLimit(realtimeReadLimit)
+	s := &realtimeSession{
+		h:     h,
+		c:     c,
+		conn:  conn,
+		beta:  strings.Contains(strings.ToLower(c.GetHeader("OpenAI-Beta")), "realtime=v1"),
+		id:    realtimeID("sess_"),
+		model: model,
+	}
+	s.serve()
+}
+
+// realtimeItem is a conversation item in the Realtime API format.
+type realtimeItem struct {
+	ID        string
+	Type      string // message, function_call or function_call_output
+	Role      string
+	Text      string
+	CallID    string
+	Name      string
+	Arguments string
+	Output    string
+}
+
+// realtimeSession is the state of one realtime connection.
+type realtimeSession struct {
+	h    *OpenAIRealtimeAPIHandler
+	c    *gin.Context
+	conn *websocket.Conn
+	beta bool
+	id   string
+
+	writeMu sync.Mutex
+
+	mu           sync.Mutex
+	model        string
+	instructions string
+	tools        []gjson.Result
+	toolChoice   gjson.Result
+	temperature  gjson.Result
+	maxTokens    gjson.Result
+	items        []realtimeItem
+	active       context.CancelFunc
+	activeDone   chan struct{}
+}
+
+func realtimeID(prefix string) string {
+	return prefix + strings.ReplaceAll(uuid.NewString(), "-", "")[:20]
+}
+
+func (s *realtimeSession) send(event gin.H) {
+	event["event_id"] = realtimeID("event_")
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.conn.WriteJSON(event); err != nil {
+		log.Debugf("realtime %s: write failed: %v", s.id, err)
+	}
+}
+
+func (s *realtimeSession) sendError(code, message, clientEventID string) {
+	detail := gin.H{"type": "invalid_request_error", "code": code, "message": message}
+	if clientEventID != "" {
+		detail["event_id"] = clientEventID
+	}
+	s.send(gin.H{"type": "error", "error": detail})
+}
+
+func (s *realtimeSession) serve() {
+	defer func() {
+		s.mu.Lock()
+		cancel, done := s.active, s.activeDone
+		s.mu.Unlock()
+		if cancel != nil {
+			cancel()
+			<-done
+		}
+		_ = s.conn.Close()
+	}()
+
+	s.send(gin.H{"type": "session.created", "session": s.sessionObject()})
+	for {
+		_, payload, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if !gjson.ValidBytes(payload) {
+			s.sendError("invalid_json", "The event is not valid JSON.", "")
+			continue
+		}
+		s.handleEvent(gjson.ParseBytes(payload))
+	}
+}
+
+func (s *realtimeSession) handleEvent(event gjson.Result) {
+	eventID := event.Get("event_id").String()
+	switch eventType := event.Get("type").String(); eventType {
+	case "session.update":
+		s.updateSession(event.Get("session"))
+		s.send(gin.H{"type": "session.updated", "session": s.sessionObject()})
+	case "conversation.item.create":
+		item, errMsg := parseRealtimeItem(event.Get("item"))
+		if errMsg != "" {
+			s.sendError("invalid_value", errMsg, eventID)
+			return
+		}
+		s.mu.Lock()
+		previous := s.lastItemID()
+		s.items = append(s.items, item)
+		s.mu.Unlock()
+		s.sendItemCreated(item, previous)
+	case "conversation.item.delete":
+		itemID := event.Get("item_id").String()
+		if !s.deleteItem(itemID) {
+			s.sendError("item_not_found", "Item with item_id not found: "+itemID, eventID)
+			return
+		}
+		s.send(gin.H{"type": "conversation.item.deleted", "item_id": itemID})
+	case "response.create":
+		s.startResponse(event.Get("response"), eventID)
+	case "response.cancel":
+		s.mu.Lock()
+		cancel := s.active
+		s.mu.Unlock()
+		if cancel == nil {
+			s.sendError("response_cancel_not_active", "There is no active response to cancel.", eventID)
+			return
+		}
+		cancel()
+	default:
+		if strings.HasPrefix(eventType, "input_audio_buffer.") || strings.HasPrefix(eventType, "output_audio_buffer.") {
+			s.sendError("unsupported_modality", "Audio is not supported by this server; send text items instead.", eventID)
+			return
+		}
+		s.sendError("invalid_event", "Unsupported event type: "+eventType, eventID)
+	}
+}
+
+// updateSession applies the supported fields of a session.update. Both beta and GA field names
+// are accepted.
+func (s *realtimeSession) updateSession(session gjson.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v := session.Get("model"); v.Type == gjson.String && v.String() != "" {
+		s.model = v.String()
+	}
+	if v := session.Get("instructions"); v.Exists() {
+		s.instructions = v.String()
+	}
+	if v := session.Get("tools"); v.IsArray() {
+		s.tools = v.Array()
+	}
+	if v := session.Get("tool_choice"); v.Exists() {
+		s.toolChoice = v
+	}
+	if v := session.Get("temperature"); v.Exists() {
+		s.temperature = v
+	}
+	for _, key := range []string{"max_response_output_tokens", "max_output_tokens"} {
+		if v := session.Get(key); v.Exists() {
+			s.maxTokens = v
+		}
+	}
+}
+
+func (s *realtimeSession) sessionObject() gin.H {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tools := make([]any, 0, len(s.tools))
+	for _, tool := range s.tools {
+		tools = append(tools, tool.Value())
+	}
+	session := gin.H{
+		"id":           s.id,
+		"object":       "realtime.session",
+		"model":        s.model,
+		"instructions": s.instructions,
+		"tools":        tools,
+		"tool_choice":  "auto",
+	}
+	if s.toolChoice.Exists() {
+		session["tool_choice"] = s.toolChoice.Value()
+	}
+	maxTokens := any("inf")
+	if s.maxTokens.Exists() {
+		maxTokens = s.maxTokens.Value()
+	}
+	if s.beta {
+		session["modalities"] = []string{"text"}
+		session["max_response_output_tokens"] = maxTokens
+	} else {
+		session["type"] = "realtime"
+		session["output_modalities"] = []string{"text"}
+		session["max_output_tokens"] = maxTokens
+	}
+	if s.temperature.Exists() {
+		session["temperature"] = s.temperature.Value()
+	}
+	return session
+}
+
+func (s *realtimeSession) lastItemID() any {
+	if len(s.items) == 0 {
+		return nil
+	}
+	return s.items[len(s.items)-1].ID
+}
+
+func (s *realtimeSession) deleteItem(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.items {
+		if s.items[i].ID == id {
+			s.items = append(s.items[:i], s.items[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *realtimeSession) sendItemCreated(item realtimeItem, previous any) {
+	obj := s.itemObject(item, "completed")
+	if s.beta {
+		s.send(gin.H{"type": "conversation.item.created", "previous_item_id": previous, "item": obj})
+		return
+	}
+	s.send(gin.H{"type": "conversation.item.added", "previous_item_id": previous, "item": obj})
+	s.send(gin.H{"type": "conversation.item.done", "previous_item_id": previous, "item": obj})
+}
+
+// parseRealtimeItem converts a client conversation item. Text parts of messages are joined.
+func parseRealtimeItem(raw gjson.Result) (realtimeItem, string) {
+	if !raw.IsObject() {
+		return realtimeItem{}, "Missing required parameter: 'item'."
+	}
+	item := realtimeItem{ID: raw.Get("id").String(), Type: raw.Get("type").String()}
+	if item.ID == "" {
+		item.ID = realtimeID("item_")
+	}
+	switch item.Type {
+	case "message":
+		item.Role = raw.Get("role").String()
+		if item.Role != "user" && item.Role != "assistant" && item.Role != "system" {
+			return realtimeItem{}, "Invalid message role: " + item.Role
+		}
+		var text strings.Builder
+		for _, part := range raw.Get("content").Array() {
+			switch part.Get("type").String() {
+			case "input_text", "text", "output_text":
+				text.WriteString(part.Get("text").String())
+			case "input_audio", "audio", "output_audio":
+				if transcript := part.Get("transcript").String(); transcript != "" {
+					text.WriteString(transcript)
+					continue
+				}
+				return realtimeItem{}, "Audio content is not supported by this server."
+			}
+		}
+		item.Text = text.String()
+	case "function_call":
+		item.CallID = raw.Get("call_id").String()
+		item.Name = raw.Get("name").String()
+		item.Arguments = raw.Get("arguments").String()
+	case "function_call_output":
+		item.CallID = raw.Get("call_id").String()
+		item.Output = raw.Get("output").String()
+		if item.CallID == "" {
+			return realtimeItem{}, "Missing required parameter: 'item.call_id'."
+		}
+	default:
+		return realtimeItem{}, "Invalid item type: " + item.Type
+	}
+	return item, ""
+}
+
+// itemObject renders item in the Realtime API format.
+func (s *realtimeSession) itemObject(item realtimeItem, status string) gin.H {
+	obj := gin.H{"id": item.ID, "object": "realtime.item", "type": item.Type, "status": status}
+	switch item.Type {
+	case "message":
+		partType := "input_text"
+		if item.Role == "assistant" {
+			partType = s.outputTextType()
+		}
+		obj["role"] = item.Role
+		obj["content"] = []gin.H{{"type": partType, "text": item.Text}}
+	case "function_call":
+		obj["call_id"] = item.CallID
+		obj["name"] = item.Name
+		obj["arguments"] = item.Arguments
+	case "function_call_output":
+		obj["call_id"] = item.CallID
+		obj["output"] = item.Output
+	}
+	return obj
+}
+
+func (s *realtimeSession) outputTextType() string {
+	if s.beta {
+		return "text"
+	}
+	return "output_text"
+}
+
+func (s *realtimeSession) textEvent(suffix string) string {
+	if s.beta {
+		return "response.text." + suffix
+	}
+	return "response.output_text." + suffix
+}
+
+// buildChatRequest renders the conversation as a streaming chat completion request. Response
+// level overrides from response.create take precedence over the session configuration.
+func (s *realtimeSession) buildChatRequest(items []realtimeItem, overrides gjson.Result) []byte {
+	s.mu.Lock()
+	model := s.model
+	instructions := s.instructions
+	tools := s.tools
+	toolChoice := s.toolChoice
+	temperature := s.temperature
+	maxTokens := s.maxTokens
+	s.mu.Unlock()
+
+	if v := overrides.Get("instructions"); v.Exists() {
+		instructions = v.String()
+	}
+	if v := overrides.Get("tools"); v.IsArray() {
+		tools = v.Array()
+	}
+	if v := overrides.Get("tool_choice"); v.Exists() {
+		toolChoice = v
+	}
+	if v := overrides.Get("temperature"); v.Exists() {
+		temperature = v
+	}
+	for _, key := range []string{"max_response_output_tokens", "max_output_tokens"} {
+		if v := overrides.Get(key); v.Exists() {
+			maxTokens = v
+		}
+	}
+
+	out := `{"model":"","stream":true,"stream_options":{"include_usage":true},"messages":[]}`
+	out, _ = sjson.Set(out, "model", model)
+	if instructions != "" {
+		out, _ = sjson.SetRaw(out, "messages.-1", mustJSON(gin.H{"role": "system", "content": instructions}))
+	}
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+		switch item.Type {
+		case "message":
+			out, _ = sjson.SetRaw(out, "messages.-1", mustJSON(gin.H{"role": item.Role, "content": item.Text}))
+		case "function_call":
+			// Consecutive calls belong to one assistant turn.
+			calls := make([]gin.H, 0, 1)
+			for ; i < len(items) && items[i].Type == "function_call"; i++ {
+				arguments := items[i].Arguments
+				if arguments == "" {
+					arguments = "{}"
+				}
+				calls = append(calls, gin.H{
+					"id":       items[i].CallID,
+					"type":     "function",
+					"function": gin.H{"name": items[i].Name, "arguments": arguments},
+				})
+			}
+			i--
+			out, _ = sjson.SetRaw(out, "messages.-1", mustJSON(gin.H{"role": "assistant", "content": nil, "tool_calls": calls}))
+		case "function_call_output":
+			out, _ = sjson.SetRaw(out, "messages.-1", mustJSON(gin.H{"role": "tool", "tool_call_id": item.CallID, "content": item.Output}))
+		}
+	}
+	for _, tool := range tools {
+		if tool.Get("type").String() != "function" {
+			continue
+		}
+		function := gin.H{"name": tool.Get("name").String()}
+		if v := tool.Get("description"); v.Exists() {
+			function["description"] = v.String()
+		}
+		if v := tool.Get("parameters"); v.Exists() {
+			function["parameters"] = v.Value()
+		}
+		out, _ = sjson.SetRaw(out, "tools.-1", mustJSON(gin.H{"type": "function", "function": function}))
+	}
+	if toolChoice.Exists() {
+		if toolChoice.IsObject() && toolChoice.Get("name").Exists() {
+			out, _ = sjson.SetRaw(out, "tool_choice", mustJSON(gin.H{"type": "function", "function": gin.H{"name": toolChoice.Get("name").String()}}))
+		} else if toolChoice.Type == gjson.String {
+			out, _ = sjson.Set(out, "tool_choice", toolChoice.String())
+		}
+	}
+	if temperature.Type == gjson.Number {
+		out, _ = sjson.Set(out, "temperature", temperature.Float())
+	}
+	if maxTokens.Type == gjson.Number && maxTokens.Int() > 0 {
+		out, _ = sjson.Set(out, "max_tokens", maxTokens.Int())
+	}
+	return []byte(out)
+}
+
+func mustJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// startResponse runs a response in the background; only one response may be active at a time.
+func (s *realtimeSession) startResponse(overrides gjson.Result, eventID string) {
+	s.mu.Lock()
+	if s.active != nil {
+		s.mu.Unlock()
+		s.sendError("conversation_already_has_active_response", "Conversation already has an active response.", eventID)
+		return
+	}
+	items := append([]realtimeItem(nil), s.items...)
+	outOfBand := overrides.Get("conversation").String() == "none"
+	if input := overrides.Get("input"); input.IsArray() {
+		items = items[:0]
+		for _, raw := range input.Array() {
+			if item, errMsg := parseRealtimeItem(raw); errMsg == "" {
+				items = append(items, item)
+			}
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.active, s.activeDone = cancel, done
+	s.mu.Unlock()
+
+	rawJSON := s.buildChatRequest(items, overrides)
+	go func() {
+		defer close(done)
+		defer func() {
+			cancel()
+			s.mu.Lock()
+			s.active, s.activeDone = nil, nil
+			s.mu.Unlock()
+		}()
+		s.runResponse(ctx, rawJSON, outOfBand, overrides.Get("metadata"))
+	}()
+}
+
+// responseOutput accumulates the output items of a response.
+type responseOutput struct {
+	s     *realtimeSession
+	id    string
+	text  *realtimeItem
+	calls map[int64]*realtimeItem
+	order []*realtimeItem
+	usage gjson.Result
+}
+
+func (o *responseOutput) outputIndex(item *realtimeItem) int {
+	for i, existing := range o.order {
+		if existing == item {
+			return i
+		}
+	}
+	return -1
+}
+
+func (o *responseOutput) addItem(item *realtimeItem) {
+	o.order = append(o.order, item)
+	o.s.send(gin.H{"type": "response.output_item.added", "response_id": o.id, "output_index": len(o.order) - 1, "item": o.s.itemObject(*item, "in_progress")})
+}
+
+func (o *responseOutput) appendText(delta string) {
+	if o.text == nil {
+		o.text = &realtimeItem{ID: realtimeID("item_"), Type: "message", Role: "assistant"}
+		o.addItem(o.text)
+		o.s.send(gin.H{"type": "response.content_part.added", "response_id": o.id, "item_id": o.text.ID, "output_index": o.outputIndex(o.text), "content_index": 0, "part": gin.H{"type": o.s.outputTextType(), "text": ""}})
+	}
+	o.text.Text += delta
+	o.s.send(gin.H{"type": o.s.textEvent("delta"), "response_id": o.id, "item_id": o.text.ID, "output_index": o.outputIndex(o.text), "content_index": 0, "delta": delta})
+}
+
+func (o *responseOutput) appendToolCall(call gjson.Result) {
+	index := call.Get("index").Int()
+	item := o.calls[index]
+	if item == nil {
+		callID := call.Get("id").String()
+		if callID == "" {
+			callID = realtimeID("call_")
+		}
+		item = &realtimeItem{ID: realtimeID("item_"), Type: "function_call", CallID: callID, Name: call.Get("function.name").String()}
+		o.calls[index] = item
+		o.addItem(item)
+	} else if name := call.Get("function.name").String(); name != "" && item.Name == "" {
+		item.Name = name
+	}
+	if delta := call.Get("function.arguments").String(); delta != "" {
+		item.Arguments += delta
+		o.s.send(gin.H{"type": "response.function_call_arguments.delta", "response_id": o.id, "item_id": item.ID, "output_index": o.outputIndex(item), "call_id": item.CallID, "delta": delta})
+	}
+}
+
+// finish closes every output item and returns them.
+func (o *responseOutput) finish() []realtimeItem {
+	items := make([]realtimeItem, 0, len(o.order))
+	for i, item := range o.order {
+		switch item.Type {
+		case "message":
+			o.s.send(gin.H{"type": o.s.textEvent("done"), "response_id": o.id, "item_id": item.ID, "output_index": i, "content_index": 0, "text": item.Text})
+			o.s.send(gin.H{"type": "response.content_part.done", "response_id": o.id, "item_id": item.ID, "output_index": i, "content_index": 0, "part": gin.H{"type": o.s.outputTextType(), "text": item.Text}})
+		case "function_call":
+			o.s.send(gin.H{"type": "response.function_call_arguments.done", "response_id": o.id, "item_id": item.ID, "output_index": i, "call_id": item.CallID, "name": item.Name, "arguments": item.Arguments})
+		}
+		o.s.send(gin.H{"type": "response.output_item.done", "response_id": o.id, "output_index": i, "item": o.s.itemObject(*item, "completed")})
+		items = append(items, *item)
+	}
+	return items
+}
+
+func (s *realtimeSession) runResponse(ctx context.Context, rawJSON []byte, outOfBand bool, metadata gjson.Result) {
+	out := &responseOutput{s: s, id: realtimeID("resp_"), calls: make(map[int64]*realtimeItem)}
+	response := gin.H{"id": out.id, "object": "realtime.response", "status": "in_progress", "output": []any{}}
+	if outOfBand {
+		response["conversation_id"] = nil
+	}
+	if metadata.Exists() {
+		response["metadata"] = metadata.Value()
+	}
+	s.send(gin.H{"type": "response.created", "response": response})
+
+	cliCtx, cliCancel := s.h.GetContextWithCancel(s.h, s.c, ctx)
+	modelName := gjson.GetBytes(rawJSON, "model").String()
+	dataChan, errChan := s.h.ExecuteStreamWithAuthManager(cliCtx, s.h.HandlerType(), modelName, rawJSON, "")
+
+	var failure *interfaces.ErrorMessage
+	for dataChan != nil || errChan != nil {
+		select {
+		case chunk, ok := <-dataChan:
+			if !ok {
+				dataChan = nil
+				continue
+			}
+			out.consume(chunk)
+		case errMsg, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			if errMsg != nil {
+				failure = errMsg
+			}
+		}
+	}
+
+	items := out.finish()
+	cancelled := ctx.Err() != nil
+	switch {
+	case cancelled:
+		response["status"] = "cancelled"
+		response["status_details"] = gin.H{"type": "cancelled", "reason": "client_cancelled"}
+		cliCancel(context.Canceled)
+	case failure != nil:
+		message := http.StatusText(failure.StatusCode)
+		if failure.Error != nil && failure.Error.Error() != "" {
+			message = failure.Error.Error()
+		}
+		response["status"] = "failed"
+		response["status_details"] = gin.H{"type": "failed", "error": gin.H{"type": "server_error", "code": failure.StatusCode, "message": message}}
+		cliCancel(failure.Error)
+	default:
+		response["status"] = "completed"
+		cliCancel(nil)
+	}
+	if !outOfBand && len(items) > 0 {
+		s.mu.Lock()
+		s.items = append(s.items, items...)
+		s.mu.Unlock()
+	}
+	output := make([]gin.H, 0, len(items))
+	for _, item := range items {
+		output = append(output, s.itemObject(item, "completed"))
+	}
+	response["output"] = output
+	if out.usage.Exists() {
+		input := out.usage.Get("prompt_tokens").Int()
+		completion := out.usage.Get("completion_tokens").Int()
+		response["usage"] = gin.H{"total_tokens": input + completion, "input_tokens": input, "output_tokens": completion}
+	}
+	s.send(gin.H{"type": "response.done", "response": response})
+}
+
+// consume applies one chat completion chunk to the response.
+func (o *responseOutput) consume(chunk []byte) {
+	if !gjson.ValidBytes(chunk) {
+		return
+	}
+	root := gjson.ParseBytes(chunk)
+	if usage := root.Get("usage"); usage.IsObject() {
+		o.usage = usage
+	}
+	delta := root.Get("choices.0.delta")
+	if text := delta.Get("content").String(); text != "" {
+		o.appendText(text)
+	}
+	for _, call := range delta.Get("tool_calls").Array() {
+		o.appendToolCall(call)
+	}
+}
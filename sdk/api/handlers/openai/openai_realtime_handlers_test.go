@@ -0,0 +1,227 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// realtimeStreamExecutor answers the first request with text and a tool call and later requests
+// with text only, recording every request payload.
+type realtimeStreamExecutor struct {
+	mu       sync.Mutex
+	payloads [][]byte
+}
+
+func (e *realtimeStreamExecutor) Identifier() string { return "test-provider" }
+
+func (e *realtimeStreamExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *realtimeStreamExecutor) ExecuteStream(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	e.mu.Lock()
+	e.payloads = append(e.payloads, req.Payload)
+	call := len(e.payloads)
+	e.mu.Unlock()
+
+	chunks := []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":"Let me "}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"check."}}]}`,
+	}
+	if call == 1 {
+		chunks = append(chunks,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":"}}]}}]}`,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}`,
+		)
+	}
+	chunks = append(chunks, `{"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":5}}`)
+	ch := make(chan coreexecutor.StreamChunk, len(chunks))
+	for _, chunk := range chunks {
+		ch <- coreexecutor.StreamChunk{Payload: []byte(chunk)}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (e *realtimeStreamExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *realtimeStreamExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *realtimeStreamExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *realtimeStreamExecutor) payload(i int) []byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.payloads[i]
+}
+
+func dialRealtime(t *testing.T, header http.Header) (*websocket.Conn, *realtimeStreamExecutor) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	executor := &realtimeStreamExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "auth-realtime", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "test-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	h := NewOpenAIRealtimeAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	router := gin.New()
+	router.GET("/v1/realtime", h.Realtime)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/realtime?model=test-model"
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, executor
+}
+
+// readUntil collects events up to and including the first event of type stop.
+func readUntil(t *testing.T, conn *websocket.Conn, stop string) []gjson.Result {
+	t.Helper()
+	var events []gjson.Result
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read while waiting for %s: %v", stop, err)
+		}
+		event := gjson.ParseBytes(payload)
+		events = append(events, event)
+		if event.Get("type").String() == stop {
+			return events
+		}
+	}
+}
+
+func eventsOfType(events []gjson.Result, eventType string) []gjson.Result {
+	var out []gjson.Result
+	for _, event := range events {
+		if event.Get("type").String() == eventType {
+			out = append(out, event)
+		}
+	}
+	return out
+}
+
+func TestRealtimeTextAndFunctionCalls(t *testing.T) {
+	conn, executor := dialRealtime(t, nil)
+	created := readUntil(t, conn, "session.created")
+	if model := created[0].Get("session.model").String(); model != "test-model" {
+		t.Fatalf("session model = %q", model)
+	}
+
+	send := func(event string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(event)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	send(`{"type":"session.update","session":{"instructions":"Be brief.","tools":[{"type":"function","name":"get_weather","description":"Weather","parameters":{"type":"object"}}]}}`)
+	readUntil(t, conn, "session.updated")
+	send(`{"type":"conversation.item.create","item":{"type":"message","role":"user","content":[{"type":"input_text","text":"Weather in Paris?"}]}}`)
+	readUntil(t, conn, "conversation.item.done")
+	send(`{"type":"response.create"}`)
+	events := readUntil(t, conn, "response.done")
+
+	var text strings.Builder
+	for _, delta := range eventsOfType(events, "response.output_text.delta") {
+		text.WriteString(delta.Get("delta").String())
+	}
+	if text.String() != "Let me check." {
+		t.Errorf("streamed text = %q", text.String())
+	}
+	argsDone := eventsOfType(events, "response.function_call_arguments.done")
+	if len(argsDone) != 1 || argsDone[0].Get("arguments").String() != `{"city":"Paris"}` || argsDone[0].Get("call_id").String() != "call_1" {
+		t.Fatalf("function call events = %v", argsDone)
+	}
+	done := events[len(events)-1].Get("response")
+	if done.Get("status").String() != "completed" || len(done.Get("output").Array()) != 2 || done.Get("usage.total_tokens").Int() != 17 {
+		t.Fatalf("response.done = %s", done.Raw)
+	}
+
+	first := executor.payload(0)
+	if gjson.GetBytes(first, "messages.0.role").String() != "system" || gjson.GetBytes(first, "messages.1.content").String() != "Weather in Paris?" {
+		t.Errorf("chat request messages = %s", gjson.GetBytes(first, "messages").Raw)
+	}
+	if gjson.GetBytes(first, "tools.0.function.name").String() != "get_weather" || !gjson.GetBytes(first, "stream").Bool() {
+		t.Errorf("chat request = %s", first)
+	}
+
+	send(`{"type":"conversation.item.create","item":{"type":"function_call_output","call_id":"call_1","output":"{\"temp\":21}"}}`)
+	readUntil(t, conn, "conversation.item.done")
+	send(`{"type":"response.create"}`)
+	readUntil(t, conn, "response.done")
+
+	messages := gjson.GetBytes(executor.payload(1), "messages").Array()
+	if len(messages) != 5 {
+		t.Fatalf("follow-up messages = %d: %v", len(messages), messages)
+	}
+	if messages[2].Get("role").String() != "assistant" || messages[2].Get("content").String() != "Let me check." {
+		t.Errorf("assistant text message = %s", messages[2].Raw)
+	}
+	if messages[3].Get("tool_calls.0.id").String() != "call_1" || messages[4].Get("role").String() != "tool" || messages[4].Get("tool_call_id").String() != "call_1" {
+		t.Errorf("tool call history = %s %s", messages[3].Raw, messages[4].Raw)
+	}
+}
+
+func TestRealtimeBetaEventsAndAudioRejection(t *testing.T) {
+	conn, _ := dialRealtime(t, http.Header{"OpenAI-Beta": []string{"realtime=v1"}})
+	created := readUntil(t, conn, "session.created")
+	if !created[0].Get("session.modalities").IsArray() {
+		t.Fatalf("beta session = %s", created[0].Raw)
+	}
+
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"input_audio_buffer.append","event_id":"evt_1","audio":"AAAA"}`))
+	errEvent := readUntil(t, conn, "error")
+	if code := errEvent[0].Get("error.code").String(); code != "unsupported_modality" || errEvent[0].Get("error.event_id").String() != "evt_1" {
+		t.Fatalf("audio error = %s", errEvent[0].Raw)
+	}
+
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"conversation.item.create","item":{"type":"message","role":"user","content":[{"type":"input_text","text":"Hi"}]}}`))
+	readUntil(t, conn, "conversation.item.created")
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"response.create"}`))
+	events := readUntil(t, conn, "response.done")
+	if len(eventsOfType(events, "response.text.delta")) == 0 || len(eventsOfType(events, "response.text.done")) != 1 {
+		t.Fatalf("beta text events missing: %v", events)
+	}
+}
+
+func TestRealtimeRequiresModel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewOpenAIRealtimeAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, coreauth.NewManager(nil, nil, nil)))
+	router := gin.New()
+	router.GET("/v1/realtime", h.Realtime)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/realtime", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,112 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// rawChunkExecutor streams a fixed list of chunks exactly as an upstream executor might emit them.
+type rawChunkExecutor struct {
+	chunks []string
+}
+
+func (e *rawChunkExecutor) Identifier() string { return "test-provider" }
+
+func (e *rawChunkExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *rawChunkExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	ch := make(chan coreexecutor.StreamChunk, len(e.chunks))
+	for _, chunk := range e.chunks {
+		ch <- coreexecutor.StreamChunk{Payload: []byte(chunk)}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (e *rawChunkExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *rawChunkExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *rawChunkExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func streamThroughHandler(t *testing.T, chunks []string, register func(*gin.Engine, *handlers.BaseAPIHandler), path string) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	executor := &rawChunkExecutor{chunks: chunks}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "auth-framing", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "test-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	router := gin.New()
+	register(router, handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"model":"test-model","stream":true,"input":"hi","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", resp.Code, resp.Body.String())
+	}
+	return resp.Body.String()
+}
+
+func TestChatCompletionsStreamFramingGolden(t *testing.T) {
+	got := streamThroughHandler(t, []string{
+		`{"id":"c1","choices":[{"delta":{"content":"Hi"}}]}`,
+		"data: {\"id\":\"c1\",\"choices\":[]}\r\n\r\n",
+		"data: [DONE]",
+	}, func(r *gin.Engine, base *handlers.BaseAPIHandler) {
+		r.POST("/v1/chat/completions", NewOpenAIAPIHandler(base).ChatCompletions)
+	}, "/v1/chat/completions")
+
+	want := "data: {\"id\":\"c1\",\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n" +
+		"data: {\"id\":\"c1\",\"choices\":[]}\n\n" +
+		"data: [DONE]\n\n"
+	if got != want {
+		t.Fatalf("stream mismatch\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestResponsesStreamFramingGolden(t *testing.T) {
+	got := streamThroughHandler(t, []string{
+		"event: response.created",
+		`data: {"type":"response.created"}`,
+		"",
+		"event: response.output_text.delta",
+		`data: {"type":"response.output_text.delta","delta":"Hi"}`,
+		"",
+		`data: {"type":"response.completed"}`,
+	}, func(r *gin.Engine, base *handlers.BaseAPIHandler) {
+		r.POST("/v1/responses", NewOpenAIResponsesAPIHandler(base).Responses)
+	}, "/v1/responses")
+
+	want := "event: response.created\ndata: {\"type\":\"response.created\"}\n\n" +
+		"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"Hi\"}\n\n" +
+		"event: response.completed\ndata: {\"type\":\"response.completed\"}\n\n"
+	if got != want {
+		t.Fatalf("stream mismatch\n got: %q\nwant: %q", got, want)
+	}
+}
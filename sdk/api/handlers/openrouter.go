@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// providerPreferencesContextKey is the gin context key holding the OpenRouter provider
+// preferences of the request.
+const providerPreferencesContextKey = "providerPreferences"
+
+// providerPreferences is the subset of OpenRouter's provider object the proxy honours.
+type providerPreferences struct {
+	// Order lists proxy provider keys, most preferred first.
+	Order []string
+	// AllowFallbacks permits providers outside Order once the ordered ones are exhausted.
+	AllowFallbacks bool
+}
+
+// openRouterProviderAliases maps OpenRouter provider names to the proxy providers serving the
+// same upstream. Names without an alias are used as proxy provider keys as-is.
+var openRouterProviderAliases = map[string][]string{
+	"anthropic":        {"claude"},
+	"openai":           {"codex"},
+	"google":           {"gemini", "vertex", "gemini-cli", "aistudio", "antigravity"},
+	"google-vertex":    {"vertex"},
+	"google-ai-studio": {"aistudio", "gemini"},
+	"alibaba":          {"qwen"},
+}
+
+// applyOpenRouterExtensions strips the OpenRouter request extensions provider, transforms,
+// route and models from OpenAI-format requests so OpenRouter clients work unchanged. Provider
+// preferences are kept on the gin context for routing, and the models of a fallback route are
+// returned in order. Transforms have no equivalent and are ignored.
+func applyOpenRouterExtensions(ctx context.Context, handlerType, modelName string, rawJSON []byte) ([]byte, []string) {
+	if handlerType != "openai" && handlerType != "openai-response" {
+		return rawJSON, nil
+	}
+	provider := gjson.GetBytes(rawJSON, "provider")
+	models := gjson.GetBytes(rawJSON, "models")
+	transforms := gjson.GetBytes(rawJSON, "transforms")
+	route := gjson.GetBytes(rawJSON, "route")
+	if !provider.Exists() && !models.Exists() && !transforms.Exists() && !route.Exists() {
+		return rawJSON, nil
+	}
+	for _, field := range []string{"provider", "models", "transforms", "route"} {
+		rawJSON, _ = sjson.DeleteBytes(rawJSON, field)
+	}
+
+	if provider.IsObject() {
+		prefs := providerPreferences{AllowFallbacks: true}
+		if allow := provider.Get("allow_fallbacks"); allow.Exists() {
+			prefs.AllowFallbacks = allow.Bool()
+		}
+		seen := make(map[string]struct{})
+		for _, name := range provider.Get("order").Array() {
+			for _, key := range proxyProvidersFor(name.String()) {
+				if _, dup := seen[key]; !dup {
+					seen[key] = struct{}{}
+					prefs.Order = append(prefs.Order, key)
+				}
+			}
+		}
+		if ginCtx := ginContextOf(ctx); ginCtx != nil && len(prefs.Order) > 0 {
+			ginCtx.Set(providerPreferencesContextKey, prefs)
+		}
+	}
+	if transforms.Exists() {
+		log.Debugf("openrouter: ignoring unsupported transforms %s", transforms.Raw)
+	}
+
+	// OpenRouter treats a models list as a fallback route; route "fallback" is its legacy spelling.
+	var fallbacks []string
+	for _, model := range models.Array() {
+		name := strings.TrimSpace(model.String())
+		if name == "" || strings.EqualFold(name, modelName) {
+			continue
+		}
+		fallbacks = append(fallbacks, name)
+	}
+	return rawJSON, fallbacks
+}
+
+// proxyProvidersFor resolves an OpenRouter provider name such as "Anthropic" or "google-vertex".
+func proxyProvidersFor(name string) []string {
+	key := strings.ToLower(strings.Join(strings.Fields(name), "-"))
+	if key == "" {
+		return nil
+	}
+	if aliases, ok := openRouterProviderAliases[key]; ok {
+		return aliases
+	}
+	return []string{key}
+}
+
+// ginContextOf returns the gin context carried by ctx, or nil.
+func ginContextOf(ctx context.Context) *gin.Context {
+	if ctx == nil {
+		return nil
+	}
+	ginCtx, _ := ctx.Value("gin").(*gin.Context)
+	return ginCtx
+}
+
+// requestProviderPreferences returns the provider preferences captured for the request.
+func requestProviderPreferences(ctx context.Context) (providerPreferences, bool) {
+	ginCtx := ginContextOf(ctx)
+	if ginCtx == nil {
+		return providerPreferences{}, false
+	}
+	value, ok := ginCtx.Get(providerPreferencesContextKey)
+	if !ok {
+		return providerPreferences{}, false
+	}
+	prefs, ok := value.(providerPreferences)
+	return prefs, ok
+}
+
+// restrictProviders limits providers to the preferred ones when the client disallowed
+// fallbacks to other providers.
+func restrictProviders(ctx context.Context, modelName string, providers []string) ([]string, *interfaces.ErrorMessage) {
+	prefs, ok := requestProviderPreferences(ctx)
+	if !ok || prefs.AllowFallbacks {
+		return providers, nil
+	}
+	allowed := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		for _, preferred := range prefs.Order {
+			if strings.EqualFold(provider, preferred) {
+				allowed = append(allowed, provider)
+				break
+			}
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, &interfaces.ErrorMessage{
+			StatusCode: http.StatusBadGateway,
+			Error:      fmt.Errorf("no provider in provider.order serves model %s", modelName),
+		}
+	}
+	return allowed, nil
+}
+
+// modelFallbackEligible reports whether a failed model of a fallback route should give way to
+// the next one. Errors caused by the request itself are returned to the client.
+func modelFallbackEligible(msg *interfaces.ErrorMessage) bool {
+	if msg == nil {
+		return false
+	}
+	switch status := msg.StatusCode; {
+	case status == http.StatusBadRequest, status == http.StatusUnauthorized, status == http.StatusPaymentRequired:
+		return false
+	default:
+		return true
+	}
+}
+
+// withRequestModel points the request body at model and reports the substitution to the client.
+func withRequestModel(ctx context.Context, rawJSON []byte, requested, model string) []byte {
+	if gjson.GetBytes(rawJSON, "model").Exists() {
+		if updated, err := sjson.SetBytes(rawJSON, "model", model); err == nil {
+			rawJSON = updated
+		}
+	}
+	if ginCtx := ginContextOf(ctx); ginCtx != nil {
+		ginCtx.Header("X-CPA-Requested-Model", requested)
+		ginCtx.Header("X-CPA-Served-Model", model)
+	}
+	return rawJSON
+}
+
+// executeWithModelFallback serves a non-streaming request from the first model of the route
+// that succeeds.
+func (h *BaseAPIHandler) executeWithModelFallback(ctx context.Context, handlerType, modelName string, fallbacks []string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
+	resp, errMsg := h.ExecuteWithAuthManager(ctx, handlerType, modelName, rawJSON, alt)
+	failed := modelName
+	for _, model := range fallbacks {
+		if errMsg == nil || !modelFallbackEligible(errMsg) {
+			break
+		}
+		log.Debugf("openrouter: model %s failed with status %d, falling back to %s", failed, errMsg.StatusCode, model)
+		resp, errMsg = h.ExecuteWithAuthManager(ctx, handlerType, model, withRequestModel(ctx, rawJSON, modelName, model), alt)
+		failed = model
+	}
+	return resp, errMsg
+}
+
+// streamWithModelFallback serves a streaming request from the first model of the route that
+// starts streaming. Once payload was forwarded, errors end the stream as usual.
+func (h *BaseAPIHandler) streamWithModelFallback(ctx context.Context, handlerType, modelName string, fallbacks []string, rawJSON []byte, alt string) (<-chan []byte, <-chan *interfaces.ErrorMessage) {
+	dataOut := make(chan []byte)
+	errOut := make(chan *interfaces.ErrorMessage, 1)
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+	go func() {
+		defer close(dataOut)
+		defer close(errOut)
+		models := append([]string{modelName}, fallbacks...)
+		for i, model := range models {
+			body := rawJSON
+			if i > 0 {
+				body = withRequestModel(ctx, rawJSON, modelName, model)
+			}
+			dataChan, errChan := h.ExecuteStreamWithAuthManager(ctx, handlerType, model, body, alt)
+			started := false
+			fallBack := false
+			for !fallBack && (dataChan != nil || errChan != nil) {
+				select {
+				case <-done:
+					return
+				case chunk, ok := <-dataChan:
+					if !ok {
+						dataChan = nil
+						continue
+					}
+					started = true
+					select {
+					case <-done:
+						return
+					case dataOut <- chunk:
+					}
+				case msg, ok := <-errChan:
+					if !ok {
+						errChan = nil
+						continue
+					}
+					if msg == nil {
+						continue
+					}
+					if !started && i < len(models)-1 && modelFallbackEligible(msg) {
+						log.Debugf("openrouter: model %s failed with status %d, falling back to %s", model, msg.StatusCode, models[i+1])
+						fallBack = true
+						continue
+					}
+					errOut <- msg
+					return
+				}
+			}
+			if !fallBack {
+				return
+			}
+		}
+	}()
+	return dataOut, errOut
+}
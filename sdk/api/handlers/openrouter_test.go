@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyOpenRouterExtensions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+	raw := []byte(`{"model":"a","messages":[],"provider":{"order":["Anthropic","gemini-cli"],"allow_fallbacks":false},"transforms":["middle-out"],"route":"fallback","models":["a","b","c"]}`)
+
+	out, fallbacks := applyOpenRouterExtensions(ctx, "openai", "a", raw)
+	for _, field := range []string{"provider", "transforms", "route", "models"} {
+		if gjson.GetBytes(out, field).Exists() {
+			t.Fatalf("%s was forwarded: %s", field, out)
+		}
+	}
+	if len(fallbacks) != 2 || fallbacks[0] != "b" || fallbacks[1] != "c" {
+		t.Fatalf("fallbacks = %v", fallbacks)
+	}
+	prefs, ok := requestProviderPreferences(ctx)
+	if !ok || prefs.AllowFallbacks || len(prefs.Order) != 2 || prefs.Order[0] != "claude" || prefs.Order[1] != "gemini-cli" {
+		t.Fatalf("preferences = %+v, %v", prefs, ok)
+	}
+
+	providers, errMsg := restrictProviders(ctx, "a", []string{"codex", "gemini-cli"})
+	if errMsg != nil || len(providers) != 1 || providers[0] != "gemini-cli" {
+		t.Fatalf("restrictProviders = %v, %v", providers, errMsg)
+	}
+	if _, errMsg = restrictProviders(ctx, "a", []string{"codex"}); errMsg == nil {
+		t.Fatalf("expected an error when no ordered provider serves the model")
+	}
+
+	if out, _ = applyOpenRouterExtensions(ctx, "claude", "a", raw); string(out) != string(raw) {
+		t.Fatalf("non-OpenAI formats must be left unchanged")
+	}
+}
+
+type modelFailingStreamExecutor struct {
+	failingStreamModel string
+}
+
+func (e *modelFailingStreamExecutor) Identifier() string { return "codex" }
+
+func (e *modelFailingStreamExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "Execute not implemented"}
+}
+
+func (e *modelFailingStreamExecutor) ExecuteStream(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	ch := make(chan coreexecutor.StreamChunk, 1)
+	if req.Model == e.failingStreamModel {
+		ch <- coreexecutor.StreamChunk{Err: &coreauth.Error{Code: "unavailable", Message: "unavailable", HTTPStatus: http.StatusServiceUnavailable}}
+	} else {
+		ch <- coreexecutor.StreamChunk{Payload: []byte(req.Model)}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (e *modelFailingStreamExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *modelFailingStreamExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (e *modelFailingStreamExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented", HTTPStatus: http.StatusNotImplemented}
+}
+
+func TestExecuteStreamWithAuthManager_ModelFallbackRoute(t *testing.T) {
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(&modelFailingStreamExecutor{failingStreamModel: "primary-model"})
+	auth := &coreauth.Auth{ID: "route-auth", Provider: "codex", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "primary-model"}, {ID: "backup-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+	body := []byte(`{"model":"primary-model","stream":true,"route":"fallback","models":["primary-model","backup-model"],"messages":[{"role":"user","content":"hi"}]}`)
+	dataChan, errChan := handler.ExecuteStreamWithAuthManager(context.Background(), "openai", "primary-model", body, "")
+
+	var got []byte
+	for chunk := range dataChan {
+		got = append(got, chunk...)
+	}
+	for msg := range errChan {
+		if msg != nil {
+			t.Fatalf("unexpected error: %+v", msg)
+		}
+	}
+	if string(got) != "backup-model" {
+		t.Fatalf("expected the backup model to serve the stream, got %q", got)
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/translator/gemini/common"
+)
+
+// geminiFamilyProviders are the upstreams that only accept inline media.
+var geminiFamilyProviders = map[string]struct{}{
+	"gemini":      {},
+	"gemini-cli":  {},
+	"vertex":      {},
+	"aistudio":    {},
+	"antigravity": {},
+}
+
+// applyRemoteMedia inlines remote image URLs of OpenAI-format requests once, before the request is
+// dispatched, when any candidate provider is a Gemini-family upstream. Retries across credentials
+// reuse the inlined body instead of downloading again.
+func applyRemoteMedia(ctx context.Context, handlerType string, providers []string, rawJSON []byte) []byte {
+	if !common.RemoteMediaEnabled() {
+		return rawJSON
+	}
+	if handlerType != "openai" && handlerType != "openai-response" {
+		return rawJSON
+	}
+	for _, provider := range providers {
+		if _, ok := geminiFamilyProviders[provider]; ok {
+			return common.InlineRemoteMedia(ctx, handlerType, rawJSON)
+		}
+	}
+	return rawJSON
+}
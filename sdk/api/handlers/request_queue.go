@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+const (
+	defaultQueueMaxWait   = 30 * time.Second
+	defaultQueueMaxQueued = 100
+	// queuePollInterval bounds how long a waiter sleeps before re-checking credential availability.
+	queuePollInterval = 500 * time.Millisecond
+	minQueuePoll      = 10 * time.Millisecond
+)
+
+// queuedRequests counts requests currently waiting in the admission queue.
+var queuedRequests atomic.Int64
+
+// QueuedRequests returns the number of requests waiting for a rate-limited credential.
+func QueuedRequests() int64 { return queuedRequests.Load() }
+
+type admissionWaiter struct {
+	priority int
+	seq      uint64
+	wake     chan struct{}
+}
+
+// admissionQueue orders requests waiting for the credentials of one model by priority, then
+// arrival. Only the head of a queue is admitted, so higher-priority requests go first once a
+// credential recovers.
+type admissionQueue struct {
+	mu      sync.Mutex
+	seq     uint64
+	total   int
+	waiting map[string][]*admissionWaiter
+}
+
+var defaultAdmissionQueue = &admissionQueue{}
+
+// errQueueShed describes why a request was not admitted and when the caller may retry.
+type errQueueShed struct {
+	reason     string
+	retryAfter time.Duration
+}
+
+func (e *errQueueShed) Error() string { return e.reason }
+
+// admit blocks until available reports a usable credential and the request is the head of the
+// queue for key, or until maxWait elapses. available returns whether a credential is usable and,
+// if not, how long until one recovers.
+func (q *admissionQueue) admit(ctx context.Context, key string, priority int, maxWait time.Duration, maxQueued int, available func() (bool, time.Duration)) error {
+	q.mu.Lock()
+	if len(q.waiting[key]) == 0 {
+		ok, retryIn := available()
+		if ok {
+			q.mu.Unlock()
+			return nil
+		}
+		if maxWait <= 0 || retryIn > maxWait {
+			q.mu.Unlock()
+			return &errQueueShed{reason: "all credentials for this model are rate limited", retryAfter: retryIn}
+		}
+	}
+	if q.total >= maxQueued {
+		q.mu.Unlock()
+		_, retryIn := available()
+		return &errQueueShed{reason: "request queue is full", retryAfter: retryIn}
+	}
+	q.seq++
+	w := &admissionWaiter{priority: priority, seq: q.seq, wake: make(chan struct{}, 1)}
+	q.insertLocked(key, w)
+	q.mu.Unlock()
+	queuedRequests.Add(1)
+	defer queuedRequests.Add(-1)
+
+	deadline := time.NewTimer(maxWait)
+	defer deadline.Stop()
+	for {
+		_, retryIn := available()
+		poll := queuePollInterval
+		if retryIn > 0 && retryIn < poll {
+			poll = retryIn
+		}
+		if poll < minQueuePoll {
+			poll = minQueuePoll
+		}
+		timer := time.NewTimer(poll)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			q.remove(key, w)
+			return ctx.Err()
+		case <-deadline.C:
+			timer.Stop()
+			q.remove(key, w)
+			_, retryIn = available()
+			return &errQueueShed{reason: "timed out waiting for a rate-limited credential", retryAfter: retryIn}
+		case <-w.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+		q.mu.Lock()
+		queue := q.waiting[key]
+		if len(queue) > 0 && queue[0] == w {
+			if ok, _ := available(); ok {
+				q.removeLocked(key, w)
+				q.mu.Unlock()
+				return nil
+			}
+		}
+		q.mu.Unlock()
+	}
+}
+
+func (q *admissionQueue) insertLocked(key string, w *admissionWaiter) {
+	if q.waiting == nil {
+		q.waiting = make(map[string][]*admissionWaiter)
+	}
+	queue := append(q.waiting[key], w)
+	sort.SliceStable(queue, func(i, j int) bool {
+		if queue[i].priority != queue[j].priority {
+			return queue[i].priority > queue[j].priority
+		}
+		return queue[i].seq < queue[j].seq
+	})
+	q.waiting[key] = queue
+	q.total++
+	notify(queue[0])
+}
+
+func (q *admissionQueue) remove(key string, w *admissionWaiter) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.removeLocked(key, w)
+}
+
+// removeLocked drops w from the queue and wakes the new head so the queue keeps draining.
+func (q *admissionQueue) removeLocked(key string, w *admissionWaiter) {
+	queue := q.waiting[key]
+	for i, candidate := range queue {
+		if candidate != w {
+			continue
+		}
+		queue = append(queue[:i], queue[i+1:]...)
+		q.total--
+		break
+	}
+	if len(queue) == 0 {
+		delete(q.waiting, key)
+		return
+	}
+	q.waiting[key] = queue
+	notify(queue[0])
+}
+
+func notify(w *admissionWaiter) {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// queueSettings resolves the priority and maximum wait of an API key.
+func queueSettings(cfg config.RequestQueueConfig, apiKey string) (int, time.Duration) {
+	maxWait := parseQueueWait(cfg.MaxWait, defaultQueueMaxWait)
+	for _, entry := range cfg.APIKeys {
+		if entry.APIKey == apiKey {
+			return entry.Priority, parseQueueWait(entry.MaxWait, maxWait)
+		}
+	}
+	return 0, maxWait
+}
+
+func parseQueueWait(raw string, fallback time.Duration) time.Duration {
+	if raw = strings.TrimSpace(raw); raw == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil || parsed < 0 {
+		return fallback
+	}
+	return parsed
+}
+
+// admitRequest applies request-queue admission control. While every credential for the model is
+// rate limited the request waits its turn by API key priority, or is shed with 429.
+func (h *BaseAPIHandler) admitRequest(ctx context.Context, providers []string, modelName string) *interfaces.ErrorMessage {
+	if h == nil || h.Cfg == nil || !h.Cfg.RequestQueue.Enabled || h.AuthManager == nil || ctx == nil {
+		return nil
+	}
+	queueCfg := h.Cfg.RequestQueue
+	apiKey := ""
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok {
+		apiKey = callerAPIKey(ginCtx)
+	}
+	priority, maxWait := queueSettings(queueCfg, apiKey)
+	maxQueued := queueCfg.MaxQueued
+	if maxQueued <= 0 {
+		maxQueued = defaultQueueMaxQueued
+	}
+	sorted := append([]string(nil), providers...)
+	sort.Strings(sorted)
+	key := strings.Join(sorted, ",") + "|" + modelName
+
+	err := defaultAdmissionQueue.admit(ctx, key, priority, maxWait, maxQueued, func() (bool, time.Duration) {
+		return h.AuthManager.ModelAvailability(providers, modelName)
+	})
+	if err == nil {
+		return nil
+	}
+	shed, ok := err.(*errQueueShed)
+	if !ok {
+		return &interfaces.ErrorMessage{StatusCode: http.StatusRequestTimeout, Error: err}
+	}
+	addon := http.Header{}
+	if shed.retryAfter > 0 {
+		addon.Set("Retry-After", strconv.Itoa(int((shed.retryAfter+time.Second-1)/time.Second)))
+	}
+	return &interfaces.ErrorMessage{
+		StatusCode: http.StatusTooManyRequests,
+		Error:      fmt.Errorf("%s, retry later", shed.reason),
+		Addon:      addon,
+	}
+}
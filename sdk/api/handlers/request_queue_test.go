@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestAdmissionQueue_AdmitsHigherPriorityFirst(t *testing.T) {
+	q := &admissionQueue{}
+	var available atomic.Bool
+	check := func() (bool, time.Duration) {
+		if available.Load() {
+			return true, 0
+		}
+		return false, time.Second
+	}
+
+	order := make(chan string, 2)
+	start := func(name string, priority int) {
+		go func() {
+			if err := q.admit(context.Background(), "k", priority, 5*time.Second, 10, check); err != nil {
+				order <- "error: " + err.Error()
+				return
+			}
+			order <- name
+		}()
+	}
+	start("low", -1)
+	waitForQueued(t, q, 1)
+	start("high", 5)
+	waitForQueued(t, q, 2)
+
+	available.Store(true)
+	if first, second := <-order, <-order; first != "high" || second != "low" {
+		t.Fatalf("admission order = %s, %s; want high, low", first, second)
+	}
+	if q.total != 0 || len(q.waiting) != 0 {
+		t.Fatalf("queue not drained: total=%d", q.total)
+	}
+}
+
+func TestAdmissionQueue_Sheds(t *testing.T) {
+	q := &admissionQueue{}
+	unavailable := func() (bool, time.Duration) { return false, 20 * time.Second }
+
+	var shed *errQueueShed
+	if err := q.admit(context.Background(), "k", 0, 0, 10, unavailable); !errors.As(err, &shed) || shed.retryAfter != 20*time.Second {
+		t.Fatalf("expected immediate shed, got %v", err)
+	}
+	// Credentials recover after the wait budget: no point in queueing.
+	if err := q.admit(context.Background(), "k", 0, time.Second, 10, unavailable); !errors.As(err, &shed) {
+		t.Fatalf("expected shed when recovery exceeds max wait, got %v", err)
+	}
+	soon := func() (bool, time.Duration) { return false, 10 * time.Millisecond }
+	if err := q.admit(context.Background(), "k", 0, 50*time.Millisecond, 10, soon); !errors.As(err, &shed) || shed.reason != "timed out waiting for a rate-limited credential" {
+		t.Fatalf("expected timeout shed, got %v", err)
+	}
+	if err := q.admit(context.Background(), "k", 0, time.Second, 0, soon); !errors.As(err, &shed) || shed.reason != "request queue is full" {
+		t.Fatalf("expected full-queue shed, got %v", err)
+	}
+}
+
+func TestAdmitRequest_ShedsWhileCredentialsCoolDown(t *testing.T) {
+	manager := coreauth.NewManager(nil, nil, nil)
+	auth := &coreauth.Auth{
+		ID:       "queue-auth",
+		Provider: "codex",
+		Status:   coreauth.StatusActive,
+		ModelStates: map[string]*coreauth.ModelState{"queue-model": {
+			Unavailable:    true,
+			NextRetryAfter: time.Now().Add(90 * time.Second),
+			Quota:          coreauth.QuotaState{Exceeded: true},
+		}},
+	}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "queue-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{RequestQueue: sdkconfig.RequestQueueConfig{
+		Enabled: true,
+		APIKeys: []sdkconfig.APIKeyPriority{{APIKey: "batch", Priority: -10, MaxWait: "0s"}},
+	}}, manager)
+
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("apiKey", "batch")
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+
+	errMsg := handler.admitRequest(ctx, []string{"codex"}, "queue-model")
+	if errMsg == nil || errMsg.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %+v", errMsg)
+	}
+	if got := errMsg.Addon.Get("Retry-After"); got != "90" {
+		t.Fatalf("Retry-After = %q, want 90", got)
+	}
+	if errMsg = handler.admitRequest(ctx, []string{"codex"}, "other-model"); errMsg != nil {
+		t.Fatalf("expected models without credentials to pass through, got %+v", errMsg)
+	}
+}
+
+func waitForQueued(t *testing.T, q *admissionQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		q.mu.Lock()
+		total := q.total
+		q.mu.Unlock()
+		if total == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d queued requests", n)
+}
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// requestSchemaError describes the first structural problem found in a request body, with the
+// JSON path of the offending value so clients can locate it.
+type requestSchemaError struct {
+	path    string
+	message string
+}
+
+func (e *requestSchemaError) Error() string {
+	if e.path == "" {
+		return "invalid request: " + e.message
+	}
+	return fmt.Sprintf("invalid request: %s: %s", e.path, e.message)
+}
+
+func schemaErrorf(path, format string, args ...any) *requestSchemaError {
+	return &requestSchemaError{path: path, message: fmt.Sprintf(format, args...)}
+}
+
+// validateRequestSchema checks the required fields, value types and obviously invalid
+// combinations of an inbound request in handlerType's format. It catches malformed bodies before
+// translation, where they would otherwise surface as confusing upstream errors. Formats without
+// a validator are accepted unchanged.
+func validateRequestSchema(handlerType string, rawJSON []byte) error {
+	var validate func(gjson.Result) *requestSchemaError
+	switch handlerType {
+	case "openai":
+		validate = validateOpenAIChatRequest
+	case "openai-response":
+		validate = validateOpenAIResponsesRequest
+	case "claude":
+		validate = validateClaudeRequest
+	case "gemini":
+		validate = validateGeminiRequest
+	default:
+		return nil
+	}
+	if !gjson.ValidBytes(rawJSON) {
+		return &requestSchemaError{message: "body is not valid JSON"}
+	}
+	root := gjson.ParseBytes(rawJSON)
+	if !root.IsObject() {
+		return &requestSchemaError{message: "body must be a JSON object"}
+	}
+	if err := validate(root); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validateOpenAIChatRequest(root gjson.Result) *requestSchemaError {
+	if err := requireString(root, "model"); err != nil {
+		return err
+	}
+	if err := checkCommonSampling(root, "max_tokens", "max_completion_tokens"); err != nil {
+		return err
+	}
+	messages := root.Get("messages")
+	if !messages.IsArray() {
+		return missingOrType(messages, "messages", "an array")
+	}
+	if len(messages.Array()) == 0 {
+		return schemaErrorf("messages", "must contain at least one message")
+	}
+	for i, msg := range messages.Array() {
+		path := fmt.Sprintf("messages[%d]", i)
+		if !msg.IsObject() {
+			return schemaErrorf(path, "must be an object")
+		}
+		role := msg.Get("role")
+		if role.Type != gjson.String {
+			return missingOrType(role, path+".role", "a string")
+		}
+		content := msg.Get("content")
+		if content.Exists() && content.Type != gjson.String && content.Type != gjson.Null && !content.IsArray() {
+			return schemaErrorf(path+".content", "must be a string or an array of content parts")
+		}
+		switch role.String() {
+		case "system", "developer", "user":
+			if !content.Exists() || content.Type == gjson.Null {
+				return schemaErrorf(path+".content", "is required for %s messages", role.String())
+			}
+		case "assistant":
+			toolCalls := msg.Get("tool_calls")
+			if toolCalls.Exists() && toolCalls.Type != gjson.Null {
+				if !toolCalls.IsArray() {
+					return schemaErrorf(path+".tool_calls", "must be an array")
+				}
+				for j, call := range toolCalls.Array() {
+					callPath := fmt.Sprintf("%s.tool_calls[%d]", path, j)
+					if err := requireString(call, "id"); err != nil {
+						return prefixed(callPath, err)
+					}
+					if err := requireString(call, "function.name"); err != nil {
+						return prefixed(callPath, err)
+					}
+				}
+			}
+		case "tool":
+			if err := requireString(msg, "tool_call_id"); err != nil {
+				return &requestSchemaError{path: path + ".tool_call_id", message: "is required for tool messages; set it to the id of the assistant tool call this message answers"}
+			}
+		case "function":
+			if err := requireString(msg, "name"); err != nil {
+				return prefixed(path, err)
+			}
+		default:
+			return schemaErrorf(path+".role", "unknown role %q; expected system, developer, user, assistant or tool", role.String())
+		}
+	}
+	return validateOpenAITools(root.Get("tools"), "function.name")
+}
+
+func validateOpenAIResponsesRequest(root gjson.Result) *requestSchemaError {
+	if err := requireString(root, "model"); err != nil {
+		return err
+	}
+	if err := checkCommonSampling(root, "max_output_tokens"); err != nil {
+		return err
+	}
+	if instructions := root.Get("instructions"); instructions.Exists() && instructions.Type != gjson.String && instructions.Type != gjson.Null {
+		return schemaErrorf("instructions", "must be a string")
+	}
+	input := root.Get("input")
+	if input.Exists() && input.Type != gjson.String && !input.IsArray() {
+		return schemaErrorf("input", "must be a string or an array of input items")
+	}
+	if input.IsArray() {
+		for i, item := range input.Array() {
+			path := fmt.Sprintf("input[%d]", i)
+			if !item.IsObject() {
+				return schemaErrorf(path, "must be an object")
+			}
+			switch item.Get("type").String() {
+			case "function_call":
+				if err := requireString(item, "call_id"); err != nil {
+					return prefixed(path, err)
+				}
+				if err := requireString(item, "name"); err != nil {
+					return prefixed(path, err)
+				}
+			case "function_call_output":
+				if err := requireString(item, "call_id"); err != nil {
+					return &requestSchemaError{path: path + ".call_id", message: "is required for function_call_output items; set it to the call_id of the function call this output answers"}
+				}
+			}
+		}
+	}
+	return validateOpenAITools(root.Get("tools"), "")
+}
+
+// validateOpenAITools checks that function tools are named. namePath is where Chat Completions
+// nests the name; Responses tools carry it at the top level.
+func validateOpenAITools(tools gjson.Result, namePath string) *requestSchemaError {
+	if !tools.Exists() || tools.Type == gjson.Null {
+		return nil
+	}
+	if !tools.IsArray() {
+		return schemaErrorf("tools", "must be an array")
+	}
+	for i, tool := range tools.Array() {
+		path := fmt.Sprintf("tools[%d]", i)
+		if !tool.IsObject() {
+			return schemaErrorf(path, "must be an object")
+		}
+		if tool.Get("type").String() != "function" {
+			continue
+		}
+		field := namePath
+		if field == "" || !tool.Get("function").Exists() {
+			field = "name"
+		}
+		if err := requireString(tool, field); err != nil {
+			return prefixed(path, err)
+		}
+	}
+	return nil
+}
+
+func validateClaudeRequest(root gjson.Result) *requestSchemaError {
+	if err := requireString(root, "model"); err != nil {
+		return err
+	}
+	if err := checkCommonSampling(root, "max_tokens"); err != nil {
+		return err
+	}
+	if system := root.Get("system"); system.Exists() && system.Type != gjson.String && system.Type != gjson.Null && !system.IsArray() {
+		return schemaErrorf("system", "must be a string or an array of text blocks")
+	}
+	messages := root.Get("messages")
+	if !messages.IsArray() {
+		return missingOrType(messages, "messages", "an array")
+	}
+	for i, msg := range messages.Array() {
+		path := fmt.Sprintf("messages[%d]", i)
+		if !msg.IsObject() {
+			return schemaErrorf(path, "must be an object")
+		}
+		if err := requireString(msg, "role"); err != nil {
+			return prefixed(path, err)
+		}
+		role := msg.Get("role").String()
+		content := msg.Get("content")
+		if content.Type == gjson.String {
+			continue
+		}
+		if !content.IsArray() {
+			return missingOrType(content, path+".content", "a string or an array of content blocks")
+		}
+		for j, block := range content.Array() {
+			blockPath := fmt.Sprintf("%s.content[%d]", path, j)
+			if err := requireString(block, "type"); err != nil {
+				return prefixed(blockPath, err)
+			}
+			switch block.Get("type").String() {
+			case "tool_use":
+				if role != "assistant" {
+					return schemaErrorf(blockPath, "tool_use blocks are only valid in assistant messages")
+				}
+				if err := requireString(block, "id"); err != nil {
+					return prefixed(blockPath, err)
+				}
+				if err := requireString(block, "name"); err != nil {
+					return prefixed(blockPath, err)
+				}
+			case "tool_result":
+				if role != "user" {
+					return schemaErrorf(blockPath, "tool_result blocks are only valid in user messages")
+				}
+				if err := requireString(block, "tool_use_id"); err != nil {
+					return &requestSchemaError{path: blockPath + ".tool_use_id", message: "is required; set it to the id of the tool_use block this result answers"}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateGeminiRequest(root gjson.Result) *requestSchemaError {
+	if nested := root.Get("generateContentRequest"); nested.Exists() {
+		// countTokens may wrap a full generateContent request.
+		if !nested.IsObject() {
+			return schemaErrorf("generateContentRequest", "must be an object")
+		}
+		return prefixed("generateContentRequest", validateGeminiRequest(nested))
+	}
+	contents := root.Get("contents")
+	if !contents.IsArray() {
+		return missingOrType(contents, "contents", "an array")
+	}
+	for i, content := range contents.Array() {
+		path := fmt.Sprintf("contents[%d]", i)
+		if !content.IsObject() {
+			return schemaErrorf(path, "must be an object")
+		}
+		if role := content.Get("role"); role.Exists() && role.String() != "user" && role.String() != "model" && role.String() != "function" {
+			return schemaErrorf(path+".role", "must be \"user\" or \"model\"")
+		}
+		parts := content.Get("parts")
+		if !parts.IsArray() {
+			return missingOrType(parts, path+".parts", "an array")
+		}
+		for j, part := range parts.Array() {
+			partPath := fmt.Sprintf("%s.parts[%d]", path, j)
+			if !part.IsObject() {
+				return schemaErrorf(partPath, "must be an object")
+			}
+			if call := part.Get("functionCall"); call.Exists() {
+				if err := requireString(call, "name"); err != nil {
+					return prefixed(partPath+".functionCall", err)
+				}
+			}
+			if response := part.Get("functionResponse"); response.Exists() {
+				if err := requireString(response, "name"); err != nil {
+					return prefixed(partPath+".functionResponse", err)
+				}
+			}
+		}
+	}
+	if generation := root.Get("generationConfig"); generation.Exists() && !generation.IsObject() {
+		return schemaErrorf("generationConfig", "must be an object")
+	}
+	return nil
+}
+
+// checkCommonSampling checks the types of the stream flag, the sampling parameters and the
+// given token-limit fields.
+func checkCommonSampling(root gjson.Result, tokenFields ...string) *requestSchemaError {
+	if stream := root.Get("stream"); stream.Exists() && stream.Type != gjson.True && stream.Type != gjson.False && stream.Type != gjson.Null {
+		return schemaErrorf("stream", "must be a boolean")
+	}
+	for _, field := range []string{"temperature", "top_p"} {
+		if value := root.Get(field); value.Exists() && value.Type != gjson.Number && value.Type != gjson.Null {
+			return schemaErrorf(field, "must be a number")
+		}
+	}
+	for _, field := range tokenFields {
+		value := root.Get(field)
+		if !value.Exists() || value.Type == gjson.Null {
+			continue
+		}
+		if value.Type != gjson.Number || value.Num != float64(int64(value.Num)) {
+			return schemaErrorf(field, "must be an integer")
+		}
+		if value.Int() <= 0 {
+			return schemaErrorf(field, "must be greater than 0")
+		}
+	}
+	return nil
+}
+
+// requireString checks that path holds a non-empty string.
+func requireString(parent gjson.Result, path string) *requestSchemaError {
+	value := parent.Get(path)
+	if value.Type != gjson.String {
+		return missingOrType(value, path, "a string")
+	}
+	if strings.TrimSpace(value.String()) == "" {
+		return schemaErrorf(path, "must not be empty")
+	}
+	return nil
+}
+
+func missingOrType(value gjson.Result, path, want string) *requestSchemaError {
+	if !value.Exists() || value.Type == gjson.Null {
+		return schemaErrorf(path, "is required")
+	}
+	return schemaErrorf(path, "must be %s", want)
+}
+
+// prefixed nests err's path under parent.
+func prefixed(parent string, err *requestSchemaError) *requestSchemaError {
+	if err == nil {
+		return nil
+	}
+	if err.path == "" {
+		err.path = parent
+	} else {
+		err.path = parent + "." + err.path
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRequestSchema(t *testing.T) {
+	cases := []struct {
+		name        string
+		handlerType string
+		body        string
+		wantErr     string
+	}{
+		{"openai valid", "openai", `{"model":"m","messages":[{"role":"user","content":"hi"},{"role":"assistant","content":null,"tool_calls":[{"id":"c1","type":"function","function":{"name":"f","arguments":"{}"}}]},{"role":"tool","tool_call_id":"c1","content":"ok"}]}`, ""},
+		{"openai not json", "openai", `{"model":`, "body is not valid JSON"},
+		{"openai missing messages", "openai", `{"model":"m"}`, "messages: is required"},
+		{"openai tool without id", "openai", `{"model":"m","messages":[{"role":"user","content":"hi"},{"role":"tool","content":"ok"}]}`, "messages[1].tool_call_id: is required for tool messages"},
+		{"openai bad role", "openai", `{"model":"m","messages":[{"role":"robot","content":"hi"}]}`, `messages[0].role: unknown role "robot"`},
+		{"openai bad max_tokens", "openai", `{"model":"m","max_tokens":"10","messages":[{"role":"user","content":"hi"}]}`, "max_tokens: must be an integer"},
+		{"openai unnamed tool", "openai", `{"model":"m","messages":[{"role":"user","content":"hi"}],"tools":[{"type":"function","function":{}}]}`, "tools[0].function.name: is required"},
+		{"responses valid", "openai-response", `{"model":"m","input":[{"type":"function_call_output","call_id":"c1","output":"x"}],"tools":[{"type":"function","name":"f"}]}`, ""},
+		{"responses output without call_id", "openai-response", `{"model":"m","input":[{"type":"function_call_output","output":"x"}]}`, "input[0].call_id: is required"},
+		{"claude valid", "claude", `{"model":"m","max_tokens":10,"messages":[{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"x"}]}]}`, ""},
+		{"claude tool_result without id", "claude", `{"model":"m","messages":[{"role":"user","content":[{"type":"tool_result","content":"x"}]}]}`, "messages[0].content[0].tool_use_id: is required"},
+		{"claude tool_use from user", "claude", `{"model":"m","messages":[{"role":"user","content":[{"type":"tool_use","id":"t1","name":"f","input":{}}]}]}`, "only valid in assistant messages"},
+		{"gemini valid", "gemini", `{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`, ""},
+		{"gemini count wrapper", "gemini", `{"generateContentRequest":{"contents":[{"parts":[{}]}]}}`, ""},
+		{"gemini missing parts", "gemini", `{"contents":[{"role":"user"}]}`, "contents[0].parts: is required"},
+		{"unvalidated format", "gemini-cli", `[]`, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateRequestSchema(tc.handlerType, []byte(tc.body))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("error = %v, want containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
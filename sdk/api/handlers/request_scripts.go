@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/scripthook"
+	"golang.org/x/net/context"
+)
+
+// applyInboundScripts runs the inbound request-scripts on the client request body.
+func (h *BaseAPIHandler) applyInboundScripts(ctx context.Context, handlerType, modelName string, rawJSON []byte) []byte {
+	if h == nil || h.Cfg == nil || len(h.Cfg.RequestScripts) == 0 {
+		return rawJSON
+	}
+	return scripthook.Apply(ctx, h.Cfg.RequestScripts, scripthook.StageInbound, modelName, handlerType, rawJSON)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	"github.com/tidwall/gjson"
+)
+
+// knownRequestFields lists the top-level request fields accepted per inbound API format when
+// strict-request-fields is enabled. Formats without an entry are not validated.
+var knownRequestFields = map[string]map[string]struct{}{
+	"openai": fieldSet(
+		"model", "messages", "stream", "stream_options", "max_tokens", "max_completion_tokens",
+		"temperature", "top_p", "top_k", "n", "stop", "presence_penalty", "frequency_penalty",
+		"logit_bias", "logprobs", "top_logprobs", "user", "tools", "tool_choice", "parallel_tool_calls",
+		"functions", "function_call", "response_format", "seed", "reasoning_effort", "modalities",
+		"audio", "metadata", "store", "service_tier", "prediction", "web_search_options", "verbosity",
+		"safety_identifier", "prompt_cache_key", "image_config", "safety_settings",
+	),
+	"openai-response": fieldSet(
+		"model", "input", "instructions", "stream", "stream_options", "max_output_tokens",
+		"max_tool_calls", "temperature", "top_p", "top_logprobs", "tools", "tool_choice",
+		"parallel_tool_calls", "reasoning", "text", "include", "metadata", "store", "service_tier",
+		"previous_response_id", "conversation", "prompt", "prompt_cache_key", "safety_identifier",
+		"truncation", "background", "user", "safety_settings",
+	),
+	"claude": fieldSet(
+		"model", "messages", "system", "max_tokens", "stream", "temperature", "top_p", "top_k",
+		"stop_sequences", "tools", "tool_choice", "thinking", "metadata", "service_tier",
+		"container", "mcp_servers", "context_management", "safety_settings",
+	),
+	"gemini": fieldSet(
+		"contents", "systemInstruction", "system_instruction", "tools", "toolConfig", "tool_config",
+		"safetySettings", "safety_settings", "generationConfig", "generation_config",
+		"cachedContent", "cached_content", "labels",
+	),
+}
+
+// knownStreamOptions lists the OpenAI stream_options keys the proxy honours.
+var knownStreamOptions = fieldSet("include_usage")
+
+func fieldSet(fields ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		set[field] = struct{}{}
+	}
+	return set
+}
+
+// unsupportedRequestFields returns the offending fields of rawJSON for the given inbound format,
+// sorted for stable error messages. It reports unknown top-level fields, unknown stream_options
+// keys, and stream_options sent on a non-streaming request.
+func unsupportedRequestFields(handlerType string, rawJSON []byte, stream bool) []string {
+	known, ok := knownRequestFields[handlerType]
+	if !ok {
+		return nil
+	}
+	root := gjson.ParseBytes(rawJSON)
+	if !root.IsObject() {
+		return nil
+	}
+	var offending []string
+	root.ForEach(func(key, _ gjson.Result) bool {
+		if _, found := known[key.String()]; !found {
+			offending = append(offending, key.String())
+		}
+		return true
+	})
+	if streamOptions := root.Get("stream_options"); streamOptions.Exists() && (handlerType == "openai" || handlerType == "openai-response") {
+		if !stream {
+			offending = append(offending, "stream_options (requires stream=true)")
+		}
+		streamOptions.ForEach(func(key, _ gjson.Result) bool {
+			if _, found := knownStreamOptions[key.String()]; !found {
+				offending = append(offending, "stream_options."+key.String())
+			}
+			return true
+		})
+	}
+	sort.Strings(offending)
+	return offending
+}
+
+// validateRequestFields rejects requests carrying unsupported fields when strict-request-fields
+// is enabled. It returns nil when validation is disabled or the request is clean.
+func (h *BaseAPIHandler) validateRequestFields(handlerType string, rawJSON []byte, stream bool) *interfaces.ErrorMessage {
+	if h == nil || h.Cfg == nil || !h.Cfg.StrictRequestFields {
+		return nil
+	}
+	offending := unsupportedRequestFields(handlerType, rawJSON, stream)
+	if len(offending) == 0 {
+		return nil
+	}
+	return &interfaces.ErrorMessage{
+		StatusCode: http.StatusBadRequest,
+		Error:      fmt.Errorf("unsupported request fields: %s", strings.Join(offending, ", ")),
+	}
+}
+
+// validateRequest runs the schema checks and, when enabled, the strict field checks on an
+// inbound request before it is translated.
+func (h *BaseAPIHandler) validateRequest(handlerType string, rawJSON []byte, stream bool) *interfaces.ErrorMessage {
+	if err := validateRequestSchema(handlerType, rawJSON); err != nil {
+		return &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: err}
+	}
+	return h.validateRequestFields(handlerType, rawJSON, stream)
+}
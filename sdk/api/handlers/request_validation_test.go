@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestUnsupportedRequestFields(t *testing.T) {
+	raw := []byte(`{"model":"gpt-5","messages":[],"stream_options":{"include_usage":true,"include_obfuscation":false},"foo":1,"extra_body":{}}`)
+	got := unsupportedRequestFields("openai", raw, false)
+	want := []string{"extra_body", "foo", "stream_options (requires stream=true)", "stream_options.include_obfuscation"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unsupportedRequestFields = %v, want %v", got, want)
+	}
+
+	if got = unsupportedRequestFields("claude", []byte(`{"model":"m","messages":[],"thinking":{"type":"enabled"}}`), true); len(got) != 0 {
+		t.Fatalf("expected clean claude request, got %v", got)
+	}
+	if got = unsupportedRequestFields("gemini-cli", raw, false); len(got) != 0 {
+		t.Fatalf("formats without a field list must not be validated, got %v", got)
+	}
+}
+
+func TestValidateRequestFieldsDisabledByDefault(t *testing.T) {
+	raw := []byte(`{"model":"gpt-5","foo":1}`)
+	h := &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{}}
+	if errMsg := h.validateRequestFields("openai", raw, false); errMsg != nil {
+		t.Fatalf("expected no validation when disabled, got %v", errMsg.Error)
+	}
+	h.Cfg.StrictRequestFields = true
+	errMsg := h.validateRequestFields("openai", raw, false)
+	if errMsg == nil || errMsg.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 when strict-request-fields is enabled, got %+v", errMsg)
+	}
+}
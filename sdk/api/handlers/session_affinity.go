@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	"github.com/tidwall/gjson"
+)
+
+// sessionIDHeaders are the client headers accepted as explicit conversation identifiers.
+var sessionIDHeaders = []string{"X-Session-Id", "Session_id", "X-Conversation-Id"}
+
+// sessionIDFields are request body fields clients use to identify a conversation or end user.
+var sessionIDFields = []string{"metadata.user_id", "prompt_cache_key", "session_id", "user", "conversation_id"}
+
+// sessionKey returns the conversation key for sticky routing, or "" when session affinity is off
+// so requests do not pay for canonicalizing and hashing their first message.
+func (h *BaseAPIHandler) sessionKey(ctx context.Context, rawJSON []byte) string {
+	if h == nil || !h.AuthManager.SessionAffinityEnabled() {
+		return ""
+	}
+	return conversationSessionKey(ctx, rawJSON)
+}
+
+// conversationSessionKey derives a stable identifier for the conversation a request belongs to,
+// used to keep later turns on the same credential. Explicit session identifiers from headers or
+// the body are preferred; otherwise the first user message identifies the conversation. The
+// client API key is mixed in so different clients never share a binding. An empty string means
+// no identifier could be derived.
+func conversationSessionKey(ctx context.Context, rawJSON []byte) string {
+	apiKey := ""
+	source := ""
+	if ctx != nil {
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+			apiKey = ginCtx.GetString("apiKey")
+			if ginCtx.Request != nil {
+				for _, header := range sessionIDHeaders {
+					if value := strings.TrimSpace(ginCtx.GetHeader(header)); value != "" {
+						source = "id:" + value
+						break
+					}
+				}
+			}
+		}
+	}
+	if source == "" && len(rawJSON) > 0 {
+		for _, field := range sessionIDFields {
+			if value := gjson.GetBytes(rawJSON, field); value.Type == gjson.String && strings.TrimSpace(value.String()) != "" {
+				source = "id:" + strings.TrimSpace(value.String())
+				break
+			}
+		}
+	}
+	if source == "" {
+		if first := firstUserMessage(rawJSON); first != "" {
+			// Canonicalize so re-serialized history from the client still maps to the same key.
+			if canonical, err := util.CanonicalJSON([]byte(first)); err == nil {
+				first = string(canonical)
+			}
+			source = "msg:" + first
+		}
+	}
+	if source == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(apiKey + "\x00" + source))
+	return hex.EncodeToString(sum[:])
+}
+
+// firstUserMessage returns the raw JSON of the first user turn in an OpenAI Chat Completions,
+// Claude Messages, OpenAI Responses or Gemini request.
+func firstUserMessage(rawJSON []byte) string {
+	if len(rawJSON) == 0 {
+		return ""
+	}
+	for _, path := range []string{"messages", "contents", "request.contents", "input"} {
+		turns := gjson.GetBytes(rawJSON, path)
+		if turns.Type == gjson.String && path == "input" {
+			return turns.String()
+		}
+		if !turns.IsArray() {
+			continue
+		}
+		first := ""
+		turns.ForEach(func(_, turn gjson.Result) bool {
+			if turn.Get("role").String() != "user" {
+				return true
+			}
+			content := turn.Get("content")
+			if !content.Exists() {
+				content = turn.Get("parts")
+			}
+			first = content.Raw
+			return false
+		})
+		if first != "" {
+			return first
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConversationSessionKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newCtx := func(apiKey, sessionHeader string) context.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		if sessionHeader != "" {
+			c.Request.Header.Set("X-Session-Id", sessionHeader)
+		}
+		c.Set("apiKey", apiKey)
+		return context.WithValue(context.Background(), "gin", c)
+	}
+
+	turn1 := []byte(`{"messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hello"}]}`)
+	turn2 := []byte(`{"messages":[{"role":"system","content":"be brief"},{"role":"user","content":"hello"},{"role":"assistant","content":"hi"},{"role":"user","content":"more"}]}`)
+	other := []byte(`{"messages":[{"role":"user","content":"different"}]}`)
+
+	k1 := conversationSessionKey(newCtx("key-a", ""), turn1)
+	if k1 == "" {
+		t.Fatalf("expected session key from first user message")
+	}
+	if k2 := conversationSessionKey(newCtx("key-a", ""), turn2); k2 != k1 {
+		t.Fatalf("later turn produced a different key")
+	}
+	if k := conversationSessionKey(newCtx("key-a", ""), other); k == k1 {
+		t.Fatalf("different conversation produced the same key")
+	}
+	if k := conversationSessionKey(newCtx("key-b", ""), turn1); k == k1 {
+		t.Fatalf("different API keys must not share a session")
+	}
+	if a, b := conversationSessionKey(newCtx("key-a", "s1"), turn1), conversationSessionKey(newCtx("key-a", "s1"), other); a != b || a == k1 {
+		t.Fatalf("explicit session header should override message hashing")
+	}
+
+	claude := []byte(`{"metadata":{"user_id":"user_abc_session_1"},"messages":[{"role":"user","content":"x"}]}`)
+	claude2 := []byte(`{"metadata":{"user_id":"user_abc_session_1"},"messages":[{"role":"user","content":"y"}]}`)
+	if conversationSessionKey(newCtx("", ""), claude) != conversationSessionKey(newCtx("", ""), claude2) {
+		t.Fatalf("metadata.user_id should identify the conversation")
+	}
+
+	gemini := []byte(`{"contents":[{"role":"user","parts":[{"text":"hello"}]},{"role":"model","parts":[{"text":"hi"}]}]}`)
+	if conversationSessionKey(nil, gemini) == "" {
+		t.Fatalf("expected session key from gemini contents")
+	}
+	if conversationSessionKey(nil, []byte(`{"model":"x"}`)) != "" {
+		t.Fatalf("expected no session key without messages")
+	}
+}
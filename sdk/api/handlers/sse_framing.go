@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bytes"
+
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+	"github.com/tidwall/gjson"
+)
+
+// sseDoneSentinel terminates OpenAI Chat Completions streams.
+var sseDoneSentinel = []byte("[DONE]")
+
+// SSEFramer normalises the chunks of one streaming response into spec-compliant server-sent
+// events for the client protocol. Upstream chunks arrive in several shapes: bare JSON payloads,
+// single SSE lines ("event: x", "data: {...}"), whole events with stray blank lines, and CRLF line
+// endings. The framer reassembles them and emits every event exactly once as
+//
+//	event: <name>\n   (Claude and OpenAI Responses only; the name defaults to the payload's "type")
+//	data: <line>\n    (one per payload line)
+//	\n
+//
+// Upstream comments, id and retry fields are dropped, as is any upstream "[DONE]" sentinel; the
+// OpenAI handlers write their own once the stream ends. Keep-alive comments are written
+// by ForwardStream and do not pass through the framer.
+type SSEFramer struct {
+	namedEvents bool
+
+	event []byte
+	data  [][]byte
+}
+
+// NewSSEFramer returns a framer for a stream served to a client of handlerType.
+func NewSSEFramer(handlerType string) *SSEFramer {
+	return &SSEFramer{
+		namedEvents: handlerType == Claude || handlerType == OpenaiResponse,
+	}
+}
+
+// Frame consumes one upstream chunk and returns the complete events it finishes, which may be
+// none when the chunk only starts an event.
+func (f *SSEFramer) Frame(chunk []byte) []byte {
+	if f == nil {
+		return chunk
+	}
+	chunk = bytes.ReplaceAll(chunk, []byte("\r\n"), []byte("\n"))
+	chunk = bytes.ReplaceAll(chunk, []byte("\r"), []byte("\n"))
+	trimmed := bytes.TrimSpace(chunk)
+	if len(trimmed) == 0 {
+		return f.flushIfComplete(nil, true)
+	}
+
+	var out []byte
+	if !isSSEField(trimmed) {
+		// A bare payload is one complete event.
+		out = f.Flush()
+		for _, line := range bytes.Split(trimmed, []byte("\n")) {
+			f.data = append(f.data, line)
+		}
+		return append(out, f.dispatch()...)
+	}
+
+	// The line terminator of the final line is not a blank line.
+	chunk = bytes.TrimSuffix(chunk, []byte("\n"))
+	for _, line := range bytes.Split(chunk, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			out = append(out, f.dispatch()...)
+			continue
+		}
+		name, value := splitSSEField(line)
+		switch name {
+		case "event":
+			if len(f.data) > 0 {
+				// A new event starts before the previous one was terminated.
+				out = append(out, f.dispatch()...)
+			}
+			f.event = append(f.event[:0], value...)
+		case "data":
+			f.data = append(f.data, append([]byte(nil), value...))
+		}
+	}
+	return f.flushIfComplete(out, false)
+}
+
+// flushIfComplete dispatches a pending event whose payload is already complete JSON, since
+// upstreams that send one SSE line per chunk rarely follow it with a separate blank line chunk.
+func (f *SSEFramer) flushIfComplete(out []byte, force bool) []byte {
+	if len(f.data) == 0 {
+		return out
+	}
+	payload := bytes.Join(f.data, []byte("\n"))
+	if force || gjson.ValidBytes(payload) || bytes.Equal(bytes.TrimSpace(payload), sseDoneSentinel) {
+		out = append(out, f.dispatch()...)
+	}
+	return out
+}
+
+// Flush returns any event still pending at the end of the stream.
+func (f *SSEFramer) Flush() []byte {
+	if f == nil {
+		return nil
+	}
+	return f.dispatch()
+}
+
+func (f *SSEFramer) dispatch() []byte {
+	if len(f.data) == 0 {
+		f.event = f.event[:0]
+		return nil
+	}
+	event, data := f.event, f.data
+	f.event, f.data = nil, nil
+
+	payload := bytes.Join(data, []byte("\n"))
+	if bytes.Equal(bytes.TrimSpace(payload), sseDoneSentinel) {
+		return nil
+	}
+	var out []byte
+	if f.namedEvents {
+		if len(event) == 0 {
+			event = []byte(gjson.GetBytes(payload, "type").String())
+		}
+		if len(event) > 0 {
+			out = append(out, "event: "...)
+			out = append(out, event...)
+			out = append(out, '\n')
+		}
+	}
+	for _, line := range data {
+		out = append(out, "data: "...)
+		out = append(out, line...)
+		out = append(out, '\n')
+	}
+	return append(out, '\n')
+}
+
+// isSSEField reports whether chunk starts with an SSE field or comment rather than a bare payload.
+func isSSEField(chunk []byte) bool {
+	if chunk[0] == ':' {
+		return true
+	}
+	for _, prefix := range []string{"data:", "event:", "id:", "retry:"} {
+		if bytes.HasPrefix(chunk, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSSEField parses one SSE line per the spec: the value follows the first colon, minus a
+// single leading space. Comment lines yield an empty field name.
+func splitSSEField(line []byte) (string, []byte) {
+	line = bytes.TrimLeft(line, " \t")
+	idx := bytes.IndexByte(line, ':')
+	if idx <= 0 {
+		return "", nil
+	}
+	value := line[idx+1:]
+	if len(value) > 0 && value[0] == ' ' {
+		value = value[1:]
+	}
+	return string(line[:idx]), value
+}
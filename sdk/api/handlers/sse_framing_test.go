@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"testing"
+
+	. "github.com/router-for-me/CLIProxyAPI/v6/internal/constant"
+)
+
+func frameAll(handlerType string, chunks ...string) string {
+	framer := NewSSEFramer(handlerType)
+	var out []byte
+	for _, chunk := range chunks {
+		out = append(out, framer.Frame([]byte(chunk))...)
+	}
+	return string(append(out, framer.Flush()...))
+}
+
+func TestSSEFramerGolden(t *testing.T) {
+	tests := []struct {
+		name        string
+		handlerType string
+		chunks      []string
+		want        string
+	}{
+		{
+			name:        "openai bare json chunks",
+			handlerType: OpenAI,
+			chunks:      []string{`{"id":"1"}`, `{"id":"2"}`},
+			want:        "data: {\"id\":\"1\"}\n\ndata: {\"id\":\"2\"}\n\n",
+		},
+		{
+			name:        "openai drops upstream done sentinel and comments",
+			handlerType: OpenAI,
+			chunks:      []string{": OPENROUTER PROCESSING\n\n", "data: {\"id\":\"1\"}\n\n", "data: [DONE]\n\n", "[DONE]"},
+			want:        "data: {\"id\":\"1\"}\n\n",
+		},
+		{
+			name:        "openai crlf line endings",
+			handlerType: OpenAI,
+			chunks:      []string{"data: {\"id\":\"1\"}\r\n\r\n"},
+			want:        "data: {\"id\":\"1\"}\n\n",
+		},
+		{
+			name:        "claude line per chunk passthrough",
+			handlerType: Claude,
+			chunks: []string{
+				"event: message_start\n", "data: {\"type\":\"message_start\"}\n", "\n",
+				"event: ping\n", "data: {\"type\":\"ping\"}\n", "\n",
+				"event: message_stop\n", "data: {\"type\":\"message_stop\"}\n", "\n",
+			},
+			want: "event: message_start\ndata: {\"type\":\"message_start\"}\n\n" +
+				"event: ping\ndata: {\"type\":\"ping\"}\n\n" +
+				"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+		},
+		{
+			name:        "claude whole events with extra blank lines",
+			handlerType: Claude,
+			chunks: []string{
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\"}\n\n\n",
+				"event: message_delta\ndata: {\"type\":\"message_delta\"}\n\n\n",
+			},
+			want: "event: content_block_delta\ndata: {\"type\":\"content_block_delta\"}\n\n" +
+				"event: message_delta\ndata: {\"type\":\"message_delta\"}\n\n",
+		},
+		{
+			name:        "claude event name taken from payload type",
+			handlerType: Claude,
+			chunks:      []string{"data: {\"type\":\"message_stop\"}"},
+			want:        "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+		},
+		{
+			name:        "claude unterminated event before next event",
+			handlerType: Claude,
+			chunks:      []string{"event: a\ndata: {\"type\":\"a\"", "\nevent: b\ndata: {\"type\":\"b\"}\n\n"},
+			want:        "event: a\ndata: {\"type\":\"a\"\n\nevent: b\ndata: {\"type\":\"b\"}\n\n",
+		},
+		{
+			name:        "responses unterminated lines",
+			handlerType: OpenaiResponse,
+			chunks: []string{
+				"event: response.created", "data: {\"type\":\"response.created\"}", "",
+				"event: response.completed", "data: {\"type\":\"response.completed\"}",
+			},
+			want: "event: response.created\ndata: {\"type\":\"response.created\"}\n\n" +
+				"event: response.completed\ndata: {\"type\":\"response.completed\"}\n\n",
+		},
+		{
+			name:        "responses drops id and retry fields",
+			handlerType: OpenaiResponse,
+			chunks:      []string{"id: 7\nretry: 1000\nevent: response.done\ndata: {\"type\":\"response.done\"}\n\n"},
+			want:        "event: response.done\ndata: {\"type\":\"response.done\"}\n\n",
+		},
+		{
+			name:        "multi-line data is split across data fields",
+			handlerType: Gemini,
+			chunks:      []string{"{\n  \"candidates\": []\n}"},
+			want:        "data: {\ndata:   \"candidates\": []\ndata: }\n\n",
+		},
+		{
+			name:        "pending data flushed at end of stream",
+			handlerType: Gemini,
+			chunks:      []string{"data: partial"},
+			want:        "data: partial\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := frameAll(tt.handlerType, tt.chunks...); got != tt.want {
+				t.Fatalf("framed output mismatch\n got: %q\nwant: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSEFramerNilPassesThrough(t *testing.T) {
+	var framer *SSEFramer
+	if got := string(framer.Frame([]byte("[{\"a\":1}"))); got != "[{\"a\":1}" {
+		t.Fatalf("nil framer = %q", got)
+	}
+	if framer.Flush() != nil {
+		t.Fatal("nil framer flush returned data")
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/tidwall/gjson"
+	"golang.org/x/net/context"
+)
+
+const (
+	// streamProgressInterval throttles StreamProgress events for a single stream.
+	streamProgressInterval = time.Second
+	// streamProgressTokens forces a StreamProgress event once this many estimated tokens were
+	// forwarded since the last one, keeping budget enforcement tight on fast streams.
+	streamProgressTokens = 256
+)
+
+// streamTextKeys lists JSON keys whose string values carry generated output across the
+// OpenAI, Responses, Claude, and Gemini streaming formats.
+var streamTextKeys = map[string]struct{}{
+	"text": {}, "content": {}, "delta": {}, "thinking": {}, "partial_json": {},
+	"arguments": {}, "refusal": {}, "reasoning_content": {},
+}
+
+// streamEventTracker reports start, progress, and end events for a streaming response to
+// usage stream plugins. A nil tracker is valid and reports nothing.
+type streamEventTracker struct {
+	ctx            context.Context
+	handlerType    string
+	event          coreusage.StreamEvent
+	started        bool
+	ended          bool
+	lastProgress   time.Time
+	progressTokens int64
+	textBytes      int64
+	terminal       streamTerminalState
+}
+
+// newStreamEventTracker returns nil when no stream plugin is registered so the streaming
+// path pays no cost in the common case.
+func newStreamEventTracker(ctx context.Context, handlerType, modelName string) *streamEventTracker {
+	if !coreusage.HasStreamPlugins() {
+		return nil
+	}
+	event := coreusage.StreamEvent{Model: modelName, StartedAt: time.Now()}
+	if ctx != nil {
+		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+			event.APIKey = callerAPIKey(ginCtx)
+			if v, exists := ginCtx.Get(usageTagsContextKey); exists {
+				event.Tags, _ = v.(map[string]string)
+			}
+		}
+	}
+	return &streamEventTracker{
+		ctx:         ctx,
+		handlerType: handlerType,
+		event:       event,
+		terminal:    streamTerminalState{model: modelName},
+	}
+}
+
+// chunk records a forwarded chunk, emitting StreamStart before the first one and throttled
+// StreamProgress events afterwards. limit reports that a plugin asked to truncate the stream
+// after this chunk; a non-nil errMsg means a plugin aborted the stream before the chunk is sent.
+func (t *streamEventTracker) chunk(payload []byte) (limit bool, errMsg *interfaces.ErrorMessage) {
+	if t == nil {
+		return false, nil
+	}
+	if !t.started {
+		t.started = true
+		t.lastProgress = time.Now()
+		if err := t.publish(coreusage.StreamStart); err != nil {
+			return false, t.abort(err)
+		}
+	}
+	t.event.Chunks++
+	t.event.Bytes += int64(len(payload))
+	forEachStreamJSON(payload, func(data gjson.Result) {
+		t.terminal.observe(t.handlerType, data)
+		t.textBytes += streamTextBytes(data)
+	})
+	// Roughly four bytes of generated text per token.
+	estimated := t.textBytes / 4
+	t.progressTokens += estimated - t.event.EstimatedOutputTokens
+	t.event.EstimatedOutputTokens = estimated
+	if time.Since(t.lastProgress) < streamProgressInterval && t.progressTokens < streamProgressTokens {
+		return false, nil
+	}
+	t.lastProgress = time.Now()
+	t.progressTokens = 0
+	if err := t.publish(coreusage.StreamProgress); err != nil {
+		if errors.Is(err, coreusage.ErrStreamLimitReached) {
+			t.event.Err = err
+			return true, nil
+		}
+		return false, t.abort(err)
+	}
+	return false, nil
+}
+
+// limitChunk returns the payload that ends a truncated stream in the client's protocol, or nil
+// when the format has no graceful truncation, in which case the stream ends with an error.
+func (t *streamEventTracker) limitChunk() []byte {
+	if t == nil {
+		return nil
+	}
+	return t.terminal.limitChunk(t.handlerType, t.event.EstimatedOutputTokens)
+}
+
+// end emits StreamEnd once. errMsg is the error the stream terminated with, if any.
+func (t *streamEventTracker) end(errMsg *interfaces.ErrorMessage) {
+	if t == nil || t.ended {
+		return
+	}
+	t.ended = true
+	if errMsg != nil {
+		t.event.Failed = true
+		t.event.Err = errMsg.Error
+	} else if t.ctx != nil && t.ctx.Err() != nil {
+		t.event.Failed = true
+		t.event.Err = t.ctx.Err()
+	}
+	_ = t.publish(coreusage.StreamEnd)
+}
+
+func (t *streamEventTracker) publish(eventType coreusage.StreamEventType) error {
+	t.event.Type = eventType
+	t.event.Time = time.Now()
+	return coreusage.PublishStreamEvent(t.ctx, t.event)
+}
+
+// abort converts a plugin error into the error message sent to the client and closes the stream.
+func (t *streamEventTracker) abort(err error) *interfaces.ErrorMessage {
+	status := http.StatusTooManyRequests
+	var se interface{ StatusCode() int }
+	if errors.As(err, &se) && se.StatusCode() > 0 {
+		status = se.StatusCode()
+	}
+	errMsg := &interfaces.ErrorMessage{StatusCode: status, Error: err}
+	t.end(errMsg)
+	return errMsg
+}
+
+// forEachStreamJSON calls fn for every JSON document in a stream chunk, which is either a bare
+// JSON object or SSE lines of which the data: lines carry JSON.
+func forEachStreamJSON(payload []byte, fn func(gjson.Result)) {
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 {
+		return
+	}
+	if trimmed[0] == '{' && gjson.ValidBytes(trimmed) {
+		fn(gjson.ParseBytes(trimmed))
+		return
+	}
+	for _, line := range bytes.Split(trimmed, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		data := bytes.TrimSpace(line[len("data:"):])
+		if len(data) > 0 && data[0] == '{' && gjson.ValidBytes(data) {
+			fn(gjson.ParseBytes(data))
+		}
+	}
+}
+
+// streamTextBytes sums the generated text carried by a stream event. Responses API summary
+// events (".done", "response.completed") repeat earlier deltas and are skipped.
+func streamTextBytes(data gjson.Result) int64 {
+	if eventType := data.Get("type").String(); strings.HasPrefix(eventType, "response.") && !strings.HasSuffix(eventType, ".delta") {
+		return 0
+	}
+	var total int64
+	var walk func(value gjson.Result)
+	walk = func(value gjson.Result) {
+		value.ForEach(func(key, item gjson.Result) bool {
+			if item.Type == gjson.String {
+				if _, ok := streamTextKeys[key.String()]; ok {
+					total += int64(len(item.Str))
+				}
+				return true
+			}
+			if item.IsObject() || item.IsArray() {
+				walk(item)
+			}
+			return true
+		})
+	}
+	walk(data)
+	return total
+}
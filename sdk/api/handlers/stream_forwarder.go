@@ -119,3 +119,23 @@ func (h *BaseAPIHandler) ForwardStream(c *gin.Context, flusher http.Flusher, can
 		}
 	}
 }
+
+// FirstChunkKeepAlive returns a channel that fires once the keep-alive interval passes while a
+// handler is still waiting for the first upstream chunk. It returns nil, which never fires, unless
+// streaming.keepalive-before-first-chunk is enabled together with a keep-alive interval.
+func (h *BaseAPIHandler) FirstChunkKeepAlive() <-chan time.Time {
+	if h == nil || h.Cfg == nil || !h.Cfg.Streaming.KeepAliveBeforeFirstChunk {
+		return nil
+	}
+	interval := StreamingKeepAliveInterval(h.Cfg)
+	if interval <= 0 {
+		return nil
+	}
+	return time.After(interval)
+}
+
+// WriteSSEKeepAlive writes a standard SSE comment heartbeat and flushes it.
+func WriteSSEKeepAlive(c *gin.Context, flusher http.Flusher) {
+	_, _ = c.Writer.Write([]byte(": keep-alive\n\n"))
+	flusher.Flush()
+}
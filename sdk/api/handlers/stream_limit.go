@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// streamTerminalState keeps the identifiers and open blocks seen on a stream so that a
+// truncated stream can be closed with a well-formed final event.
+type streamTerminalState struct {
+	id          string
+	model       string
+	created     int64
+	sequence    int64
+	openBlocks  map[int64]struct{}
+	sawMessages bool
+}
+
+// observe records identifiers and open Claude content blocks from a forwarded event.
+func (s *streamTerminalState) observe(handlerType string, data gjson.Result) {
+	switch handlerType {
+	case "openai":
+		if id := data.Get("id").String(); id != "" {
+			s.id = id
+		}
+		if model := data.Get("model").String(); model != "" {
+			s.model = model
+		}
+		if created := data.Get("created").Int(); created > 0 {
+			s.created = created
+		}
+	case "openai-response":
+		if id := data.Get("response.id").String(); id != "" {
+			s.id = id
+		}
+		if model := data.Get("response.model").String(); model != "" {
+			s.model = model
+		}
+		if seq := data.Get("sequence_number"); seq.Exists() {
+			s.sequence = seq.Int()
+		}
+	case "claude":
+		switch data.Get("type").String() {
+		case "message_start":
+			s.sawMessages = true
+		case "content_block_start":
+			if s.openBlocks == nil {
+				s.openBlocks = make(map[int64]struct{})
+			}
+			s.openBlocks[data.Get("index").Int()] = struct{}{}
+		case "content_block_stop":
+			delete(s.openBlocks, data.Get("index").Int())
+		}
+	case "gemini":
+		if model := data.Get("modelVersion").String(); model != "" {
+			s.model = model
+		}
+		if id := data.Get("responseId").String(); id != "" {
+			s.id = id
+		}
+	}
+}
+
+// limitChunk builds the final event(s) that end a stream because a token limit was reached,
+// using each protocol's native length finish reason.
+func (s *streamTerminalState) limitChunk(handlerType string, outputTokens int64) []byte {
+	switch handlerType {
+	case "openai":
+		created := s.created
+		if created == 0 {
+			created = time.Now().Unix()
+		}
+		out := `{"object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"length"}]}`
+		out, _ = sjson.Set(out, "id", s.id)
+		out, _ = sjson.Set(out, "created", created)
+		out, _ = sjson.Set(out, "model", s.model)
+		return []byte(out)
+	case "openai-response":
+		out := `{"type":"response.incomplete","response":{"object":"response","status":"incomplete","incomplete_details":{"reason":"max_output_tokens"}}}`
+		out, _ = sjson.Set(out, "sequence_number", s.sequence+1)
+		out, _ = sjson.Set(out, "response.id", s.id)
+		out, _ = sjson.Set(out, "response.model", s.model)
+		return []byte(fmt.Sprintf("event: response.incomplete\ndata: %s\n", out))
+	case "claude":
+		if !s.sawMessages {
+			return nil
+		}
+		var chunk []byte
+		indexes := make([]int64, 0, len(s.openBlocks))
+		for index := range s.openBlocks {
+			indexes = append(indexes, index)
+		}
+		sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+		for _, index := range indexes {
+			stop, _ := sjson.Set(`{"type":"content_block_stop"}`, "index", index)
+			chunk = append(chunk, fmt.Sprintf("event: content_block_stop\ndata: %s\n\n", stop)...)
+		}
+		delta := `{"type":"message_delta","delta":{"stop_reason":"max_tokens","stop_sequence":null},"usage":{"output_tokens":0}}`
+		delta, _ = sjson.Set(delta, "usage.output_tokens", outputTokens)
+		chunk = append(chunk, fmt.Sprintf("event: message_delta\ndata: %s\n\n", delta)...)
+		chunk = append(chunk, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"...)
+		return chunk
+	case "gemini":
+		out := `{"candidates":[{"content":{"role":"model","parts":[{"text":""}]},"finishReason":"MAX_TOKENS","index":0}]}`
+		if s.model != "" {
+			out, _ = sjson.Set(out, "modelVersion", s.model)
+		}
+		if s.id != "" {
+			out, _ = sjson.Set(out, "responseId", s.id)
+		}
+		return []byte(out)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestStreamTerminalStateClaudeClosesOpenBlocks(t *testing.T) {
+	var state streamTerminalState
+	payload := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\"}}\n\n" +
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"hello\"}}\n\n"
+	forEachStreamJSON([]byte(payload), func(data gjson.Result) { state.observe("claude", data) })
+
+	out := string(state.limitChunk("claude", 12))
+	stop := strings.Index(out, "event: content_block_stop")
+	delta := strings.Index(out, `"stop_reason":"max_tokens"`)
+	end := strings.Index(out, "event: message_stop")
+	if stop < 0 || delta < stop || end < delta {
+		t.Fatalf("unexpected terminal events: %s", out)
+	}
+	if !strings.Contains(out, `"output_tokens":12`) {
+		t.Fatalf("expected output token count in message_delta: %s", out)
+	}
+}
+
+func TestStreamTextBytesSkipsResponsesSummaries(t *testing.T) {
+	delta := gjson.Parse(`{"type":"response.output_text.delta","delta":"abcd"}`)
+	done := gjson.Parse(`{"type":"response.output_text.done","text":"abcd"}`)
+	if got := streamTextBytes(delta); got != 4 {
+		t.Fatalf("delta bytes = %d, want 4", got)
+	}
+	if got := streamTextBytes(done); got != 0 {
+		t.Fatalf("done bytes = %d, want 0", got)
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/thoughtfilter"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"golang.org/x/net/context"
+)
+
+// thinkingOutputPolicies holds the thinking-output policies compiled from the current configuration.
+var thinkingOutputPolicies atomic.Pointer[thoughtfilter.Set]
+
+// configureThinkingOutput compiles policies once per configuration load.
+func configureThinkingOutput(policies []config.ThinkingOutputPolicy) {
+	thinkingOutputPolicies.Store(thoughtfilter.Compile(policies))
+}
+
+// thinkingOutputPolicy returns the caller's thinking-output policy, or nil when none applies.
+func thinkingOutputPolicy(ctx context.Context) *thoughtfilter.Policy {
+	policies := thinkingOutputPolicies.Load()
+	if policies == nil || ctx == nil {
+		return nil
+	}
+	var apiKey string
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok {
+		apiKey = callerAPIKey(ginCtx)
+	}
+	return policies.Select(apiKey)
+}
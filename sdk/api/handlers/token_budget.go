@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// tokenBudgetLedger tracks token consumption per client API key against api-key-budgets.
+// Completed usage records are charged through HandleUsage; in-flight streams are checked
+// through HandleStreamEvent so they can be truncated once the remaining budget is spent.
+type tokenBudgetLedger struct {
+	mu      sync.Mutex
+	limits  map[string]tokenBudgetLimit
+	windows map[string]*tokenBudgetWindow
+	// active is false while no budgets are configured, letting the usage hooks skip the lock.
+	active atomic.Bool
+}
+
+type tokenBudgetLimit struct {
+	maxTokens int64
+	period    time.Duration
+}
+
+type tokenBudgetWindow struct {
+	start time.Time
+	used  int64
+}
+
+var (
+	defaultTokenBudgets       = newTokenBudgetLedger()
+	registerTokenBudgetPlugin sync.Once
+)
+
+// configureTokenBudgets applies api-key-budgets. The ledger joins the usage plugins the first
+// time budgets are configured, so deployments without budgets never pay for its hooks.
+func configureTokenBudgets(budgets []config.APIKeyBudget) {
+	defaultTokenBudgets.configure(budgets)
+	if defaultTokenBudgets.active.Load() {
+		registerTokenBudgetPlugin.Do(func() {
+			coreusage.RegisterPlugin(defaultTokenBudgets)
+		})
+	}
+}
+
+func newTokenBudgetLedger() *tokenBudgetLedger {
+	return &tokenBudgetLedger{
+		limits:  make(map[string]tokenBudgetLimit),
+		windows: make(map[string]*tokenBudgetWindow),
+	}
+}
+
+// configure replaces the budget limits. Consumption recorded so far is kept.
+func (l *tokenBudgetLedger) configure(budgets []config.APIKeyBudget) {
+	limits := make(map[string]tokenBudgetLimit, len(budgets))
+	for _, budget := range budgets {
+		apiKey := strings.TrimSpace(budget.APIKey)
+		if apiKey == "" || budget.MaxTokens <= 0 {
+			continue
+		}
+		limit := tokenBudgetLimit{maxTokens: budget.MaxTokens}
+		if period := strings.TrimSpace(budget.Period); period != "" {
+			parsed, err := time.ParseDuration(period)
+			if err != nil || parsed <= 0 {
+				log.Warnf("api-key-budgets: invalid period %q, budget will not reset", period)
+			} else {
+				limit.period = parsed
+			}
+		}
+		limits[apiKey] = limit
+	}
+	l.mu.Lock()
+	l.limits = limits
+	l.mu.Unlock()
+	l.active.Store(len(limits) > 0)
+}
+
+// remaining returns the tokens left for apiKey and whether a budget applies.
+func (l *tokenBudgetLedger) remaining(apiKey string, now time.Time) (int64, bool) {
+	if !l.active.Load() {
+		return 0, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit, ok := l.limits[apiKey]
+	if !ok {
+		return 0, false
+	}
+	return limit.maxTokens - l.windowLocked(apiKey, limit, now).used, true
+}
+
+func (l *tokenBudgetLedger) windowLocked(apiKey string, limit tokenBudgetLimit, now time.Time) *tokenBudgetWindow {
+	window, ok := l.windows[apiKey]
+	if !ok {
+		window = &tokenBudgetWindow{start: now}
+		l.windows[apiKey] = window
+	}
+	if limit.period > 0 && now.Sub(window.start) >= limit.period {
+		window.start = now
+		window.used = 0
+	}
+	return window
+}
+
+// HandleUsage charges completed requests against the caller's budget.
+func (l *tokenBudgetLedger) HandleUsage(_ context.Context, record coreusage.Record) {
+	tokens := record.Detail.TotalTokens
+	if tokens <= 0 {
+		tokens = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+	}
+	if tokens <= 0 || record.APIKey == "" || !l.active.Load() {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit, ok := l.limits[record.APIKey]
+	if !ok {
+		return
+	}
+	l.windowLocked(record.APIKey, limit, time.Now()).used += tokens
+}
+
+// HandleStreamEvent truncates streams whose estimated output exceeds the remaining budget.
+func (l *tokenBudgetLedger) HandleStreamEvent(_ context.Context, event coreusage.StreamEvent) error {
+	if event.Type != coreusage.StreamProgress || event.APIKey == "" {
+		return nil
+	}
+	remaining, ok := l.remaining(event.APIKey, time.Now())
+	if !ok || event.EstimatedOutputTokens < remaining {
+		return nil
+	}
+	return fmt.Errorf("token budget exhausted for API key: %w", coreusage.ErrStreamLimitReached)
+}
+
+// checkTokenBudget rejects requests from API keys whose token budget is already exhausted.
+func (h *BaseAPIHandler) checkTokenBudget(ctx context.Context) *interfaces.ErrorMessage {
+	if ctx == nil {
+		return nil
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok {
+		return nil
+	}
+	remaining, budgeted := defaultTokenBudgets.remaining(callerAPIKey(ginCtx), time.Now())
+	if !budgeted || remaining > 0 {
+		return nil
+	}
+	return &interfaces.ErrorMessage{
+		StatusCode: http.StatusTooManyRequests,
+		Error:      fmt.Errorf("token budget exhausted for this API key"),
+	}
+}
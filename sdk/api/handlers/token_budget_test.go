@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"golang.org/x/net/context"
+)
+
+func TestTokenBudgetLedger(t *testing.T) {
+	ledger := newTokenBudgetLedger()
+	ledger.configure([]sdkconfig.APIKeyBudget{
+		{APIKey: "k1", MaxTokens: 100},
+		{APIKey: "k2", MaxTokens: 50, Period: "1h"},
+	})
+
+	if _, ok := ledger.remaining("other", time.Now()); ok {
+		t.Fatalf("expected no budget for unconfigured key")
+	}
+	ledger.HandleUsage(context.Background(), coreusage.Record{APIKey: "k1", Detail: coreusage.Detail{InputTokens: 30, OutputTokens: 10}})
+	if remaining, _ := ledger.remaining("k1", time.Now()); remaining != 60 {
+		t.Fatalf("k1 remaining = %d, want 60", remaining)
+	}
+
+	err := ledger.HandleStreamEvent(context.Background(), coreusage.StreamEvent{Type: coreusage.StreamProgress, APIKey: "k1", EstimatedOutputTokens: 60})
+	if !errors.Is(err, coreusage.ErrStreamLimitReached) {
+		t.Fatalf("expected stream limit error, got %v", err)
+	}
+	if err = ledger.HandleStreamEvent(context.Background(), coreusage.StreamEvent{Type: coreusage.StreamProgress, APIKey: "k1", EstimatedOutputTokens: 59}); err != nil {
+		t.Fatalf("expected stream within budget to continue, got %v", err)
+	}
+
+	ledger.HandleUsage(context.Background(), coreusage.Record{APIKey: "k2", Detail: coreusage.Detail{TotalTokens: 50}})
+	if remaining, _ := ledger.remaining("k2", time.Now()); remaining != 0 {
+		t.Fatalf("k2 remaining = %d, want 0", remaining)
+	}
+	if remaining, _ := ledger.remaining("k2", time.Now().Add(2*time.Hour)); remaining != 50 {
+		t.Fatalf("k2 remaining after period = %d, want 50", remaining)
+	}
+}
+
+type budgetStreamExecutor struct{}
+
+func (budgetStreamExecutor) Identifier() string { return "codex" }
+
+func (budgetStreamExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "Execute not implemented"}
+}
+
+func (budgetStreamExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (<-chan coreexecutor.StreamChunk, error) {
+	text := strings.Repeat("a", 2000)
+	ch := make(chan coreexecutor.StreamChunk, 2)
+	ch <- coreexecutor.StreamChunk{Payload: []byte(`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"budget-model","choices":[{"index":0,"delta":{"content":"` + text + `"}}]}`)}
+	ch <- coreexecutor.StreamChunk{Payload: []byte(`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"budget-model","choices":[{"index":0,"delta":{"content":"never sent"}}]}`)}
+	close(ch)
+	return ch, nil
+}
+
+func (budgetStreamExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (budgetStreamExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (budgetStreamExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented", HTTPStatus: http.StatusNotImplemented}
+}
+
+func TestExecuteStreamWithAuthManager_TruncatesAtTokenBudget(t *testing.T) {
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(budgetStreamExecutor{})
+	auth := &coreauth.Auth{ID: "budget-auth", Provider: "codex", Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("manager.Register: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "budget-model"}})
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+		defaultTokenBudgets.configure(nil)
+	})
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{
+		APIKeyBudgets: []sdkconfig.APIKeyBudget{{APIKey: "budget-key", MaxTokens: 100}},
+	}, manager)
+
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("apiKey", "budget-key")
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+
+	dataChan, errChan := handler.ExecuteStreamWithAuthManager(ctx, "openai", "budget-model", []byte(`{"model":"budget-model","stream":true,"messages":[{"role":"user","content":"hi"}]}`), "")
+	var chunks []string
+	for chunk := range dataChan {
+		chunks = append(chunks, string(chunk))
+	}
+	for msg := range errChan {
+		if msg != nil {
+			t.Fatalf("unexpected error: %+v", msg)
+		}
+	}
+
+	if len(chunks) != 2 {
+		t.Fatalf("expected content chunk plus final chunk, got %d: %v", len(chunks), chunks)
+	}
+	if !strings.Contains(chunks[1], `"finish_reason":"length"`) || !strings.Contains(chunks[1], `"id":"chatcmpl-1"`) {
+		t.Fatalf("unexpected final chunk: %s", chunks[1])
+	}
+}
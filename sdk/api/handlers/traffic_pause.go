@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
+)
+
+// defaultPauseRetryAfter is the Retry-After value (seconds) used when traffic-pause does not set one.
+const defaultPauseRetryAfter = 30
+
+// trafficPaused is the live global pause flag. It mirrors traffic-pause.paused on every config
+// load and is set directly by the management API, so a pause or drain takes effect before the
+// config file is written and reloaded.
+var trafficPaused atomic.Bool
+
+// SetTrafficPaused pauses or resumes all traffic immediately.
+func SetTrafficPaused(paused bool) { trafficPaused.Store(paused) }
+
+// TrafficPaused reports whether all traffic is currently paused.
+func TrafficPaused() bool { return trafficPaused.Load() }
+
+// inFlightRequests counts generation requests currently being served across all handlers.
+var inFlightRequests atomic.Int64
+
+// InFlightRequests returns the number of generation requests currently being served.
+// Operators use it to tell when a paused proxy has finished draining.
+func InFlightRequests() int64 { return inFlightRequests.Load() }
+
+// trackInFlight marks a request as in flight and returns the function that releases it.
+func trackInFlight() func() {
+	inFlightRequests.Add(1)
+	var once atomic.Bool
+	return func() {
+		if once.CompareAndSwap(false, true) {
+			inFlightRequests.Add(-1)
+		}
+	}
+}
+
+// applyTrafficPause removes paused providers from the candidates of a request. It returns a 503
+// with Retry-After when traffic is paused globally or every candidate provider is paused.
+func (h *BaseAPIHandler) applyTrafficPause(providers []string) ([]string, *interfaces.ErrorMessage) {
+	if h == nil || h.Cfg == nil {
+		return providers, nil
+	}
+	pause := h.Cfg.TrafficPause
+	paused := trafficPaused.Load()
+	if !paused && len(pause.Providers) == 0 {
+		return providers, nil
+	}
+	if !paused {
+		available := make([]string, 0, len(providers))
+		for _, provider := range providers {
+			if !providerPaused(pause.Providers, provider) {
+				available = append(available, provider)
+			}
+		}
+		if len(available) > 0 {
+			return available, nil
+		}
+	}
+	retryAfter := pause.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = defaultPauseRetryAfter
+	}
+	addon := http.Header{}
+	addon.Set("Retry-After", strconv.Itoa(retryAfter))
+	return nil, &interfaces.ErrorMessage{
+		StatusCode: http.StatusServiceUnavailable,
+		Error:      errors.New("service is paused for maintenance, retry later"),
+		Addon:      addon,
+	}
+}
+
+func providerPaused(paused []string, provider string) bool {
+	for _, candidate := range paused {
+		if strings.EqualFold(strings.TrimSpace(candidate), provider) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func TestApplyTrafficPause(t *testing.T) {
+	h := &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{}}
+	if got, errMsg := h.applyTrafficPause([]string{"claude"}); errMsg != nil || !reflect.DeepEqual(got, []string{"claude"}) {
+		t.Fatalf("unpaused: got %v, %v", got, errMsg)
+	}
+
+	h.Cfg.TrafficPause = sdkconfig.TrafficPause{Providers: []string{"Claude"}}
+	if got, errMsg := h.applyTrafficPause([]string{"claude", "kiro"}); errMsg != nil || !reflect.DeepEqual(got, []string{"kiro"}) {
+		t.Fatalf("provider pause with fallback: got %v, %v", got, errMsg)
+	}
+	_, errMsg := h.applyTrafficPause([]string{"claude"})
+	if errMsg == nil || errMsg.StatusCode != http.StatusServiceUnavailable || errMsg.Addon.Get("Retry-After") != "30" {
+		t.Fatalf("provider pause without fallback: got %+v", errMsg)
+	}
+
+	h.Cfg.TrafficPause = sdkconfig.TrafficPause{RetryAfter: 120}
+	SetTrafficPaused(true)
+	t.Cleanup(func() { SetTrafficPaused(false) })
+	_, errMsg = h.applyTrafficPause([]string{"kiro"})
+	if errMsg == nil || errMsg.Addon.Get("Retry-After") != "120" {
+		t.Fatalf("global pause: got %+v", errMsg)
+	}
+
+	// A reload mirrors the persisted flag into the live state.
+	h.UpdateClients(&sdkconfig.SDKConfig{})
+	if got, errMsg := h.applyTrafficPause([]string{"kiro"}); errMsg != nil || !reflect.DeepEqual(got, []string{"kiro"}) {
+		t.Fatalf("after reload without pause: got %v, %v", got, errMsg)
+	}
+}
+
+func TestTrackInFlight(t *testing.T) {
+	before := InFlightRequests()
+	release := trackInFlight()
+	if InFlightRequests() != before+1 {
+		t.Fatalf("expected in-flight count to increase")
+	}
+	release()
+	release()
+	if InFlightRequests() != before {
+		t.Fatalf("expected in-flight count to return to %d, got %d", before, InFlightRequests())
+	}
+}
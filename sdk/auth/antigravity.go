@@ -2,13 +2,15 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/antigravity"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/oauthcallback"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/browser"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
@@ -56,17 +58,14 @@ func (AntigravityAuthenticator) Login(ctx context.Context, cfg *config.Config, o
 		return nil, fmt.Errorf("antigravity: failed to generate state: %w", err)
 	}
 
-	srv, port, cbChan, errServer := startAntigravityCallbackServer(callbackPort)
+	srv, errServer := oauthcallback.Start(callbackOptions(cfg, callbackPort, true, "/oauth-callback", state))
 	if errServer != nil {
 		return nil, fmt.Errorf("antigravity: failed to start callback server: %w", errServer)
 	}
-	defer func() {
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		_ = srv.Shutdown(shutdownCtx)
-	}()
+	defer stopCallbackServer("antigravity", srv)
+	port := srv.Port()
 
-	redirectURI := fmt.Sprintf("http://localhost:%d/oauth-callback", port)
+	redirectURI := srv.RedirectURI()
 	authURL := authSvc.BuildAuthURL(state, redirectURI)
 
 	if !opts.NoBrowser {
@@ -87,10 +86,18 @@ func (AntigravityAuthenticator) Login(ctx context.Context, cfg *config.Config, o
 
 	fmt.Println("Waiting for antigravity authentication callback...")
 
-	var cbRes callbackResult
-	timeoutTimer := time.NewTimer(5 * time.Minute)
-	defer timeoutTimer.Stop()
+	callbackCh := make(chan *oauthcallback.Result, 1)
+	callbackErrCh := make(chan error, 1)
+	go func() {
+		result, errWait := srv.Wait(ctx, 5*time.Minute)
+		if errWait != nil {
+			callbackErrCh <- errWait
+			return
+		}
+		callbackCh <- result
+	}()
 
+	var cbRes *oauthcallback.Result
 	var manualPromptTimer *time.Timer
 	var manualPromptC <-chan time.Time
 	if opts.Prompt != nil {
@@ -102,17 +109,20 @@ func (AntigravityAuthenticator) Login(ctx context.Context, cfg *config.Config, o
 waitForCallback:
 	for {
 		select {
-		case res := <-cbChan:
-			cbRes = res
+		case cbRes = <-callbackCh:
 			break waitForCallback
+		case errWait := <-callbackErrCh:
+			if errors.Is(errWait, oauthcallback.ErrTimeout) {
+				return nil, fmt.Errorf("antigravity: authentication timed out")
+			}
+			return nil, fmt.Errorf("antigravity: callback wait failed: %w", errWait)
 		case <-manualPromptC:
 			manualPromptC = nil
 			if manualPromptTimer != nil {
 				manualPromptTimer.Stop()
 			}
 			select {
-			case res := <-cbChan:
-				cbRes = res
+			case cbRes = <-callbackCh:
 				break waitForCallback
 			default:
 			}
@@ -127,14 +137,12 @@ waitForCallback:
 			if parsed == nil {
 				continue
 			}
-			cbRes = callbackResult{
+			cbRes = &oauthcallback.Result{
 				Code:  parsed.Code,
 				State: parsed.State,
 				Error: parsed.Error,
 			}
 			break waitForCallback
-		case <-timeoutTimer.C:
-			return nil, fmt.Errorf("antigravity: authentication timed out")
 		}
 	}
 
@@ -167,18 +175,38 @@ waitForCallback:
 		return nil, fmt.Errorf("antigravity: empty email returned from user info")
 	}
 
-	// Fetch project ID via loadCodeAssist (same approach as Gemini CLI)
-	projectID := ""
-	if accessToken != "" {
-		fetchedProjectID, errProject := authSvc.FetchProjectID(ctx, accessToken)
-		if errProject != nil {
-			log.Warnf("antigravity: failed to fetch project ID: %v", errProject)
-		} else {
-			projectID = fetchedProjectID
+	// Look up the project via loadCodeAssist (same approach as Gemini CLI) and onboard accounts
+	// that have none yet to the chosen tier.
+	projectID, tierID := "", ""
+	status, errLoad := authSvc.LoadCodeAssist(ctx, accessToken)
+	if errLoad != nil {
+		log.Warnf("antigravity: failed to load code assist status: %v", errLoad)
+	} else {
+		projectID, tierID = status.ProjectID, status.CurrentTier
+		if projectID == "" {
+			tierID = chooseAntigravityTier(status, opts)
+			fmt.Printf("Onboarding %s to tier %s...\n", email, tierID)
+			onboardedProjectID, errOnboard := authSvc.OnboardUser(ctx, accessToken, tierID)
+			if errOnboard != nil {
+				log.Warnf("antigravity: onboarding failed: %v", errOnboard)
+			}
+			projectID = onboardedProjectID
+		}
+		if projectID != "" {
 			log.Infof("antigravity: obtained project ID %s", projectID)
 		}
 	}
 
+	if projectID != "" && opts.Metadata["skip_verify"] != "true" {
+		fmt.Println("Sending a test request to verify the account...")
+		reply, errVerify := authSvc.VerifyGenerateContent(ctx, accessToken, projectID)
+		if errVerify != nil {
+			log.Warnf("antigravity: test request failed, the account may not serve requests yet: %v", errVerify)
+		} else {
+			fmt.Printf("Test request succeeded, %s replied %q\n", antigravity.VerifyModel, reply)
+		}
+	}
+
 	now := time.Now()
 	metadata := map[string]any{
 		"type":          "antigravity",
@@ -194,6 +222,9 @@ waitForCallback:
 	if projectID != "" {
 		metadata["project_id"] = projectID
 	}
+	if tierID != "" {
+		metadata["tier"] = tierID
+	}
 
 	fileName := antigravity.CredentialFileName(email)
 	label := email
@@ -214,48 +245,50 @@ waitForCallback:
 	}, nil
 }
 
-type callbackResult struct {
-	Code  string
-	Error string
-	State string
-}
-
-func startAntigravityCallbackServer(port int) (*http.Server, int, <-chan callbackResult, error) {
-	if port <= 0 {
-		port = antigravity.CallbackPort
+// chooseAntigravityTier picks the tier an account without a project is onboarded to: the tier
+// named in the "tier" metadata option, else the user's choice when several tiers are allowed and
+// a prompt is available, else the account's default tier.
+func chooseAntigravityTier(status *antigravity.CodeAssistStatus, opts *LoginOptions) string {
+	defaultTier := status.DefaultTierID()
+	if tier := strings.TrimSpace(opts.Metadata["tier"]); tier != "" {
+		return tier
 	}
-	addr := fmt.Sprintf(":%d", port)
-	listener, err := net.Listen("tcp", addr)
-	if err != nil {
-		return nil, 0, nil, err
+	if opts.Prompt == nil || len(status.AllowedTiers) < 2 {
+		return defaultTier
 	}
-	port = listener.Addr().(*net.TCPAddr).Port
-	resultCh := make(chan callbackResult, 1)
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/oauth-callback", func(w http.ResponseWriter, r *http.Request) {
-		q := r.URL.Query()
-		res := callbackResult{
-			Code:  strings.TrimSpace(q.Get("code")),
-			Error: strings.TrimSpace(q.Get("error")),
-			State: strings.TrimSpace(q.Get("state")),
+	fmt.Println("This account has not been onboarded yet. Available tiers:")
+	for i, tier := range status.AllowedTiers {
+		line := fmt.Sprintf("  %d. %s", i+1, tier.ID)
+		if tier.Name != "" {
+			line += " (" + tier.Name + ")"
 		}
-		resultCh <- res
-		if res.Code != "" && res.Error == "" {
-			_, _ = w.Write([]byte("<h1>Login successful</h1><p>You can close this window.</p>"))
-		} else {
-			_, _ = w.Write([]byte("<h1>Login failed</h1><p>Please check the CLI output.</p>"))
+		if tier.ID == defaultTier {
+			line += " [default]"
 		}
-	})
-
-	srv := &http.Server{Handler: mux}
-	go func() {
-		if errServe := srv.Serve(listener); errServe != nil && !strings.Contains(errServe.Error(), "Server closed") {
-			log.Warnf("antigravity callback server error: %v", errServe)
+		if tier.Description != "" {
+			line += " - " + tier.Description
 		}
-	}()
-
-	return srv, port, resultCh, nil
+		fmt.Println(line)
+	}
+	input, errPrompt := opts.Prompt(fmt.Sprintf("Select a tier by number or ID (Enter for %s): ", defaultTier))
+	if errPrompt != nil {
+		return defaultTier
+	}
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return defaultTier
+	}
+	if n, errAtoi := strconv.Atoi(input); errAtoi == nil && n >= 1 && n <= len(status.AllowedTiers) {
+		return status.AllowedTiers[n-1].ID
+	}
+	for _, tier := range status.AllowedTiers {
+		if strings.EqualFold(tier.ID, input) {
+			return tier.ID
+		}
+	}
+	log.Warnf("antigravity: unknown tier %q, using %s", input, defaultTier)
+	return defaultTier
 }
 
 // FetchAntigravityProjectID exposes project discovery for external callers.
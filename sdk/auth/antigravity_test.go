@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/antigravity"
+)
+
+func TestChooseAntigravityTier(t *testing.T) {
+	status := &antigravity.CodeAssistStatus{AllowedTiers: []antigravity.Tier{
+		{ID: "free-tier", IsDefault: true},
+		{ID: "standard-tier"},
+	}}
+	answer := func(input string) func(string) (string, error) {
+		return func(string) (string, error) { return input, nil }
+	}
+
+	cases := []struct {
+		name string
+		opts *LoginOptions
+		want string
+	}{
+		{name: "no prompt", opts: &LoginOptions{}, want: "free-tier"},
+		{name: "enter", opts: &LoginOptions{Prompt: answer("")}, want: "free-tier"},
+		{name: "number", opts: &LoginOptions{Prompt: answer("2")}, want: "standard-tier"},
+		{name: "id", opts: &LoginOptions{Prompt: answer("Standard-Tier")}, want: "standard-tier"},
+		{name: "unknown", opts: &LoginOptions{Prompt: answer("9")}, want: "free-tier"},
+		{name: "option", opts: &LoginOptions{Metadata: map[string]string{"tier": "standard-tier"}, Prompt: answer("1")}, want: "standard-tier"},
+	}
+	for _, tc := range cases {
+		if got := chooseAntigravityTier(status, tc.opts); got != tc.want {
+			t.Errorf("%s: tier = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
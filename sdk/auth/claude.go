@@ -2,14 +2,15 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
+	// legacy client removed
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/claude"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/oauthcallback"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/browser"
-	// legacy client removed
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
@@ -62,20 +63,16 @@ func (a *ClaudeAuthenticator) Login(ctx context.Context, cfg *config.Config, opt
 		return nil, fmt.Errorf("claude state generation failed: %w", err)
 	}
 
-	oauthServer := claude.NewOAuthServer(callbackPort)
-	if err = oauthServer.Start(); err != nil {
-		if strings.Contains(err.Error(), "already in use") {
+	callbackOpts := callbackOptions(cfg, callbackPort, false, "/callback", state)
+	callbackOpts.SuccessHTML = claude.SuccessPage()
+	oauthServer, err := oauthcallback.Start(callbackOpts)
+	if err != nil {
+		if errors.Is(err, oauthcallback.ErrPortInUse) {
 			return nil, claude.NewAuthenticationError(claude.ErrPortInUse, err)
 		}
 		return nil, claude.NewAuthenticationError(claude.ErrServerStartFailed, err)
 	}
-	defer func() {
-		stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		if stopErr := oauthServer.Stop(stopCtx); stopErr != nil {
-			log.Warnf("claude oauth server stop error: %v", stopErr)
-		}
-	}()
+	defer stopCallbackServer("claude", oauthServer)
 
 	authSvc := claude.NewClaudeAuth(cfg)
 
@@ -103,12 +100,12 @@ func (a *ClaudeAuthenticator) Login(ctx context.Context, cfg *config.Config, opt
 
 	fmt.Println("Waiting for Claude authentication callback...")
 
-	callbackCh := make(chan *claude.OAuthResult, 1)
+	callbackCh := make(chan *oauthcallback.Result, 1)
 	callbackErrCh := make(chan error, 1)
 	manualDescription := ""
 
 	go func() {
-		result, errWait := oauthServer.WaitForCallback(5 * time.Minute)
+		result, errWait := oauthServer.Wait(ctx, 5*time.Minute)
 		if errWait != nil {
 			callbackErrCh <- errWait
 			return
@@ -116,7 +113,7 @@ func (a *ClaudeAuthenticator) Login(ctx context.Context, cfg *config.Config, opt
 		callbackCh <- result
 	}()
 
-	var result *claude.OAuthResult
+	var result *oauthcallback.Result
 	var manualPromptTimer *time.Timer
 	var manualPromptC <-chan time.Time
 	if opts.Prompt != nil {
@@ -131,7 +128,7 @@ waitForCallback:
 		case result = <-callbackCh:
 			break waitForCallback
 		case err = <-callbackErrCh:
-			if strings.Contains(err.Error(), "timeout") {
+			if errors.Is(err, oauthcallback.ErrTimeout) {
 				return nil, claude.NewAuthenticationError(claude.ErrCallbackTimeout, err)
 			}
 			return nil, err
@@ -144,7 +141,7 @@ waitForCallback:
 			case result = <-callbackCh:
 				break waitForCallback
 			case err = <-callbackErrCh:
-				if strings.Contains(err.Error(), "timeout") {
+				if errors.Is(err, oauthcallback.ErrTimeout) {
 					return nil, claude.NewAuthenticationError(claude.ErrCallbackTimeout, err)
 				}
 				return nil, err
@@ -162,7 +159,7 @@ waitForCallback:
 				continue
 			}
 			manualDescription = parsed.ErrorDescription
-			result = &claude.OAuthResult{
+			result = &oauthcallback.Result{
 				Code:  parsed.Code,
 				State: parsed.State,
 				Error: parsed.Error,
@@ -4,14 +4,16 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	// legacy client removed
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/codex"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/oauthcallback"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/browser"
-	// legacy client removed
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
@@ -64,20 +66,16 @@ func (a *CodexAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 		return nil, fmt.Errorf("codex state generation failed: %w", err)
 	}
 
-	oauthServer := codex.NewOAuthServer(callbackPort)
-	if err = oauthServer.Start(); err != nil {
-		if strings.Contains(err.Error(), "already in use") {
+	callbackOpts := callbackOptions(cfg, callbackPort, false, "/auth/callback", state)
+	callbackOpts.SuccessHTML = codex.SuccessPage()
+	oauthServer, err := oauthcallback.Start(callbackOpts)
+	if err != nil {
+		if errors.Is(err, oauthcallback.ErrPortInUse) {
 			return nil, codex.NewAuthenticationError(codex.ErrPortInUse, err)
 		}
 		return nil, codex.NewAuthenticationError(codex.ErrServerStartFailed, err)
 	}
-	defer func() {
-		stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		if stopErr := oauthServer.Stop(stopCtx); stopErr != nil {
-			log.Warnf("codex oauth server stop error: %v", stopErr)
-		}
-	}()
+	defer stopCallbackServer("codex", oauthServer)
 
 	authSvc := codex.NewCodexAuth(cfg)
 
@@ -104,12 +102,12 @@ func (a *CodexAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 
 	fmt.Println("Waiting for Codex authentication callback...")
 
-	callbackCh := make(chan *codex.OAuthResult, 1)
+	callbackCh := make(chan *oauthcallback.Result, 1)
 	callbackErrCh := make(chan error, 1)
 	manualDescription := ""
 
 	go func() {
-		result, errWait := oauthServer.WaitForCallback(5 * time.Minute)
+		result, errWait := oauthServer.Wait(ctx, 5*time.Minute)
 		if errWait != nil {
 			callbackErrCh <- errWait
 			return
@@ -117,7 +115,7 @@ func (a *CodexAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 		callbackCh <- result
 	}()
 
-	var result *codex.OAuthResult
+	var result *oauthcallback.Result
 	var manualPromptTimer *time.Timer
 	var manualPromptC <-chan time.Time
 	if opts.Prompt != nil {
@@ -132,7 +130,7 @@ waitForCallback:
 		case result = <-callbackCh:
 			break waitForCallback
 		case err = <-callbackErrCh:
-			if strings.Contains(err.Error(), "timeout") {
+			if errors.Is(err, oauthcallback.ErrTimeout) {
 				return nil, codex.NewAuthenticationError(codex.ErrCallbackTimeout, err)
 			}
 			return nil, err
@@ -145,7 +143,7 @@ waitForCallback:
 			case result = <-callbackCh:
 				break waitForCallback
 			case err = <-callbackErrCh:
-				if strings.Contains(err.Error(), "timeout") {
+				if errors.Is(err, oauthcallback.ErrTimeout) {
 					return nil, codex.NewAuthenticationError(codex.ErrCallbackTimeout, err)
 				}
 				return nil, err
@@ -163,7 +161,7 @@ waitForCallback:
 				continue
 			}
 			manualDescription = parsed.ErrorDescription
-			result = &codex.OAuthResult{
+			result = &oauthcallback.Result{
 				Code:  parsed.Code,
 				State: parsed.State,
 				Error: parsed.Error,
@@ -2,11 +2,13 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/iflow"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/oauthcallback"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/browser"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/misc"
@@ -49,26 +51,24 @@ func (a *IFlowAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 
 	authSvc := iflow.NewIFlowAuth(cfg)
 
-	oauthServer := iflow.NewOAuthServer(callbackPort)
-	if err := oauthServer.Start(); err != nil {
-		if strings.Contains(err.Error(), "already in use") {
-			return nil, fmt.Errorf("iflow authentication server port in use: %w", err)
-		}
-		return nil, fmt.Errorf("iflow authentication server failed: %w", err)
-	}
-	defer func() {
-		stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		if stopErr := oauthServer.Stop(stopCtx); stopErr != nil {
-			log.Warnf("iflow oauth server stop error: %v", stopErr)
-		}
-	}()
-
 	state, err := misc.GenerateRandomState()
 	if err != nil {
 		return nil, fmt.Errorf("iflow auth: failed to generate state: %w", err)
 	}
 
+	callbackOpts := callbackOptions(cfg, callbackPort, true, "/oauth2callback", state)
+	callbackOpts.SuccessRedirect = iflow.SuccessRedirectURL
+	callbackOpts.FailureRedirect = iflow.ErrorRedirectURL
+	oauthServer, err := oauthcallback.Start(callbackOpts)
+	if err != nil {
+		if errors.Is(err, oauthcallback.ErrPortInUse) {
+			return nil, fmt.Errorf("iflow authentication server port in use: %w", err)
+		}
+		return nil, fmt.Errorf("iflow authentication server failed: %w", err)
+	}
+	defer stopCallbackServer("iflow", oauthServer)
+	callbackPort = oauthServer.Port()
+
 	authURL, redirectURI := authSvc.AuthorizationURL(state, callbackPort)
 
 	if !opts.NoBrowser {
@@ -89,11 +89,11 @@ func (a *IFlowAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 
 	fmt.Println("Waiting for iFlow authentication callback...")
 
-	callbackCh := make(chan *iflow.OAuthResult, 1)
+	callbackCh := make(chan *oauthcallback.Result, 1)
 	callbackErrCh := make(chan error, 1)
 
 	go func() {
-		result, errWait := oauthServer.WaitForCallback(5 * time.Minute)
+		result, errWait := oauthServer.Wait(ctx, 5*time.Minute)
 		if errWait != nil {
 			callbackErrCh <- errWait
 			return
@@ -101,7 +101,7 @@ func (a *IFlowAuthenticator) Login(ctx context.Context, cfg *config.Config, opts
 		callbackCh <- result
 	}()
 
-	var result *iflow.OAuthResult
+	var result *oauthcallback.Result
 	var manualPromptTimer *time.Timer
 	var manualPromptC <-chan time.Time
 	if opts.Prompt != nil {
@@ -140,7 +140,7 @@ waitForCallback:
 			if parsed == nil {
 				continue
 			}
-			result = &iflow.OAuthResult{
+			result = &oauthcallback.Result{
 				Code:  parsed.Code,
 				State: parsed.State,
 				Error: parsed.Error,
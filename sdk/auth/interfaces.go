@@ -18,7 +18,9 @@ type LoginOptions struct {
 	ProjectID    string
 	CallbackPort int
 	Metadata     map[string]string
-	Prompt       func(prompt string) (string, error)
+	// Labels are stored on the saved credential for use in routing rules, e.g. {"tier": "pro"}.
+	Labels map[string]string
+	Prompt func(prompt string) (string, error)
 }
 
 // Authenticator manages login and optional refresh flows for a provider.
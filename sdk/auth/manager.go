@@ -57,6 +57,9 @@ func (m *Manager) Login(ctx context.Context, provider string, cfg *config.Config
 	if record == nil {
 		return nil, "", fmt.Errorf("cliproxy auth: authenticator %s returned nil record", provider)
 	}
+	if opts != nil {
+		record.MergeLabels(opts.Labels)
+	}
 
 	if m.store == nil {
 		return record, "", nil
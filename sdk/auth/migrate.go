@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+// MigrateOptions controls MigrateTokens.
+type MigrateOptions struct {
+	// RemoveSource deletes each credential from the source store once its copy is verified.
+	RemoveSource bool
+	// Probe optionally checks a migrated credential as read back from the target store. A
+	// failing probe keeps the source copy.
+	Probe func(ctx context.Context, auth *cliproxyauth.Auth) error
+}
+
+// MigrateResult reports the outcome for one credential.
+type MigrateResult struct {
+	ID      string
+	Target  string
+	Removed bool
+	Err     error
+}
+
+// MigrateTokens copies every credential of from into to. Each copy is read back from the
+// target and compared with the source before the source copy is removed, so a credential is
+// never deleted unless the target demonstrably holds it.
+func MigrateTokens(ctx context.Context, from, to cliproxyauth.Store, opts MigrateOptions) ([]MigrateResult, error) {
+	if from == nil || to == nil {
+		return nil, fmt.Errorf("auth migrate: source and target stores are required")
+	}
+	auths, err := from.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("auth migrate: list source: %w", err)
+	}
+	results := make([]MigrateResult, 0, len(auths))
+	for _, auth := range auths {
+		if auth == nil {
+			continue
+		}
+		result := MigrateResult{ID: auth.ID}
+		result.Target, result.Err = migrateOne(ctx, to, auth, opts.Probe)
+		if result.Err == nil && opts.RemoveSource {
+			if errDelete := from.Delete(ctx, auth.ID); errDelete != nil {
+				result.Err = fmt.Errorf("copied but source not removed: %w", errDelete)
+			} else {
+				result.Removed = true
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func migrateOne(ctx context.Context, to cliproxyauth.Store, auth *cliproxyauth.Auth, probe func(context.Context, *cliproxyauth.Auth) error) (string, error) {
+	if len(auth.Metadata) == 0 {
+		return "", fmt.Errorf("no metadata to migrate")
+	}
+	copied := migrationCopy(auth)
+	target, err := to.Save(ctx, copied)
+	if err != nil {
+		return "", fmt.Errorf("save: %w", err)
+	}
+	stored, err := to.List(ctx)
+	if err != nil {
+		return target, fmt.Errorf("verify: list target: %w", err)
+	}
+	var found *cliproxyauth.Auth
+	for _, candidate := range stored {
+		if candidate != nil && candidate.ID == copied.ID {
+			found = candidate
+			break
+		}
+	}
+	if found == nil {
+		return target, fmt.Errorf("verify: %s not found in target", copied.ID)
+	}
+	if !metadataEqual(auth.Metadata, found.Metadata) {
+		return target, fmt.Errorf("verify: %s differs in target", copied.ID)
+	}
+	if probe != nil {
+		if errProbe := probe(ctx, found); errProbe != nil {
+			return target, fmt.Errorf("probe: %w", errProbe)
+		}
+	}
+	return target, nil
+}
+
+// migrationCopy clones auth without the attributes tied to the source store, such as its path.
+func migrationCopy(auth *cliproxyauth.Auth) *cliproxyauth.Auth {
+	copied := auth.Clone()
+	copied.Storage = nil
+	copied.Attributes = make(map[string]string, len(auth.Attributes))
+	for key, value := range auth.Attributes {
+		if key != "path" {
+			copied.Attributes[key] = value
+		}
+	}
+	id := filepath.ToSlash(strings.TrimSpace(auth.ID))
+	if filepath.IsAbs(auth.ID) {
+		id = filepath.Base(auth.ID)
+	}
+	copied.ID = id
+	copied.FileName = id
+	return copied
+}
+
+// metadataEqual compares metadata as JSON, ignoring the disabled flag some stores add on save.
+func metadataEqual(a, b map[string]any) bool {
+	strip := func(m map[string]any) map[string]any {
+		out := make(map[string]any, len(m))
+		for key, value := range m {
+			if key != "disabled" {
+				out[key] = value
+			}
+		}
+		return out
+	}
+	left, errLeft := json.Marshal(strip(a))
+	right, errRight := json.Marshal(strip(b))
+	return errLeft == nil && errRight == nil && jsonEqual(left, right)
+}
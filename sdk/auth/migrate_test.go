@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+)
+
+func newMigrateStore(t *testing.T, files map[string]string) (*FileTokenStore, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	store := NewFileTokenStore()
+	store.SetBaseDir(dir)
+	return store, dir
+}
+
+func TestMigrateTokensVerifiesBeforeRemovingSource(t *testing.T) {
+	from, fromDir := newMigrateStore(t, map[string]string{
+		"claude-a.json": `{"type":"claude","email":"a@example.com","refresh_token":"r1"}`,
+		"codex-b.json":  `{"type":"codex","email":"b@example.com","refresh_token":"r2"}`,
+	})
+	to, toDir := newMigrateStore(t, nil)
+
+	failing := func(_ context.Context, auth *cliproxyauth.Auth) error {
+		if auth.Provider == "codex" {
+			return errors.New("token rejected")
+		}
+		return nil
+	}
+	results, err := MigrateTokens(context.Background(), from, to, MigrateOptions{RemoveSource: true, Probe: failing})
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %+v", results)
+	}
+	for _, result := range results {
+		switch result.ID {
+		case "claude-a.json":
+			if result.Err != nil || !result.Removed {
+				t.Fatalf("claude result = %+v", result)
+			}
+		case "codex-b.json":
+			if result.Err == nil || result.Removed {
+				t.Fatalf("codex result = %+v", result)
+			}
+		}
+	}
+	if _, errStat := os.Stat(filepath.Join(toDir, "claude-a.json")); errStat != nil {
+		t.Fatalf("migrated file missing: %v", errStat)
+	}
+	if _, errStat := os.Stat(filepath.Join(fromDir, "claude-a.json")); !os.IsNotExist(errStat) {
+		t.Fatalf("verified source copy should be removed, stat err = %v", errStat)
+	}
+	if _, errStat := os.Stat(filepath.Join(fromDir, "codex-b.json")); errStat != nil {
+		t.Fatalf("source copy of a failed probe must be kept: %v", errStat)
+	}
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/auth/oauthcallback"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// callbackOptions returns the callback server options for a login on port. Port fallback is only
+// enabled when the provider's redirect URI follows the bound port; providers with a registered
+// fixed redirect URI must get exactly that port.
+func callbackOptions(cfg *config.Config, port int, followsPort bool, path, state string) oauthcallback.Options {
+	opts := oauthcallback.Options{Port: port, Path: path, State: state}
+	if cfg != nil {
+		opts.Host = strings.TrimSpace(cfg.OAuthCallback.Host)
+		if followsPort {
+			opts.FallbackPorts = cfg.OAuthCallback.PortRange
+		}
+	}
+	return opts
+}
+
+// stopCallbackServer shuts srv down, logging failures under provider.
+func stopCallbackServer(provider string, srv *oauthcallback.Server) {
+	stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := srv.Stop(stopCtx); err != nil {
+		log.Warnf("%s oauth server stop error: %v", provider, err)
+	}
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+// accountCapLevel is how far a credential is into its routing.account-caps limits.
+type accountCapLevel int
+
+const (
+	accountCapNone accountCapLevel = iota
+	accountCapSoft
+	accountCapHard
+)
+
+// accountCapLedger counts the usage of each auth within its current usage window.
+type accountCapLedger struct {
+	mu      sync.Mutex
+	windows map[string]*accountCapWindow
+}
+
+type accountCapWindow struct {
+	resetAt  time.Time
+	tokens   int64
+	requests int64
+	level    accountCapLevel
+}
+
+// accountCapSchedule describes when the usage window of a credential resets.
+type accountCapSchedule struct {
+	// rolling is the length of a window that starts with the first request; 0 means daily resets.
+	rolling time.Duration
+	// dailyAt is the time of day of daily resets in location.
+	dailyAt  time.Duration
+	location *time.Location
+}
+
+// defaultAccountCapWindows models the usage windows of providers with known reset schedules.
+var defaultAccountCapWindows = map[string]struct{ window, timezone string }{
+	"claude":      {window: "5h"},
+	"codex":       {window: "5h"},
+	"gemini-cli":  {window: "daily", timezone: "America/Los_Angeles"},
+	"antigravity": {window: "daily", timezone: "America/Los_Angeles"},
+}
+
+// accountCapFor returns the first account-caps entry that applies to auth, or nil.
+func accountCapFor(cfg *internalconfig.Config, auth *Auth) *internalconfig.AccountCap {
+	if cfg == nil || auth == nil {
+		return nil
+	}
+	for i := range cfg.Routing.AccountCaps {
+		rule := &cfg.Routing.AccountCaps[i]
+		if provider := strings.TrimSpace(rule.Provider); provider != "" && !strings.EqualFold(provider, auth.Provider) {
+			continue
+		}
+		if len(rule.Auths) > 0 && !accountCapMatchesAuth(rule.Auths, auth) {
+			continue
+		}
+		if !auth.MatchesLabels(rule.Labels) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+func accountCapMatchesAuth(names []string, auth *Auth) bool {
+	email := ""
+	if auth.Metadata != nil {
+		email, _ = auth.Metadata["email"].(string)
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if strings.EqualFold(name, auth.ID) || strings.EqualFold(name, auth.Label) || strings.EqualFold(name, strings.TrimSpace(email)) {
+			return true
+		}
+	}
+	return false
+}
+
+// accountCapScheduleFor resolves the reset schedule of rule for a credential of provider.
+// Unparsable windows fall back to daily resets.
+func accountCapScheduleFor(rule *internalconfig.AccountCap, provider string) accountCapSchedule {
+	window := strings.ToLower(strings.TrimSpace(rule.Window))
+	timezone := strings.TrimSpace(rule.Timezone)
+	if window == "" {
+		defaults, ok := defaultAccountCapWindows[strings.ToLower(strings.TrimSpace(provider))]
+		if !ok {
+			defaults.window = "daily"
+		}
+		window = defaults.window
+		if timezone == "" {
+			timezone = defaults.timezone
+		}
+	}
+
+	schedule := accountCapSchedule{location: time.UTC}
+	if timezone != "" {
+		if location, err := time.LoadLocation(timezone); err == nil {
+			schedule.location = location
+		} else {
+			log.Debugf("account-caps: unknown timezone %q, using UTC", timezone)
+		}
+	}
+	if at, ok := strings.CutPrefix(window, "daily"); ok {
+		if at = strings.TrimPrefix(at, "@"); at != "" {
+			if parsed, err := time.Parse("15:04", at); err == nil {
+				schedule.dailyAt = time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute
+			} else {
+				log.Debugf("account-caps: invalid daily reset time %q, using midnight", at)
+			}
+		}
+		return schedule
+	}
+	if parsed, err := time.ParseDuration(window); err == nil && parsed > 0 {
+		schedule.rolling = parsed
+	} else {
+		log.Debugf("account-caps: invalid window %q, using daily resets", window)
+	}
+	return schedule
+}
+
+// nextReset returns when a window opened at now resets.
+func (s accountCapSchedule) nextReset(now time.Time) time.Time {
+	if s.rolling > 0 {
+		return now.Add(s.rolling)
+	}
+	local := now.In(s.location)
+	hour, minute := int(s.dailyAt/time.Hour), int(s.dailyAt%time.Hour/time.Minute)
+	reset := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, s.location)
+	if !reset.After(now) {
+		reset = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, s.location)
+	}
+	return reset
+}
+
+// accountCapLevelOf returns the cap level reached by the given usage.
+func accountCapLevelOf(rule *internalconfig.AccountCap, tokens, requests int64) accountCapLevel {
+	switch {
+	case rule.HardTokens > 0 && tokens >= rule.HardTokens, rule.HardRequests > 0 && requests >= rule.HardRequests:
+		return accountCapHard
+	case rule.SoftTokens > 0 && tokens >= rule.SoftTokens, rule.SoftRequests > 0 && requests >= rule.SoftRequests:
+		return accountCapSoft
+	default:
+		return accountCapNone
+	}
+}
+
+// charge adds usage to the current window of authID and returns the level before and after it.
+func (l *accountCapLedger) charge(authID string, rule *internalconfig.AccountCap, schedule accountCapSchedule, tokens, requests int64, now time.Time) (before, after accountCapLevel, resetAt time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.windows == nil {
+		l.windows = make(map[string]*accountCapWindow)
+	}
+	window, ok := l.windows[authID]
+	if !ok || !now.Before(window.resetAt) {
+		window = &accountCapWindow{resetAt: schedule.nextReset(now)}
+		l.windows[authID] = window
+	}
+	before = window.level
+	window.tokens += tokens
+	window.requests += requests
+	window.level = accountCapLevelOf(rule, window.tokens, window.requests)
+	return before, window.level, window.resetAt
+}
+
+// level returns the cap level of authID under rule and when its window resets.
+func (l *accountCapLedger) level(authID string, rule *internalconfig.AccountCap, now time.Time) (accountCapLevel, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	window, ok := l.windows[authID]
+	if !ok || !now.Before(window.resetAt) {
+		return accountCapNone, time.Time{}
+	}
+	// Re-evaluate against the current rule so config changes apply immediately.
+	return accountCapLevelOf(rule, window.tokens, window.requests), window.resetAt
+}
+
+// accountCapLevel reports whether auth is past a soft or hard account cap and when its usage
+// window resets.
+func (m *Manager) accountCapLevel(cfg *internalconfig.Config, auth *Auth, now time.Time) (accountCapLevel, time.Time) {
+	rule := accountCapFor(cfg, auth)
+	if rule == nil {
+		return accountCapNone, time.Time{}
+	}
+	return m.accountCaps.level(auth.ID, rule, now)
+}
+
+// HandleUsage implements usage.Plugin. It charges the usage records of the manager's credentials
+// against routing.account-caps; failed requests only count their tokens.
+func (m *Manager) HandleUsage(_ context.Context, record usage.Record) {
+	if m == nil || record.AuthID == "" {
+		return
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || len(cfg.Routing.AccountCaps) == 0 {
+		return
+	}
+	m.mu.RLock()
+	auth := m.auths[record.AuthID]
+	var rule *internalconfig.AccountCap
+	var provider, name string
+	if auth != nil {
+		rule = accountCapFor(cfg, auth)
+		provider = auth.Provider
+		name = auth.Label
+		if name == "" {
+			name = auth.ID
+		}
+	}
+	m.mu.RUnlock()
+	if rule == nil {
+		return
+	}
+
+	tokens := record.Detail.TotalTokens
+	if tokens <= 0 {
+		tokens = record.Detail.InputTokens + record.Detail.OutputTokens + record.Detail.ReasoningTokens
+	}
+	var requests int64
+	if !record.Failed {
+		requests = 1
+	}
+	schedule := accountCapScheduleFor(rule, provider)
+	before, after, resetAt := m.accountCaps.charge(record.AuthID, rule, schedule, tokens, requests, time.Now())
+	if after <= before {
+		return
+	}
+	switch after {
+	case accountCapHard:
+		log.Infof("account-caps: %s credential %s reached its hard cap, out of rotation until %s", provider, name, resetAt.Format(time.RFC3339))
+	case accountCapSoft:
+		log.Infof("account-caps: %s credential %s reached its soft cap, used only as a fallback until %s", provider, name, resetAt.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+func TestAccountCapsRotateCredentials(t *testing.T) {
+	m := newConcurrencyManager(t, internalconfig.RoutingConfig{AccountCaps: []internalconfig.AccountCap{
+		{Provider: "claude", SoftTokens: 100, HardTokens: 200},
+	}})
+	ctx := context.Background()
+	pick := func() (*Auth, error) {
+		auth, _, _, err := m.pickNextMixedOnce(ctx, []string{"claude"}, "", cliproxyexecutor.Options{}, map[string]struct{}{})
+		return auth, err
+	}
+
+	m.HandleUsage(ctx, usage.Record{AuthID: "a", Detail: usage.Detail{TotalTokens: 150}})
+	for i := 0; i < 3; i++ {
+		if auth, err := pick(); err != nil || auth.ID != "b" {
+			t.Fatalf("pick %d = %v, %v; want b while a is past its soft cap", i, auth, err)
+		}
+	}
+
+	m.HandleUsage(ctx, usage.Record{AuthID: "b", Detail: usage.Detail{TotalTokens: 250}})
+	if auth, err := pick(); err != nil || auth.ID != "a" {
+		t.Fatalf("pick = %v, %v; want soft-capped a as the fallback", auth, err)
+	}
+
+	m.HandleUsage(ctx, usage.Record{AuthID: "a", Detail: usage.Detail{TotalTokens: 50}})
+	_, err := pick()
+	var cooldown *modelCooldownError
+	if err == nil {
+		t.Fatal("expected an error once every credential is past its hard cap")
+	}
+	if cooldown, _ = err.(*modelCooldownError); cooldown == nil || cooldown.resetIn <= 4*time.Hour {
+		t.Fatalf("error = %v, want a cooldown until the 5h claude window resets", err)
+	}
+}
+
+func TestAccountCapScheduleDailyReset(t *testing.T) {
+	schedule := accountCapScheduleFor(&internalconfig.AccountCap{Window: "daily@08:30", Timezone: "UTC"}, "claude")
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	if got, want := schedule.nextReset(now), time.Date(2025, 3, 2, 8, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("nextReset = %v, want %v", got, want)
+	}
+	if got := accountCapScheduleFor(&internalconfig.AccountCap{}, "claude").nextReset(now); !got.Equal(now.Add(5 * time.Hour)) {
+		t.Fatalf("claude default window reset = %v, want 5h rolling", got)
+	}
+}
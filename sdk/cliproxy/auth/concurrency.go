@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// errAllAuthsBusy is returned by pickNextMixedOnce when every candidate is at its concurrency cap.
+var errAllAuthsBusy = &Error{
+	Code:       "auth_concurrency_exhausted",
+	Message:    "all credentials are at their concurrent request limit",
+	Retryable:  true,
+	HTTPStatus: http.StatusTooManyRequests,
+}
+
+// concurrencyLimiter counts in-flight requests per auth.
+type concurrencyLimiter struct {
+	mu       sync.Mutex
+	inflight map[string]int
+	// released is closed and replaced whenever a slot frees up, waking waiting requests.
+	released chan struct{}
+}
+
+func (l *concurrencyLimiter) full(authID string, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight[authID] >= limit
+}
+
+func (l *concurrencyLimiter) tryAcquire(authID string, limit int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit > 0 && l.inflight[authID] >= limit {
+		return false
+	}
+	if l.inflight == nil {
+		l.inflight = make(map[string]int)
+	}
+	l.inflight[authID]++
+	return true
+}
+
+func (l *concurrencyLimiter) release(authID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n := l.inflight[authID]; n > 1 {
+		l.inflight[authID] = n - 1
+	} else {
+		delete(l.inflight, authID)
+	}
+	if l.released != nil {
+		close(l.released)
+		l.released = nil
+	}
+}
+
+// wait returns a channel closed on the next release.
+func (l *concurrencyLimiter) wait() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released == nil {
+		l.released = make(chan struct{})
+	}
+	return l.released
+}
+
+// concurrencyLimit returns the concurrent request cap of auth; 0 means unlimited.
+func concurrencyLimit(cfg *internalconfig.Config, auth *Auth) int {
+	if cfg == nil || auth == nil {
+		return 0
+	}
+	if rule := labelRuleFor(cfg, auth, labelRuleSetsConcurrency); rule != nil {
+		return max(*rule.MaxConcurrency, 0)
+	}
+	if limit, ok := cfg.Routing.ProviderMaxConcurrency[strings.ToLower(strings.TrimSpace(auth.Provider))]; ok {
+		return max(limit, 0)
+	}
+	return max(cfg.Routing.MaxConcurrencyPerAuth, 0)
+}
+
+func concurrencyLimited(cfg *internalconfig.Config) bool {
+	if cfg == nil {
+		return false
+	}
+	if cfg.Routing.MaxConcurrencyPerAuth > 0 {
+		return true
+	}
+	for _, limit := range cfg.Routing.ProviderMaxConcurrency {
+		if limit > 0 {
+			return true
+		}
+	}
+	for _, rule := range cfg.Routing.LabelRules {
+		if rule.MaxConcurrency != nil && *rule.MaxConcurrency > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// concurrencyWait returns how long a request waits for a free credential when all are busy.
+func concurrencyWait(cfg *internalconfig.Config) time.Duration {
+	if cfg == nil {
+		return 0
+	}
+	if raw := strings.TrimSpace(cfg.Routing.ConcurrencyWait); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return 0
+}
+
+// pickNextMixed picks an auth with a free concurrency slot and takes the slot; callers release it
+// with releaseAuth once the request finished. When every candidate is busy it waits up to
+// routing.concurrency-wait for a slot before failing with errAllAuthsBusy.
+func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	var deadline <-chan time.Time
+	limited := concurrencyLimited(cfg)
+	for {
+		var released <-chan struct{}
+		if limited {
+			released = m.concurrency.wait()
+		}
+		auth, executor, provider, err := m.pickNextMixedOnce(ctx, providers, model, opts, tried)
+		if err == nil {
+			if m.concurrency.tryAcquire(auth.ID, concurrencyLimit(cfg, auth)) {
+				return auth, executor, provider, nil
+			}
+			// Another request took the last slot between the pick and the acquire.
+			continue
+		}
+		if !errors.Is(err, errAllAuthsBusy) {
+			return nil, nil, "", err
+		}
+		if deadline == nil {
+			wait := concurrencyWait(cfg)
+			if wait <= 0 {
+				return nil, nil, "", err
+			}
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		select {
+		case <-released:
+		case <-deadline:
+			return nil, nil, "", err
+		case <-ctx.Done():
+			return nil, nil, "", ctx.Err()
+		}
+	}
+}
+
+// releaseAuth frees the concurrency slot taken by pickNextMixed.
+func (m *Manager) releaseAuth(authID string) {
+	m.concurrency.release(authID)
+}
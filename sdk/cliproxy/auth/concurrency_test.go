@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+type stubExecutor struct{ provider string }
+
+func (e stubExecutor) Identifier() string { return e.provider }
+
+func (stubExecutor) Execute(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func (stubExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	return nil, nil
+}
+
+func (stubExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (stubExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, nil
+}
+
+func (stubExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, nil
+}
+
+func newConcurrencyManager(t *testing.T, routing internalconfig.RoutingConfig) *Manager {
+	t.Helper()
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	m.SetConfig(&internalconfig.Config{Routing: routing})
+	m.RegisterExecutor(stubExecutor{provider: "claude"})
+	for _, id := range []string{"a", "b"} {
+		if _, err := m.Register(context.Background(), &Auth{ID: id, Provider: "claude"}); err != nil {
+			t.Fatalf("Register(%s) error = %v", id, err)
+		}
+	}
+	return m
+}
+
+func TestPickNextMixed_ConcurrencyOverflow(t *testing.T) {
+	m := newConcurrencyManager(t, internalconfig.RoutingConfig{ProviderMaxConcurrency: map[string]int{"claude": 1}})
+	ctx := context.Background()
+
+	first, _, _, err := m.pickNextMixed(ctx, []string{"claude"}, "", cliproxyexecutor.Options{}, map[string]struct{}{})
+	if err != nil {
+		t.Fatalf("first pick error = %v", err)
+	}
+	second, _, _, err := m.pickNextMixed(ctx, []string{"claude"}, "", cliproxyexecutor.Options{}, map[string]struct{}{})
+	if err != nil {
+		t.Fatalf("second pick error = %v", err)
+	}
+	if first.ID == second.ID {
+		t.Fatalf("second request reused busy auth %q", first.ID)
+	}
+	if _, _, _, err = m.pickNextMixed(ctx, []string{"claude"}, "", cliproxyexecutor.Options{}, map[string]struct{}{}); !errors.Is(err, errAllAuthsBusy) {
+		t.Fatalf("third pick error = %v, want errAllAuthsBusy", err)
+	}
+
+	m.releaseAuth(first.ID)
+	third, _, _, err := m.pickNextMixed(ctx, []string{"claude"}, "", cliproxyexecutor.Options{}, map[string]struct{}{})
+	if err != nil || third.ID != first.ID {
+		t.Fatalf("pick after release = %v, %v; want %q", third, err, first.ID)
+	}
+}
+
+func TestPickNextMixed_ConcurrencyWait(t *testing.T) {
+	m := newConcurrencyManager(t, internalconfig.RoutingConfig{MaxConcurrencyPerAuth: 1, ConcurrencyWait: "2s"})
+	ctx := context.Background()
+	var held []string
+	for i := 0; i < 2; i++ {
+		auth, _, _, err := m.pickNextMixed(ctx, []string{"claude"}, "", cliproxyexecutor.Options{}, map[string]struct{}{})
+		if err != nil {
+			t.Fatalf("pick %d error = %v", i, err)
+		}
+		held = append(held, auth.ID)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		m.releaseAuth(held[1])
+	}()
+	auth, _, _, err := m.pickNextMixed(ctx, []string{"claude"}, "", cliproxyexecutor.Options{}, map[string]struct{}{})
+	if err != nil {
+		t.Fatalf("waiting pick error = %v", err)
+	}
+	if auth.ID != held[1] {
+		t.Fatalf("waiting pick got %q, want released %q", auth.ID, held[1])
+	}
+}
@@ -139,6 +139,15 @@ type Manager struct {
 	// It is initialized in NewManager; never Load() before first Store().
 	runtimeConfig atomic.Value
 
+	// sessionAffinity binds conversations to auths when routing.session-affinity is enabled.
+	sessionAffinity sessionAffinityTable
+
+	// concurrency counts in-flight requests per auth for the routing concurrency caps.
+	concurrency concurrencyLimiter
+
+	// accountCaps counts per-auth usage for routing.account-caps.
+	accountCaps accountCapLedger
+
 	// Optional HTTP RoundTripper provider injected by host.
 	rtProvider RoundTripperProvider
 
@@ -301,6 +310,12 @@ func (m *Manager) rebuildAPIKeyModelAliasLocked(cfg *internalconfig.Config) {
 				compileAPIKeyModelAliasForModels(byAlias, entry.Models)
 			}
 		default:
+			if keyProvider, ok := internalconfig.LookupAPIKeyProvider(provider); ok {
+				if entry := resolveProviderKeyConfig(cfg, keyProvider, auth); entry != nil {
+					compileAPIKeyModelAliasForModels(byAlias, entry.Models)
+				}
+				break
+			}
 			// OpenAI-compat uses config selection from auth.Attributes.
 			providerKey := ""
 			compatName := ""
@@ -579,6 +594,7 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 			return cliproxyexecutor.Response{}, errPick
 		}
 
+		logging.SetRequestRoute(ctx, provider, req.Model, auth.EnsureIndex())
 		entry := logEntryWithRequestID(ctx)
 		debugLogAuthSelection(entry, auth, provider, req.Model)
 
@@ -593,6 +609,7 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 		execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
 		execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
 		resp, errExec := executor.Execute(execCtx, auth, execReq, opts)
+		m.releaseAuth(auth.ID)
 		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil}
 		if errExec != nil {
 			if errCtx := execCtx.Err(); errCtx != nil {
@@ -632,6 +649,7 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 			return cliproxyexecutor.Response{}, errPick
 		}
 
+		logging.SetRequestRoute(ctx, provider, req.Model, auth.EnsureIndex())
 		entry := logEntryWithRequestID(ctx)
 		debugLogAuthSelection(entry, auth, provider, req.Model)
 
@@ -646,6 +664,7 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 		execReq.Model = m.applyOAuthModelAlias(auth, execReq.Model)
 		execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
 		resp, errExec := executor.CountTokens(execCtx, auth, execReq, opts)
+		m.releaseAuth(auth.ID)
 		result := Result{AuthID: auth.ID, Provider: provider, Model: routeModel, Success: errExec == nil}
 		if errExec != nil {
 			if errCtx := execCtx.Err(); errCtx != nil {
@@ -685,6 +704,7 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 			return nil, errPick
 		}
 
+		logging.SetRequestRoute(ctx, provider, req.Model, auth.EnsureIndex())
 		entry := logEntryWithRequestID(ctx)
 		debugLogAuthSelection(entry, auth, provider, req.Model)
 
@@ -700,6 +720,7 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 		execReq.Model = m.applyAPIKeyModelAlias(auth, execReq.Model)
 		chunks, errStream := executor.ExecuteStream(execCtx, auth, execReq, opts)
 		if errStream != nil {
+			m.releaseAuth(auth.ID)
 			if errCtx := execCtx.Err(); errCtx != nil {
 				return nil, errCtx
 			}
@@ -717,6 +738,7 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 		out := make(chan cliproxyexecutor.StreamChunk)
 		go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, streamChunks <-chan cliproxyexecutor.StreamChunk) {
 			defer close(out)
+			defer m.releaseAuth(streamAuth.ID)
 			var failed bool
 			forward := true
 			for chunk := range streamChunks {
@@ -843,6 +865,10 @@ func (m *Manager) applyAPIKeyModelAlias(auth *Auth, requestedModel string) strin
 	case "vertex":
 		upstreamModel = resolveUpstreamModelForVertexAPIKey(cfg, auth, requestedModel)
 	default:
+		if keyProvider, ok := internalconfig.LookupAPIKeyProvider(provider); ok {
+			upstreamModel = resolveUpstreamModelForProviderKey(cfg, keyProvider, auth, requestedModel)
+			break
+		}
 		upstreamModel = resolveUpstreamModelForOpenAICompatAPIKey(cfg, auth, requestedModel)
 	}
 
@@ -926,6 +952,13 @@ func resolveVertexAPIKeyConfig(cfg *internalconfig.Config, auth *Auth) *internal
 	return resolveAPIKeyConfig(cfg.VertexCompatAPIKey, auth)
 }
 
+func resolveProviderKeyConfig(cfg *internalconfig.Config, provider internalconfig.APIKeyProvider, auth *Auth) *internalconfig.ProviderKey {
+	if cfg == nil {
+		return nil
+	}
+	return resolveAPIKeyConfig(*provider.Keys(cfg), auth)
+}
+
 func resolveUpstreamModelForGeminiAPIKey(cfg *internalconfig.Config, auth *Auth, requestedModel string) string {
 	entry := resolveGeminiAPIKeyConfig(cfg, auth)
 	if entry == nil {
@@ -958,6 +991,14 @@ func resolveUpstreamModelForVertexAPIKey(cfg *internalconfig.Config, auth *Auth,
 	return resolveModelAliasFromConfigModels(requestedModel, asModelAliasEntries(entry.Models))
 }
 
+func resolveUpstreamModelForProviderKey(cfg *internalconfig.Config, provider internalconfig.APIKeyProvider, auth *Auth, requestedModel string) string {
+	entry := resolveProviderKeyConfig(cfg, provider, auth)
+	if entry == nil {
+		return ""
+	}
+	return resolveModelAliasFromConfigModels(requestedModel, asModelAliasEntries(entry.Models))
+}
+
 func resolveUpstreamModelForOpenAICompatAPIKey(cfg *internalconfig.Config, auth *Auth, requestedModel string) string {
 	providerKey := ""
 	compatName := ""
@@ -1100,6 +1141,58 @@ func (m *Manager) closestCooldownWait(providers []string, model string, attempt
 	return minWait, found
 }
 
+// ModelAvailability reports whether any auth of the given providers can currently serve model.
+// When every matching auth is cooling down it returns false and the time until the first one
+// recovers. Providers without matching auths report true so the normal routing error surfaces.
+func (m *Manager) ModelAvailability(providers []string, model string) (bool, time.Duration) {
+	if m == nil || len(providers) == 0 {
+		return true, 0
+	}
+	providerSet := make(map[string]struct{}, len(providers))
+	for _, provider := range providers {
+		if key := strings.TrimSpace(strings.ToLower(provider)); key != "" {
+			providerSet[key] = struct{}{}
+		}
+	}
+	modelKey := strings.TrimSpace(model)
+	if modelKey != "" {
+		if parsed := thinking.ParseSuffix(modelKey); parsed.ModelName != "" {
+			modelKey = strings.TrimSpace(parsed.ModelName)
+		}
+	}
+	registryRef := registry.GetGlobalRegistry()
+	now := time.Now()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var earliest time.Time
+	for _, auth := range m.auths {
+		if auth == nil || auth.Disabled {
+			continue
+		}
+		if _, ok := providerSet[strings.TrimSpace(strings.ToLower(auth.Provider))]; !ok {
+			continue
+		}
+		if modelKey != "" && registryRef != nil && !registryRef.ClientSupportsModel(auth.ID, modelKey) {
+			continue
+		}
+		blocked, reason, next := isAuthBlockedForModel(auth, model, now)
+		if !blocked {
+			return true, 0
+		}
+		if reason == blockReasonDisabled || next.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || next.Before(earliest) {
+			earliest = next
+		}
+	}
+	if earliest.IsZero() {
+		return true, 0
+	}
+	return false, earliest.Sub(now)
+}
+
 func (m *Manager) shouldRetryAfterError(err error, attempt int, providers []string, model string, maxWait time.Duration) (time.Duration, bool) {
 	if err == nil {
 		return 0, false
@@ -1561,7 +1654,12 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		m.mu.RUnlock()
 		return nil, nil, &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
-	selected, errPick := m.selector.Pick(ctx, provider, model, opts, candidates)
+	if candidates = restrictToLabels(candidates, opts); len(candidates) == 0 {
+		m.mu.RUnlock()
+		return nil, nil, &Error{Code: "auth_not_found", Message: "no auth with the requested labels available"}
+	}
+	candidates = preferQuotaHeadroom(candidates, opts, time.Now())
+	selected, errPick := m.pickAuth(ctx, provider, model, opts, candidates)
 	if errPick != nil {
 		m.mu.RUnlock()
 		return nil, nil, errPick
@@ -1583,7 +1681,7 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 	return authCopy, executor, nil
 }
 
-func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, error) {
+func (m *Manager) pickNextMixedOnce(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, error) {
 	providerSet := make(map[string]struct{}, len(providers))
 	for _, provider := range providers {
 		p := strings.TrimSpace(strings.ToLower(provider))
@@ -1607,6 +1705,11 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 		}
 	}
 	registryRef := registry.GetGlobalRegistry()
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	busy := false
+	now := time.Now()
+	var softCapped []*Auth
+	var capResetAt time.Time
 	for _, candidate := range m.auths {
 		if candidate == nil || candidate.Disabled {
 			continue
@@ -1627,13 +1730,44 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 		if modelKey != "" && registryRef != nil && !registryRef.ClientSupportsModel(candidate.ID, modelKey) {
 			continue
 		}
+		if m.concurrency.full(candidate.ID, concurrencyLimit(cfg, candidate)) {
+			busy = true
+			continue
+		}
+		switch level, resetAt := m.accountCapLevel(cfg, candidate, now); level {
+		case accountCapHard:
+			if capResetAt.IsZero() || resetAt.Before(capResetAt) {
+				capResetAt = resetAt
+			}
+			continue
+		case accountCapSoft:
+			softCapped = append(softCapped, candidate)
+			continue
+		}
 		candidates = append(candidates, candidate)
 	}
+	if len(candidates) == 0 {
+		// Credentials past a soft cap are only used once nothing else is left.
+		candidates = softCapped
+	}
 	if len(candidates) == 0 {
 		m.mu.RUnlock()
+		if busy {
+			return nil, nil, "", errAllAuthsBusy
+		}
+		if !capResetAt.IsZero() {
+			return nil, nil, "", newModelCooldownError(model, "", capResetAt.Sub(now))
+		}
 		return nil, nil, "", &Error{Code: "auth_not_found", Message: "no auth available"}
 	}
-	selected, errPick := m.selector.Pick(ctx, "mixed", model, opts, candidates)
+	if candidates = restrictToLabels(candidates, opts); len(candidates) == 0 {
+		m.mu.RUnlock()
+		return nil, nil, "", &Error{Code: "auth_not_found", Message: "no auth with the requested labels available"}
+	}
+	candidates = preferHealthyProviders(candidates, model, now)
+	candidates = preferProviderOrder(candidates, model, opts, now)
+	candidates = preferQuotaHeadroom(candidates, opts, now)
+	selected, errPick := m.pickAuth(ctx, "mixed", model, opts, candidates)
 	if errPick != nil {
 		m.mu.RUnlock()
 		return nil, nil, "", errPick
@@ -1972,7 +2106,22 @@ func (m *Manager) markRefreshPending(id string, now time.Time) bool {
 	return true
 }
 
-func (m *Manager) refreshAuth(ctx context.Context, id string) {
+// Refresh refreshes the credentials of the auth with the given ID immediately, regardless of its
+// refresh schedule.
+func (m *Manager) Refresh(ctx context.Context, id string) error {
+	m.mu.RLock()
+	auth := m.auths[id]
+	m.mu.RUnlock()
+	if auth == nil {
+		return &Error{Code: "auth_not_found", Message: "auth not found: " + id}
+	}
+	if m.executorFor(auth.Provider) == nil {
+		return &Error{Code: "executor_not_found", Message: "executor not registered"}
+	}
+	return m.refreshAuth(ctx, id)
+}
+
+func (m *Manager) refreshAuth(ctx context.Context, id string) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -1984,13 +2133,13 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	}
 	m.mu.RUnlock()
 	if auth == nil || exec == nil {
-		return
+		return nil
 	}
 	cloned := auth.Clone()
 	updated, err := exec.Refresh(ctx, cloned)
 	if err != nil && errors.Is(err, context.Canceled) {
 		log.Debugf("refresh canceled for %s, %s", auth.Provider, auth.ID)
-		return
+		return err
 	}
 	log.Debugf("refreshed %s, %s, %v", auth.Provider, auth.ID, err)
 	now := time.Now()
@@ -2002,7 +2151,7 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 			m.auths[id] = current
 		}
 		m.mu.Unlock()
-		return
+		return err
 	}
 	if updated == nil {
 		updated = cloned
@@ -2018,7 +2167,8 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	// If the Authenticator did not set it (zero value), shouldRefresh will use default logic
 	updated.LastError = nil
 	updated.UpdatedAt = now
-	_, _ = m.Update(ctx, updated)
+	_, err = m.Update(ctx, updated)
+	return err
 }
 
 func (m *Manager) executorFor(provider string) ProviderExecutor {
@@ -2069,15 +2219,9 @@ func executorKeyFromAuth(auth *Auth) string {
 	return strings.ToLower(strings.TrimSpace(auth.Provider))
 }
 
-// logEntryWithRequestID returns a logrus entry with request_id field if available in context.
+// logEntryWithRequestID returns a logrus entry with the request_id and correlation fields available in context.
 func logEntryWithRequestID(ctx context.Context) *log.Entry {
-	if ctx == nil {
-		return log.NewEntry(log.StandardLogger())
-	}
-	if reqID := logging.GetRequestID(ctx); reqID != "" {
-		return log.WithField("request_id", reqID)
-	}
-	return log.NewEntry(log.StandardLogger())
+	return logging.RequestEntry(ctx)
 }
 
 func debugLogAuthSelection(entry *log.Entry, auth *Auth, provider string, model string) {
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"strconv"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// labelRuleFor returns the first routing.label-rules entry that matches auth and sets the setting
+// reported by has, or nil.
+func labelRuleFor(cfg *internalconfig.Config, auth *Auth, has func(*internalconfig.LabelRule) bool) *internalconfig.LabelRule {
+	if cfg == nil || auth == nil {
+		return nil
+	}
+	for i := range cfg.Routing.LabelRules {
+		rule := &cfg.Routing.LabelRules[i]
+		if has(rule) && auth.MatchesLabels(rule.Labels) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func labelRuleSetsPriority(rule *internalconfig.LabelRule) bool {
+	return rule.Priority != nil
+}
+
+func labelRuleSetsConcurrency(rule *internalconfig.LabelRule) bool {
+	return rule.MaxConcurrency != nil
+}
+
+// restrictToLabels keeps the candidates carrying the label selector of the request, if it has one.
+// Unlike the prefer* filters it may return no candidates.
+func restrictToLabels(candidates []*Auth, opts cliproxyexecutor.Options) []*Auth {
+	selector, _ := opts.Metadata[cliproxyexecutor.AuthLabelsMetadataKey].(map[string]string)
+	if len(selector) == 0 {
+		return candidates
+	}
+	matching := make([]*Auth, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.MatchesLabels(selector) {
+			matching = append(matching, candidate)
+		}
+	}
+	return matching
+}
+
+// HasAuthWithLabels reports whether an enabled auth carries every label of selector.
+func (m *Manager) HasAuthWithLabels(selector map[string]string) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, auth := range m.auths {
+		if !auth.Disabled && auth.MatchesLabels(selector) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyLabelPriorities returns candidates with the priority of label-rules applied, as copies of
+// the affected auths, and a map from those copies back to the original auths.
+func applyLabelPriorities(cfg *internalconfig.Config, candidates []*Auth) ([]*Auth, map[*Auth]*Auth) {
+	if cfg == nil || len(cfg.Routing.LabelRules) == 0 {
+		return candidates, nil
+	}
+	var out []*Auth
+	var originals map[*Auth]*Auth
+	for i, candidate := range candidates {
+		rule := labelRuleFor(cfg, candidate, labelRuleSetsPriority)
+		if rule == nil || authPriority(candidate) == *rule.Priority {
+			continue
+		}
+		if out == nil {
+			out = append([]*Auth(nil), candidates...)
+			originals = make(map[*Auth]*Auth)
+		}
+		override := candidate.Clone()
+		if override.Attributes == nil {
+			override.Attributes = make(map[string]string, 1)
+		}
+		override.Attributes["priority"] = strconv.Itoa(*rule.Priority)
+		out[i] = override
+		originals[override] = candidate
+	}
+	if out == nil {
+		return candidates, nil
+	}
+	return out, originals
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LabelsMetadataKey is the metadata key that stores the free-form labels of a credential,
+// e.g. {"tier": "pro", "region": "eu"}. Labels persist with the auth file.
+const LabelsMetadataKey = "labels"
+
+// Labels returns the labels of the credential, or nil when it has none.
+func (a *Auth) Labels() map[string]string {
+	if a == nil || a.Metadata == nil {
+		return nil
+	}
+	var labels map[string]string
+	switch raw := a.Metadata[LabelsMetadataKey].(type) {
+	case map[string]string:
+		labels = make(map[string]string, len(raw))
+		for key, value := range raw {
+			labels[key] = value
+		}
+	case map[string]any:
+		labels = make(map[string]string, len(raw))
+		for key, value := range raw {
+			switch v := value.(type) {
+			case string:
+				labels[key] = v
+			case nil:
+			default:
+				labels[key] = fmt.Sprint(v)
+			}
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// SetLabels replaces the labels of the credential. An empty map removes them.
+func (a *Auth) SetLabels(labels map[string]string) {
+	if a == nil {
+		return
+	}
+	if len(labels) == 0 {
+		if a.Metadata != nil {
+			delete(a.Metadata, LabelsMetadataKey)
+		}
+		return
+	}
+	if a.Metadata == nil {
+		a.Metadata = make(map[string]any)
+	}
+	stored := make(map[string]any, len(labels))
+	for key, value := range labels {
+		stored[key] = value
+	}
+	a.Metadata[LabelsMetadataKey] = stored
+}
+
+// MergeLabels adds labels to the credential, overwriting existing values of the same keys.
+// An empty value removes the key.
+func (a *Auth) MergeLabels(labels map[string]string) {
+	if a == nil || len(labels) == 0 {
+		return
+	}
+	merged := a.Labels()
+	if merged == nil {
+		merged = make(map[string]string, len(labels))
+	}
+	for key, value := range labels {
+		if value == "" {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = value
+	}
+	a.SetLabels(merged)
+}
+
+// MatchesLabels reports whether the credential carries every label of selector.
+// Values compare case-insensitively; a "*" value only requires the key to be present.
+func (a *Auth) MatchesLabels(selector map[string]string) bool {
+	if len(selector) == 0 {
+		return true
+	}
+	labels := a.Labels()
+	for key, want := range selector {
+		have, ok := labels[strings.TrimSpace(key)]
+		if !ok {
+			return false
+		}
+		want = strings.TrimSpace(want)
+		if want != "*" && !strings.EqualFold(want, have) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseLabels parses a comma-separated list of key=value pairs such as "tier=pro,region=eu".
+func ParseLabels(spec string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q: expected key=value", pair)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if err := ValidateLabel(key, value); err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// ValidateLabel checks that a label key is non-empty and that neither part contains the
+// separators used by ParseLabels.
+func ValidateLabel(key, value string) error {
+	if key == "" {
+		return fmt.Errorf("label key is empty")
+	}
+	if strings.ContainsAny(key, "=, \t") {
+		return fmt.Errorf("invalid label key %q", key)
+	}
+	if strings.Contains(value, ",") {
+		return fmt.Errorf("invalid value %q for label %q", value, key)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels(" tier=pro, region=eu ,owner=alice,")
+	if err != nil {
+		t.Fatalf("ParseLabels: %v", err)
+	}
+	want := map[string]string{"tier": "pro", "region": "eu", "owner": "alice"}
+	if !reflect.DeepEqual(labels, want) {
+		t.Fatalf("labels = %v, want %v", labels, want)
+	}
+	for _, spec := range []string{"tier", "=pro", "my tier=pro"} {
+		if _, err = ParseLabels(spec); err == nil {
+			t.Fatalf("ParseLabels(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestAuthLabelsSurvivePersistence(t *testing.T) {
+	auth := &Auth{ID: "a"}
+	auth.MergeLabels(map[string]string{"tier": "pro", "region": "eu"})
+	auth.MergeLabels(map[string]string{"region": "", "owner": "alice"})
+
+	raw, err := json.Marshal(auth.Metadata)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	loaded := &Auth{ID: "a"}
+	if err = json.Unmarshal(raw, &loaded.Metadata); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := map[string]string{"tier": "pro", "owner": "alice"}
+	if got := loaded.Labels(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("labels = %v, want %v", got, want)
+	}
+
+	loaded.SetLabels(nil)
+	if _, ok := loaded.Metadata[LabelsMetadataKey]; ok {
+		t.Fatal("SetLabels(nil) kept the labels metadata")
+	}
+}
+
+func TestAuthMatchesLabels(t *testing.T) {
+	auth := &Auth{ID: "a"}
+	auth.SetLabels(map[string]string{"tier": "Pro", "region": "eu"})
+
+	cases := []struct {
+		selector map[string]string
+		want     bool
+	}{
+		{nil, true},
+		{map[string]string{"tier": "pro"}, true},
+		{map[string]string{"tier": "pro", "region": "eu"}, true},
+		{map[string]string{"region": "*"}, true},
+		{map[string]string{"tier": "free"}, false},
+		{map[string]string{"owner": "*"}, false},
+	}
+	for _, tc := range cases {
+		if got := auth.MatchesLabels(tc.selector); got != tc.want {
+			t.Fatalf("MatchesLabels(%v) = %v, want %v", tc.selector, got, tc.want)
+		}
+	}
+}
+
+func TestAccountCapForLabels(t *testing.T) {
+	cfg := &internalconfig.Config{}
+	cfg.Routing.AccountCaps = []internalconfig.AccountCap{
+		{Labels: map[string]string{"tier": "pro"}, HardRequests: 800},
+		{Provider: "claude", HardRequests: 100},
+	}
+	pro := &Auth{ID: "pro", Provider: "claude"}
+	pro.SetLabels(map[string]string{"tier": "pro"})
+	free := &Auth{ID: "free", Provider: "claude"}
+
+	if rule := accountCapFor(cfg, pro); rule == nil || rule.HardRequests != 800 {
+		t.Fatalf("rule for labelled auth = %+v, want the tier=pro entry", rule)
+	}
+	if rule := accountCapFor(cfg, free); rule == nil || rule.HardRequests != 100 {
+		t.Fatalf("rule for unlabelled auth = %+v, want the provider entry", rule)
+	}
+}
+
+func TestLabelRulesPriorityAndConcurrency(t *testing.T) {
+	priority, limit := 10, 1
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	m.SetConfig(&internalconfig.Config{Routing: internalconfig.RoutingConfig{LabelRules: []internalconfig.LabelRule{
+		{Labels: map[string]string{"region": "eu"}, Priority: &priority, MaxConcurrency: &limit},
+	}}})
+	m.RegisterExecutor(stubExecutor{provider: "claude"})
+	eu := &Auth{ID: "eu", Provider: "claude"}
+	eu.SetLabels(map[string]string{"region": "eu"})
+	ctx := context.Background()
+	for _, auth := range []*Auth{eu, {ID: "us", Provider: "claude"}} {
+		if _, err := m.Register(ctx, auth); err != nil {
+			t.Fatalf("Register(%s) error = %v", auth.ID, err)
+		}
+	}
+	pick := func() *Auth {
+		auth, _, _, err := m.pickNextMixed(ctx, []string{"claude"}, "", cliproxyexecutor.Options{}, map[string]struct{}{})
+		if err != nil {
+			t.Fatalf("pick error = %v", err)
+		}
+		return auth
+	}
+
+	first := pick()
+	if first.ID != "eu" {
+		t.Fatalf("first pick = %s, want the higher-priority eu credential", first.ID)
+	}
+	if first.Attributes["priority"] != "" {
+		t.Fatalf("picked auth carries the overridden priority %q", first.Attributes["priority"])
+	}
+	for i := 0; i < 2; i++ {
+		if got := pick(); got.ID != "us" {
+			t.Fatalf("pick %d = %s, want us while eu is at its label concurrency cap", i, got.ID)
+		}
+	}
+	m.releaseAuth(first.ID)
+	if got := pick(); got.ID != "eu" {
+		t.Fatalf("pick after release = %s, want eu", got.ID)
+	}
+}
+
+func TestPickRestrictsToRequestedLabels(t *testing.T) {
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	m.RegisterExecutor(stubExecutor{provider: "claude"})
+	pool := &Auth{ID: "pool", Provider: "claude"}
+	pool.SetLabels(map[string]string{"pool": "canary"})
+	ctx := context.Background()
+	for _, auth := range []*Auth{pool, {ID: "other", Provider: "claude"}} {
+		if _, err := m.Register(ctx, auth); err != nil {
+			t.Fatalf("Register(%s) error = %v", auth.ID, err)
+		}
+	}
+
+	opts := cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.AuthLabelsMetadataKey: map[string]string{"pool": "canary"}}}
+	for i := 0; i < 3; i++ {
+		auth, _, _, err := m.pickNextMixed(ctx, []string{"claude"}, "", opts, map[string]struct{}{})
+		if err != nil || auth.ID != "pool" {
+			t.Fatalf("pick %d = %v, %v; want the labelled credential", i, auth, err)
+		}
+		m.releaseAuth(auth.ID)
+	}
+	if _, _, _, err := m.pickNextMixed(ctx, []string{"claude"}, "", opts, map[string]struct{}{"pool": {}}); err == nil {
+		t.Fatal("pick without a labelled credential left succeeded")
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// preferProviderOrder narrows candidates to the first provider of the client's preferred order
+// that still has a usable credential. Credentials that failed are excluded by the caller, so
+// retries move down the order and finally to the remaining providers.
+func preferProviderOrder(candidates []*Auth, model string, opts cliproxyexecutor.Options, now time.Time) []*Auth {
+	order, _ := opts.Metadata[cliproxyexecutor.ProviderOrderMetadataKey].([]string)
+	if len(order) == 0 {
+		return candidates
+	}
+	for _, provider := range order {
+		preferred := make([]*Auth, 0, len(candidates))
+		usable := false
+		for _, candidate := range candidates {
+			if !strings.EqualFold(candidate.Provider, provider) {
+				continue
+			}
+			preferred = append(preferred, candidate)
+			if blocked, _, _ := isAuthBlockedForModel(candidate, model, now); !blocked {
+				usable = true
+			}
+		}
+		if usable {
+			return preferred
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestPreferProviderOrder(t *testing.T) {
+	now := time.Now()
+	claude := &Auth{ID: "c", Provider: "claude"}
+	gemini := &Auth{ID: "g", Provider: "gemini-cli"}
+	codex := &Auth{ID: "x", Provider: "codex"}
+	opts := cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.ProviderOrderMetadataKey: []string{"gemini-cli", "claude"}}}
+
+	if got := preferProviderOrder([]*Auth{claude, gemini, codex}, "m", opts, now); len(got) != 1 || got[0] != gemini {
+		t.Fatalf("expected the first ordered provider, got %d candidates", len(got))
+	}
+
+	gemini.ModelStates = map[string]*ModelState{"m": {Unavailable: true, NextRetryAfter: now.Add(time.Minute)}}
+	if got := preferProviderOrder([]*Auth{claude, gemini, codex}, "m", opts, now); len(got) != 1 || got[0] != claude {
+		t.Fatalf("expected the next ordered provider while the first is cooling down")
+	}
+	if got := preferProviderOrder([]*Auth{codex}, "m", opts, now); len(got) != 1 || got[0] != codex {
+		t.Fatalf("expected unlisted providers once the ordered ones are exhausted")
+	}
+	if got := preferProviderOrder([]*Auth{claude, codex}, "m", cliproxyexecutor.Options{}, now); len(got) != 2 {
+		t.Fatalf("requests without an order must not be filtered")
+	}
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/providerstatus"
+)
+
+// providerDegraded reports whether a provider has an active major incident on its status page.
+// It is a variable so tests can simulate incidents.
+var providerDegraded = providerstatus.Degraded
+
+// preferHealthyProviders drops candidates of providers with an ongoing incident when another
+// provider still has a usable candidate, so mixed routing fails over before errors pile up.
+func preferHealthyProviders(candidates []*Auth, model string, now time.Time) []*Auth {
+	healthy := make([]*Auth, 0, len(candidates))
+	usable := false
+	degraded := false
+	for _, candidate := range candidates {
+		if providerDegraded(candidate.Provider) {
+			degraded = true
+			continue
+		}
+		healthy = append(healthy, candidate)
+		if blocked, _, _ := isAuthBlockedForModel(candidate, model, now); !blocked {
+			usable = true
+		}
+	}
+	if !degraded || !usable {
+		return candidates
+	}
+	return healthy
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPreferHealthyProviders(t *testing.T) {
+	original := providerDegraded
+	providerDegraded = func(provider string) bool { return provider == "claude" }
+	defer func() { providerDegraded = original }()
+
+	now := time.Now()
+	claude := &Auth{ID: "c", Provider: "claude"}
+	vertex := &Auth{ID: "v", Provider: "vertex"}
+
+	got := preferHealthyProviders([]*Auth{claude, vertex}, "m", now)
+	if len(got) != 1 || got[0] != vertex {
+		t.Fatalf("expected only the healthy provider, got %d candidates", len(got))
+	}
+
+	vertex.ModelStates = map[string]*ModelState{"m": {Unavailable: true, NextRetryAfter: now.Add(time.Minute)}}
+	if got = preferHealthyProviders([]*Auth{claude, vertex}, "m", now); len(got) != 2 {
+		t.Fatalf("expected degraded provider to stay when no healthy candidate is usable")
+	}
+}
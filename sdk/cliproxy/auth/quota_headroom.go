@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/quotawindow"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// batchQuotaHeadroom is the remaining share of its tightest usage window a credential needs to
+// take batch traffic while others are short on quota.
+const batchQuotaHeadroom = 0.2
+
+// quotaHeadroom reports the remaining share of the usage windows of an auth.
+// It is a variable so tests can simulate usage windows.
+var quotaHeadroom = quotawindow.Headroom
+
+// preferQuotaHeadroom keeps batch requests off credentials that are close to exhausting a
+// subscription usage window, so interactive traffic keeps their remaining quota. Credentials
+// without tracked windows count as having headroom. Other requests are not affected.
+func preferQuotaHeadroom(candidates []*Auth, opts cliproxyexecutor.Options, now time.Time) []*Auth {
+	if batch, _ := opts.Metadata[cliproxyexecutor.BatchMetadataKey].(bool); !batch {
+		return candidates
+	}
+	roomy := make([]*Auth, 0, len(candidates))
+	for _, candidate := range candidates {
+		if remaining, _, known := quotaHeadroom(candidate.ID, now); known && remaining < batchQuotaHeadroom {
+			continue
+		}
+		roomy = append(roomy, candidate)
+	}
+	if len(roomy) == 0 {
+		return candidates
+	}
+	return roomy
+}
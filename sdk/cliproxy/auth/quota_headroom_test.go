@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestPreferQuotaHeadroom(t *testing.T) {
+	original := quotaHeadroom
+	quotaHeadroom = func(authID string, now time.Time) (float64, time.Time, bool) {
+		switch authID {
+		case "low":
+			return 0.05, now.Add(time.Hour), true
+		case "high":
+			return 0.8, now.Add(time.Hour), true
+		}
+		return 1, time.Time{}, false
+	}
+	defer func() { quotaHeadroom = original }()
+
+	now := time.Now()
+	low := &Auth{ID: "low", Provider: "claude"}
+	high := &Auth{ID: "high", Provider: "claude"}
+	untracked := &Auth{ID: "untracked", Provider: "claude"}
+	batch := cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.BatchMetadataKey: true}}
+
+	if got := preferQuotaHeadroom([]*Auth{low, high, untracked}, batch, now); len(got) != 2 || got[0] != high || got[1] != untracked {
+		t.Fatalf("batch requests should skip the exhausted credential, got %d candidates", len(got))
+	}
+	if got := preferQuotaHeadroom([]*Auth{low}, batch, now); len(got) != 1 {
+		t.Fatalf("batch requests must fall back when no credential has headroom")
+	}
+	if got := preferQuotaHeadroom([]*Auth{low, high}, cliproxyexecutor.Options{}, now); len(got) != 2 {
+		t.Fatalf("interactive requests must not be filtered")
+	}
+}
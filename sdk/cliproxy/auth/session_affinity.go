@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	defaultSessionAffinityTTL = time.Hour
+	// sessionAffinitySweepSize is the table size above which expired bindings are swept on write.
+	sessionAffinitySweepSize = 4096
+	// sessionAffinityMaxEntries caps the table. Every distinct conversation adds a binding, so
+	// without a cap a client sending unique first messages could grow it for a whole TTL.
+	sessionAffinityMaxEntries = 65536
+)
+
+type sessionBinding struct {
+	authID  string
+	expires time.Time
+}
+
+// sessionAffinityTable pins conversations to the auth that served them.
+type sessionAffinityTable struct {
+	mu       sync.Mutex
+	bindings map[string]sessionBinding
+	// sweepAt is the size that triggers the next sweep; it doubles past the live set so sweeps
+	// stay amortized instead of scanning the whole table on every write.
+	sweepAt int
+}
+
+func (t *sessionAffinityTable) lookup(key string, now time.Time) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	binding, ok := t.bindings[key]
+	if !ok {
+		return "", false
+	}
+	if now.After(binding.expires) {
+		delete(t.bindings, key)
+		return "", false
+	}
+	return binding.authID, true
+}
+
+func (t *sessionAffinityTable) bind(key, authID string, ttl time.Duration, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.bindings == nil {
+		t.bindings = make(map[string]sessionBinding)
+	}
+	if t.sweepAt < sessionAffinitySweepSize {
+		t.sweepAt = sessionAffinitySweepSize
+	}
+	if _, exists := t.bindings[key]; !exists && len(t.bindings) >= t.sweepAt {
+		for k, binding := range t.bindings {
+			if now.After(binding.expires) {
+				delete(t.bindings, k)
+			}
+		}
+		// Still full of live bindings: drop an arbitrary tenth so the sweep cost is amortized.
+		// The affected conversations simply get re-bound on their next turn.
+		if len(t.bindings) >= sessionAffinityMaxEntries {
+			evict := len(t.bindings) / 10
+			for k := range t.bindings {
+				if evict <= 0 {
+					break
+				}
+				delete(t.bindings, k)
+				evict--
+			}
+		}
+		t.sweepAt = min(max(2*len(t.bindings), sessionAffinitySweepSize), sessionAffinityMaxEntries)
+	}
+	t.bindings[key] = sessionBinding{authID: authID, expires: now.Add(ttl)}
+}
+
+// SessionAffinityEnabled reports whether routing.session-affinity is on, letting callers skip
+// deriving conversation keys that would never be used.
+func (m *Manager) SessionAffinityEnabled() bool {
+	if m == nil {
+		return false
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	enabled, _ := sessionAffinitySettings(cfg)
+	return enabled
+}
+
+// sessionAffinitySettings reports whether sticky routing is enabled and the binding lifetime.
+func sessionAffinitySettings(cfg *internalconfig.Config) (bool, time.Duration) {
+	if cfg == nil || !cfg.Routing.SessionAffinity {
+		return false, 0
+	}
+	ttl := defaultSessionAffinityTTL
+	if raw := strings.TrimSpace(cfg.Routing.SessionAffinityTTL); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed > 0 {
+			ttl = parsed
+		}
+	}
+	return true, ttl
+}
+
+func sessionKeyFromOptions(opts cliproxyexecutor.Options) string {
+	if len(opts.Metadata) == 0 {
+		return ""
+	}
+	key, _ := opts.Metadata[cliproxyexecutor.SessionKeyMetadataKey].(string)
+	return strings.TrimSpace(key)
+}
+
+// pickAuth selects an auth among candidates, preferring the auth bound to the request's
+// conversation when session affinity is enabled. A bound auth that is cooling down or no longer
+// a candidate is skipped and the conversation is re-bound to whatever the selector picks.
+func (m *Manager) pickAuth(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, candidates []*Auth) (*Auth, error) {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	candidates, originals := applyLabelPriorities(cfg, candidates)
+	original := func(auth *Auth) *Auth {
+		if source, ok := originals[auth]; ok {
+			return source
+		}
+		return auth
+	}
+	enabled, ttl := sessionAffinitySettings(cfg)
+	sessionKey := sessionKeyFromOptions(opts)
+	if !enabled || sessionKey == "" {
+		selected, err := m.selector.Pick(ctx, provider, model, opts, candidates)
+		return original(selected), err
+	}
+
+	now := time.Now()
+	if authID, ok := m.sessionAffinity.lookup(sessionKey, now); ok {
+		for _, candidate := range candidates {
+			if candidate == nil || candidate.ID != authID {
+				continue
+			}
+			if blocked, _, _ := isAuthBlockedForModel(candidate, model, now); !blocked {
+				m.sessionAffinity.bind(sessionKey, authID, ttl, now)
+				return original(candidate), nil
+			}
+			break
+		}
+	}
+
+	selected, err := m.selector.Pick(ctx, provider, model, opts, candidates)
+	if err != nil || selected == nil {
+		return original(selected), err
+	}
+	m.sessionAffinity.bind(sessionKey, selected.ID, ttl, now)
+	return original(selected), nil
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+func TestManagerPickAuth_SessionAffinity(t *testing.T) {
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	m.SetConfig(&internalconfig.Config{Routing: internalconfig.RoutingConfig{SessionAffinity: true}})
+	auths := []*Auth{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	session := cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.SessionKeyMetadataKey: "conv-1"}}
+
+	first, err := m.pickAuth(context.Background(), "gemini", "m", session, auths)
+	if err != nil {
+		t.Fatalf("pickAuth() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		// Requests without a session keep advancing the round-robin cursor.
+		if _, err = m.pickAuth(context.Background(), "gemini", "m", cliproxyexecutor.Options{}, auths); err != nil {
+			t.Fatalf("pickAuth() error = %v", err)
+		}
+		got, errPick := m.pickAuth(context.Background(), "gemini", "m", session, auths)
+		if errPick != nil {
+			t.Fatalf("pickAuth() error = %v", errPick)
+		}
+		if got.ID != first.ID {
+			t.Fatalf("turn %d picked %q, want sticky %q", i, got.ID, first.ID)
+		}
+	}
+
+	// A cooling-down auth is skipped and the conversation moves to another one.
+	for _, auth := range auths {
+		if auth.ID == first.ID {
+			auth.ModelStates = map[string]*ModelState{"m": {
+				Unavailable:    true,
+				NextRetryAfter: time.Now().Add(time.Minute),
+				Quota:          QuotaState{Exceeded: true},
+			}}
+		}
+	}
+	fallback, err := m.pickAuth(context.Background(), "gemini", "m", session, auths)
+	if err != nil {
+		t.Fatalf("pickAuth() error = %v", err)
+	}
+	if fallback.ID == first.ID {
+		t.Fatalf("expected fallback away from cooling-down auth %q", first.ID)
+	}
+	again, err := m.pickAuth(context.Background(), "gemini", "m", session, auths)
+	if err != nil {
+		t.Fatalf("pickAuth() error = %v", err)
+	}
+	if again.ID != fallback.ID {
+		t.Fatalf("expected conversation re-bound to %q, got %q", fallback.ID, again.ID)
+	}
+}
+
+func TestManagerPickAuth_SessionAffinityDisabled(t *testing.T) {
+	m := NewManager(nil, &RoundRobinSelector{}, nil)
+	auths := []*Auth{{ID: "a"}, {ID: "b"}}
+	session := cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.SessionKeyMetadataKey: "conv-1"}}
+
+	first, _ := m.pickAuth(context.Background(), "gemini", "m", session, auths)
+	second, _ := m.pickAuth(context.Background(), "gemini", "m", session, auths)
+	if first.ID == second.ID {
+		t.Fatalf("expected round-robin rotation when session affinity is disabled")
+	}
+}
+
+func TestSessionAffinityTable_HardCap(t *testing.T) {
+	var table sessionAffinityTable
+	now := time.Now()
+	for i := 0; i < sessionAffinityMaxEntries+100; i++ {
+		table.bind(fmt.Sprintf("conv-%d", i), "a", time.Hour, now)
+	}
+	if n := len(table.bindings); n > sessionAffinityMaxEntries {
+		t.Fatalf("table grew to %d live bindings, cap is %d", n, sessionAffinityMaxEntries)
+	}
+}
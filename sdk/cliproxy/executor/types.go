@@ -1,6 +1,7 @@
 package executor
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 
@@ -10,6 +11,38 @@ import (
 // RequestedModelMetadataKey stores the client-requested model name in Options.Metadata.
 const RequestedModelMetadataKey = "requested_model"
 
+// SessionKeyMetadataKey stores the conversation hash used for sticky credential routing in Options.Metadata.
+const SessionKeyMetadataKey = "session_key"
+
+// BatchMetadataKey marks requests replayed from a batch job in Options.Metadata. Routing sends
+// them to credentials with subscription quota headroom.
+const BatchMetadataKey = "batch"
+
+// ProviderOrderMetadataKey stores the client's preferred provider order ([]string) in
+// Options.Metadata. Mixed routing serves the request from the first listed provider with a
+// usable credential.
+const ProviderOrderMetadataKey = "provider_order"
+
+// AuthLabelsMetadataKey stores a label selector (map[string]string) in Options.Metadata. Routing
+// only serves the request from credentials carrying all of its labels.
+const AuthLabelsMetadataKey = "auth_labels"
+
+type batchContextKey struct{}
+
+// WithBatchRequest marks ctx as serving a batch job request.
+func WithBatchRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, batchContextKey{}, true)
+}
+
+// IsBatchRequest reports whether ctx was marked by WithBatchRequest.
+func IsBatchRequest(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	batch, _ := ctx.Value(batchContextKey{}).(bool)
+	return batch
+}
+
 // Request encapsulates the translated payload that will be sent to a provider executor.
 type Request struct {
 	// Model is the upstream model identifier after translation.
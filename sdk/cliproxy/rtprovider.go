@@ -47,7 +47,11 @@ func (p *defaultRoundTripperProvider) RoundTripperFor(auth *coreauth.Auth) http.
 	}
 	var transport *http.Transport
 	// Handle different proxy schemes.
-	if proxyURL.Scheme == "socks5" {
+	if strings.EqualFold(proxyStr, "direct") {
+		// "direct" bypasses the global proxy and proxy environment variables.
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+	} else if proxyURL.Scheme == "socks5" {
 		// Configure SOCKS5 proxy with optional authentication.
 		username := proxyURL.User.Username()
 		password, _ := proxyURL.User.Password()
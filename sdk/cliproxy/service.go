@@ -321,6 +321,25 @@ func (s *Service) applyCoreAuthRemoval(ctx context.Context, id string) {
 	}
 }
 
+// persistDiscoveredProject stores a project id the Gemini CLI executor discovered for an auth
+// whose file had none, so later requests and restarts skip the discovery.
+func (s *Service) persistDiscoveredProject(ctx context.Context, authID, projectID string) {
+	if s == nil || s.coreManager == nil {
+		return
+	}
+	existing, ok := s.coreManager.GetByID(authID)
+	if !ok || existing == nil {
+		return
+	}
+	if existing.Metadata == nil {
+		existing.Metadata = make(map[string]any)
+	}
+	existing.Metadata["project_id"] = projectID
+	if _, err := s.coreManager.Update(ctx, existing); err != nil {
+		log.Errorf("failed to persist project for auth %s: %v", authID, err)
+	}
+}
+
 func (s *Service) applyRetryConfig(cfg *config.Config) {
 	if s == nil || s.coreManager == nil || cfg == nil {
 		return
@@ -375,7 +394,9 @@ func (s *Service) ensureExecutorsForAuth(a *coreauth.Auth) {
 	case "vertex":
 		s.coreManager.RegisterExecutor(executor.NewGeminiVertexExecutor(s.cfg))
 	case "gemini-cli":
-		s.coreManager.RegisterExecutor(executor.NewGeminiCLIExecutor(s.cfg))
+		geminiCLIExec := executor.NewGeminiCLIExecutor(s.cfg)
+		geminiCLIExec.SetProjectDiscoveredHandler(s.persistDiscoveredProject)
+		s.coreManager.RegisterExecutor(geminiCLIExec)
 	case "aistudio":
 		if s.wsGateway != nil {
 			s.coreManager.RegisterExecutor(executor.NewAIStudioExecutor(s.cfg, a.ID, s.wsGateway))
@@ -391,6 +412,14 @@ func (s *Service) ensureExecutorsForAuth(a *coreauth.Auth) {
 		s.coreManager.RegisterExecutor(executor.NewQwenExecutor(s.cfg))
 	case "iflow":
 		s.coreManager.RegisterExecutor(executor.NewIFlowExecutor(s.cfg))
+	case "mistral":
+		s.coreManager.RegisterExecutor(executor.NewMistralExecutor(s.cfg))
+	case "xai":
+		s.coreManager.RegisterExecutor(executor.NewXAIExecutor(s.cfg))
+	case "deepseek":
+		s.coreManager.RegisterExecutor(executor.NewDeepSeekExecutor(s.cfg))
+	case "ollama":
+		s.coreManager.RegisterExecutor(executor.NewOllamaExecutor(s.cfg))
 	case "kiro":
 		s.coreManager.RegisterExecutor(executor.NewKiroExecutor(s.cfg))
 	case "github-copilot":
@@ -805,6 +834,19 @@ func (s *Service) registerModelsForAuth(a *coreauth.Auth) {
 		models = s.fetchKiroModels(a)
 		models = applyExcludedModels(models, excluded)
 	default:
+		if keyProvider, ok := config.LookupAPIKeyProvider(provider); ok {
+			models = registry.GetStaticModelDefinitionsByChannel(provider)
+			if entry := s.resolveConfigProviderKey(a, keyProvider); entry != nil {
+				if len(entry.Models) > 0 {
+					models = buildConfigModels(entry.Models, provider, provider)
+				}
+				if authKind == "apikey" {
+					excluded = entry.ExcludedModels
+				}
+			}
+			models = applyExcludedModels(models, excluded)
+			break
+		}
 		// Handle OpenAI-compatibility providers by name using config
 		if s.cfg != nil {
 			providerKey := provider
@@ -998,6 +1040,18 @@ func (s *Service) resolveConfigVertexCompatKey(auth *coreauth.Auth) *config.Vert
 	return nil
 }
 
+func (s *Service) resolveConfigProviderKey(auth *coreauth.Auth, provider config.APIKeyProvider) *config.ProviderKey {
+	if auth == nil || s.cfg == nil {
+		return nil
+	}
+	var attrKey, attrBase string
+	if auth.Attributes != nil {
+		attrKey = strings.TrimSpace(auth.Attributes["api_key"])
+		attrBase = strings.TrimSpace(auth.Attributes["base_url"])
+	}
+	return provider.FindKey(s.cfg, attrKey, attrBase)
+}
+
 func (s *Service) resolveConfigCodexKey(auth *coreauth.Auth) *config.CodexKey {
 	if auth == nil || s.cfg == nil {
 		return nil
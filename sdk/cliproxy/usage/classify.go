@@ -0,0 +1,166 @@
+package usage
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/gjson"
+)
+
+// Workload labels assigned to requests by the default classifier.
+const (
+	WorkloadChat  = "chat"
+	WorkloadCode  = "code"
+	WorkloadAgent = "agent"
+)
+
+// Classifier labels the workload type of a raw client request (e.g. "chat", "code", "agent").
+// Labels are recorded on usage records so usage can be broken down by workload.
+type Classifier interface {
+	Classify(rawJSON []byte) string
+}
+
+// ClassifierFunc adapts a function to the Classifier interface.
+type ClassifierFunc func(rawJSON []byte) string
+
+// Classify implements Classifier.
+func (f ClassifierFunc) Classify(rawJSON []byte) string { return f(rawJSON) }
+
+var (
+	classifierMu     sync.RWMutex
+	activeClassifier Classifier = HeuristicClassifier{}
+)
+
+// SetClassifier replaces the request classifier. Passing nil disables classification.
+func SetClassifier(c Classifier) {
+	classifierMu.Lock()
+	activeClassifier = c
+	classifierMu.Unlock()
+}
+
+// ClassifyRequest labels rawJSON with the active classifier. It returns an empty string when
+// classification is disabled.
+func ClassifyRequest(rawJSON []byte) string {
+	classifierMu.RLock()
+	c := activeClassifier
+	classifierMu.RUnlock()
+	if c == nil || len(rawJSON) == 0 {
+		return ""
+	}
+	return c.Classify(rawJSON)
+}
+
+// agentFingerprints are system prompt fragments of coding agents and agent frameworks.
+var agentFingerprints = []string{
+	"claude code",
+	"claude agent sdk",
+	"codex cli",
+	"interactive cli tool",
+	"you are cline",
+	"you are roo",
+	"kilo code",
+	"opencode",
+	"gemini cli",
+	"autonomous agent",
+}
+
+// codeFingerprints are system prompt fragments of coding assistants.
+var codeFingerprints = []string{
+	"software engineer",
+	"coding assistant",
+	"programming assistant",
+	"expert programmer",
+	"code review",
+}
+
+// toolHistoryMarkers appear in requests whose history contains tool calls or results in any of
+// the OpenAI, Claude, Responses or Gemini formats.
+var toolHistoryMarkers = [][]byte{
+	[]byte(`"tool_calls"`),
+	[]byte(`"tool_use"`),
+	[]byte(`"tool_result"`),
+	[]byte(`"function_call"`),
+	[]byte(`"function_call_output"`),
+	[]byte(`"functionCall"`),
+	[]byte(`"functionResponse"`),
+}
+
+// HeuristicClassifier is the default Classifier. Requests that declare tools and either carry
+// tool calls in their history or come from a known agent system prompt are "agent"; requests
+// with code fences or a coding-assistant system prompt are "code"; everything else is "chat".
+type HeuristicClassifier struct{}
+
+// Classify implements Classifier.
+func (HeuristicClassifier) Classify(rawJSON []byte) string {
+	system := strings.ToLower(systemPrompt(rawJSON))
+	hasTools := declaresTools(rawJSON)
+	if hasTools {
+		if containsAny(system, agentFingerprints) {
+			return WorkloadAgent
+		}
+		for _, marker := range toolHistoryMarkers {
+			if bytes.Contains(rawJSON, marker) {
+				return WorkloadAgent
+			}
+		}
+	}
+	if bytes.Contains(rawJSON, []byte("```")) || containsAny(system, codeFingerprints) || containsAny(system, agentFingerprints) {
+		return WorkloadCode
+	}
+	return WorkloadChat
+}
+
+func declaresTools(rawJSON []byte) bool {
+	for _, path := range []string{"tools", "functions", "request.tools"} {
+		if tools := gjson.GetBytes(rawJSON, path); tools.IsArray() && len(tools.Array()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// systemPrompt collects the system instructions of a Claude, OpenAI Chat Completions, OpenAI
+// Responses or Gemini request.
+func systemPrompt(rawJSON []byte) string {
+	var b strings.Builder
+	appendText := func(value gjson.Result) {
+		if value.Type == gjson.String {
+			b.WriteString(value.String())
+			b.WriteByte('\n')
+			return
+		}
+		value.ForEach(func(_, part gjson.Result) bool {
+			if text := part.Get("text"); text.Exists() {
+				b.WriteString(text.String())
+				b.WriteByte('\n')
+			}
+			return true
+		})
+	}
+	for _, path := range []string{"system", "instructions", "systemInstruction.parts", "request.systemInstruction.parts"} {
+		if value := gjson.GetBytes(rawJSON, path); value.Exists() {
+			appendText(value)
+		}
+	}
+	gjson.GetBytes(rawJSON, "messages").ForEach(func(_, message gjson.Result) bool {
+		role := message.Get("role").String()
+		if role == "system" || role == "developer" {
+			appendText(message.Get("content"))
+		}
+		return true
+	})
+	return b.String()
+}
+
+func containsAny(text string, fragments []string) bool {
+	if text == "" {
+		return false
+	}
+	for _, fragment := range fragments {
+		if strings.Contains(text, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package usage
+
+import "testing"
+
+func TestHeuristicClassifier(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"plain chat", `{"messages":[{"role":"user","content":"What is the capital of France?"}]}`, WorkloadChat},
+		{"code fence", "{\"messages\":[{\"role\":\"user\",\"content\":\"Why does this fail?\\n```go\\nfmt.Println(x)\\n```\"}]}", WorkloadCode},
+		{"coding system prompt", `{"system":"You are an expert programmer.","messages":[{"role":"user","content":"hi"}]}`, WorkloadCode},
+		{"tools with history", `{"tools":[{"name":"read_file"}],"messages":[{"role":"assistant","content":[{"type":"tool_use","id":"1"}]}]}`, WorkloadAgent},
+		{"agent fingerprint", `{"tools":[{"type":"function"}],"messages":[{"role":"system","content":"You are Claude Code, an interactive CLI tool."},{"role":"user","content":"fix it"}]}`, WorkloadAgent},
+		{"gemini tools", `{"request":{"tools":[{"functionDeclarations":[]}],"contents":[{"role":"model","parts":[{"functionCall":{"name":"ls"}}]}]}}`, WorkloadAgent},
+		{"tools without history", `{"tools":[{"name":"get_weather"}],"messages":[{"role":"user","content":"Weather in Paris?"}]}`, WorkloadChat},
+	}
+	for _, tc := range cases {
+		if got := (HeuristicClassifier{}).Classify([]byte(tc.body)); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSetClassifier(t *testing.T) {
+	t.Cleanup(func() { SetClassifier(HeuristicClassifier{}) })
+
+	SetClassifier(ClassifierFunc(func([]byte) string { return "batch" }))
+	if got := ClassifyRequest([]byte(`{}`)); got != "batch" {
+		t.Fatalf("custom classifier: got %q", got)
+	}
+	SetClassifier(nil)
+	if got := ClassifyRequest([]byte(`{}`)); got != "" {
+		t.Fatalf("disabled classifier: got %q", got)
+	}
+}
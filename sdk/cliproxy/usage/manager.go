@@ -19,6 +19,19 @@ type Record struct {
 	RequestedAt time.Time
 	Failed      bool
 	Detail      Detail
+	// Tags carries selected client request metadata (see usage-metadata-keys).
+	Tags map[string]string
+	// Workload is the request's workload label from the usage classifier (e.g. "chat", "code", "agent").
+	Workload string
+	// Region is the upstream region that served the request, when the provider reports one.
+	Region string
+	// Tenant is the id of the tenant whose API key authenticated the request, if any.
+	Tenant string
+	// ClientIP is the address of the client that made the request.
+	ClientIP string
+	// Estimated reports that Detail was estimated locally with a tokenizer because the upstream
+	// returned no token counts.
+	Estimated bool
 }
 
 // Detail holds the token usage breakdown.
@@ -0,0 +1,125 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StreamEventType identifies the phase of a streaming response reported to stream plugins.
+type StreamEventType string
+
+const (
+	// StreamStart is emitted once before the first chunk is forwarded to the client.
+	StreamStart StreamEventType = "start"
+	// StreamProgress is emitted periodically while chunks are being forwarded.
+	StreamProgress StreamEventType = "progress"
+	// StreamEnd is emitted once when the stream finishes, fails, or is aborted.
+	StreamEnd StreamEventType = "end"
+)
+
+// ErrStreamLimitReached may be wrapped by a StreamPlugin error to end a stream gracefully.
+// Instead of failing, the stream is closed with the protocol's length finish reason
+// (e.g. OpenAI "length", Claude "max_tokens").
+var ErrStreamLimitReached = errors.New("stream limit reached")
+
+// StreamEvent describes the state of an in-flight streaming response.
+// Token figures are estimates derived from the forwarded payload; the authoritative
+// counts still arrive through Plugin.HandleUsage once the provider reports usage.
+type StreamEvent struct {
+	Type      StreamEventType
+	Model     string
+	APIKey    string
+	Tags      map[string]string
+	StartedAt time.Time
+	Time      time.Time
+	// Chunks and Bytes count the payload forwarded to the client so far.
+	Chunks int64
+	Bytes  int64
+	// EstimatedOutputTokens approximates the output tokens forwarded so far from the generated text.
+	EstimatedOutputTokens int64
+	// Failed and Err describe how the stream ended; they are only set on StreamEnd.
+	// A stream truncated through ErrStreamLimitReached is not failed but carries the limit error.
+	Failed bool
+	Err    error
+}
+
+// StreamPlugin is an optional extension of Plugin for consumers that need live streaming events.
+// Plugins registered through Register receive stream events when they implement this interface.
+//
+// Events are delivered synchronously on the streaming goroutine, so implementations must return
+// quickly. Returning a non-nil error from a StreamStart or StreamProgress event aborts the stream,
+// which allows plugins to enforce budgets mid-response; wrap ErrStreamLimitReached to truncate the
+// stream with a length finish reason rather than an error. Errors returned for StreamEnd are ignored.
+type StreamPlugin interface {
+	HandleStreamEvent(ctx context.Context, event StreamEvent) error
+}
+
+// HasStreamPlugins reports whether any registered plugin implements StreamPlugin.
+func (m *Manager) HasStreamPlugins() bool {
+	if m == nil {
+		return false
+	}
+	m.pluginsMu.RLock()
+	defer m.pluginsMu.RUnlock()
+	for _, plugin := range m.plugins {
+		if _, ok := plugin.(StreamPlugin); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishStreamEvent delivers event to every registered StreamPlugin and returns the first
+// error reported by a plugin. All plugins receive the event even when one of them fails.
+func (m *Manager) PublishStreamEvent(ctx context.Context, event StreamEvent) error {
+	if m == nil {
+		return nil
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	m.pluginsMu.RLock()
+	plugins := make([]Plugin, len(m.plugins))
+	copy(plugins, m.plugins)
+	m.pluginsMu.RUnlock()
+
+	var firstErr error
+	for _, plugin := range plugins {
+		streamPlugin, ok := plugin.(StreamPlugin)
+		if !ok {
+			continue
+		}
+		if err := safeInvokeStream(streamPlugin, ctx, event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if event.Type == StreamEnd {
+		return nil
+	}
+	return firstErr
+}
+
+func safeInvokeStream(plugin StreamPlugin, ctx context.Context, event StreamEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("usage: stream plugin panic recovered: %v", r)
+			err = nil
+		}
+	}()
+	if errHandle := plugin.HandleStreamEvent(ctx, event); errHandle != nil {
+		return fmt.Errorf("usage: stream aborted by plugin: %w", errHandle)
+	}
+	return nil
+}
+
+// HasStreamPlugins reports whether the default manager has stream plugins registered.
+func HasStreamPlugins() bool { return DefaultManager().HasStreamPlugins() }
+
+// PublishStreamEvent publishes a stream event using the default manager.
+func PublishStreamEvent(ctx context.Context, event StreamEvent) error {
+	return DefaultManager().PublishStreamEvent(ctx, event)
+}
@@ -0,0 +1,72 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recordingStreamPlugin struct {
+	events []StreamEvent
+	err    error
+}
+
+func (p *recordingStreamPlugin) HandleUsage(context.Context, Record) {}
+
+func (p *recordingStreamPlugin) HandleStreamEvent(_ context.Context, event StreamEvent) error {
+	p.events = append(p.events, event)
+	return p.err
+}
+
+type recordOnlyPlugin struct{}
+
+func (recordOnlyPlugin) HandleUsage(context.Context, Record) {}
+
+type panickingStreamPlugin struct{}
+
+func (panickingStreamPlugin) HandleUsage(context.Context, Record) {}
+
+func (panickingStreamPlugin) HandleStreamEvent(context.Context, StreamEvent) error {
+	panic("boom")
+}
+
+func TestManagerHasStreamPlugins(t *testing.T) {
+	m := NewManager(0)
+	m.Register(recordOnlyPlugin{})
+	if m.HasStreamPlugins() {
+		t.Fatalf("expected no stream plugins for record-only plugin")
+	}
+	m.Register(&recordingStreamPlugin{})
+	if !m.HasStreamPlugins() {
+		t.Fatalf("expected stream plugin to be detected")
+	}
+}
+
+func TestManagerPublishStreamEvent(t *testing.T) {
+	budgetErr := errors.New("budget exceeded")
+	recorder := &recordingStreamPlugin{}
+	limiter := &recordingStreamPlugin{err: budgetErr}
+
+	m := NewManager(0)
+	m.Register(recordOnlyPlugin{})
+	m.Register(panickingStreamPlugin{})
+	m.Register(limiter)
+	m.Register(recorder)
+
+	if err := m.PublishStreamEvent(context.Background(), StreamEvent{Type: StreamStart, Model: "m"}); !errors.Is(err, budgetErr) {
+		t.Fatalf("start error = %v, want %v", err, budgetErr)
+	}
+	if err := m.PublishStreamEvent(context.Background(), StreamEvent{Type: StreamEnd, Model: "m"}); err != nil {
+		t.Fatalf("end error = %v, want nil", err)
+	}
+
+	if len(recorder.events) != 2 {
+		t.Fatalf("recorder got %d events, want 2", len(recorder.events))
+	}
+	if recorder.events[0].Type != StreamStart || recorder.events[1].Type != StreamEnd {
+		t.Fatalf("unexpected event order: %+v", recorder.events)
+	}
+	if recorder.events[0].Time.IsZero() {
+		t.Fatalf("expected event time to be set")
+	}
+}
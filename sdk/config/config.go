@@ -9,12 +9,44 @@ import internalconfig "github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 type SDKConfig = internalconfig.SDKConfig
 type AccessConfig = internalconfig.AccessConfig
 type AccessProvider = internalconfig.AccessProvider
+type APIKeyModelAccess = internalconfig.APIKeyModelAccess
+type APIKeyProtocolAccess = internalconfig.APIKeyProtocolAccess
+type APIKeyBudget = internalconfig.APIKeyBudget
+type TrafficPause = internalconfig.TrafficPause
+type RequestQueueConfig = internalconfig.RequestQueueConfig
+type LatencyRoutingConfig = internalconfig.LatencyRoutingConfig
+type ModelDowngradeChain = internalconfig.ModelDowngradeChain
+type CanaryRoute = internalconfig.CanaryRoute
+type CanarySplit = internalconfig.CanarySplit
+type APIKeyPriority = internalconfig.APIKeyPriority
+type RequestScript = internalconfig.RequestScript
+type GuardrailPolicy = internalconfig.GuardrailPolicy
+type ThinkingOutputPolicy = internalconfig.ThinkingOutputPolicy
+type LogprobsRule = internalconfig.LogprobsRule
+type SafetySettingsConfig = internalconfig.SafetySettingsConfig
+type SafetySettingsRule = internalconfig.SafetySettingsRule
+type OAuthCallbackConfig = internalconfig.OAuthCallbackConfig
+type SystemPromptConfig = internalconfig.SystemPromptConfig
+type SystemPromptRule = internalconfig.SystemPromptRule
+type ContextWindowConfig = internalconfig.ContextWindowConfig
+type UsageSnapshotConfig = internalconfig.UsageSnapshotConfig
+type UsageReportConfig = internalconfig.UsageReportConfig
+type UsageReportS3Config = internalconfig.UsageReportS3Config
+type AuthGuardConfig = internalconfig.AuthGuardConfig
+type BatchConfig = internalconfig.BatchConfig
+type ProviderStatusConfig = internalconfig.ProviderStatusConfig
+type VertexRegionRule = internalconfig.VertexRegionRule
+type ProviderStatusFeed = internalconfig.ProviderStatusFeed
+type TelemetryConfig = internalconfig.TelemetryConfig
+type TenantsConfig = internalconfig.TenantsConfig
+type AuditLogConfig = internalconfig.AuditLogConfig
 
 type Config = internalconfig.Config
 
 type StreamingConfig = internalconfig.StreamingConfig
 type TLSConfig = internalconfig.TLSConfig
 type RemoteManagement = internalconfig.RemoteManagement
+type RetryPolicy = internalconfig.RetryPolicy
 type AmpCode = internalconfig.AmpCode
 type OAuthModelAlias = internalconfig.OAuthModelAlias
 type PayloadConfig = internalconfig.PayloadConfig
@@ -25,6 +57,9 @@ type PayloadModelRule = internalconfig.PayloadModelRule
 type GeminiKey = internalconfig.GeminiKey
 type CodexKey = internalconfig.CodexKey
 type ClaudeKey = internalconfig.ClaudeKey
+type ProviderKey = internalconfig.ProviderKey
+type ProviderModel = internalconfig.ProviderModel
+type APIKeyProvider = internalconfig.APIKeyProvider
 type VertexCompatKey = internalconfig.VertexCompatKey
 type VertexCompatModel = internalconfig.VertexCompatModel
 type OpenAICompatibility = internalconfig.OpenAICompatibility
@@ -37,12 +72,18 @@ const (
 	AccessProviderTypeConfigAPIKey = internalconfig.AccessProviderTypeConfigAPIKey
 	DefaultAccessProviderName      = internalconfig.DefaultAccessProviderName
 	DefaultPanelGitHubRepository   = internalconfig.DefaultPanelGitHubRepository
+	DefaultOllamaBaseURL           = internalconfig.DefaultOllamaBaseURL
 )
 
 func MakeInlineAPIKeyProvider(keys []string) *AccessProvider {
 	return internalconfig.MakeInlineAPIKeyProvider(keys)
 }
 
+// LookupAPIKeyProvider returns the provider configured through ProviderKey entries named name.
+func LookupAPIKeyProvider(name string) (APIKeyProvider, bool) {
+	return internalconfig.LookupAPIKeyProvider(name)
+}
+
 func LoadConfig(configFile string) (*Config, error) { return internalconfig.LoadConfig(configFile) }
 
 func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
@@ -10,6 +10,7 @@ type Registry struct {
 	mu        sync.RWMutex
 	requests  map[Format]map[Format]RequestTransform
 	responses map[Format]map[Format]ResponseTransform
+	gate      RequestGate
 }
 
 // NewRegistry constructs an empty translator registry.
@@ -38,12 +39,23 @@ func (r *Registry) Register(from, to Format, request RequestTransform, response
 	r.responses[from][to] = response
 }
 
+// SetRequestGate installs the gate applied to every request payload before translation.
+// Passing nil removes it.
+func (r *Registry) SetRequestGate(gate RequestGate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gate = gate
+}
+
 // TranslateRequest converts a payload between schemas, returning the original payload
 // if no translator is registered.
 func (r *Registry) TranslateRequest(from, to Format, model string, rawJSON []byte, stream bool) []byte {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	if r.gate != nil {
+		rawJSON = r.gate(from, model, rawJSON)
+	}
 	if byTarget, ok := r.requests[from]; ok {
 		if fn, isOk := byTarget[to]; isOk && fn != nil {
 			return fn(model, rawJSON, stream)
@@ -116,6 +128,11 @@ func Register(from, to Format, request RequestTransform, response ResponseTransf
 	defaultRegistry.Register(from, to, request, response)
 }
 
+// SetRequestGate installs the request gate on the default registry.
+func SetRequestGate(gate RequestGate) {
+	defaultRegistry.SetRequestGate(gate)
+}
+
 // TranslateRequest is a helper on the default registry.
 func TranslateRequest(from, to Format, model string, rawJSON []byte, stream bool) []byte {
 	return defaultRegistry.TranslateRequest(from, to, model, rawJSON, stream)
@@ -8,6 +8,10 @@ import "context"
 // It returns the converted request payload as a byte slice.
 type RequestTransform func(model string, rawJSON []byte, stream bool) []byte
 
+// RequestGate adapts a request payload in the source schema to what the target model supports
+// before it is translated, e.g. by dropping tools for a model without tool calling.
+type RequestGate func(from Format, model string, rawJSON []byte) []byte
+
 // ResponseStreamTransform is a function type that converts a streaming response from a source schema to a target schema.
 // It takes a context, the model name, the raw JSON of the original and converted requests, the raw JSON of the current response chunk, and an optional parameter.
 // It returns a slice of strings, where each string is a chunk of the converted streaming response.
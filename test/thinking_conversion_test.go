@@ -1709,27 +1709,27 @@ func TestThinkingE2EMatrix_Body(t *testing.T) {
 			expectField: "",
 			expectErr:   false,
 		},
-		// Case 34: thinking.budget_tokens=8192 → 8192 (keeps budget)
+		// Case 34: thinking.budget_tokens=8192 → medium → clamped to low (tie prefers lower)
 		{
 			name:            "34",
 			from:            "claude",
 			to:              "gemini",
 			model:           "gemini-mixed-model",
 			inputJSON:       `{"model":"gemini-mixed-model","messages":[{"role":"user","content":"hi"}],"thinking":{"type":"enabled","budget_tokens":8192}}`,
-			expectField:     "generationConfig.thinkingConfig.thinkingBudget",
-			expectValue:     "8192",
+			expectField:     "generationConfig.thinkingConfig.thinkingLevel",
+			expectValue:     "low",
 			includeThoughts: "true",
 			expectErr:       false,
 		},
-		// Case 35: thinking.budget_tokens=64000 → clamped to 32768 (keeps budget)
+		// Case 35: thinking.budget_tokens=64000 → high
 		{
 			name:            "35",
 			from:            "claude",
 			to:              "gemini",
 			model:           "gemini-mixed-model",
 			inputJSON:       `{"model":"gemini-mixed-model","messages":[{"role":"user","content":"hi"}],"thinking":{"type":"enabled","budget_tokens":64000}}`,
-			expectField:     "generationConfig.thinkingConfig.thinkingBudget",
-			expectValue:     "32768",
+			expectField:     "generationConfig.thinkingConfig.thinkingLevel",
+			expectValue:     "high",
 			includeThoughts: "true",
 			expectErr:       false,
 		},